@@ -40,6 +40,7 @@ import (
 	"github.com/grafana/mimir/pkg/scheduler/schedulerpb"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/util/activequeries"
 	"github.com/grafana/mimir/pkg/util/gziphandler"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/push"
@@ -240,6 +241,9 @@ func (a *API) RegisterDistributor(d *distributor.Distributor, pushConfig distrib
 	pushFn := d.GetPushFunc(a.cfg.DistributorPushWrapper)
 	a.RegisterRoute("/api/v1/push", push.Handler(pushConfig.MaxRecvMsgSize, a.sourceIPs, a.cfg.SkipLabelNameValidationHeader, pushFn), true, false, "POST")
 	a.RegisterRoute("/otlp/v1/metrics", push.OTLPHandler(pushConfig.MaxRecvMsgSize, a.sourceIPs, a.cfg.SkipLabelNameValidationHeader, reg, pushFn), true, false, "POST")
+	a.RegisterRoute("/api/v1/push/influx/write", push.InfluxHandler(pushConfig.MaxRecvMsgSize, a.sourceIPs, pushConfig.Influx, reg, pushFn), true, false, "POST")
+	a.RegisterRoute("/api/v1/push/graphite", push.GraphiteHandler(pushConfig.MaxRecvMsgSize, a.sourceIPs, pushConfig.Graphite, reg, pushFn), true, false, "POST")
+	a.RegisterRoute("/api/v1/dead_letters", http.HandlerFunc(d.DeadLetters.Handler), true, true, "GET")
 
 	a.indexPage.AddLinks(defaultWeight, "Distributor", []IndexPageLink{
 		{Desc: "Ring status", Path: "/distributor/ring"},
@@ -346,6 +350,12 @@ func (a *API) RegisterCompactor(c *compactor.MultitenantCompactor) {
 	a.RegisterRoute("/api/v1/upload/block/{block}/check", http.HandlerFunc(c.GetBlockUploadStateHandler), true, false, http.MethodGet)
 	a.RegisterRoute("/compactor/delete_tenant", http.HandlerFunc(c.DeleteTenant), true, true, "POST")
 	a.RegisterRoute("/compactor/delete_tenant_status", http.HandlerFunc(c.DeleteTenantStatus), true, true, "GET")
+	a.RegisterRoute("/compactor/block_rewrite_jobs", http.HandlerFunc(c.CreateBlockRewriteJob), true, true, http.MethodPost)
+	a.RegisterRoute("/compactor/block_rewrite_jobs", http.HandlerFunc(c.BlockRewriteJobs), true, true, http.MethodGet)
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/series"), http.HandlerFunc(c.DeleteSeries), true, true, http.MethodDelete)
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/admin/tsdb/delete_series"), http.HandlerFunc(c.DeleteSeries), true, true, http.MethodPost, http.MethodPut)
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/admin/tsdb/delete_series"), http.HandlerFunc(c.SeriesDeletionRequests), true, true, http.MethodGet)
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/admin/tsdb/cancel_delete_request"), http.HandlerFunc(c.CancelSeriesDeletionRequest), true, true, http.MethodPost, http.MethodPut)
 }
 
 type Distributor interface {
@@ -371,11 +381,13 @@ func (a *API) RegisterQueryAPI(handler http.Handler, buildInfoHandler http.Handl
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/query_exemplars"), handler, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/labels"), handler, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/label/{name}/values"), handler, true, true, "GET")
-	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/series"), handler, true, true, "GET", "POST", "DELETE")
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/series"), handler, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/status/buildinfo"), buildInfoHandler, false, true, "GET")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/metadata"), handler, true, true, "GET")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/cardinality/label_names"), handler, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/cardinality/label_values"), handler, true, true, "GET", "POST")
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/cardinality/active_growth"), handler, true, true, "GET", "POST")
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/cardinality/active_series"), handler, true, true, "GET", "POST")
 }
 
 // RegisterQueryFrontendHandler registers the Prometheus routes supported by the
@@ -385,6 +397,13 @@ func (a *API) RegisterQueryFrontendHandler(h http.Handler, buildInfoHandler http
 	a.RegisterQueryAPI(h, buildInfoHandler)
 }
 
+// RegisterActiveQueries registers the endpoints to list and cancel the in-flight queries tracked by
+// the given tracker, under the path prefix of the component.
+func (a *API) RegisterActiveQueries(pathPrefix string, tracker *activequeries.Tracker) {
+	a.RegisterRoute(path.Join(pathPrefix, "active_queries"), http.HandlerFunc(tracker.ListHandler), true, true, "GET")
+	a.RegisterRoute(path.Join(pathPrefix, "active_queries/{id}"), http.HandlerFunc(tracker.CancelHandler), true, true, "DELETE")
+}
+
 func (a *API) RegisterQueryFrontend1(f *frontendv1.Frontend) {
 	frontendv1pb.RegisterFrontendServer(a.server.GRPC, f)
 }
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// processBlockRewriteJobs applies the relabel configs of the pending block rewrite jobs of the tenant to the
+// blocks created before each job.
+//
+// Each block is downloaded and rewritten with the relabelled series, and the original block is marked for
+// deletion. Blocks whose series are not changed by the job, and rewritten blocks, are tracked in the job, so
+// that they're not processed again. A job is marked as completed once a pass doesn't find any block left to
+// rewrite: this guarantees that blocks compacted concurrently from not yet rewritten blocks are rewritten too.
+func (c *MultitenantCompactor) processBlockRewriteJobs(ctx context.Context, userID string) error {
+	if !c.cfgProvider.CompactorBlockRewriteEnabled(userID) {
+		return nil
+	}
+
+	// Only one compactor processes the jobs of a tenant, so that blocks don't get rewritten twice.
+	if owned, err := c.shardingStrategy.blocksCleanerOwnUser(userID); err != nil || !owned {
+		return err
+	}
+
+	jobs, err := mimir_tsdb.ListBlockRewriteJobs(ctx, c.bucketClient, userID)
+	if err != nil {
+		return err
+	}
+
+	var pending []*mimir_tsdb.BlockRewriteJob
+	for _, j := range jobs {
+		if j.State == mimir_tsdb.BlockRewriteJobPending {
+			pending = append(pending, j)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	logger := util_log.WithUserID(userID, c.logger)
+	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
+
+	workDir := filepath.Join(c.compactorCfg.DataDir, "block-rewrite", userID)
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove block rewrite work directory", "path", workDir, "err", err)
+		}
+	}()
+
+	// Jobs are applied one after the other, because each job may rewrite the blocks the next one applies to.
+	rewrittenAny := false
+	for _, j := range pending {
+		metas, err := c.fetchRewritableBlockMetas(ctx, logger, userBucket, userID)
+		if err != nil {
+			return err
+		}
+
+		jobLogger := log.With(logger, "job_id", j.ID)
+		rewritten, err := c.applyBlockRewriteJob(ctx, jobLogger, userBucket, userID, j, metas, workDir)
+		if rewritten {
+			rewrittenAny = true
+		}
+		if err != nil {
+			return err
+		}
+
+		if rewritten {
+			continue
+		}
+
+		j.State = mimir_tsdb.BlockRewriteJobCompleted
+		j.StateChangeTime = time.Now().Unix()
+		j.ProcessedBlocks = nil
+		if err := mimir_tsdb.WriteBlockRewriteJob(ctx, c.bucketClient, userID, c.cfgProvider, j); err != nil {
+			return err
+		}
+
+		c.blockRewriteJobsCompleted.Inc()
+		level.Info(jobLogger).Log("msg", "block rewrite job completed", "rewritten_blocks", j.RewrittenBlocks)
+	}
+
+	if !rewrittenAny {
+		return nil
+	}
+
+	// Update the bucket index right away, so that the rewritten blocks are queried without waiting
+	// for the next blocks cleanup.
+	return c.updateBucketIndex(ctx, logger, userID)
+}
+
+// applyBlockRewriteJob applies the job to the blocks in input, downsampled blocks included, so that they don't keep
+// serving the series changed by the job. Returns true if at least one block has been rewritten.
+func (c *MultitenantCompactor) applyBlockRewriteJob(ctx context.Context, logger log.Logger, userBucket objstore.Bucket, userID string, j *mimir_tsdb.BlockRewriteJob, metas map[ulid.ULID]*metadata.Meta, workDir string) (bool, error) {
+	rewritten := false
+
+	for _, meta := range metas {
+		if !j.AppliesToBlock(meta.ULID, meta.MinTime) {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return rewritten, ctx.Err()
+		}
+
+		blockLogger := log.With(logger, "block", meta.ULID)
+		newID, ok, err := c.rewriteBlockWithRelabelConfigs(ctx, blockLogger, userBucket, meta, j.RelabelConfigs, filepath.Join(workDir, meta.ULID.String()))
+		if err != nil {
+			return rewritten, errors.Wrapf(err, "rewrite block %s", meta.ULID)
+		}
+
+		if ok {
+			rewritten = true
+			j.RewrittenBlocks++
+			if newID != (ulid.ULID{}) {
+				j.ProcessedBlocks = append(j.ProcessedBlocks, newID)
+			}
+		} else {
+			j.ProcessedBlocks = append(j.ProcessedBlocks, meta.ULID)
+		}
+
+		if err := mimir_tsdb.WriteBlockRewriteJob(ctx, c.bucketClient, userID, c.cfgProvider, j); err != nil {
+			return rewritten, err
+		}
+	}
+
+	return rewritten, nil
+}
+
+// rewriteBlockWithRelabelConfigs rewrites the block applying the relabel configs to its series, and marks the
+// original block for deletion. Returns false if the relabel configs don't change any series of the block, and
+// the block hasn't been rewritten. The returned block ID is zero if all the series of the block have been dropped.
+func (c *MultitenantCompactor) rewriteBlockWithRelabelConfigs(ctx context.Context, logger log.Logger, userBucket objstore.Bucket, meta *metadata.Meta, relabelConfigs []*relabel.Config, dir string) (ulid.ULID, bool, error) {
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove block rewrite block directory", "path", dir, "err", err)
+		}
+	}()
+
+	if err := block.Download(ctx, logger, userBucket, meta.ULID, filepath.Join(dir, meta.ULID.String())); err != nil {
+		return ulid.ULID{}, false, errors.Wrap(err, "download block")
+	}
+
+	newID, changed, err := block.Relabel(logger, dir, meta.ULID, metadata.CompactorRewriteSource, relabelConfigs)
+	if err != nil {
+		return ulid.ULID{}, false, errors.Wrap(err, "relabel block")
+	}
+	if !changed {
+		level.Debug(logger).Log("msg", "block doesn't contain series changed by the relabel configs")
+		return ulid.ULID{}, false, nil
+	}
+
+	newDir := filepath.Join(dir, newID.String())
+	newMeta, err := metadata.ReadFromDir(newDir)
+	if err != nil {
+		return ulid.ULID{}, false, errors.Wrap(err, "read rewritten block meta")
+	}
+
+	if newMeta.Stats.NumSeries > 0 {
+		// Relabelled series don't necessarily belong to the compactor shard of the original block anymore,
+		// so the rewritten block is handled as a non-split one.
+		if _, ok := newMeta.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel]; ok {
+			delete(newMeta.Thanos.Labels, mimir_tsdb.CompactorShardIDExternalLabel)
+			if err := newMeta.WriteToDir(logger, newDir); err != nil {
+				return ulid.ULID{}, false, errors.Wrap(err, "write rewritten block meta")
+			}
+		}
+
+		if err := block.VerifyIndex(logger, filepath.Join(newDir, block.IndexFilename), newMeta.MinTime, newMeta.MaxTime); err != nil {
+			return ulid.ULID{}, false, errors.Wrapf(err, "invalid result block %s", newDir)
+		}
+
+		if err := block.Upload(ctx, logger, userBucket, newDir, nil); err != nil {
+			return ulid.ULID{}, false, errors.Wrapf(err, "upload of %s failed", newID)
+		}
+
+		level.Info(logger).Log("msg", "uploaded block rewritten with relabel configs", "result_block", newID)
+	} else {
+		level.Info(logger).Log("msg", "all series of the block have been dropped by the relabel configs")
+		newID = ulid.ULID{}
+	}
+
+	c.blockRewriteBlocksRewritten.Inc()
+
+	// Spawn a new context so we always mark a block for deletion in full on shutdown.
+	delCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := block.MarkForDeletion(delCtx, logger, userBucket, meta.ULID, "source of block rewritten by block rewrite job", c.blockRewriteBlocksMarkedForDeletion); err != nil {
+		return ulid.ULID{}, false, errors.Wrapf(err, "mark block %s for deletion", meta.ULID)
+	}
+
+	return newID, true, nil
+}
+
+// updateBucketIndex updates the bucket index of the tenant with the blocks and deletion marks currently in the bucket.
+func (c *MultitenantCompactor) updateBucketIndex(ctx context.Context, logger log.Logger, userID string) error {
+	idx, err := bucketindex.ReadIndex(ctx, c.bucketClient, userID, c.cfgProvider, logger)
+	if errors.Is(err, bucketindex.ErrIndexCorrupted) {
+		level.Warn(logger).Log("msg", "found a corrupted bucket index, recreating it")
+	} else if err != nil && !errors.Is(err, bucketindex.ErrIndexNotFound) {
+		return err
+	}
+
+	idx, _, err = bucketindex.NewUpdater(c.bucketClient, userID, c.cfgProvider, logger).UpdateIndex(ctx, idx)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(bucketindex.WriteIndex(ctx, c.bucketClient, userID, c.cfgProvider, idx), "write bucket index")
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/model/relabel"
+	"gopkg.in/yaml.v3"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// BlockRewriteJobRequest is the request body of the API creating a block rewrite job.
+type BlockRewriteJobRequest struct {
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs"`
+}
+
+// BlockRewriteJobsResponse is the response of the API listing the block rewrite jobs.
+type BlockRewriteJobsResponse struct {
+	Jobs []*mimir_tsdb.BlockRewriteJob `yaml:"jobs"`
+}
+
+// CreateBlockRewriteJob handles requests to rewrite the blocks of the tenant, applying the relabel configs
+// in the request body to the stored series. The job is applied by the compactor to all the blocks of the tenant
+// with samples older than the job creation time.
+func (c *MultitenantCompactor) CreateBlockRewriteJob(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := c.blockRewriteTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := BlockRewriteJobRequest{}
+	if err := yaml.Unmarshal(payload, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := mimir_tsdb.NewBlockRewriteJob(req.RelabelConfigs, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	logger := log.With(util_log.WithContext(ctx, c.logger), "user", tenantID, "job_id", job.ID)
+
+	if err := mimir_tsdb.WriteBlockRewriteJob(ctx, c.bucketClient, tenantID, c.cfgProvider, job); err != nil {
+		level.Error(logger).Log("msg", "failed to write block rewrite job", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(logger).Log("msg", "block rewrite job created", "relabel_configs", len(job.RelabelConfigs))
+	util.WriteYAMLResponse(w, job)
+}
+
+// BlockRewriteJobs lists all the block rewrite jobs of the tenant, regardless of their state.
+func (c *MultitenantCompactor) BlockRewriteJobs(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := c.blockRewriteTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	jobs, err := mimir_tsdb.ListBlockRewriteJobs(r.Context(), c.bucketClient, tenantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+
+	util.WriteYAMLResponse(w, BlockRewriteJobsResponse{Jobs: jobs})
+}
+
+func (c *MultitenantCompactor) blockRewriteTenantID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tenantID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return "", false
+	}
+
+	if !c.cfgProvider.CompactorBlockRewriteEnabled(tenantID) {
+		http.Error(w, "block rewrite is disabled", http.StatusForbidden)
+		return "", false
+	}
+
+	return tenantID, true
+}
@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/weaveworks/common/user"
+	"gopkg.in/yaml.v3"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func TestMultitenantCompactor_BlockRewriteJobsAPI(t *testing.T) {
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.blockRewriteEnabled["user-1"] = true
+	cfgProvider.blockRewriteEnabled["user-2"] = true
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepareWithConfigProvider(t, prepareConfig(t), bkt, cfgProvider)
+	c.bucketClient = bkt
+
+	doRequest := func(handler http.HandlerFunc, userID, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", bytes.NewReader([]byte(body)))
+		if userID != "" {
+			req = req.WithContext(user.InjectOrgID(req.Context(), userID))
+		}
+
+		resp := httptest.NewRecorder()
+		handler(resp, req)
+		return resp
+	}
+
+	listJobs := func(userID string) []*mimir_tsdb.BlockRewriteJob {
+		resp := doRequest(c.BlockRewriteJobs, userID, http.MethodGet, "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		res := BlockRewriteJobsResponse{}
+		require.NoError(t, yaml.Unmarshal(resp.Body.Bytes(), &res))
+		return res.Jobs
+	}
+
+	t.Run("should fail without tenant", func(t *testing.T) {
+		resp := doRequest(c.CreateBlockRewriteJob, "", http.MethodPost, "relabel_configs: [{action: labeldrop, regex: pod}]")
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("should fail if block rewrite is disabled for the tenant", func(t *testing.T) {
+		resp := doRequest(c.CreateBlockRewriteJob, "user-3", http.MethodPost, "relabel_configs: [{action: labeldrop, regex: pod}]")
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("should fail on invalid requests", func(t *testing.T) {
+		for name, body := range map[string]string{
+			"no relabel configs":    "",
+			"invalid yaml":          "relabel_configs: {",
+			"invalid action":        "relabel_configs: [{action: unknown}]",
+			"invalid regex":         "relabel_configs: [{action: labeldrop, regex: '('}]",
+			"missing target label":  "relabel_configs: [{action: replace, source_labels: [job]}]",
+			"empty relabel configs": "relabel_configs: []",
+		} {
+			t.Run(name, func(t *testing.T) {
+				resp := doRequest(c.CreateBlockRewriteJob, "user-1", http.MethodPost, body)
+				assert.Equal(t, http.StatusBadRequest, resp.Code)
+			})
+		}
+
+		assert.Empty(t, listJobs("user-1"))
+	})
+
+	t.Run("should create and list jobs", func(t *testing.T) {
+		resp := doRequest(c.CreateBlockRewriteJob, "user-1", http.MethodPost, `
+relabel_configs:
+  - source_labels: [__name__]
+    regex: old
+    target_label: __name__
+    replacement: new
+`)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		created := mimir_tsdb.BlockRewriteJob{}
+		require.NoError(t, yaml.Unmarshal(resp.Body.Bytes(), &created))
+		assert.Equal(t, mimir_tsdb.BlockRewriteJobPending, created.State)
+
+		jobs := listJobs("user-1")
+		require.Len(t, jobs, 1)
+		assert.Equal(t, created.ID, jobs[0].ID)
+		require.Len(t, jobs[0].RelabelConfigs, 1)
+		assert.Equal(t, "new", jobs[0].RelabelConfigs[0].Replacement)
+
+		assert.Empty(t, listJobs("user-2"))
+	})
+}
+
+func TestMultitenantCompactor_ProcessBlockRewriteJobs(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.blockRewriteEnabled[userID] = true
+
+	cfg := prepareConfig(t)
+	cfg.ConsistencyDelay = 0
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepareWithConfigProvider(t, cfg, bkt, cfgProvider)
+	c.bucketClient = bucketindex.BucketWithGlobalMarkers(bkt)
+	c.shardingStrategy = &mockShardingStrategy{owned: true}
+
+	// The first block contains the series series_id=0..4, while the second block contains series_id=0 only.
+	block1 := createTSDBBlock(t, bkt, userID, 0, 2*time.Hour.Milliseconds(), 5, map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: "1_of_2"})
+	block2 := createTSDBBlock(t, bkt, userID, 2*time.Hour.Milliseconds(), 4*time.Hour.Milliseconds(), 1, nil)
+
+	var relabelConfigs []*relabel.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- source_labels: [series_id]
+  regex: "1"
+  action: drop
+`), &relabelConfigs))
+
+	job, err := mimir_tsdb.NewBlockRewriteJob(relabelConfigs, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, mimir_tsdb.WriteBlockRewriteJob(ctx, bkt, userID, nil, job))
+
+	// Blocks with samples newer than the job should not be rewritten.
+	block3 := createTSDBBlock(t, bkt, userID, time.Now().Add(time.Hour).UnixMilli(), time.Now().Add(3*time.Hour).UnixMilli(), 5, nil)
+
+	// The first pass should rewrite the first block only.
+	require.NoError(t, c.processBlockRewriteJobs(ctx, userID))
+
+	assert.True(t, blockMarkedForDeletion(t, bkt, userID, block1))
+	assert.False(t, blockMarkedForDeletion(t, bkt, userID, block2))
+	assert.False(t, blockMarkedForDeletion(t, bkt, userID, block3))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.blockRewriteBlocksRewritten))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.blockRewriteBlocksMarkedForDeletion))
+
+	jobs, err := mimir_tsdb.ListBlockRewriteJobs(ctx, bkt, userID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, mimir_tsdb.BlockRewriteJobPending, jobs[0].State)
+	assert.Equal(t, 1, jobs[0].RewrittenBlocks)
+	require.Len(t, jobs[0].ProcessedBlocks, 2)
+	assert.True(t, jobs[0].IsBlockProcessed(block2))
+
+	var rewritten ulid.ULID
+	for _, id := range jobs[0].ProcessedBlocks {
+		if id != block2 {
+			rewritten = id
+		}
+	}
+
+	meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), objstore.NewPrefixedBucket(bkt, userID), rewritten)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), meta.Stats.NumSeries)
+	assert.Equal(t, int64(0), meta.MinTime)
+	assert.Empty(t, meta.Thanos.Labels)
+
+	// The bucket index should have been updated with the rewritten block.
+	idx, err := bucketindex.ReadIndex(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ulid.ULID{block1, block2, block3, rewritten}, idx.Blocks.GetULIDs())
+	assert.Equal(t, []ulid.ULID{block1}, idx.BlockDeletionMarks.GetULIDs())
+
+	// The second pass should not find any block left to rewrite, and mark the job as completed.
+	require.NoError(t, c.processBlockRewriteJobs(ctx, userID))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.blockRewriteBlocksRewritten))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.blockRewriteJobsCompleted))
+
+	jobs, err = mimir_tsdb.ListBlockRewriteJobs(ctx, bkt, userID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, mimir_tsdb.BlockRewriteJobCompleted, jobs[0].State)
+	assert.Equal(t, 1, jobs[0].RewrittenBlocks)
+	assert.Empty(t, jobs[0].ProcessedBlocks)
+}
+
+func TestMultitenantCompactor_ProcessBlockRewriteJobsShouldRewriteDownsampledBlocks(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.blockRewriteEnabled[userID] = true
+	cfgProvider.downsampling5mAfter[userID] = 24 * time.Hour
+
+	cfg := prepareConfig(t)
+	cfg.ConsistencyDelay = 0
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepareWithConfigProvider(t, cfg, bkt, cfgProvider)
+	c.bucketClient = bucketindex.BucketWithGlobalMarkers(bkt)
+	c.shardingStrategy = &mockShardingStrategy{owned: true}
+
+	now := time.Now()
+	raw := createTSDBBlock(t, bkt, userID, now.Add(-50*time.Hour).UnixMilli(), now.Add(-48*time.Hour).UnixMilli(), 5, nil)
+	require.NoError(t, c.downsampleUserBlocks(ctx, userID))
+
+	idx, err := bucketindex.ReadIndex(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, idx.Blocks, 2)
+
+	var downsampled ulid.ULID
+	for _, b := range idx.Blocks {
+		if b.Resolution == block.Resolution5m {
+			downsampled = b.ID
+		}
+	}
+
+	var relabelConfigs []*relabel.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- source_labels: [series_id]
+  regex: "1"
+  action: drop
+`), &relabelConfigs))
+
+	job, err := mimir_tsdb.NewBlockRewriteJob(relabelConfigs, now)
+	require.NoError(t, err)
+	require.NoError(t, mimir_tsdb.WriteBlockRewriteJob(ctx, bkt, userID, nil, job))
+
+	// Both the raw block and the downsampled block should be rewritten.
+	require.NoError(t, c.processBlockRewriteJobs(ctx, userID))
+
+	assert.True(t, blockMarkedForDeletion(t, bkt, userID, raw))
+	assert.True(t, blockMarkedForDeletion(t, bkt, userID, downsampled))
+	assert.Equal(t, 2.0, prom_testutil.ToFloat64(c.blockRewriteBlocksRewritten))
+
+	jobs, err := mimir_tsdb.ListBlockRewriteJobs(ctx, bkt, userID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Len(t, jobs[0].ProcessedBlocks, 2)
+
+	resolutions := map[int64]int{}
+	for _, id := range jobs[0].ProcessedBlocks {
+		meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), objstore.NewPrefixedBucket(bkt, userID), id)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(4), meta.Stats.NumSeries)
+		assert.Equal(t, []ulid.ULID{raw}, meta.Compaction.Sources)
+		resolutions[meta.Thanos.Downsample.Resolution]++
+	}
+	assert.Equal(t, map[int64]int{block.ResolutionRaw: 1, block.Resolution5m: 1}, resolutions)
+
+	// The rewritten raw block should not be downsampled again, because the rewritten downsampled block covers it.
+	require.NoError(t, c.downsampleUserBlocks(ctx, userID))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.downsamplingBlocksCreated))
+}
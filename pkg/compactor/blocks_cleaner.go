@@ -343,6 +343,10 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 		level.Info(userLogger).Log("msg", "deleted files under "+block.DebugMetas+" for tenant marked for deletion", "count", deleted)
 	}
 
+	if err := userBucket.Delete(ctx, mimir_tsdb.RetentionRulesStatusFilename); err != nil && !userBucket.IsObjNotFoundErr(err) {
+		return errors.Wrap(err, "failed to delete retention rules status")
+	}
+
 	// Tenant deletion mark file is inside Markers as well.
 	if deleted, err := bucket.DeletePrefix(ctx, userBucket, bucketindex.MarkersPathname, userLogger); err != nil {
 		return errors.Wrap(err, "failed to delete marker files")
@@ -382,8 +386,10 @@ func (c *BlocksCleaner) cleanUser(ctx context.Context, userID string) (returnErr
 	if idx != nil {
 		// We do not want to stop the remaining work in the cleaner if an
 		// error occurs here. Errors are logged in the function.
-		retention := c.cfgProvider.CompactorBlocksRetentionPeriod(userID)
-		c.applyUserRetentionPeriod(ctx, idx, retention, userBucket, userLogger)
+		for _, resolution := range []int64{block.ResolutionRaw, block.Resolution5m, block.Resolution1h} {
+			retention := c.cfgProvider.CompactorBlocksRetentionPeriodForResolution(userID, resolution)
+			c.applyUserRetentionPeriod(ctx, idx, resolution, retention, userBucket, userLogger)
+		}
 	}
 
 	// Generate an updated in-memory version of the bucket index.
@@ -528,19 +534,23 @@ func (c *BlocksCleaner) cleanUserPartialBlocks(ctx context.Context, partials map
 	}
 }
 
-// applyUserRetentionPeriod marks blocks for deletion which have aged past the retention period.
-func (c *BlocksCleaner) applyUserRetentionPeriod(ctx context.Context, idx *bucketindex.Index, retention time.Duration, userBucket objstore.Bucket, userLogger log.Logger) {
+// applyUserRetentionPeriod marks blocks with the given resolution for deletion which have aged past the retention period.
+func (c *BlocksCleaner) applyUserRetentionPeriod(ctx context.Context, idx *bucketindex.Index, resolution int64, retention time.Duration, userBucket objstore.Bucket, userLogger log.Logger) {
 	// The retention period of zero is a special value indicating to never delete.
 	if retention <= 0 {
 		return
 	}
 
-	level.Debug(userLogger).Log("msg", "applying retention", "retention", retention.String())
+	level.Debug(userLogger).Log("msg", "applying retention", "retention", retention.String(), "resolution", resolution)
 	blocks := listBlocksOutsideRetentionPeriod(idx, time.Now().Add(-retention))
 
 	// Attempt to mark all blocks. It is not critical if a marking fails, as
 	// the cleaner will retry applying the retention in its next cycle.
 	for _, b := range blocks {
+		if b.Resolution != resolution {
+			continue
+		}
+
 		level.Info(userLogger).Log("msg", "applied retention: marking block for deletion", "block", b.ID, "maxTime", b.MaxTime)
 		if err := block.MarkForDeletion(ctx, userLogger, userBucket, b.ID, fmt.Sprintf("block exceeding retention of %v", retention), c.blocksMarkedForDeletion); err != nil {
 			level.Warn(userLogger).Log("msg", "failed to mark block for deletion", "block", b.ID, "err", err)
@@ -6,6 +6,7 @@
 package compactor
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"errors"
@@ -689,6 +690,60 @@ func TestBlocksCleaner_ShouldRemoveBlocksOutsideRetentionPeriod(t *testing.T) {
 	}
 }
 
+func TestBlocksCleaner_ShouldApplyRetentionPeriodPerResolution(t *testing.T) {
+	bucketClient, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	bucketClient = bucketindex.BucketWithGlobalMarkers(bucketClient)
+
+	ts := func(hours int) int64 {
+		return time.Now().Add(time.Duration(hours)*time.Hour).Unix() * 1000
+	}
+
+	createBlockWithResolution := func(minT, maxT, resolution int64) ulid.ULID {
+		id := createTSDBBlock(t, bucketClient, "user-1", minT, maxT, 2, nil)
+		if resolution == block.ResolutionRaw {
+			return id
+		}
+
+		meta, err := block.DownloadMeta(context.Background(), log.NewNopLogger(), bucket.NewUserBucketClient("user-1", bucketClient, nil), id)
+		require.NoError(t, err)
+		meta.Thanos.Downsample.Resolution = resolution
+
+		var buf bytes.Buffer
+		require.NoError(t, meta.Write(&buf))
+		require.NoError(t, bucketClient.Upload(context.Background(), path.Join("user-1", id.String(), metadata.MetaFilename), &buf))
+		return id
+	}
+
+	raw := createBlockWithResolution(ts(-30), ts(-28), block.ResolutionRaw)
+	block5m := createBlockWithResolution(ts(-30), ts(-28), block.Resolution5m)
+	block1h := createBlockWithResolution(ts(-30), ts(-28), block.Resolution1h)
+
+	cfg := BlocksCleanerConfig{
+		DeletionDelay:           time.Hour,
+		CleanupInterval:         time.Minute,
+		CleanupConcurrency:      1,
+		DeleteBlocksConcurrency: 1,
+	}
+
+	ctx := context.Background()
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.userRetentionPeriods["user-1"] = 24 * time.Hour
+	cfgProvider.resolutionRetentionPeriods["user-1"] = map[int64]time.Duration{
+		block.Resolution1h: 48 * time.Hour,
+	}
+
+	cleaner := NewBlocksCleaner(cfg, bucketClient, tsdb.AllUsers, cfgProvider, test.NewTestingLogger(t), prometheus.NewPedanticRegistry())
+
+	// The retention is applied once the bucket index exists, so we run the cleanup twice.
+	require.NoError(t, cleaner.runCleanupWithErr(ctx))
+	require.NoError(t, cleaner.runCleanupWithErr(ctx))
+
+	// Blocks with a resolution without a specific retention period fall back to the default one.
+	checkBlock(t, "user-1", bucketClient, raw, true, true)
+	checkBlock(t, "user-1", bucketClient, block5m, true, true)
+	checkBlock(t, "user-1", bucketClient, block1h, true, false)
+}
+
 func checkBlock(t *testing.T, user string, bucketClient objstore.Bucket, block ulid.ULID, metaJSONExists bool, markedForDeletion bool) {
 	exists, err := bucketClient.Exists(context.Background(), path.Join(user, block.String(), metadata.MetaFilename))
 	require.NoError(t, err)
@@ -955,6 +1010,12 @@ type mockConfigProvider struct {
 	instancesShardSize           map[string]int
 	splitGroups                  map[string]int
 	blockUploadEnabled           map[string]bool
+	seriesDeletionEnabled        map[string]bool
+	blockRewriteEnabled          map[string]bool
+	downsampling5mAfter          map[string]time.Duration
+	downsampling1hAfter          map[string]time.Duration
+	resolutionRetentionPeriods   map[string]map[int64]time.Duration
+	retentionRules               map[string]map[string]time.Duration
 	userPartialBlockDelay        map[string]time.Duration
 	userPartialBlockDelayInvalid map[string]bool
 }
@@ -965,6 +1026,12 @@ func newMockConfigProvider() *mockConfigProvider {
 		splitAndMergeShards:          make(map[string]int),
 		splitGroups:                  make(map[string]int),
 		blockUploadEnabled:           make(map[string]bool),
+		seriesDeletionEnabled:        make(map[string]bool),
+		blockRewriteEnabled:          make(map[string]bool),
+		downsampling5mAfter:          make(map[string]time.Duration),
+		downsampling1hAfter:          make(map[string]time.Duration),
+		resolutionRetentionPeriods:   make(map[string]map[int64]time.Duration),
+		retentionRules:               make(map[string]map[string]time.Duration),
 		userPartialBlockDelay:        make(map[string]time.Duration),
 		userPartialBlockDelayInvalid: make(map[string]bool),
 	}
@@ -1002,6 +1069,33 @@ func (m *mockConfigProvider) CompactorBlockUploadEnabled(tenantID string) bool {
 	return m.blockUploadEnabled[tenantID]
 }
 
+func (m *mockConfigProvider) CompactorSeriesDeletionEnabled(tenantID string) bool {
+	return m.seriesDeletionEnabled[tenantID]
+}
+
+func (m *mockConfigProvider) CompactorBlockRewriteEnabled(tenantID string) bool {
+	return m.blockRewriteEnabled[tenantID]
+}
+
+func (m *mockConfigProvider) CompactorDownsampling5mAfter(user string) time.Duration {
+	return m.downsampling5mAfter[user]
+}
+
+func (m *mockConfigProvider) CompactorDownsampling1hAfter(user string) time.Duration {
+	return m.downsampling1hAfter[user]
+}
+
+func (m *mockConfigProvider) CompactorBlocksRetentionPeriodForResolution(user string, resolution int64) time.Duration {
+	if result, ok := m.resolutionRetentionPeriods[user][resolution]; ok {
+		return result
+	}
+	return m.CompactorBlocksRetentionPeriod(user)
+}
+
+func (m *mockConfigProvider) CompactorRetentionRules(user string) map[string]time.Duration {
+	return m.retentionRules[user]
+}
+
 func (m *mockConfigProvider) CompactorPartialBlockDeletionDelay(user string) (time.Duration, bool) {
 	return m.userPartialBlockDelay[user], !m.userPartialBlockDelayInvalid[user]
 }
@@ -94,6 +94,8 @@ type Config struct {
 	TenantCleanupDelay    time.Duration           `yaml:"tenant_cleanup_delay" category:"advanced"`
 	MaxCompactionTime     time.Duration           `yaml:"max_compaction_time" category:"advanced"`
 
+	SeriesDeletionCancelPeriod time.Duration `yaml:"series_deletion_cancel_period" category:"experimental"`
+
 	// Compactor concurrency options
 	MaxOpeningBlocksConcurrency int `yaml:"max_opening_blocks_concurrency" category:"advanced"` // Number of goroutines opening blocks before compaction.
 	MaxClosingBlocksConcurrency int `yaml:"max_closing_blocks_concurrency" category:"advanced"` // Max number of blocks that can be closed concurrently during split compaction. Note that closing of newly compacted block uses a lot of memory for writing index.
@@ -141,6 +143,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.DurationVar(&cfg.DeletionDelay, "compactor.deletion-delay", 12*time.Hour, "Time before a block marked for deletion is deleted from bucket. "+
 		"If not 0, blocks will be marked for deletion and compactor component will permanently delete blocks marked for deletion from the bucket. "+
 		"If 0, blocks will be deleted straight away. Note that deleting blocks immediately can cause query failures.")
+	f.DurationVar(&cfg.SeriesDeletionCancelPeriod, "compactor.series-deletion-cancel-period", 24*time.Hour, "Period during which a series deletion request can be cancelled. Once the period expires, the compactor removes the matching series from the blocks. The period should be longer than -querier.query-ingesters-within, so that the deleted series are not queried from ingesters anymore.")
 	f.DurationVar(&cfg.TenantCleanupDelay, "compactor.tenant-cleanup-delay", 6*time.Hour, "For tenants marked for deletion, this is time between deleting of last block, and doing final cleanup (marker files, debug files) of the tenant.")
 	// compactor concurrency options
 	f.IntVar(&cfg.MaxOpeningBlocksConcurrency, "compactor.max-opening-blocks-concurrency", 1, "Number of goroutines opening blocks before compaction.")
@@ -200,6 +203,24 @@ type ConfigProvider interface {
 
 	// CompactorBlockUploadEnabled returns whether block upload is enabled for a given tenant.
 	CompactorBlockUploadEnabled(tenantID string) bool
+
+	// CompactorSeriesDeletionEnabled returns whether series deletion is enabled for a given tenant.
+	CompactorSeriesDeletionEnabled(tenantID string) bool
+
+	// CompactorBlockRewriteEnabled returns whether block rewrite is enabled for a given tenant.
+	CompactorBlockRewriteEnabled(tenantID string) bool
+
+	// CompactorDownsampling5mAfter returns the age after which raw blocks are downsampled to 5m resolution. 0 = disabled.
+	CompactorDownsampling5mAfter(userID string) time.Duration
+
+	// CompactorDownsampling1hAfter returns the age after which 5m resolution blocks are downsampled to 1h resolution. 0 = disabled.
+	CompactorDownsampling1hAfter(userID string) time.Duration
+
+	// CompactorBlocksRetentionPeriodForResolution returns the retention period of the blocks with the given resolution.
+	CompactorBlocksRetentionPeriodForResolution(userID string, resolution int64) time.Duration
+
+	// CompactorRetentionRules returns the retention periods of the series matching the given selectors, keyed by series selector.
+	CompactorRetentionRules(userID string) map[string]time.Duration
 }
 
 // MultitenantCompactor is a multi-tenant TSDB blocks compactor based on Thanos.
@@ -251,6 +272,27 @@ type MultitenantCompactor struct {
 	compactionRunInterval          prometheus.Gauge
 	blocksMarkedForDeletion        prometheus.Counter
 
+	// Metrics tracking the processing of series deletion requests.
+	seriesDeletionBlocksMarkedForDeletion prometheus.Counter
+	seriesDeletionBlocksRewritten         prometheus.Counter
+	seriesDeletionRequestsProcessed       prometheus.Counter
+	seriesDeletionFailures                prometheus.Counter
+
+	// Metrics tracking the processing of block rewrite jobs.
+	blockRewriteBlocksMarkedForDeletion prometheus.Counter
+	blockRewriteBlocksRewritten         prometheus.Counter
+	blockRewriteJobsCompleted           prometheus.Counter
+	blockRewriteFailures                prometheus.Counter
+
+	// Metrics tracking the downsampling of blocks.
+	downsamplingBlocksCreated prometheus.Counter
+	downsamplingFailures      prometheus.Counter
+
+	// Metrics tracking the retention rules.
+	retentionRulesBlocksMarkedForDeletion prometheus.Counter
+	retentionRulesBlocksRewritten         prometheus.Counter
+	retentionRulesFailures                prometheus.Counter
+
 	// Metrics shared across all BucketCompactor instances.
 	bucketCompactorMetrics *BucketCompactorMetrics
 
@@ -351,6 +393,61 @@ func newMultitenantCompactor(
 			Help:        blocksMarkedForDeletionHelp,
 			ConstLabels: prometheus.Labels{"reason": "compaction"},
 		}),
+		seriesDeletionBlocksMarkedForDeletion: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name:        blocksMarkedForDeletionName,
+			Help:        blocksMarkedForDeletionHelp,
+			ConstLabels: prometheus.Labels{"reason": "series-deletion"},
+		}),
+		seriesDeletionBlocksRewritten: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_series_deletion_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten to remove the series matching a deletion request.",
+		}),
+		seriesDeletionRequestsProcessed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_series_deletion_requests_processed_total",
+			Help: "Total number of series deletion requests whose series have been removed from all blocks.",
+		}),
+		seriesDeletionFailures: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_series_deletion_failures_total",
+			Help: "Total number of failures while processing series deletion requests.",
+		}),
+		blockRewriteBlocksMarkedForDeletion: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name:        blocksMarkedForDeletionName,
+			Help:        blocksMarkedForDeletionHelp,
+			ConstLabels: prometheus.Labels{"reason": "block-rewrite"},
+		}),
+		blockRewriteBlocksRewritten: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_block_rewrite_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten by block rewrite jobs.",
+		}),
+		blockRewriteJobsCompleted: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_block_rewrite_jobs_completed_total",
+			Help: "Total number of block rewrite jobs which have been applied to all blocks.",
+		}),
+		blockRewriteFailures: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_block_rewrite_failures_total",
+			Help: "Total number of failures while processing block rewrite jobs.",
+		}),
+		downsamplingBlocksCreated: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_downsampling_blocks_created_total",
+			Help: "Total number of downsampled blocks created.",
+		}),
+		downsamplingFailures: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_downsampling_failures_total",
+			Help: "Total number of failures while downsampling blocks.",
+		}),
+		retentionRulesBlocksMarkedForDeletion: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name:        blocksMarkedForDeletionName,
+			Help:        blocksMarkedForDeletionHelp,
+			ConstLabels: prometheus.Labels{"reason": "retention-rules"},
+		}),
+		retentionRulesBlocksRewritten: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_retention_rules_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten to remove the series older than the retention period of a matching retention rule.",
+		}),
+		retentionRulesFailures: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_retention_rules_failures_total",
+			Help: "Total number of failures while applying retention rules.",
+		}),
 	}
 
 	c.bucketCompactorMetrics = NewBucketCompactorMetrics(c.blocksMarkedForDeletion, registerer)
@@ -616,6 +713,46 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 
 		c.compactionRunSucceededTenants.Inc()
 		level.Info(c.logger).Log("msg", "successfully compacted user blocks", "user", userID)
+
+		if err := c.processSeriesDeletionRequests(ctx, userID); err != nil {
+			if errors.Is(err, context.Canceled) {
+				level.Info(c.logger).Log("msg", "processing of series deletion requests was interrupted by a shutdown", "user", userID)
+				return
+			}
+
+			c.seriesDeletionFailures.Inc()
+			level.Error(c.logger).Log("msg", "failed to process series deletion requests", "user", userID, "err", err)
+		}
+
+		if err := c.processBlockRewriteJobs(ctx, userID); err != nil {
+			if errors.Is(err, context.Canceled) {
+				level.Info(c.logger).Log("msg", "processing of block rewrite jobs was interrupted by a shutdown", "user", userID)
+				return
+			}
+
+			c.blockRewriteFailures.Inc()
+			level.Error(c.logger).Log("msg", "failed to process block rewrite jobs", "user", userID, "err", err)
+		}
+
+		if err := c.downsampleUserBlocks(ctx, userID); err != nil {
+			if errors.Is(err, context.Canceled) {
+				level.Info(c.logger).Log("msg", "downsampling of blocks was interrupted by a shutdown", "user", userID)
+				return
+			}
+
+			c.downsamplingFailures.Inc()
+			level.Error(c.logger).Log("msg", "failed to downsample blocks", "user", userID, "err", err)
+		}
+
+		if err := c.applyRetentionRules(ctx, userID); err != nil {
+			if errors.Is(err, context.Canceled) {
+				level.Info(c.logger).Log("msg", "applying retention rules was interrupted by a shutdown", "user", userID)
+				return
+			}
+
+			c.retentionRulesFailures.Inc()
+			level.Error(c.logger).Log("msg", "failed to apply retention rules", "user", userID, "err", err)
+		}
 	}
 
 	// Delete local files for unowned tenants, if there are any. This cleans up
@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// downsampleUserBlocks downsamples the blocks of the tenant whose samples are older than the per-tenant thresholds:
+// raw blocks are downsampled to 5m resolution, and 5m resolution blocks are downsampled to 1h resolution.
+//
+// The original blocks are kept, so that they can be queried at their resolution until they reach their retention
+// period. A block is not downsampled again if all its source blocks have already been downsampled, which is the
+// case for blocks compacted from already downsampled blocks.
+func (c *MultitenantCompactor) downsampleUserBlocks(ctx context.Context, userID string) error {
+	after5m := c.cfgProvider.CompactorDownsampling5mAfter(userID)
+	if after5m <= 0 {
+		return nil
+	}
+
+	// Only one compactor downsamples the blocks of a tenant, so that blocks don't get downsampled twice.
+	if owned, err := c.shardingStrategy.blocksCleanerOwnUser(userID); err != nil || !owned {
+		return err
+	}
+
+	logger := util_log.WithUserID(userID, c.logger)
+	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
+
+	metas, err := c.fetchRewritableBlockMetas(ctx, logger, userBucket, userID)
+	if err != nil {
+		return err
+	}
+
+	workDir := filepath.Join(c.compactorCfg.DataDir, "downsample", userID)
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove downsampling work directory", "path", workDir, "err", err)
+		}
+	}()
+
+	passes := []struct {
+		from, to int64
+		after    time.Duration
+	}{
+		{from: block.ResolutionRaw, to: block.Resolution5m, after: after5m},
+		{from: block.Resolution5m, to: block.Resolution1h, after: c.cfgProvider.CompactorDownsampling1hAfter(userID)},
+	}
+
+	created := 0
+	for _, p := range passes {
+		if p.after <= 0 {
+			continue
+		}
+
+		// Blocks created by a pass are taken into account by the next one.
+		newMetas, err := c.downsampleBlocks(ctx, logger, userBucket, metas, p.from, p.to, time.Now().Add(-p.after), workDir)
+		for _, m := range newMetas {
+			metas[m.ULID] = m
+		}
+		created += len(newMetas)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if created == 0 {
+		return nil
+	}
+
+	// Update the bucket index right away, so that the downsampled blocks are queried without waiting
+	// for the next blocks cleanup.
+	return c.updateBucketIndex(ctx, logger, userID)
+}
+
+// downsampleBlocks downsamples the blocks with the from resolution and samples older than threshold to the to
+// resolution, unless they have already been downsampled. Returns the metas of the created blocks.
+func (c *MultitenantCompactor) downsampleBlocks(ctx context.Context, logger log.Logger, userBucket objstore.Bucket, metas map[ulid.ULID]*metadata.Meta, from, to int64, threshold time.Time, workDir string) ([]*metadata.Meta, error) {
+	// Source blocks which have already been downsampled to the target resolution.
+	downsampled := map[ulid.ULID]struct{}{}
+	for _, m := range metas {
+		if m.Thanos.Downsample.Resolution != to {
+			continue
+		}
+		for _, id := range m.Compaction.Sources {
+			downsampled[id] = struct{}{}
+		}
+	}
+
+	var candidates []*metadata.Meta
+	for _, m := range metas {
+		if m.Thanos.Downsample.Resolution != from || m.MaxTime > threshold.UnixMilli() {
+			continue
+		}
+
+		missing := false
+		for _, id := range m.Compaction.Sources {
+			if _, ok := downsampled[id]; !ok {
+				missing = true
+				break
+			}
+		}
+		if missing {
+			candidates = append(candidates, m)
+		}
+	}
+
+	// Downsample the oldest blocks first.
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].MinTime < candidates[j].MinTime
+	})
+
+	var created []*metadata.Meta
+	for _, m := range candidates {
+		if ctx.Err() != nil {
+			return created, ctx.Err()
+		}
+
+		blockLogger := log.With(logger, "block", m.ULID, "resolution", to)
+		newMeta, err := c.downsampleBlock(ctx, blockLogger, userBucket, m, to, filepath.Join(workDir, m.ULID.String()))
+		if err != nil {
+			return created, errors.Wrapf(err, "downsample block %s", m.ULID)
+		}
+
+		created = append(created, newMeta)
+	}
+
+	return created, nil
+}
+
+// downsampleBlock downloads the block, and uploads a new block with the samples downsampled to the given resolution.
+func (c *MultitenantCompactor) downsampleBlock(ctx context.Context, logger log.Logger, userBucket objstore.Bucket, meta *metadata.Meta, resolution int64, dir string) (*metadata.Meta, error) {
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove downsampling block directory", "path", dir, "err", err)
+		}
+	}()
+
+	begin := time.Now()
+
+	if err := block.Download(ctx, logger, userBucket, meta.ULID, filepath.Join(dir, meta.ULID.String())); err != nil {
+		return nil, errors.Wrap(err, "download block")
+	}
+
+	newID, err := block.Downsample(logger, dir, meta.ULID, resolution)
+	if err != nil {
+		return nil, errors.Wrap(err, "downsample block")
+	}
+
+	newDir := filepath.Join(dir, newID.String())
+	newMeta, err := metadata.ReadFromDir(newDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "read downsampled block meta")
+	}
+
+	if err := block.VerifyIndex(logger, filepath.Join(newDir, block.IndexFilename), newMeta.MinTime, newMeta.MaxTime); err != nil {
+		return nil, errors.Wrapf(err, "invalid result block %s", newDir)
+	}
+
+	if err := block.Upload(ctx, logger, userBucket, newDir, nil); err != nil {
+		return nil, errors.Wrapf(err, "upload of %s failed", newID)
+	}
+
+	c.downsamplingBlocksCreated.Inc()
+	level.Info(logger).Log("msg", "uploaded downsampled block", "result_block", newID, "duration", time.Since(begin))
+
+	return newMeta, nil
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func TestMultitenantCompactor_DownsampleUserBlocks(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.downsampling5mAfter[userID] = 24 * time.Hour
+	cfgProvider.downsampling1hAfter[userID] = 72 * time.Hour
+
+	cfg := prepareConfig(t)
+	cfg.ConsistencyDelay = 0
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepareWithConfigProvider(t, cfg, bkt, cfgProvider)
+	c.bucketClient = bucketindex.BucketWithGlobalMarkers(bkt)
+	c.shardingStrategy = &mockShardingStrategy{owned: true}
+
+	now := time.Now()
+	oldest := createTSDBBlock(t, bkt, userID, now.Add(-100*time.Hour).UnixMilli(), now.Add(-98*time.Hour).UnixMilli(), 5, nil)
+	old := createTSDBBlock(t, bkt, userID, now.Add(-50*time.Hour).UnixMilli(), now.Add(-48*time.Hour).UnixMilli(), 5, nil)
+	recent := createTSDBBlock(t, bkt, userID, now.Add(-4*time.Hour).UnixMilli(), now.Add(-2*time.Hour).UnixMilli(), 5, nil)
+
+	// The first pass should downsample both old blocks to 5m, and the oldest one to 1h too.
+	require.NoError(t, c.downsampleUserBlocks(ctx, userID))
+	assert.Equal(t, 3.0, prom_testutil.ToFloat64(c.downsamplingBlocksCreated))
+
+	idx, err := bucketindex.ReadIndex(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, idx.Blocks, 6)
+	assert.Empty(t, idx.BlockDeletionMarks)
+
+	byResolution := map[int64][]ulid.ULID{}
+	for _, b := range idx.Blocks {
+		byResolution[b.Resolution] = append(byResolution[b.Resolution], b.ID)
+	}
+	assert.ElementsMatch(t, []ulid.ULID{oldest, old, recent}, byResolution[block.ResolutionRaw])
+	assert.Len(t, byResolution[block.Resolution5m], 2)
+	assert.Len(t, byResolution[block.Resolution1h], 1)
+
+	for _, id := range append(byResolution[block.Resolution5m], byResolution[block.Resolution1h]...) {
+		meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), objstore.NewPrefixedBucket(bkt, userID), id)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), meta.Stats.NumSeries)
+		assert.Less(t, meta.MaxTime, now.Add(-24*time.Hour).UnixMilli())
+
+		// Downsampled blocks keep the sources of the original block.
+		require.Len(t, meta.Compaction.Sources, 1)
+		assert.Contains(t, []ulid.ULID{oldest, old}, meta.Compaction.Sources[0])
+	}
+
+	// The second pass should not downsample blocks again.
+	require.NoError(t, c.downsampleUserBlocks(ctx, userID))
+	assert.Equal(t, 3.0, prom_testutil.ToFloat64(c.downsamplingBlocksCreated))
+
+	// Blocks of tenants without downsampling shouldn't be downsampled.
+	createTSDBBlock(t, bkt, "user-2", now.Add(-100*time.Hour).UnixMilli(), now.Add(-98*time.Hour).UnixMilli(), 5, nil)
+	require.NoError(t, c.downsampleUserBlocks(ctx, "user-2"))
+	assert.Equal(t, 3.0, prom_testutil.ToFloat64(c.downsamplingBlocksCreated))
+}
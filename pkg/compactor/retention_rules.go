@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// applyRetentionRules removes the series matching the selector of a retention rule from the blocks whose samples
+// are all older than the retention period of the rule.
+//
+// Each block past the retention period of a rule is checked by downloading its index only. Blocks containing
+// matching series are then fully downloaded and rewritten without them, and the original blocks are marked for
+// deletion. Blocks which don't contain matching series, and rewritten blocks, are tracked in the retention rules
+// status of the tenant, so that they're not checked again for the same rule.
+func (c *MultitenantCompactor) applyRetentionRules(ctx context.Context, userID string) error {
+	rules := c.cfgProvider.CompactorRetentionRules(userID)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	// Only one compactor applies the retention rules of a tenant, so that blocks don't get rewritten twice.
+	if owned, err := c.shardingStrategy.blocksCleanerOwnUser(userID); err != nil || !owned {
+		return err
+	}
+
+	logger := util_log.WithUserID(userID, c.logger)
+	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
+
+	metas, err := c.fetchRewritableBlockMetas(ctx, logger, userBucket, userID)
+	if err != nil {
+		return err
+	}
+
+	status, err := mimir_tsdb.ReadRetentionRulesStatus(ctx, c.bucketClient, userID)
+	if err != nil {
+		return err
+	}
+
+	// Forget about removed rules and deleted blocks, so that the status doesn't grow indefinitely.
+	statusChanged := status.Retain(func(selector string) bool {
+		_, ok := rules[selector]
+		return ok
+	}, func(id ulid.ULID) bool {
+		_, ok := metas[id]
+		return ok
+	})
+
+	workDir := filepath.Join(c.compactorCfg.DataDir, "retention-rules", userID)
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove retention rules work directory", "path", workDir, "err", err)
+		}
+	}()
+
+	selectors := make([]string, 0, len(rules))
+	for selector := range rules {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	now := time.Now()
+	rewritten := 0
+
+	for _, meta := range metas {
+		var toApply []string
+		for _, selector := range selectors {
+			if meta.MaxTime <= now.Add(-rules[selector]).UnixMilli() && !status.IsBlockClean(selector, meta.ULID) {
+				toApply = append(toApply, selector)
+			}
+		}
+		if len(toApply) == 0 {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// The whole time range of the block is past the retention period of the rules to apply.
+		t, err := mimir_tsdb.NewTombstone(meta.MinTime, meta.MaxTime, toApply, now)
+		if err != nil {
+			return errors.Wrapf(err, "create tombstone for block %s", meta.ULID)
+		}
+
+		blockLogger := log.With(logger, "block", meta.ULID)
+		newID, ok, err := c.rewriteBlockWithTombstones(ctx, blockLogger, userBucket, meta, []*mimir_tsdb.Tombstone{t}, filepath.Join(workDir, meta.ULID.String()), "retention rules", c.retentionRulesBlocksRewritten, c.retentionRulesBlocksMarkedForDeletion)
+		if err != nil {
+			return errors.Wrapf(err, "rewrite block %s", meta.ULID)
+		}
+
+		if ok {
+			rewritten++
+			if newID != (ulid.ULID{}) {
+				// Rewriting a block only removes series, so the new block is clean for the same rules as the original one.
+				for _, selector := range selectors {
+					if status.IsBlockClean(selector, meta.ULID) {
+						status.AddCleanBlock(selector, newID)
+					}
+				}
+				for _, selector := range toApply {
+					status.AddCleanBlock(selector, newID)
+				}
+			}
+		} else {
+			for _, selector := range toApply {
+				status.AddCleanBlock(selector, meta.ULID)
+			}
+		}
+
+		if err := mimir_tsdb.WriteRetentionRulesStatus(ctx, c.bucketClient, userID, c.cfgProvider, status); err != nil {
+			return err
+		}
+		statusChanged = false
+	}
+
+	if statusChanged {
+		if err := mimir_tsdb.WriteRetentionRulesStatus(ctx, c.bucketClient, userID, c.cfgProvider, status); err != nil {
+			return err
+		}
+	}
+
+	if rewritten == 0 {
+		return nil
+	}
+
+	// Update the bucket index right away, so that queriers stop querying the original blocks without waiting
+	// for the next blocks cleanup.
+	return c.updateBucketIndex(ctx, logger, userID)
+}
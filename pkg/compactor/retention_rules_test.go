@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func TestMultitenantCompactor_ApplyRetentionRules(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.retentionRules[userID] = map[string]time.Duration{
+		`{series_id=~"0|1"}`: 7 * 24 * time.Hour,
+		`{series_id="3"}`:    30 * 24 * time.Hour,
+		`{series_id="100"}`:  24 * time.Hour,
+	}
+
+	cfg := prepareConfig(t)
+	cfg.ConsistencyDelay = 0
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepareWithConfigProvider(t, cfg, bkt, cfgProvider)
+	c.bucketClient = bucketindex.BucketWithGlobalMarkers(bkt)
+
+	blocksCompactor, err := tsdb.NewLeveledCompactor(ctx, nil, log.NewNopLogger(), []int64{2 * time.Hour.Milliseconds()}, nil, nil, true)
+	require.NoError(t, err)
+	c.blocksCompactor = blocksCompactor
+	c.shardingStrategy = &mockShardingStrategy{owned: true}
+
+	now := time.Now()
+	old := createTSDBBlock(t, bkt, userID, now.Add(-10*24*time.Hour).UnixMilli(), now.Add(-10*24*time.Hour+2*time.Hour).UnixMilli(), 5, nil)
+	recent := createTSDBBlock(t, bkt, userID, now.Add(-4*time.Hour).UnixMilli(), now.Add(-2*time.Hour).UnixMilli(), 5, nil)
+
+	// The old block is past the retention period of the first rule only.
+	require.NoError(t, c.applyRetentionRules(ctx, userID))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.retentionRulesBlocksRewritten))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.retentionRulesBlocksMarkedForDeletion))
+	assert.True(t, blockMarkedForDeletion(t, bkt, userID, old))
+	assert.False(t, blockMarkedForDeletion(t, bkt, userID, recent))
+
+	idx, err := bucketindex.ReadIndex(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, idx.Blocks, 3)
+
+	var newID ulid.ULID
+	for _, b := range idx.Blocks {
+		if b.ID != old && b.ID != recent {
+			newID = b.ID
+		}
+	}
+	require.NotZero(t, newID)
+
+	meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), objstore.NewPrefixedBucket(bkt, userID), newID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), meta.Stats.NumSeries)
+
+	status, err := mimir_tsdb.ReadRetentionRulesStatus(ctx, bkt, userID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]ulid.ULID{
+		`{series_id=~"0|1"}`: {newID},
+		`{series_id="100"}`:  {newID},
+	}, status.CleanBlocks)
+
+	// The second pass should not rewrite blocks again.
+	require.NoError(t, c.applyRetentionRules(ctx, userID))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.retentionRulesBlocksRewritten))
+
+	// Blocks of tenants without retention rules shouldn't be rewritten.
+	createTSDBBlock(t, bkt, "user-2", now.Add(-10*24*time.Hour).UnixMilli(), now.Add(-10*24*time.Hour+2*time.Hour).UnixMilli(), 5, nil)
+	require.NoError(t, c.applyRetentionRules(ctx, "user-2"))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.retentionRulesBlocksRewritten))
+}
@@ -0,0 +1,297 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	"github.com/grafana/mimir/pkg/util"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// processSeriesDeletionRequests removes the series matching the pending deletion requests of the tenant from
+// the blocks, once the cancellation period of the requests has expired.
+//
+// Each block overlapping a request is checked by downloading its index only. Blocks containing matching series
+// are then fully downloaded and rewritten without them, and the original blocks are marked for deletion. Blocks
+// which don't contain matching series, and rewritten blocks, are tracked in the request, so that they're not
+// checked again. A request is marked as processed once a pass doesn't find any block left to rewrite: this
+// guarantees that blocks compacted concurrently from not yet rewritten blocks are checked too. A request is
+// not marked as processed before the ingesters stopped shipping, and being queried for, the samples in its
+// time range either, so that the blocks uploaded by the ingesters afterwards get rewritten too.
+func (c *MultitenantCompactor) processSeriesDeletionRequests(ctx context.Context, userID string) error {
+	if !c.cfgProvider.CompactorSeriesDeletionEnabled(userID) {
+		return nil
+	}
+
+	// Only one compactor processes the requests of a tenant, so that blocks don't get rewritten twice.
+	if owned, err := c.shardingStrategy.blocksCleanerOwnUser(userID); err != nil || !owned {
+		return err
+	}
+
+	tombstones, err := mimir_tsdb.ListTombstones(ctx, c.bucketClient, userID)
+	if err != nil {
+		return err
+	}
+
+	logger := util_log.WithUserID(userID, c.logger)
+	now := time.Now()
+
+	var pending []*mimir_tsdb.Tombstone
+	for _, t := range tombstones {
+		switch {
+		case t.State == mimir_tsdb.TombstonePending:
+			if !c.canCancelTombstone(t, now) {
+				pending = append(pending, t)
+			}
+
+		case now.Sub(time.Unix(t.StateChangeTime, 0)) > c.compactorCfg.DeletionDelay:
+			// Processed requests are kept until the blocks they replaced have been deleted, because queriers
+			// keep filtering out the deleted series in the meanwhile.
+			if err := mimir_tsdb.DeleteTombstone(ctx, c.bucketClient, userID, c.cfgProvider, t.RequestID); err != nil {
+				return err
+			}
+			level.Info(logger).Log("msg", "deleted series deletion request", "request_id", t.RequestID, "state", t.State)
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
+	metas, err := c.fetchRewritableBlockMetas(ctx, logger, userBucket, userID)
+	if err != nil {
+		return err
+	}
+
+	workDir := filepath.Join(c.compactorCfg.DataDir, "series-deletion", userID)
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove series deletion work directory", "path", workDir, "err", err)
+		}
+	}()
+
+	// Keep track of the requests which needed a block to be rewritten in this pass.
+	rewritten := map[string]bool{}
+
+	for _, meta := range metas {
+		var toApply []*mimir_tsdb.Tombstone
+		for _, t := range pending {
+			if t.Overlaps(meta.MinTime, meta.MaxTime-1) && !t.IsBlockClean(meta.ULID) {
+				toApply = append(toApply, t)
+			}
+		}
+		if len(toApply) == 0 {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		blockLogger := log.With(logger, "block", meta.ULID)
+		newID, ok, err := c.rewriteBlockWithTombstones(ctx, blockLogger, userBucket, meta, toApply, filepath.Join(workDir, meta.ULID.String()), "series deletion", c.seriesDeletionBlocksRewritten, c.seriesDeletionBlocksMarkedForDeletion)
+		if err != nil {
+			return errors.Wrapf(err, "rewrite block %s", meta.ULID)
+		}
+
+		for _, t := range toApply {
+			if ok {
+				rewritten[t.RequestID] = true
+				if newID != (ulid.ULID{}) {
+					t.CleanBlocks = append(t.CleanBlocks, newID)
+				}
+			} else {
+				t.CleanBlocks = append(t.CleanBlocks, meta.ULID)
+			}
+
+			if err := mimir_tsdb.WriteTombstone(ctx, c.bucketClient, userID, c.cfgProvider, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	processed := false
+	for _, t := range pending {
+		if rewritten[t.RequestID] || now.Sub(util.TimeFromMillis(t.EndTime)) <= c.ingestersWindow() {
+			continue
+		}
+
+		t.State = mimir_tsdb.TombstoneProcessed
+		t.StateChangeTime = time.Now().Unix()
+		t.CleanBlocks = nil
+		if err := mimir_tsdb.WriteTombstone(ctx, c.bucketClient, userID, c.cfgProvider, t); err != nil {
+			return err
+		}
+
+		c.seriesDeletionRequestsProcessed.Inc()
+		level.Info(logger).Log("msg", "series deletion request processed", "request_id", t.RequestID)
+		processed = true
+	}
+
+	if processed {
+		return mimir_tsdb.BumpResultsCacheGen(ctx, c.bucketClient, userID, c.cfgProvider, time.Now())
+	}
+	return nil
+}
+
+// ingestersWindow returns how long after their timestamp samples may still be uploaded by the ingesters, and
+// queried from them. The ingesters compact their head into a block once it spans 1.5 times the largest block
+// range, ship the block at the next ship interval, and keep it until the retention period expires.
+func (c *MultitenantCompactor) ingestersWindow() time.Duration {
+	tsdbCfg := c.storageCfg.TSDB
+
+	var maxBlockRange time.Duration
+	for _, r := range tsdbCfg.BlockRanges {
+		if r > maxBlockRange {
+			maxBlockRange = r
+		}
+	}
+
+	return maxBlockRange*3/2 + tsdbCfg.HeadCompactionInterval + tsdbCfg.ShipInterval + tsdbCfg.Retention
+}
+
+func (c *MultitenantCompactor) fetchRewritableBlockMetas(ctx context.Context, logger log.Logger, userBucket objstore.InstrumentedBucket, userID string) (map[ulid.ULID]*metadata.Meta, error) {
+	fetcher, err := block.NewMetaFetcher(
+		logger,
+		c.compactorCfg.MetaSyncConcurrency,
+		userBucket,
+		c.metaSyncDirForUser(userID),
+		nil,
+		[]block.MetadataFilter{
+			block.NewConsistencyDelayMetaFilter(logger, c.compactorCfg.ConsistencyDelay, nil),
+			NewExcludeMarkedForDeletionFilter(userBucket),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	metas, _, err := fetcher.Fetch(ctx)
+	return metas, errors.Wrap(err, "fetch blocks metadata")
+}
+
+// rewriteBlockWithTombstones rewrites the block without the series matching the input tombstones, and marks
+// the original block for deletion. Returns false if the block doesn't contain matching series, and hasn't been
+// rewritten. The returned block ID is zero if all the samples of the block have been deleted. The reason is
+// used in logs and in the deletion mark of the original block.
+func (c *MultitenantCompactor) rewriteBlockWithTombstones(ctx context.Context, logger log.Logger, userBucket objstore.Bucket, meta *metadata.Meta, tombstones []*mimir_tsdb.Tombstone, dir, reason string, blocksRewritten, blocksMarkedForDeletion prometheus.Counter) (ulid.ULID, bool, error) {
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove rewritten block directory", "path", dir, "err", err)
+		}
+	}()
+
+	bdir := filepath.Join(dir, meta.ULID.String())
+	if err := os.MkdirAll(filepath.Join(bdir, block.ChunksDirname), 0750); err != nil {
+		return ulid.ULID{}, false, errors.Wrap(err, "create block dir")
+	}
+
+	// The index is enough to find out whether the block contains series matching the tombstones.
+	for _, name := range []string{block.MetaFilename, block.IndexFilename} {
+		if err := objstore.DownloadFile(ctx, logger, userBucket, path.Join(meta.ULID.String(), name), filepath.Join(bdir, name)); err != nil {
+			return ulid.ULID{}, false, err
+		}
+	}
+
+	numTombstones, err := writeBlockTombstones(logger, bdir, tombstones)
+	if err != nil {
+		return ulid.ULID{}, false, err
+	}
+	if numTombstones == 0 {
+		level.Debug(logger).Log("msg", "block doesn't contain series matching the tombstones", "reason", reason)
+		return ulid.ULID{}, false, nil
+	}
+
+	level.Info(logger).Log("msg", "block contains series matching the tombstones; rewriting block", "reason", reason, "tombstones", numTombstones)
+
+	chunksDir := path.Join(meta.ULID.String(), block.ChunksDirname)
+	if err := objstore.DownloadDir(ctx, logger, userBucket, chunksDir, chunksDir, filepath.Join(bdir, block.ChunksDirname)); err != nil {
+		return ulid.ULID{}, false, errors.Wrap(err, "download chunks")
+	}
+
+	// The compaction of a single block drops the samples covered by its tombstones.
+	newID, err := c.blocksCompactor.Compact(dir, []string{bdir}, nil)
+	if err != nil {
+		return ulid.ULID{}, false, errors.Wrap(err, "compact block")
+	}
+
+	if newID != (ulid.ULID{}) {
+		newDir := filepath.Join(dir, newID.String())
+		newMeta, err := metadata.InjectThanos(logger, newDir, metadata.Thanos{
+			Labels:       meta.Thanos.Labels,
+			Downsample:   meta.Thanos.Downsample,
+			Source:       metadata.CompactorSource,
+			SegmentFiles: block.GetSegmentFiles(newDir),
+		}, nil)
+		if err != nil {
+			return ulid.ULID{}, false, errors.Wrapf(err, "failed to finalize the block %s", newDir)
+		}
+
+		if err = os.Remove(filepath.Join(newDir, "tombstones")); err != nil {
+			return ulid.ULID{}, false, errors.Wrap(err, "remove tombstones")
+		}
+
+		if err := block.VerifyIndex(logger, filepath.Join(newDir, block.IndexFilename), newMeta.MinTime, newMeta.MaxTime); err != nil {
+			return ulid.ULID{}, false, errors.Wrapf(err, "invalid result block %s", newDir)
+		}
+
+		if err := block.Upload(ctx, logger, userBucket, newDir, nil); err != nil {
+			return ulid.ULID{}, false, errors.Wrapf(err, "upload of %s failed", newID)
+		}
+
+		level.Info(logger).Log("msg", "uploaded block rewritten without deleted series", "reason", reason, "result_block", newID)
+	} else {
+		level.Info(logger).Log("msg", "all samples of the block have been deleted", "reason", reason)
+	}
+
+	blocksRewritten.Inc()
+
+	// Spawn a new context so we always mark a block for deletion in full on shutdown.
+	delCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := block.MarkForDeletion(delCtx, logger, userBucket, meta.ULID, "source of block rewritten by "+reason, blocksMarkedForDeletion); err != nil {
+		return ulid.ULID{}, false, errors.Wrapf(err, "mark block %s for deletion", meta.ULID)
+	}
+
+	return newID, true, nil
+}
+
+// writeBlockTombstones writes the TSDB tombstones file of the block in the input directory, covering the
+// series and time ranges matching the input tombstones. Returns the number of tombstones written.
+func writeBlockTombstones(logger log.Logger, bdir string, tombstones []*mimir_tsdb.Tombstone) (uint64, error) {
+	b, err := tsdb.OpenBlock(logger, bdir, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "open block")
+	}
+
+	for _, t := range tombstones {
+		for _, ms := range t.Matchers() {
+			if err := b.Delete(t.StartTime, t.EndTime, ms...); err != nil {
+				_ = b.Close()
+				return 0, errors.Wrap(err, "delete series")
+			}
+		}
+	}
+
+	numTombstones := b.Meta().Stats.NumTombstones
+	return numTombstones, errors.Wrap(b.Close(), "close block")
+}
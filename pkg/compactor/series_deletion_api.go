@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// DeleteSeries handles requests to delete series, compatible with the Prometheus TSDB admin API.
+//
+// The request is stored as a pending tombstone in the bucket. Series matching a pending tombstone are
+// filtered out by queriers, and removed from the blocks by the compactor once the cancellation period expires.
+// The results cache generation of the tenant is bumped, so that the query-frontend doesn't return the results
+// cached before the request anymore.
+func (c *MultitenantCompactor) DeleteSeries(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := c.seriesDeletionTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	startTime := int64(0)
+	endTime := util.TimeToMillis(now)
+
+	if s := r.Form.Get("start"); s != "" {
+		t, err := util.ParseTime(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		startTime = t
+	}
+	if s := r.Form.Get("end"); s != "" {
+		t, err := util.ParseTime(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if t > endTime {
+			http.Error(w, "the end time can't be in the future", http.StatusBadRequest)
+			return
+		}
+		endTime = t
+	}
+
+	tombstone, err := mimir_tsdb.NewTombstone(startTime, endTime, r.Form["match[]"], now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	logger := log.With(util_log.WithContext(ctx, c.logger), "user", tenantID, "request_id", tombstone.RequestID)
+
+	// Submitting the same request again should not reset the state of an existing one.
+	existing, err := mimir_tsdb.ReadTombstone(ctx, c.bucketClient, tenantID, tombstone.RequestID)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to read series deletion request", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil && existing.State != mimir_tsdb.TombstoneCancelled {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := mimir_tsdb.WriteTombstone(ctx, c.bucketClient, tenantID, c.cfgProvider, tombstone); err != nil {
+		level.Error(logger).Log("msg", "failed to write series deletion request", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The generation is bumped after the request is written, so that a querier reading the new generation also reads the request.
+	if err := mimir_tsdb.BumpResultsCacheGen(ctx, c.bucketClient, tenantID, c.cfgProvider, now); err != nil {
+		level.Error(logger).Log("msg", "failed to bump results cache generation", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(logger).Log("msg", "series deletion request created", "selectors", tombstone.Selectors, "start", startTime, "end", endTime)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SeriesDeletionRequestsResponse is the response of the API listing the series deletion requests.
+type SeriesDeletionRequestsResponse struct {
+	Status string                  `json:"status"`
+	Data   []*mimir_tsdb.Tombstone `json:"data"`
+}
+
+// SeriesDeletionRequests lists all the series deletion requests of the tenant, regardless of their state.
+func (c *MultitenantCompactor) SeriesDeletionRequests(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := c.seriesDeletionTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	tombstones, err := mimir_tsdb.ListTombstones(r.Context(), c.bucketClient, tenantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(tombstones, func(i, j int) bool {
+		return tombstones[i].CreationTime < tombstones[j].CreationTime
+	})
+
+	util.WriteJSONResponse(w, SeriesDeletionRequestsResponse{Status: "success", Data: tombstones})
+}
+
+// CancelSeriesDeletionRequest cancels a pending series deletion request, as long as the cancellation period
+// hasn't expired yet.
+func (c *MultitenantCompactor) CancelSeriesDeletionRequest(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := c.seriesDeletionTenantID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requestID := r.Form.Get("request_id")
+	if requestID == "" {
+		http.Error(w, "missing request_id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tombstone, err := mimir_tsdb.ReadTombstone(ctx, c.bucketClient, tenantID, requestID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tombstone == nil {
+		http.Error(w, "series deletion request not found", http.StatusNotFound)
+		return
+	}
+	if tombstone.State != mimir_tsdb.TombstonePending {
+		http.Error(w, "series deletion request is not pending", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if !c.canCancelTombstone(tombstone, now) {
+		http.Error(w, "series deletion request can't be cancelled anymore, because the cancellation period expired", http.StatusBadRequest)
+		return
+	}
+
+	tombstone.State = mimir_tsdb.TombstoneCancelled
+	tombstone.StateChangeTime = now.Unix()
+	if err := mimir_tsdb.WriteTombstone(ctx, c.bucketClient, tenantID, c.cfgProvider, tombstone); err != nil {
+		level.Error(util_log.WithContext(ctx, c.logger)).Log("msg", "failed to cancel series deletion request", "user", tenantID, "request_id", requestID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := mimir_tsdb.BumpResultsCacheGen(ctx, c.bucketClient, tenantID, c.cfgProvider, now); err != nil {
+		level.Error(util_log.WithContext(ctx, c.logger)).Log("msg", "failed to bump results cache generation", "user", tenantID, "request_id", requestID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(util_log.WithContext(ctx, c.logger)).Log("msg", "series deletion request cancelled", "user", tenantID, "request_id", requestID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// canCancelTombstone returns whether the tombstone is still within the cancellation period. Once the
+// period expires, the compactor can start removing the series from the blocks.
+func (c *MultitenantCompactor) canCancelTombstone(t *mimir_tsdb.Tombstone, now time.Time) bool {
+	return now.Before(time.Unix(t.CreationTime, 0).Add(c.compactorCfg.SeriesDeletionCancelPeriod))
+}
+
+func (c *MultitenantCompactor) seriesDeletionTenantID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tenantID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return "", false
+	}
+
+	if !c.cfgProvider.CompactorSeriesDeletionEnabled(tenantID) {
+		http.Error(w, "series deletion is disabled", http.StatusForbidden)
+		return "", false
+	}
+
+	return tenantID, true
+}
@@ -0,0 +1,338 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/weaveworks/common/user"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+func TestMultitenantCompactor_SeriesDeletionAPI(t *testing.T) {
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.seriesDeletionEnabled["user-1"] = true
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepareWithConfigProvider(t, prepareConfig(t), bkt, cfgProvider)
+	c.bucketClient = bkt
+
+	doRequest := func(handler http.HandlerFunc, userID, method string, params url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/?"+params.Encode(), nil)
+		if userID != "" {
+			req = req.WithContext(user.InjectOrgID(req.Context(), userID))
+		}
+
+		resp := httptest.NewRecorder()
+		handler(resp, req)
+		return resp
+	}
+
+	listRequests := func(userID string) []*mimir_tsdb.Tombstone {
+		resp := doRequest(c.SeriesDeletionRequests, userID, http.MethodGet, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		res := SeriesDeletionRequestsResponse{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+		return res.Data
+	}
+
+	t.Run("should fail without tenant", func(t *testing.T) {
+		resp := doRequest(c.DeleteSeries, "", http.MethodDelete, url.Values{"match[]": {`{job="test"}`}})
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("should fail if series deletion is disabled for the tenant", func(t *testing.T) {
+		resp := doRequest(c.DeleteSeries, "user-2", http.MethodDelete, url.Values{"match[]": {`{job="test"}`}})
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("should fail on invalid requests", func(t *testing.T) {
+		for name, params := range map[string]url.Values{
+			"no selectors":       {},
+			"invalid selector":   {"match[]": {`{job=}`}},
+			"invalid start time": {"match[]": {`{job="test"}`}, "start": {"invalid"}},
+			"end time in future": {"match[]": {`{job="test"}`}, "end": {"9999999999"}},
+			"end before start":   {"match[]": {`{job="test"}`}, "start": {"200"}, "end": {"100"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				resp := doRequest(c.DeleteSeries, "user-1", http.MethodDelete, params)
+				assert.Equal(t, http.StatusBadRequest, resp.Code)
+			})
+		}
+
+		assert.Empty(t, listRequests("user-1"))
+	})
+
+	readResultsCacheGen := func(userID string) string {
+		gen, err := mimir_tsdb.ReadResultsCacheGen(context.Background(), bkt, userID)
+		require.NoError(t, err)
+		return gen
+	}
+
+	t.Run("should create, list and cancel deletion requests", func(t *testing.T) {
+		params := url.Values{"match[]": {`{job="test"}`}, "start": {"100"}, "end": {"200"}}
+
+		// Submitting the same request twice should create one request only.
+		for i := 0; i < 2; i++ {
+			resp := doRequest(c.DeleteSeries, "user-1", http.MethodPost, params)
+			require.Equal(t, http.StatusNoContent, resp.Code)
+		}
+
+		// Creating a request should bump the results cache generation.
+		createdGen := readResultsCacheGen("user-1")
+		assert.NotEmpty(t, createdGen)
+
+		requests := listRequests("user-1")
+		require.Len(t, requests, 1)
+		assert.Equal(t, mimir_tsdb.TombstonePending, requests[0].State)
+		assert.Equal(t, []string{`{job="test"}`}, requests[0].Selectors)
+		assert.Equal(t, int64(100000), requests[0].StartTime)
+		assert.Equal(t, int64(200000), requests[0].EndTime)
+
+		requestID := requests[0].RequestID
+
+		resp := doRequest(c.CancelSeriesDeletionRequest, "user-1", http.MethodPost, url.Values{"request_id": {"missing"}})
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+
+		resp = doRequest(c.CancelSeriesDeletionRequest, "user-1", http.MethodPost, url.Values{"request_id": {requestID}})
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+
+		requests = listRequests("user-1")
+		require.Len(t, requests, 1)
+		assert.Equal(t, mimir_tsdb.TombstoneCancelled, requests[0].State)
+		assert.NotEqual(t, createdGen, readResultsCacheGen("user-1"))
+
+		// A cancelled request can't be cancelled again.
+		resp = doRequest(c.CancelSeriesDeletionRequest, "user-1", http.MethodPost, url.Values{"request_id": {requestID}})
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+		// A cancelled request can be submitted again.
+		resp = doRequest(c.DeleteSeries, "user-1", http.MethodPost, params)
+		require.Equal(t, http.StatusNoContent, resp.Code)
+
+		requests = listRequests("user-1")
+		require.Len(t, requests, 1)
+		assert.Equal(t, mimir_tsdb.TombstonePending, requests[0].State)
+	})
+
+	t.Run("should not cancel a request once the cancellation period expired", func(t *testing.T) {
+		tombstone, err := mimir_tsdb.NewTombstone(0, 100, []string{`{job="old"}`}, time.Now().Add(-2*c.compactorCfg.SeriesDeletionCancelPeriod))
+		require.NoError(t, err)
+		require.NoError(t, mimir_tsdb.WriteTombstone(context.Background(), bkt, "user-1", nil, tombstone))
+
+		resp := doRequest(c.CancelSeriesDeletionRequest, "user-1", http.MethodPost, url.Values{"request_id": {tombstone.RequestID}})
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Contains(t, resp.Body.String(), "cancellation period expired")
+	})
+}
+
+func TestMultitenantCompactor_ProcessSeriesDeletionRequests(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.seriesDeletionEnabled[userID] = true
+
+	cfg := prepareConfig(t)
+	cfg.ConsistencyDelay = 0
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepareWithConfigProvider(t, cfg, bkt, cfgProvider)
+	c.bucketClient = bucketindex.BucketWithGlobalMarkers(bkt)
+
+	blocksCompactor, err := tsdb.NewLeveledCompactor(ctx, nil, log.NewNopLogger(), []int64{2 * time.Hour.Milliseconds()}, nil, nil, true)
+	require.NoError(t, err)
+	c.blocksCompactor = blocksCompactor
+	c.shardingStrategy = &mockShardingStrategy{owned: true}
+
+	// The first block contains the series series_id=0..4, while the second block contains series_id=0 only.
+	block1 := createTSDBBlock(t, bkt, userID, 0, 2*time.Hour.Milliseconds(), 5, nil)
+	block2 := createTSDBBlock(t, bkt, userID, 2*time.Hour.Milliseconds(), 4*time.Hour.Milliseconds(), 1, nil)
+
+	expired, err := mimir_tsdb.NewTombstone(0, 4*time.Hour.Milliseconds(), []string{`{series_id="1"}`}, time.Now().Add(-2*cfg.SeriesDeletionCancelPeriod))
+	require.NoError(t, err)
+	require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt, userID, nil, expired))
+
+	cancellable, err := mimir_tsdb.NewTombstone(0, 4*time.Hour.Milliseconds(), []string{`{series_id="0"}`}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt, userID, nil, cancellable))
+
+	// The samples of this request may still be uploaded by the ingesters.
+	recentEnd := time.Now().Add(-time.Hour)
+	recent, err := mimir_tsdb.NewTombstone(recentEnd.Add(-time.Hour).UnixMilli(), recentEnd.UnixMilli(), []string{`{series_id="2"}`}, time.Now().Add(-2*cfg.SeriesDeletionCancelPeriod))
+	require.NoError(t, err)
+	require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt, userID, nil, recent))
+
+	// The first pass should rewrite the first block only.
+	require.NoError(t, c.processSeriesDeletionRequests(ctx, userID))
+
+	assert.True(t, blockMarkedForDeletion(t, bkt, userID, block1))
+	assert.False(t, blockMarkedForDeletion(t, bkt, userID, block2))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.seriesDeletionBlocksRewritten))
+
+	expired, err = mimir_tsdb.ReadTombstone(ctx, bkt, userID, expired.RequestID)
+	require.NoError(t, err)
+	assert.Equal(t, mimir_tsdb.TombstonePending, expired.State)
+	require.Len(t, expired.CleanBlocks, 2)
+	assert.True(t, expired.IsBlockClean(block2))
+
+	var rewritten ulid.ULID
+	for _, id := range expired.CleanBlocks {
+		if id != block2 {
+			rewritten = id
+		}
+	}
+
+	meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), objstore.NewPrefixedBucket(bkt, userID), rewritten)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), meta.Stats.NumSeries)
+	assert.Equal(t, int64(0), meta.MinTime)
+	assert.Empty(t, meta.Thanos.Labels)
+
+	// The second pass should not find any block left to rewrite, and mark the request as processed.
+	require.NoError(t, c.processSeriesDeletionRequests(ctx, userID))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.seriesDeletionBlocksRewritten))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.seriesDeletionRequestsProcessed))
+
+	expired, err = mimir_tsdb.ReadTombstone(ctx, bkt, userID, expired.RequestID)
+	require.NoError(t, err)
+	assert.Equal(t, mimir_tsdb.TombstoneProcessed, expired.State)
+	assert.Empty(t, expired.CleanBlocks)
+
+	// Processing a request should bump the results cache generation.
+	gen, err := mimir_tsdb.ReadResultsCacheGen(ctx, bkt, userID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, gen)
+
+	// The request whose samples may still be uploaded by the ingesters should not be marked as processed.
+	recent, err = mimir_tsdb.ReadTombstone(ctx, bkt, userID, recent.RequestID)
+	require.NoError(t, err)
+	assert.Equal(t, mimir_tsdb.TombstonePending, recent.State)
+
+	// The request still within the cancellation period should not be applied.
+	cancellable, err = mimir_tsdb.ReadTombstone(ctx, bkt, userID, cancellable.RequestID)
+	require.NoError(t, err)
+	assert.Equal(t, mimir_tsdb.TombstonePending, cancellable.State)
+	assert.Empty(t, cancellable.CleanBlocks)
+	assert.False(t, blockMarkedForDeletion(t, bkt, userID, block2))
+
+	// Processed requests should be deleted once the replaced blocks have been deleted.
+	c.compactorCfg.DeletionDelay = -time.Hour
+	require.NoError(t, c.processSeriesDeletionRequests(ctx, userID))
+
+	expired, err = mimir_tsdb.ReadTombstone(ctx, bkt, userID, expired.RequestID)
+	require.NoError(t, err)
+	assert.Nil(t, expired)
+
+	// The request should be marked as processed once the ingesters window expired.
+	c.storageCfg.TSDB.Retention = 0
+	c.storageCfg.TSDB.BlockRanges = nil
+	c.storageCfg.TSDB.HeadCompactionInterval = 0
+	c.storageCfg.TSDB.ShipInterval = 0
+	require.NoError(t, c.processSeriesDeletionRequests(ctx, userID))
+
+	recent, err = mimir_tsdb.ReadTombstone(ctx, bkt, userID, recent.RequestID)
+	require.NoError(t, err)
+	assert.Equal(t, mimir_tsdb.TombstoneProcessed, recent.State)
+}
+
+func blockMarkedForDeletion(t *testing.T, bkt objstore.Bucket, userID string, blockID ulid.ULID) bool {
+	exists, err := bkt.Exists(context.Background(), strings.Join([]string{userID, blockID.String(), metadata.DeletionMarkFilename}, "/"))
+	require.NoError(t, err)
+	return exists
+}
+
+type mockShardingStrategy struct {
+	owned bool
+}
+
+func (m *mockShardingStrategy) compactorOwnUser(string) (bool, error) {
+	return m.owned, nil
+}
+
+func (m *mockShardingStrategy) blocksCleanerOwnUser(string) (bool, error) {
+	return m.owned, nil
+}
+
+func (m *mockShardingStrategy) ownJob(*Job) (bool, error) {
+	return m.owned, nil
+}
+
+func TestMultitenantCompactor_ProcessSeriesDeletionRequestsShouldRewriteDownsampledBlocks(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.seriesDeletionEnabled[userID] = true
+	cfgProvider.downsampling5mAfter[userID] = 24 * time.Hour
+
+	cfg := prepareConfig(t)
+	cfg.ConsistencyDelay = 0
+
+	bkt := objstore.NewInMemBucket()
+	c, _, _, _, _ := prepareWithConfigProvider(t, cfg, bkt, cfgProvider)
+	c.bucketClient = bucketindex.BucketWithGlobalMarkers(bkt)
+
+	blocksCompactor, err := tsdb.NewLeveledCompactor(ctx, nil, log.NewNopLogger(), []int64{2 * time.Hour.Milliseconds()}, nil, nil, true)
+	require.NoError(t, err)
+	c.blocksCompactor = blocksCompactor
+	c.shardingStrategy = &mockShardingStrategy{owned: true}
+
+	now := time.Now()
+	raw := createTSDBBlock(t, bkt, userID, now.Add(-50*time.Hour).UnixMilli(), now.Add(-48*time.Hour).UnixMilli(), 5, nil)
+	require.NoError(t, c.downsampleUserBlocks(ctx, userID))
+
+	idx, err := bucketindex.ReadIndex(ctx, bkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, idx.Blocks, 2)
+
+	var downsampled ulid.ULID
+	for _, b := range idx.Blocks {
+		if b.Resolution == block.Resolution5m {
+			downsampled = b.ID
+		}
+	}
+
+	tombstone, err := mimir_tsdb.NewTombstone(now.Add(-50*time.Hour).UnixMilli(), now.Add(-48*time.Hour).UnixMilli(), []string{`{series_id="1"}`}, now.Add(-2*cfg.SeriesDeletionCancelPeriod))
+	require.NoError(t, err)
+	require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt, userID, nil, tombstone))
+
+	// Both the raw block and the downsampled block should be rewritten.
+	require.NoError(t, c.processSeriesDeletionRequests(ctx, userID))
+
+	assert.True(t, blockMarkedForDeletion(t, bkt, userID, raw))
+	assert.True(t, blockMarkedForDeletion(t, bkt, userID, downsampled))
+	assert.Equal(t, 2.0, prom_testutil.ToFloat64(c.seriesDeletionBlocksRewritten))
+
+	tombstone, err = mimir_tsdb.ReadTombstone(ctx, bkt, userID, tombstone.RequestID)
+	require.NoError(t, err)
+	require.Len(t, tombstone.CleanBlocks, 2)
+
+	resolutions := map[int64]int{}
+	for _, id := range tombstone.CleanBlocks {
+		meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), objstore.NewPrefixedBucket(bkt, userID), id)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(4), meta.Stats.NumSeries)
+		resolutions[meta.Thanos.Downsample.Resolution]++
+	}
+	assert.Equal(t, map[int64]int{block.ResolutionRaw: 1, block.Resolution5m: 1}, resolutions)
+}
@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"errors"
+	"flag"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/limiter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+
+	util_math "github.com/grafana/mimir/pkg/util/math"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+var (
+	errInvalidAdaptiveLimitingPressureThreshold = errors.New("the adaptive limiting pressure threshold must be greater than 0 and less than 1")
+	errInvalidAdaptiveLimitingPressureTTL       = errors.New("the adaptive limiting pressure TTL must be greater than 0")
+)
+
+// AdaptiveLimitingConfig configures the limiting of the ingestion rate of the heaviest tenants, based on the
+// push pressure reported by the ingesters.
+type AdaptiveLimitingConfig struct {
+	Enabled           bool          `yaml:"enabled" category:"experimental"`
+	PressureThreshold float64       `yaml:"pressure_threshold" category:"experimental"`
+	PressureTTL       time.Duration `yaml:"pressure_ttl" category:"experimental"`
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *AdaptiveLimitingConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "distributor.adaptive-limiting.enabled", false, "Enable the limiting of the ingestion rate of the heaviest tenants when the ingesters are overloaded. The ingesters report their push pressure in the responses to the push requests, and when the highest pressure of the ingesters in the shard of a tenant exceeds the threshold, the tenant is limited proportionally to that pressure if its ingestion rate is higher than the average rate of the tenants.")
+	f.Float64Var(&cfg.PressureThreshold, "distributor.adaptive-limiting.pressure-threshold", 0.8, "Push pressure of the ingesters above which the ingestion rate of the heaviest tenants is limited. The pressure of an ingester reaches 1 when it reaches one of its instance limits, at which point the heaviest tenants whose shard includes it are limited to the average ingestion rate of the tenants.")
+	f.DurationVar(&cfg.PressureTTL, "distributor.adaptive-limiting.pressure-ttl", 10*time.Second, "How long the push pressure reported by an ingester is taken into account.")
+}
+
+// Validate the config.
+func (cfg *AdaptiveLimitingConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.PressureThreshold <= 0 || cfg.PressureThreshold >= 1 {
+		return errInvalidAdaptiveLimitingPressureThreshold
+	}
+	if cfg.PressureTTL <= 0 {
+		return errInvalidAdaptiveLimitingPressureTTL
+	}
+	return nil
+}
+
+type pressureReport struct {
+	pressure   float64
+	reportedAt time.Time
+}
+
+// adaptiveLimiter limits the ingestion rate of the heaviest tenants when the ingesters of their shard report a push
+// pressure above the threshold. The tenants whose ingestion rate is above the average rate of the tenants are limited
+// to a rate between their current rate and the average rate, proportionally to how much the pressure of their shard
+// exceeds the threshold. The limits are local to each distributor, and are recomputed on each tick.
+type adaptiveLimiter struct {
+	cfg         AdaptiveLimitingConfig
+	limits      *validation.Overrides
+	rateLimiter *limiter.RateLimiter
+
+	mtx sync.Mutex
+	// Push pressure reported by each ingester, by address.
+	pressures map[string]pressureReport
+	// Ingesters of the shard of each tenant, by address, with the last time the tenant's series were pushed to them.
+	shards map[string]map[string]time.Time
+	// Ingestion rate of each tenant, including the rejected samples.
+	rates map[string]*util_math.EwmaRate
+	// Ingestion rate limit of the limited tenants.
+	limited map[string]float64
+
+	limitedRate *prometheus.GaugeVec
+}
+
+func newAdaptiveLimiter(cfg AdaptiveLimitingConfig, limits *validation.Overrides, reg prometheus.Registerer) *adaptiveLimiter {
+	l := &adaptiveLimiter{
+		cfg:       cfg,
+		limits:    limits,
+		pressures: map[string]pressureReport{},
+		shards:    map[string]map[string]time.Time{},
+		rates:     map[string]*util_math.EwmaRate{},
+		limited:   map[string]float64{},
+
+		limitedRate: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_distributor_adaptive_ingestion_rate_limit",
+			Help: "The ingestion rate limit of the tenants limited by the distributor because the ingesters are overloaded.",
+		}, []string{"user"}),
+	}
+	l.rateLimiter = limiter.NewRateLimiter(l, instanceIngestionRateTickInterval)
+
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cortex_distributor_ingesters_push_pressure",
+		Help: "The highest push pressure recently reported by the ingesters.",
+	}, func() float64 {
+		return l.pressure(time.Now())
+	})
+
+	return l
+}
+
+// Limit implements limiter.RateLimiterStrategy.
+func (l *adaptiveLimiter) Limit(userID string) float64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if limit, ok := l.limited[userID]; ok {
+		return limit
+	}
+	return float64(rate.Inf)
+}
+
+// Burst implements limiter.RateLimiterStrategy.
+func (l *adaptiveLimiter) Burst(userID string) int {
+	return l.limits.IngestionBurstSize(userID)
+}
+
+// observePressure records the push pressure reported by an ingester in the response to a push of the tenant's
+// series, which means that the ingester belongs to the shard of the tenant.
+func (l *adaptiveLimiter) observePressure(userID, addr string, pressure float64, now time.Time) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.pressures[addr] = pressureReport{pressure: pressure, reportedAt: now}
+
+	shard, ok := l.shards[userID]
+	if !ok {
+		shard = map[string]time.Time{}
+		l.shards[userID] = shard
+	}
+	shard[addr] = now
+}
+
+// pressure returns the highest push pressure reported by the ingesters within the TTL.
+func (l *adaptiveLimiter) pressure(now time.Time) float64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.expire(now)
+
+	highest := 0.0
+	for _, report := range l.pressures {
+		highest = math.Max(highest, report.pressure)
+	}
+	return highest
+}
+
+// userPressure returns the highest push pressure reported within the TTL by the ingesters the tenant's series have
+// been pushed to within the TTL, which are the ingesters of its shard. Must be called with the lock held, after
+// expire.
+func (l *adaptiveLimiter) userPressure(userID string) float64 {
+	highest := 0.0
+	for addr := range l.shards[userID] {
+		if report, ok := l.pressures[addr]; ok {
+			highest = math.Max(highest, report.pressure)
+		}
+	}
+	return highest
+}
+
+// expire removes the push pressures reported, and the ingesters of the shards the series have been pushed to,
+// before the TTL. Must be called with the lock held.
+func (l *adaptiveLimiter) expire(now time.Time) {
+	for addr, report := range l.pressures {
+		if now.Sub(report.reportedAt) > l.cfg.PressureTTL {
+			delete(l.pressures, addr)
+		}
+	}
+	for userID, shard := range l.shards {
+		for addr, pushedAt := range shard {
+			if now.Sub(pushedAt) > l.cfg.PressureTTL {
+				delete(shard, addr)
+			}
+		}
+		if len(shard) == 0 {
+			delete(l.shards, userID)
+		}
+	}
+}
+
+// AllowN records n items received from the tenant, and returns whether they're allowed by its limit.
+func (l *adaptiveLimiter) AllowN(now time.Time, userID string, n int) bool {
+	l.mtx.Lock()
+	r, ok := l.rates[userID]
+	if !ok {
+		r = util_math.NewEWMARate(0.2, instanceIngestionRateTickInterval)
+		l.rates[userID] = r
+	}
+	l.mtx.Unlock()
+
+	// The rejected items are counted too, so that a limited tenant isn't unlimited on the next tick.
+	r.Add(int64(n))
+	return l.rateLimiter.AllowN(now, userID, n)
+}
+
+// tick updates the ingestion rates of the tenants, and the limits of the heaviest tenants. It must be called
+// every instanceIngestionRateTickInterval.
+func (l *adaptiveLimiter) tick(now time.Time) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.expire(now)
+
+	total, active := 0.0, 0
+	for _, r := range l.rates {
+		r.Tick()
+		if r.Rate() > 0 {
+			total += r.Rate()
+			active++
+		}
+	}
+
+	limited := map[string]float64{}
+	if active > 0 {
+		average := total / float64(active)
+
+		for userID, r := range l.rates {
+			userRate := r.Rate()
+			if userRate <= average {
+				continue
+			}
+			// Only the tenants whose shard is overloaded are limited.
+			pressure := l.userPressure(userID)
+			if pressure <= l.cfg.PressureThreshold {
+				continue
+			}
+			overload := math.Min((pressure-l.cfg.PressureThreshold)/(1-l.cfg.PressureThreshold), 1)
+			limited[userID] = average + (userRate-average)*(1-overload)
+		}
+	}
+
+	for userID := range l.limited {
+		if _, ok := limited[userID]; !ok {
+			l.limitedRate.DeleteLabelValues(userID)
+		}
+	}
+	for userID, limit := range limited {
+		l.limitedRate.WithLabelValues(userID).Set(limit)
+	}
+	l.limited = limited
+}
+
+// deleteUser removes the state and the metrics of the tenant.
+func (l *adaptiveLimiter) deleteUser(userID string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.rates, userID)
+	delete(l.shards, userID)
+	delete(l.limited, userID)
+	l.limitedRate.DeleteLabelValues(userID)
+}
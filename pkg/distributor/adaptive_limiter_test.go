@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/common/user"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestAdaptiveLimitingConfig_Validate(t *testing.T) {
+	cfg := AdaptiveLimitingConfig{}
+	flagext.DefaultValues(&cfg)
+	require.NoError(t, cfg.Validate())
+
+	cfg.Enabled = true
+	require.NoError(t, cfg.Validate())
+
+	cfg.PressureThreshold = 1
+	require.ErrorIs(t, cfg.Validate(), errInvalidAdaptiveLimitingPressureThreshold)
+
+	cfg.PressureThreshold = 0.5
+	cfg.PressureTTL = 0
+	require.ErrorIs(t, cfg.Validate(), errInvalidAdaptiveLimitingPressureTTL)
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	limits := validation.Limits{}
+	flagext.DefaultValues(&limits)
+	limits.IngestionBurstSize = 100
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	l := newAdaptiveLimiter(AdaptiveLimitingConfig{Enabled: true, PressureThreshold: 0.5, PressureTTL: 10 * time.Second}, overrides, reg)
+
+	// Each tenant pushes at a constant rate, so that the rates don't change across ticks. The shard of the "heavy"
+	// tenant is made of ingester-1 and ingester-2, and the shard of the "other-heavy" tenant of ingester-3.
+	now := time.Now()
+	observe := func(userID, addr string, pressure float64) {
+		l.observePressure(userID, addr, pressure, now)
+	}
+	tick := func() {
+		l.AllowN(now, "heavy", 1000)
+		l.AllowN(now, "other-heavy", 1000)
+		l.AllowN(now, "light-1", 100)
+		l.AllowN(now, "light-2", 100)
+		l.tick(now)
+		now = now.Add(instanceIngestionRateTickInterval)
+	}
+
+	// The tenants aren't limited until the pressure of their shard exceeds the threshold.
+	observe("heavy", "ingester-1", 0.5)
+	observe("other-heavy", "ingester-3", 0.1)
+	tick()
+	assert.Equal(t, float64(rate.Inf), l.Limit("heavy"))
+
+	// The heaviest tenant whose shard is overloaded is limited halfway between its rate and the average rate,
+	// which is 550, while the other heavy tenant isn't limited because its shard isn't overloaded.
+	observe("heavy", "ingester-2", 0.75)
+	tick()
+	assert.Equal(t, 0.75, l.pressure(now))
+	assert.Equal(t, 775.0, l.Limit("heavy"))
+	assert.Equal(t, float64(rate.Inf), l.Limit("other-heavy"))
+	assert.Equal(t, float64(rate.Inf), l.Limit("light-1"))
+	assert.Equal(t, float64(rate.Inf), l.Limit("light-2"))
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_adaptive_ingestion_rate_limit The ingestion rate limit of the tenants limited by the distributor because the ingesters are overloaded.
+		# TYPE cortex_distributor_adaptive_ingestion_rate_limit gauge
+		cortex_distributor_adaptive_ingestion_rate_limit{user="heavy"} 775
+
+		# HELP cortex_distributor_ingesters_push_pressure The highest push pressure recently reported by the ingesters.
+		# TYPE cortex_distributor_ingesters_push_pressure gauge
+		cortex_distributor_ingesters_push_pressure 0.75
+	`), "cortex_distributor_adaptive_ingestion_rate_limit", "cortex_distributor_ingesters_push_pressure"))
+
+	// The pressure reported in the response to the push of another tenant is taken into account for all the
+	// tenants whose shard includes the ingester, and the heaviest tenant is limited to the average rate when an
+	// ingester of its shard reaches its limits.
+	observe("light-1", "ingester-2", 1.5)
+	tick()
+	assert.Equal(t, 550.0, l.Limit("heavy"))
+	assert.Equal(t, float64(rate.Inf), l.Limit("other-heavy"))
+
+	// Once the limit is applied, the tenant can't push more than the burst.
+	now = now.Add(instanceIngestionRateTickInterval)
+	assert.True(t, l.AllowN(now, "heavy", 100))
+	assert.False(t, l.AllowN(now, "heavy", 100))
+	assert.True(t, l.AllowN(now, "light-1", 1000))
+
+	// The tenants aren't limited anymore once the reported pressure expires.
+	now = now.Add(time.Minute)
+	observe("heavy", "ingester-1", 0.1)
+	tick()
+	assert.Equal(t, 0.1, l.pressure(now))
+	assert.Equal(t, float64(rate.Inf), l.Limit("heavy"))
+	assert.Equal(t, 0, testutil.CollectAndCount(reg, "cortex_distributor_adaptive_ingestion_rate_limit"))
+
+	l.deleteUser("heavy")
+	assert.NotContains(t, l.rates, "heavy")
+	assert.NotContains(t, l.shards, "heavy")
+}
+
+func TestDistributor_AdaptiveLimiting(t *testing.T) {
+	limits := &validation.Limits{}
+	flagext.DefaultValues(limits)
+	limits.IngestionRate = float64(rate.Inf)
+	limits.IngestionBurstSize = 100
+
+	ds, _, regs := prepare(t, prepConfig{
+		numIngesters:          3,
+		happyIngesters:        3,
+		numDistributors:       1,
+		limits:                limits,
+		adaptiveLimiting:      true,
+		ingestersPushPressure: 1.5,
+	})
+
+	now := time.Now()
+	mtime.NowForce(now)
+	t.Cleanup(mtime.NowReset)
+
+	push := func(userID string, samples int) error {
+		_, err := ds[0].Push(user.InjectOrgID(context.Background(), userID), makeWriteRequest(0, samples, 0, false))
+		return err
+	}
+
+	// The push pressure is received from the ingesters.
+	require.NoError(t, push("heavy", 100))
+	require.NoError(t, push("light", 10))
+	assert.Equal(t, 1.5, ds[0].adaptiveLimiter.pressure(time.Now()))
+
+	// The heaviest tenant is limited to the average rate once its limit is rechecked.
+	ds[0].adaptiveLimiter.tick(time.Now())
+	mtime.NowForce(now.Add(2 * instanceIngestionRateTickInterval))
+
+	require.NoError(t, push("heavy", 100))
+	err := push("heavy", 100)
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusTooManyRequests), resp.Code)
+	assert.Contains(t, string(resp.Body), validation.NewAdaptiveIngestionRateLimitedError(55).Error())
+	require.NoError(t, push("light", 100))
+
+	require.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="adaptive_rate_limited",user="heavy"} 100
+	`), "cortex_discarded_samples_total"))
+}
@@ -0,0 +1,362 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package aggregation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/extract"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// AggregatorLabel is the label of the aggregated series set to the ID of the distributor which aggregated them,
+	// because the distributor in charge of a metric changes when distributors join or leave the ring.
+	AggregatorLabel = "aggregator"
+
+	// flushCheckPeriod is how often the aggregated series are checked for the end of their interval.
+	flushCheckPeriod = time.Second
+
+	// staleIntervals is the number of intervals after which a series which didn't receive any sample
+	// is no longer part of its aggregated series.
+	staleIntervals = 5
+)
+
+type Aggregator interface {
+	services.Service
+	Aggregate(user string, rules validation.AggregationRules, maxSeries int, ts []mimirpb.PreallocTimeseries) []mimirpb.PreallocTimeseries
+	DeleteMetricsForUser(user string)
+}
+
+// PushFunc pushes the aggregated series of a user, which is injected in the context.
+type PushFunc func(ctx context.Context, req *mimirpb.WriteRequest) error
+
+type aggregator struct {
+	services.Service
+
+	instanceID string
+	push       PushFunc
+	log        log.Logger
+
+	mtx    sync.Mutex
+	series map[string]map[uint64]*aggregatedSeries // Aggregated series keyed by user and hash of the labels.
+
+	inputSamples   *prometheus.CounterVec
+	skippedSamples *prometheus.CounterVec
+	outputSamples  *prometheus.CounterVec
+	pushFailures   *prometheus.CounterVec
+}
+
+// aggregatedSeries is the sum of the series of a metric with the same labels, except the labels removed by the rule.
+type aggregatedSeries struct {
+	labels    labels.Labels
+	interval  time.Duration
+	counter   bool
+	nextFlush time.Time
+
+	// total is the sum of the increases of the input series of a counter.
+	total  float64
+	inputs map[uint64]*inputSeries
+}
+
+type inputSeries struct {
+	lastTimestamp int64
+	lastValue     float64
+	updatedAt     time.Time
+
+	// inInterval is whether the series received a sample since the last flush of the aggregated series.
+	inInterval bool
+}
+
+// NewAggregator returns a new aggregator, if aggregation is disabled it returns nil.
+func NewAggregator(cfg Config, instanceID string, push PushFunc, reg prometheus.Registerer, log log.Logger) Aggregator {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	a := &aggregator{
+		instanceID: instanceID,
+		push:       push,
+		log:        log,
+		series:     map[string]map[uint64]*aggregatedSeries{},
+
+		inputSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_aggregation_input_samples_total",
+			Help: "The total number of samples the distributor aggregated according to the aggregation rules.",
+		}, []string{"user"}),
+		skippedSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_aggregation_skipped_samples_total",
+			Help: "The total number of samples the distributor didn't aggregate because the maximum number of aggregated series of the user has been reached.",
+		}, []string{"user"}),
+		outputSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_aggregation_output_samples_total",
+			Help: "The total number of samples of aggregated series the distributor pushed.",
+		}, []string{"user"}),
+		pushFailures: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_aggregation_push_failures_total",
+			Help: "The total number of failed pushes of aggregated series.",
+		}, []string{"user"}),
+	}
+
+	a.Service = services.NewTimerService(flushCheckPeriod, nil, a.iteration, nil)
+	return a
+}
+
+func (a *aggregator) DeleteMetricsForUser(user string) {
+	a.inputSamples.DeleteLabelValues(user)
+	a.skippedSamples.DeleteLabelValues(user)
+	a.outputSamples.DeleteLabelValues(user)
+	a.pushFailures.DeleteLabelValues(user)
+}
+
+func (a *aggregator) iteration(ctx context.Context) error {
+	a.flush(ctx, time.Now())
+	return nil
+}
+
+// Aggregate adds the samples of the series of the metrics with an aggregation rule to their aggregated series,
+// which get pushed at the end of each interval of the rule.
+//
+// The series which would create a new aggregated series once the user has maxSeries aggregated series aren't
+// aggregated. If maxSeries is 0, the number of aggregated series isn't limited.
+//
+// It returns the series which should be ingested: the given series, except the aggregated ones if the rule drops them.
+// The dropped series are returned to the pool.
+func (a *aggregator) Aggregate(user string, rules validation.AggregationRules, maxSeries int, ts []mimirpb.PreallocTimeseries) []mimirpb.PreallocTimeseries {
+	return a.aggregate(time.Now(), user, rules, maxSeries, ts)
+}
+
+func (a *aggregator) aggregate(now time.Time, user string, rules validation.AggregationRules, maxSeries int, ts []mimirpb.PreallocTimeseries) []mimirpb.PreallocTimeseries {
+	if len(rules) == 0 {
+		return ts
+	}
+
+	var (
+		removeIndexes []int
+		samples       int
+		skipped       int
+	)
+
+	a.mtx.Lock()
+	for idx, series := range ts {
+		metric, err := extract.UnsafeMetricNameFromLabelAdapters(series.Labels)
+		if err != nil {
+			continue
+		}
+		rule, ok := rules[metric]
+		if !ok {
+			continue
+		}
+
+		if len(series.Samples) > 0 {
+			lbls := mimirpb.FromLabelAdaptersToLabels(series.Labels)
+			agg := a.aggregatedSeries(now, user, metric, rule, maxSeries, lbls)
+			if agg == nil {
+				// The input series is ingested as is, so that its samples don't get lost.
+				skipped += len(series.Samples)
+				continue
+			}
+
+			in, ok := agg.inputs[lbls.Hash()]
+			if !ok {
+				in = &inputSeries{}
+				agg.inputs[lbls.Hash()] = in
+			}
+			for _, s := range series.Samples {
+				agg.add(in, s, ok)
+				ok = true
+			}
+			in.updatedAt = now
+			samples += len(series.Samples)
+		}
+
+		if rule.DropInput {
+			removeIndexes = append(removeIndexes, idx)
+		}
+	}
+	a.mtx.Unlock()
+
+	if samples > 0 {
+		a.inputSamples.WithLabelValues(user).Add(float64(samples))
+	}
+	if skipped > 0 {
+		a.skippedSamples.WithLabelValues(user).Add(float64(skipped))
+	}
+
+	if len(removeIndexes) > 0 {
+		for _, idx := range removeIndexes {
+			mimirpb.ReusePreallocTimeseries(&ts[idx])
+		}
+		ts = util.RemoveSliceIndexes(ts, removeIndexes)
+	}
+	return ts
+}
+
+// aggregatedSeries returns the aggregated series of the series with the given labels, creating it if needed, or nil
+// if it doesn't exist and the user already has maxSeries aggregated series. Must be called with mtx held.
+func (a *aggregator) aggregatedSeries(now time.Time, user, metric string, rule validation.AggregationRule, maxSeries int, lbls labels.Labels) *aggregatedSeries {
+	b := labels.NewBuilder(lbls)
+	b.Set(model.MetricNameLabel, outputMetricName(metric, rule))
+	b.Del(rule.Without...)
+	b.Set(AggregatorLabel, a.instanceID)
+	outLbls := b.Labels(nil)
+
+	userSeries, ok := a.series[user]
+	if !ok {
+		userSeries = map[uint64]*aggregatedSeries{}
+		a.series[user] = userSeries
+	}
+
+	interval := time.Duration(rule.Interval)
+	agg, ok := userSeries[outLbls.Hash()]
+	if !ok {
+		if maxSeries > 0 && len(userSeries) >= maxSeries {
+			return nil
+		}
+		agg = &aggregatedSeries{
+			// The labels reference the request buffer, which can be reused once the request is done.
+			labels:    copyLabels(outLbls),
+			nextFlush: intervalEnd(now, interval),
+			inputs:    map[uint64]*inputSeries{},
+		}
+		userSeries[outLbls.Hash()] = agg
+	}
+	// The rule may have changed since the aggregated series has been created.
+	agg.interval = interval
+	agg.counter = rule.Counter
+	return agg
+}
+
+// add adds a sample of the input series to the aggregated series. The samples older than the last
+// sample of the input series are ignored. The increase of a counter is only known from its second sample.
+func (s *aggregatedSeries) add(in *inputSeries, sample mimirpb.Sample, seen bool) {
+	if seen && sample.TimestampMs <= in.lastTimestamp {
+		return
+	}
+
+	if s.counter && seen {
+		increase := sample.Value - in.lastValue
+		if increase < 0 {
+			// The counter has been reset.
+			increase = sample.Value
+		}
+		s.total += increase
+	}
+
+	in.lastTimestamp = sample.TimestampMs
+	in.lastValue = sample.Value
+	in.inInterval = true
+}
+
+// flush pushes a sample of the aggregated series whose interval ended, and removes the input series
+// which went stale.
+func (a *aggregator) flush(ctx context.Context, now time.Time) {
+	reqs := map[string]*mimirpb.WriteRequest{}
+
+	a.mtx.Lock()
+	for userID, userSeries := range a.series {
+		for key, agg := range userSeries {
+			if now.Before(agg.nextFlush) {
+				continue
+			}
+
+			if sample, ok := agg.sample(); ok {
+				req, ok := reqs[userID]
+				if !ok {
+					req = &mimirpb.WriteRequest{Timeseries: mimirpb.PreallocTimeseriesSliceFromPool(), Source: mimirpb.API}
+					reqs[userID] = req
+				}
+
+				ts := mimirpb.TimeseriesFromPool()
+				// The labels are copied because the series gets returned to the pool once pushed.
+				ts.Labels = append(ts.Labels, mimirpb.FromLabelsToLabelAdapters(agg.labels)...)
+				ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: agg.nextFlush.UnixMilli(), Value: sample})
+				req.Timeseries = append(req.Timeseries, mimirpb.PreallocTimeseries{TimeSeries: ts})
+			}
+
+			for inKey, in := range agg.inputs {
+				if now.Sub(in.updatedAt) > staleIntervals*agg.interval {
+					delete(agg.inputs, inKey)
+				}
+			}
+			if len(agg.inputs) == 0 {
+				delete(userSeries, key)
+				continue
+			}
+			agg.nextFlush = intervalEnd(now, agg.interval)
+		}
+
+		if len(userSeries) == 0 {
+			delete(a.series, userID)
+		}
+	}
+	a.mtx.Unlock()
+
+	for userID, req := range reqs {
+		samples := len(req.Timeseries)
+		if err := a.push(user.InjectOrgID(ctx, userID), req); err != nil {
+			level.Warn(a.log).Log("msg", "failed to push aggregated series", "user", userID, "err", err)
+			a.pushFailures.WithLabelValues(userID).Inc()
+			continue
+		}
+		a.outputSamples.WithLabelValues(userID).Add(float64(samples))
+	}
+}
+
+// sample returns the value of the aggregated series for the interval which ended, and whether it has a value.
+// The aggregated series of a counter has a value as long as it has input series, while the one of a gauge only
+// has a value if its input series received samples during the interval.
+func (s *aggregatedSeries) sample() (float64, bool) {
+	if s.counter {
+		return s.total, len(s.inputs) > 0
+	}
+
+	var (
+		sum float64
+		ok  bool
+	)
+	for _, in := range s.inputs {
+		if in.inInterval {
+			sum += in.lastValue
+			in.inInterval = false
+			ok = true
+		}
+	}
+	return sum, ok
+}
+
+// outputMetricName returns the name of the aggregated metric of the given metric.
+func outputMetricName(metric string, rule validation.AggregationRule) string {
+	if len(rule.Without) == 0 {
+		return metric + ":sum"
+	}
+	return fmt.Sprintf("%s:sum_without_%s", metric, strings.Join(rule.Without, "_"))
+}
+
+// intervalEnd returns the end of the interval, aligned to the interval duration, which contains t.
+func intervalEnd(t time.Time, interval time.Duration) time.Time {
+	return t.Truncate(interval).Add(interval)
+}
+
+func copyLabels(lbls labels.Labels) labels.Labels {
+	res := make(labels.Labels, 0, len(lbls))
+	for _, l := range lbls {
+		res = append(res, labels.Label{Name: strings.Clone(l.Name), Value: strings.Clone(l.Value)})
+	}
+	return res
+}
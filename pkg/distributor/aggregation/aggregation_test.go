@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package aggregation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestNewAggregator_Disabled(t *testing.T) {
+	assert.Nil(t, NewAggregator(Config{}, "distributor-1", nil, nil, log.NewNopLogger()))
+}
+
+func TestAggregator(t *testing.T) {
+	rules := validation.AggregationRules{
+		"requests_total":   {Without: []string{"pod"}, Interval: model.Duration(time.Minute), Counter: true},
+		"memory_bytes":     {Without: []string{"pod", "container"}, Interval: model.Duration(time.Minute), DropInput: true},
+		"temperature_degc": {Interval: model.Duration(time.Minute)},
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	pushed := map[string][]string{}
+	a := NewAggregator(Config{Enabled: true}, "distributor-1", func(ctx context.Context, req *mimirpb.WriteRequest) error {
+		userID, err := user.ExtractOrgID(ctx)
+		require.NoError(t, err)
+		for _, ts := range req.Timeseries {
+			for _, s := range ts.Samples {
+				pushed[userID] = append(pushed[userID], formatSample(ts.Labels, s))
+			}
+		}
+		return nil
+	}, reg, log.NewNopLogger()).(*aggregator)
+
+	start := time.Unix(600, 0)
+	in := []mimirpb.PreallocTimeseries{
+		series(start, 10, "requests_total", "pod", "a", "status", "200"),
+		series(start, 20, "requests_total", "pod", "b", "status", "200"),
+		series(start, 1, "memory_bytes", "container", "app", "pod", "a"),
+		series(start, 2, "memory_bytes", "container", "sidecar", "pod", "b"),
+		series(start, 20, "temperature_degc", "room", "kitchen"),
+		series(start, 1, "up", "pod", "a"),
+	}
+	out := a.aggregate(start, "user", rules, 0, in)
+	assert.Equal(t, []string{
+		`{__name__="requests_total", pod="a", status="200"} 10@600`,
+		`{__name__="requests_total", pod="b", status="200"} 20@600`,
+		`{__name__="temperature_degc", room="kitchen"} 20@600`,
+		`{__name__="up", pod="a"} 1@600`,
+	}, formatSeries(out))
+
+	// The counter's increase is only known from the second sample of each series, the counter of pod b has been reset.
+	next := start.Add(30 * time.Second)
+	a.aggregate(next, "user", rules, 0, []mimirpb.PreallocTimeseries{
+		series(next, 15, "requests_total", "pod", "a", "status", "200"),
+		series(next, 5, "requests_total", "pod", "b", "status", "200"),
+		series(next, 4, "memory_bytes", "container", "sidecar", "pod", "b"),
+	})
+
+	// Nothing gets pushed until the end of the interval.
+	a.flush(context.Background(), next)
+	assert.Empty(t, pushed)
+
+	a.flush(context.Background(), start.Add(time.Minute))
+	assert.ElementsMatch(t, []string{
+		`{__name__="memory_bytes:sum_without_pod_container", aggregator="distributor-1"} 5@660`,
+		`{__name__="requests_total:sum_without_pod", aggregator="distributor-1", status="200"} 10@660`,
+		`{__name__="temperature_degc:sum", aggregator="distributor-1", room="kitchen"} 20@660`,
+	}, pushed["user"])
+
+	// The gauge doesn't get a sample if its series didn't receive any sample during the interval, while the counter does.
+	pushed = map[string][]string{}
+	a.flush(context.Background(), start.Add(2*time.Minute))
+	assert.Equal(t, []string{
+		`{__name__="requests_total:sum_without_pod", aggregator="distributor-1", status="200"} 10@720`,
+	}, pushed["user"])
+
+	// The aggregated series are removed once all their series went stale.
+	a.flush(context.Background(), start.Add(time.Hour))
+	assert.Empty(t, a.series)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_aggregation_input_samples_total The total number of samples the distributor aggregated according to the aggregation rules.
+		# TYPE cortex_distributor_aggregation_input_samples_total counter
+		cortex_distributor_aggregation_input_samples_total{user="user"} 8
+
+		# HELP cortex_distributor_aggregation_output_samples_total The total number of samples of aggregated series the distributor pushed.
+		# TYPE cortex_distributor_aggregation_output_samples_total counter
+		cortex_distributor_aggregation_output_samples_total{user="user"} 5
+	`), "cortex_distributor_aggregation_input_samples_total", "cortex_distributor_aggregation_output_samples_total"))
+}
+
+func TestAggregator_MaxSeries(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	a := NewAggregator(Config{Enabled: true}, "distributor-1", func(context.Context, *mimirpb.WriteRequest) error {
+		return nil
+	}, reg, log.NewNopLogger()).(*aggregator)
+
+	now := time.Unix(600, 0)
+	rules := validation.AggregationRules{"memory_bytes": {Without: []string{"pod"}, Interval: model.Duration(time.Minute), DropInput: true}}
+	out := a.aggregate(now, "user", rules, 1, []mimirpb.PreallocTimeseries{
+		series(now, 1, "memory_bytes", "cluster", "one", "pod", "a"),
+		series(now, 2, "memory_bytes", "cluster", "one", "pod", "b"),
+		series(now, 3, "memory_bytes", "cluster", "two", "pod", "a"),
+	})
+
+	// The series which would create a new aggregated series over the limit are ingested instead of being aggregated.
+	assert.Equal(t, []string{`{__name__="memory_bytes", cluster="two", pod="a"} 3@600`}, formatSeries(out))
+	assert.Len(t, a.series["user"], 1)
+
+	// The limit is per user.
+	out = a.aggregate(now, "other", rules, 1, []mimirpb.PreallocTimeseries{series(now, 1, "memory_bytes", "cluster", "two", "pod", "a")})
+	assert.Empty(t, out)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_aggregation_skipped_samples_total The total number of samples the distributor didn't aggregate because the maximum number of aggregated series of the user has been reached.
+		# TYPE cortex_distributor_aggregation_skipped_samples_total counter
+		cortex_distributor_aggregation_skipped_samples_total{user="user"} 1
+	`), "cortex_distributor_aggregation_skipped_samples_total"))
+}
+
+func TestAggregator_PushFailure(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	a := NewAggregator(Config{Enabled: true}, "distributor-1", func(context.Context, *mimirpb.WriteRequest) error {
+		return errors.New("push failed")
+	}, reg, log.NewNopLogger()).(*aggregator)
+
+	now := time.Unix(600, 0)
+	rules := validation.AggregationRules{"memory_bytes": {Without: []string{"pod"}, Interval: model.Duration(time.Minute)}}
+	a.aggregate(now, "user", rules, 0, []mimirpb.PreallocTimeseries{series(now, 1, "memory_bytes", "pod", "a")})
+	a.flush(context.Background(), now.Add(time.Minute))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_aggregation_push_failures_total The total number of failed pushes of aggregated series.
+		# TYPE cortex_distributor_aggregation_push_failures_total counter
+		cortex_distributor_aggregation_push_failures_total{user="user"} 1
+	`), "cortex_distributor_aggregation_push_failures_total"))
+
+	a.DeleteMetricsForUser("user")
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(""), "cortex_distributor_aggregation_push_failures_total"))
+}
+
+func series(t time.Time, value float64, name string, lbls ...string) mimirpb.PreallocTimeseries {
+	ts := &mimirpb.TimeSeries{
+		Labels:  []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: name}},
+		Samples: []mimirpb.Sample{{TimestampMs: t.UnixMilli(), Value: value}},
+	}
+	for i := 0; i < len(lbls); i += 2 {
+		ts.Labels = append(ts.Labels, mimirpb.LabelAdapter{Name: lbls[i], Value: lbls[i+1]})
+	}
+	return mimirpb.PreallocTimeseries{TimeSeries: ts}
+}
+
+func formatSeries(series []mimirpb.PreallocTimeseries) []string {
+	var res []string
+	for _, ts := range series {
+		for _, s := range ts.Samples {
+			res = append(res, formatSample(ts.Labels, s))
+		}
+	}
+	return res
+}
+
+func formatSample(lbls []mimirpb.LabelAdapter, s mimirpb.Sample) string {
+	return mimirpb.FromLabelAdaptersToLabels(lbls).String() + " " + model.SampleValue(s.Value).String() + "@" + model.Time(s.TimestampMs).String()
+}
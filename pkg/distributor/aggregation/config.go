@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package aggregation
+
+import (
+	"flag"
+)
+
+type Config struct {
+	Enabled bool `yaml:"enabled" category:"experimental"`
+}
+
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.Enabled, "distributor.aggregation.enabled", false, "Enables the feature to aggregate the series of the metrics at ingestion, depending on the per-tenant aggregation rules.")
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package deadletter
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// maxMessageLength is the maximum length of the error message of an entry.
+const maxMessageLength = 1024
+
+// Entry is a series rejected at ingestion.
+type Entry struct {
+	// Time is when the series has been rejected.
+	Time time.Time `json:"time"`
+	// Series are the labels of the rejected series. They're empty if the series has been rejected by the ingesters,
+	// which only report the first rejected sample in the error message.
+	Series string `json:"series,omitempty"`
+	// SampleTimestamp is the timestamp of the first sample of the rejected series, in milliseconds.
+	SampleTimestamp int64  `json:"sampleTimestamp,omitempty"`
+	Reason          string `json:"reason"`
+	Message         string `json:"message"`
+	SourceIP        string `json:"sourceIP,omitempty"`
+}
+
+// Limits are the per-tenant limits of the dead letters.
+type Limits interface {
+	DeadLetterMaxEntries(userID string) int
+}
+
+// Buffer keeps the latest series rejected at ingestion for each tenant, up to the tenant's limit,
+// in a ring buffer.
+type Buffer struct {
+	limits Limits
+
+	mtx   sync.Mutex
+	users map[string]*ring
+
+	recordedEntries *prometheus.CounterVec
+}
+
+type ring struct {
+	entries []Entry
+	next    int
+}
+
+func NewBuffer(limits Limits, reg prometheus.Registerer) *Buffer {
+	return &Buffer{
+		limits: limits,
+		users:  map[string]*ring{},
+
+		recordedEntries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_dead_letter_entries_total",
+			Help: "The total number of series rejected at ingestion recorded in the dead letters.",
+		}, []string{"user", "reason"}),
+	}
+}
+
+// Enabled returns whether the series rejected for the user are recorded.
+func (b *Buffer) Enabled(userID string) bool {
+	return b.limits.DeadLetterMaxEntries(userID) > 0
+}
+
+// Record records the rejected series of the user, replacing the oldest one if the limit of entries is reached.
+func (b *Buffer) Record(userID string, e Entry) {
+	limit := b.limits.DeadLetterMaxEntries(userID)
+	if limit <= 0 {
+		return
+	}
+
+	if len(e.Message) > maxMessageLength {
+		e.Message = e.Message[:maxMessageLength]
+	}
+
+	b.mtx.Lock()
+	r, ok := b.users[userID]
+	if !ok {
+		r = &ring{}
+		b.users[userID] = r
+	}
+	r.add(e, limit)
+	b.mtx.Unlock()
+
+	b.recordedEntries.WithLabelValues(userID, e.Reason).Inc()
+}
+
+// Entries returns the series rejected for the user, from the oldest to the newest.
+func (b *Buffer) Entries(userID string) []Entry {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	r, ok := b.users[userID]
+	if !ok {
+		return nil
+	}
+	return r.list()
+}
+
+// DeleteUser removes the entries and the metrics of the user.
+func (b *Buffer) DeleteUser(userID string) {
+	b.mtx.Lock()
+	delete(b.users, userID)
+	b.mtx.Unlock()
+
+	b.recordedEntries.DeletePartialMatch(prometheus.Labels{"user": userID})
+}
+
+type entriesResponse struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Handler is the HTTP handler listing the series rejected for the tenant, from the newest to the oldest.
+// The entries can be filtered by reason with the "reason" parameter, and their number limited with the
+// "limit" parameter.
+//
+// The handler is per instance: it only lists the entries of this buffer, which are the series rejected by
+// this distributor, and doesn't fan out to the other distributors.
+func (b *Buffer) Handler(w http.ResponseWriter, r *http.Request) {
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if v := r.FormValue("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	reason := r.FormValue("reason")
+
+	entries := b.Entries(userID)
+	res := make([]Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		if reason != "" && entries[i].Reason != reason {
+			continue
+		}
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		res = append(res, entries[i])
+	}
+
+	util.WriteJSONResponse(w, entriesResponse{Entries: res})
+}
+
+// add adds the entry to the ring, which is resized if the limit has changed.
+func (r *ring) add(e Entry, limit int) {
+	if cap(r.entries) != limit {
+		entries := r.list()
+		if len(entries) > limit-1 {
+			entries = entries[len(entries)-limit+1:]
+		}
+		r.entries = append(make([]Entry, 0, limit), entries...)
+		r.next = 0
+	}
+
+	if len(r.entries) < limit {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % limit
+}
+
+func (r *ring) list() []Entry {
+	res := make([]Entry, 0, len(r.entries))
+	res = append(res, r.entries[r.next:]...)
+	return append(res, r.entries[:r.next]...)
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package deadletter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+)
+
+type limitsMock map[string]int
+
+func (l limitsMock) DeadLetterMaxEntries(userID string) int {
+	return l[userID]
+}
+
+func TestBuffer_Record(t *testing.T) {
+	limits := limitsMock{"user-1": 3}
+	b := NewBuffer(limits, nil)
+
+	assert.True(t, b.Enabled("user-1"))
+	assert.False(t, b.Enabled("user-2"))
+
+	for i := 0; i < 5; i++ {
+		b.Record("user-1", entry(i, "reason"))
+		b.Record("user-2", entry(i, "reason"))
+	}
+
+	// Only the latest entries are kept.
+	assert.Equal(t, []Entry{entry(2, "reason"), entry(3, "reason"), entry(4, "reason")}, b.Entries("user-1"))
+	assert.Empty(t, b.Entries("user-2"))
+
+	// The latest entries are kept when the limit changes.
+	limits["user-1"] = 2
+	b.Record("user-1", entry(5, "reason"))
+	assert.Equal(t, []Entry{entry(4, "reason"), entry(5, "reason")}, b.Entries("user-1"))
+
+	limits["user-1"] = 4
+	b.Record("user-1", entry(6, "reason"))
+	b.Record("user-1", entry(7, "reason"))
+	b.Record("user-1", entry(8, "reason"))
+	assert.Equal(t, []Entry{entry(5, "reason"), entry(6, "reason"), entry(7, "reason"), entry(8, "reason")}, b.Entries("user-1"))
+
+	// The long messages are truncated.
+	e := entry(9, "reason")
+	e.Message = strings.Repeat("a", 2*maxMessageLength)
+	b.Record("user-1", e)
+	entries := b.Entries("user-1")
+	assert.Len(t, entries[len(entries)-1].Message, maxMessageLength)
+
+	b.DeleteUser("user-1")
+	assert.Empty(t, b.Entries("user-1"))
+}
+
+func TestBuffer_Handler(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	b := NewBuffer(limitsMock{"user-1": 10}, reg)
+
+	for i := 0; i < 4; i++ {
+		b.Record("user-1", entry(i, fmt.Sprintf("reason_%d", i%2)))
+	}
+
+	for name, tc := range map[string]struct {
+		query            string
+		expectedStatus   int
+		expectedOrdinals []int
+	}{
+		"all entries": {
+			expectedStatus:   http.StatusOK,
+			expectedOrdinals: []int{3, 2, 1, 0},
+		},
+		"filtered by reason": {
+			query:            "?reason=reason_1",
+			expectedStatus:   http.StatusOK,
+			expectedOrdinals: []int{3, 1},
+		},
+		"limited": {
+			query:            "?limit=1",
+			expectedStatus:   http.StatusOK,
+			expectedOrdinals: []int{3},
+		},
+		"invalid limit": {
+			query:          "?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/dead_letters"+tc.query, nil)
+			req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+			resp := httptest.NewRecorder()
+			b.Handler(resp, req)
+
+			require.Equal(t, tc.expectedStatus, resp.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var res entriesResponse
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+			expected := []Entry{}
+			for _, i := range tc.expectedOrdinals {
+				expected = append(expected, entry(i, fmt.Sprintf("reason_%d", i%2)))
+			}
+			assert.Equal(t, expected, res.Entries)
+		})
+	}
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_dead_letter_entries_total The total number of series rejected at ingestion recorded in the dead letters.
+		# TYPE cortex_distributor_dead_letter_entries_total counter
+		cortex_distributor_dead_letter_entries_total{reason="reason_0",user="user-1"} 2
+		cortex_distributor_dead_letter_entries_total{reason="reason_1",user="user-1"} 2
+	`), "cortex_distributor_dead_letter_entries_total"))
+
+	b.DeleteUser("user-1")
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(""), "cortex_distributor_dead_letter_entries_total"))
+}
+
+func entry(i int, reason string) Entry {
+	return Entry{
+		Time:            time.Unix(int64(i), 0).UTC(),
+		Series:          fmt.Sprintf(`{__name__="series_%d"}`, i),
+		SampleTimestamp: int64(i * 1000),
+		Reason:          reason,
+		Message:         fmt.Sprintf("error %d", i),
+		SourceIP:        "10.0.0.1",
+	}
+}
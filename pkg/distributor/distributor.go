@@ -13,6 +13,7 @@ import (
 	"math"
 	"net/http"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -38,16 +39,22 @@ import (
 	"go.uber.org/atomic"
 	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	grpc_metadata "google.golang.org/grpc/metadata"
 
 	"github.com/grafana/dskit/tenant"
 
+	"github.com/grafana/mimir/pkg/distributor/aggregation"
+	"github.com/grafana/mimir/pkg/distributor/deadletter"
 	"github.com/grafana/mimir/pkg/distributor/forwarding"
 	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/ephemeral"
+	"github.com/grafana/mimir/pkg/util/extract"
 	"github.com/grafana/mimir/pkg/util/globalerror"
 	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
+	util_limiter "github.com/grafana/mimir/pkg/util/limiter"
 	util_math "github.com/grafana/mimir/pkg/util/math"
 	"github.com/grafana/mimir/pkg/util/push"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -93,6 +100,7 @@ type Distributor struct {
 	ingesterPool  *ring_client.Pool
 	limits        *validation.Overrides
 	forwarder     forwarding.Forwarder
+	aggregator    aggregation.Aggregator
 
 	// The global rate limiter requires a distributors ring to count
 	// the number of healthy instances
@@ -100,9 +108,15 @@ type Distributor struct {
 	distributorsRing       *ring.Ring
 	healthyInstancesCount  *atomic.Uint32
 
+	// Pool of the clients to the other distributors of the ring, which some push requests are routed to.
+	distributorPool *ring_client.Pool
+
 	// For handling HA replicas.
 	HATracker *haTracker
 
+	// Series rejected at ingestion.
+	DeadLetters *deadletter.Buffer
+
 	// Per-user rate limiters.
 	requestRateLimiter   *limiter.RateLimiter
 	ingestionRateLimiter *limiter.RateLimiter
@@ -139,17 +153,28 @@ type Distributor struct {
 	replicationFactor                prometheus.Gauge
 	latestSeenSampleTimestampPerUser *prometheus.GaugeVec
 
+	forwardedTenantSamples            *prometheus.CounterVec
 	discardedSamplesTooManyHaClusters *prometheus.CounterVec
 	discardedSamplesRateLimited       *prometheus.CounterVec
+	discardedNativeHistogramsDisabled *prometheus.CounterVec
 	discardedRequestsRateLimited      *prometheus.CounterVec
 	discardedExemplarsRateLimited     *prometheus.CounterVec
 	discardedMetadataRateLimited      *prometheus.CounterVec
 
+	// Limiter of the heaviest tenants when the ingesters are overloaded, nil if disabled.
+	adaptiveLimiter                       *adaptiveLimiter
+	discardedSamplesAdaptiveRateLimited   *prometheus.CounterVec
+	discardedExemplarsAdaptiveRateLimited *prometheus.CounterVec
+	discardedMetadataAdaptiveRateLimited  *prometheus.CounterVec
+
 	sampleValidationMetrics   *validation.SampleValidationMetrics
 	exemplarValidationMetrics *validation.ExemplarValidationMetrics
 	metadataValidationMetrics *validation.MetadataValidationMetrics
 
 	pushWithMiddlewares push.Func
+
+	// The push functions of the requests routed to this distributor, keyed by route.
+	routedPushFuncs map[string]push.Func
 }
 
 // Config contains the configuration required to
@@ -168,6 +193,9 @@ type Config struct {
 	// for testing and for extending the ingester by adding calls to the client
 	IngesterClientFactory ring_client.PoolFactory `yaml:"-"`
 
+	// for testing, the factory of the clients to the other distributors
+	DistributorClientFactory ring_client.PoolFactory `yaml:"-"`
+
 	// when true the distributor does not validate the label name, Mimir doesn't directly use
 	// this (and should never use it) but this feature is used by other projects built on top of it
 	SkipLabelNameValidation bool `yaml:"-"`
@@ -181,8 +209,18 @@ type Config struct {
 	// Configuration for forwarding of metrics to alternative ingestion endpoint.
 	Forwarding forwarding.Config
 
+	// Configuration for the aggregation of metrics at ingestion.
+	Aggregation aggregation.Config `yaml:"aggregation"`
+
+	// Configuration for the limiting of the heaviest tenants when the ingesters are overloaded.
+	AdaptiveLimiting AdaptiveLimitingConfig `yaml:"adaptive_limiting"`
+
 	// Enable the experimental feature to mark series as ephemeral.
 	EphemeralSeriesEnabled bool `yaml:"ephemeral_series_enabled" category:"experimental"`
+
+	// Configuration for the ingestion of the Influx line protocol and Graphite plaintext protocol.
+	Influx   push.InfluxConfig   `yaml:"influx"`
+	Graphite push.GraphiteConfig `yaml:"graphite"`
 }
 
 type InstanceLimits struct {
@@ -197,6 +235,10 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.HATrackerConfig.RegisterFlags(f)
 	cfg.DistributorRing.RegisterFlags(f, logger)
 	cfg.Forwarding.RegisterFlags(f)
+	cfg.Aggregation.RegisterFlags(f)
+	cfg.AdaptiveLimiting.RegisterFlags(f)
+	cfg.Influx.RegisterFlags(f)
+	cfg.Graphite.RegisterFlags(f)
 
 	f.IntVar(&cfg.MaxRecvMsgSize, "distributor.max-recv-msg-size", 100<<20, "Max message size in bytes that the distributors will accept for incoming push requests to the remote write API. If exceeded, the request will be rejected.")
 	f.DurationVar(&cfg.RemoteTimeout, "distributor.remote-timeout", 2*time.Second, "Timeout for downstream ingesters.")
@@ -217,6 +259,14 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 		return err
 	}
 
+	if err := cfg.Graphite.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.AdaptiveLimiting.Validate(); err != nil {
+		return err
+	}
+
 	return cfg.Forwarding.Validate()
 }
 
@@ -291,6 +341,11 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 			Name:      "distributor_received_metadata_total",
 			Help:      "The total number of received metadata, excluding rejected.",
 		}, []string{"user"}),
+		forwardedTenantSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_forward_tenant_samples_total",
+			Help:      "The total number of samples copied into other tenants of the cluster by the forwarding rules.",
+		}, []string{"user"}),
 		incomingRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Namespace: "cortex",
 			Name:      "distributor_requests_in_total",
@@ -358,6 +413,7 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 
 		discardedSamplesTooManyHaClusters: validation.DiscardedSamplesCounter(reg, validation.ReasonTooManyHAClusters),
 		discardedSamplesRateLimited:       validation.DiscardedSamplesCounter(reg, validation.ReasonRateLimited),
+		discardedNativeHistogramsDisabled: validation.DiscardedSamplesCounter(reg, validation.ReasonNativeHistogramsDisabled),
 		discardedRequestsRateLimited:      validation.DiscardedRequestsCounter(reg, validation.ReasonRateLimited),
 		discardedExemplarsRateLimited:     validation.DiscardedExemplarsCounter(reg, validation.ReasonRateLimited),
 		discardedMetadataRateLimited:      validation.DiscardedMetadataCounter(reg, validation.ReasonRateLimited),
@@ -365,6 +421,8 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		sampleValidationMetrics:   validation.NewSampleValidationMetrics(reg),
 		exemplarValidationMetrics: validation.NewExemplarValidationMetrics(reg),
 		metadataValidationMetrics: validation.NewMetadataValidationMetrics(reg),
+
+		DeadLetters: deadletter.NewBuffer(limits, reg),
 	}
 
 	promauto.With(reg).NewGauge(prometheus.GaugeOpts{
@@ -418,9 +476,23 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 			return nil, err
 		}
 
-		subservices = append(subservices, distributorsLifecycler, distributorsRing)
+		if cfg.DistributorClientFactory == nil {
+			requestDuration := promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+				Name:    "cortex_distributor_routing_client_request_duration_seconds",
+				Help:    "Time spent routing push requests to other distributors.",
+				Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+			}, []string{"operation", "status_code"})
+			cfg.DistributorClientFactory = func(addr string) (ring_client.PoolClient, error) {
+				return MakeDistributorClient(addr, clientConfig.GRPCClientConfig, requestDuration)
+			}
+		}
+		d.distributorPool = newDistributorPool(cfg.PoolConfig, distributorsRing, cfg.DistributorClientFactory, reg, log)
+
+		subservices = append(subservices, distributorsLifecycler, distributorsRing, d.distributorPool)
 		requestRateStrategy = newGlobalRateStrategy(newRequestRateStrategy(limits), d)
-		ingestionRateStrategy = newGlobalRateStrategy(newIngestionRateStrategy(limits), d)
+		// All the push requests of the tenants deduplicated per series by the HA tracker are routed to a single
+		// distributor, which enforces their whole ingestion rate limit.
+		ingestionRateStrategy = newHARoutedRateStrategy(newIngestionRateStrategy(limits), newGlobalRateStrategy(newIngestionRateStrategy(limits), d), limits)
 	}
 
 	d.requestRateLimiter = limiter.NewRateLimiter(requestRateStrategy, 10*time.Second)
@@ -438,7 +510,24 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		subservices = append(subservices, d.forwarder)
 	}
 
+	d.aggregator = aggregation.NewAggregator(cfg.Aggregation, cfg.DistributorRing.Common.InstanceID, d.pushAggregatedSeries, reg, log)
+	// The aggregator is an optional feature, if it's disabled then d.aggregator will be nil.
+	if d.aggregator != nil {
+		subservices = append(subservices, d.aggregator)
+	}
+
+	if cfg.AdaptiveLimiting.Enabled {
+		d.adaptiveLimiter = newAdaptiveLimiter(cfg.AdaptiveLimiting, limits, reg)
+		d.discardedSamplesAdaptiveRateLimited = validation.DiscardedSamplesCounter(reg, validation.ReasonAdaptiveRateLimited)
+		d.discardedExemplarsAdaptiveRateLimited = validation.DiscardedExemplarsCounter(reg, validation.ReasonAdaptiveRateLimited)
+		d.discardedMetadataAdaptiveRateLimited = validation.DiscardedMetadataCounter(reg, validation.ReasonAdaptiveRateLimited)
+	}
+
 	d.pushWithMiddlewares = d.GetPushFunc(nil)
+	d.routedPushFuncs = map[string]push.Func{
+		haTrackerRoute:   d.wrapRoutedPushWithMiddlewares(haTrackerRoute, d.push),
+		aggregationRoute: d.wrapRoutedPushWithMiddlewares(aggregationRoute, d.push),
+	}
 
 	subservices = append(subservices, d.ingesterPool, d.activeUsers)
 	d.subservices, err = services.NewManager(subservices...)
@@ -513,6 +602,9 @@ func (d *Distributor) running(ctx context.Context) error {
 
 		case <-ingestionRateTicker.C:
 			d.ingestionRate.Tick()
+			if d.adaptiveLimiter != nil {
+				d.adaptiveLimiter.tick(time.Now())
+			}
 
 		case err := <-d.subservicesWatcher.Chan():
 			return errors.Wrap(err, "distributor subservice failed")
@@ -535,11 +627,13 @@ func (d *Distributor) cleanupInactiveUser(userID string) {
 	d.incomingMetadata.DeleteLabelValues(userID)
 	d.nonHASamples.DeleteLabelValues(userID)
 	d.latestSeenSampleTimestampPerUser.DeleteLabelValues(userID)
+	d.forwardedTenantSamples.DeleteLabelValues(userID)
 
 	filter := prometheus.Labels{"user": userID}
 	d.dedupedSamples.DeletePartialMatch(filter)
 	d.discardedSamplesTooManyHaClusters.DeletePartialMatch(filter)
 	d.discardedSamplesRateLimited.DeletePartialMatch(filter)
+	d.discardedNativeHistogramsDisabled.DeletePartialMatch(filter)
 
 	d.discardedRequestsRateLimited.DeleteLabelValues(userID)
 	d.discardedExemplarsRateLimited.DeleteLabelValues(userID)
@@ -552,12 +646,26 @@ func (d *Distributor) cleanupInactiveUser(userID string) {
 	if d.forwarder != nil {
 		d.forwarder.DeleteMetricsForUser(userID)
 	}
+	if d.aggregator != nil {
+		d.aggregator.DeleteMetricsForUser(userID)
+	}
+	if d.adaptiveLimiter != nil {
+		d.adaptiveLimiter.deleteUser(userID)
+		d.discardedSamplesAdaptiveRateLimited.DeletePartialMatch(filter)
+		d.discardedExemplarsAdaptiveRateLimited.DeleteLabelValues(userID)
+		d.discardedMetadataAdaptiveRateLimited.DeleteLabelValues(userID)
+	}
+	d.DeadLetters.DeleteUser(userID)
 }
 
 func (d *Distributor) RemoveGroupMetricsForUser(userID, group string) {
 	d.dedupedSamples.DeleteLabelValues(userID, group)
 	d.discardedSamplesTooManyHaClusters.DeleteLabelValues(userID, group)
 	d.discardedSamplesRateLimited.DeleteLabelValues(userID, group)
+	d.discardedNativeHistogramsDisabled.DeleteLabelValues(userID, group)
+	if d.adaptiveLimiter != nil {
+		d.discardedSamplesAdaptiveRateLimited.DeleteLabelValues(userID, group)
+	}
 	d.sampleValidationMetrics.DeleteUserMetricsForGroup(userID, group)
 }
 
@@ -667,6 +775,22 @@ func (d *Distributor) validateSeries(nowt time.Time, ts mimirpb.PreallocTimeseri
 		}
 	}
 
+	if len(ts.Histograms) > 0 && !d.limits.NativeHistogramsIngestionEnabled(userID) {
+		d.discardedNativeHistogramsDisabled.WithLabelValues(userID, group).Add(float64(len(ts.Histograms)))
+		ts.Histograms = ts.Histograms[:0]
+	}
+
+	for _, h := range ts.Histograms {
+		delta := now - model.Time(h.Timestamp)
+		if delta > 0 {
+			d.sampleDelayHistogram.Observe(float64(delta) / 1000)
+		}
+
+		if err := validation.ValidateSampleHistogram(d.sampleValidationMetrics, now, d.limits, userID, group, ts.Labels, h); err != nil {
+			return err
+		}
+	}
+
 	if d.limits.MaxGlobalExemplarsPerUser(userID) == 0 {
 		mimirpb.ClearExemplars(ts.TimeSeries)
 		return nil
@@ -707,6 +831,7 @@ func (d *Distributor) wrapPushWithMiddlewares(externalMiddleware func(next push.
 	middlewares = append(middlewares, d.prePushHaDedupeMiddleware)
 	middlewares = append(middlewares, d.prePushRelabelMiddleware)
 	middlewares = append(middlewares, d.prePushValidationMiddleware)
+	middlewares = append(middlewares, d.prePushAggregationMiddleware)
 	middlewares = append(middlewares, d.prePushForwardingMiddleware)
 	middlewares = append(middlewares, d.prePushEphemeralMiddleware)
 	if externalMiddleware != nil {
@@ -739,6 +864,18 @@ func (d *Distributor) prePushHaDedupeMiddleware(next push.Func) push.Func {
 			return nil, err
 		}
 
+		// The samples received from the elected replicas are tracked by the distributor which deduplicates them, so
+		// all the push requests of the tenants deduplicated per series are routed to the same distributor.
+		if d.perSeriesHADedupEnabled(userID) && !isRoutedRequest(ctx, haTrackerRoute) {
+			addr, self, err := d.routeOwner(shardByUser(userID))
+			if err != nil {
+				return nil, err
+			}
+			if !self {
+				return d.routePush(ctx, addr, haTrackerRoute, req)
+			}
+		}
+
 		if len(req.Timeseries) == 0 || !d.limits.AcceptHASamples(userID) {
 			cleanupInDefer = false
 			return next(ctx, pushReq)
@@ -758,11 +895,14 @@ func (d *Distributor) prePushHaDedupeMiddleware(next push.Func) push.Func {
 		numSamples := 0
 		group := d.activeGroups.UpdateActiveGroupTimestamp(userID, validation.GroupLabel(d.limits, userID, req.Timeseries), time.Now())
 		for _, ts := range req.Timeseries {
-			numSamples += len(ts.Samples)
+			numSamples += len(ts.Samples) + len(ts.Histograms)
 		}
 
 		removeReplica, err := d.checkSample(ctx, userID, cluster, replica)
-		if err != nil {
+		// With the per-series deduplication, the samples of a non-elected replica are accepted where they fill
+		// the gaps of the elected replica.
+		fillGaps := errors.Is(err, replicasNotMatchError{}) && d.perSeriesHADedupEnabled(userID)
+		if err != nil && !fillGaps {
 			if errors.Is(err, replicasNotMatchError{}) {
 				// These samples have been deduped.
 				d.dedupedSamples.WithLabelValues(userID, cluster).Add(float64(numSamples))
@@ -777,7 +917,7 @@ func (d *Distributor) prePushHaDedupeMiddleware(next push.Func) push.Func {
 			return nil, err
 		}
 
-		if removeReplica {
+		if removeReplica || fillGaps {
 			// If we found both the cluster and replica labels, we only want to include the cluster label when
 			// storing series in Mimir. If we kept the replica label we would end up with another series for the same
 			// series we're trying to dedupe when HA tracking moves over to a different replica.
@@ -789,11 +929,33 @@ func (d *Distributor) prePushHaDedupeMiddleware(next push.Func) push.Func {
 			d.nonHASamples.WithLabelValues(userID).Add(float64(numSamples))
 		}
 
+		if fillGaps {
+			var deduped int
+			req.Timeseries, deduped = d.HATracker.fillElectedReplicaGaps(userID, cluster, replica, req.Timeseries)
+			d.dedupedSamples.WithLabelValues(userID, cluster).Add(float64(deduped))
+			if len(req.Timeseries) == 0 {
+				return nil, httpgrpc.Errorf(http.StatusAccepted, err.Error())
+			}
+			// The metadata is only accepted from the elected replica.
+			req.Metadata = nil
+		} else if removeReplica && d.perSeriesHADedupEnabled(userID) {
+			d.HATracker.trackElectedReplicaSamples(userID, cluster, req.Timeseries)
+		}
+
 		cleanupInDefer = false
 		return next(ctx, pushReq)
 	}
 }
 
+// perSeriesHADedupEnabled returns whether the HA tracker deduplicates the samples of the user per series.
+func (d *Distributor) perSeriesHADedupEnabled(userID string) bool {
+	return perSeriesHADedupEnabled(d.limits, userID)
+}
+
+func perSeriesHADedupEnabled(limits *validation.Overrides, userID string) bool {
+	return limits.AcceptHASamples(userID) && limits.HAPerSeriesDedupWindow(userID) > 0
+}
+
 func (d *Distributor) prePushRelabelMiddleware(next push.Func) push.Func {
 	return func(ctx context.Context, pushReq *push.Request) (*mimirpb.WriteResponse, error) {
 		cleanupInDefer := true
@@ -896,6 +1058,10 @@ func (d *Distributor) prePushValidationMiddleware(next push.Func) push.Func {
 				earliestSampleTimestampMs = util_math.Min64(earliestSampleTimestampMs, s.TimestampMs)
 				latestSampleTimestampMs = util_math.Max64(latestSampleTimestampMs, s.TimestampMs)
 			}
+			for _, h := range ts.Histograms {
+				earliestSampleTimestampMs = util_math.Min64(earliestSampleTimestampMs, h.Timestamp)
+				latestSampleTimestampMs = util_math.Max64(latestSampleTimestampMs, h.Timestamp)
+			}
 		}
 		// Update this metric even in case of errors.
 		if latestSampleTimestampMs > 0 {
@@ -932,11 +1098,12 @@ func (d *Distributor) prePushValidationMiddleware(next push.Func) push.Func {
 					// use case because we format it calling Error() and then we discard it.
 					firstPartialErr = httpgrpc.Errorf(http.StatusBadRequest, validationErr.Error())
 				}
+				d.recordDeadLetter(ctx, userID, ts.TimeSeries, validationErr)
 				removeIndexes = append(removeIndexes, tsIdx)
 				continue
 			}
 
-			validatedSamples += len(ts.Samples)
+			validatedSamples += len(ts.Samples) + len(ts.Histograms)
 			validatedExemplars += len(ts.Exemplars)
 		}
 		if len(removeIndexes) > 0 {
@@ -980,6 +1147,15 @@ func (d *Distributor) prePushValidationMiddleware(next push.Func) push.Func {
 			return nil, httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewIngestionRateLimitedError(d.limits.IngestionRate(userID), d.limits.IngestionBurstSize(userID)).Error())
 		}
 
+		// The heaviest tenants are limited when the ingesters are overloaded, instead of the ingesters
+		// rejecting the push requests of all the tenants.
+		if d.adaptiveLimiter != nil && !d.adaptiveLimiter.AllowN(now, userID, totalN) {
+			d.discardedSamplesAdaptiveRateLimited.WithLabelValues(userID, group).Add(float64(validatedSamples))
+			d.discardedExemplarsAdaptiveRateLimited.WithLabelValues(userID).Add(float64(validatedExemplars))
+			d.discardedMetadataAdaptiveRateLimited.WithLabelValues(userID).Add(float64(validatedMetadata))
+			return nil, httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewAdaptiveIngestionRateLimitedError(d.adaptiveLimiter.Limit(userID)).Error())
+		}
+
 		// totalN included samples, exemplars and metadata. Ingester follows this pattern when computing its ingestion rate.
 		d.ingestionRate.Add(int64(totalN))
 
@@ -994,6 +1170,67 @@ func (d *Distributor) prePushValidationMiddleware(next push.Func) push.Func {
 	}
 }
 
+// prePushAggregationMiddleware is used as push.Func middleware in front of push method.
+// It aggregates the time series matching the aggregation rules, and drops them if the rules say so.
+// The time series of each metric are aggregated by the distributor in charge of the metric, which the
+// time series are routed to.
+func (d *Distributor) prePushAggregationMiddleware(next push.Func) push.Func {
+	if d.aggregator == nil {
+		// Aggregation is disabled, no need to wrap "next".
+		return next
+	}
+
+	return func(ctx context.Context, pushReq *push.Request) (*mimirpb.WriteResponse, error) {
+		cleanupInDefer := true
+		defer func() {
+			if cleanupInDefer {
+				pushReq.CleanUp()
+			}
+		}()
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		rules := d.limits.AggregationRules(userID)
+		if len(rules) == 0 {
+			cleanupInDefer = false
+			return next(ctx, pushReq)
+		}
+
+		req, err := pushReq.WriteRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		var routedErr error
+		if !isRoutedRequest(ctx, aggregationRoute) {
+			req.Timeseries, routedErr, err = d.routeAggregatedSeries(ctx, userID, rules, req.Source, req.Timeseries)
+			if err != nil {
+				return nil, err
+			}
+		}
+		req.Timeseries = d.aggregator.Aggregate(userID, rules, d.limits.MaxAggregatedSeriesPerUser(userID), req.Timeseries)
+
+		cleanupInDefer = false
+		res, err := next(ctx, pushReq)
+		if err != nil {
+			// Errors resulting from the pushing of the series of this distributor have priority.
+			return nil, err
+		}
+
+		return res, routedErr
+	}
+}
+
+// pushAggregatedSeries pushes the series aggregated by the aggregator through all the distributor's middlewares,
+// so that they are subject to the limits of the tenant like any other series.
+func (d *Distributor) pushAggregatedSeries(ctx context.Context, req *mimirpb.WriteRequest) error {
+	_, err := d.Push(ctx, req)
+	return err
+}
+
 // prePushForwardingMiddleware is used as push.Func middleware in front of push method.
 // It forwards time series to configured remote_write endpoints if the forwarding rules say so.
 func (d *Distributor) prePushForwardingMiddleware(next push.Func) push.Func {
@@ -1003,6 +1240,11 @@ func (d *Distributor) prePushForwardingMiddleware(next push.Func) push.Func {
 	}
 
 	return func(ctx context.Context, pushReq *push.Request) (*mimirpb.WriteResponse, error) {
+		if isCopiedSeriesRequest(ctx) {
+			// The series copied into other tenants aren't forwarded again.
+			return next(ctx, pushReq)
+		}
+
 		userID, err := tenant.TenantID(ctx)
 		if err != nil {
 			return nil, err
@@ -1116,7 +1358,7 @@ func (d *Distributor) metricsMiddleware(next push.Func) push.Func {
 		numSamples := 0
 		numExemplars := 0
 		for _, ts := range req.Timeseries {
-			numSamples += len(ts.Samples)
+			numSamples += len(ts.Samples) + len(ts.Histograms)
 			numExemplars += len(ts.Exemplars)
 		}
 
@@ -1195,7 +1437,17 @@ func (d *Distributor) forwardSamples(ctx context.Context, userID string, ts []mi
 	forwardingErrCh := make(chan error)
 	forwardingRules := d.limits.ForwardingRules(userID)
 	endpoint := d.limits.ForwardingEndpoint(userID)
+	if len(forwardingRules) == 0 {
+		close(forwardingErrCh)
+		return ts, forwardingErrCh
+	}
+
+	// The series copied into other tenants aren't forwarded to the endpoint.
+	ts, forwardingRules, tenantsErrCh := d.copySeriesToTenants(ctx, userID, forwardingRules, ts)
 	if endpoint == "" || len(forwardingRules) == 0 {
+		if tenantsErrCh != nil {
+			return ts, tenantsErrCh
+		}
 		close(forwardingErrCh)
 		return ts, forwardingErrCh
 	}
@@ -1211,9 +1463,159 @@ func (d *Distributor) forwardSamples(ctx context.Context, userID string, ts []mi
 	// The cleanup func will cleanup the new slice, it's the forwarders responsibility to return the old one to the pool.
 	ts, forwardingErrCh = d.forwarder.Forward(ctx, endpoint, dropSamplesBeforeTimestamp, forwardingRules, ts, userID)
 
+	if tenantsErrCh != nil {
+		return ts, mergeErrChannels(tenantsErrCh, forwardingErrCh)
+	}
 	return ts, forwardingErrCh
 }
 
+type contextKey int
+
+const (
+	// copiedSeriesRequest marks the context of the requests pushing the series copied into other tenants.
+	copiedSeriesRequest contextKey = 1
+
+	// routedRequest is the key of the route of the requests routed to this distributor by another distributor.
+	routedRequest contextKey = 2
+)
+
+// isCopiedSeriesRequest returns whether the request pushes the series copied into another tenant.
+func isCopiedSeriesRequest(ctx context.Context) bool {
+	copied, _ := ctx.Value(copiedSeriesRequest).(bool)
+	return copied
+}
+
+// copySeriesToTenants copies the series of the metrics whose forwarding rule has a tenant into that tenant, and
+// removes them from the given series unless the rule ingests them. The copies are pushed through all the
+// distributor's middlewares except the forwarding one, so that they're subject to the rate limits and the
+// validation of the tenant they're copied into, like any other series of that tenant.
+//
+// It returns the remaining series, the forwarding rules of the other metrics, and a chan of errors which gets closed
+// once the copies have been pushed, nil if no series has been copied.
+func (d *Distributor) copySeriesToTenants(ctx context.Context, userID string, rules validation.ForwardingRules, ts []mimirpb.PreallocTimeseries) ([]mimirpb.PreallocTimeseries, validation.ForwardingRules, chan error) {
+	copying := false
+	for _, rule := range rules {
+		if rule.Tenant != "" {
+			copying = true
+			break
+		}
+	}
+	if !copying {
+		return ts, rules, nil
+	}
+
+	endpointRules := make(validation.ForwardingRules, len(rules))
+	for metric, rule := range rules {
+		if rule.Tenant == "" {
+			endpointRules[metric] = rule
+		}
+	}
+
+	var (
+		reqs          = map[string]*mimirpb.WriteRequest{}
+		removeIndexes []int
+		samples       int
+	)
+	for idx, series := range ts {
+		metric, err := extract.UnsafeMetricNameFromLabelAdapters(series.Labels)
+		if err != nil {
+			continue
+		}
+		rule, ok := rules[metric]
+		if !ok || rule.Tenant == "" {
+			continue
+		}
+
+		req, ok := reqs[rule.Tenant]
+		if !ok {
+			req = &mimirpb.WriteRequest{Source: mimirpb.API}
+			reqs[rule.Tenant] = req
+		}
+		req.Timeseries = append(req.Timeseries, copySeriesWithLabels(series, rule.Labels))
+		samples += len(series.Samples) + len(series.Histograms)
+
+		if !rule.Ingest {
+			removeIndexes = append(removeIndexes, idx)
+		}
+	}
+
+	if len(removeIndexes) > 0 {
+		for _, idx := range removeIndexes {
+			mimirpb.ReusePreallocTimeseries(&ts[idx])
+		}
+		ts = util.RemoveSliceIndexes(ts, removeIndexes)
+	}
+	if len(reqs) == 0 {
+		return ts, endpointRules, nil
+	}
+	d.forwardedTenantSamples.WithLabelValues(userID).Add(float64(samples))
+
+	errCh := make(chan error, len(reqs))
+	go func() {
+		defer close(errCh)
+
+		for tenantID, req := range reqs {
+			// The copies are routed like any other request of the tenant they're copied into.
+			tenantCtx := context.WithValue(user.InjectOrgID(ctx, tenantID), copiedSeriesRequest, true)
+			tenantCtx = context.WithValue(tenantCtx, routedRequest, "")
+			_, err := d.pushWithMiddlewares(tenantCtx, push.NewParsedRequest(req))
+			if err == nil {
+				continue
+			}
+			level.Warn(d.log).Log("msg", "failed to copy series into tenant", "user", userID, "tenant", tenantID, "err", err)
+			if d.cfg.Forwarding.PropagateErrors {
+				errCh <- err
+			}
+		}
+	}()
+	return ts, endpointRules, errCh
+}
+
+// copySeriesWithLabels returns a copy of the series, without exemplars, with the given labels added. The copy doesn't
+// reference the request buffer.
+func copySeriesWithLabels(series mimirpb.PreallocTimeseries, add map[string]string) mimirpb.PreallocTimeseries {
+	b := labels.NewBuilder(mimirpb.FromLabelAdaptersToLabels(series.Labels))
+	for name, value := range add {
+		b.Set(name, value)
+	}
+	lbls := b.Labels(nil)
+
+	ts := &mimirpb.TimeSeries{
+		Labels:     make([]mimirpb.LabelAdapter, 0, len(lbls)),
+		Samples:    append([]mimirpb.Sample(nil), series.Samples...),
+		Histograms: append([]mimirpb.Histogram(nil), series.Histograms...),
+	}
+	for _, l := range lbls {
+		ts.Labels = append(ts.Labels, mimirpb.LabelAdapter{Name: strings.Clone(l.Name), Value: strings.Clone(l.Value)})
+	}
+	return mimirpb.PreallocTimeseries{TimeSeries: ts}
+}
+
+// mergeErrChannels returns a chan of the errors of both chans, which gets closed once both are closed.
+func mergeErrChannels(a, b <-chan error) chan error {
+	merged := make(chan error, 2)
+	go func() {
+		defer close(merged)
+		for a != nil || b != nil {
+			select {
+			case err, ok := <-a:
+				if !ok {
+					a = nil
+					continue
+				}
+				merged <- err
+			case err, ok := <-b:
+				if !ok {
+					b = nil
+					continue
+				}
+				merged <- err
+			}
+		}
+	}()
+	return merged
+}
+
 // Push is gRPC method registered as client.IngesterServer and distributor.DistributorServer.
 func (d *Distributor) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error) {
 	pushReq := push.NewParsedRequest(req)
@@ -1222,6 +1624,14 @@ func (d *Distributor) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mim
 		mimirpb.ReuseSlice(req.EphemeralTimeseries)
 	})
 
+	// The requests routed by another distributor have already been through the middlewares preceding the one of
+	// their route on that distributor.
+	if route := routeFromIncomingContext(ctx); route != "" {
+		if pushFn, ok := d.routedPushFuncs[route]; ok {
+			return pushFn(context.WithValue(ctx, routedRequest, route), pushReq)
+		}
+	}
+
 	return d.pushWithMiddlewares(ctx, pushReq)
 }
 
@@ -1331,11 +1741,40 @@ func (d *Distributor) push(ctx context.Context, pushReq *push.Request) (*mimirpb
 	}, func() { pushReq.CleanUp(); cancel() })
 
 	if err != nil {
+		if resp, ok := httpgrpc.HTTPResponseFromError(err); ok && resp.Code/100 == 4 {
+			// The series have been rejected by the ingesters, which only report the first rejected sample.
+			d.recordDeadLetter(ctx, userID, nil, errors.New(string(resp.Body)))
+		}
 		return nil, err
 	}
 	return &mimirpb.WriteResponse{}, nil
 }
 
+// recordDeadLetter records the series rejected with the given error in the dead letters of the user, if enabled.
+// The series is nil if it's unknown.
+func (d *Distributor) recordDeadLetter(ctx context.Context, userID string, ts *mimirpb.TimeSeries, err error) {
+	if !d.DeadLetters.Enabled(userID) {
+		return
+	}
+
+	e := deadletter.Entry{
+		Time:     time.Now(),
+		Reason:   validation.DiscardReasonFromError(err),
+		Message:  err.Error(),
+		SourceIP: util.GetSourceIPsFromOutgoingCtx(ctx),
+	}
+	if ts != nil {
+		// The labels are formatted into a new string, so the entry doesn't retain the request buffer.
+		e.Series = mimirpb.FromLabelAdaptersToLabels(ts.Labels).String()
+		if len(ts.Samples) > 0 {
+			e.SampleTimestamp = ts.Samples[0].TimestampMs
+		} else if len(ts.Histograms) > 0 {
+			e.SampleTimestamp = ts.Histograms[0].Timestamp
+		}
+	}
+	d.DeadLetters.Record(userID, e)
+}
+
 func preallocSliceIfNeeded[T any](size int) []T {
 	if size > 0 {
 		return make([]T, 0, size)
@@ -1358,7 +1797,7 @@ func (d *Distributor) getTokensForSeries(userID string, series []mimirpb.Preallo
 func (d *Distributor) updateReceivedMetrics(req *mimirpb.WriteRequest, userID string) {
 	var receivedSamples, receivedExemplars, receivedMetadata int
 	for _, ts := range req.Timeseries {
-		receivedSamples += len(ts.TimeSeries.Samples)
+		receivedSamples += len(ts.TimeSeries.Samples) + len(ts.TimeSeries.Histograms)
 		receivedExemplars += len(ts.TimeSeries.Exemplars)
 	}
 	receivedMetadata = len(req.Metadata)
@@ -1407,7 +1846,18 @@ func (d *Distributor) send(ctx context.Context, ingester ring.InstanceDesc, time
 		Source:              source,
 		EphemeralTimeseries: ephemeral,
 	}
-	_, err = c.Push(ctx, &req)
+	if d.adaptiveLimiter == nil {
+		_, err = c.Push(ctx, &req)
+	} else {
+		// The push pressure is reported in the headers, which are received in the trailers on errors.
+		var header, trailer grpc_metadata.MD
+		_, err = c.Push(ctx, &req, grpc.Header(&header), grpc.Trailer(&trailer))
+		if pressure, ok := ingester_client.PushPressureFromMetadata(grpc_metadata.Join(header, trailer)); ok {
+			if userID, err := tenant.TenantID(ctx); err == nil {
+				d.adaptiveLimiter.observePressure(userID, ingester.Addr, pressure, time.Now())
+			}
+		}
+	}
 	if resp, ok := httpgrpc.HTTPResponseFromError(err); ok {
 		// Wrap HTTP gRPC error with more explanatory message.
 		return httpgrpc.Errorf(int(resp.Code), "failed pushing to ingester: %s", resp.Body)
@@ -1728,6 +2178,77 @@ func (cm *labelValuesCardinalityConcurrentMap) toLabelValuesCardinalityResponse(
 	}
 }
 
+// ActiveGrowth queries the ingesters for the limit metric names and label pairs for which the most series have been
+// created within their series growth tracking window. The numbers of created series are summed across the ingesters
+// and adjusted to the replication factor. Since each ingester only returns its own top items, the result is an
+// approximation when the items are not evenly distributed across the ingesters.
+func (d *Distributor) ActiveGrowth(ctx context.Context, limit int) (*ingester_client.ActiveGrowthResponse, error) {
+	replicationSet, err := d.GetIngesters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure we get a successful response from all the ingesters
+	replicationSet.MaxErrors = 0
+	replicationSet.MaxUnavailableZones = 0
+
+	req := &ingester_client.ActiveGrowthRequest{Limit: int32(limit)}
+	resps, err := d.forReplicationSet(ctx, replicationSet, func(ctx context.Context, client ingester_client.IngesterClient) (interface{}, error) {
+		return client.ActiveGrowth(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metricNames := map[seriesGrowthKey]uint64{}
+	labelPairs := map[seriesGrowthKey]uint64{}
+	for _, resp := range resps {
+		r := resp.(*ingester_client.ActiveGrowthResponse)
+		for _, item := range r.MetricNames {
+			metricNames[seriesGrowthKey{item.LabelName, item.LabelValue}] += item.CreatedSeries
+		}
+		for _, item := range r.LabelPairs {
+			labelPairs[seriesGrowthKey{item.LabelName, item.LabelValue}] += item.CreatedSeries
+		}
+	}
+
+	replicationFactor := uint64(d.ingestersRing.ReplicationFactor())
+	return &ingester_client.ActiveGrowthResponse{
+		MetricNames: topSeriesGrowth(metricNames, replicationFactor, limit),
+		LabelPairs:  topSeriesGrowth(labelPairs, replicationFactor, limit),
+	}, nil
+}
+
+type seriesGrowthKey struct {
+	labelName, labelValue string
+}
+
+// topSeriesGrowth adjusts the numbers of created series to the replication factor, and returns the limit items with
+// the most created series.
+func topSeriesGrowth(counts map[seriesGrowthKey]uint64, replicationFactor uint64, limit int) []*ingester_client.SeriesGrowth {
+	items := make([]*ingester_client.SeriesGrowth, 0, len(counts))
+	for key, count := range counts {
+		if count /= replicationFactor; count == 0 {
+			continue
+		}
+		items = append(items, &ingester_client.SeriesGrowth{LabelName: key.labelName, LabelValue: key.labelValue, CreatedSeries: count})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CreatedSeries != items[j].CreatedSeries {
+			return items[i].CreatedSeries > items[j].CreatedSeries
+		}
+		if items[i].LabelName != items[j].LabelName {
+			return items[i].LabelName < items[j].LabelName
+		}
+		return items[i].LabelValue < items[j].LabelValue
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
 // LabelNames returns all of the label names.
 func (d *Distributor) LabelNames(ctx context.Context, from, to model.Time, matchers ...*labels.Matcher) ([]string, error) {
 	replicationSet, err := d.GetIngesters(ctx)
@@ -1798,6 +2319,71 @@ func (d *Distributor) MetricsForLabelMatchers(ctx context.Context, from, through
 	return result, nil
 }
 
+// ActiveSeries queries the ingesters for the series which received samples within their active series idle timeout
+// and match the matchers, and returns them deduplicated across the replicas and sorted.
+func (d *Distributor) ActiveSeries(ctx context.Context, matchers []*labels.Matcher) ([]labels.Labels, error) {
+	replicationSet, err := d.GetIngesters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matchersProto, err := ingester_client.ToLabelMatchers(matchers)
+	if err != nil {
+		return nil, err
+	}
+	req := &ingester_client.ActiveSeriesRequest{Matchers: matchersProto}
+
+	// The series are deduplicated across the ingesters by the limiter, which fails once the number of series
+	// exceeds the maximum number of series fetched per query.
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	queryLimiter := util_limiter.NewQueryLimiter(d.limits.MaxFetchedSeriesPerQuery(userID), 0, 0)
+
+	resps, err := d.forReplicationSet(ctx, replicationSet, func(ctx context.Context, client ingester_client.IngesterClient) (interface{}, error) {
+		stream, err := client.ActiveSeries(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = stream.CloseSend() }()
+
+		var series []labels.Labels
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			for _, m := range resp.Metric {
+				if limitErr := queryLimiter.AddSeries(m.Labels); limitErr != nil {
+					return nil, httpgrpc.Errorf(http.StatusUnprocessableEntity, limitErr.Error())
+				}
+				series = append(series, mimirpb.FromLabelAdaptersToLabelsWithCopy(m.Labels))
+			}
+		}
+		return series, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := map[uint64]labels.Labels{}
+	for _, resp := range resps {
+		for _, m := range resp.([]labels.Labels) {
+			metrics[labels.StableHash(m)] = m
+		}
+	}
+
+	result := make([]labels.Labels, 0, len(metrics))
+	for _, m := range metrics {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool { return labels.Compare(result[i], result[j]) < 0 })
+	return result, nil
+}
+
 // MetricsMetadata returns all metric metadata of a user.
 func (d *Distributor) MetricsMetadata(ctx context.Context) ([]scrape.MetricMetadata, error) {
 	replicationSet, err := d.GetIngesters(ctx)
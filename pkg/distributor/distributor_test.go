@@ -43,13 +43,16 @@ import (
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	grpc_metadata "google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/grafana/mimir/pkg/distributor/distributorpb"
 	"github.com/grafana/mimir/pkg/distributor/forwarding"
 	"github.com/grafana/mimir/pkg/ingester"
 	"github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/chunk"
+	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/chunkcompat"
 	"github.com/grafana/mimir/pkg/util/ephemeral"
 	"github.com/grafana/mimir/pkg/util/extract"
@@ -2805,6 +2808,158 @@ func TestDistributor_LabelValuesCardinality_Concurrency(t *testing.T) {
 	})
 }
 
+func TestDistributor_ActiveGrowth(t *testing.T) {
+	const numIngesters = 3
+
+	fixtures := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "test_1", "status", "200"),
+		labels.FromStrings(labels.MetricName, "test_1", "status", "500"),
+		labels.FromStrings(labels.MetricName, "test_2"),
+	}
+
+	tests := map[string]struct {
+		limit          int
+		expectedResult *client.ActiveGrowthResponse
+	}{
+		"should return the metric names and label pairs sorted by the number of created series": {
+			limit: 10,
+			expectedResult: &client.ActiveGrowthResponse{
+				MetricNames: []*client.SeriesGrowth{
+					{LabelName: labels.MetricName, LabelValue: "test_1", CreatedSeries: 2},
+					{LabelName: labels.MetricName, LabelValue: "test_2", CreatedSeries: 1},
+				},
+				LabelPairs: []*client.SeriesGrowth{
+					{LabelName: "status", LabelValue: "200", CreatedSeries: 1},
+					{LabelName: "status", LabelValue: "500", CreatedSeries: 1},
+				},
+			},
+		},
+		"should return the limit metric names and label pairs with the most created series": {
+			limit: 1,
+			expectedResult: &client.ActiveGrowthResponse{
+				MetricNames: []*client.SeriesGrowth{
+					{LabelName: labels.MetricName, LabelValue: "test_1", CreatedSeries: 2},
+				},
+				LabelPairs: []*client.SeriesGrowth{
+					{LabelName: "status", LabelValue: "200", CreatedSeries: 1},
+				},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ds, ingesters, _ := prepare(t, prepConfig{
+				numIngesters:      numIngesters,
+				happyIngesters:    numIngesters,
+				numDistributors:   1,
+				replicationFactor: 3,
+			})
+
+			ctx := user.InjectOrgID(context.Background(), "active-growth")
+			for _, series := range fixtures {
+				_, err := ds[0].Push(ctx, mockWriteRequest(series, 1, 100000))
+				require.NoError(t, err)
+			}
+
+			// The final ingester may not have received the series yet when Push() returns.
+			test.Poll(t, time.Second, testData.expectedResult, func() interface{} {
+				res, err := ds[0].ActiveGrowth(ctx, testData.limit)
+				require.NoError(t, err)
+				return res
+			})
+
+			assert.Equal(t, numIngesters, countMockIngestersCalls(ingesters, "ActiveGrowth"))
+		})
+	}
+
+	t.Run("should fail with an error if at least one ingester fails", func(t *testing.T) {
+		ds, ingesters, _ := prepare(t, prepConfig{
+			numIngesters:    numIngesters,
+			happyIngesters:  numIngesters,
+			numDistributors: 1,
+		})
+		ingesters[0].happy = false
+
+		_, err := ds[0].ActiveGrowth(user.InjectOrgID(context.Background(), "active-growth"), 10)
+		require.Error(t, err)
+	})
+}
+
+func TestDistributor_ActiveSeries(t *testing.T) {
+	const numIngesters = 3
+
+	fixtures := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "test_1", "status", "500"),
+		labels.FromStrings(labels.MetricName, "test_1", "status", "200"),
+		labels.FromStrings(labels.MetricName, "test_2"),
+	}
+
+	ds, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:      numIngesters,
+		happyIngesters:    numIngesters,
+		numDistributors:   1,
+		replicationFactor: 3,
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "active-series")
+	for _, series := range fixtures {
+		_, err := ds[0].Push(ctx, mockWriteRequest(series, 1, 100000))
+		require.NoError(t, err)
+	}
+
+	// The series are deduplicated across the ingesters, and sorted.
+	// The final ingester may not have received the series yet when Push() returns.
+	test.Poll(t, time.Second, []labels.Labels{fixtures[1], fixtures[0]}, func() interface{} {
+		series, err := ds[0].ActiveSeries(ctx, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "test_1")})
+		require.NoError(t, err)
+		return series
+	})
+
+	assert.LessOrEqual(t, numIngesters-1, countMockIngestersCalls(ingesters, "ActiveSeries"))
+
+	// A failed ingester is tolerated, since the series are replicated.
+	ingesters[0].happy = false
+	series, err := ds[0].ActiveSeries(ctx, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "test_2")})
+	require.NoError(t, err)
+	assert.Equal(t, []labels.Labels{fixtures[2]}, series)
+}
+
+func TestDistributor_ActiveSeries_MaxFetchedSeriesPerQuery(t *testing.T) {
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.MaxFetchedSeriesPerQuery = 1
+
+	ds, _, _ := prepare(t, prepConfig{
+		numIngesters:      3,
+		happyIngesters:    3,
+		numDistributors:   1,
+		replicationFactor: 3,
+		limits:            &limits,
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "active-series")
+	for _, series := range []labels.Labels{
+		labels.FromStrings(labels.MetricName, "test_1", "status", "500"),
+		labels.FromStrings(labels.MetricName, "test_1", "status", "200"),
+	} {
+		_, err := ds[0].Push(ctx, mockWriteRequest(series, 1, 100000))
+		require.NoError(t, err)
+	}
+
+	// The series replicated across the ingesters are only counted once.
+	series, err := ds[0].ActiveSeries(ctx, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "status", "500")})
+	require.NoError(t, err)
+	assert.Len(t, series, 1)
+
+	// The request fails once the number of series exceeds the limit.
+	_, err = ds[0].ActiveSeries(ctx, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "test_1")})
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusUnprocessableEntity), resp.Code)
+	assert.Equal(t, fmt.Sprintf(limiter.MaxSeriesHitMsgFormat, 1), string(resp.Body))
+}
+
 func TestHaDedupeMiddleware(t *testing.T) {
 	ctxWithUser := user.InjectOrgID(context.Background(), "user")
 	const replica1 = "replicaA"
@@ -2813,14 +2968,15 @@ func TestHaDedupeMiddleware(t *testing.T) {
 	const cluster2 = "clusterB"
 
 	type testCase struct {
-		name              string
-		ctx               context.Context
-		enableHaTracker   bool
-		acceptHaSamples   bool
-		reqs              []*mimirpb.WriteRequest
-		expectedReqs      []*mimirpb.WriteRequest
-		expectedNextCalls int
-		expectErrs        []int
+		name                 string
+		ctx                  context.Context
+		enableHaTracker      bool
+		acceptHaSamples      bool
+		perSeriesDedupWindow time.Duration
+		reqs                 []*mimirpb.WriteRequest
+		expectedReqs         []*mimirpb.WriteRequest
+		expectedNextCalls    int
+		expectErrs           []int
 	}
 	testCases := []testCase{
 		{
@@ -2885,6 +3041,24 @@ func TestHaDedupeMiddleware(t *testing.T) {
 			expectedReqs:      []*mimirpb.WriteRequest{makeWriteRequestForGenerators(5, labelSetGenWithCluster(cluster1), nil, nil)},
 			expectedNextCalls: 1,
 			expectErrs:        []int{0, 202, 400, 400},
+		}, {
+			name:                 "perform HA deduplication per series",
+			ctx:                  ctxWithUser,
+			enableHaTracker:      true,
+			acceptHaSamples:      true,
+			perSeriesDedupWindow: time.Minute,
+			reqs: []*mimirpb.WriteRequest{
+				makeWriteRequestForGenerators(3, labelSetGenWithReplicaAndCluster(replica1, cluster1), nil, nil),
+				makeWriteRequestForGenerators(5, labelSetGenWithReplicaAndCluster(replica2, cluster1), nil, nil),
+				makeWriteRequestForGenerators(3, labelSetGenWithReplicaAndCluster(replica2, cluster1), nil, nil),
+			},
+			expectedReqs: []*mimirpb.WriteRequest{
+				makeWriteRequestForGenerators(3, labelSetGenWithCluster(cluster1), nil, nil),
+				// Only the series missing from the elected replica are accepted from the non-elected one.
+				{Timeseries: makeWriteRequestForGenerators(5, labelSetGenWithCluster(cluster1), nil, nil).Timeseries[3:]},
+			},
+			expectedNextCalls: 2,
+			expectErrs:        []int{0, 0, 202},
 		},
 	}
 
@@ -2911,6 +3085,7 @@ func TestHaDedupeMiddleware(t *testing.T) {
 			limits.AcceptHASamples = tc.acceptHaSamples
 			limits.MaxLabelValueLength = 15
 			limits.HAMaxClusters = 1
+			limits.HAPerSeriesDedupWindow = model.Duration(tc.perSeriesDedupWindow)
 
 			ds, _, _ := prepare(t, prepConfig{
 				numDistributors: 1,
@@ -3469,6 +3644,9 @@ type prepConfig struct {
 	getForwarder                       func() forwarding.Forwarder
 	getEphemeralSeriesProvider         func() ephemeral.SeriesCheckerByUser
 	markEphemeral                      bool
+	aggregation                        bool
+	adaptiveLimiting                   bool
+	ingestersPushPressure              float64
 
 	timeOut bool
 }
@@ -3492,6 +3670,7 @@ func prepare(t *testing.T, cfg prepConfig) ([]*Distributor, []mockIngester, []*p
 			zone:                          zone,
 			labelNamesStreamResponseDelay: labelNamesStreamResponseDelay,
 			timeOut:                       cfg.timeOut,
+			pushPressure:                  cfg.ingestersPushPressure,
 		})
 	}
 	for i := cfg.happyIngesters; i < cfg.numIngesters; i++ {
@@ -3559,6 +3738,11 @@ func prepare(t *testing.T, cfg prepConfig) ([]*Distributor, []mockIngester, []*p
 
 	distributors := make([]*Distributor, 0, cfg.numDistributors)
 	registries := make([]*prometheus.Registry, 0, cfg.numDistributors)
+	distributorsByAddr := sync.Map{}
+	distributorFactory := func(addr string) (ring_client.PoolClient, error) {
+		d, _ := distributorsByAddr.Load(addr)
+		return &mockDistributorClient{distributor: d.(*Distributor)}, nil
+	}
 	for i := 0; i < cfg.numDistributors; i++ {
 		if cfg.limits == nil {
 			cfg.limits = &validation.Limits{}
@@ -3570,10 +3754,12 @@ func prepare(t *testing.T, cfg prepConfig) ([]*Distributor, []mockIngester, []*p
 		flagext.DefaultValues(&distributorCfg, &clientConfig)
 
 		distributorCfg.IngesterClientFactory = factory
+		distributorCfg.DistributorClientFactory = distributorFactory
 		distributorCfg.DistributorRing.Common.HeartbeatPeriod = 100 * time.Millisecond
 		distributorCfg.DistributorRing.Common.InstanceID = strconv.Itoa(i)
 		distributorCfg.DistributorRing.Common.KVStore.Mock = kvStore
 		distributorCfg.DistributorRing.Common.InstanceAddr = "127.0.0.1"
+		distributorCfg.DistributorRing.Common.InstancePort = 9095 + i
 		distributorCfg.SkipLabelNameValidation = cfg.skipLabelNameValidation
 		distributorCfg.InstanceLimits.MaxInflightPushRequests = cfg.maxInflightRequests
 		distributorCfg.InstanceLimits.MaxInflightPushRequestsBytes = cfg.maxInflightRequestsBytes
@@ -3590,6 +3776,14 @@ func prepare(t *testing.T, cfg prepConfig) ([]*Distributor, []mockIngester, []*p
 			distributorCfg.EphemeralSeriesEnabled = true
 		}
 
+		if cfg.aggregation {
+			distributorCfg.Aggregation.Enabled = true
+		}
+
+		if cfg.adaptiveLimiting {
+			distributorCfg.AdaptiveLimiting = AdaptiveLimitingConfig{Enabled: true, PressureThreshold: 0.8, PressureTTL: time.Minute}
+		}
+
 		cfg.limits.IngestionTenantShardSize = cfg.shuffleShardSize
 
 		if cfg.enableTracker {
@@ -3628,6 +3822,7 @@ func prepare(t *testing.T, cfg prepConfig) ([]*Distributor, []mockIngester, []*p
 
 		distributors = append(distributors, d)
 		registries = append(registries, reg)
+		distributorsByAddr.Store(d.distributorsLifecycler.GetInstanceAddr(), d)
 	}
 
 	// If the distributors ring is setup, wait until the first distributor
@@ -3880,6 +4075,32 @@ func mustEqualMatcher(k, v string) *labels.Matcher {
 	return m
 }
 
+// mockDistributorClient pushes the requests routed to another distributor as if they were received through gRPC.
+type mockDistributorClient struct {
+	distributorpb.DistributorClient
+	grpc_health_v1.HealthClient
+	distributor *Distributor
+}
+
+func (c *mockDistributorClient) Push(ctx context.Context, req *mimirpb.WriteRequest, _ ...grpc.CallOption) (*mimirpb.WriteResponse, error) {
+	// The request is copied because it's returned to the pool by the distributor.
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	received := &mimirpb.WriteRequest{}
+	if err := received.Unmarshal(data); err != nil {
+		return nil, err
+	}
+
+	md, _ := grpc_metadata.FromOutgoingContext(ctx)
+	return c.distributor.Push(grpc_metadata.NewIncomingContext(ctx, md), received)
+}
+
+func (c *mockDistributorClient) Close() error {
+	return nil
+}
+
 type mockIngester struct {
 	sync.Mutex
 	client.IngesterClient
@@ -3897,6 +4118,7 @@ type mockIngester struct {
 	labelNamesStreamResponseDelay time.Duration
 	timeOut                       bool
 	tokens                        []uint32
+	pushPressure                  float64
 }
 
 func (i *mockIngester) series() map[uint32]*mimirpb.PreallocTimeseries {
@@ -3976,12 +4198,14 @@ func (i *mockIngester) Push(ctx context.Context, req *mimirpb.WriteRequest, opts
 		if !ok {
 			// Make a copy because the request Timeseries are reused
 			item := mimirpb.TimeSeries{
-				Labels:  make([]mimirpb.LabelAdapter, len(series.TimeSeries.Labels)),
-				Samples: make([]mimirpb.Sample, len(series.TimeSeries.Samples)),
+				Labels:     make([]mimirpb.LabelAdapter, len(series.TimeSeries.Labels)),
+				Samples:    make([]mimirpb.Sample, len(series.TimeSeries.Samples)),
+				Histograms: make([]mimirpb.Histogram, len(series.TimeSeries.Histograms)),
 			}
 
 			copy(item.Labels, series.TimeSeries.Labels)
 			copy(item.Samples, series.TimeSeries.Samples)
+			copy(item.Histograms, series.TimeSeries.Histograms)
 
 			if ephemeral {
 				i.ephemeralTimeseries[hash] = &mimirpb.PreallocTimeseries{TimeSeries: &item}
@@ -3990,6 +4214,7 @@ func (i *mockIngester) Push(ctx context.Context, req *mimirpb.WriteRequest, opts
 			}
 		} else {
 			existing.Samples = append(existing.Samples, series.Samples...)
+			existing.Histograms = append(existing.Histograms, series.Histograms...)
 		}
 	}
 
@@ -4003,6 +4228,14 @@ func (i *mockIngester) Push(ctx context.Context, req *mimirpb.WriteRequest, opts
 		set[*m] = struct{}{}
 	}
 
+	if i.pushPressure > 0 {
+		for _, opt := range opts {
+			if header, ok := opt.(grpc.HeaderCallOption); ok {
+				*header.HeaderAddr = grpc_metadata.Pairs(client.PushPressureKey, strconv.FormatFloat(i.pushPressure, 'f', -1, 64))
+			}
+		}
+	}
+
 	return &mimirpb.WriteResponse{}, nil
 }
 
@@ -4248,6 +4481,38 @@ func (i *mockIngester) LabelValuesCardinality(ctx context.Context, req *client.L
 	return &labelValuesCardinalityStream{results: []*client.LabelValuesCardinalityResponse{result}}, nil
 }
 
+func (i *mockIngester) ActiveGrowth(ctx context.Context, req *client.ActiveGrowthRequest, opts ...grpc.CallOption) (*client.ActiveGrowthResponse, error) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.trackCall("ActiveGrowth")
+
+	if !i.happy {
+		return nil, errFail
+	}
+
+	// All the series of the mock are considered as recently created.
+	result := &client.ActiveGrowthResponse{}
+	metricNames := map[string]uint64{}
+	labelPairs := map[[2]string]uint64{}
+	for _, ts := range i.timeseries {
+		for _, lbl := range ts.Labels {
+			if lbl.Name == labels.MetricName {
+				metricNames[lbl.Value]++
+			} else {
+				labelPairs[[2]string{lbl.Name, lbl.Value}]++
+			}
+		}
+	}
+	for name, count := range metricNames {
+		result.MetricNames = append(result.MetricNames, &client.SeriesGrowth{LabelName: labels.MetricName, LabelValue: name, CreatedSeries: count})
+	}
+	for pair, count := range labelPairs {
+		result.LabelPairs = append(result.LabelPairs, &client.SeriesGrowth{LabelName: pair[0], LabelValue: pair[1], CreatedSeries: count})
+	}
+	return result, nil
+}
+
 type labelValuesCardinalityStream struct {
 	grpc.ClientStream
 	i       int
@@ -4267,6 +4532,50 @@ func (s *labelValuesCardinalityStream) Recv() (*client.LabelValuesCardinalityRes
 	return result, nil
 }
 
+func (i *mockIngester) ActiveSeries(ctx context.Context, req *client.ActiveSeriesRequest, opts ...grpc.CallOption) (client.Ingester_ActiveSeriesClient, error) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.trackCall("ActiveSeries")
+
+	if !i.happy {
+		return nil, errFail
+	}
+
+	matchers, err := client.FromLabelMatchers(req.GetMatchers())
+	if err != nil {
+		return nil, err
+	}
+
+	// All the series of the mock are considered as active.
+	result := &client.ActiveSeriesResponse{}
+	for _, ts := range i.timeseries {
+		if match(ts.Labels, matchers) {
+			result.Metric = append(result.Metric, &mimirpb.Metric{Labels: ts.Labels})
+		}
+	}
+	return &activeSeriesStream{results: []*client.ActiveSeriesResponse{result}}, nil
+}
+
+type activeSeriesStream struct {
+	grpc.ClientStream
+	i       int
+	results []*client.ActiveSeriesResponse
+}
+
+func (*activeSeriesStream) CloseSend() error {
+	return nil
+}
+
+func (s *activeSeriesStream) Recv() (*client.ActiveSeriesResponse, error) {
+	if s.i >= len(s.results) {
+		return nil, io.EOF
+	}
+	result := s.results[s.i]
+	s.i++
+	return result, nil
+}
+
 func (i *mockIngester) trackCall(name string) {
 	if i.calls == nil {
 		i.calls = map[string]int{}
@@ -4544,6 +4853,104 @@ func TestDistributorValidation(t *testing.T) {
 	}
 }
 
+func TestDistributor_Push_NativeHistograms(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+	now := model.Now()
+
+	makeRequest := func(buckets int) *mimirpb.WriteRequest {
+		deltas := make([]int64, buckets)
+		for i := range deltas {
+			deltas[i] = 1
+		}
+		return &mimirpb.WriteRequest{Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+			Labels: []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "test"}},
+			Histograms: []mimirpb.Histogram{{
+				Count:          &mimirpb.Histogram_CountInt{CountInt: uint64(buckets * (buckets + 1) / 2)},
+				Schema:         3,
+				ZeroCount:      &mimirpb.Histogram_ZeroCountInt{},
+				PositiveSpans:  []mimirpb.BucketSpan{{Offset: 0, Length: uint32(buckets)}},
+				PositiveDeltas: deltas,
+				Timestamp:      int64(now),
+			}},
+		}}}}
+	}
+
+	tests := map[string]struct {
+		enabled            bool
+		maxBuckets         int
+		buckets            int
+		expectedHistograms int
+		expectedErr        string
+		expectedDiscarded  string
+	}{
+		"native histograms ingestion disabled": {
+			buckets: 4,
+			expectedDiscarded: `
+				# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+				# TYPE cortex_discarded_samples_total counter
+				cortex_discarded_samples_total{group="",reason="native_histograms_disabled",user="user"} 1
+			`,
+		},
+		"native histograms ingestion enabled": {
+			enabled:            true,
+			buckets:            4,
+			expectedHistograms: 1,
+		},
+		"native histogram within the buckets limit": {
+			enabled:            true,
+			maxBuckets:         4,
+			buckets:            4,
+			expectedHistograms: 1,
+		},
+		"native histogram exceeding the buckets limit": {
+			enabled:     true,
+			maxBuckets:  3,
+			buckets:     4,
+			expectedErr: fmt.Sprintf("received a native histogram sample with too many buckets, timestamp: %d series: 'test' buckets: 4 limit: 3 (err-mimir-max-native-histogram-buckets)", now),
+			expectedDiscarded: `
+				# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+				# TYPE cortex_discarded_samples_total counter
+				cortex_discarded_samples_total{group="",reason="max_native_histogram_buckets",user="user"} 1
+			`,
+		},
+	}
+
+	for testName, tc := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.NativeHistogramsIngestionEnabled = tc.enabled
+			limits.MaxNativeHistogramBuckets = tc.maxBuckets
+
+			ds, ingesters, regs := prepare(t, prepConfig{
+				limits:            limits,
+				numIngesters:      1,
+				happyIngesters:    1,
+				numDistributors:   1,
+				replicationFactor: 1,
+			})
+
+			_, err := ds[0].Push(ctx, makeRequest(tc.buckets))
+			if tc.expectedErr != "" {
+				res, ok := httpgrpc.HTTPResponseFromError(err)
+				require.True(t, ok)
+				require.Equal(t, int32(http.StatusBadRequest), res.Code)
+				require.Contains(t, string(res.GetBody()), tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			histograms := 0
+			for _, ts := range ingesters[0].timeseries {
+				histograms += len(ts.Histograms)
+			}
+			assert.Equal(t, tc.expectedHistograms, histograms)
+
+			assert.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(tc.expectedDiscarded), "cortex_discarded_samples_total"))
+		})
+	}
+}
+
 func TestRemoveReplicaLabel(t *testing.T) {
 	replicaLabel := "replica"
 	clusterLabel := "cluster"
@@ -4692,6 +5099,269 @@ func TestDistributor_Push_Relabel(t *testing.T) {
 	}
 }
 
+func TestDistributor_Push_Aggregation(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.AggregationRules = validation.AggregationRules{
+		"foo": {Without: []string{"pod"}, Interval: model.Duration(time.Second), DropInput: true},
+	}
+
+	ds, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		numDistributors:   1,
+		replicationFactor: 1,
+		limits:            &limits,
+		aggregation:       true,
+	})
+
+	now := time.Now().UnixMilli()
+	for _, series := range []labels.Labels{
+		labels.FromStrings("__name__", "foo", "cluster", "one", "pod", "a"),
+		labels.FromStrings("__name__", "foo", "cluster", "one", "pod", "b"),
+		labels.FromStrings("__name__", "bar", "cluster", "one", "pod", "a"),
+	} {
+		_, err := ds[0].Push(ctx, mockWriteRequest(series, 2, now))
+		require.NoError(t, err)
+	}
+
+	ingestedSeries := func() []string {
+		var res []string
+		for _, ts := range ingesters[0].series() {
+			res = append(res, mimirpb.FromLabelAdaptersToLabels(ts.Labels).String())
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	// The aggregated series are dropped, and their sum is ingested at the end of the interval.
+	assert.Equal(t, []string{`{__name__="bar", cluster="one", pod="a"}`}, ingestedSeries())
+	test.Poll(t, 5*time.Second, []string{
+		`{__name__="bar", cluster="one", pod="a"}`,
+		`{__name__="foo:sum_without_pod", aggregator="0", cluster="one"}`,
+	}, func() interface{} {
+		return ingestedSeries()
+	})
+}
+
+func TestDistributor_Push_AggregationWithMultipleDistributors(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.AggregationRules = validation.AggregationRules{
+		"foo": {Without: []string{"pod"}, Interval: model.Duration(time.Second), DropInput: true},
+	}
+
+	ds, ingesters, regs := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		numDistributors:   2,
+		replicationFactor: 1,
+		limits:            &limits,
+		aggregation:       true,
+	})
+
+	// The series of the metric are aggregated by the same distributor, whichever distributor receives them.
+	now := time.Now().UnixMilli()
+	_, err := ds[0].Push(ctx, mockWriteRequest(labels.FromStrings("__name__", "foo", "cluster", "one", "pod", "a"), 2, now))
+	require.NoError(t, err)
+	_, err = ds[1].Push(ctx, mockWriteRequest(labels.FromStrings("__name__", "foo", "cluster", "one", "pod", "b"), 3, now))
+	require.NoError(t, err)
+
+	ownerAddr, _, err := ds[0].routeOwner(shardByMetricName("user", "foo"))
+	require.NoError(t, err)
+	owner := 0
+	if ds[1].distributorsLifecycler.GetInstanceAddr() == ownerAddr {
+		owner = 1
+	}
+
+	for i, reg := range regs {
+		expected := ""
+		if i == owner {
+			expected = `
+				# HELP cortex_distributor_aggregation_input_samples_total The total number of samples the distributor aggregated according to the aggregation rules.
+				# TYPE cortex_distributor_aggregation_input_samples_total counter
+				cortex_distributor_aggregation_input_samples_total{user="user"} 2
+			`
+		}
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "cortex_distributor_aggregation_input_samples_total"))
+	}
+
+	ingestedSeries := func() []string {
+		var res []string
+		for _, ts := range ingesters[0].series() {
+			res = append(res, mimirpb.FromLabelAdaptersToLabels(ts.Labels).String())
+		}
+		return res
+	}
+
+	// The aggregated series are dropped, and their sum is ingested by the distributor in charge of the metric.
+	assert.Empty(t, ingestedSeries())
+	test.Poll(t, 5*time.Second, []string{
+		fmt.Sprintf(`{__name__="foo:sum_without_pod", aggregator="%d", cluster="one"}`, owner),
+	}, func() interface{} {
+		return ingestedSeries()
+	})
+}
+
+func TestDistributor_Push_HADedupPerSeriesWithMultipleDistributors(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.AcceptHASamples = true
+	limits.MaxLabelValueLength = 15
+	limits.HAPerSeriesDedupWindow = model.Duration(time.Minute)
+
+	ds, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		numDistributors:   2,
+		replicationFactor: 1,
+		limits:            &limits,
+		enableTracker:     true,
+	})
+
+	// The requests of the tenant are deduplicated by the same distributor, whichever distributor receives them.
+	_, err := ds[0].Push(ctx, makeWriteRequestForGenerators(3, labelSetGenWithReplicaAndCluster("replica1", "cluster1"), nil, nil))
+	require.NoError(t, err)
+	_, err = ds[1].Push(ctx, makeWriteRequestForGenerators(5, labelSetGenWithReplicaAndCluster("replica2", "cluster1"), nil, nil))
+	require.NoError(t, err)
+	_, err = ds[1].Push(ctx, makeWriteRequestForGenerators(3, labelSetGenWithReplicaAndCluster("replica2", "cluster1"), nil, nil))
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusAccepted), resp.Code)
+
+	// Only the series missing from the elected replica are accepted from the non-elected one.
+	series := ingesters[0].series()
+	assert.Len(t, series, 5)
+	for _, ts := range series {
+		assert.Len(t, ts.Samples, 1)
+	}
+}
+
+func TestDistributor_Push_DeadLetters(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+	ctx = util.AddSourceIPsToOutgoingContext(ctx, "10.0.0.1")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.DeadLetterMaxEntries = 10
+
+	ds, _, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		numDistributors:   1,
+		replicationFactor: 1,
+		limits:            &limits,
+	})
+
+	_, err := ds[0].Push(ctx, mockWriteRequest(labels.FromStrings("__name__", "foo", "valid", "a"), 1, 1000))
+	require.NoError(t, err)
+	_, err = ds[0].Push(ctx, mockWriteRequest(labels.FromStrings("__name__", "foo", "in-valid", "a"), 1, 2000))
+	require.Error(t, err)
+
+	entries := ds[0].DeadLetters.Entries("user")
+	require.Len(t, entries, 1)
+	assert.Equal(t, `{__name__="foo", in-valid="a"}`, entries[0].Series)
+	assert.Equal(t, int64(2000), entries[0].SampleTimestamp)
+	assert.Equal(t, "label_invalid", entries[0].Reason)
+	assert.Equal(t, "10.0.0.1", entries[0].SourceIP)
+}
+
+func TestDistributor_Push_ForwardingToTenant(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.ForwardingRules = validation.ForwardingRules{
+		"slo_errors_total": {Ingest: true, Tenant: "shared", Labels: map[string]string{"team": "payments"}},
+		"debug_total":      {Tenant: "shared"},
+	}
+
+	ds, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		numDistributors:   1,
+		replicationFactor: 1,
+		limits:            &limits,
+		forwarding:        true,
+	})
+
+	for _, series := range []labels.Labels{
+		labels.FromStrings("__name__", "slo_errors_total", "job", "a"),
+		labels.FromStrings("__name__", "debug_total", "job", "a"),
+		labels.FromStrings("__name__", "other", "job", "a"),
+	} {
+		_, err := ds[0].Push(ctx, mockWriteRequest(series, 1, 1000))
+		require.NoError(t, err)
+	}
+
+	// The series are ingested into the tenant they're copied into, with the labels of the rule.
+	expected := map[uint32]string{}
+	for _, series := range []struct {
+		tenant string
+		labels labels.Labels
+	}{
+		{"user", labels.FromStrings("__name__", "slo_errors_total", "job", "a")},
+		{"user", labels.FromStrings("__name__", "other", "job", "a")},
+		{"shared", labels.FromStrings("__name__", "slo_errors_total", "job", "a", "team", "payments")},
+		{"shared", labels.FromStrings("__name__", "debug_total", "job", "a")},
+	} {
+		expected[shardByAllLabels(series.tenant, mimirpb.FromLabelsToLabelAdapters(series.labels))] = series.labels.String()
+	}
+
+	ingested := map[uint32]string{}
+	for hash, ts := range ingesters[0].series() {
+		ingested[hash] = mimirpb.FromLabelAdaptersToLabels(ts.Labels).String()
+	}
+	assert.Equal(t, expected, ingested)
+	assert.Equal(t, 2.0, testutil.ToFloat64(ds[0].forwardedTenantSamples.WithLabelValues("user")))
+}
+
+func TestDistributor_Push_ForwardingToTenantShouldValidateCopiedSeries(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.MaxLabelNamesPerSeries = 3
+	limits.ForwardingRules = validation.ForwardingRules{
+		"slo_errors_total": {Ingest: true, Tenant: "shared", Labels: map[string]string{"team": "payments", "env": "prod"}},
+	}
+
+	ds, ingesters, regs := prepare(t, prepConfig{
+		numIngesters:      1,
+		happyIngesters:    1,
+		numDistributors:   1,
+		replicationFactor: 1,
+		limits:            &limits,
+		forwarding:        true,
+	})
+
+	// The copy has too many labels for the tenant it's copied into, so only the original series is ingested, and
+	// the validation error of the copy is propagated.
+	series := labels.FromStrings("__name__", "slo_errors_total", "job", "a")
+	_, err := ds[0].Push(ctx, mockWriteRequest(series, 1, 1000))
+	require.ErrorContains(t, err, string(globalerror.MaxLabelNamesPerSeries))
+
+	ingested := map[uint32]string{}
+	for hash, ts := range ingesters[0].series() {
+		ingested[hash] = mimirpb.FromLabelAdaptersToLabels(ts.Labels).String()
+	}
+	assert.Equal(t, map[uint32]string{
+		shardByAllLabels("user", mimirpb.FromLabelsToLabelAdapters(series)): series.String(),
+	}, ingested)
+
+	assert.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="max_label_names_per_series",user="shared"} 1
+	`), "cortex_discarded_samples_total"))
+}
+
 func countMockIngestersCalls(ingesters []mockIngester, name string) int {
 	count := 0
 	for i := 0; i < len(ingesters); i++ {
@@ -15,6 +15,7 @@ type Config struct {
 	RequestConcurrency int           `yaml:"request_concurrency" category:"experimental"`
 	RequestTimeout     time.Duration `yaml:"request_timeout" category:"experimental"`
 	PropagateErrors    bool          `yaml:"propagate_errors" category:"experimental"`
+	Queue              QueueConfig   `yaml:"queue"`
 
 	GRPCClientConfig grpcclient.Config `yaml:"grpc_client" doc:"description=Configures the gRPC client used to communicate between the distributors and the configured remote write endpoints used by the metrics forwarding feature."`
 }
@@ -24,6 +25,7 @@ func (c *Config) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&c.RequestConcurrency, "distributor.forwarding.request-concurrency", 10, "Maximum concurrency at which forwarding requests get performed.")
 	f.DurationVar(&c.RequestTimeout, "distributor.forwarding.request-timeout", 2*time.Second, "Timeout for requests to ingestion endpoints to which we forward metrics.")
 	f.BoolVar(&c.PropagateErrors, "distributor.forwarding.propagate-errors", true, "If disabled then forwarding requests are always considered to be successful, errors are ignored.")
+	c.Queue.RegisterFlags(f)
 	c.GRPCClientConfig.RegisterFlagsWithPrefix("distributor.forwarding.grpc-client", f)
 }
 
@@ -31,5 +33,41 @@ func (c *Config) Validate() error {
 	if c.RequestConcurrency < 1 {
 		return errors.New("distributor.forwarding.request-concurrency must be greater than 0")
 	}
+	return c.Queue.Validate()
+}
+
+type QueueConfig struct {
+	Enabled    bool          `yaml:"enabled" category:"experimental"`
+	Capacity   int           `yaml:"capacity" category:"experimental"`
+	Shards     int           `yaml:"shards" category:"experimental"`
+	MinBackoff time.Duration `yaml:"min_backoff" category:"experimental"`
+	MaxBackoff time.Duration `yaml:"max_backoff" category:"experimental"`
+	MaxRetries int           `yaml:"max_retries" category:"experimental"`
+	WALDir     string        `yaml:"wal_dir" category:"experimental"`
+}
+
+func (c *QueueConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.Enabled, "distributor.forwarding.queue.enabled", false, "Enables queueing the forwarding requests per tenant and endpoint, and retrying them in the background. The pushes of the tenant don't wait for the forwarding requests, and their errors are never propagated.")
+	f.IntVar(&c.Capacity, "distributor.forwarding.queue.capacity", 1000, "Maximum number of forwarding requests queued for each shard of a tenant's endpoint. When the queue is full, the oldest request is dropped.")
+	f.IntVar(&c.Shards, "distributor.forwarding.queue.shards", 4, "Number of shards of the queue of each tenant's endpoint. The series are sharded by labels, and each shard sends its requests one at a time, in order.")
+	f.DurationVar(&c.MinBackoff, "distributor.forwarding.queue.min-backoff", 100*time.Millisecond, "Minimum delay before retrying a failed forwarding request.")
+	f.DurationVar(&c.MaxBackoff, "distributor.forwarding.queue.max-backoff", 10*time.Second, "Maximum delay before retrying a failed forwarding request.")
+	f.IntVar(&c.MaxRetries, "distributor.forwarding.queue.max-retries", 10, "Maximum number of retries of a failed forwarding request, after which it is dropped. 0 to retry until the request succeeds.")
+	f.StringVar(&c.WALDir, "distributor.forwarding.queue.wal-dir", "", "Directory where the queued forwarding requests are written, so that they are sent after a restart of the distributor. If empty, the queued requests are only kept in memory.")
+}
+
+func (c *QueueConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Capacity < 1 {
+		return errors.New("distributor.forwarding.queue.capacity must be greater than 0")
+	}
+	if c.Shards < 1 {
+		return errors.New("distributor.forwarding.queue.shards must be greater than 0")
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		return errors.New("distributor.forwarding.queue.max-backoff must be greater than or equal to distributor.forwarding.queue.min-backoff")
+	}
 	return nil
 }
@@ -65,6 +65,9 @@ type forwarder struct {
 	httpGrpcClientPool *client.Pool
 	activeGroups       *util.ActiveGroupsCleanupService
 
+	// queues are the queues of the forwarding requests, nil if queueing is disabled.
+	queues *queues
+
 	requestsTotal           prometheus.Counter
 	errorsTotal             *prometheus.CounterVec
 	samplesTotal            prometheus.Counter
@@ -132,6 +135,9 @@ func NewForwarder(cfg Config, reg prometheus.Registerer, log log.Logger, limits
 	}
 
 	f.httpGrpcClientPool = f.newHTTPGrpcClientsPool()
+	if cfg.Queue.Enabled {
+		f.queues = newQueues(cfg.Queue, f.newQueuedRequest, reg, log)
+	}
 	f.Service = services.NewIdleService(f.start, f.stop)
 
 	return f
@@ -139,6 +145,9 @@ func NewForwarder(cfg Config, reg prometheus.Registerer, log log.Logger, limits
 
 func (f *forwarder) DeleteMetricsForUser(user string) {
 	f.discardedSamplesTooOld.DeleteLabelValues(user)
+	if f.queues != nil {
+		f.queues.deleteMetricsForUser(user)
+	}
 }
 
 func (f *forwarder) newHTTPGrpcClientsPool() *client.Pool {
@@ -197,10 +206,17 @@ func (f *forwarder) start(ctx context.Context) error {
 		go f.worker()
 	}
 
+	if f.queues != nil {
+		return f.queues.start()
+	}
 	return nil
 }
 
 func (f *forwarder) stop(_ error) error {
+	if f.queues != nil {
+		f.queues.stop()
+	}
+
 	close(f.reqCh)
 	f.workerWg.Wait()
 
@@ -225,6 +241,8 @@ func (f *forwarder) worker() {
 //
 // The forwarding requests get executed with a limited concurrency which is configurable, in a situation where the
 // concurrency limit is exhausted this function will block until a go routine is available to execute the requests.
+// If queueing is enabled, the forwarding requests get queued instead, and the returned chan of errors never contains
+// their errors.
 // The slice of time series which gets passed into this function must not be returned to the pool by the caller, the
 // returned slice of time series must be returned to the pool by the caller once it is done using it.
 //
@@ -255,7 +273,11 @@ func (f *forwarder) Forward(ctx context.Context, endpoint string, dontForwardBef
 		errCh <- err
 	}
 
-	if len(toForward) > 0 {
+	if len(toForward) > 0 && f.queues != nil {
+		f.queues.enqueue(user, endpoint, toForward)
+		f.pools.putTsSlice(toForward)
+		close(errCh)
+	} else if len(toForward) > 0 {
 		var requestWg sync.WaitGroup
 		requestWg.Add(1)
 
@@ -458,6 +480,26 @@ func (f *forwarder) submitForwardingRequest(ctx context.Context, user string, en
 	}
 }
 
+// newQueuedRequest returns a request used by a queue to send its forwarding requests to the user's endpoint.
+// It's not returned to the pool.
+func (f *forwarder) newQueuedRequest(user, endpoint string) *request {
+	return &request{
+		client:             &f.client,
+		httpGrpcClientPool: f.httpGrpcClientPool,
+		log:                f.log,
+		timeout:            f.cfg.RequestTimeout,
+
+		user:     user,
+		endpoint: endpoint,
+
+		requests:  f.requestsTotal,
+		errors:    f.errorsTotal,
+		samples:   f.samplesTotal,
+		exemplars: f.exemplarsTotal,
+		latency:   f.requestLatencyHistogram,
+	}
+}
+
 // do performs a forwarding request.
 func (r *request) do() {
 	spanlog, ctx := spanlogger.NewWithLogger(r.ctx, r.log, "request.do")
@@ -475,7 +517,7 @@ func (r *request) do() {
 	protoBuf := proto.NewBuffer(protoBufBytes)
 	err := protoBuf.Marshal(&mimirpb.WriteRequest{Timeseries: r.ts})
 	if err != nil {
-		r.handleError(ctx, http.StatusBadRequest, errors.Wrap(err, "failed to marshal write request for forwarding"))
+		r.handleError(ctx, httpgrpc.Errorf(http.StatusBadRequest, errors.Wrap(err, "failed to marshal write request for forwarding").Error()))
 		return
 	}
 
@@ -485,20 +527,34 @@ func (r *request) do() {
 	protoBufBytes = protoBuf.Bytes()
 	snappyBuf = snappy.Encode(snappyBuf[:cap(snappyBuf)], protoBufBytes)
 
-	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	if err := r.send(ctx, snappyBuf); err != nil {
+		r.handleError(ctx, err)
+	}
+}
+
+// send sends the snappy-compressed write request to the endpoint. The returned error has the status code 500
+// if the request can be retried, or 400 otherwise.
+func (r *request) send(ctx context.Context, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
+	var err error
 	if strings.HasPrefix(r.endpoint, httpGrpcPrefix) {
-		err = r.doHTTPGrpc(ctx, snappyBuf)
+		err = r.doHTTPGrpc(ctx, body)
 	} else {
-		err = r.doHTTP(ctx, snappyBuf)
+		err = r.doHTTP(ctx, body)
 	}
 
-	if err != nil {
-		r.errors.WithLabelValues("failed").Inc()
-
-		r.handleError(ctx, http.StatusInternalServerError, err)
+	if err == nil {
+		return nil
+	}
+	if _, ok := httpgrpc.HTTPResponseFromError(err); ok {
+		// The endpoint has returned an error.
+		return err
 	}
+
+	r.errors.WithLabelValues("failed").Inc()
+	return httpgrpc.Errorf(http.StatusInternalServerError, err.Error())
 }
 
 func (r *request) doHTTP(ctx context.Context, body []byte) error {
@@ -537,21 +593,19 @@ func (r *request) doHTTP(ctx context.Context, body []byte) error {
 			line = scanner.Text()
 		}
 
-		r.processHTTPResponse(ctx, httpResp.StatusCode, line)
+		return r.processHTTPResponse(httpResp.StatusCode, line)
 	}
 	return nil
 }
 
-func (r *request) processHTTPResponse(ctx context.Context, code int, message string) {
+func (r *request) processHTTPResponse(code int, message string) error {
 	r.errors.WithLabelValues(strconv.Itoa(code)).Inc()
 
-	err := errors.Errorf("server returned HTTP status %d: %s", code, message)
 	if code/100 == 5 || code == http.StatusTooManyRequests {
 		// The forwarding endpoint has returned a retriable error, so we want the client to retry.
-		r.handleError(ctx, http.StatusInternalServerError, err)
-		return
+		return httpgrpc.Errorf(http.StatusInternalServerError, "server returned HTTP status %d: %s", code, message)
 	}
-	r.handleError(ctx, http.StatusBadRequest, err)
+	return httpgrpc.Errorf(http.StatusBadRequest, "server returned HTTP status %d: %s", code, message)
 }
 
 var headers = []*httpgrpc.Header{
@@ -614,16 +668,17 @@ func (r *request) doHTTPGrpc(ctx context.Context, body []byte) error {
 			line = scanner.Text()
 		}
 
-		r.processHTTPResponse(ctx, int(resp.Code), line)
+		return r.processHTTPResponse(int(resp.Code), line)
 	}
 	return nil
 }
 
-func (r *request) handleError(ctx context.Context, status int, err error) {
+// handleError logs the error of the request, and propagates it if enabled. The error must be an httpgrpc error.
+func (r *request) handleError(ctx context.Context, err error) {
 	logger := spanlogger.FromContext(ctx, r.log)
-	level.Warn(logger).Log("msg", "error in forwarding request", "statusCode", status, "err", err)
+	level.Warn(logger).Log("msg", "error in forwarding request", "err", err)
 	if r.propagateErrors {
-		r.errCh <- httpgrpc.Errorf(status, err.Error())
+		r.errCh <- err
 	}
 }
 
@@ -0,0 +1,493 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package forwarding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/backoff"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/httpgrpc"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/extract"
+)
+
+const (
+	// endpointFile is the file of the WAL directory of a queue which contains the endpoint of the queue.
+	endpointFile = "endpoint"
+
+	batchFileExt = ".batch"
+	tmpFileExt   = ".tmp"
+
+	droppedReasonQueueFull        = "queue_full"
+	droppedReasonRetriesExhausted = "retries_exhausted"
+	droppedReasonRejected         = "rejected"
+)
+
+// queues are the queues of the forwarding requests of each tenant and endpoint. Each queue is split in shards,
+// which send their requests one at a time, in order, and retry the requests failed with a retriable error.
+type queues struct {
+	cfg        QueueConfig
+	newRequest func(user, endpoint string) *request
+	log        log.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mtx    sync.Mutex
+	queues map[queueKey]*queue
+
+	length         *prometheus.GaugeVec
+	droppedSamples *prometheus.CounterVec
+	retries        *prometheus.CounterVec
+	lag            *prometheus.GaugeVec
+}
+
+type queueKey struct {
+	user     string
+	endpoint string
+}
+
+type queue struct {
+	user     string
+	endpoint string
+
+	// dir is the WAL directory of the queue, empty if the WAL is disabled.
+	dir    string
+	seq    atomic.Uint64
+	shards []*queueShard
+}
+
+type queueShard struct {
+	mtx     sync.Mutex
+	batches []*batch
+	notify  chan struct{}
+}
+
+// batch is a queued forwarding request.
+type batch struct {
+	body       []byte // Snappy-compressed write request.
+	counts     TimeseriesCounts
+	rules      []string // Metric names of the forwarded series.
+	enqueuedAt time.Time
+
+	// file is the WAL file of the batch, empty if the WAL is disabled.
+	file string
+}
+
+func newQueues(cfg QueueConfig, newRequest func(user, endpoint string) *request, reg prometheus.Registerer, log log.Logger) *queues {
+	return &queues{
+		cfg:        cfg,
+		newRequest: newRequest,
+		log:        log,
+		queues:     map[queueKey]*queue{},
+
+		length: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_distributor_forward_queue_length",
+			Help: "The number of forwarding requests queued or being sent.",
+		}, []string{"user"}),
+		droppedSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_forward_queue_dropped_samples_total",
+			Help: "The total number of samples of the queued forwarding requests which have been dropped.",
+		}, []string{"user", "reason"}),
+		retries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_forward_queue_retries_total",
+			Help: "The total number of retries of the queued forwarding requests.",
+		}, []string{"user"}),
+		lag: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_distributor_forward_lag_seconds",
+			Help: "The time between the queueing and the forwarding of the latest forwarded samples of each forwarding rule.",
+		}, []string{"user", "rule"}),
+	}
+}
+
+// start starts the queues, and replays the forwarding requests of the WAL if enabled.
+func (qs *queues) start() error {
+	qs.ctx, qs.cancel = context.WithCancel(context.Background())
+
+	if qs.cfg.WALDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(qs.cfg.WALDir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "failed to create forwarding queue WAL directory")
+	}
+	return qs.replay()
+}
+
+// stop stops sending the queued forwarding requests. The requests which aren't sent yet are kept in the WAL if enabled.
+func (qs *queues) stop() {
+	qs.cancel()
+	qs.wg.Wait()
+}
+
+func (qs *queues) deleteMetricsForUser(user string) {
+	qs.length.DeleteLabelValues(user)
+	qs.droppedSamples.DeletePartialMatch(prometheus.Labels{"user": user})
+	qs.retries.DeleteLabelValues(user)
+	qs.lag.DeletePartialMatch(prometheus.Labels{"user": user})
+}
+
+// enqueue queues the forwarding of the series to the endpoint. The series are sharded by labels, and each shard
+// gets a forwarding request. The series are copied, so the caller can return them to the pool.
+func (qs *queues) enqueue(user, endpoint string, ts []mimirpb.PreallocTimeseries) {
+	q, err := qs.getOrCreateQueue(user, endpoint)
+	if err != nil {
+		level.Warn(qs.log).Log("msg", "failed to create forwarding queue", "user", user, "err", err)
+		return
+	}
+
+	sharded := make([][]mimirpb.PreallocTimeseries, len(q.shards))
+	for _, series := range ts {
+		idx := shardIndex(series, len(q.shards))
+		sharded[idx] = append(sharded[idx], series)
+	}
+
+	for idx, series := range sharded {
+		if len(series) == 0 {
+			continue
+		}
+
+		body, err := (&mimirpb.WriteRequest{Timeseries: series}).Marshal()
+		if err != nil {
+			level.Warn(qs.log).Log("msg", "failed to marshal forwarding request", "user", user, "err", err)
+			continue
+		}
+
+		b := newBatch(snappy.Encode(nil, body), series, time.Now())
+		if q.dir != "" {
+			if b.file, err = writeBatchFile(q.dir, q.seq.Inc(), b.body); err != nil {
+				// The request is still sent, but it's lost if the distributor restarts before.
+				level.Warn(qs.log).Log("msg", "failed to write forwarding request to the WAL", "user", user, "err", err)
+			}
+		}
+		qs.push(q, q.shards[idx], b)
+	}
+}
+
+// push adds the batch to the shard, dropping the oldest batch waiting to be sent if the shard is full.
+func (qs *queues) push(q *queue, s *queueShard, b *batch) {
+	var dropped *batch
+
+	s.mtx.Lock()
+	if len(s.batches) >= qs.cfg.Capacity {
+		dropped = s.batches[0]
+		s.batches = s.batches[1:]
+	}
+	s.batches = append(s.batches, b)
+	s.mtx.Unlock()
+
+	qs.length.WithLabelValues(q.user).Inc()
+	if dropped != nil {
+		qs.drop(q, dropped, droppedReasonQueueFull)
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (qs *queues) getOrCreateQueue(user, endpoint string) (*queue, error) {
+	qs.mtx.Lock()
+	defer qs.mtx.Unlock()
+
+	key := queueKey{user: user, endpoint: endpoint}
+	if q, ok := qs.queues[key]; ok {
+		return q, nil
+	}
+
+	q := qs.newQueue(user, endpoint)
+	if qs.cfg.WALDir != "" {
+		q.dir = queueDir(qs.cfg.WALDir, user, endpoint)
+		if err := os.MkdirAll(q.dir, os.ModePerm); err != nil {
+			return nil, errors.Wrap(err, "failed to create forwarding queue WAL directory")
+		}
+		if err := os.WriteFile(filepath.Join(q.dir, endpointFile), []byte(endpoint), 0o600); err != nil {
+			return nil, errors.Wrap(err, "failed to write forwarding queue endpoint")
+		}
+	}
+
+	qs.startQueue(q)
+	qs.queues[key] = q
+	return q, nil
+}
+
+func (qs *queues) newQueue(user, endpoint string) *queue {
+	q := &queue{
+		user:     user,
+		endpoint: endpoint,
+		shards:   make([]*queueShard, qs.cfg.Shards),
+	}
+	for i := range q.shards {
+		q.shards[i] = &queueShard{notify: make(chan struct{}, 1)}
+	}
+	return q
+}
+
+func (qs *queues) startQueue(q *queue) {
+	qs.wg.Add(len(q.shards))
+	for _, s := range q.shards {
+		go qs.run(q, s)
+	}
+}
+
+// run sends the forwarding requests of the shard, one at a time, until the queues are stopped.
+func (qs *queues) run(q *queue, s *queueShard) {
+	defer qs.wg.Done()
+
+	req := qs.newRequest(q.user, q.endpoint)
+	for {
+		b, ok := s.next(qs.ctx)
+		if !ok {
+			return
+		}
+		if !qs.send(q, req, b) {
+			// The queues have been stopped while the request was being sent.
+			return
+		}
+	}
+}
+
+// next returns the next batch to send, waiting for one to be queued if needed. It returns false
+// if the context is canceled.
+func (s *queueShard) next(ctx context.Context) (*batch, bool) {
+	for {
+		s.mtx.Lock()
+		if len(s.batches) > 0 {
+			b := s.batches[0]
+			s.batches = s.batches[1:]
+			s.mtx.Unlock()
+			return b, true
+		}
+		s.mtx.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-s.notify:
+		}
+	}
+}
+
+// send sends the batch, retrying it with backoff as long as it fails with a retriable error, up to the maximum
+// number of retries. It returns false if the queues have been stopped before the batch has been sent or dropped.
+func (qs *queues) send(q *queue, req *request, b *batch) bool {
+	boff := backoff.New(qs.ctx, backoff.Config{MinBackoff: qs.cfg.MinBackoff, MaxBackoff: qs.cfg.MaxBackoff})
+	for {
+		req.counts = b.counts
+		err := req.send(qs.ctx, b.body)
+		if err == nil {
+			for _, rule := range b.rules {
+				qs.lag.WithLabelValues(q.user, rule).Set(time.Since(b.enqueuedAt).Seconds())
+			}
+			qs.remove(q, b)
+			return true
+		}
+		if qs.ctx.Err() != nil {
+			return false
+		}
+
+		if resp, ok := httpgrpc.HTTPResponseFromError(err); ok && resp.Code != http.StatusInternalServerError {
+			level.Warn(qs.log).Log("msg", "forwarding request rejected", "user", q.user, "err", err)
+			qs.drop(q, b, droppedReasonRejected)
+			return true
+		}
+		if qs.cfg.MaxRetries > 0 && boff.NumRetries() >= qs.cfg.MaxRetries {
+			level.Warn(qs.log).Log("msg", "forwarding request failed after the maximum number of retries", "user", q.user, "err", err)
+			qs.drop(q, b, droppedReasonRetriesExhausted)
+			return true
+		}
+
+		select {
+		case <-qs.ctx.Done():
+			return false
+		case <-time.After(boff.NextDelay()):
+		}
+		qs.retries.WithLabelValues(q.user).Inc()
+	}
+}
+
+func (qs *queues) drop(q *queue, b *batch, reason string) {
+	qs.droppedSamples.WithLabelValues(q.user, reason).Add(float64(b.counts.SampleCount))
+	qs.remove(q, b)
+}
+
+// remove removes the batch, which has been sent or dropped, from the WAL.
+func (qs *queues) remove(q *queue, b *batch) {
+	qs.length.WithLabelValues(q.user).Dec()
+	if b.file == "" {
+		return
+	}
+	if err := os.Remove(b.file); err != nil && !os.IsNotExist(err) {
+		level.Warn(qs.log).Log("msg", "failed to remove forwarding request from the WAL", "user", q.user, "err", err)
+	}
+}
+
+// replay queues the forwarding requests of the WAL, in the order in which they have been queued.
+func (qs *queues) replay() error {
+	userDirs, err := os.ReadDir(qs.cfg.WALDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read forwarding queue WAL directory")
+	}
+
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() {
+			continue
+		}
+		queueDirs, err := os.ReadDir(filepath.Join(qs.cfg.WALDir, userDir.Name()))
+		if err != nil {
+			return errors.Wrap(err, "failed to read forwarding queue WAL directory")
+		}
+		for _, queueDir := range queueDirs {
+			if !queueDir.IsDir() {
+				continue
+			}
+			if err := qs.replayQueue(userDir.Name(), filepath.Join(qs.cfg.WALDir, userDir.Name(), queueDir.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (qs *queues) replayQueue(user, dir string) error {
+	endpoint, err := os.ReadFile(filepath.Join(dir, endpointFile))
+	if os.IsNotExist(err) {
+		// The queue has been created, but it has never been written to.
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read forwarding queue endpoint")
+	}
+
+	q := qs.newQueue(user, string(endpoint))
+	q.dir = dir
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read forwarding queue WAL directory")
+	}
+	// The names of the files are the zero-padded sequence numbers of the batches.
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name())
+		if strings.HasSuffix(file.Name(), tmpFileExt) {
+			// The distributor stopped before the batch has been completely written.
+			_ = os.Remove(path)
+			continue
+		}
+		if !strings.HasSuffix(file.Name(), batchFileExt) {
+			continue
+		}
+
+		var seq uint64
+		if _, err := fmt.Sscanf(file.Name(), "%d"+batchFileExt, &seq); err != nil {
+			continue
+		}
+		if seq > q.seq.Load() {
+			q.seq.Store(seq)
+		}
+
+		b, series, err := readBatchFile(path)
+		if err != nil {
+			level.Warn(qs.log).Log("msg", "dropping corrupted forwarding request from the WAL", "user", user, "file", path, "err", err)
+			_ = os.Remove(path)
+			continue
+		}
+		qs.push(q, q.shards[shardIndex(series[0], len(q.shards))], b)
+	}
+
+	qs.mtx.Lock()
+	qs.queues[queueKey{user: q.user, endpoint: q.endpoint}] = q
+	qs.mtx.Unlock()
+
+	qs.startQueue(q)
+	return nil
+}
+
+// newBatch returns the batch of the snappy-compressed write request of the series.
+func newBatch(body []byte, series []mimirpb.PreallocTimeseries, now time.Time) *batch {
+	b := &batch{body: body, enqueuedAt: now}
+
+	rules := map[string]struct{}{}
+	for _, ts := range series {
+		b.counts.count(ts)
+		if metric, err := extract.UnsafeMetricNameFromLabelAdapters(ts.Labels); err == nil {
+			rules[metric] = struct{}{}
+		}
+	}
+	for rule := range rules {
+		// The metric name references the request buffer.
+		b.rules = append(b.rules, strings.Clone(rule))
+	}
+	return b
+}
+
+// writeBatchFile writes the snappy-compressed write request into the WAL directory, and returns the path of the file.
+func writeBatchFile(dir string, seq uint64, body []byte) (string, error) {
+	path := filepath.Join(dir, fmt.Sprintf("%020d%s", seq, batchFileExt))
+	if err := os.WriteFile(path+tmpFileExt, body, 0o600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(path+tmpFileExt, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// readBatchFile reads the batch from its WAL file, and returns the series of its write request.
+func readBatchFile(path string) (*batch, []mimirpb.PreallocTimeseries, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var req mimirpb.WriteRequest
+	if err := req.Unmarshal(decoded); err != nil {
+		return nil, nil, err
+	}
+	if len(req.Timeseries) == 0 {
+		return nil, nil, errors.New("empty write request")
+	}
+
+	b := newBatch(body, req.Timeseries, info.ModTime())
+	b.file = path
+	return b, req.Timeseries, nil
+}
+
+// queueDir returns the WAL directory of the queue of the user's endpoint.
+func queueDir(walDir, user, endpoint string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(endpoint))
+	return filepath.Join(walDir, user, fmt.Sprintf("%016x", h.Sum64()))
+}
+
+func shardIndex(ts mimirpb.PreallocTimeseries, shards int) int {
+	return int(mimirpb.FromLabelAdaptersToLabels(ts.Labels).Hash() % uint64(shards))
+}
@@ -0,0 +1,280 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package forwarding
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func testQueueConfig() Config {
+	cfg := testConfig
+	cfg.Queue = QueueConfig{
+		Enabled:    true,
+		Capacity:   10,
+		Shards:     2,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		MaxRetries: 3,
+	}
+	return cfg
+}
+
+func TestQueueConfig_Validate(t *testing.T) {
+	cfg := testQueueConfig()
+	require.NoError(t, cfg.Validate())
+
+	cfg.Queue.Shards = 0
+	require.EqualError(t, cfg.Validate(), "distributor.forwarding.queue.shards must be greater than 0")
+
+	cfg = testQueueConfig()
+	cfg.Queue.MaxBackoff = 0
+	require.EqualError(t, cfg.Validate(), "distributor.forwarding.queue.max-backoff must be greater than or equal to distributor.forwarding.queue.min-backoff")
+}
+
+func TestForwardingQueue(t *testing.T) {
+	now := time.Now().UnixMilli()
+	url, status, series := newQueueTestServer(t)
+	// The requests are retried until they succeed, even if the retries of a shard are fast.
+	cfg := testQueueConfig()
+	cfg.Queue.MaxRetries = 1000
+	f, reg := newForwarder(t, cfg, true)
+
+	// The first requests fail with a retriable error.
+	status.Store(http.StatusServiceUnavailable)
+
+	rules := validation.ForwardingRules{"metric1": {}, "metric2": {Ingest: true}}
+	tsToIngest, errCh := f.Forward(context.Background(), url, 0, rules, []mimirpb.PreallocTimeseries{
+		newSample(t, now, 1, 100, "__name__", "metric1", "some_label", "foo"),
+		newSample(t, now, 2, 200, "__name__", "metric1", "some_label", "bar"),
+		newSample(t, now, 3, 300, "__name__", "metric2", "some_label", "foo"),
+	}, "user")
+	require.Len(t, tsToIngest, 1)
+
+	// The push doesn't wait for the forwarding requests.
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+
+	test.Poll(t, time.Second, true, func() interface{} {
+		return testutil.ToFloat64(f.(*forwarder).queues.retries.WithLabelValues("user")) > 0
+	})
+	status.Store(http.StatusOK)
+
+	test.Poll(t, time.Second, []string{
+		`{__name__="metric1", some_label="bar"}`,
+		`{__name__="metric1", some_label="foo"}`,
+		`{__name__="metric2", some_label="foo"}`,
+	}, func() interface{} {
+		return series()
+	})
+
+	test.Poll(t, time.Second, nil, func() interface{} {
+		return testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_distributor_forward_queue_length The number of forwarding requests queued or being sent.
+			# TYPE cortex_distributor_forward_queue_length gauge
+			cortex_distributor_forward_queue_length{user="user"} 0
+		`), "cortex_distributor_forward_queue_length")
+	})
+	require.Equal(t, 2, testutil.CollectAndCount(reg, "cortex_distributor_forward_lag_seconds"))
+}
+
+func TestForwardingQueue_DroppedRequests(t *testing.T) {
+	now := time.Now().UnixMilli()
+
+	for name, tc := range map[string]struct {
+		status         int
+		expectedReason string
+	}{
+		"rejected request": {
+			status:         http.StatusBadRequest,
+			expectedReason: droppedReasonRejected,
+		},
+		"retries exhausted": {
+			status:         http.StatusInternalServerError,
+			expectedReason: droppedReasonRetriesExhausted,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			url, status, _ := newQueueTestServer(t)
+			status.Store(int64(tc.status))
+			f, reg := newForwarder(t, testQueueConfig(), true)
+
+			_, errCh := f.Forward(context.Background(), url, 0, validation.ForwardingRules{"metric1": {}}, []mimirpb.PreallocTimeseries{
+				newSample(t, now, 1, 100, "__name__", "metric1", "some_label", "foo"),
+			}, "user")
+			for err := range errCh {
+				require.NoError(t, err)
+			}
+
+			test.Poll(t, time.Second, nil, func() interface{} {
+				return testutil.GatherAndCompare(reg, strings.NewReader(`
+					# HELP cortex_distributor_forward_queue_dropped_samples_total The total number of samples of the queued forwarding requests which have been dropped.
+					# TYPE cortex_distributor_forward_queue_dropped_samples_total counter
+					cortex_distributor_forward_queue_dropped_samples_total{reason="`+tc.expectedReason+`",user="user"} 1
+				`), "cortex_distributor_forward_queue_dropped_samples_total")
+			})
+		})
+	}
+}
+
+func TestForwardingQueue_Full(t *testing.T) {
+	now := time.Now().UnixMilli()
+
+	// The server blocks the requests until it's released.
+	release := make(chan struct{})
+	received := atomic.NewInt64(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received.Inc()
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	cfg := testQueueConfig()
+	cfg.Queue.Capacity = 1
+	cfg.Queue.Shards = 1
+	f, reg := newForwarder(t, cfg, true)
+
+	forward := func(value float64) {
+		_, errCh := f.Forward(context.Background(), srv.URL, 0, validation.ForwardingRules{"metric1": {}}, []mimirpb.PreallocTimeseries{
+			newSample(t, now, value, 100, "__name__", "metric1", "some_label", "foo"),
+		}, "user")
+		for err := range errCh {
+			require.NoError(t, err)
+		}
+	}
+
+	// The first request is being sent, the second one is queued, and dropped when the third one gets queued.
+	forward(1)
+	test.Poll(t, time.Second, int64(1), func() interface{} { return received.Load() })
+	forward(2)
+	forward(3)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_forward_queue_dropped_samples_total The total number of samples of the queued forwarding requests which have been dropped.
+		# TYPE cortex_distributor_forward_queue_dropped_samples_total counter
+		cortex_distributor_forward_queue_dropped_samples_total{reason="queue_full",user="user"} 1
+
+		# HELP cortex_distributor_forward_queue_length The number of forwarding requests queued or being sent.
+		# TYPE cortex_distributor_forward_queue_length gauge
+		cortex_distributor_forward_queue_length{user="user"} 2
+	`), "cortex_distributor_forward_queue_dropped_samples_total", "cortex_distributor_forward_queue_length"))
+}
+
+func TestForwardingQueue_WAL(t *testing.T) {
+	now := time.Now().UnixMilli()
+	failingURL, status, _ := newQueueTestServer(t)
+	status.Store(http.StatusServiceUnavailable)
+
+	cfg := testQueueConfig()
+	cfg.Queue.MaxRetries = 0
+	cfg.Queue.WALDir = t.TempDir()
+
+	f, _ := newForwarder(t, cfg, false)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), f))
+
+	_, errCh := f.Forward(context.Background(), failingURL, 0, validation.ForwardingRules{"metric1": {}}, []mimirpb.PreallocTimeseries{
+		newSample(t, now, 1, 100, "__name__", "metric1", "some_label", "foo"),
+		newSample(t, now, 2, 200, "__name__", "metric1", "some_label", "bar"),
+	}, "user")
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+
+	// The requests which haven't been sent are kept in the WAL when the forwarder stops.
+	test.Poll(t, time.Second, true, func() interface{} {
+		return testutil.ToFloat64(f.(*forwarder).queues.retries.WithLabelValues("user")) > 0
+	})
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), f))
+	require.Len(t, walBatchFiles(t, cfg.Queue.WALDir), 2)
+
+	// The endpoint of the queue is read from the WAL, so it's replaced by a working one.
+	url, _, series := newQueueTestServer(t)
+	endpointPath := filepath.Join(queueDir(cfg.Queue.WALDir, "user", failingURL), endpointFile)
+	require.NoError(t, os.WriteFile(endpointPath, []byte(url), 0o600))
+
+	// The requests are sent once the forwarder restarts.
+	newForwarder(t, cfg, true)
+
+	test.Poll(t, time.Second, []string{
+		`{__name__="metric1", some_label="bar"}`,
+		`{__name__="metric1", some_label="foo"}`,
+	}, func() interface{} {
+		return series()
+	})
+	test.Poll(t, time.Second, 0, func() interface{} {
+		return len(walBatchFiles(t, cfg.Queue.WALDir))
+	})
+}
+
+// newQueueTestServer returns a remote write server which responds with the returned status, and a function which
+// returns the sorted series it has received.
+func newQueueTestServer(t *testing.T) (string, *atomic.Int64, func() []string) {
+	t.Helper()
+
+	var (
+		mtx    sync.Mutex
+		series []string
+	)
+	status := atomic.NewInt64(http.StatusOK)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		code := int(status.Load())
+		if code != http.StatusOK {
+			http.Error(w, "", code)
+			return
+		}
+
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		writeReq := decodeBody(t, body)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		for _, ts := range writeReq.Timeseries {
+			series = append(series, mimirpb.FromLabelAdaptersToLabels(ts.Labels).String())
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL, status, func() []string {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		res := append([]string(nil), series...)
+		sort.Strings(res)
+		return res
+	}
+}
+
+func walBatchFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	var files []string
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(path, batchFileExt) {
+			files = append(files, path)
+		}
+		return err
+	}))
+	return files
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/globalerror"
+	util_math "github.com/grafana/mimir/pkg/util/math"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -41,6 +42,10 @@ type haTrackerLimits interface {
 	// MaxHAClusters returns max number of clusters that HA tracker should track for a user.
 	// Samples from additional clusters are rejected.
 	MaxHAClusters(user string) int
+
+	// HAPerSeriesDedupWindow returns the window within which the samples of the elected replica take precedence
+	// over the samples of the non-elected replicas, per series. 0 if the per-series deduplication is disabled.
+	HAPerSeriesDedupWindow(user string) time.Duration
 }
 
 // ProtoReplicaDescFactory makes new InstanceDescs
@@ -125,6 +130,7 @@ type haTracker struct {
 	electedReplicaTimestamp       *prometheus.GaugeVec
 	electedReplicaPropagationTime prometheus.Histogram
 	kvCASCalls                    *prometheus.CounterVec
+	gapFilledSamples              *prometheus.CounterVec
 
 	cleanupRuns               prometheus.Counter
 	replicasMarkedForDeletion prometheus.Counter
@@ -138,6 +144,18 @@ type haClusterInfo struct {
 	electedLastSeenTimestamp    int64
 	nonElectedLastSeenReplica   string
 	nonElectedLastSeenTimestamp int64
+
+	// Tracked only when the per-series deduplication is enabled. Key = hash of the series labels, without the replica label.
+	series           map[uint64]*haSeriesInfo
+	gapFilledSamples uint64
+}
+
+// For one series of a cluster, the information we need to fill the gaps of the elected replica
+// with the samples of the non-elected replicas.
+type haSeriesInfo struct {
+	electedLastSampleTimestamp int64
+	fillerReplica              string
+	fillerLastSampleTimestamp  int64
 }
 
 // newHATracker returns a new HA cluster tracker using either Consul
@@ -172,6 +190,10 @@ func newHATracker(cfg HATrackerConfig, limits haTrackerLimits, reg prometheus.Re
 			Name: "cortex_ha_tracker_kv_store_cas_total",
 			Help: "The total number of CAS calls to the KV store for a user ID/cluster.",
 		}, []string{"user", "cluster"}),
+		gapFilledSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ha_tracker_gap_filled_samples_total",
+			Help: "The total number of samples accepted from a non-elected replica to fill the gaps of the elected replica, when the per-series deduplication is enabled.",
+		}, []string{"user", "cluster"}),
 
 		cleanupRuns: promauto.With(reg).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_ha_tracker_replicas_cleanup_started_total",
@@ -242,6 +264,7 @@ func (h *haTracker) loop(ctx context.Context) error {
 		if replica.DeletedAt > 0 {
 			h.electedReplicaChanges.DeleteLabelValues(user, cluster)
 			h.electedReplicaTimestamp.DeleteLabelValues(user, cluster)
+			h.gapFilledSamples.DeleteLabelValues(user, cluster)
 
 			h.electedLock.Lock()
 			defer h.electedLock.Unlock()
@@ -288,6 +311,7 @@ func (h *haTracker) updateKVLoop(ctx context.Context) {
 			return
 		case t := <-tick.C:
 			h.updateKVStoreAll(ctx, t)
+			h.purgeStaleSeries(t)
 		case t := <-cleanupTick.C:
 			h.cleanupRuns.Inc()
 			h.cleanupOldReplicas(ctx, t.Add(-deletionTimeout))
@@ -448,6 +472,156 @@ func (h *haTracker) checkReplica(ctx context.Context, userID, cluster, replica s
 	return h.checkReplica(ctx, userID, cluster, replica, now)
 }
 
+// trackElectedReplicaSamples records the timestamp of the latest sample of each series received from the elected replica
+// of the cluster, when the per-series deduplication is enabled. The series labels must not include the replica label.
+func (h *haTracker) trackElectedReplicaSamples(userID, cluster string, series []mimirpb.PreallocTimeseries) {
+	if h.limits.HAPerSeriesDedupWindow(userID) <= 0 {
+		return
+	}
+
+	// Hash the series before taking the lock, which is shared by the requests of all the tenants.
+	hashes := seriesHashes(series)
+
+	h.electedLock.Lock()
+	defer h.electedLock.Unlock()
+
+	entry := h.clusters[userID][cluster]
+	if entry == nil {
+		return
+	}
+
+	for idx, ts := range series {
+		s := entry.seriesInfo(hashes[idx])
+		for _, sample := range ts.Samples {
+			s.electedLastSampleTimestamp = util_math.Max64(s.electedLastSampleTimestamp, sample.TimestampMs)
+		}
+		for _, hist := range ts.Histograms {
+			s.electedLastSampleTimestamp = util_math.Max64(s.electedLastSampleTimestamp, hist.Timestamp)
+		}
+	}
+}
+
+// fillElectedReplicaGaps keeps only the samples received from a non-elected replica of the cluster which fill a gap
+// of the elected replica, that is the samples of the series for which the elected replica has not sent any sample
+// within the per-series deduplication window. The gaps of a series are filled by a single non-elected replica at a time.
+// The series left without samples are removed. The series labels must not include the replica label.
+// Returns the remaining series and the number of removed samples.
+func (h *haTracker) fillElectedReplicaGaps(userID, cluster, replica string, series []mimirpb.PreallocTimeseries) ([]mimirpb.PreallocTimeseries, int) {
+	window := h.limits.HAPerSeriesDedupWindow(userID).Milliseconds()
+	hashes := seriesHashes(series)
+
+	h.electedLock.Lock()
+	defer h.electedLock.Unlock()
+
+	entry := h.clusters[userID][cluster]
+
+	var (
+		removeIndexes []int
+		removed       int
+		filled        int
+	)
+	for idx, ts := range series {
+		accept := func(int64) bool { return false }
+		if entry != nil {
+			s := entry.seriesInfo(hashes[idx])
+			accept = func(t int64) bool {
+				// A series never received from the elected replica is a gap too.
+				if s.electedLastSampleTimestamp > 0 && t-s.electedLastSampleTimestamp <= window {
+					return false
+				}
+				if s.fillerReplica != replica && s.fillerReplica != "" && t-s.fillerLastSampleTimestamp <= window {
+					return false
+				}
+				s.fillerReplica = replica
+				s.fillerLastSampleTimestamp = util_math.Max64(s.fillerLastSampleTimestamp, t)
+				return true
+			}
+		}
+
+		samples := ts.Samples[:0]
+		for _, sample := range ts.Samples {
+			if accept(sample.TimestampMs) {
+				samples = append(samples, sample)
+			}
+		}
+		histograms := ts.Histograms[:0]
+		for _, hist := range ts.Histograms {
+			if accept(hist.Timestamp) {
+				histograms = append(histograms, hist)
+			}
+		}
+
+		removed += len(ts.Samples) - len(samples) + len(ts.Histograms) - len(histograms)
+		filled += len(samples) + len(histograms)
+		ts.Samples, ts.Histograms = samples, histograms
+
+		if len(samples) == 0 && len(histograms) == 0 {
+			removeIndexes = append(removeIndexes, idx)
+		}
+	}
+
+	if filled > 0 {
+		entry.gapFilledSamples += uint64(filled)
+		h.gapFilledSamples.WithLabelValues(userID, cluster).Add(float64(filled))
+	}
+
+	if len(removeIndexes) > 0 {
+		for _, idx := range removeIndexes {
+			mimirpb.ReusePreallocTimeseries(&series[idx])
+		}
+		series = util.RemoveSliceIndexes(series, removeIndexes)
+	}
+
+	return series, removed
+}
+
+// purgeStaleSeries removes the series which haven't received any sample within the per-series deduplication window,
+// since the next samples of these series are accepted from any replica anyway.
+func (h *haTracker) purgeStaleSeries(now time.Time) {
+	h.electedLock.Lock()
+	defer h.electedLock.Unlock()
+
+	for userID, clusters := range h.clusters {
+		window := h.limits.HAPerSeriesDedupWindow(userID)
+		deadline := timestamp.FromTime(now.Add(-window))
+
+		for _, entry := range clusters {
+			if window <= 0 {
+				entry.series = nil
+				continue
+			}
+
+			for key, s := range entry.series {
+				if util_math.Max64(s.electedLastSampleTimestamp, s.fillerLastSampleTimestamp) < deadline {
+					delete(entry.series, key)
+				}
+			}
+		}
+	}
+}
+
+// seriesHashes returns the hash of the labels of each series.
+func seriesHashes(series []mimirpb.PreallocTimeseries) []uint64 {
+	hashes := make([]uint64, len(series))
+	for idx, ts := range series {
+		hashes[idx] = mimirpb.FromLabelAdaptersToLabels(ts.Labels).Hash()
+	}
+	return hashes
+}
+
+// Must be called with electedLock held.
+func (c *haClusterInfo) seriesInfo(key uint64) *haSeriesInfo {
+	if c.series == nil {
+		c.series = map[uint64]*haSeriesInfo{}
+	}
+	s := c.series[key]
+	if s == nil {
+		s = &haSeriesInfo{}
+		c.series[key] = s
+	}
+	return s
+}
+
 func (h *haTracker) withinUpdateTimeout(now time.Time, receivedAt int64) bool {
 	return now.Sub(timestamp.Time(receivedAt)) < h.cfg.UpdateTimeout+h.updateTimeoutJitter
 }
@@ -564,4 +738,5 @@ func (h *haTracker) cleanupHATrackerMetricsForUser(userID string) {
 	h.electedReplicaChanges.DeletePartialMatch(filter)
 	h.electedReplicaTimestamp.DeletePartialMatch(filter)
 	h.kvCASCalls.DeletePartialMatch(filter)
+	h.gapFilledSamples.DeletePartialMatch(filter)
 }
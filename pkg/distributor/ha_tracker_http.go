@@ -33,6 +33,11 @@ type haTrackerReplica struct {
 	ElectedAt    time.Time     `json:"electedAt"`
 	UpdateTime   time.Duration `json:"updateDuration"`
 	FailoverTime time.Duration `json:"failoverDuration"`
+
+	// Populated only when the per-series deduplication is enabled for the user.
+	PerSeriesDedupWindow time.Duration `json:"perSeriesDedupWindow,omitempty"`
+	TrackedSeries        int           `json:"trackedSeries,omitempty"`
+	GapFilledSamples     uint64        `json:"gapFilledSamples,omitempty"`
 }
 
 func (h *haTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -49,6 +54,10 @@ func (h *haTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				ElectedAt:    timestamp.Time(desc.ReceivedAt),
 				UpdateTime:   time.Until(timestamp.Time(desc.ReceivedAt).Add(h.cfg.UpdateTimeout)),
 				FailoverTime: time.Until(timestamp.Time(desc.ReceivedAt).Add(h.cfg.FailoverTimeout)),
+
+				PerSeriesDedupWindow: h.limits.HAPerSeriesDedupWindow(userID),
+				TrackedSeries:        len(entry.series),
+				GapFilledSamples:     entry.gapFilledSamples,
 			})
 		}
 	}
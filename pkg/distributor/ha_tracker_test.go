@@ -8,6 +8,8 @@ package distributor
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -389,6 +391,83 @@ func TestCheckReplicaUpdateTimeout(t *testing.T) {
 }
 
 // Test that writes only happen every write timeout.
+func TestHATracker_PerSeriesDedup(t *testing.T) {
+	const (
+		user    = "user"
+		cluster = "c1"
+	)
+
+	reg := prometheus.NewPedanticRegistry()
+	codec := GetReplicaDescCodec()
+	kvStore, closer := consul.NewInMemoryClient(codec, log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	mock := kv.PrefixClient(kvStore, "prefix")
+	c, err := newHATracker(HATrackerConfig{
+		EnableHATracker:        true,
+		KVStore:                kv.Config{Mock: mock},
+		UpdateTimeout:          time.Second,
+		UpdateTimeoutJitterMax: 0,
+		FailoverTimeout:        time.Second,
+	}, trackerLimits{maxClusters: 100, perSeriesDedupWindow: 10 * time.Second}, reg, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	defer services.StopAndAwaitTerminated(context.Background(), c) //nolint:errcheck
+
+	series := func(name string, timestamps ...int64) mimirpb.PreallocTimeseries {
+		ts := mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels: []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: name}, {Name: "cluster", Value: cluster}},
+		}}
+		for _, t := range timestamps {
+			ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: t, Value: 1})
+		}
+		return ts
+	}
+	names := func(series []mimirpb.PreallocTimeseries) []string {
+		var res []string
+		for _, ts := range series {
+			res = append(res, fmt.Sprintf("%s%v", mimirpb.FromLabelAdaptersToLabels(ts.Labels).Get(labels.MetricName), ts.Samples))
+		}
+		return res
+	}
+
+	now := time.Now()
+	require.NoError(t, c.checkReplica(context.Background(), user, cluster, "r1", now))
+	assert.True(t, errors.Is(c.checkReplica(context.Background(), user, cluster, "r2", now), replicasNotMatchError{}))
+
+	c.trackElectedReplicaSamples(user, cluster, []mimirpb.PreallocTimeseries{series("a", 10000, 20000), series("b", 20000)})
+
+	// The samples within the window of the elected replica are deduplicated, the others fill its gaps.
+	filled, removed := c.fillElectedReplicaGaps(user, cluster, "r2", []mimirpb.PreallocTimeseries{series("a", 25000, 35000), series("b", 30000), series("c", 30000)})
+	assert.Equal(t, []string{"a[{35000 1}]", "c[{30000 1}]"}, names(filled))
+	assert.Equal(t, 2, removed)
+
+	// The gaps of a series are filled by a single non-elected replica at a time.
+	filled, removed = c.fillElectedReplicaGaps(user, cluster, "r3", []mimirpb.PreallocTimeseries{series("a", 40000), series("c", 50000)})
+	assert.Equal(t, []string{"c[{50000 1}]"}, names(filled))
+	assert.Equal(t, 1, removed)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_ha_tracker_gap_filled_samples_total The total number of samples accepted from a non-elected replica to fill the gaps of the elected replica, when the per-series deduplication is enabled.
+		# TYPE cortex_ha_tracker_gap_filled_samples_total counter
+		cortex_ha_tracker_gap_filled_samples_total{cluster="c1",user="user"} 3
+	`), "cortex_ha_tracker_gap_filled_samples_total"))
+
+	// The series which haven't received any sample within the window are purged.
+	c.purgeStaleSeries(time.UnixMilli(46000))
+	c.electedLock.RLock()
+	assert.Len(t, c.clusters[user][cluster].series, 1)
+	c.electedLock.RUnlock()
+
+	// The per-series deduplication status is exposed on the status page.
+	req := httptest.NewRequest(http.MethodGet, "/distributor/ha_tracker", nil)
+	req.Header.Set("Accept", "application/json")
+	resp := httptest.NewRecorder()
+	c.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"perSeriesDedupWindow":10000000000,"trackedSeries":1,"gapFilledSamples":3`)
+}
+
 func TestCheckReplicaMultiUser(t *testing.T) {
 	replica := "r1"
 	cluster := "c1"
@@ -679,13 +758,18 @@ func TestReplicasNotMatchError(t *testing.T) {
 }
 
 type trackerLimits struct {
-	maxClusters int
+	maxClusters          int
+	perSeriesDedupWindow time.Duration
 }
 
 func (l trackerLimits) MaxHAClusters(_ string) int {
 	return l.maxClusters
 }
 
+func (l trackerLimits) HAPerSeriesDedupWindow(_ string) time.Duration {
+	return l.perSeriesDedupWindow
+}
+
 func TestHATracker_MetricsCleanup(t *testing.T) {
 	reg := prometheus.NewPedanticRegistry()
 	tr, err := newHATracker(HATrackerConfig{EnableHATracker: false}, nil, reg, log.NewNopLogger())
@@ -237,6 +237,11 @@ func (d *Distributor) queryIngesterStream(ctx context.Context, replicationSet ri
 					} else {
 						existing.Samples = mergeSamples(existing.Samples, series.Samples)
 					}
+					if existing.Histograms == nil {
+						existing.Histograms = series.Histograms
+					} else {
+						existing.Histograms = mergeHistograms(existing.Histograms, series.Histograms)
+					}
 					hashToTimeSeries[key] = existing
 				}
 			}
@@ -361,6 +366,29 @@ func sameSamples(a, b []mimirpb.Sample) bool {
 	return true
 }
 
+// Merges and dedupes two sorted slices with histograms together.
+func mergeHistograms(a, b []mimirpb.Histogram) []mimirpb.Histogram {
+	result := make([]mimirpb.Histogram, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i].Timestamp < b[j].Timestamp {
+			result = append(result, a[i])
+			i++
+		} else if a[i].Timestamp > b[j].Timestamp {
+			result = append(result, b[j])
+			j++
+		} else {
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	// Add the rest of a or b. One of them is empty now.
+	result = append(result, a[i:]...)
+	result = append(result, b[j:]...)
+	return result
+}
+
 // Build a slice of chunks, eliminating duplicates.
 // This is O(N^2) but most of the time N is small.
 func accumulateChunks(a, b []ingester_client.Chunk) []ingester_client.Chunk {
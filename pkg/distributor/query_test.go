@@ -119,6 +119,30 @@ func TestMergeSamplesIntoFirstNilB(t *testing.T) {
 	require.Equal(t, b, a)
 }
 
+func TestMergeHistograms(t *testing.T) {
+	a := []mimirpb.Histogram{
+		{Sum: 1, Timestamp: 10},
+		{Sum: 2, Timestamp: 20},
+		{Sum: 3, Timestamp: 30},
+	}
+
+	b := []mimirpb.Histogram{
+		{Sum: 1, Timestamp: 5},
+		{Sum: 3, Timestamp: 30},
+		{Sum: 4, Timestamp: 40},
+	}
+
+	require.Equal(t, []mimirpb.Histogram{
+		{Sum: 1, Timestamp: 5},
+		{Sum: 1, Timestamp: 10},
+		{Sum: 2, Timestamp: 20},
+		{Sum: 3, Timestamp: 30},
+		{Sum: 4, Timestamp: 40},
+	}, mergeHistograms(a, b))
+	require.Equal(t, a, mergeHistograms(a, nil))
+	require.Equal(t, b, mergeHistograms(nil, b))
+}
+
 func TestMergeExemplars(t *testing.T) {
 	now := timestamp.FromTime(time.Now())
 	exemplar1 := mimirpb.Exemplar{Labels: mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("traceID", "trace-1")), TimestampMs: now, Value: 1}
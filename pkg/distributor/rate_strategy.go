@@ -48,6 +48,36 @@ func (s *globalStrategy) Burst(tenantID string) int {
 	return s.baseStrategy.Burst(tenantID)
 }
 
+// haRoutedStrategy applies the limit of the routed strategy to the tenants deduplicated per series by the HA tracker,
+// whose push requests are all routed to a single distributor, and the limit of the other strategy to other tenants.
+type haRoutedStrategy struct {
+	routedStrategy limiter.RateLimiterStrategy
+	otherStrategy  limiter.RateLimiterStrategy
+	limits         *validation.Overrides
+}
+
+func newHARoutedRateStrategy(routedStrategy, otherStrategy limiter.RateLimiterStrategy, limits *validation.Overrides) limiter.RateLimiterStrategy {
+	return &haRoutedStrategy{
+		routedStrategy: routedStrategy,
+		otherStrategy:  otherStrategy,
+		limits:         limits,
+	}
+}
+
+func (s *haRoutedStrategy) Limit(tenantID string) float64 {
+	if perSeriesHADedupEnabled(s.limits, tenantID) {
+		return s.routedStrategy.Limit(tenantID)
+	}
+	return s.otherStrategy.Limit(tenantID)
+}
+
+func (s *haRoutedStrategy) Burst(tenantID string) int {
+	if perSeriesHADedupEnabled(s.limits, tenantID) {
+		return s.routedStrategy.Burst(tenantID)
+	}
+	return s.otherStrategy.Burst(tenantID)
+}
+
 type requestRateStrategy struct {
 	limits *validation.Overrides
 }
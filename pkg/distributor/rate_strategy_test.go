@@ -7,7 +7,9 @@ package distributor
 
 import (
 	"testing"
+	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -33,6 +35,24 @@ func TestIngestionRateStrategy(t *testing.T) {
 		assert.Equal(t, strategy.Burst("test"), 10000)
 	})
 
+	t.Run("rate limiter should not share the limit of the tenants deduplicated per series across the number of distributors", func(t *testing.T) {
+		// Init limits overrides
+		overrides, err := validation.NewOverrides(validation.Limits{
+			IngestionRate:          float64(1000),
+			IngestionBurstSize:     10000,
+			AcceptHASamples:        true,
+			HAPerSeriesDedupWindow: model.Duration(time.Minute),
+		}, nil)
+		require.NoError(t, err)
+
+		mockRing := newReadLifecyclerMock()
+		mockRing.On("HealthyInstancesCount").Return(2)
+
+		strategy := newHARoutedRateStrategy(newIngestionRateStrategy(overrides), newGlobalRateStrategy(newIngestionRateStrategy(overrides), mockRing), overrides)
+		assert.Equal(t, strategy.Limit("test"), float64(1000))
+		assert.Equal(t, strategy.Burst("test"), 10000)
+	})
+
 	t.Run("infinite rate limiter should return unlimited settings", func(t *testing.T) {
 		strategy := newInfiniteRateStrategy()
 
@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/grpcclient"
+	"github.com/grafana/dskit/ring"
+	ring_client "github.com/grafana/dskit/ring/client"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/httpgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	grpc_metadata "google.golang.org/grpc/metadata"
+
+	"github.com/grafana/mimir/pkg/distributor/distributorpb"
+	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/extract"
+	"github.com/grafana/mimir/pkg/util/push"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// The push requests whose processing depends on state kept in the memory of the distributors are routed to the
+// distributor in charge of them, so that this state is complete whichever distributor receives the requests.
+const (
+	// routeMetadataKey is the gRPC metadata key set to the route of the push requests routed to another distributor.
+	routeMetadataKey = "x-mimir-distributor-route"
+
+	// haTrackerRoute routes all the push requests of the tenants deduplicated per series by the HA tracker, which
+	// tracks the samples received from the elected replicas in memory.
+	haTrackerRoute = "ha-tracker"
+
+	// aggregationRoute routes the series of the metrics with an aggregation rule, whose aggregated series are
+	// kept in memory.
+	aggregationRoute = "aggregation"
+)
+
+// HealthAndDistributorClient is the union of DistributorClient and grpc_health_v1.HealthClient.
+type HealthAndDistributorClient interface {
+	distributorpb.DistributorClient
+	grpc_health_v1.HealthClient
+	Close() error
+}
+
+type closableHealthAndDistributorClient struct {
+	distributorpb.DistributorClient
+	grpc_health_v1.HealthClient
+	conn *grpc.ClientConn
+}
+
+// MakeDistributorClient makes a new DistributorClient, used to route the push requests to other distributors.
+func MakeDistributorClient(addr string, cfg grpcclient.Config, requestDuration *prometheus.HistogramVec) (HealthAndDistributorClient, error) {
+	dialOpts, err := cfg.DialOption(grpcclient.Instrument(requestDuration))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.Dial(addr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &closableHealthAndDistributorClient{
+		DistributorClient: distributorpb.NewDistributorClient(conn),
+		HealthClient:      grpc_health_v1.NewHealthClient(conn),
+		conn:              conn,
+	}, nil
+}
+
+func (c *closableHealthAndDistributorClient) Close() error {
+	return c.conn.Close()
+}
+
+// newDistributorPool returns the pool of the clients to the distributors of the ring.
+func newDistributorPool(cfg PoolConfig, distributorsRing ring.ReadRing, factory ring_client.PoolFactory, reg prometheus.Registerer, logger log.Logger) *ring_client.Pool {
+	poolCfg := ring_client.PoolConfig{
+		CheckInterval: cfg.ClientCleanupPeriod,
+		// The health of the distributors is already tracked by the heartbeats of the ring.
+		HealthCheckEnabled: false,
+	}
+
+	clients := promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+		Name: "cortex_distributor_routing_clients",
+		Help: "The current number of clients to the other distributors, used to route the push requests.",
+	})
+	return ring_client.NewPool("distributor", poolCfg, ring_client.NewRingServiceDiscovery(distributorsRing), factory, clients, logger)
+}
+
+// wrapRoutedPushWithMiddlewares returns push function wrapped in the Distributor's middlewares which the requests
+// routed to this distributor with the given route haven't been through on the distributor which received them.
+func (d *Distributor) wrapRoutedPushWithMiddlewares(route string, next push.Func) push.Func {
+	var middlewares []func(push.Func) push.Func
+
+	// The middlewares are in the same order as in wrapPushWithMiddlewares.
+	switch route {
+	case haTrackerRoute:
+		middlewares = append(middlewares, d.prePushHaDedupeMiddleware)
+		middlewares = append(middlewares, d.prePushRelabelMiddleware)
+		middlewares = append(middlewares, d.prePushValidationMiddleware)
+		fallthrough
+	case aggregationRoute:
+		middlewares = append(middlewares, d.prePushAggregationMiddleware)
+		middlewares = append(middlewares, d.prePushForwardingMiddleware)
+		middlewares = append(middlewares, d.prePushEphemeralMiddleware)
+	}
+
+	for ix := len(middlewares) - 1; ix >= 0; ix-- {
+		next = middlewares[ix](next)
+	}
+
+	return next
+}
+
+// routeFromIncomingContext returns the route of the push request received from another distributor, if any.
+func routeFromIncomingContext(ctx context.Context) string {
+	md, ok := grpc_metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(routeMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// isRoutedRequest returns whether the push request has been routed to this distributor with the given route.
+func isRoutedRequest(ctx context.Context, route string) bool {
+	routed, _ := ctx.Value(routedRequest).(string)
+	return routed == route
+}
+
+// routeOwner returns the address of the distributor in charge of the given token, and whether it's this distributor.
+// The distributor is chosen among the healthy ones by rendezvous hashing, so that only the keys of a distributor
+// joining or leaving the ring move to another distributor. A distributor which isn't part of the distributors ring,
+// like the one of the ruler, is in charge of all the requests it receives, since they're only ever sent to it.
+func (d *Distributor) routeOwner(token uint32) (addr string, self bool, err error) {
+	if d.distributorsRing == nil {
+		return "", true, nil
+	}
+
+	healthy, err := d.distributorsRing.GetAllHealthy(ring.WriteNoExtend)
+	if err != nil {
+		return "", false, err
+	}
+
+	var maxWeight uint32
+	for i, instance := range healthy.Instances {
+		weight := ingester_client.HashAdd32(token, instance.Addr)
+		if i == 0 || weight > maxWeight {
+			addr, maxWeight = instance.Addr, weight
+		}
+	}
+	return addr, addr == d.distributorsLifecycler.GetInstanceAddr(), nil
+}
+
+// routePush pushes the request to the distributor with the given address, which processes it from the middleware
+// of the given route onwards.
+func (d *Distributor) routePush(ctx context.Context, addr, route string, req *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error) {
+	c, err := d.distributorPool.GetClientFor(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(grpc_metadata.AppendToOutgoingContext(ctx, routeMetadataKey, route), d.cfg.RemoteTimeout)
+	defer cancel()
+
+	resp, err := c.(distributorpb.DistributorClient).Push(ctx, req)
+	if _, ok := httpgrpc.HTTPResponseFromError(err); ok {
+		// The request has been rejected by the other distributor, which is reported as is.
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed routing push request to distributor %s", addr)
+	}
+	return resp, nil
+}
+
+// routeAggregatedSeries routes the series of the metrics with an aggregation rule to the distributor in charge of their
+// metric, so that all the samples of a series are aggregated by the same distributor. It returns the other series, the
+// first error returned by the distributors the series have been routed to, and an error if they couldn't be routed.
+func (d *Distributor) routeAggregatedSeries(ctx context.Context, userID string, rules validation.AggregationRules, source mimirpb.WriteRequest_SourceEnum, ts []mimirpb.PreallocTimeseries) (_ []mimirpb.PreallocTimeseries, routedErr, err error) {
+	var (
+		reqs          = map[string]*mimirpb.WriteRequest{}
+		removeIndexes []int
+	)
+	for idx, series := range ts {
+		metric, err := extract.UnsafeMetricNameFromLabelAdapters(series.Labels)
+		if err != nil {
+			continue
+		}
+		if _, ok := rules[metric]; !ok {
+			continue
+		}
+
+		addr, self, err := d.routeOwner(shardByMetricName(userID, metric))
+		if err != nil {
+			return nil, nil, err
+		}
+		if self {
+			continue
+		}
+
+		req, ok := reqs[addr]
+		if !ok {
+			req = &mimirpb.WriteRequest{Source: source}
+			reqs[addr] = req
+		}
+		req.Timeseries = append(req.Timeseries, series)
+		removeIndexes = append(removeIndexes, idx)
+	}
+	if len(reqs) == 0 {
+		return ts, nil, nil
+	}
+
+	for addr, req := range reqs {
+		if _, err := d.routePush(ctx, addr, aggregationRoute, req); err != nil && routedErr == nil {
+			routedErr = err
+		}
+	}
+
+	for _, idx := range removeIndexes {
+		mimirpb.ReusePreallocTimeseries(&ts[idx])
+	}
+	return util.RemoveSliceIndexes(ts, removeIndexes), routedErr, nil
+}
@@ -58,8 +58,8 @@ type Limits interface {
 	// This method is copied from compactor.ConfigProvider.
 	CompactorSplitAndMergeShards(userID string) int
 
-	// CompactorBlocksRetentionPeriod returns the retention period for a given user.
-	CompactorBlocksRetentionPeriod(userID string) time.Duration
+	// CompactorBlocksMaxRetentionPeriod returns the longest retention period of the blocks of any resolution for a given user.
+	CompactorBlocksMaxRetentionPeriod(userID string) time.Duration
 
 	// OutOfOrderTimeWindow returns the out-of-order time window for the user.
 	OutOfOrderTimeWindow(userID string) model.Duration
@@ -67,6 +67,12 @@ type Limits interface {
 	// CreationGracePeriod returns the time interval to control how far into the future
 	// incoming samples are accepted compared to the wall clock.
 	CreationGracePeriod(userID string) time.Duration
+
+	// QueryCostBudget returns the maximum estimated cost of a query, expressed as number of fetched series. 0 to disable.
+	QueryCostBudget(userID string) int
+
+	// QueryCostBudgetAction returns the action taken on the queries whose estimated cost exceeds the budget.
+	QueryCostBudgetAction(userID string) string
 }
 
 type limitsMiddleware struct {
@@ -96,7 +102,7 @@ func (l limitsMiddleware) Do(ctx context.Context, r Request) (Response, error) {
 	}
 
 	// Clamp the time range based on the max query lookback and block retention period.
-	blocksRetentionPeriod := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, l.CompactorBlocksRetentionPeriod)
+	blocksRetentionPeriod := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, l.CompactorBlocksMaxRetentionPeriod)
 	maxQueryLookback := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, l.MaxQueryLookback)
 	maxLookback := util_math.MinDuration(blocksRetentionPeriod, maxQueryLookback)
 	if maxLookback > 0 {
@@ -284,6 +290,7 @@ func (rth roundTripperHandler) Do(ctx context.Context, r Request) (Response, err
 	if err := user.InjectOrgIDIntoHTTPRequest(ctx, request); err != nil {
 		return nil, apierror.New(apierror.TypeBadData, err.Error())
 	}
+	injectQueryClassIntoHTTPRequest(ctx, request)
 
 	response, err := rth.next.RoundTrip(request)
 	if err != nil {
@@ -291,6 +291,8 @@ type mockLimits struct {
 	compactorBlocksRetentionPeriod time.Duration
 	outOfOrderTimeWindow           model.Duration
 	creationGracePeriod            time.Duration
+	queryCostBudget                int
+	queryCostBudgetAction          string
 }
 
 func (m mockLimits) MaxQueryLookback(string) time.Duration {
@@ -331,11 +333,19 @@ func (m mockLimits) SplitInstantQueriesByInterval(string) time.Duration {
 	return m.splitInstantQueriesInterval
 }
 
+func (m mockLimits) QueryCostBudget(string) int {
+	return m.queryCostBudget
+}
+
+func (m mockLimits) QueryCostBudgetAction(string) string {
+	return m.queryCostBudgetAction
+}
+
 func (m mockLimits) CompactorSplitAndMergeShards(userID string) int {
 	return m.compactorShards
 }
 
-func (m mockLimits) CompactorBlocksRetentionPeriod(userID string) time.Duration {
+func (m mockLimits) CompactorBlocksMaxRetentionPeriod(userID string) time.Duration {
 	return m.compactorBlocksRetentionPeriod
 }
 
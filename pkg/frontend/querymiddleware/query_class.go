@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
+)
+
+const (
+	// queryClassLongRange is the class of the range queries whose time range is longer than the configured threshold.
+	queryClassLongRange = "long-range"
+
+	// queryClassDashboard is the class of the queries issued by Grafana dashboards.
+	queryClassDashboard = "dashboard"
+
+	// grafanaDashboardUIDHeader is the header set by Grafana on the queries issued by dashboard panels.
+	grafanaDashboardUIDHeader = "X-Dashboard-Uid"
+)
+
+type queryClassContextKey int
+
+const queryClassKey queryClassContextKey = 0
+
+// newQueryClassTripperware returns a Tripperware which assigns a class to each query, used by the query-scheduler
+// to prioritize the queries of the same tenant. The class is propagated to all the requests the query is split into.
+// The class set in the requests of untrusted callers is removed, so that it doesn't reach the query-scheduler.
+func newQueryClassTripperware(longRangeThreshold time.Duration) Tripperware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			switch class := classifyQuery(r, longRangeThreshold); {
+			case class != "":
+				r = r.Clone(context.WithValue(r.Context(), queryClassKey, class))
+				r.Header.Set(httpgrpcutil.QueryClassHeader, class)
+			case r.Header.Get(httpgrpcutil.QueryClassHeader) != "":
+				r = r.Clone(r.Context())
+				r.Header.Del(httpgrpcutil.QueryClassHeader)
+			}
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// classifyQuery returns the class of the query. The class computed from the query takes precedence over the
+// class explicitly set in the request, which is only accepted from trusted internal callers, so that the
+// requests can't escape the in-flight cap of the computed class.
+func classifyQuery(r *http.Request, longRangeThreshold time.Duration) string {
+	if longRangeThreshold > 0 && isRangeQuery(r.URL.Path) {
+		start, startErr := util.ParseTime(r.FormValue("start"))
+		end, endErr := util.ParseTime(r.FormValue("end"))
+		if startErr == nil && endErr == nil && time.Duration(end-start)*time.Millisecond > longRangeThreshold {
+			return queryClassLongRange
+		}
+	}
+
+	if r.Header.Get(grafanaDashboardUIDHeader) != "" {
+		return queryClassDashboard
+	}
+
+	if isTrustedInternalRequest(r) {
+		return r.Header.Get(httpgrpcutil.QueryClassHeader)
+	}
+
+	return ""
+}
+
+// isTrustedInternalRequest returns whether the request has been received through the gRPC server, which is
+// used by internal callers only, like the ruler evaluating the rules remotely, rather than through the HTTP server.
+func isTrustedInternalRequest(r *http.Request) bool {
+	_, ok := grpc.Method(r.Context())
+	return ok
+}
+
+// injectQueryClassIntoHTTPRequest sets the query class stored in the context, if any, in the request headers.
+func injectQueryClassIntoHTTPRequest(ctx context.Context, r *http.Request) {
+	if class, ok := ctx.Value(queryClassKey).(string); ok {
+		r.Header.Set(httpgrpcutil.QueryClassHeader, class)
+	}
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
+)
+
+func TestClassifyQuery(t *testing.T) {
+	for name, tc := range map[string]struct {
+		url      string
+		headers  map[string]string
+		trusted  bool
+		expected string
+	}{
+		"no class": {
+			url: "/api/v1/query_range?query=up&start=0&end=3600&step=60",
+		},
+		"explicit class from trusted caller": {
+			url:      "/api/v1/query_range?query=up&start=0&end=3600&step=60",
+			headers:  map[string]string{"X-Mimir-Query-Class": "ruler"},
+			trusted:  true,
+			expected: "ruler",
+		},
+		"explicit class from untrusted caller": {
+			url:     "/api/v1/query_range?query=up&start=0&end=3600&step=60",
+			headers: map[string]string{"X-Mimir-Query-Class": "ruler"},
+		},
+		"explicit class from trusted caller doesn't override computed class": {
+			url:      "/api/v1/query_range?query=up&start=0&end=864000&step=60",
+			headers:  map[string]string{"X-Mimir-Query-Class": "ruler"},
+			trusted:  true,
+			expected: queryClassLongRange,
+		},
+		"long-range query": {
+			url:      "/api/v1/query_range?query=up&start=0&end=864000&step=60",
+			headers:  map[string]string{"X-Dashboard-Uid": "abc"},
+			expected: queryClassLongRange,
+		},
+		"short range dashboard query": {
+			url:      "/api/v1/query_range?query=up&start=0&end=3600&step=60",
+			headers:  map[string]string{"X-Dashboard-Uid": "abc"},
+			expected: queryClassDashboard,
+		},
+		"instant query": {
+			url: "/api/v1/query?query=up&time=864000",
+		},
+		"invalid range query": {
+			url: "/api/v1/query_range?query=up&start=foo&end=864000&step=60",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			if tc.trusted {
+				r = r.WithContext(grpc.NewContextWithServerTransportStream(r.Context(), &serverTransportStreamMock{}))
+			}
+
+			assert.Equal(t, tc.expected, classifyQuery(r, 24*time.Hour))
+		})
+	}
+}
+
+func TestQueryClassTripperware(t *testing.T) {
+	var downstream *http.Request
+	tripper := newQueryClassTripperware(24 * time.Hour)(RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		downstream = r
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?query=up&start=0&end=864000&step=60", nil)
+	_, err := tripper.RoundTrip(r)
+	require.NoError(t, err)
+
+	require.NotNil(t, downstream)
+	assert.Equal(t, queryClassLongRange, downstream.Header.Get(httpgrpcutil.QueryClassHeader))
+	assert.Empty(t, r.Header.Get(httpgrpcutil.QueryClassHeader), "the original request should not be modified")
+
+	// The class is propagated to the requests the query is split into.
+	subRequest := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?query=up&start=0&end=3600&step=60", nil)
+	injectQueryClassIntoHTTPRequest(downstream.Context(), subRequest)
+	assert.Equal(t, queryClassLongRange, subRequest.Header.Get(httpgrpcutil.QueryClassHeader))
+
+	// Requests without a class are left untouched.
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+	_, err = tripper.RoundTrip(r)
+	require.NoError(t, err)
+	assert.Same(t, r, downstream)
+
+	// The class set by untrusted callers is removed.
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+	r.Header.Set(httpgrpcutil.QueryClassHeader, "ruler")
+	_, err = tripper.RoundTrip(r)
+	require.NoError(t, err)
+	assert.Empty(t, downstream.Header.Get(httpgrpcutil.QueryClassHeader))
+	assert.Equal(t, "ruler", r.Header.Get(httpgrpcutil.QueryClassHeader), "the original request should not be modified")
+
+	// The class set by trusted callers is kept.
+	r = r.WithContext(grpc.NewContextWithServerTransportStream(r.Context(), &serverTransportStreamMock{}))
+	_, err = tripper.RoundTrip(r)
+	require.NoError(t, err)
+	assert.Equal(t, "ruler", downstream.Header.Get(httpgrpcutil.QueryClassHeader))
+}
+
+// serverTransportStreamMock mocks the stream of a request received through the gRPC server.
+type serverTransportStreamMock struct{}
+
+func (serverTransportStreamMock) Method() string               { return "/httpgrpc.HTTP/Handle" }
+func (serverTransportStreamMock) SetHeader(metadata.MD) error  { return nil }
+func (serverTransportStreamMock) SendHeader(metadata.MD) error { return nil }
+func (serverTransportStreamMock) SetTrailer(metadata.MD) error { return nil }
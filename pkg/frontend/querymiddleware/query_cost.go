@@ -0,0 +1,379 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// queryClassExpensive is the class of the queries whose estimated cost exceeds the tenant's query cost budget.
+	queryClassExpensive = "expensive"
+
+	// cardinalityLabelValuesPath is the path of the label values cardinality API, relative to the Prometheus API prefix.
+	cardinalityLabelValuesPath = "cardinality/label_values"
+
+	// selectorCardinalityTTL is how long the cardinality of a selector is cached for.
+	selectorCardinalityTTL = 10 * time.Minute
+)
+
+// BucketIndexLoader loads the bucket index of a tenant.
+type BucketIndexLoader interface {
+	GetIndex(ctx context.Context, userID string) (*bucketindex.Index, error)
+}
+
+type queryCostMetrics struct {
+	estimations         prometheus.Counter
+	estimationFailures  prometheus.Counter
+	queriesOverBudget   *prometheus.CounterVec
+	estimatedQueryCosts prometheus.Histogram
+}
+
+func newQueryCostMetrics(registerer prometheus.Registerer) *queryCostMetrics {
+	return &queryCostMetrics{
+		estimations: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_query_frontend_query_cost_estimations_total",
+			Help: "Total number of queries whose cost has been estimated.",
+		}),
+		estimationFailures: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_query_frontend_query_cost_estimation_failures_total",
+			Help: "Total number of queries whose cost couldn't be estimated. These queries are admitted.",
+		}),
+		queriesOverBudget: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_query_frontend_queries_over_cost_budget_total",
+			Help: "Total number of queries whose estimated cost exceeded the query cost budget, by action taken.",
+		}, []string{"action"}),
+		estimatedQueryCosts: promauto.With(registerer).NewHistogram(prometheus.HistogramOpts{
+			Name:    "cortex_query_frontend_estimated_query_cost",
+			Help:    "Estimated cost of the queries, expressed as number of fetched series.",
+			Buckets: prometheus.ExponentialBuckets(100, 4, 10),
+		}),
+	}
+}
+
+// queryCostRoundTripper estimates the cost of a query before executing it, and rejects or deprioritizes
+// the query if its estimated cost exceeds the tenant's query cost budget.
+//
+// The cost of a query is the number of series it's estimated to fetch from ingesters and store-gateways.
+// For each selector of the query, the number of series in the ingesters is fetched via the label values
+// cardinality API. The number of series fetched from the store-gateways is estimated from the number of
+// series of each block overlapping the selector time range, as stored in the bucket index, assuming the
+// selector matches the same fraction of the block series as it does in the ingesters.
+type queryCostRoundTripper struct {
+	next       http.RoundTripper
+	downstream http.RoundTripper
+	codec      Codec
+	limits     Limits
+
+	// cache is used to cache the cardinality of the query selectors. It's optional.
+	cache cache.Cache
+
+	// indexLoader is used to load the bucket index. It's optional: if nil, each day of the
+	// selector time range is assumed to be stored in a block containing as many series as the ingesters.
+	indexLoader BucketIndexLoader
+
+	lookbackDelta time.Duration
+	logger        log.Logger
+	metrics       *queryCostMetrics
+}
+
+// newQueryCostRoundTripper makes a new queryCostRoundTripper. Queries are executed by next, while
+// the cardinality of the query selectors is fetched from downstream.
+func newQueryCostRoundTripper(next, downstream http.RoundTripper, codec Codec, limits Limits, c cache.Cache, indexLoader BucketIndexLoader, lookbackDelta time.Duration, logger log.Logger, metrics *queryCostMetrics) http.RoundTripper {
+	return &queryCostRoundTripper{
+		next:          next,
+		downstream:    downstream,
+		codec:         codec,
+		limits:        limits,
+		cache:         c,
+		indexLoader:   indexLoader,
+		lookbackDelta: lookbackDelta,
+		logger:        logger,
+		metrics:       metrics,
+	}
+}
+
+func (rt *queryCostRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	// The cardinality API doesn't support cross-tenant queries, so the query cost is only estimated for single tenant queries.
+	tenantID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		return rt.next.RoundTrip(r)
+	}
+
+	budget := rt.limits.QueryCostBudget(tenantID)
+	if budget <= 0 {
+		return rt.next.RoundTrip(r)
+	}
+
+	req, err := rt.codec.DecodeRequest(r.Context(), r)
+	if err != nil {
+		// Let the next round tripper return the error.
+		return rt.next.RoundTrip(r)
+	}
+
+	spanLog, ctx := spanlogger.NewWithLogger(r.Context(), rt.logger, "queryCostRoundTripper.RoundTrip")
+	defer spanLog.Finish()
+
+	rt.metrics.estimations.Inc()
+	cost, err := rt.estimateQueryCost(ctx, r, tenantID, req)
+	if err != nil {
+		rt.metrics.estimationFailures.Inc()
+		level.Warn(spanLog).Log("msg", "failed to estimate the query cost, the query is admitted", "query", req.GetQuery(), "err", err)
+		return rt.next.RoundTrip(r)
+	}
+
+	rt.metrics.estimatedQueryCosts.Observe(float64(cost.total))
+	level.Debug(spanLog).Log("msg", "estimated query cost", "query", req.GetQuery(), "cost", cost.total, "budget", budget)
+	if cost.total <= uint64(budget) {
+		return rt.next.RoundTrip(r)
+	}
+
+	action := rt.limits.QueryCostBudgetAction(tenantID)
+	rt.metrics.queriesOverBudget.WithLabelValues(action).Inc()
+
+	if action != validation.QueryCostBudgetActionDeprioritize {
+		return nil, apierror.New(apierror.TypeBadData, validation.NewQueryCostBudgetExceededError(cost.total, budget, cost.topSelector, cost.topSelectorCost).Error())
+	}
+
+	level.Info(spanLog).Log("msg", "the estimated query cost exceeds the budget, the query is deprioritized", "query", req.GetQuery(), "cost", cost.total, "budget", budget, "selector", cost.topSelector)
+
+	r = r.Clone(context.WithValue(r.Context(), queryClassKey, queryClassExpensive))
+	r.Header.Set(httpgrpcutil.QueryClassHeader, queryClassExpensive)
+	return rt.next.RoundTrip(r)
+}
+
+type queryCost struct {
+	total uint64
+
+	// topSelector is the most expensive selector of the query, and topSelectorCost its estimated cost.
+	topSelector     string
+	topSelectorCost uint64
+}
+
+func (rt *queryCostRoundTripper) estimateQueryCost(ctx context.Context, r *http.Request, tenantID string, req Request) (queryCost, error) {
+	expr, err := parser.ParseExpr(req.GetQuery())
+	if err != nil {
+		return queryCost{}, err
+	}
+
+	var blocks bucketindex.Blocks
+	blocksKnown := false
+	if rt.indexLoader != nil {
+		idx, err := rt.indexLoader.GetIndex(ctx, tenantID)
+		switch {
+		case err == nil:
+			blocks, blocksKnown = idx.Blocks, true
+		case errors.Is(err, bucketindex.ErrIndexNotFound):
+			// The tenant has no blocks in the storage yet.
+			blocksKnown = true
+		default:
+			level.Warn(rt.logger).Log("msg", "failed to load the bucket index to estimate the query cost", "user", tenantID, "err", err)
+		}
+	}
+
+	var cost queryCost
+	for _, sel := range querySelectorsTimeRanges(expr, req.GetStart(), req.GetEnd(), rt.lookbackDelta) {
+		selectorSeries, totalSeries, err := rt.fetchSelectorCardinality(ctx, r, tenantID, sel.selector)
+		if err != nil {
+			return queryCost{}, errors.Wrapf(err, "fetch cardinality of selector %s", sel.selector)
+		}
+
+		selectorCost := selectorSeries
+		if blocksKnown {
+			selectorCost += estimateBlocksSeries(blocks, sel.minT, sel.maxT, selectorSeries, totalSeries)
+		} else {
+			selectorCost += selectorSeries * uint64(math.Ceil(float64(sel.maxT-sel.minT)/float64(day.Milliseconds())))
+		}
+
+		cost.total += selectorCost
+		if cost.topSelector == "" || selectorCost > cost.topSelectorCost {
+			cost.topSelector, cost.topSelectorCost = sel.selector, selectorCost
+		}
+	}
+
+	return cost, nil
+}
+
+// estimateBlocksSeries returns the estimated number of series fetched by a selector from the blocks
+// overlapping the [minT, maxT] time range. The selector is assumed to match the same fraction of the
+// series of each block as it does in the ingesters. Blocks without stats are assumed to contain as many
+// series as the ingesters.
+func estimateBlocksSeries(blocks bucketindex.Blocks, minT, maxT int64, selectorSeries, totalSeries uint64) uint64 {
+	var series float64
+	for _, b := range blocks {
+		if !b.Within(minT, maxT) {
+			continue
+		}
+
+		if b.SeriesCount == 0 || totalSeries == 0 {
+			series += float64(selectorSeries)
+			continue
+		}
+
+		series += float64(b.SeriesCount) * float64(selectorSeries) / float64(totalSeries)
+	}
+
+	return uint64(math.Ceil(series))
+}
+
+type selectorTimeRange struct {
+	selector   string
+	minT, maxT int64
+}
+
+// querySelectorsTimeRanges returns the selectors of the query along with the time range they select, taking
+// into account range selectors, subqueries, offsets and the @ modifier. Selectors used multiple times are
+// returned once, with the union of their time ranges.
+func querySelectorsTimeRanges(expr parser.Expr, start, end int64, lookbackDelta time.Duration) []selectorTimeRange {
+	ranges := map[string]*selectorTimeRange{}
+
+	parser.Inspect(expr, func(node parser.Node, path []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+
+		selectRange := lookbackDelta
+		if len(path) > 0 {
+			if ms, ok := path[len(path)-1].(*parser.MatrixSelector); ok {
+				selectRange = ms.Range
+			}
+		}
+
+		offset := vs.OriginalOffset
+		for _, n := range path {
+			if sq, ok := n.(*parser.SubqueryExpr); ok {
+				selectRange += sq.Range
+				offset += sq.OriginalOffset
+			}
+		}
+
+		minT, maxT := start, end
+		if vs.Timestamp != nil {
+			minT, maxT = *vs.Timestamp, *vs.Timestamp
+		}
+		minT -= (selectRange + offset).Milliseconds()
+		maxT -= offset.Milliseconds()
+
+		selector := (&parser.VectorSelector{Name: vs.Name, LabelMatchers: vs.LabelMatchers}).String()
+		if r, ok := ranges[selector]; ok {
+			if minT < r.minT {
+				r.minT = minT
+			}
+			if maxT > r.maxT {
+				r.maxT = maxT
+			}
+			return nil
+		}
+
+		ranges[selector] = &selectorTimeRange{selector: selector, minT: minT, maxT: maxT}
+		return nil
+	})
+
+	result := make([]selectorTimeRange, 0, len(ranges))
+	for _, r := range ranges {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].selector < result[j].selector
+	})
+	return result
+}
+
+// labelValuesCardinalityResponse is the subset of the label values cardinality API response used to estimate the query cost.
+type labelValuesCardinalityResponse struct {
+	SeriesCountTotal uint64 `json:"series_count_total"`
+	Labels           []struct {
+		SeriesCount uint64 `json:"series_count"`
+	} `json:"labels"`
+}
+
+// fetchSelectorCardinality returns the number of series matching the selector in the ingesters, and the total
+// number of series of the tenant in the ingesters.
+func (rt *queryCostRoundTripper) fetchSelectorCardinality(ctx context.Context, r *http.Request, tenantID, selector string) (selectorSeries, totalSeries uint64, _ error) {
+	key := fmt.Sprintf("QC:%s:%s", tenantID, cacheHashKey(selector))
+	if rt.cache != nil {
+		if val, ok := rt.cache.Fetch(ctx, []string{key})[key]; ok && len(val) == 16 {
+			return binary.BigEndian.Uint64(val[0:8]), binary.BigEndian.Uint64(val[8:16]), nil
+		}
+	}
+
+	// The cardinality API shares the Prometheus API prefix with the query API.
+	u := &url.URL{
+		Path: path.Join(path.Dir(r.URL.Path), cardinalityLabelValuesPath),
+		RawQuery: url.Values{
+			"label_names[]": []string{model.MetricNameLabel},
+			"selector":      []string{selector},
+			"limit":         []string{"0"},
+		}.Encode(),
+	}
+	req := (&http.Request{
+		Method:     http.MethodGet,
+		RequestURI: u.String(), // This is what the httpgrpc code looks at.
+		URL:        u,
+		Body:       http.NoBody,
+		Header:     http.Header{},
+	}).WithContext(ctx)
+	if err := user.InjectOrgIDIntoHTTPRequest(ctx, req); err != nil {
+		return 0, 0, err
+	}
+
+	resp, err := rt.downstream.RoundTrip(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+	}
+
+	var cardinality labelValuesCardinalityResponse
+	if err := json.Unmarshal(body, &cardinality); err != nil {
+		return 0, 0, errors.Wrap(err, "decode label values cardinality response")
+	}
+
+	for _, l := range cardinality.Labels {
+		selectorSeries += l.SeriesCount
+	}
+	totalSeries = cardinality.SeriesCountTotal
+
+	if rt.cache != nil {
+		val := make([]byte, 16)
+		binary.BigEndian.PutUint64(val[0:8], selectorSeries)
+		binary.BigEndian.PutUint64(val[8:16], totalSeries)
+		rt.cache.Store(ctx, map[string][]byte{key: val}, selectorCardinalityTTL)
+	}
+
+	return selectorSeries, totalSeries, nil
+}
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestQuerySelectorsTimeRanges(t *testing.T) {
+	const (
+		start = int64(100 * time.Hour / time.Millisecond)
+		end   = int64(200 * time.Hour / time.Millisecond)
+	)
+
+	hours := func(h int64) int64 { return h * time.Hour.Milliseconds() }
+	minutes := func(m int64) int64 { return m * time.Minute.Milliseconds() }
+
+	for query, expected := range map[string][]selectorTimeRange{
+		`up`: {
+			{selector: `up`, minT: start - minutes(5), maxT: end},
+		},
+		`rate(http_requests_total{job="api"}[1h])`: {
+			{selector: `http_requests_total{job="api"}`, minT: start - hours(1), maxT: end},
+		},
+		`rate(http_requests_total[1h] offset 1h)`: {
+			{selector: `http_requests_total`, minT: start - hours(2), maxT: end - hours(1)},
+		},
+		`max_over_time(rate(http_requests_total[5m])[1d:1m])`: {
+			{selector: `http_requests_total`, minT: start - hours(24) - minutes(5), maxT: end},
+		},
+		`up @ 3600`: {
+			{selector: `up`, minT: hours(1) - minutes(5), maxT: hours(1)},
+		},
+		`up + sum_over_time(up[10m]) + on() count({__name__=~"node_.*"})`: {
+			{selector: `up`, minT: start - minutes(10), maxT: end},
+			{selector: `{__name__=~"node_.*"}`, minT: start - minutes(5), maxT: end},
+		},
+	} {
+		t.Run(query, func(t *testing.T) {
+			expr, err := parser.ParseExpr(query)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, querySelectorsTimeRanges(expr, start, end, 5*time.Minute))
+		})
+	}
+}
+
+func TestEstimateBlocksSeries(t *testing.T) {
+	blocks := bucketindex.Blocks{
+		{ID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: 100, SeriesCount: 1000},
+		{ID: ulid.MustNew(2, nil), MinTime: 100, MaxTime: 200, SeriesCount: 3000},
+		{ID: ulid.MustNew(3, nil), MinTime: 200, MaxTime: 300},
+	}
+
+	// The selector matches 10% of the series in the ingesters.
+	assert.Equal(t, uint64(100), estimateBlocksSeries(blocks, 0, 50, 10, 100))
+	assert.Equal(t, uint64(400), estimateBlocksSeries(blocks, 50, 150, 10, 100))
+
+	// Blocks without stats are assumed to contain as many series as the ingesters.
+	assert.Equal(t, uint64(310), estimateBlocksSeries(blocks, 150, 250, 10, 100))
+	assert.Equal(t, uint64(10), estimateBlocksSeries(blocks, 250, 260, 10, 100))
+
+	assert.Equal(t, uint64(0), estimateBlocksSeries(blocks, 400, 500, 10, 100))
+	assert.Equal(t, uint64(0), estimateBlocksSeries(nil, 0, 500, 10, 100))
+}
+
+func TestQueryCostRoundTripper(t *testing.T) {
+	const (
+		rangeQuery = "/prometheus/api/v1/query_range?query=sum(rate(http_requests_total[5m]))+%2F+sum(rate(up[5m]))&start=0&end=86400&step=60"
+		dayMs      = int64(24 * time.Hour / time.Millisecond)
+	)
+
+	// Cardinality of the selectors in the ingesters.
+	cardinality := map[string]uint64{
+		"http_requests_total": 400,
+		"up":                  10,
+	}
+	const totalSeries = 1000
+
+	index := &bucketindex.Index{Blocks: bucketindex.Blocks{
+		{ID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: dayMs / 2, SeriesCount: 2000},
+		{ID: ulid.MustNew(2, nil), MinTime: dayMs / 2, MaxTime: dayMs, SeriesCount: 3000},
+	}}
+
+	tests := map[string]struct {
+		limits                      mockLimits
+		indexLoader                 BucketIndexLoader
+		cardinalityFailure          bool
+		expectedErr                 string
+		expectedQueryClass          string
+		expectedCardinalityRequests int
+		expectedMetrics             string
+	}{
+		"query cost budget disabled": {
+			limits: mockLimits{},
+		},
+		"query cost within the budget": {
+			limits:                      mockLimits{queryCostBudget: 3000, queryCostBudgetAction: validation.QueryCostBudgetActionReject},
+			indexLoader:                 &mockBucketIndexLoader{index: index},
+			expectedCardinalityRequests: 2,
+			expectedMetrics: `
+				# HELP cortex_query_frontend_query_cost_estimations_total Total number of queries whose cost has been estimated.
+				# TYPE cortex_query_frontend_query_cost_estimations_total counter
+				cortex_query_frontend_query_cost_estimations_total 1
+				# HELP cortex_query_frontend_query_cost_estimation_failures_total Total number of queries whose cost couldn't be estimated. These queries are admitted.
+				# TYPE cortex_query_frontend_query_cost_estimation_failures_total counter
+				cortex_query_frontend_query_cost_estimation_failures_total 0
+			`,
+		},
+		"query cost exceeding the budget, with reject action": {
+			// http_requests_total: 400 series in the ingesters + 40% of the 5000 series in the blocks = 2400 series.
+			// up: 10 series in the ingesters + 1% of the 5000 series in the blocks = 60 series.
+			limits:                      mockLimits{queryCostBudget: 2000, queryCostBudgetAction: validation.QueryCostBudgetActionReject},
+			indexLoader:                 &mockBucketIndexLoader{index: index},
+			expectedCardinalityRequests: 2,
+			expectedErr:                 "the estimated cost of the query exceeds the limit (estimated fetched series: 2460, limit: 2000); the most expensive selector is http_requests_total, which is estimated to fetch 2400 series",
+			expectedMetrics: `
+				# HELP cortex_query_frontend_query_cost_estimations_total Total number of queries whose cost has been estimated.
+				# TYPE cortex_query_frontend_query_cost_estimations_total counter
+				cortex_query_frontend_query_cost_estimations_total 1
+				# HELP cortex_query_frontend_query_cost_estimation_failures_total Total number of queries whose cost couldn't be estimated. These queries are admitted.
+				# TYPE cortex_query_frontend_query_cost_estimation_failures_total counter
+				cortex_query_frontend_query_cost_estimation_failures_total 0
+				# HELP cortex_query_frontend_queries_over_cost_budget_total Total number of queries whose estimated cost exceeded the query cost budget, by action taken.
+				# TYPE cortex_query_frontend_queries_over_cost_budget_total counter
+				cortex_query_frontend_queries_over_cost_budget_total{action="reject"} 1
+			`,
+		},
+		"query cost exceeding the budget, with deprioritize action": {
+			limits:                      mockLimits{queryCostBudget: 2000, queryCostBudgetAction: validation.QueryCostBudgetActionDeprioritize},
+			indexLoader:                 &mockBucketIndexLoader{index: index},
+			expectedCardinalityRequests: 2,
+			expectedQueryClass:          queryClassExpensive,
+			expectedMetrics: `
+				# HELP cortex_query_frontend_query_cost_estimations_total Total number of queries whose cost has been estimated.
+				# TYPE cortex_query_frontend_query_cost_estimations_total counter
+				cortex_query_frontend_query_cost_estimations_total 1
+				# HELP cortex_query_frontend_query_cost_estimation_failures_total Total number of queries whose cost couldn't be estimated. These queries are admitted.
+				# TYPE cortex_query_frontend_query_cost_estimation_failures_total counter
+				cortex_query_frontend_query_cost_estimation_failures_total 0
+				# HELP cortex_query_frontend_queries_over_cost_budget_total Total number of queries whose estimated cost exceeded the query cost budget, by action taken.
+				# TYPE cortex_query_frontend_queries_over_cost_budget_total counter
+				cortex_query_frontend_queries_over_cost_budget_total{action="deprioritize"} 1
+			`,
+		},
+		"query cost exceeding the budget, without bucket index": {
+			// Each day of the selectors time range is assumed to be stored in a block with as many series as the ingesters:
+			// the query range plus the 5m range selector overlaps 2 days.
+			limits:                      mockLimits{queryCostBudget: 1000, queryCostBudgetAction: validation.QueryCostBudgetActionReject},
+			expectedCardinalityRequests: 2,
+			expectedErr:                 "the estimated cost of the query exceeds the limit (estimated fetched series: 1230, limit: 1000); the most expensive selector is http_requests_total, which is estimated to fetch 1200 series",
+		},
+		"query cost exceeding the budget, with bucket index not found": {
+			limits:                      mockLimits{queryCostBudget: 400, queryCostBudgetAction: validation.QueryCostBudgetActionReject},
+			indexLoader:                 &mockBucketIndexLoader{err: bucketindex.ErrIndexNotFound},
+			expectedCardinalityRequests: 2,
+			expectedErr:                 "the estimated cost of the query exceeds the limit (estimated fetched series: 410, limit: 400)",
+		},
+		"query cost estimation failure": {
+			limits:                      mockLimits{queryCostBudget: 1, queryCostBudgetAction: validation.QueryCostBudgetActionReject},
+			indexLoader:                 &mockBucketIndexLoader{index: index},
+			cardinalityFailure:          true,
+			expectedCardinalityRequests: 1,
+			expectedMetrics: `
+				# HELP cortex_query_frontend_query_cost_estimations_total Total number of queries whose cost has been estimated.
+				# TYPE cortex_query_frontend_query_cost_estimations_total counter
+				cortex_query_frontend_query_cost_estimations_total 1
+				# HELP cortex_query_frontend_query_cost_estimation_failures_total Total number of queries whose cost couldn't be estimated. These queries are admitted.
+				# TYPE cortex_query_frontend_query_cost_estimation_failures_total counter
+				cortex_query_frontend_query_cost_estimation_failures_total 1
+			`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				queried             *http.Request
+				cardinalityRequests int
+			)
+
+			next := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				queried = r
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			})
+
+			downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				cardinalityRequests++
+				assert.Equal(t, "/prometheus/api/v1/cardinality/label_values", r.URL.Path)
+				assert.Equal(t, []string{"__name__"}, r.URL.Query()["label_names[]"])
+				assert.Equal(t, "user-1", r.Header.Get(user.OrgIDHeaderName))
+
+				if testData.cardinalityFailure {
+					return &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader("cardinality analysis is disabled"))}, nil
+				}
+
+				body := fmt.Sprintf(`{"series_count_total":%d,"labels":[{"label_name":"__name__","series_count":%d}]}`, totalSeries, cardinality[r.URL.Query().Get("selector")])
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+			})
+
+			reg := prometheus.NewPedanticRegistry()
+			rt := newQueryCostRoundTripper(next, downstream, newTestPrometheusCodec(), testData.limits, nil, testData.indexLoader, 5*time.Minute, log.NewNopLogger(), newQueryCostMetrics(reg))
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			req := httptest.NewRequest(http.MethodGet, rangeQuery, nil).WithContext(ctx)
+
+			_, err := rt.RoundTrip(req)
+			if testData.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testData.expectedErr)
+				assert.Nil(t, queried)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, queried)
+				assert.Equal(t, testData.expectedQueryClass, queried.Header.Get(httpgrpcutil.QueryClassHeader))
+
+				// The query class must be propagated to the sub-requests too.
+				subRequest := httptest.NewRequest(http.MethodGet, rangeQuery, nil)
+				injectQueryClassIntoHTTPRequest(queried.Context(), subRequest)
+				assert.Equal(t, testData.expectedQueryClass, subRequest.Header.Get(httpgrpcutil.QueryClassHeader))
+			}
+
+			assert.Equal(t, testData.expectedCardinalityRequests, cardinalityRequests)
+
+			if testData.expectedMetrics != "" {
+				assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(testData.expectedMetrics),
+					"cortex_query_frontend_query_cost_estimations_total",
+					"cortex_query_frontend_query_cost_estimation_failures_total",
+					"cortex_query_frontend_queries_over_cost_budget_total",
+				))
+			}
+		})
+	}
+}
+
+type mockBucketIndexLoader struct {
+	index *bucketindex.Index
+	err   error
+}
+
+func (m *mockBucketIndexLoader) GetIndex(context.Context, string) (*bucketindex.Index, error) {
+	return m.index, m.err
+}
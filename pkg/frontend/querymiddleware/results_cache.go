@@ -15,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gogo/protobuf/types"
@@ -30,6 +31,7 @@ import (
 	"github.com/uber/jaeger-client-go"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/util"
 )
 
@@ -42,22 +44,31 @@ const (
 
 	// noStoreValue is the value that cacheControlHeader has if the response indicates that the results should not be cached.
 	noStoreValue = "no-store"
+
+	// resultsCacheName is the name of the results cache, used to identify it in logs and metrics.
+	resultsCacheName = "frontend-cache"
 )
 
 var (
-	supportedResultsCacheBackends = []string{cache.BackendMemcached}
+	supportedResultsCacheBackends = []string{cache.BackendMemcached, cache.BackendRedis, resultsCacheBackendInMemory}
+
+	errInMemoryResultsCacheMaxSizeNotPositive = errors.New("the in-memory results cache max size must be positive")
+	errInMemoryFirstTierRequiresRemoteBackend = errors.New("the in-memory results cache first tier requires a memcached or redis backend")
 )
 
 // ResultsCacheConfig is the config for the results cache.
 type ResultsCacheConfig struct {
-	cache.BackendConfig `yaml:",inline"`
-	Compression         cache.CompressionConfig `yaml:",inline"`
+	mimir_tsdb.CacheBackendConfig `yaml:",inline"`
+	InMemory                      InMemoryResultsCacheConfig `yaml:"inmemory"`
+	Compression                   cache.CompressionConfig    `yaml:",inline"`
 }
 
 // RegisterFlags registers flags.
 func (cfg *ResultsCacheConfig) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&cfg.Backend, "query-frontend.results-cache.backend", "", fmt.Sprintf("Backend for query-frontend results cache, if not empty. Supported values: %s.", supportedResultsCacheBackends))
 	cfg.Memcached.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.memcached.")
+	cfg.Redis.RegisterFlagsWithPrefix("query-frontend.results-cache.redis", f)
+	cfg.InMemory.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.inmemory.")
 	cfg.Compression.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.")
 }
 
@@ -66,10 +77,28 @@ func (cfg *ResultsCacheConfig) Validate() error {
 		return errUnsupportedResultsCacheBackend(cfg.Backend)
 	}
 
-	if cfg.Backend == cache.BackendMemcached {
+	switch cfg.Backend {
+	case cache.BackendMemcached:
 		if err := cfg.Memcached.Validate(); err != nil {
 			return errors.Wrap(err, "query-frontend results cache")
 		}
+	case cache.BackendRedis:
+		if err := cfg.Redis.Validate(); err != nil {
+			return errors.Wrap(err, "query-frontend results cache")
+		}
+	case resultsCacheBackendInMemory:
+		if cfg.InMemory.MaxSizeBytes == 0 {
+			return errors.Wrap(errInMemoryResultsCacheMaxSizeNotPositive, "query-frontend results cache")
+		}
+	}
+
+	if cfg.InMemory.FirstTierEnabled {
+		if cfg.Backend != cache.BackendMemcached && cfg.Backend != cache.BackendRedis {
+			return errors.Wrap(errInMemoryFirstTierRequiresRemoteBackend, "query-frontend results cache")
+		}
+		if cfg.InMemory.MaxSizeBytes == 0 {
+			return errors.Wrap(errInMemoryResultsCacheMaxSizeNotPositive, "query-frontend results cache")
+		}
 	}
 
 	if err := cfg.Compression.Validate(); err != nil {
@@ -79,6 +108,20 @@ func (cfg *ResultsCacheConfig) Validate() error {
 	return nil
 }
 
+// InMemoryResultsCacheConfig is the config for the in-process results cache.
+type InMemoryResultsCacheConfig struct {
+	MaxSizeBytes     uint64        `yaml:"max_size_bytes"`
+	FirstTierEnabled bool          `yaml:"first_tier_enabled" category:"experimental"`
+	FirstTierTTL     time.Duration `yaml:"first_tier_ttl" category:"experimental"`
+}
+
+// RegisterFlagsWithPrefix registers flags.
+func (cfg *InMemoryResultsCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.Uint64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", uint64(256*units.MiB), fmt.Sprintf("Maximum size in bytes of the in-memory results cache. Used when the backend is %s or when the in-memory first tier is enabled.", resultsCacheBackendInMemory))
+	f.BoolVar(&cfg.FirstTierEnabled, prefix+"first-tier-enabled", false, fmt.Sprintf("Enable an in-memory cache tier in front of the %s or %s backend. Cache entries are looked up in memory first, and entries fetched from the remote backend are kept in memory too.", cache.BackendMemcached, cache.BackendRedis))
+	f.DurationVar(&cfg.FirstTierTTL, prefix+"first-tier-ttl", 5*time.Minute, "Maximum time an entry is kept in the in-memory first tier.")
+}
+
 func errUnsupportedResultsCacheBackend(unsupportedBackend string) error {
 	return fmt.Errorf("unsupported cache backend: %q, supported values: %v", unsupportedBackend, supportedResultsCacheBackends)
 }
@@ -89,11 +132,31 @@ func newResultsCache(cfg ResultsCacheConfig, logger log.Logger, reg prometheus.R
 	// when running in monolithic mode.
 	reg = prometheus.WrapRegistererWith(prometheus.Labels{"component": "query-frontend"}, reg)
 
-	client, err := cache.CreateClient("frontend-cache", cfg.BackendConfig, logger, prometheus.WrapRegistererWithPrefix("thanos_", reg))
-	if err != nil {
-		return nil, err
-	} else if client == nil {
-		return nil, errUnsupportedResultsCacheBackend(cfg.Backend)
+	reg = prometheus.WrapRegistererWithPrefix("thanos_", reg)
+
+	var client cache.Cache
+	if cfg.Backend == resultsCacheBackendInMemory {
+		inMemory, err := newInMemoryCache(resultsCacheName, cfg.InMemory.MaxSizeBytes, logger, reg)
+		if err != nil {
+			return nil, err
+		}
+		client = inMemory
+	} else {
+		remote, err := mimir_tsdb.CreateCacheClient(resultsCacheName, cfg.CacheBackendConfig, logger, reg)
+		if err != nil {
+			return nil, err
+		} else if remote == nil {
+			return nil, errUnsupportedResultsCacheBackend(cfg.Backend)
+		}
+		client = remote
+
+		if cfg.InMemory.FirstTierEnabled {
+			inMemory, err := newInMemoryCache(resultsCacheName, cfg.InMemory.MaxSizeBytes, logger, reg)
+			if err != nil {
+				return nil, err
+			}
+			client = newTieredCache(inMemory, remote, cfg.InMemory.FirstTierTTL)
+		}
 	}
 
 	return cache.NewVersioned(
@@ -168,6 +231,12 @@ func (t ConstSplitter) GenerateCacheKey(_ context.Context, userID string, r Requ
 	return fmt.Sprintf("%s:%s:%d:%d:%d", userID, r.GetQuery(), r.GetStep(), startInterval, stepOffset)
 }
 
+// ResultsCacheGenLoader loads the results cache generation of the tenants, which changes whenever the results
+// cached for them become outdated, like when their series deletion requests change.
+type ResultsCacheGenLoader interface {
+	ResultsCacheGen(ctx context.Context, tenantIDs []string) (string, error)
+}
+
 // shouldCacheFn checks whether the current request should go to cache
 // or not. If not, just send the request to next handler.
 type shouldCacheFn func(r Request) bool
@@ -195,8 +264,9 @@ func isRequestCachable(req Request, maxCacheTime int64, cacheUnalignedRequests b
 	return true, ""
 }
 
-// isResponseCachable says whether the response should be cached or not.
-func isResponseCachable(r Response, logger log.Logger) bool {
+// isResponseCachable says whether the response should be cached or not. The response is not cached if it has
+// been computed with a different results cache generation than the input one.
+func isResponseCachable(r Response, cacheGen string, logger log.Logger) bool {
 	headerValues := getHeaderValuesWithName(r, cacheControlHeader)
 	for _, v := range headerValues {
 		if v == noStoreValue {
@@ -205,6 +275,15 @@ func isResponseCachable(r Response, logger log.Logger) bool {
 		}
 	}
 
+	var respCacheGen string
+	if headerValues := getHeaderValuesWithName(r, mimir_tsdb.ResultsCacheGenHeader); len(headerValues) > 0 {
+		respCacheGen = headerValues[0]
+	}
+	if respCacheGen != cacheGen {
+		level.Debug(logger).Log("msg", "results cache generation in response doesn't match the expected one, not caching the response", "expected", cacheGen, "actual", respCacheGen)
+		return false
+	}
+
 	return true
 }
 
@@ -0,0 +1,336 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cache"
+	lru "github.com/hashicorp/golang-lru/simplelru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// resultsCacheBackendInMemory is the value for the in-process results cache backend.
+	resultsCacheBackendInMemory = "inmemory"
+
+	inMemoryCacheOpSet      = "set"
+	inMemoryCacheOpGetMulti = "getmulti"
+	inMemoryCacheOpDelete   = "delete"
+
+	inMemoryCacheReasonMaxItemSize = "max-item-size"
+
+	// inMemoryCacheEntryOverheadBytes is the estimated memory overhead of each cache entry
+	// (LRU list element, map bucket entry, slice and string headers and expiration time).
+	inMemoryCacheEntryOverheadBytes = 128
+
+	maxInt = int(^uint(0) >> 1)
+)
+
+var (
+	_ cache.Cache = (*inMemoryCache)(nil)
+	_ cache.Cache = (*tieredCache)(nil)
+)
+
+type inMemoryCacheEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// inMemoryCache is a thread-safe in-process LRU cache, bounded by the estimated size in bytes of the
+// stored entries. Each entry expires after the TTL it was stored with.
+type inMemoryCache struct {
+	name         string
+	logger       log.Logger
+	maxSizeBytes uint64
+
+	mtx     sync.Mutex
+	lru     *lru.LRU
+	curSize uint64
+
+	// Metrics.
+	requests   prometheus.Counter
+	hits       prometheus.Counter
+	operations *prometheus.CounterVec
+	skipped    *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	dataSize   *prometheus.HistogramVec
+	evicted    prometheus.Counter
+	expired    prometheus.Counter
+}
+
+// newInMemoryCache makes a new inMemoryCache. The input registerer is expected to be the same one
+// used by the remote cache clients, so that the in-memory cache exports the same metrics under
+// the backend="inmemory" label.
+func newInMemoryCache(name string, maxSizeBytes uint64, logger log.Logger, reg prometheus.Registerer) (*inMemoryCache, error) {
+	c := &inMemoryCache{
+		name:         name,
+		logger:       logger,
+		maxSizeBytes: maxSizeBytes,
+	}
+
+	// Initialize the LRU cache with a high size limit since we manage evictions ourselves
+	// based on the stored size using the RemoveOldest() method.
+	l, err := lru.NewLRU(maxInt, c.onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = l
+
+	reg = prometheus.WrapRegistererWith(
+		prometheus.Labels{"name": name, "backend": resultsCacheBackendInMemory},
+		prometheus.WrapRegistererWithPrefix("cache_", reg))
+
+	c.requests = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "requests_total",
+		Help: "Total number of items requests to cache.",
+	})
+	c.hits = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "hits_total",
+		Help: "Total number of items requests to the cache that were a hit.",
+	})
+	c.operations = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "operations_total",
+		Help: "Total number of operations against cache.",
+	}, []string{"operation"})
+	c.skipped = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "operation_skipped_total",
+		Help: "Total number of operations against cache that have been skipped.",
+	}, []string{"operation", "reason"})
+	c.duration = promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "operation_duration_seconds",
+		Help:    "Duration of operations against cache.",
+		Buckets: []float64{0.00001, 0.0001, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1},
+	}, []string{"operation"})
+	c.dataSize = promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "operation_data_size_bytes",
+		Help:    "Tracks the size of the data stored in and fetched from cache.",
+		Buckets: []float64{32, 256, 512, 1024, 32 * 1024, 256 * 1024, 512 * 1024, 1024 * 1024, 32 * 1024 * 1024},
+	}, []string{"operation"})
+	for _, op := range []string{inMemoryCacheOpSet, inMemoryCacheOpGetMulti, inMemoryCacheOpDelete} {
+		c.operations.WithLabelValues(op)
+		c.duration.WithLabelValues(op)
+	}
+	c.skipped.WithLabelValues(inMemoryCacheOpSet, inMemoryCacheReasonMaxItemSize)
+	c.dataSize.WithLabelValues(inMemoryCacheOpSet)
+	c.dataSize.WithLabelValues(inMemoryCacheOpGetMulti)
+
+	c.evicted = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "items_evicted_total",
+		Help: "Total number of items evicted from the in-memory cache to make room for new items.",
+	})
+	c.expired = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "items_expired_total",
+		Help: "Total number of items removed from the in-memory cache because their TTL expired.",
+	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "items",
+		Help: "Current number of items in the in-memory cache.",
+	}, func() float64 {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		return float64(c.lru.Len())
+	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "items_size_bytes",
+		Help: "Current estimated size in bytes of the items in the in-memory cache.",
+	}, func() float64 {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		return float64(c.curSize)
+	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "max_size_bytes",
+		Help: "Maximum size in bytes of the items held in the in-memory cache.",
+	}, func() float64 {
+		return float64(c.maxSizeBytes)
+	})
+
+	level.Info(logger).Log("msg", "created in-memory cache", "name", name, "maxSizeBytes", maxSizeBytes)
+
+	return c, nil
+}
+
+// Store implements cache.Cache.
+func (c *inMemoryCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	start := time.Now()
+	expiresAt := start.Add(ttl)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for key, value := range data {
+		c.operations.WithLabelValues(inMemoryCacheOpSet).Inc()
+
+		size := entrySize(key, value)
+		if size > c.maxSizeBytes {
+			c.skipped.WithLabelValues(inMemoryCacheOpSet, inMemoryCacheReasonMaxItemSize).Inc()
+			continue
+		}
+
+		// Remove the previous entry, if any, so that its size is released.
+		c.lru.Remove(key)
+
+		for c.curSize+size > c.maxSizeBytes {
+			if _, _, ok := c.lru.RemoveOldest(); !ok {
+				break
+			}
+			c.evicted.Inc()
+		}
+
+		// The caller may be passing in a sub-slice of a huge array. Copy the data
+		// to ensure we don't waste huge amounts of space for something small.
+		v := make([]byte, len(value))
+		copy(v, value)
+
+		c.lru.Add(key, inMemoryCacheEntry{data: v, expiresAt: expiresAt})
+		c.curSize += size
+		c.dataSize.WithLabelValues(inMemoryCacheOpSet).Observe(float64(len(value)))
+	}
+
+	c.duration.WithLabelValues(inMemoryCacheOpSet).Observe(time.Since(start).Seconds())
+}
+
+// Fetch implements cache.Cache.
+func (c *inMemoryCache) Fetch(_ context.Context, keys []string, _ ...cache.Option) map[string][]byte {
+	start := time.Now()
+	c.operations.WithLabelValues(inMemoryCacheOpGetMulti).Inc()
+	c.requests.Add(float64(len(keys)))
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var (
+		found     = make(map[string][]byte, len(keys))
+		foundSize = 0
+	)
+
+	for _, key := range keys {
+		value, ok := c.lru.Get(key)
+		if !ok {
+			continue
+		}
+
+		entry := value.(inMemoryCacheEntry)
+		if start.After(entry.expiresAt) {
+			c.lru.Remove(key)
+			c.expired.Inc()
+			continue
+		}
+
+		found[key] = entry.data
+		foundSize += len(entry.data)
+	}
+
+	c.hits.Add(float64(len(found)))
+	c.dataSize.WithLabelValues(inMemoryCacheOpGetMulti).Observe(float64(foundSize))
+	c.duration.WithLabelValues(inMemoryCacheOpGetMulti).Observe(time.Since(start).Seconds())
+
+	return found
+}
+
+// Name implements cache.Cache.
+func (c *inMemoryCache) Name() string {
+	return c.name
+}
+
+// Delete implements cache.Cache.
+func (c *inMemoryCache) Delete(_ context.Context, key string) error {
+	start := time.Now()
+	c.operations.WithLabelValues(inMemoryCacheOpDelete).Inc()
+
+	c.mtx.Lock()
+	c.lru.Remove(key)
+	c.mtx.Unlock()
+
+	c.duration.WithLabelValues(inMemoryCacheOpDelete).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// onEvict is called by the LRU each time an entry is removed, either explicitly or because it's the oldest one.
+// It's always called while holding the lock.
+func (c *inMemoryCache) onEvict(key, value interface{}) {
+	c.curSize -= entrySize(key.(string), value.(inMemoryCacheEntry).data)
+}
+
+func entrySize(key string, value []byte) uint64 {
+	return uint64(len(key)+len(value)) + inMemoryCacheEntryOverheadBytes
+}
+
+// tieredCache is a two-tier cache. Lookups are first served by the first tier (typically in-memory),
+// and only the keys missing from it are looked up in the second tier (typically a remote cache).
+// Entries found in the second tier are stored back in the first one.
+type tieredCache struct {
+	first        cache.Cache
+	second       cache.Cache
+	firstTierTTL time.Duration
+}
+
+// newTieredCache makes a new tieredCache. Entries are kept in the first tier at most for firstTierTTL.
+func newTieredCache(first, second cache.Cache, firstTierTTL time.Duration) *tieredCache {
+	return &tieredCache{
+		first:        first,
+		second:       second,
+		firstTierTTL: firstTierTTL,
+	}
+}
+
+// Store implements cache.Cache.
+func (t *tieredCache) Store(ctx context.Context, data map[string][]byte, ttl time.Duration) {
+	firstTTL := ttl
+	if firstTTL > t.firstTierTTL {
+		firstTTL = t.firstTierTTL
+	}
+
+	t.first.Store(ctx, data, firstTTL)
+	t.second.Store(ctx, data, ttl)
+}
+
+// Fetch implements cache.Cache.
+func (t *tieredCache) Fetch(ctx context.Context, keys []string, opts ...cache.Option) map[string][]byte {
+	found := t.first.Fetch(ctx, keys, opts...)
+	if len(found) == len(keys) {
+		return found
+	}
+
+	missing := make([]string, 0, len(keys)-len(found))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	fromSecond := t.second.Fetch(ctx, missing, opts...)
+	if len(fromSecond) == 0 {
+		return found
+	}
+
+	t.first.Store(ctx, fromSecond, t.firstTierTTL)
+
+	if found == nil {
+		found = make(map[string][]byte, len(fromSecond))
+	}
+	for key, value := range fromSecond {
+		found[key] = value
+	}
+
+	return found
+}
+
+// Name implements cache.Cache.
+func (t *tieredCache) Name() string {
+	return t.second.Name()
+}
+
+// Delete implements cache.Cache.
+func (t *tieredCache) Delete(ctx context.Context, key string) error {
+	if err := t.first.Delete(ctx, key); err != nil {
+		return err
+	}
+	return t.second.Delete(ctx, key)
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCache_StoreAndFetch(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewPedanticRegistry()
+
+	c, err := newInMemoryCache("test", 1024*1024, log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	c.Store(ctx, map[string][]byte{"key-1": []byte("value-1"), "key-2": []byte("value-2")}, time.Minute)
+	c.Store(ctx, map[string][]byte{"expired": []byte("value")}, -time.Minute)
+
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+	}, c.Fetch(ctx, []string{"key-1", "key-2", "expired", "missing"}))
+
+	require.NoError(t, c.Delete(ctx, "key-1"))
+	assert.Equal(t, map[string][]byte{"key-2": []byte("value-2")}, c.Fetch(ctx, []string{"key-1", "key-2"}))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cache_requests_total Total number of items requests to cache.
+		# TYPE cache_requests_total counter
+		cache_requests_total{backend="inmemory",name="test"} 6
+
+		# HELP cache_hits_total Total number of items requests to the cache that were a hit.
+		# TYPE cache_hits_total counter
+		cache_hits_total{backend="inmemory",name="test"} 3
+
+		# HELP cache_items Current number of items in the in-memory cache.
+		# TYPE cache_items gauge
+		cache_items{backend="inmemory",name="test"} 1
+
+		# HELP cache_items_expired_total Total number of items removed from the in-memory cache because their TTL expired.
+		# TYPE cache_items_expired_total counter
+		cache_items_expired_total{backend="inmemory",name="test"} 1
+	`), "cache_requests_total", "cache_hits_total", "cache_items", "cache_items_expired_total"))
+}
+
+func TestInMemoryCache_ShouldEvictOldestEntriesWhenFull(t *testing.T) {
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("x"), 100)
+	itemSize := entrySize("key-0", value)
+
+	reg := prometheus.NewPedanticRegistry()
+	c, err := newInMemoryCache("test", 3*itemSize, log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	c.Store(ctx, map[string][]byte{"key-0": value}, time.Minute)
+	c.Store(ctx, map[string][]byte{"key-1": value}, time.Minute)
+	c.Store(ctx, map[string][]byte{"key-2": value}, time.Minute)
+
+	// Access the oldest entry, so that it's not the least recently used anymore.
+	require.Len(t, c.Fetch(ctx, []string{"key-0"}), 1)
+
+	c.Store(ctx, map[string][]byte{"key-3": value}, time.Minute)
+
+	found := c.Fetch(ctx, []string{"key-0", "key-1", "key-2", "key-3"})
+	assert.Contains(t, found, "key-0")
+	assert.NotContains(t, found, "key-1")
+	assert.Contains(t, found, "key-2")
+	assert.Contains(t, found, "key-3")
+
+	// An item bigger than the whole cache should be skipped.
+	c.Store(ctx, map[string][]byte{"huge": bytes.Repeat([]byte("x"), int(4*itemSize))}, time.Minute)
+	assert.Empty(t, c.Fetch(ctx, []string{"huge"}))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.evicted))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.skipped.WithLabelValues(inMemoryCacheOpSet, inMemoryCacheReasonMaxItemSize)))
+	assert.Equal(t, 3*itemSize, c.curSize)
+}
+
+func TestInMemoryCache_ShouldReplaceExistingEntry(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := newInMemoryCache("test", 1024*1024, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	c.Store(ctx, map[string][]byte{"key": []byte("short")}, time.Minute)
+	c.Store(ctx, map[string][]byte{"key": []byte("a longer value")}, time.Minute)
+
+	assert.Equal(t, map[string][]byte{"key": []byte("a longer value")}, c.Fetch(ctx, []string{"key"}))
+	assert.Equal(t, entrySize("key", []byte("a longer value")), c.curSize)
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+
+	first, err := newInMemoryCache("test", 1024*1024, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	second := cache.NewInstrumentedMockCache()
+
+	c := newTieredCache(first, second, time.Minute)
+
+	// Entries stored through the tiered cache should be stored in both tiers.
+	c.Store(ctx, map[string][]byte{"key-1": []byte("value-1")}, time.Hour)
+	assert.Equal(t, map[string][]byte{"key-1": []byte("value-1")}, first.Fetch(ctx, []string{"key-1"}))
+	assert.Equal(t, map[string][]byte{"key-1": []byte("value-1")}, second.Fetch(ctx, []string{"key-1"}))
+
+	// A lookup fully served by the first tier should not hit the second one.
+	fetchCalls := second.CountFetchCalls()
+	assert.Equal(t, map[string][]byte{"key-1": []byte("value-1")}, c.Fetch(ctx, []string{"key-1"}))
+	assert.Equal(t, fetchCalls, second.CountFetchCalls())
+
+	// Entries only found in the second tier should be stored back in the first one.
+	second.Store(ctx, map[string][]byte{"key-2": []byte("value-2")}, time.Hour)
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+	}, c.Fetch(ctx, []string{"key-1", "key-2", "missing"}))
+	assert.Equal(t, map[string][]byte{"key-2": []byte("value-2")}, first.Fetch(ctx, []string{"key-2"}))
+
+	// Deletions should be propagated to both tiers.
+	require.NoError(t, c.Delete(ctx, "key-1"))
+	assert.Empty(t, first.Fetch(ctx, []string{"key-1"}))
+	assert.Empty(t, second.Fetch(ctx, []string{"key-1"}))
+}
@@ -15,12 +15,15 @@ import (
 	"github.com/go-kit/log"
 	"github.com/gogo/protobuf/types"
 	"github.com/grafana/dskit/cache"
-	mimir_tsdb "github.com/grafana/dskit/cache"
 	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util/test"
 )
 
 func TestResultsCacheConfig_Validate(t *testing.T) {
@@ -38,9 +41,9 @@ func TestResultsCacheConfig_Validate(t *testing.T) {
 		},
 		"should pass with memcached backend": {
 			cfg: ResultsCacheConfig{
-				BackendConfig: cache.BackendConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
 					Backend: cache.BackendMemcached,
-					Memcached: mimir_tsdb.MemcachedConfig{
+					Memcached: cache.MemcachedConfig{
 						Addresses: "localhost",
 					},
 				},
@@ -48,18 +51,84 @@ func TestResultsCacheConfig_Validate(t *testing.T) {
 		},
 		"should fail with invalid memcached config": {
 			cfg: ResultsCacheConfig{
-				BackendConfig: cache.BackendConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
 					Backend: cache.BackendMemcached,
-					Memcached: mimir_tsdb.MemcachedConfig{
+					Memcached: cache.MemcachedConfig{
 						Addresses: "",
 					},
 				},
 			},
 			expected: errors.New("query-frontend results cache: no memcached addresses configured"),
 		},
+		"should pass with redis backend": {
+			cfg: ResultsCacheConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
+					Backend: cache.BackendRedis,
+					Redis: cache.RedisClientConfig{
+						Endpoint:            []string{"localhost:6379"},
+						MaxAsyncConcurrency: 1,
+					},
+				},
+			},
+		},
+		"should fail with invalid redis config": {
+			cfg: ResultsCacheConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
+					Backend: cache.BackendRedis,
+					Redis: cache.RedisClientConfig{
+						MaxAsyncConcurrency: 1,
+					},
+				},
+			},
+			expected: errors.New("query-frontend results cache: no redis endpoint provided"),
+		},
+		"should pass with inmemory backend": {
+			cfg: ResultsCacheConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
+					Backend: resultsCacheBackendInMemory,
+				},
+				InMemory: InMemoryResultsCacheConfig{
+					MaxSizeBytes: 1024,
+				},
+			},
+		},
+		"should fail with inmemory backend and no max size": {
+			cfg: ResultsCacheConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
+					Backend: resultsCacheBackendInMemory,
+				},
+			},
+			expected: errors.New("query-frontend results cache: the in-memory results cache max size must be positive"),
+		},
+		"should pass with inmemory first tier in front of memcached": {
+			cfg: ResultsCacheConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
+					Backend: cache.BackendMemcached,
+					Memcached: cache.MemcachedConfig{
+						Addresses: "localhost",
+					},
+				},
+				InMemory: InMemoryResultsCacheConfig{
+					MaxSizeBytes:     1024,
+					FirstTierEnabled: true,
+				},
+			},
+		},
+		"should fail with inmemory first tier and inmemory backend": {
+			cfg: ResultsCacheConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
+					Backend: resultsCacheBackendInMemory,
+				},
+				InMemory: InMemoryResultsCacheConfig{
+					MaxSizeBytes:     1024,
+					FirstTierEnabled: true,
+				},
+			},
+			expected: errors.New("query-frontend results cache: the in-memory results cache first tier requires a memcached or redis backend"),
+		},
 		"should fail with unsupported backend": {
 			cfg: ResultsCacheConfig{
-				BackendConfig: cache.BackendConfig{
+				CacheBackendConfig: mimir_tsdb.CacheBackendConfig{
 					Backend: "unsupported",
 				},
 			},
@@ -78,6 +147,66 @@ func TestResultsCacheConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestNewResultsCache_Redis(t *testing.T) {
+	server := test.NewRedisServer(t, "secret")
+
+	cfg := ResultsCacheConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.Backend = cache.BackendRedis
+	cfg.Redis.Endpoint = []string{server.Addr()}
+	cfg.Redis.Password = flagext.SecretWithValue("secret")
+	require.NoError(t, cfg.Validate())
+
+	c, err := newResultsCache(cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	c.Store(ctx, map[string][]byte{"key": []byte("value")}, time.Minute)
+
+	require.Eventually(t, func() bool {
+		return len(c.Fetch(ctx, []string{"key", "missing"})) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, map[string][]byte{"key": []byte("value")}, c.Fetch(ctx, []string{"key", "missing"}))
+}
+
+func TestNewResultsCache_InMemory(t *testing.T) {
+	cfg := ResultsCacheConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.Backend = resultsCacheBackendInMemory
+	require.NoError(t, cfg.Validate())
+
+	c, err := newResultsCache(cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	c.Store(ctx, map[string][]byte{"key": []byte("value")}, time.Minute)
+	assert.Equal(t, map[string][]byte{"key": []byte("value")}, c.Fetch(ctx, []string{"key", "missing"}))
+}
+
+func TestNewResultsCache_InMemoryFirstTier(t *testing.T) {
+	server := test.NewRedisServer(t, "")
+
+	cfg := ResultsCacheConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.Backend = cache.BackendRedis
+	cfg.Redis.Endpoint = []string{server.Addr()}
+	cfg.InMemory.FirstTierEnabled = true
+	require.NoError(t, cfg.Validate())
+
+	c, err := newResultsCache(cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	c.Store(ctx, map[string][]byte{"key": []byte("value")}, time.Minute)
+
+	// The entry is immediately available from the first tier, and eventually stored in redis too.
+	assert.Equal(t, map[string][]byte{"key": []byte("value")}, c.Fetch(ctx, []string{"key"}))
+	require.Eventually(t, func() bool {
+		return server.Keys(0) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
 func mkAPIResponse(start, end, step int64) *PrometheusResponse {
 	var samples []mimirpb.Sample
 	for i := start; i <= end; i += step {
@@ -297,6 +426,7 @@ func TestIsResponseCachable(t *testing.T) {
 	for _, tc := range []struct {
 		name     string
 		response Response
+		cacheGen string
 		expected bool
 	}{
 		// Tests only for cacheControlHeader
@@ -355,10 +485,33 @@ func TestIsResponseCachable(t *testing.T) {
 			}),
 			expected: true,
 		},
+		// Tests for the results cache generation.
+		{
+			name: "results cache generation matches the expected one",
+			response: Response(&PrometheusResponse{
+				Headers: []*PrometheusResponseHeader{{Name: mimir_tsdb.ResultsCacheGenHeader, Values: []string{"1"}}},
+			}),
+			cacheGen: "1",
+			expected: true,
+		},
+		{
+			name: "results cache generation doesn't match the expected one",
+			response: Response(&PrometheusResponse{
+				Headers: []*PrometheusResponseHeader{{Name: mimir_tsdb.ResultsCacheGenHeader, Values: []string{"1"}}},
+			}),
+			cacheGen: "2",
+			expected: false,
+		},
+		{
+			name:     "results cache generation is missing",
+			response: Response(&PrometheusResponse{}),
+			cacheGen: "1",
+			expected: false,
+		},
 	} {
 		{
 			t.Run(tc.name, func(t *testing.T) {
-				ret := isResponseCachable(tc.response, log.NewNopLogger())
+				ret := isResponseCachable(tc.response, tc.cacheGen, log.NewNopLogger())
 				require.Equal(t, tc.expected, ret)
 			})
 		}
@@ -36,18 +36,29 @@ const (
 
 // Config for query_range middleware chain.
 type Config struct {
-	SplitQueriesByInterval time.Duration `yaml:"split_queries_by_interval" category:"advanced"`
-	AlignQueriesWithStep   bool          `yaml:"align_queries_with_step"`
-	ResultsCacheConfig     `yaml:"results_cache"`
-	CacheResults           bool   `yaml:"cache_results"`
-	MaxRetries             int    `yaml:"max_retries" category:"advanced"`
-	ShardedQueries         bool   `yaml:"parallelize_shardable_queries"`
-	CacheUnalignedRequests bool   `yaml:"cache_unaligned_requests" category:"advanced"`
-	MaxSeriesPerShard      uint64 `yaml:"query_sharding_max_series_per_shard" category:"experimental"`
+	SplitQueriesByInterval  time.Duration `yaml:"split_queries_by_interval" category:"advanced"`
+	AlignQueriesWithStep    bool          `yaml:"align_queries_with_step"`
+	ResultsCacheConfig      `yaml:"results_cache"`
+	CacheResults            bool          `yaml:"cache_results"`
+	MaxRetries              int           `yaml:"max_retries" category:"advanced"`
+	ShardedQueries          bool          `yaml:"parallelize_shardable_queries"`
+	CacheUnalignedRequests  bool          `yaml:"cache_unaligned_requests" category:"advanced"`
+	MaxSeriesPerShard       uint64        `yaml:"query_sharding_max_series_per_shard" category:"experimental"`
+	LongRangeQueryThreshold time.Duration `yaml:"long_range_query_threshold" category:"experimental"`
+
+	QueryCostBucketIndexEnabled bool `yaml:"query_cost_bucket_index_enabled" category:"experimental"`
 
 	// CacheSplitter allows to inject a CacheSplitter to use for generating cache keys.
 	// If nil, the querymiddleware package uses a ConstSplitter with SplitQueriesByInterval.
 	CacheSplitter CacheSplitter `yaml:"-"`
+
+	// BucketIndexLoader allows to inject the BucketIndexLoader used to estimate the query cost.
+	// If nil, the query cost is estimated without the blocks stats.
+	BucketIndexLoader BucketIndexLoader `yaml:"-"`
+
+	// ResultsCacheGenLoader allows to inject the ResultsCacheGenLoader used to invalidate the cached results.
+	// If nil, the cached results are never invalidated.
+	ResultsCacheGenLoader ResultsCacheGenLoader `yaml:"-"`
 }
 
 // RegisterFlags adds the flags required to config this to the given FlagSet.
@@ -59,6 +70,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&cfg.ShardedQueries, "query-frontend.parallelize-shardable-queries", false, "True to enable query sharding.")
 	f.BoolVar(&cfg.CacheUnalignedRequests, "query-frontend.cache-unaligned-requests", false, "Cache requests that are not step-aligned.")
 	f.Uint64Var(&cfg.MaxSeriesPerShard, maxSeriesPerShardFlagName, 0, "How many series a single sharded partial query should load at most. This is not a strict requirement guaranteed to be honoured by query sharding, but a hint given to the query sharding when the query execution is initially planned. 0 to disable cardinality-based hints.")
+	f.DurationVar(&cfg.LongRangeQueryThreshold, "query-frontend.long-range-query-threshold", 0, "Range queries whose time range is longer than this threshold are assigned the long-range query class, unless the request explicitly sets a query class. The query-scheduler uses query classes to prioritize the queries of a tenant. 0 to disable.")
+	f.BoolVar(&cfg.QueryCostBucketIndexEnabled, "query-frontend.query-cost-bucket-index-enabled", false, "True to read the tenants bucket index to estimate the number of series fetched from the store-gateways when enforcing the query cost budget. Requires the blocks storage bucket to be configured in the query-frontend. When disabled, each day of the query time range is assumed to be stored in a block containing as many series as the ingesters.")
 	cfg.ResultsCacheConfig.RegisterFlags(f)
 }
 
@@ -154,6 +167,7 @@ func NewTripperware(
 	}
 	return MergeTripperwares(
 		newActiveUsersTripperware(registerer),
+		newQueryClassTripperware(cfg.LongRangeQueryThreshold),
 		queryRangeTripperware,
 	), err
 }
@@ -218,6 +232,7 @@ func newQueryTripperware(
 			splitter,
 			cacheExtractor,
 			shouldCache,
+			cfg.ResultsCacheGenLoader,
 			log,
 			registerer,
 		))
@@ -273,11 +288,17 @@ func newQueryTripperware(
 		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("retry", metrics, log), newRetryMiddleware(log, cfg.MaxRetries, retryMiddlewareMetrics))
 	}
 
+	queryCostMetrics := newQueryCostMetrics(registerer)
+
 	return func(next http.RoundTripper) http.RoundTripper {
-		queryrange := newLimitedParallelismRoundTripper(next, codec, limits, queryRangeMiddleware...)
-		instant := defaultInstantQueryParamsRoundTripper(
-			newLimitedParallelismRoundTripper(next, codec, limits, queryInstantMiddleware...),
+		queryrange := newQueryCostRoundTripper(
+			newLimitedParallelismRoundTripper(next, codec, limits, queryRangeMiddleware...),
+			next, codec, limits, c, cfg.BucketIndexLoader, engineOpts.LookbackDelta, log, queryCostMetrics,
 		)
+		instant := defaultInstantQueryParamsRoundTripper(newQueryCostRoundTripper(
+			newLimitedParallelismRoundTripper(next, codec, limits, queryInstantMiddleware...),
+			next, codec, limits, c, cfg.BucketIndexLoader, engineOpts.LookbackDelta, log, queryCostMetrics,
+		))
 		return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
 			switch {
 			case isRangeQuery(r.URL.Path):
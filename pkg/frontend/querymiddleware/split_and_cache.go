@@ -98,6 +98,7 @@ type splitAndCacheMiddleware struct {
 	splitter               CacheSplitter
 	extractor              Extractor
 	shouldCacheReq         shouldCacheFn
+	cacheGenLoader         ResultsCacheGenLoader
 }
 
 // newSplitAndCacheMiddleware makes a new splitAndCacheMiddleware.
@@ -112,6 +113,7 @@ func newSplitAndCacheMiddleware(
 	splitter CacheSplitter,
 	extractor Extractor,
 	shouldCacheReq shouldCacheFn,
+	cacheGenLoader ResultsCacheGenLoader,
 	logger log.Logger,
 	reg prometheus.Registerer) Middleware {
 	metrics := newSplitAndCacheMiddlewareMetrics(reg)
@@ -130,6 +132,7 @@ func newSplitAndCacheMiddleware(
 			splitter:               splitter,
 			extractor:              extractor,
 			shouldCacheReq:         shouldCacheReq,
+			cacheGenLoader:         cacheGenLoader,
 			logger:                 logger,
 		}
 	})
@@ -152,6 +155,16 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 	maxCacheFreshness := validation.MaxDurationPerTenant(tenantIDs, s.limits.MaxCacheFreshness)
 	maxCacheTime := int64(model.Now().Add(-maxCacheFreshness))
 
+	// The results cache generation is part of the cache keys, so that the outdated results are not used anymore.
+	var cacheGen string
+	if isCacheEnabled && s.cacheGenLoader != nil {
+		cacheGen, err = s.cacheGenLoader.ResultsCacheGen(ctx, tenantIDs)
+		if err != nil {
+			level.Warn(s.logger).Log("msg", "failed to load results cache generation, not using the results cache", "err", err)
+			isCacheEnabled = false
+		}
+	}
+
 	// Lookup the results cache.
 	if isCacheEnabled {
 		s.metrics.queryResultCacheAttemptedCount.Add(float64(len(splitReqs)))
@@ -169,6 +182,9 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 			}
 
 			splitReq.cacheKey = s.splitter.GenerateCacheKey(ctx, tenant.JoinTenantIDs(tenantIDs), splitReq.orig)
+			if cacheGen != "" {
+				splitReq.cacheKey = cacheGen + ":" + splitReq.cacheKey
+			}
 			lookupKeys = append(lookupKeys, splitReq.cacheKey)
 			lookupReqs = append(lookupReqs, splitReq)
 		}
@@ -251,7 +267,7 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 
 			for downstreamIdx, downstreamReq := range splitReq.downstreamRequests {
 				downstreamRes := splitReq.downstreamResponses[downstreamIdx]
-				if !isResponseCachable(downstreamRes, s.logger) {
+				if !isResponseCachable(downstreamRes, cacheGen, s.logger) {
 					continue
 				}
 
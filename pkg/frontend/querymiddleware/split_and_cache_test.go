@@ -21,6 +21,7 @@ import (
 	"github.com/grafana/dskit/cache"
 	"github.com/grafana/dskit/concurrency"
 	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/model"
@@ -35,6 +36,7 @@ import (
 	apierror "github.com/grafana/mimir/pkg/api/error"
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/querier/stats"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/util"
 )
 
@@ -96,6 +98,7 @@ func TestSplitAndCacheMiddleware_SplitByInterval(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
 		log.NewNopLogger(),
 		reg,
 	)
@@ -161,6 +164,7 @@ func TestSplitAndCacheMiddleware_ResultsCache(t *testing.T) {
 		ConstSplitter(day),
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		prometheus.NewPedanticRegistry(),
 	)
@@ -246,6 +250,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAli
 		ConstSplitter(day),
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		reg,
 	)
@@ -326,6 +331,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_EnabledCachingOfStepUnalignedReque
 		ConstSplitter(day),
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		prometheus.NewPedanticRegistry(),
 	)
@@ -482,6 +488,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotCacheRequestEarlierThanMa
 				cacheSplitter,
 				PrometheusResponseExtractor{},
 				resultsCacheAlwaysEnabled,
+				nil,
 				log.NewNopLogger(),
 				reg,
 			)
@@ -694,6 +701,7 @@ func TestSplitAndCacheMiddleware_ResultsCacheFuzzy(t *testing.T) {
 					ConstSplitter(day),
 					PrometheusResponseExtractor{},
 					resultsCacheAlwaysEnabled,
+					nil,
 					log.NewNopLogger(),
 					prometheus.NewPedanticRegistry(),
 				).Wrap(downstream)
@@ -930,6 +938,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ExtentsEdgeCases(t *testing.T) {
 				cacheSplitter,
 				PrometheusResponseExtractor{},
 				resultsCacheAlwaysEnabled,
+				nil,
 				log.NewNopLogger(),
 				prometheus.NewPedanticRegistry(),
 			).Wrap(HandlerFunc(func(_ context.Context, req Request) (Response, error) {
@@ -962,6 +971,88 @@ func TestSplitAndCacheMiddleware_ResultsCache_ExtentsEdgeCases(t *testing.T) {
 	}
 }
 
+func TestSplitAndCacheMiddleware_ResultsCacheGen(t *testing.T) {
+	cacheBackend := cache.NewInstrumentedMockCache()
+	cacheGenLoader := &mockResultsCacheGenLoader{gen: "1"}
+
+	mw := newSplitAndCacheMiddleware(
+		true,
+		true,
+		24*time.Hour,
+		false,
+		mockLimits{maxCacheFreshness: 10 * time.Minute},
+		newTestPrometheusCodec(),
+		cacheBackend,
+		ConstSplitter(day),
+		PrometheusResponseExtractor{},
+		resultsCacheAlwaysEnabled,
+		cacheGenLoader,
+		log.NewNopLogger(),
+		prometheus.NewPedanticRegistry(),
+	)
+
+	// The downstream response carries the generation of the querier.
+	querierGen := "1"
+	downstreamReqs := 0
+	rc := mw.Wrap(HandlerFunc(func(_ context.Context, req Request) (Response, error) {
+		downstreamReqs++
+		return &PrometheusResponse{
+			Status:  "success",
+			Data:    &PrometheusData{ResultType: model.ValMatrix.String()},
+			Headers: []*PrometheusResponseHeader{{Name: mimir_tsdb.ResultsCacheGenHeader, Values: []string{querierGen}}},
+		}, nil
+	}))
+
+	req := Request(&PrometheusRangeQueryRequest{
+		Path:  "/api/v1/query_range",
+		Start: parseTimeRFC3339(t, "2021-10-15T10:00:00Z").Unix() * 1000,
+		End:   parseTimeRFC3339(t, "2021-10-15T12:00:00Z").Unix() * 1000,
+		Step:  120 * 1000,
+		Query: `{__name__=~".+"}`,
+	})
+	ctx := user.InjectOrgID(context.Background(), "1")
+
+	_, err := rc.Do(ctx, req)
+	require.NoError(t, err)
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, downstreamReqs)
+	assert.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	// Once the generation changes, the cached results should not be used anymore.
+	cacheGenLoader.gen = "2"
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, downstreamReqs)
+
+	// The response computed by a querier with another generation should not be cached.
+	assert.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	querierGen = "2"
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 3, downstreamReqs)
+	assert.Equal(t, 2, cacheBackend.CountStoreCalls())
+
+	// The results cache should not be used at all if the generation can't be loaded.
+	cacheGenLoader.err = errors.New("failed to load")
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 4, downstreamReqs)
+	assert.Equal(t, 2, cacheBackend.CountStoreCalls())
+}
+
+type mockResultsCacheGenLoader struct {
+	gen string
+	err error
+}
+
+func (m *mockResultsCacheGenLoader) ResultsCacheGen(context.Context, []string) (string, error) {
+	return m.gen, m.err
+}
+
 func TestSplitAndCacheMiddleware_StoreAndFetchCacheExtents(t *testing.T) {
 	cacheBackend := cache.NewMockCache()
 	mw := newSplitAndCacheMiddleware(
@@ -975,6 +1066,7 @@ func TestSplitAndCacheMiddleware_StoreAndFetchCacheExtents(t *testing.T) {
 		ConstSplitter(day),
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		prometheus.NewPedanticRegistry(),
 	).Wrap(nil).(*splitAndCacheMiddleware)
@@ -1022,6 +1114,7 @@ func TestSplitAndCacheMiddleware_WrapMultipleTimes(t *testing.T) {
 		ConstSplitter(day),
 		PrometheusResponseExtractor{},
 		resultsCacheAlwaysEnabled,
+		nil,
 		log.NewNopLogger(),
 		prometheus.NewPedanticRegistry(),
 	)
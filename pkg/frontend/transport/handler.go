@@ -31,6 +31,7 @@ import (
 	apierror "github.com/grafana/mimir/pkg/api/error"
 	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/activequeries"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 )
@@ -63,10 +64,11 @@ func (cfg *HandlerConfig) RegisterFlags(f *flag.FlagSet) {
 // Handler accepts queries and forwards them to RoundTripper. It can wait on in-flight requests and log slow queries,
 // all other logic is inside the RoundTripper.
 type Handler struct {
-	cfg          HandlerConfig
-	log          log.Logger
-	roundTripper http.RoundTripper
-	at           *activitytracker.ActivityTracker
+	cfg           HandlerConfig
+	log           log.Logger
+	roundTripper  http.RoundTripper
+	at            *activitytracker.ActivityTracker
+	activeQueries *activequeries.Tracker
 
 	// Metrics.
 	querySeconds *prometheus.CounterVec
@@ -82,12 +84,13 @@ type Handler struct {
 }
 
 // NewHandler creates a new frontend handler.
-func NewHandler(cfg HandlerConfig, roundTripper http.RoundTripper, log log.Logger, reg prometheus.Registerer, at *activitytracker.ActivityTracker) *Handler {
+func NewHandler(cfg HandlerConfig, roundTripper http.RoundTripper, log log.Logger, reg prometheus.Registerer, at *activitytracker.ActivityTracker, activeQueries *activequeries.Tracker) *Handler {
 	h := &Handler{
-		cfg:          cfg,
-		log:          log,
-		roundTripper: roundTripper,
-		at:           at,
+		cfg:           cfg,
+		log:           log,
+		roundTripper:  roundTripper,
+		at:            at,
+		activeQueries: activeQueries,
 	}
 	h.cond = sync.NewCond(&h.mtx)
 
@@ -192,6 +195,12 @@ func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	activityIndex := f.at.Insert(func() string { return httpRequestActivity(r, params) })
 	defer f.at.Delete(activityIndex)
 
+	// Track the query as in-flight, so that it can be listed and canceled. Canceling the query cancels the
+	// context of the request, which is propagated to all the queries it's been split and sharded into.
+	ctx, activeQueryDone := f.activeQueries.Track(r.Context(), r, params, stats)
+	defer activeQueryDone()
+	r = r.WithContext(ctx)
+
 	startTime := time.Now()
 	resp, err := f.roundTripper.RoundTrip(r)
 	queryResponseTime := time.Since(startTime)
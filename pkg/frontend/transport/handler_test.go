@@ -31,6 +31,7 @@ import (
 	"github.com/weaveworks/common/user"
 	"go.uber.org/atomic"
 
+	"github.com/grafana/mimir/pkg/util/activequeries"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
 )
 
@@ -149,7 +150,7 @@ func TestHandler_ServeHTTP(t *testing.T) {
 			t.Cleanup(func() { require.NoError(t, at.Close()) })
 
 			logger := &testLogger{}
-			handler := NewHandler(tt.cfg, roundTripper, logger, reg, at)
+			handler := NewHandler(tt.cfg, roundTripper, logger, reg, at, nil)
 
 			req := tt.request().WithContext(user.InjectOrgID(context.Background(), "12345"))
 			resp := httptest.NewRecorder()
@@ -242,7 +243,7 @@ func TestHandler_FailedRoundTrip(t *testing.T) {
 			reg := prometheus.NewPedanticRegistry()
 			logs := &concurrency.SyncBuffer{}
 			logger := log.NewLogfmtLogger(logs)
-			handler := NewHandler(test.cfg, roundTripper, logger, reg, nil)
+			handler := NewHandler(test.cfg, roundTripper, logger, reg, nil, nil)
 
 			ctx := user.InjectOrgID(context.Background(), "12345")
 			req := httptest.NewRequest("GET", test.path, nil)
@@ -272,6 +273,39 @@ func TestHandler_FailedRoundTrip(t *testing.T) {
 	}
 }
 
+func TestHandler_ActiveQueries(t *testing.T) {
+	started := make(chan struct{})
+	roundTripper := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		close(started)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	tracker := activequeries.NewTracker()
+	cfg := HandlerConfig{MaxBodySize: 1024, QueryStatsEnabled: true}
+	handler := NewHandler(cfg, roundTripper, &testLogger{}, prometheus.NewPedanticRegistry(), nil, tracker)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?query=up&start=0&end=3600&step=60", nil)
+		req = req.WithContext(user.InjectOrgID(context.Background(), "12345"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-started
+
+	queries := tracker.List("12345")
+	require.Len(t, queries, 1)
+	assert.Equal(t, "up", queries[0].Query)
+	assert.Equal(t, "60", queries[0].Step)
+
+	// Canceling the query cancels the request sent downstream.
+	require.True(t, tracker.Cancel("12345", queries[0].ID))
+	<-done
+	assert.Empty(t, tracker.List("12345"))
+}
+
 // Test Handler.Stop.
 func TestHandler_Stop(t *testing.T) {
 	const (
@@ -293,7 +327,7 @@ func TestHandler_Stop(t *testing.T) {
 	reg := prometheus.NewPedanticRegistry()
 	cfg := HandlerConfig{MaxBodySize: 1024}
 	logger := &testLogger{}
-	handler := NewHandler(cfg, roundTripper, logger, reg, nil)
+	handler := NewHandler(cfg, roundTripper, logger, reg, nil, nil)
 
 	var wg sync.WaitGroup
 	for i := 0; i < requests; i++ {
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/limiter"
+)
+
+// activeSeriesTargetSizeBytes is the maximum size in bytes of the messages streamed by ActiveSeries.
+// We arbitrarily set it to 1mb to avoid reaching the actual gRPC default limit (4mb).
+const activeSeriesTargetSizeBytes = 1 * 1024 * 1024
+
+var (
+	errActiveSeriesTrackingDisabled = errors.New("the active series tracking is disabled in the ingesters")
+	errActiveSeriesNotReady         = errors.New("the active series are not available until the active series idle timeout has elapsed since the last reload of the active series custom trackers")
+)
+
+// ActiveSeries implements client.IngesterServer. It streams the labels of the series which received samples
+// within the active series idle timeout, and match the matchers of the request. It fails once the number of
+// series exceeds the maximum number of series fetched per query of the tenant.
+func (i *Ingester) ActiveSeries(req *client.ActiveSeriesRequest, srv client.Ingester_ActiveSeriesServer) error {
+	if err := i.checkRunning(); err != nil {
+		return err
+	}
+	if !i.cfg.ActiveSeriesMetricsEnabled {
+		return errActiveSeriesTrackingDisabled
+	}
+
+	userID, err := tenant.TenantID(srv.Context())
+	if err != nil {
+		return err
+	}
+
+	matchers, err := client.FromLabelMatchers(req.GetMatchers())
+	if err != nil {
+		return err
+	}
+
+	db := i.getTSDB(userID)
+	if db == nil {
+		return nil
+	}
+
+	maxSeries := i.limits.MaxFetchedSeriesPerQuery(userID)
+	numSeries := 0
+
+	response := &client.ActiveSeriesResponse{}
+	responseSizeBytes := 0
+	valid, err := db.activeSeries.ForEach(time.Now(), matchers, func(series labels.Labels) error {
+		numSeries++
+		if maxSeries > 0 && numSeries > maxSeries {
+			return httpgrpc.Errorf(http.StatusUnprocessableEntity, limiter.MaxSeriesHitMsgFormat, maxSeries)
+		}
+
+		metric := &mimirpb.Metric{Labels: mimirpb.FromLabelsToLabelAdapters(series)}
+		response.Metric = append(response.Metric, metric)
+		responseSizeBytes += metric.Size()
+
+		if responseSizeBytes < activeSeriesTargetSizeBytes {
+			return nil
+		}
+		if err := client.SendActiveSeriesResponse(srv, response); err != nil {
+			return err
+		}
+		response.Metric = response.Metric[:0]
+		responseSizeBytes = 0
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errActiveSeriesNotReady
+	}
+
+	if len(response.Metric) > 0 {
+		return client.SendActiveSeriesResponse(srv, response)
+	}
+	return nil
+}
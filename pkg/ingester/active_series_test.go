@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/limiter"
+)
+
+func TestIngester_ActiveSeries(t *testing.T) {
+	ing, err := prepareIngesterWithBlocksStorage(t, defaultIngesterTestConfig(t), nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	activeSeries := func(matchers ...*labels.Matcher) []labels.Labels {
+		req, err := client.ToLabelMatchers(matchers)
+		require.NoError(t, err)
+
+		srv := &mockActiveSeriesServer{context: ctx}
+		require.NoError(t, ing.ActiveSeries(&client.ActiveSeriesRequest{Matchers: req}, srv))
+
+		var series []labels.Labels
+		for _, resp := range srv.SentResponses {
+			for _, m := range resp.Metric {
+				series = append(series, mimirpb.FromLabelAdaptersToLabelsWithCopy(m.Labels))
+			}
+		}
+		return series
+	}
+
+	// The tenant has no TSDB yet.
+	assert.Empty(t, activeSeries(labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")))
+
+	fixtures := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "foo", "pod", "1"),
+		labels.FromStrings(labels.MetricName, "foo", "pod", "2"),
+		labels.FromStrings(labels.MetricName, "bar", "pod", "1"),
+	}
+	for _, lbls := range fixtures {
+		_, err := ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{lbls}, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API))
+		require.NoError(t, err)
+	}
+
+	assert.ElementsMatch(t, fixtures[:2], activeSeries(labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "foo")))
+	assert.ElementsMatch(t, []labels.Labels{fixtures[0], fixtures[2]}, activeSeries(labels.MustNewMatcher(labels.MatchEqual, "pod", "1")))
+	assert.Empty(t, activeSeries(labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "baz")))
+}
+
+func TestIngester_ActiveSeries_MaxFetchedSeriesPerQuery(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.MaxFetchedSeriesPerQuery = 1
+
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, defaultIngesterTestConfig(t), limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	for _, lbls := range []labels.Labels{
+		labels.FromStrings(labels.MetricName, "foo", "pod", "1"),
+		labels.FromStrings(labels.MetricName, "foo", "pod", "2"),
+	} {
+		_, err := ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{lbls}, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API))
+		require.NoError(t, err)
+	}
+
+	activeSeries := func(matcher *labels.Matcher) error {
+		req, err := client.ToLabelMatchers([]*labels.Matcher{matcher})
+		require.NoError(t, err)
+		return ing.ActiveSeries(&client.ActiveSeriesRequest{Matchers: req}, &mockActiveSeriesServer{context: ctx})
+	}
+
+	require.NoError(t, activeSeries(labels.MustNewMatcher(labels.MatchEqual, "pod", "1")))
+
+	// The request fails once the number of series exceeds the limit.
+	err = activeSeries(labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "foo"))
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusUnprocessableEntity), resp.Code)
+	assert.Equal(t, fmt.Sprintf(limiter.MaxSeriesHitMsgFormat, 1), string(resp.Body))
+}
+
+func TestIngester_ActiveSeries_Disabled(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.ActiveSeriesMetricsEnabled = false
+
+	ing, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	srv := &mockActiveSeriesServer{context: user.InjectOrgID(context.Background(), userID)}
+	require.ErrorIs(t, ing.ActiveSeries(&client.ActiveSeriesRequest{}, srv), errActiveSeriesTrackingDisabled)
+}
+
+type mockActiveSeriesServer struct {
+	client.Ingester_ActiveSeriesServer
+	SentResponses []*client.ActiveSeriesResponse
+	context       context.Context
+}
+
+func (m *mockActiveSeriesServer) Send(resp *client.ActiveSeriesResponse) error {
+	// The response is reused by the ingester after it's sent.
+	m.SentResponses = append(m.SentResponses, &client.ActiveSeriesResponse{Metric: append([]*mimirpb.Metric(nil), resp.Metric...)})
+	return nil
+}
+
+func (m *mockActiveSeriesServer) Context() context.Context {
+	return m.context
+}
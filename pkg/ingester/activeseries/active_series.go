@@ -124,6 +124,57 @@ func (c *ActiveSeries) Active(now time.Time) (int, []int, bool) {
 	return total, totalMatching, true
 }
 
+// ForEach calls f with the labels of each active series matching all the matchers, until f returns an error.
+// The series are only listed if the first return value is true, which shows if enough time has passed since
+// last reload.
+func (c *ActiveSeries) ForEach(now time.Time, matchers []*labels.Matcher, f func(labels.Labels) error) (bool, error) {
+	c.mu.RLock()
+	lastMatchersUpdate := c.lastMatchersUpdate
+	c.mu.RUnlock()
+
+	activeSince := now.Add(-c.timeout)
+	if lastMatchersUpdate.After(activeSince) {
+		return false, nil
+	}
+
+	var series []labels.Labels
+	for s := 0; s < numStripes; s++ {
+		// The stripe isn't locked while calling f, which may be slow.
+		series = c.stripes[s].activeSeries(activeSince.UnixNano(), matchers, series[:0])
+		for _, lbls := range series {
+			if err := f(lbls); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// activeSeries appends the labels of the series active since activeSinceNanos and matching all the matchers to buf.
+func (s *seriesStripe) activeSeries(activeSinceNanos int64, matchers []*labels.Matcher, buf []labels.Labels) []labels.Labels {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, entries := range s.refs {
+		for _, entry := range entries {
+			if entry.nanos.Load() < activeSinceNanos || !matchesAll(matchers, entry.lbs) {
+				continue
+			}
+			buf = append(buf, entry.lbs)
+		}
+	}
+	return buf
+}
+
+func matchesAll(matchers []*labels.Matcher, series labels.Labels) bool {
+	for _, m := range matchers {
+		if !m.Matches(series.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
 // getTotalAndUpdateMatching will return the total active series in the stripe and also update the slice provided
 // with each matcher's total.
 func (s *seriesStripe) getTotalAndUpdateMatching(matching []int) int {
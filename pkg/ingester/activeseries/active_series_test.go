@@ -101,6 +101,49 @@ func TestActiveSeries_ShouldCorrectlyHandleHashCollisions(t *testing.T) {
 	assert.True(t, valid)
 }
 
+func TestActiveSeries_ForEach(t *testing.T) {
+	ls1 := labels.FromStrings("a", "1", "b", "1")
+	ls2 := labels.FromStrings("a", "2", "b", "1")
+	ls3 := labels.FromStrings("a", "3", "b", "2")
+
+	now := time.Now()
+	c := NewActiveSeries(&Matchers{}, DefaultTimeout)
+	c.UpdateSeries(ls1, now.Add(-2*DefaultTimeout), copyFn)
+	c.UpdateSeries(ls2, now, copyFn)
+	c.UpdateSeries(ls3, now, copyFn)
+
+	list := func(matchers ...*labels.Matcher) []labels.Labels {
+		var series []labels.Labels
+		valid, err := c.ForEach(now, matchers, func(lbls labels.Labels) error {
+			series = append(series, lbls)
+			return nil
+		})
+		require.NoError(t, err)
+		require.True(t, valid)
+		return series
+	}
+
+	// The series which aren't active anymore aren't listed, even if they haven't been purged yet.
+	assert.ElementsMatch(t, []labels.Labels{ls2, ls3}, list())
+	assert.ElementsMatch(t, []labels.Labels{ls2}, list(labels.MustNewMatcher(labels.MatchEqual, "b", "1")))
+	assert.Empty(t, list(labels.MustNewMatcher(labels.MatchEqual, "c", "1")))
+
+	// The listing stops at the first error.
+	calls := 0
+	_, err := c.ForEach(now, nil, func(labels.Labels) error {
+		calls++
+		return fmt.Errorf("failed")
+	})
+	require.EqualError(t, err, "failed")
+	assert.Equal(t, 1, calls)
+
+	// The series aren't listed until enough time has passed since the last reload.
+	c.ReloadMatchers(&Matchers{}, now)
+	valid, err := c.ForEach(now, nil, func(labels.Labels) error { return nil })
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
 func TestActiveSeries_Purge_NoMatchers(t *testing.T) {
 	series := []labels.Labels{
 		labels.FromStrings("a", "1"),
@@ -1784,6 +1784,282 @@ func (m *TimeSeriesFile) GetData() []byte {
 	return nil
 }
 
+type TransferChunksResponse struct {
+}
+
+func (m *TransferChunksResponse) Reset()      { *m = TransferChunksResponse{} }
+func (*TransferChunksResponse) ProtoMessage() {}
+func (*TransferChunksResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_60f6df4f3586b478, []int{33}
+}
+func (m *TransferChunksResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TransferChunksResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TransferChunksResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TransferChunksResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TransferChunksResponse.Merge(m, src)
+}
+func (m *TransferChunksResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *TransferChunksResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TransferChunksResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TransferChunksResponse proto.InternalMessageInfo
+
+type ActiveGrowthRequest struct {
+	// Maximum number of metric names and of label pairs to return.
+	Limit int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (m *ActiveGrowthRequest) Reset()      { *m = ActiveGrowthRequest{} }
+func (*ActiveGrowthRequest) ProtoMessage() {}
+func (*ActiveGrowthRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_60f6df4f3586b478, []int{34}
+}
+func (m *ActiveGrowthRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ActiveGrowthRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ActiveGrowthRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ActiveGrowthRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ActiveGrowthRequest.Merge(m, src)
+}
+func (m *ActiveGrowthRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *ActiveGrowthRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ActiveGrowthRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ActiveGrowthRequest proto.InternalMessageInfo
+
+func (m *ActiveGrowthRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ActiveGrowthResponse struct {
+	MetricNames []*SeriesGrowth `protobuf:"bytes,1,rep,name=metric_names,json=metricNames,proto3" json:"metric_names,omitempty"`
+	LabelPairs  []*SeriesGrowth `protobuf:"bytes,2,rep,name=label_pairs,json=labelPairs,proto3" json:"label_pairs,omitempty"`
+}
+
+func (m *ActiveGrowthResponse) Reset()      { *m = ActiveGrowthResponse{} }
+func (*ActiveGrowthResponse) ProtoMessage() {}
+func (*ActiveGrowthResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_60f6df4f3586b478, []int{35}
+}
+func (m *ActiveGrowthResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ActiveGrowthResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ActiveGrowthResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ActiveGrowthResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ActiveGrowthResponse.Merge(m, src)
+}
+func (m *ActiveGrowthResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *ActiveGrowthResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ActiveGrowthResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ActiveGrowthResponse proto.InternalMessageInfo
+
+func (m *ActiveGrowthResponse) GetMetricNames() []*SeriesGrowth {
+	if m != nil {
+		return m.MetricNames
+	}
+	return nil
+}
+
+func (m *ActiveGrowthResponse) GetLabelPairs() []*SeriesGrowth {
+	if m != nil {
+		return m.LabelPairs
+	}
+	return nil
+}
+
+type SeriesGrowth struct {
+	LabelName  string `protobuf:"bytes,1,opt,name=label_name,json=labelName,proto3" json:"label_name,omitempty"`
+	LabelValue string `protobuf:"bytes,2,opt,name=label_value,json=labelValue,proto3" json:"label_value,omitempty"`
+	// Number of series created within the growth tracking window.
+	CreatedSeries uint64 `protobuf:"varint,3,opt,name=created_series,json=createdSeries,proto3" json:"created_series,omitempty"`
+}
+
+func (m *SeriesGrowth) Reset()      { *m = SeriesGrowth{} }
+func (*SeriesGrowth) ProtoMessage() {}
+func (*SeriesGrowth) Descriptor() ([]byte, []int) {
+	return fileDescriptor_60f6df4f3586b478, []int{36}
+}
+func (m *SeriesGrowth) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SeriesGrowth) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SeriesGrowth.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SeriesGrowth) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SeriesGrowth.Merge(m, src)
+}
+func (m *SeriesGrowth) XXX_Size() int {
+	return m.Size()
+}
+func (m *SeriesGrowth) XXX_DiscardUnknown() {
+	xxx_messageInfo_SeriesGrowth.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SeriesGrowth proto.InternalMessageInfo
+
+func (m *SeriesGrowth) GetLabelName() string {
+	if m != nil {
+		return m.LabelName
+	}
+	return ""
+}
+
+func (m *SeriesGrowth) GetLabelValue() string {
+	if m != nil {
+		return m.LabelValue
+	}
+	return ""
+}
+
+func (m *SeriesGrowth) GetCreatedSeries() uint64 {
+	if m != nil {
+		return m.CreatedSeries
+	}
+	return 0
+}
+
+type ActiveSeriesRequest struct {
+	Matchers []*LabelMatcher `protobuf:"bytes,1,rep,name=matchers,proto3" json:"matchers,omitempty"`
+}
+
+func (m *ActiveSeriesRequest) Reset()      { *m = ActiveSeriesRequest{} }
+func (*ActiveSeriesRequest) ProtoMessage() {}
+func (*ActiveSeriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_60f6df4f3586b478, []int{37}
+}
+func (m *ActiveSeriesRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ActiveSeriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ActiveSeriesRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ActiveSeriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ActiveSeriesRequest.Merge(m, src)
+}
+func (m *ActiveSeriesRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *ActiveSeriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ActiveSeriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ActiveSeriesRequest proto.InternalMessageInfo
+
+func (m *ActiveSeriesRequest) GetMatchers() []*LabelMatcher {
+	if m != nil {
+		return m.Matchers
+	}
+	return nil
+}
+
+type ActiveSeriesResponse struct {
+	Metric []*mimirpb.Metric `protobuf:"bytes,1,rep,name=metric,proto3" json:"metric,omitempty"`
+}
+
+func (m *ActiveSeriesResponse) Reset()      { *m = ActiveSeriesResponse{} }
+func (*ActiveSeriesResponse) ProtoMessage() {}
+func (*ActiveSeriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_60f6df4f3586b478, []int{38}
+}
+func (m *ActiveSeriesResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ActiveSeriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ActiveSeriesResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ActiveSeriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ActiveSeriesResponse.Merge(m, src)
+}
+func (m *ActiveSeriesResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *ActiveSeriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ActiveSeriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ActiveSeriesResponse proto.InternalMessageInfo
+
+func (m *ActiveSeriesResponse) GetMetric() []*mimirpb.Metric {
+	if m != nil {
+		return m.Metric
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("cortex.MatchType", MatchType_name, MatchType_value)
 	proto.RegisterEnum("cortex.ReadRequest_ResponseType", ReadRequest_ResponseType_name, ReadRequest_ResponseType_value)
@@ -1822,115 +2098,132 @@ func init() {
 	proto.RegisterType((*LabelMatchers)(nil), "cortex.LabelMatchers")
 	proto.RegisterType((*LabelMatcher)(nil), "cortex.LabelMatcher")
 	proto.RegisterType((*TimeSeriesFile)(nil), "cortex.TimeSeriesFile")
+	proto.RegisterType((*TransferChunksResponse)(nil), "cortex.TransferChunksResponse")
+	proto.RegisterType((*ActiveGrowthRequest)(nil), "cortex.ActiveGrowthRequest")
+	proto.RegisterType((*ActiveGrowthResponse)(nil), "cortex.ActiveGrowthResponse")
+	proto.RegisterType((*SeriesGrowth)(nil), "cortex.SeriesGrowth")
+	proto.RegisterType((*ActiveSeriesRequest)(nil), "cortex.ActiveSeriesRequest")
+	proto.RegisterType((*ActiveSeriesResponse)(nil), "cortex.ActiveSeriesResponse")
 }
 
 func init() { proto.RegisterFile("ingester.proto", fileDescriptor_60f6df4f3586b478) }
 
 var fileDescriptor_60f6df4f3586b478 = []byte{
-	// 1640 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0xcd, 0x6f, 0xdb, 0x46,
-	0x16, 0xd7, 0x48, 0xb2, 0x6c, 0x3d, 0xd9, 0x8a, 0x3c, 0x8a, 0x6d, 0x85, 0x59, 0xd3, 0x5a, 0x2e,
-	0x92, 0xd5, 0xee, 0x26, 0xf2, 0x47, 0xb2, 0x40, 0x12, 0x14, 0x08, 0x64, 0x5b, 0x89, 0x5d, 0x47,
-	0x72, 0x42, 0xd9, 0x8d, 0x51, 0xa0, 0x20, 0x28, 0x69, 0x6c, 0x13, 0x16, 0x29, 0x85, 0xa4, 0x0a,
-	0xfb, 0x56, 0xa0, 0xf7, 0xb6, 0xe8, 0xa9, 0xa7, 0x02, 0xbd, 0xf5, 0x58, 0x14, 0x28, 0x7a, 0xeb,
-	0x39, 0x97, 0x02, 0x39, 0x06, 0x3d, 0x04, 0x8d, 0x73, 0x69, 0x6f, 0xf9, 0x13, 0x0a, 0xce, 0x0c,
-	0x29, 0x92, 0xa2, 0x3f, 0x52, 0x24, 0x39, 0x49, 0xf3, 0xde, 0x6f, 0x7e, 0xf3, 0xbe, 0x38, 0xef,
-	0x91, 0x90, 0xd5, 0x8c, 0x3d, 0x62, 0xd9, 0xc4, 0x2c, 0xf7, 0xcc, 0xae, 0xdd, 0xc5, 0xa9, 0x56,
-	0xd7, 0xb4, 0xc9, 0xa1, 0x70, 0x7d, 0x4f, 0xb3, 0xf7, 0xfb, 0xcd, 0x72, 0xab, 0xab, 0xcf, 0xef,
-	0x75, 0xf7, 0xba, 0xf3, 0x54, 0xdd, 0xec, 0xef, 0xd2, 0x15, 0x5d, 0xd0, 0x7f, 0x6c, 0x9b, 0xb0,
-	0xe0, 0x87, 0x9b, 0xea, 0xae, 0x6a, 0xa8, 0xf3, 0xba, 0xa6, 0x6b, 0xe6, 0x7c, 0xef, 0x60, 0x8f,
-	0xfd, 0xeb, 0x35, 0xd9, 0x2f, 0xdb, 0x21, 0xd5, 0x41, 0x78, 0xa0, 0x36, 0x49, 0xa7, 0xae, 0xea,
-	0xc4, 0xaa, 0x18, 0xed, 0x8f, 0xd4, 0x4e, 0x9f, 0x58, 0x32, 0x79, 0xd2, 0x27, 0x96, 0x8d, 0x17,
-	0x60, 0x4c, 0x57, 0xed, 0xd6, 0x3e, 0x31, 0xad, 0x02, 0x2a, 0x26, 0x4a, 0x99, 0xa5, 0x8b, 0x65,
-	0x66, 0x59, 0x99, 0xee, 0xaa, 0x31, 0xa5, 0xec, 0xa1, 0xa4, 0x35, 0xb8, 0x1c, 0xc9, 0x67, 0xf5,
-	0xba, 0x86, 0x45, 0xf0, 0x7f, 0x60, 0x44, 0xb3, 0x89, 0xee, 0xb2, 0xe5, 0x03, 0x6c, 0x1c, 0xcb,
-	0x10, 0xd2, 0x2a, 0x64, 0x7c, 0x52, 0x3c, 0x0b, 0xd0, 0x71, 0x96, 0x8a, 0xa1, 0xea, 0xa4, 0x80,
-	0x8a, 0xa8, 0x94, 0x96, 0xd3, 0x1d, 0xf7, 0x28, 0x3c, 0x0d, 0xa9, 0x4f, 0x29, 0xb0, 0x10, 0x2f,
-	0x26, 0x4a, 0x69, 0x99, 0xaf, 0x24, 0x13, 0x66, 0x7d, 0x2c, 0x2b, 0xaa, 0xd9, 0xd6, 0x0c, 0xb5,
-	0xa3, 0xd9, 0x47, 0xae, 0x8b, 0x73, 0x90, 0x19, 0xf0, 0x32, 0xbb, 0xd2, 0x32, 0x78, 0xc4, 0x56,
-	0x20, 0x06, 0xf1, 0x73, 0xc5, 0x60, 0x1b, 0xc4, 0x93, 0xce, 0xe4, 0x61, 0xb8, 0x11, 0x0c, 0xc3,
-	0xec, 0x70, 0x18, 0x1a, 0xc4, 0xd4, 0x88, 0xb5, 0xd2, 0xed, 0x1b, 0xb6, 0x1b, 0x90, 0x17, 0x08,
-	0xa6, 0x22, 0x01, 0x67, 0xc5, 0x46, 0x05, 0xcc, 0xd4, 0x34, 0x26, 0x8a, 0x45, 0x77, 0x72, 0x5f,
-	0x6e, 0x9c, 0x7a, 0xf4, 0x90, 0xb4, 0x6a, 0xd8, 0xe6, 0x91, 0x9c, 0xeb, 0x84, 0xc4, 0xc2, 0xca,
-	0xb0, 0x69, 0x14, 0x8a, 0x73, 0x90, 0x38, 0x20, 0x47, 0xdc, 0x26, 0xe7, 0x2f, 0xbe, 0x08, 0x23,
-	0xd4, 0x8e, 0x42, 0xbc, 0x88, 0x4a, 0x49, 0x99, 0x2d, 0xee, 0xc4, 0x6f, 0x21, 0xe9, 0x57, 0x04,
-	0x19, 0x99, 0xa8, 0x6d, 0x37, 0x35, 0x65, 0x18, 0x7d, 0xd2, 0x67, 0xc6, 0x86, 0x8a, 0xef, 0x51,
-	0x9f, 0x98, 0x6e, 0x06, 0x65, 0x17, 0x84, 0x77, 0x60, 0x46, 0x6d, 0xb5, 0x48, 0xcf, 0x26, 0x6d,
-	0xc5, 0xe4, 0xa1, 0x56, 0xec, 0xa3, 0x1e, 0x77, 0x36, 0xbb, 0x54, 0x74, 0xf7, 0xfb, 0x4e, 0x29,
-	0xbb, 0x49, 0xd9, 0x3a, 0xea, 0x11, 0x79, 0xca, 0x25, 0xf0, 0x4b, 0x2d, 0xe9, 0x26, 0x8c, 0xfb,
-	0x05, 0x38, 0x03, 0xa3, 0x8d, 0x4a, 0xed, 0xe1, 0x83, 0x6a, 0x23, 0x17, 0xc3, 0x33, 0x90, 0x6f,
-	0x6c, 0xc9, 0xd5, 0x4a, 0xad, 0xba, 0xaa, 0xec, 0x6c, 0xca, 0xca, 0xca, 0xda, 0x76, 0x7d, 0xa3,
-	0x91, 0x43, 0xd2, 0x5d, 0x67, 0x97, 0xea, 0x51, 0xe1, 0x79, 0x18, 0x35, 0x89, 0xd5, 0xef, 0xd8,
-	0xae, 0x3f, 0x53, 0x21, 0x7f, 0x18, 0x4e, 0x76, 0x51, 0xd2, 0x11, 0xe0, 0x86, 0x6d, 0x12, 0x55,
-	0x0f, 0xd0, 0x2c, 0x43, 0xb6, 0xb5, 0xdf, 0x37, 0x0e, 0x48, 0xdb, 0x4d, 0x25, 0x63, 0xbb, 0xec,
-	0xb2, 0xb1, 0x3d, 0x2b, 0x0c, 0xc3, 0x92, 0x21, 0x4f, 0xb4, 0xfc, 0x4b, 0xa7, 0xea, 0x9d, 0xa8,
-	0x1d, 0x29, 0x9a, 0xd1, 0x26, 0x87, 0x34, 0x15, 0x09, 0x19, 0xa8, 0x68, 0xdd, 0x91, 0x48, 0x3f,
-	0x20, 0xc8, 0x47, 0xf0, 0xe0, 0x5d, 0x48, 0xd1, 0xe4, 0x87, 0x9f, 0xe0, 0x5e, 0x93, 0xd5, 0xca,
-	0x43, 0x55, 0x33, 0x97, 0x6f, 0x3f, 0x7d, 0x31, 0x17, 0xfb, 0xed, 0xc5, 0xdc, 0xe2, 0x79, 0xae,
-	0x23, 0xb6, 0xaf, 0xd2, 0x56, 0x7b, 0x36, 0x31, 0x65, 0xce, 0x8e, 0x17, 0x21, 0x45, 0x2d, 0x76,
-	0xeb, 0x34, 0x1f, 0xe1, 0xdc, 0x72, 0xd2, 0x39, 0x47, 0xe6, 0x40, 0xe9, 0x27, 0x04, 0x19, 0x9f,
-	0x16, 0x8b, 0x90, 0xd1, 0x35, 0x43, 0xb1, 0x35, 0x9d, 0x28, 0xf4, 0x51, 0x73, 0x7c, 0x4c, 0xeb,
-	0x9a, 0xb1, 0xa5, 0xe9, 0xa4, 0x66, 0x51, 0xbd, 0x7a, 0xe8, 0xe9, 0xe3, 0x5c, 0xaf, 0x1e, 0x72,
-	0xfd, 0x02, 0x24, 0x9d, 0xe2, 0x29, 0x24, 0x8a, 0xa8, 0x94, 0x5d, 0xfa, 0x47, 0x84, 0x01, 0xe5,
-	0xaa, 0xd1, 0xea, 0xb6, 0x35, 0x63, 0x4f, 0xa6, 0x48, 0x8c, 0x21, 0xd9, 0x56, 0x6d, 0xb5, 0x90,
-	0x2c, 0xa2, 0xd2, 0xb8, 0x4c, 0xff, 0x4b, 0x45, 0x18, 0x73, 0x51, 0x4e, 0xd9, 0x6c, 0xd7, 0x37,
-	0xea, 0x9b, 0x8f, 0xeb, 0xb9, 0x18, 0x1e, 0x85, 0xc4, 0xce, 0xa6, 0x9c, 0x43, 0xd2, 0x37, 0x08,
-	0xc6, 0xfd, 0x05, 0x8d, 0xaf, 0x01, 0xb6, 0x6c, 0xd5, 0xb4, 0xa9, 0x69, 0x96, 0xad, 0xea, 0xbd,
-	0x81, 0xfd, 0x39, 0xaa, 0xd9, 0x72, 0x15, 0x35, 0x0b, 0x97, 0x20, 0x47, 0x8c, 0x76, 0x10, 0xcb,
-	0x7c, 0xc9, 0x12, 0xa3, 0xed, 0x47, 0xfa, 0x6f, 0xb2, 0xc4, 0xb9, 0x6e, 0xb2, 0xef, 0x10, 0x5c,
-	0xac, 0x1e, 0x12, 0xbd, 0xd7, 0x51, 0xcd, 0xf7, 0x62, 0xe2, 0xe2, 0x90, 0x89, 0x53, 0x51, 0x26,
-	0x5a, 0x3e, 0x1b, 0x37, 0x60, 0x22, 0xf0, 0xf8, 0xe0, 0x3b, 0x00, 0xf4, 0xa4, 0xa8, 0x9b, 0xa3,
-	0xd7, 0x2c, 0x3b, 0xc7, 0xb1, 0x62, 0xe6, 0xf5, 0xe3, 0x43, 0x4b, 0x5f, 0x23, 0xc8, 0x53, 0x36,
-	0xf7, 0xb9, 0xe3, 0x9c, 0x77, 0x21, 0xc3, 0xaa, 0xcc, 0x4f, 0x3a, 0xe3, 0x9a, 0x36, 0xa0, 0xf4,
-	0xd7, 0xa5, 0x7f, 0x47, 0xc8, 0xa8, 0xf8, 0x1b, 0x19, 0xd5, 0x80, 0xa9, 0x50, 0x12, 0xde, 0x82,
-	0xa7, 0xbf, 0x20, 0xc0, 0xfe, 0xae, 0xcb, 0x13, 0x7b, 0x46, 0x2b, 0x89, 0xce, 0x7b, 0xfc, 0x0d,
-	0xf2, 0x9e, 0x38, 0x33, 0xef, 0xce, 0xd3, 0x73, 0x8e, 0xbc, 0xdf, 0x82, 0x7c, 0xc0, 0x7e, 0x1e,
-	0x93, 0x7f, 0xc2, 0xb8, 0xaf, 0xd9, 0xb9, 0x0d, 0x3d, 0x33, 0xe8, 0x58, 0x96, 0xf4, 0x2d, 0x82,
-	0xc9, 0xc1, 0x90, 0xf2, 0x7e, 0x4b, 0xfa, 0x5c, 0xae, 0xfd, 0x9f, 0xa7, 0x86, 0xdb, 0xc7, 0x3d,
-	0x3b, 0x6b, 0x52, 0x91, 0x30, 0xe4, 0xb6, 0x2d, 0x62, 0x36, 0x6c, 0xd5, 0x76, 0xbd, 0x92, 0x7e,
-	0x46, 0x30, 0xe9, 0x13, 0x72, 0xaa, 0x2b, 0xee, 0xc0, 0xa9, 0x75, 0x0d, 0xc5, 0x54, 0x6d, 0x96,
-	0x69, 0x24, 0x4f, 0x78, 0x52, 0x59, 0xb5, 0x89, 0x53, 0x0c, 0x46, 0x5f, 0x1f, 0x0c, 0x0c, 0x4e,
-	0xbf, 0x4e, 0x1b, 0x7d, 0x9d, 0xf7, 0x82, 0x6b, 0x80, 0xd5, 0x9e, 0xa6, 0x84, 0x98, 0x12, 0x94,
-	0x29, 0xa7, 0xf6, 0xb4, 0xf5, 0x00, 0x59, 0x19, 0xf2, 0x66, 0xbf, 0x43, 0xc2, 0xf0, 0x24, 0x85,
-	0x4f, 0x3a, 0xaa, 0x00, 0x5e, 0xfa, 0x04, 0xf2, 0x8e, 0xe1, 0xeb, 0xab, 0x41, 0xd3, 0x67, 0x60,
-	0xb4, 0x6f, 0x11, 0x53, 0xd1, 0xda, 0xbc, 0x3a, 0x53, 0xce, 0x72, 0xbd, 0x8d, 0xaf, 0xf3, 0xcb,
-	0x37, 0x4e, 0x63, 0x7c, 0xc9, 0x8d, 0xf1, 0x90, 0xf3, 0xfc, 0x5e, 0xbe, 0x0f, 0xd8, 0x51, 0x59,
-	0x41, 0xf6, 0x45, 0x18, 0xb1, 0x1c, 0x41, 0xb8, 0xa5, 0x46, 0x58, 0x22, 0x33, 0xa4, 0xf4, 0x23,
-	0x02, 0xb1, 0x46, 0x6c, 0x53, 0x6b, 0x59, 0xf7, 0xba, 0x66, 0x30, 0xa5, 0xef, 0xb8, 0xb4, 0x6e,
-	0xc1, 0xb8, 0x5b, 0x33, 0x8a, 0x45, 0xec, 0xd3, 0x6f, 0xcc, 0x8c, 0x0b, 0x6d, 0x10, 0x5b, 0xda,
-	0x80, 0xb9, 0x13, 0x6d, 0xe6, 0xa1, 0x28, 0x41, 0x4a, 0xa7, 0x10, 0x1e, 0x8b, 0xdc, 0xe0, 0x62,
-	0x61, 0x5b, 0x65, 0xae, 0x97, 0x0a, 0x30, 0xcd, 0xc9, 0x6a, 0xc4, 0x56, 0x9d, 0xe8, 0xba, 0xd5,
-	0xb7, 0x09, 0x33, 0x43, 0x1a, 0x4e, 0x7f, 0x13, 0xc6, 0x74, 0x2e, 0xe3, 0x07, 0x14, 0xc2, 0x07,
-	0x78, 0x7b, 0x3c, 0xa4, 0xf4, 0x27, 0x82, 0x0b, 0xa1, 0xdb, 0xd6, 0x89, 0xd7, 0xae, 0xd9, 0xd5,
-	0x15, 0xf7, 0x15, 0x6a, 0x50, 0x1a, 0x59, 0x47, 0xbe, 0xce, 0xc5, 0xeb, 0x6d, 0x7f, 0xed, 0xc4,
-	0x03, 0xb5, 0x33, 0x98, 0x6a, 0x12, 0xef, 0x74, 0xaa, 0xf9, 0x9f, 0x37, 0xd5, 0x24, 0xe9, 0x39,
-	0x13, 0x6e, 0xaa, 0xa2, 0xe6, 0x99, 0x2f, 0x11, 0x8c, 0x30, 0x0f, 0xdf, 0x55, 0xfd, 0x08, 0x30,
-	0x46, 0xf8, 0x6c, 0x42, 0x1f, 0xdb, 0x11, 0xd9, 0x5b, 0x47, 0xce, 0x32, 0x15, 0x98, 0x08, 0xd4,
-	0xca, 0xdf, 0x78, 0x3f, 0x54, 0x60, 0xdc, 0xaf, 0xc1, 0x57, 0xf8, 0x90, 0x85, 0xe8, 0x90, 0x35,
-	0xe9, 0xee, 0xa6, 0x6a, 0x3a, 0x91, 0x7b, 0x93, 0x15, 0x6d, 0x48, 0x2c, 0x6d, 0xf4, 0xff, 0xe0,
-	0x45, 0x22, 0x41, 0x85, 0x6c, 0x21, 0x7d, 0x8e, 0x20, 0x3b, 0xa8, 0x90, 0x7b, 0x5a, 0x87, 0xbc,
-	0x8d, 0x02, 0x11, 0x60, 0x6c, 0x57, 0xeb, 0x10, 0x6a, 0x03, 0x3b, 0xce, 0x5b, 0x47, 0x45, 0xea,
-	0xbf, 0x1f, 0x42, 0xda, 0x73, 0x01, 0xa7, 0x61, 0xa4, 0xfa, 0x68, 0xbb, 0xf2, 0x20, 0x17, 0xc3,
-	0x13, 0x90, 0xae, 0x6f, 0x6e, 0x29, 0x6c, 0x89, 0xf0, 0x05, 0xc8, 0xc8, 0xd5, 0xfb, 0xd5, 0x1d,
-	0xa5, 0x56, 0xd9, 0x5a, 0x59, 0xcb, 0xc5, 0x31, 0x86, 0x2c, 0x13, 0xd4, 0x37, 0xb9, 0x2c, 0xb1,
-	0xf4, 0xc5, 0x28, 0x8c, 0xb9, 0x36, 0xe2, 0xdb, 0x90, 0x7c, 0xd8, 0xb7, 0xf6, 0xf1, 0xf4, 0xa0,
-	0x42, 0x1f, 0x9b, 0x9a, 0x4d, 0xf8, 0x13, 0x27, 0xcc, 0x0c, 0xc9, 0xd9, 0xf3, 0x26, 0xc5, 0xf0,
-	0x2a, 0x64, 0x7c, 0xa3, 0x0d, 0x8e, 0x7c, 0x99, 0x12, 0x2e, 0x07, 0xa4, 0xc1, 0x29, 0x48, 0x8a,
-	0x2d, 0x20, 0xbc, 0x09, 0x59, 0xaa, 0x72, 0x27, 0x12, 0x0b, 0x7b, 0x93, 0x71, 0xd4, 0xa4, 0x28,
-	0xcc, 0x9e, 0xa0, 0xf5, 0xcc, 0x5a, 0x0b, 0xbe, 0xe7, 0x0b, 0x51, 0x9f, 0x04, 0xc2, 0xc6, 0x45,
-	0x34, 0x7e, 0x29, 0x86, 0xab, 0x00, 0x83, 0xb6, 0x89, 0x2f, 0x05, 0xc0, 0xfe, 0x56, 0x2f, 0x08,
-	0x51, 0x2a, 0x8f, 0x66, 0x19, 0xd2, 0x5e, 0xd3, 0xc0, 0x85, 0x88, 0x3e, 0xc2, 0x48, 0x4e, 0xee,
-	0x30, 0x52, 0x0c, 0xdf, 0x83, 0xf1, 0x4a, 0xa7, 0x73, 0x1e, 0x1a, 0xc1, 0xaf, 0xb1, 0xc2, 0x3c,
-	0x1d, 0xef, 0x02, 0x0d, 0xdf, 0xd3, 0xf8, 0xaa, 0xf7, 0xac, 0x9c, 0xda, 0x7c, 0x84, 0x7f, 0x9f,
-	0x89, 0xf3, 0x4e, 0xdb, 0x82, 0x0b, 0xa1, 0xeb, 0x1a, 0x8b, 0xa1, 0xdd, 0xa1, 0x1b, 0x5e, 0x98,
-	0x3b, 0x51, 0xef, 0xb1, 0x36, 0xf9, 0xa0, 0x16, 0xfc, 0x24, 0x84, 0xa5, 0xe1, 0x24, 0x84, 0xbf,
-	0x3f, 0x09, 0xff, 0x3a, 0x15, 0xe3, 0xab, 0xca, 0x03, 0x98, 0x8e, 0xfe, 0xe4, 0x82, 0xaf, 0x44,
-	0xd4, 0xcc, 0xf0, 0x67, 0x20, 0xe1, 0xea, 0x59, 0xb0, 0xc1, 0x61, 0xcb, 0x1f, 0x3c, 0x7b, 0x29,
-	0xc6, 0x9e, 0xbf, 0x14, 0x63, 0xaf, 0x5f, 0x8a, 0xe8, 0xb3, 0x63, 0x11, 0x7d, 0x7f, 0x2c, 0xa2,
-	0xa7, 0xc7, 0x22, 0x7a, 0x76, 0x2c, 0xa2, 0xdf, 0x8f, 0x45, 0xf4, 0xc7, 0xb1, 0x18, 0x7b, 0x7d,
-	0x2c, 0xa2, 0xaf, 0x5e, 0x89, 0xb1, 0x67, 0xaf, 0xc4, 0xd8, 0xf3, 0x57, 0x62, 0xec, 0xe3, 0x54,
-	0xab, 0xa3, 0x11, 0xc3, 0x6e, 0xa6, 0xe8, 0x87, 0xb7, 0x1b, 0x7f, 0x05, 0x00, 0x00, 0xff, 0xff,
-	0x2f, 0xe4, 0x86, 0xaf, 0xf3, 0x13, 0x00, 0x00,
+	// 1822 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0x4d, 0x6c, 0x1b, 0xc7,
+	0x15, 0xe6, 0x90, 0xfa, 0xe3, 0x23, 0x45, 0xd3, 0x43, 0xfd, 0x30, 0x6b, 0x7b, 0xa5, 0x6e, 0xe1,
+	0x94, 0x6d, 0x12, 0xca, 0x3f, 0x09, 0xea, 0x04, 0x05, 0x52, 0x4a, 0xa6, 0x6d, 0x55, 0xa6, 0xe4,
+	0x2c, 0xa5, 0xc6, 0x28, 0x50, 0x2c, 0x96, 0xe4, 0x48, 0x5a, 0x78, 0x77, 0xc9, 0xec, 0x0e, 0x53,
+	0xe9, 0x56, 0xa0, 0x40, 0xaf, 0x2d, 0x7a, 0xea, 0xa9, 0x40, 0x6f, 0x3d, 0x16, 0x05, 0x8a, 0xde,
+	0x7a, 0xce, 0xa5, 0x80, 0x8f, 0x41, 0x0f, 0x46, 0x2d, 0x5f, 0xda, 0x5b, 0xae, 0xbd, 0x05, 0x3b,
+	0x3f, 0xfb, 0xc7, 0xa5, 0x24, 0x07, 0x71, 0x4e, 0xe4, 0xbc, 0xf7, 0xe6, 0xcd, 0xfb, 0xf9, 0xe6,
+	0xbd, 0x37, 0x0b, 0x15, 0xcb, 0x3d, 0x22, 0x3e, 0x25, 0x5e, 0x73, 0xe4, 0x0d, 0xe9, 0x10, 0xcf,
+	0xf5, 0x87, 0x1e, 0x25, 0x27, 0xca, 0x7b, 0x47, 0x16, 0x3d, 0x1e, 0xf7, 0x9a, 0xfd, 0xa1, 0xb3,
+	0x71, 0x34, 0x3c, 0x1a, 0x6e, 0x30, 0x76, 0x6f, 0x7c, 0xc8, 0x56, 0x6c, 0xc1, 0xfe, 0xf1, 0x6d,
+	0xca, 0xad, 0xb8, 0xb8, 0x67, 0x1e, 0x9a, 0xae, 0xb9, 0xe1, 0x58, 0x8e, 0xe5, 0x6d, 0x8c, 0x9e,
+	0x1d, 0xf1, 0x7f, 0xa3, 0x1e, 0xff, 0xe5, 0x3b, 0xb4, 0x5d, 0x50, 0x1e, 0x9b, 0x3d, 0x62, 0xef,
+	0x9a, 0x0e, 0xf1, 0x5b, 0xee, 0xe0, 0xe7, 0xa6, 0x3d, 0x26, 0xbe, 0x4e, 0x3e, 0x1b, 0x13, 0x9f,
+	0xe2, 0x5b, 0xb0, 0xe0, 0x98, 0xb4, 0x7f, 0x4c, 0x3c, 0xbf, 0x8e, 0xd6, 0x0b, 0x8d, 0xd2, 0x9d,
+	0xa5, 0x26, 0xb7, 0xac, 0xc9, 0x76, 0x75, 0x38, 0x53, 0x0f, 0xa5, 0xb4, 0x47, 0x70, 0x2d, 0x53,
+	0x9f, 0x3f, 0x1a, 0xba, 0x3e, 0xc1, 0x3f, 0x84, 0x59, 0x8b, 0x12, 0x47, 0x6a, 0xab, 0x25, 0xb4,
+	0x09, 0x59, 0x2e, 0xa1, 0xdd, 0x87, 0x52, 0x8c, 0x8a, 0x6f, 0x00, 0xd8, 0xc1, 0xd2, 0x70, 0x4d,
+	0x87, 0xd4, 0xd1, 0x3a, 0x6a, 0x14, 0xf5, 0xa2, 0x2d, 0x8f, 0xc2, 0x2b, 0x30, 0xf7, 0x39, 0x13,
+	0xac, 0xe7, 0xd7, 0x0b, 0x8d, 0xa2, 0x2e, 0x56, 0x9a, 0x07, 0x37, 0x62, 0x5a, 0xb6, 0x4c, 0x6f,
+	0x60, 0xb9, 0xa6, 0x6d, 0xd1, 0x53, 0xe9, 0xe2, 0x1a, 0x94, 0x22, 0xbd, 0xdc, 0xae, 0xa2, 0x0e,
+	0xa1, 0x62, 0x3f, 0x11, 0x83, 0xfc, 0xa5, 0x62, 0x70, 0x00, 0xea, 0xb4, 0x33, 0x45, 0x18, 0xee,
+	0x26, 0xc3, 0x70, 0x63, 0x32, 0x0c, 0x5d, 0xe2, 0x59, 0xc4, 0xdf, 0x1a, 0x8e, 0x5d, 0x2a, 0x03,
+	0xf2, 0x02, 0xc1, 0x72, 0xa6, 0xc0, 0x45, 0xb1, 0x31, 0x01, 0x73, 0x36, 0x8b, 0x89, 0xe1, 0xb3,
+	0x9d, 0xc2, 0x97, 0xbb, 0xe7, 0x1e, 0x3d, 0x41, 0x6d, 0xbb, 0xd4, 0x3b, 0xd5, 0xab, 0x76, 0x8a,
+	0xac, 0x6c, 0xc1, 0x72, 0xa6, 0x28, 0xae, 0x42, 0xe1, 0x19, 0x39, 0x15, 0x36, 0x05, 0x7f, 0xf1,
+	0x12, 0xcc, 0x32, 0x3b, 0xea, 0xf9, 0x75, 0xd4, 0x98, 0xd1, 0xf9, 0xe2, 0xa3, 0xfc, 0x3d, 0xa4,
+	0xfd, 0x0b, 0x41, 0x49, 0x27, 0xe6, 0x40, 0xa6, 0xa6, 0x09, 0xf3, 0x9f, 0x8d, 0xb9, 0xb1, 0x29,
+	0xf0, 0x7d, 0x32, 0x26, 0x9e, 0xcc, 0xa0, 0x2e, 0x85, 0xf0, 0x53, 0x58, 0x35, 0xfb, 0x7d, 0x32,
+	0xa2, 0x64, 0x60, 0x78, 0x22, 0xd4, 0x06, 0x3d, 0x1d, 0x09, 0x67, 0x2b, 0x77, 0xd6, 0xe5, 0xfe,
+	0xd8, 0x29, 0x4d, 0x99, 0x94, 0xfd, 0xd3, 0x11, 0xd1, 0x97, 0xa5, 0x82, 0x38, 0xd5, 0xd7, 0xde,
+	0x87, 0x72, 0x9c, 0x80, 0x4b, 0x30, 0xdf, 0x6d, 0x75, 0x9e, 0x3c, 0x6e, 0x77, 0xab, 0x39, 0xbc,
+	0x0a, 0xb5, 0xee, 0xbe, 0xde, 0x6e, 0x75, 0xda, 0xf7, 0x8d, 0xa7, 0x7b, 0xba, 0xb1, 0xf5, 0xe8,
+	0x60, 0x77, 0xa7, 0x5b, 0x45, 0xda, 0xc7, 0x50, 0xe6, 0x07, 0x89, 0xac, 0x6f, 0xc0, 0xbc, 0x47,
+	0xfc, 0xb1, 0x4d, 0xa5, 0x3f, 0xcb, 0x29, 0x7f, 0xb8, 0x9c, 0x2e, 0xa5, 0xb4, 0x53, 0xc0, 0x5d,
+	0xea, 0x11, 0xd3, 0x49, 0xa8, 0xd9, 0x84, 0x4a, 0xff, 0x78, 0xec, 0x3e, 0x23, 0x03, 0x99, 0x4a,
+	0xae, 0xed, 0x9a, 0xd4, 0xc6, 0xf7, 0x6c, 0x71, 0x19, 0x9e, 0x0c, 0x7d, 0xb1, 0x1f, 0x5f, 0x06,
+	0xa8, 0x0f, 0xa2, 0x76, 0x6a, 0x58, 0xee, 0x80, 0x9c, 0xb0, 0x54, 0x14, 0x74, 0x60, 0xa4, 0xed,
+	0x80, 0xa2, 0xfd, 0x15, 0x41, 0x2d, 0x43, 0x0f, 0x3e, 0x84, 0x39, 0x96, 0xfc, 0xf4, 0x0d, 0x1e,
+	0xf5, 0x38, 0x56, 0x9e, 0x98, 0x96, 0xb7, 0xf9, 0xe1, 0x17, 0x2f, 0xd6, 0x72, 0xff, 0x7e, 0xb1,
+	0x76, 0xfb, 0x32, 0xe5, 0x88, 0xef, 0x6b, 0x0d, 0xcc, 0x11, 0x25, 0x9e, 0x2e, 0xb4, 0xe3, 0xdb,
+	0x30, 0xc7, 0x2c, 0x96, 0x38, 0xad, 0x65, 0x38, 0xb7, 0x39, 0x13, 0x9c, 0xa3, 0x0b, 0x41, 0xed,
+	0xef, 0x08, 0x4a, 0x31, 0x2e, 0x56, 0xa1, 0xe4, 0x58, 0xae, 0x41, 0x2d, 0x87, 0x18, 0xec, 0xaa,
+	0x05, 0x3e, 0x16, 0x1d, 0xcb, 0xdd, 0xb7, 0x1c, 0xd2, 0xf1, 0x19, 0xdf, 0x3c, 0x09, 0xf9, 0x79,
+	0xc1, 0x37, 0x4f, 0x04, 0xff, 0x16, 0xcc, 0x04, 0xe0, 0xa9, 0x17, 0xd6, 0x51, 0xa3, 0x72, 0xe7,
+	0x7a, 0x86, 0x01, 0xcd, 0xb6, 0xdb, 0x1f, 0x0e, 0x2c, 0xf7, 0x48, 0x67, 0x92, 0x18, 0xc3, 0xcc,
+	0xc0, 0xa4, 0x66, 0x7d, 0x66, 0x1d, 0x35, 0xca, 0x3a, 0xfb, 0xaf, 0xad, 0xc3, 0x82, 0x94, 0x0a,
+	0x60, 0x73, 0xb0, 0xbb, 0xb3, 0xbb, 0xf7, 0xe9, 0x6e, 0x35, 0x87, 0xe7, 0xa1, 0xf0, 0x74, 0x4f,
+	0xaf, 0x22, 0xed, 0x8f, 0x08, 0xca, 0x71, 0x40, 0xe3, 0x77, 0x01, 0xfb, 0xd4, 0xf4, 0x28, 0x33,
+	0xcd, 0xa7, 0xa6, 0x33, 0x8a, 0xec, 0xaf, 0x32, 0xce, 0xbe, 0x64, 0x74, 0x7c, 0xdc, 0x80, 0x2a,
+	0x71, 0x07, 0x49, 0x59, 0xee, 0x4b, 0x85, 0xb8, 0x83, 0xb8, 0x64, 0xbc, 0x92, 0x15, 0x2e, 0x55,
+	0xc9, 0xfe, 0x8c, 0x60, 0xa9, 0x7d, 0x42, 0x9c, 0x91, 0x6d, 0x7a, 0xdf, 0x89, 0x89, 0xb7, 0x27,
+	0x4c, 0x5c, 0xce, 0x32, 0xd1, 0x8f, 0xd9, 0xb8, 0x03, 0x8b, 0x89, 0xeb, 0x83, 0x3f, 0x02, 0x60,
+	0x27, 0x65, 0x55, 0x8e, 0x51, 0xaf, 0x19, 0x1c, 0xc7, 0xc1, 0x2c, 0xf0, 0x13, 0x93, 0xd6, 0xfe,
+	0x80, 0xa0, 0xc6, 0xb4, 0xc9, 0x7b, 0x27, 0x74, 0x7e, 0x0c, 0x25, 0x8e, 0xb2, 0xb8, 0xd2, 0x55,
+	0x69, 0x5a, 0xa4, 0x32, 0x8e, 0xcb, 0xf8, 0x8e, 0x94, 0x51, 0xf9, 0xd7, 0x32, 0xaa, 0x0b, 0xcb,
+	0xa9, 0x24, 0x7c, 0x0b, 0x9e, 0xfe, 0x13, 0x01, 0x8e, 0x77, 0x5d, 0x91, 0xd8, 0x0b, 0x5a, 0x49,
+	0x76, 0xde, 0xf3, 0xaf, 0x91, 0xf7, 0xc2, 0x85, 0x79, 0x0f, 0x6e, 0xcf, 0x25, 0xf2, 0x7e, 0x0f,
+	0x6a, 0x09, 0xfb, 0x45, 0x4c, 0xbe, 0x07, 0xe5, 0x58, 0xb3, 0x93, 0x0d, 0xbd, 0x14, 0x75, 0x2c,
+	0x5f, 0xfb, 0x13, 0x82, 0xab, 0xd1, 0x90, 0xf2, 0xdd, 0x42, 0xfa, 0x52, 0xae, 0x7d, 0x00, 0x38,
+	0x6e, 0x9f, 0xf0, 0xec, 0xa2, 0x49, 0x45, 0xc3, 0x50, 0x3d, 0xf0, 0x89, 0xd7, 0xa5, 0x26, 0x95,
+	0x5e, 0x69, 0xff, 0x40, 0x70, 0x35, 0x46, 0x14, 0xaa, 0x6e, 0xca, 0x81, 0xd3, 0x1a, 0xba, 0x86,
+	0x67, 0x52, 0x9e, 0x69, 0xa4, 0x2f, 0x86, 0x54, 0xdd, 0xa4, 0x24, 0x00, 0x83, 0x3b, 0x76, 0xa2,
+	0x81, 0x21, 0xe8, 0xd7, 0x45, 0x77, 0xec, 0x88, 0x5e, 0xf0, 0x2e, 0x60, 0x73, 0x64, 0x19, 0x29,
+	0x4d, 0x05, 0xa6, 0xa9, 0x6a, 0x8e, 0xac, 0xed, 0x84, 0xb2, 0x26, 0xd4, 0xbc, 0xb1, 0x4d, 0xd2,
+	0xe2, 0x33, 0x4c, 0xfc, 0x6a, 0xc0, 0x4a, 0xc8, 0x6b, 0xbf, 0x84, 0x5a, 0x60, 0xf8, 0xf6, 0xfd,
+	0xa4, 0xe9, 0xab, 0x30, 0x3f, 0xf6, 0x89, 0x67, 0x58, 0x03, 0x81, 0xce, 0xb9, 0x60, 0xb9, 0x3d,
+	0xc0, 0xef, 0x89, 0xe2, 0x9b, 0x67, 0x31, 0x7e, 0x4b, 0xc6, 0x78, 0xc2, 0x79, 0x51, 0x97, 0x1f,
+	0x02, 0x0e, 0x58, 0x7e, 0x52, 0xfb, 0x6d, 0x98, 0xf5, 0x03, 0x42, 0xba, 0xa5, 0x66, 0x58, 0xa2,
+	0x73, 0x49, 0xed, 0x6f, 0x08, 0xd4, 0x0e, 0xa1, 0x9e, 0xd5, 0xf7, 0x1f, 0x0c, 0xbd, 0x64, 0x4a,
+	0xdf, 0x30, 0xb4, 0xee, 0x41, 0x59, 0x62, 0xc6, 0xf0, 0x09, 0x3d, 0xbf, 0x62, 0x96, 0xa4, 0x68,
+	0x97, 0x50, 0x6d, 0x07, 0xd6, 0xa6, 0xda, 0x2c, 0x42, 0xd1, 0x80, 0x39, 0x87, 0x89, 0x88, 0x58,
+	0x54, 0xa3, 0xc2, 0xc2, 0xb7, 0xea, 0x82, 0xaf, 0xd5, 0x61, 0x45, 0x28, 0xeb, 0x10, 0x6a, 0x06,
+	0xd1, 0x95, 0xe8, 0xdb, 0x83, 0xd5, 0x09, 0x8e, 0x50, 0xff, 0x3e, 0x2c, 0x38, 0x82, 0x26, 0x0e,
+	0xa8, 0xa7, 0x0f, 0x08, 0xf7, 0x84, 0x92, 0xda, 0xff, 0x10, 0x5c, 0x49, 0x55, 0xdb, 0x20, 0x5e,
+	0x87, 0xde, 0xd0, 0x31, 0xe4, 0x13, 0x2a, 0x82, 0x46, 0x25, 0xa0, 0x6f, 0x0b, 0xf2, 0xf6, 0x20,
+	0x8e, 0x9d, 0x7c, 0x02, 0x3b, 0xd1, 0x54, 0x53, 0x78, 0xa3, 0x53, 0xcd, 0x3b, 0xe1, 0x54, 0x33,
+	0xc3, 0xce, 0x59, 0x94, 0xa9, 0xca, 0x9a, 0x67, 0x7e, 0x87, 0x60, 0x96, 0x7b, 0xf8, 0xa6, 0xf0,
+	0xa3, 0xc0, 0x02, 0x11, 0xb3, 0x09, 0xbb, 0xb6, 0xb3, 0x7a, 0xb8, 0xce, 0x9c, 0x65, 0x5a, 0xb0,
+	0x98, 0xc0, 0xca, 0x37, 0x78, 0x1f, 0x1a, 0x50, 0x8e, 0x73, 0xf0, 0x4d, 0x31, 0x64, 0x21, 0x36,
+	0x64, 0x5d, 0x95, 0xbb, 0x19, 0x9b, 0x4d, 0xe4, 0xe1, 0x64, 0xc5, 0x1a, 0x12, 0x4f, 0x1b, 0xfb,
+	0x1f, 0x3d, 0x24, 0x0a, 0x8c, 0xc8, 0x17, 0xda, 0x6f, 0x10, 0x54, 0x22, 0x84, 0x3c, 0xb0, 0x6c,
+	0xf2, 0x6d, 0x00, 0x44, 0x81, 0x85, 0x43, 0xcb, 0x26, 0xcc, 0x06, 0x7e, 0x5c, 0xb8, 0xce, 0x8c,
+	0x54, 0x1d, 0x56, 0xf6, 0x3d, 0xd3, 0xf5, 0x0f, 0x89, 0xc7, 0x52, 0x18, 0x5e, 0x2b, 0xed, 0x1d,
+	0xa8, 0xb5, 0xfa, 0xd4, 0xfa, 0x9c, 0x3c, 0xf4, 0x86, 0xbf, 0xa2, 0xc7, 0xb2, 0x44, 0x2c, 0xc1,
+	0xac, 0x6d, 0x39, 0x16, 0x65, 0x86, 0xcd, 0xea, 0x7c, 0xa1, 0xfd, 0x16, 0xc1, 0x52, 0x52, 0x5a,
+	0xdc, 0x9e, 0x1f, 0x43, 0x99, 0x5f, 0xbe, 0x58, 0x33, 0x88, 0x05, 0x9f, 0x3b, 0x2f, 0xf6, 0x94,
+	0xb8, 0x24, 0x7f, 0xcd, 0x7e, 0x20, 0x9b, 0xc8, 0xc8, 0xb4, 0x26, 0x1f, 0xb4, 0x89, 0x7d, 0x60,
+	0x4b, 0xe8, 0xfb, 0xda, 0x18, 0xca, 0x71, 0xde, 0x45, 0x63, 0x42, 0xd8, 0xaa, 0xa2, 0x97, 0x9e,
+	0x6c, 0x55, 0xac, 0x07, 0x07, 0x0d, 0xa8, 0xef, 0x11, 0x93, 0x46, 0x6f, 0x98, 0x02, 0xeb, 0x2e,
+	0x8b, 0x82, 0xca, 0x0f, 0xd3, 0x1e, 0xca, 0x60, 0xf1, 0xf5, 0x37, 0xff, 0x2c, 0xf1, 0x53, 0x58,
+	0x4a, 0x2a, 0x7a, 0xdd, 0x22, 0xf7, 0xa3, 0x9f, 0x41, 0x31, 0x04, 0x25, 0x2e, 0xc2, 0x6c, 0xfb,
+	0x93, 0x83, 0xd6, 0xe3, 0x6a, 0x0e, 0x2f, 0x42, 0x71, 0x77, 0x6f, 0xdf, 0xe0, 0x4b, 0x84, 0xaf,
+	0x40, 0x49, 0x6f, 0x3f, 0x6c, 0x3f, 0x35, 0x3a, 0xad, 0xfd, 0xad, 0x47, 0xd5, 0x3c, 0xc6, 0x50,
+	0xe1, 0x84, 0xdd, 0x3d, 0x41, 0x2b, 0xdc, 0xf9, 0xff, 0x02, 0x2c, 0x48, 0xd4, 0xe1, 0x0f, 0x61,
+	0xe6, 0xc9, 0xd8, 0x3f, 0xc6, 0x2b, 0xd1, 0xd1, 0x9f, 0x7a, 0x16, 0x25, 0xc2, 0x59, 0x65, 0x75,
+	0x82, 0x2e, 0x90, 0x94, 0xc3, 0xf7, 0xa1, 0x14, 0x1b, 0x56, 0x71, 0xe6, 0xf3, 0x58, 0xb9, 0x96,
+	0xa0, 0x26, 0xe7, 0x5a, 0x2d, 0x77, 0x0b, 0xe1, 0x3d, 0xa8, 0x30, 0x96, 0x9c, 0x31, 0x7d, 0x1c,
+	0xbe, 0x75, 0xb2, 0x66, 0x7f, 0xe5, 0xc6, 0x14, 0x6e, 0x68, 0xd6, 0xa3, 0xe4, 0x97, 0x1b, 0x25,
+	0xeb, 0x23, 0x4f, 0xda, 0xb8, 0x8c, 0x51, 0x4e, 0xcb, 0xe1, 0x36, 0x40, 0x34, 0x08, 0xe1, 0xb7,
+	0x12, 0xc2, 0xf1, 0xe1, 0x4d, 0x51, 0xb2, 0x58, 0xa1, 0x9a, 0x4d, 0x28, 0x86, 0x63, 0x00, 0xae,
+	0x67, 0x4c, 0x06, 0x5c, 0xc9, 0xf4, 0x99, 0x41, 0xcb, 0xe1, 0x07, 0x50, 0x6e, 0xd9, 0xf6, 0x65,
+	0xd4, 0x28, 0x71, 0x8e, 0x9f, 0xd6, 0x63, 0xc3, 0xea, 0x94, 0xce, 0x8b, 0xdf, 0x0e, 0xab, 0xdf,
+	0xb9, 0xe3, 0x84, 0xf2, 0x83, 0x0b, 0xe5, 0xc2, 0xd3, 0xf6, 0xe1, 0x4a, 0xaa, 0x01, 0x63, 0x35,
+	0xb5, 0x3b, 0xd5, 0xb3, 0x95, 0xb5, 0xa9, 0xfc, 0x50, 0x6b, 0x0f, 0x6a, 0x51, 0x9c, 0xc3, 0x8f,
+	0x7c, 0x58, 0x9b, 0x4c, 0x42, 0xfa, 0x8b, 0xa2, 0xf2, 0xfd, 0x73, 0x65, 0x62, 0xa8, 0x7c, 0x06,
+	0x2b, 0xd9, 0x1f, 0xd1, 0xf0, 0xcd, 0x0c, 0xcc, 0x4c, 0x7e, 0xd8, 0x53, 0xde, 0xbe, 0x48, 0x2c,
+	0x76, 0x58, 0x07, 0x2a, 0xc9, 0x72, 0x8d, 0xa7, 0xbd, 0xed, 0x94, 0x30, 0x7c, 0x53, 0xea, 0x7b,
+	0xae, 0x81, 0xf0, 0x0e, 0x94, 0xe3, 0x55, 0x1b, 0x87, 0x28, 0xcf, 0xa8, 0xfc, 0xca, 0xf5, 0x6c,
+	0x66, 0x18, 0xec, 0x8e, 0x54, 0x26, 0xa6, 0xee, 0x94, 0xb2, 0x44, 0x65, 0x54, 0xae, 0x67, 0x33,
+	0x23, 0x57, 0x37, 0x7f, 0xf2, 0xfc, 0xa5, 0x9a, 0xfb, 0xf2, 0xa5, 0x9a, 0xfb, 0xea, 0xa5, 0x8a,
+	0x7e, 0x7d, 0xa6, 0xa2, 0xbf, 0x9c, 0xa9, 0xe8, 0x8b, 0x33, 0x15, 0x3d, 0x3f, 0x53, 0xd1, 0x7f,
+	0xce, 0x54, 0xf4, 0xdf, 0x33, 0x35, 0xf7, 0xd5, 0x99, 0x8a, 0x7e, 0xff, 0x4a, 0xcd, 0x3d, 0x7f,
+	0xa5, 0xe6, 0xbe, 0x7c, 0xa5, 0xe6, 0x7e, 0x31, 0xd7, 0xb7, 0x2d, 0xe2, 0xd2, 0xde, 0x1c, 0xfb,
+	0x6a, 0x7c, 0xf7, 0xeb, 0x01, 0x00, 0xbb, 0xdf, 0xf3, 0xc8, 0xb0, 0x16, 0x00, 0x00,
 }
 
 func (x MatchType) String() string {
@@ -2988,69 +3281,239 @@ func (this *TimeSeriesFile) Equal(that interface{}) bool {
 	}
 	return true
 }
-func (this *LabelNamesAndValuesRequest) GoString() string {
-	if this == nil {
-		return "nil"
-	}
-	s := make([]string, 0, 5)
-	s = append(s, "&client.LabelNamesAndValuesRequest{")
-	if this.Matchers != nil {
-		s = append(s, "Matchers: "+fmt.Sprintf("%#v", this.Matchers)+",\n")
-	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *LabelNamesAndValuesResponse) GoString() string {
-	if this == nil {
-		return "nil"
+func (this *TransferChunksResponse) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
 	}
-	s := make([]string, 0, 5)
-	s = append(s, "&client.LabelNamesAndValuesResponse{")
-	if this.Items != nil {
-		s = append(s, "Items: "+fmt.Sprintf("%#v", this.Items)+",\n")
+
+	that1, ok := that.(*TransferChunksResponse)
+	if !ok {
+		that2, ok := that.(TransferChunksResponse)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *LabelValues) GoString() string {
-	if this == nil {
-		return "nil"
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
 	}
-	s := make([]string, 0, 6)
-	s = append(s, "&client.LabelValues{")
-	s = append(s, "LabelName: "+fmt.Sprintf("%#v", this.LabelName)+",\n")
-	s = append(s, "Values: "+fmt.Sprintf("%#v", this.Values)+",\n")
-	s = append(s, "}")
-	return strings.Join(s, "")
+	return true
 }
-func (this *LabelValuesCardinalityRequest) GoString() string {
-	if this == nil {
-		return "nil"
+func (this *ActiveGrowthRequest) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
 	}
-	s := make([]string, 0, 6)
-	s = append(s, "&client.LabelValuesCardinalityRequest{")
-	s = append(s, "LabelNames: "+fmt.Sprintf("%#v", this.LabelNames)+",\n")
-	if this.Matchers != nil {
-		s = append(s, "Matchers: "+fmt.Sprintf("%#v", this.Matchers)+",\n")
+
+	that1, ok := that.(*ActiveGrowthRequest)
+	if !ok {
+		that2, ok := that.(ActiveGrowthRequest)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *LabelValuesCardinalityResponse) GoString() string {
-	if this == nil {
-		return "nil"
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
 	}
-	s := make([]string, 0, 5)
-	s = append(s, "&client.LabelValuesCardinalityResponse{")
-	if this.Items != nil {
-		s = append(s, "Items: "+fmt.Sprintf("%#v", this.Items)+",\n")
+	if this.Limit != that1.Limit {
+		return false
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
+	return true
 }
-func (this *LabelValueSeriesCount) GoString() string {
-	if this == nil {
-		return "nil"
+func (this *ActiveGrowthResponse) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*ActiveGrowthResponse)
+	if !ok {
+		that2, ok := that.(ActiveGrowthResponse)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.MetricNames) != len(that1.MetricNames) {
+		return false
+	}
+	for i := range this.MetricNames {
+		if !this.MetricNames[i].Equal(that1.MetricNames[i]) {
+			return false
+		}
+	}
+	if len(this.LabelPairs) != len(that1.LabelPairs) {
+		return false
+	}
+	for i := range this.LabelPairs {
+		if !this.LabelPairs[i].Equal(that1.LabelPairs[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *SeriesGrowth) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*SeriesGrowth)
+	if !ok {
+		that2, ok := that.(SeriesGrowth)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if this.LabelName != that1.LabelName {
+		return false
+	}
+	if this.LabelValue != that1.LabelValue {
+		return false
+	}
+	if this.CreatedSeries != that1.CreatedSeries {
+		return false
+	}
+	return true
+}
+func (this *ActiveSeriesRequest) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*ActiveSeriesRequest)
+	if !ok {
+		that2, ok := that.(ActiveSeriesRequest)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Matchers) != len(that1.Matchers) {
+		return false
+	}
+	for i := range this.Matchers {
+		if !this.Matchers[i].Equal(that1.Matchers[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *ActiveSeriesResponse) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*ActiveSeriesResponse)
+	if !ok {
+		that2, ok := that.(ActiveSeriesResponse)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Metric) != len(that1.Metric) {
+		return false
+	}
+	for i := range this.Metric {
+		if !this.Metric[i].Equal(that1.Metric[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *LabelNamesAndValuesRequest) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&client.LabelNamesAndValuesRequest{")
+	if this.Matchers != nil {
+		s = append(s, "Matchers: "+fmt.Sprintf("%#v", this.Matchers)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *LabelNamesAndValuesResponse) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&client.LabelNamesAndValuesResponse{")
+	if this.Items != nil {
+		s = append(s, "Items: "+fmt.Sprintf("%#v", this.Items)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *LabelValues) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&client.LabelValues{")
+	s = append(s, "LabelName: "+fmt.Sprintf("%#v", this.LabelName)+",\n")
+	s = append(s, "Values: "+fmt.Sprintf("%#v", this.Values)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *LabelValuesCardinalityRequest) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&client.LabelValuesCardinalityRequest{")
+	s = append(s, "LabelNames: "+fmt.Sprintf("%#v", this.LabelNames)+",\n")
+	if this.Matchers != nil {
+		s = append(s, "Matchers: "+fmt.Sprintf("%#v", this.Matchers)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *LabelValuesCardinalityResponse) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&client.LabelValuesCardinalityResponse{")
+	if this.Items != nil {
+		s = append(s, "Items: "+fmt.Sprintf("%#v", this.Items)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *LabelValueSeriesCount) GoString() string {
+	if this == nil {
+		return "nil"
 	}
 	s := make([]string, 0, 6)
 	s = append(s, "&client.LabelValueSeriesCount{")
@@ -3434,6 +3897,76 @@ func (this *TimeSeriesFile) GoString() string {
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
+func (this *TransferChunksResponse) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 4)
+	s = append(s, "&client.TransferChunksResponse{")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *ActiveGrowthRequest) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&client.ActiveGrowthRequest{")
+	s = append(s, "Limit: "+fmt.Sprintf("%#v", this.Limit)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *ActiveGrowthResponse) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&client.ActiveGrowthResponse{")
+	if this.MetricNames != nil {
+		s = append(s, "MetricNames: "+fmt.Sprintf("%#v", this.MetricNames)+",\n")
+	}
+	if this.LabelPairs != nil {
+		s = append(s, "LabelPairs: "+fmt.Sprintf("%#v", this.LabelPairs)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *SeriesGrowth) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&client.SeriesGrowth{")
+	s = append(s, "LabelName: "+fmt.Sprintf("%#v", this.LabelName)+",\n")
+	s = append(s, "LabelValue: "+fmt.Sprintf("%#v", this.LabelValue)+",\n")
+	s = append(s, "CreatedSeries: "+fmt.Sprintf("%#v", this.CreatedSeries)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *ActiveSeriesRequest) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&client.ActiveSeriesRequest{")
+	if this.Matchers != nil {
+		s = append(s, "Matchers: "+fmt.Sprintf("%#v", this.Matchers)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *ActiveSeriesResponse) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&client.ActiveSeriesResponse{")
+	if this.Metric != nil {
+		s = append(s, "Metric: "+fmt.Sprintf("%#v", this.Metric)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
 func valueToGoStringIngester(v interface{}, typ string) string {
 	rv := reflect.ValueOf(v)
 	if rv.IsNil() {
@@ -3471,6 +4004,16 @@ type IngesterClient interface {
 	// that match the matchers.
 	// The listing order of the labels is not guaranteed.
 	LabelValuesCardinality(ctx context.Context, in *LabelValuesCardinalityRequest, opts ...grpc.CallOption) (Ingester_LabelValuesCardinalityClient, error)
+	// TransferChunks receives the in-memory series of a leaving ingester, which is handing off its data
+	// to this ingester on shutdown. Once all the series are received, this ingester claims the tokens
+	// of the leaving ingester.
+	TransferChunks(ctx context.Context, opts ...grpc.CallOption) (Ingester_TransferChunksClient, error)
+	// ActiveGrowth returns the metric names and label pairs for which the most series have been created
+	// recently, to find the cardinality explosions as they happen.
+	ActiveGrowth(ctx context.Context, in *ActiveGrowthRequest, opts ...grpc.CallOption) (*ActiveGrowthResponse, error)
+	// ActiveSeries streams the labels of the series which received samples within the active series idle timeout,
+	// and match the matchers.
+	ActiveSeries(ctx context.Context, in *ActiveSeriesRequest, opts ...grpc.CallOption) (Ingester_ActiveSeriesClient, error)
 }
 
 type ingesterClient struct {
@@ -3649,44 +4192,129 @@ func (x *ingesterLabelValuesCardinalityClient) Recv() (*LabelValuesCardinalityRe
 	return m, nil
 }
 
-// IngesterServer is the server API for Ingester service.
-type IngesterServer interface {
-	Push(context.Context, *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error)
-	QueryStream(*QueryRequest, Ingester_QueryStreamServer) error
-	QueryExemplars(context.Context, *ExemplarQueryRequest) (*ExemplarQueryResponse, error)
-	LabelValues(context.Context, *LabelValuesRequest) (*LabelValuesResponse, error)
-	LabelNames(context.Context, *LabelNamesRequest) (*LabelNamesResponse, error)
-	UserStats(context.Context, *UserStatsRequest) (*UserStatsResponse, error)
-	AllUserStats(context.Context, *UserStatsRequest) (*UsersStatsResponse, error)
-	MetricsForLabelMatchers(context.Context, *MetricsForLabelMatchersRequest) (*MetricsForLabelMatchersResponse, error)
-	MetricsMetadata(context.Context, *MetricsMetadataRequest) (*MetricsMetadataResponse, error)
-	// LabelNamesAndValues provides all values for each label that matches the matchers.
-	// The order of the labels and values is not guaranteed.
-	LabelNamesAndValues(*LabelNamesAndValuesRequest, Ingester_LabelNamesAndValuesServer) error
-	// LabelValuesCardinality returns all values and series total count for label_names labels
-	// that match the matchers.
-	// The listing order of the labels is not guaranteed.
-	LabelValuesCardinality(*LabelValuesCardinalityRequest, Ingester_LabelValuesCardinalityServer) error
+func (c *ingesterClient) TransferChunks(ctx context.Context, opts ...grpc.CallOption) (Ingester_TransferChunksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Ingester_serviceDesc.Streams[3], "/cortex.Ingester/TransferChunks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ingesterTransferChunksClient{stream}
+	return x, nil
 }
 
-// UnimplementedIngesterServer can be embedded to have forward compatible implementations.
-type UnimplementedIngesterServer struct {
+type Ingester_TransferChunksClient interface {
+	Send(*TimeSeriesChunk) error
+	CloseAndRecv() (*TransferChunksResponse, error)
+	grpc.ClientStream
 }
 
-func (*UnimplementedIngesterServer) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Push not implemented")
-}
-func (*UnimplementedIngesterServer) QueryStream(req *QueryRequest, srv Ingester_QueryStreamServer) error {
-	return status.Errorf(codes.Unimplemented, "method QueryStream not implemented")
-}
-func (*UnimplementedIngesterServer) QueryExemplars(ctx context.Context, req *ExemplarQueryRequest) (*ExemplarQueryResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method QueryExemplars not implemented")
+type ingesterTransferChunksClient struct {
+	grpc.ClientStream
 }
-func (*UnimplementedIngesterServer) LabelValues(ctx context.Context, req *LabelValuesRequest) (*LabelValuesResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method LabelValues not implemented")
+
+func (x *ingesterTransferChunksClient) Send(m *TimeSeriesChunk) error {
+	return x.ClientStream.SendMsg(m)
 }
-func (*UnimplementedIngesterServer) LabelNames(ctx context.Context, req *LabelNamesRequest) (*LabelNamesResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method LabelNames not implemented")
+
+func (x *ingesterTransferChunksClient) CloseAndRecv() (*TransferChunksResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(TransferChunksResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *ingesterClient) ActiveGrowth(ctx context.Context, in *ActiveGrowthRequest, opts ...grpc.CallOption) (*ActiveGrowthResponse, error) {
+	out := new(ActiveGrowthResponse)
+	err := c.cc.Invoke(ctx, "/cortex.Ingester/ActiveGrowth", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingesterClient) ActiveSeries(ctx context.Context, in *ActiveSeriesRequest, opts ...grpc.CallOption) (Ingester_ActiveSeriesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Ingester_serviceDesc.Streams[4], "/cortex.Ingester/ActiveSeries", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ingesterActiveSeriesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Ingester_ActiveSeriesClient interface {
+	Recv() (*ActiveSeriesResponse, error)
+	grpc.ClientStream
+}
+
+type ingesterActiveSeriesClient struct {
+	grpc.ClientStream
+}
+
+func (x *ingesterActiveSeriesClient) Recv() (*ActiveSeriesResponse, error) {
+	m := new(ActiveSeriesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// IngesterServer is the server API for Ingester service.
+type IngesterServer interface {
+	Push(context.Context, *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error)
+	QueryStream(*QueryRequest, Ingester_QueryStreamServer) error
+	QueryExemplars(context.Context, *ExemplarQueryRequest) (*ExemplarQueryResponse, error)
+	LabelValues(context.Context, *LabelValuesRequest) (*LabelValuesResponse, error)
+	LabelNames(context.Context, *LabelNamesRequest) (*LabelNamesResponse, error)
+	UserStats(context.Context, *UserStatsRequest) (*UserStatsResponse, error)
+	AllUserStats(context.Context, *UserStatsRequest) (*UsersStatsResponse, error)
+	MetricsForLabelMatchers(context.Context, *MetricsForLabelMatchersRequest) (*MetricsForLabelMatchersResponse, error)
+	MetricsMetadata(context.Context, *MetricsMetadataRequest) (*MetricsMetadataResponse, error)
+	// LabelNamesAndValues provides all values for each label that matches the matchers.
+	// The order of the labels and values is not guaranteed.
+	LabelNamesAndValues(*LabelNamesAndValuesRequest, Ingester_LabelNamesAndValuesServer) error
+	// LabelValuesCardinality returns all values and series total count for label_names labels
+	// that match the matchers.
+	// The listing order of the labels is not guaranteed.
+	LabelValuesCardinality(*LabelValuesCardinalityRequest, Ingester_LabelValuesCardinalityServer) error
+	// TransferChunks receives the in-memory series of a leaving ingester, which is handing off its data
+	// to this ingester on shutdown. Once all the series are received, this ingester claims the tokens
+	// of the leaving ingester.
+	TransferChunks(Ingester_TransferChunksServer) error
+	// ActiveGrowth returns the metric names and label pairs for which the most series have been created
+	// recently, to find the cardinality explosions as they happen.
+	ActiveGrowth(context.Context, *ActiveGrowthRequest) (*ActiveGrowthResponse, error)
+	// ActiveSeries streams the labels of the series which received samples within the active series idle timeout,
+	// and match the matchers.
+	ActiveSeries(*ActiveSeriesRequest, Ingester_ActiveSeriesServer) error
+}
+
+// UnimplementedIngesterServer can be embedded to have forward compatible implementations.
+type UnimplementedIngesterServer struct {
+}
+
+func (*UnimplementedIngesterServer) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Push not implemented")
+}
+func (*UnimplementedIngesterServer) QueryStream(req *QueryRequest, srv Ingester_QueryStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method QueryStream not implemented")
+}
+func (*UnimplementedIngesterServer) QueryExemplars(ctx context.Context, req *ExemplarQueryRequest) (*ExemplarQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryExemplars not implemented")
+}
+func (*UnimplementedIngesterServer) LabelValues(ctx context.Context, req *LabelValuesRequest) (*LabelValuesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LabelValues not implemented")
+}
+func (*UnimplementedIngesterServer) LabelNames(ctx context.Context, req *LabelNamesRequest) (*LabelNamesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LabelNames not implemented")
 }
 func (*UnimplementedIngesterServer) UserStats(ctx context.Context, req *UserStatsRequest) (*UserStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UserStats not implemented")
@@ -3706,6 +4334,15 @@ func (*UnimplementedIngesterServer) LabelNamesAndValues(req *LabelNamesAndValues
 func (*UnimplementedIngesterServer) LabelValuesCardinality(req *LabelValuesCardinalityRequest, srv Ingester_LabelValuesCardinalityServer) error {
 	return status.Errorf(codes.Unimplemented, "method LabelValuesCardinality not implemented")
 }
+func (*UnimplementedIngesterServer) TransferChunks(srv Ingester_TransferChunksServer) error {
+	return status.Errorf(codes.Unimplemented, "method TransferChunks not implemented")
+}
+func (*UnimplementedIngesterServer) ActiveGrowth(ctx context.Context, req *ActiveGrowthRequest) (*ActiveGrowthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ActiveGrowth not implemented")
+}
+func (*UnimplementedIngesterServer) ActiveSeries(req *ActiveSeriesRequest, srv Ingester_ActiveSeriesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ActiveSeries not implemented")
+}
 
 func RegisterIngesterServer(s *grpc.Server, srv IngesterServer) {
 	s.RegisterService(&_Ingester_serviceDesc, srv)
@@ -3918,6 +4555,71 @@ func (x *ingesterLabelValuesCardinalityServer) Send(m *LabelValuesCardinalityRes
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Ingester_TransferChunks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IngesterServer).TransferChunks(&ingesterTransferChunksServer{stream})
+}
+
+type Ingester_TransferChunksServer interface {
+	SendAndClose(*TransferChunksResponse) error
+	Recv() (*TimeSeriesChunk, error)
+	grpc.ServerStream
+}
+
+type ingesterTransferChunksServer struct {
+	grpc.ServerStream
+}
+
+func (x *ingesterTransferChunksServer) SendAndClose(m *TransferChunksResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *ingesterTransferChunksServer) Recv() (*TimeSeriesChunk, error) {
+	m := new(TimeSeriesChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Ingester_ActiveGrowth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ActiveGrowthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngesterServer).ActiveGrowth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cortex.Ingester/ActiveGrowth",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngesterServer).ActiveGrowth(ctx, req.(*ActiveGrowthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ingester_ActiveSeries_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ActiveSeriesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IngesterServer).ActiveSeries(m, &ingesterActiveSeriesServer{stream})
+}
+
+type Ingester_ActiveSeriesServer interface {
+	Send(*ActiveSeriesResponse) error
+	grpc.ServerStream
+}
+
+type ingesterActiveSeriesServer struct {
+	grpc.ServerStream
+}
+
+func (x *ingesterActiveSeriesServer) Send(m *ActiveSeriesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _Ingester_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "cortex.Ingester",
 	HandlerType: (*IngesterServer)(nil),
@@ -3954,6 +4656,10 @@ var _Ingester_serviceDesc = grpc.ServiceDesc{
 			MethodName: "MetricsMetadata",
 			Handler:    _Ingester_MetricsMetadata_Handler,
 		},
+		{
+			MethodName: "ActiveGrowth",
+			Handler:    _Ingester_ActiveGrowth_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -3971,6 +4677,16 @@ var _Ingester_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Ingester_LabelValuesCardinality_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "TransferChunks",
+			Handler:       _Ingester_TransferChunks_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ActiveSeries",
+			Handler:       _Ingester_ActiveSeries_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "ingester.proto",
 }
@@ -5360,92 +6076,310 @@ func (m *TimeSeriesFile) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func encodeVarintIngester(dAtA []byte, offset int, v uint64) int {
-	offset -= sovIngester(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *TransferChunksResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return base
+	return dAtA[:n], nil
 }
-func (m *LabelNamesAndValuesRequest) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if len(m.Matchers) > 0 {
-		for _, e := range m.Matchers {
-			l = e.Size()
-			n += 1 + l + sovIngester(uint64(l))
-		}
-	}
-	return n
+
+func (m *TransferChunksResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *LabelNamesAndValuesResponse) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+func (m *TransferChunksResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.Items) > 0 {
-		for _, e := range m.Items {
-			l = e.Size()
-			n += 1 + l + sovIngester(uint64(l))
-		}
-	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *LabelValues) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *ActiveGrowthRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *ActiveGrowthRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ActiveGrowthRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.LabelName)
-	if l > 0 {
-		n += 1 + l + sovIngester(uint64(l))
-	}
-	if len(m.Values) > 0 {
-		for _, s := range m.Values {
-			l = len(s)
-			n += 1 + l + sovIngester(uint64(l))
-		}
+	if m.Limit != 0 {
+		i = encodeVarintIngester(dAtA, i, uint64(m.Limit))
+		i--
+		dAtA[i] = 0x8
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *LabelValuesCardinalityRequest) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *ActiveGrowthResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *ActiveGrowthResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ActiveGrowthResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.LabelNames) > 0 {
-		for _, s := range m.LabelNames {
-			l = len(s)
-			n += 1 + l + sovIngester(uint64(l))
+	if len(m.LabelPairs) > 0 {
+		for iNdEx := len(m.LabelPairs) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.LabelPairs[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintIngester(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
 		}
 	}
-	if len(m.Matchers) > 0 {
-		for _, e := range m.Matchers {
-			l = e.Size()
-			n += 1 + l + sovIngester(uint64(l))
+	if len(m.MetricNames) > 0 {
+		for iNdEx := len(m.MetricNames) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.MetricNames[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintIngester(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
 		}
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *LabelValuesCardinalityResponse) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
+func (m *SeriesGrowth) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SeriesGrowth) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeriesGrowth) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.CreatedSeries != 0 {
+		i = encodeVarintIngester(dAtA, i, uint64(m.CreatedSeries))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.LabelValue) > 0 {
+		i -= len(m.LabelValue)
+		copy(dAtA[i:], m.LabelValue)
+		i = encodeVarintIngester(dAtA, i, uint64(len(m.LabelValue)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.LabelName) > 0 {
+		i -= len(m.LabelName)
+		copy(dAtA[i:], m.LabelName)
+		i = encodeVarintIngester(dAtA, i, uint64(len(m.LabelName)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *ActiveSeriesRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ActiveSeriesRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ActiveSeriesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Matchers) > 0 {
+		for iNdEx := len(m.Matchers) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Matchers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintIngester(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *ActiveSeriesResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ActiveSeriesResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ActiveSeriesResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Metric) > 0 {
+		for iNdEx := len(m.Metric) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Metric[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintIngester(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintIngester(dAtA []byte, offset int, v uint64) int {
+	offset -= sovIngester(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *LabelNamesAndValuesRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Matchers) > 0 {
+		for _, e := range m.Matchers {
+			l = e.Size()
+			n += 1 + l + sovIngester(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *LabelNamesAndValuesResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Items) > 0 {
+		for _, e := range m.Items {
+			l = e.Size()
+			n += 1 + l + sovIngester(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *LabelValues) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.LabelName)
+	if l > 0 {
+		n += 1 + l + sovIngester(uint64(l))
+	}
+	if len(m.Values) > 0 {
+		for _, s := range m.Values {
+			l = len(s)
+			n += 1 + l + sovIngester(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *LabelValuesCardinalityRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.LabelNames) > 0 {
+		for _, s := range m.LabelNames {
+			l = len(s)
+			n += 1 + l + sovIngester(uint64(l))
+		}
+	}
+	if len(m.Matchers) > 0 {
+		for _, e := range m.Matchers {
+			l = e.Size()
+			n += 1 + l + sovIngester(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *LabelValuesCardinalityResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
 	_ = l
 	if len(m.Items) > 0 {
 		for _, e := range m.Items {
@@ -5973,56 +6907,148 @@ func (m *TimeSeriesFile) Size() (n int) {
 	return n
 }
 
-func sovIngester(x uint64) (n int) {
-	return (math_bits.Len64(x|1) + 6) / 7
-}
-func sozIngester(x uint64) (n int) {
-	return sovIngester(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+func (m *TransferChunksResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
 }
-func (this *LabelNamesAndValuesRequest) String() string {
-	if this == nil {
-		return "nil"
+
+func (m *ActiveGrowthRequest) Size() (n int) {
+	if m == nil {
+		return 0
 	}
-	repeatedStringForMatchers := "[]*LabelMatcher{"
-	for _, f := range this.Matchers {
-		repeatedStringForMatchers += strings.Replace(f.String(), "LabelMatcher", "LabelMatcher", 1) + ","
+	var l int
+	_ = l
+	if m.Limit != 0 {
+		n += 1 + sovIngester(uint64(m.Limit))
 	}
-	repeatedStringForMatchers += "}"
-	s := strings.Join([]string{`&LabelNamesAndValuesRequest{`,
-		`Matchers:` + repeatedStringForMatchers + `,`,
-		`}`,
-	}, "")
-	return s
+	return n
 }
-func (this *LabelNamesAndValuesResponse) String() string {
-	if this == nil {
-		return "nil"
+
+func (m *ActiveGrowthResponse) Size() (n int) {
+	if m == nil {
+		return 0
 	}
-	repeatedStringForItems := "[]*LabelValues{"
-	for _, f := range this.Items {
-		repeatedStringForItems += strings.Replace(f.String(), "LabelValues", "LabelValues", 1) + ","
+	var l int
+	_ = l
+	if len(m.MetricNames) > 0 {
+		for _, e := range m.MetricNames {
+			l = e.Size()
+			n += 1 + l + sovIngester(uint64(l))
+		}
 	}
-	repeatedStringForItems += "}"
-	s := strings.Join([]string{`&LabelNamesAndValuesResponse{`,
-		`Items:` + repeatedStringForItems + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func (this *LabelValues) String() string {
-	if this == nil {
-		return "nil"
+	if len(m.LabelPairs) > 0 {
+		for _, e := range m.LabelPairs {
+			l = e.Size()
+			n += 1 + l + sovIngester(uint64(l))
+		}
 	}
-	s := strings.Join([]string{`&LabelValues{`,
-		`LabelName:` + fmt.Sprintf("%v", this.LabelName) + `,`,
-		`Values:` + fmt.Sprintf("%v", this.Values) + `,`,
-		`}`,
-	}, "")
-	return s
+	return n
 }
-func (this *LabelValuesCardinalityRequest) String() string {
-	if this == nil {
-		return "nil"
+
+func (m *SeriesGrowth) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.LabelName)
+	if l > 0 {
+		n += 1 + l + sovIngester(uint64(l))
+	}
+	l = len(m.LabelValue)
+	if l > 0 {
+		n += 1 + l + sovIngester(uint64(l))
+	}
+	if m.CreatedSeries != 0 {
+		n += 1 + sovIngester(uint64(m.CreatedSeries))
+	}
+	return n
+}
+
+func (m *ActiveSeriesRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Matchers) > 0 {
+		for _, e := range m.Matchers {
+			l = e.Size()
+			n += 1 + l + sovIngester(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *ActiveSeriesResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Metric) > 0 {
+		for _, e := range m.Metric {
+			l = e.Size()
+			n += 1 + l + sovIngester(uint64(l))
+		}
+	}
+	return n
+}
+
+func sovIngester(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozIngester(x uint64) (n int) {
+	return sovIngester(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (this *LabelNamesAndValuesRequest) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForMatchers := "[]*LabelMatcher{"
+	for _, f := range this.Matchers {
+		repeatedStringForMatchers += strings.Replace(f.String(), "LabelMatcher", "LabelMatcher", 1) + ","
+	}
+	repeatedStringForMatchers += "}"
+	s := strings.Join([]string{`&LabelNamesAndValuesRequest{`,
+		`Matchers:` + repeatedStringForMatchers + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *LabelNamesAndValuesResponse) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForItems := "[]*LabelValues{"
+	for _, f := range this.Items {
+		repeatedStringForItems += strings.Replace(f.String(), "LabelValues", "LabelValues", 1) + ","
+	}
+	repeatedStringForItems += "}"
+	s := strings.Join([]string{`&LabelNamesAndValuesResponse{`,
+		`Items:` + repeatedStringForItems + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *LabelValues) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&LabelValues{`,
+		`LabelName:` + fmt.Sprintf("%v", this.LabelName) + `,`,
+		`Values:` + fmt.Sprintf("%v", this.Values) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *LabelValuesCardinalityRequest) String() string {
+	if this == nil {
+		return "nil"
 	}
 	repeatedStringForMatchers := "[]*LabelMatcher{"
 	for _, f := range this.Matchers {
@@ -6453,6 +7479,88 @@ func (this *TimeSeriesFile) String() string {
 	}, "")
 	return s
 }
+func (this *TransferChunksResponse) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&TransferChunksResponse{`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *ActiveGrowthRequest) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&ActiveGrowthRequest{`,
+		`Limit:` + fmt.Sprintf("%v", this.Limit) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *ActiveGrowthResponse) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForMetricNames := "[]*SeriesGrowth{"
+	for _, f := range this.MetricNames {
+		repeatedStringForMetricNames += strings.Replace(f.String(), "SeriesGrowth", "SeriesGrowth", 1) + ","
+	}
+	repeatedStringForMetricNames += "}"
+	repeatedStringForLabelPairs := "[]*SeriesGrowth{"
+	for _, f := range this.LabelPairs {
+		repeatedStringForLabelPairs += strings.Replace(f.String(), "SeriesGrowth", "SeriesGrowth", 1) + ","
+	}
+	repeatedStringForLabelPairs += "}"
+	s := strings.Join([]string{`&ActiveGrowthResponse{`,
+		`MetricNames:` + repeatedStringForMetricNames + `,`,
+		`LabelPairs:` + repeatedStringForLabelPairs + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *SeriesGrowth) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&SeriesGrowth{`,
+		`LabelName:` + fmt.Sprintf("%v", this.LabelName) + `,`,
+		`LabelValue:` + fmt.Sprintf("%v", this.LabelValue) + `,`,
+		`CreatedSeries:` + fmt.Sprintf("%v", this.CreatedSeries) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *ActiveSeriesRequest) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForMatchers := "[]*LabelMatcher{"
+	for _, f := range this.Matchers {
+		repeatedStringForMatchers += strings.Replace(f.String(), "LabelMatcher", "LabelMatcher", 1) + ","
+	}
+	repeatedStringForMatchers += "}"
+	s := strings.Join([]string{`&ActiveSeriesRequest{`,
+		`Matchers:` + repeatedStringForMatchers + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *ActiveSeriesResponse) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForMetric := "[]*Metric{"
+	for _, f := range this.Metric {
+		repeatedStringForMetric += strings.Replace(fmt.Sprintf("%v", f), "Metric", "mimirpb.Metric", 1) + ","
+	}
+	repeatedStringForMetric += "}"
+	s := strings.Join([]string{`&ActiveSeriesResponse{`,
+		`Metric:` + repeatedStringForMetric + `,`,
+		`}`,
+	}, "")
+	return s
+}
 func valueToStringIngester(v interface{}) string {
 	rv := reflect.ValueOf(v)
 	if rv.IsNil() {
@@ -10017,6 +11125,544 @@ func (m *LabelMatcher) Unmarshal(dAtA []byte) error {
 			}
 			m.Value = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIngester(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TransferChunksResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIngester
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TransferChunksResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TransferChunksResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIngester(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ActiveGrowthRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIngester
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ActiveGrowthRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ActiveGrowthRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIngester(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ActiveGrowthResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIngester
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ActiveGrowthResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ActiveGrowthResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MetricNames", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthIngester
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MetricNames = append(m.MetricNames, &SeriesGrowth{})
+			if err := m.MetricNames[len(m.MetricNames)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LabelPairs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthIngester
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LabelPairs = append(m.LabelPairs, &SeriesGrowth{})
+			if err := m.LabelPairs[len(m.LabelPairs)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIngester(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SeriesGrowth) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIngester
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SeriesGrowth: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SeriesGrowth: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LabelName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIngester
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LabelName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LabelValue", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIngester
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LabelValue = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreatedSeries", wireType)
+			}
+			m.CreatedSeries = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CreatedSeries |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIngester(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ActiveSeriesRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIngester
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ActiveSeriesRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ActiveSeriesRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Matchers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthIngester
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Matchers = append(m.Matchers, &LabelMatcher{})
+			if err := m.Matchers[len(m.Matchers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIngester(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ActiveSeriesResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIngester
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ActiveSeriesResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ActiveSeriesResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metric", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthIngester
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Metric = append(m.Metric, &mimirpb.Metric{})
+			if err := m.Metric[len(m.Metric)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipIngester(dAtA[iNdEx:])
@@ -76,3 +76,18 @@ func (m *IngesterServerMock) LabelValuesCardinality(req *LabelValuesCardinalityR
 	args := m.Called(req, srv)
 	return args.Error(0)
 }
+
+func (m *IngesterServerMock) TransferChunks(srv Ingester_TransferChunksServer) error {
+	args := m.Called(srv)
+	return args.Error(0)
+}
+
+func (m *IngesterServerMock) ActiveGrowth(ctx context.Context, r *ActiveGrowthRequest) (*ActiveGrowthResponse, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*ActiveGrowthResponse), args.Error(1)
+}
+
+func (m *IngesterServerMock) ActiveSeries(r *ActiveSeriesRequest, srv Ingester_ActiveSeriesServer) error {
+	args := m.Called(r, srv)
+	return args.Error(0)
+}
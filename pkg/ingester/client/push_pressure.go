@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// PushPressureKey is the key of the gRPC metadata in which the ingesters report their push pressure
+// in the response of the push requests.
+const PushPressureKey = "x-mimir-ingester-push-pressure"
+
+// SetPushPressure sets the push pressure of the ingester in the gRPC response headers of the request.
+// It fails if the context isn't the context of a gRPC request.
+func SetPushPressure(ctx context.Context, pressure float64) error {
+	return grpc.SetHeader(ctx, metadata.Pairs(PushPressureKey, strconv.FormatFloat(pressure, 'f', -1, 64)))
+}
+
+// PushPressureFromMetadata returns the push pressure reported by the ingester in the given gRPC metadata,
+// and whether it has been found.
+func PushPressureFromMetadata(md metadata.MD) (float64, bool) {
+	values := md.Get(PushPressureKey)
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	pressure, err := strconv.ParseFloat(values[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return pressure, true
+}
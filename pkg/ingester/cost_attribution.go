@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+const (
+	// costAttributionOverflowValue is the attribution of the series whose value of the cost attribution label
+	// isn't tracked, because the maximum number of tracked values has been reached.
+	costAttributionOverflowValue = "__overflow__"
+
+	// costAttributionMissingValue is the attribution of the series without the cost attribution label. It's
+	// always tracked, and doesn't count towards the maximum number of tracked values.
+	costAttributionMissingValue = "__missing__"
+)
+
+// costAttributionTracker attributes the series of a tenant to the values of its cost attribution label. The number
+// of tracked values is capped, and the series with other values are attributed to costAttributionOverflowValue.
+type costAttributionTracker struct {
+	mtx       sync.RWMutex
+	label     string
+	maxValues int
+	// Tracked values of the label, each one mapped to a copy retained by the tracker, because the
+	// values read from the write requests must not be retained.
+	values map[string]string
+}
+
+func newCostAttributionTracker(label string, maxValues int) *costAttributionTracker {
+	return &costAttributionTracker{
+		label:     label,
+		maxValues: maxValues,
+		values:    map[string]string{},
+	}
+}
+
+// attributionLabel returns the cost attribution label, or an empty string if the cost attribution is disabled.
+func (t *costAttributionTracker) attributionLabel() string {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	return t.label
+}
+
+// attribution returns the attribution of the series with the given value of the cost attribution label, which is
+// empty if the series doesn't have the label. The value starts being tracked if it isn't yet and the maximum number
+// of tracked values hasn't been reached.
+func (t *costAttributionTracker) attribution(value string) string {
+	if value == "" {
+		return costAttributionMissingValue
+	}
+
+	t.mtx.RLock()
+	attribution, ok := t.values[value]
+	t.mtx.RUnlock()
+	if ok {
+		return attribution
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if attribution, ok := t.values[value]; ok {
+		return attribution
+	}
+	if len(t.values) >= t.maxValues {
+		return costAttributionOverflowValue
+	}
+	attribution = strings.Clone(value)
+	t.values[attribution] = attribution
+	return attribution
+}
+
+// reload replaces the cost attribution label and the maximum number of tracked values. If any of them changed, the
+// tracked values are reset and the attributions which were exposed before are returned, to delete their metrics.
+func (t *costAttributionTracker) reload(label string, maxValues int) []string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if label == t.label && maxValues == t.maxValues {
+		return nil
+	}
+
+	stale := t.attributions()
+	t.label = label
+	t.maxValues = maxValues
+	t.values = map[string]string{}
+	return stale
+}
+
+// evict stops tracking the values which aren't in active, to make room for other values, and returns the
+// attributions which aren't active anymore.
+func (t *costAttributionTracker) evict(active map[string]int) []string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	var stale []string
+	for _, attribution := range t.attributions() {
+		if _, ok := active[attribution]; ok {
+			continue
+		}
+		delete(t.values, attribution)
+		stale = append(stale, attribution)
+	}
+	return stale
+}
+
+// attributions returns the tracked values, the overflow value and the missing value. Must be called with the lock held.
+func (t *costAttributionTracker) attributions() []string {
+	attributions := make([]string, 0, len(t.values)+2)
+	for _, attribution := range t.values {
+		attributions = append(attributions, attribution)
+	}
+	return append(attributions, costAttributionOverflowValue, costAttributionMissingValue)
+}
+
+// costAttributionValue returns the value of the label in the series, or an empty string if the series doesn't have it.
+func costAttributionValue(series []mimirpb.LabelAdapter, label string) string {
+	for _, l := range series {
+		if l.Name == label {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// reloadCostAttribution applies the cost attribution configuration of the user, deleting the metrics of the
+// previous configuration if it changed.
+func (i *Ingester) reloadCostAttribution(userDB *userTSDB) {
+	userID := userDB.userID
+	for _, attribution := range userDB.costAttribution.reload(i.limits.CostAttributionLabel(userID), i.limits.MaxCostAttributionPerUser(userID)) {
+		i.metrics.deletePerAttributionMetrics(userID, attribution)
+	}
+}
+
+// updateAttributedActiveSeries updates the number of active series of the user attributed to each value of the
+// cost attribution label, and stops tracking the values without active series.
+func (i *Ingester) updateAttributedActiveSeries(userDB *userTSDB, now time.Time) {
+	label := userDB.costAttribution.attributionLabel()
+	if label == "" {
+		return
+	}
+
+	active := map[string]int{}
+	valid, _ := userDB.activeSeries.ForEach(now, nil, func(series labels.Labels) error {
+		active[userDB.costAttribution.attribution(series.Get(label))]++
+		return nil
+	})
+	if !valid {
+		// The active series config is being reloaded, so the active series can't be attributed yet.
+		return
+	}
+
+	for attribution, count := range active {
+		i.metrics.attributedActiveSeries.WithLabelValues(userDB.userID, attribution).Set(float64(count))
+	}
+	for _, attribution := range userDB.costAttribution.evict(active) {
+		i.metrics.deletePerAttributionMetrics(userDB.userID, attribution)
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestCostAttributionTracker(t *testing.T) {
+	tracker := newCostAttributionTracker("team", 2)
+	assert.Equal(t, "team", tracker.attributionLabel())
+
+	assert.Equal(t, "a", tracker.attribution("a"))
+	// The series without the label are attributed to the missing value, which doesn't count towards the limit.
+	assert.Equal(t, costAttributionMissingValue, tracker.attribution(""))
+	assert.Equal(t, "b", tracker.attribution("b"))
+	// The values which aren't tracked yet are attributed to the overflow value once the limit is reached.
+	assert.Equal(t, costAttributionOverflowValue, tracker.attribution("c"))
+	assert.Equal(t, "a", tracker.attribution("a"))
+	assert.Equal(t, costAttributionMissingValue, tracker.attribution(""))
+
+	// The values without active series are evicted, making room for other values.
+	assert.ElementsMatch(t, []string{"b", costAttributionOverflowValue, costAttributionMissingValue}, tracker.evict(map[string]int{"a": 1}))
+	assert.Equal(t, "c", tracker.attribution("c"))
+	assert.Equal(t, costAttributionOverflowValue, tracker.attribution("d"))
+
+	// Reloading the same configuration keeps the tracked values.
+	assert.Empty(t, tracker.reload("team", 2))
+	assert.Equal(t, "c", tracker.attribution("c"))
+
+	// Reloading another configuration resets the tracked values.
+	assert.ElementsMatch(t, []string{"a", "c", costAttributionOverflowValue, costAttributionMissingValue}, tracker.reload("service", 1))
+	assert.Equal(t, "service", tracker.attributionLabel())
+	assert.Equal(t, "c", tracker.attribution("c"))
+	assert.Equal(t, costAttributionOverflowValue, tracker.attribution("a"))
+}
+
+func TestIngester_CostAttribution(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.CostAttributionLabel = "team"
+	limits.MaxCostAttributionPerUser = 2
+
+	registry := prometheus.NewRegistry()
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, defaultIngesterTestConfig(t), limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	for _, lbls := range []labels.Labels{
+		labels.FromStrings(labels.MetricName, "foo", "team", "a"),
+		labels.FromStrings(labels.MetricName, "bar", "team", "a"),
+		labels.FromStrings(labels.MetricName, "foo", "team", "b"),
+		labels.FromStrings(labels.MetricName, "foo"),
+		labels.FromStrings(labels.MetricName, "foo", "team", "c"),
+		labels.FromStrings(labels.MetricName, "foo", "team", "d"),
+	} {
+		_, err := ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{lbls}, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API))
+		require.NoError(t, err)
+	}
+	// The samples which aren't ingested aren't attributed.
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{labels.FromStrings(labels.MetricName, "foo", "team", "a")}, []mimirpb.Sample{{TimestampMs: 1, Value: 2}}, nil, nil, mimirpb.API))
+	require.Error(t, err)
+
+	ing.updateActiveSeries(time.Now())
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_ingester_attributed_active_series Number of currently active series per user and value of the cost attribution label.
+		# TYPE cortex_ingester_attributed_active_series gauge
+		cortex_ingester_attributed_active_series{attribution="__missing__",user="1"} 1
+		cortex_ingester_attributed_active_series{attribution="__overflow__",user="1"} 2
+		cortex_ingester_attributed_active_series{attribution="a",user="1"} 2
+		cortex_ingester_attributed_active_series{attribution="b",user="1"} 1
+		# HELP cortex_ingester_attributed_samples_total The total number of samples ingested per user and value of the cost attribution label.
+		# TYPE cortex_ingester_attributed_samples_total counter
+		cortex_ingester_attributed_samples_total{attribution="__missing__",user="1"} 1
+		cortex_ingester_attributed_samples_total{attribution="__overflow__",user="1"} 2
+		cortex_ingester_attributed_samples_total{attribution="a",user="1"} 2
+		cortex_ingester_attributed_samples_total{attribution="b",user="1"} 1
+	`), "cortex_ingester_attributed_active_series", "cortex_ingester_attributed_samples_total"))
+}
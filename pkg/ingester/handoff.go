@@ -0,0 +1,414 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/chunk"
+)
+
+const (
+	// handOffAppendBatchSize is the number of received series appended to the TSDB of a tenant before
+	// the appender is committed.
+	handOffAppendBatchSize = 1000
+
+	// handOffTenantID is the tenant ID of the hand-off requests, which transfer the series of all the tenants.
+	// It's only required by the ingester client.
+	handOffTenantID = "-1"
+)
+
+var errNoHandOffTarget = errors.New("no healthy ACTIVE ingester found in the zone of the leaving ingester")
+
+// TransferOut implements ring.FlushTransferer. When the hand-off is enabled, it streams the in-memory series
+// of all the tenants to another ingester of the same zone, which takes over the tokens of the ingester
+// once all the series have been received. If it fails, the lifecycler flushes the series to blocks instead.
+func (i *Ingester) TransferOut(ctx context.Context) error {
+	if !i.cfg.HandOffEnabled {
+		return ring.ErrTransferDisabled
+	}
+	if i.cfg.IngesterClientFactory == nil {
+		return errors.New("no ingester client to hand off the in-memory series")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, i.cfg.HandOffTimeout)
+	defer cancel()
+
+	desc, err := i.lifecycler.KVStore.Get(ctx, i.lifecycler.RingKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the ring")
+	}
+	target, err := handOffTarget(ring.GetOrCreateRingDesc(desc), i.lifecycler.ID, i.cfg.IngesterRing.HeartbeatTimeout, time.Now())
+	if err != nil {
+		return err
+	}
+
+	level.Info(i.logger).Log("msg", "handing off in-memory series", "target", target.Addr)
+	start := time.Now()
+
+	c, err := i.cfg.IngesterClientFactory(target.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to ingester %s", target.Addr)
+	}
+	defer c.Close() //nolint:errcheck
+
+	stream, err := c.TransferChunks(user.InjectOrgID(ctx, handOffTenantID))
+	if err != nil {
+		return errors.Wrapf(err, "failed to hand off the in-memory series to ingester %s", target.Addr)
+	}
+
+	numSeries := 0
+	for _, userID := range i.getTSDBUsers() {
+		n, err := i.handOffUserSeries(ctx, stream, userID)
+		numSeries += n
+		if err != nil {
+			return errors.Wrapf(err, "failed to hand off the in-memory series of tenant %s to ingester %s", userID, target.Addr)
+		}
+	}
+
+	// The last message identifies the leaving ingester, even if it has no series.
+	if err := stream.Send(&client.TimeSeriesChunk{FromIngesterId: i.lifecycler.ID}); err != nil {
+		return errors.Wrapf(err, "failed to hand off the in-memory series to ingester %s", target.Addr)
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		return errors.Wrapf(err, "failed to hand off the in-memory series to ingester %s", target.Addr)
+	}
+
+	// The in-memory series are now owned by the target, but the blocks previously compacted from
+	// the head may not have been shipped yet.
+	if i.cfg.BlocksStorageConfig.TSDB.IsBlocksShippingEnabled() {
+		i.shipBlocks(ctx, nil)
+	}
+
+	level.Info(i.logger).Log("msg", "handed off in-memory series", "target", target.Addr, "series", numSeries, "duration", time.Since(start))
+	return nil
+}
+
+// handOffUserSeries sends the in-memory series of the tenant to the stream, and returns the number of sent series.
+func (i *Ingester) handOffUserSeries(ctx context.Context, stream client.Ingester_TransferChunksClient, userID string) (int, error) {
+	db := i.getTSDB(userID)
+	if db == nil || db.Head().NumSeries() == 0 {
+		return 0, nil
+	}
+
+	q, err := db.ChunkQuerier(ctx, db.Head().MinTime(), db.Head().MaxTime(), false)
+	if err != nil {
+		return 0, err
+	}
+	defer q.Close()
+
+	ss := q.Select(false, nil, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+"))
+
+	numSeries := 0
+	var it chunks.Iterator
+	for ss.Next() {
+		series := ss.At()
+		ts := client.TimeSeriesChunk{
+			FromIngesterId: i.lifecycler.ID,
+			UserId:         userID,
+			Labels:         mimirpb.FromLabelsToLabelAdapters(series.Labels()),
+		}
+
+		it = series.Iterator(it)
+		for it.Next() {
+			meta := it.At()
+			if meta.Chunk == nil {
+				return numSeries, errors.Errorf("unfilled chunk returned from TSDB chunk querier")
+			}
+
+			ch, err := toClientChunk(meta)
+			if err != nil {
+				return numSeries, err
+			}
+			ts.Chunks = append(ts.Chunks, ch)
+		}
+		if err := it.Err(); err != nil {
+			return numSeries, err
+		}
+
+		if err := stream.Send(&ts); err != nil {
+			return numSeries, err
+		}
+		numSeries++
+		i.metrics.handOffSentSeries.Inc()
+	}
+
+	return numSeries, ss.Err()
+}
+
+// TransferChunks implements client.IngesterServer. It appends the in-memory series handed off by a leaving
+// ingester to the TSDB of their tenant, and adds the tokens of the leaving ingester to its own tokens once all
+// the series have been received.
+func (i *Ingester) TransferChunks(stream client.Ingester_TransferChunksServer) error {
+	if err := i.checkRunning(); err != nil {
+		return err
+	}
+
+	var (
+		fromIngesterID string
+		db             *userTSDB
+		app            storage.Appender
+		appSeries      int
+		numSeries      int
+	)
+
+	// The appender is committed after each batch of series, and when the tenant changes.
+	commit := func() error {
+		if app == nil {
+			return nil
+		}
+		defer db.releaseAppendLock()
+
+		err := app.Commit()
+		app, appSeries = nil, 0
+		return err
+	}
+	defer func() {
+		if app != nil {
+			_ = app.Rollback()
+			db.releaseAppendLock()
+		}
+	}()
+
+	for {
+		series, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to receive the series handed off by the leaving ingester")
+		}
+
+		fromIngesterID = series.FromIngesterId
+		if series.UserId == "" {
+			continue
+		}
+
+		if app == nil || db.userID != series.UserId || appSeries >= handOffAppendBatchSize {
+			if err := commit(); err != nil {
+				return err
+			}
+
+			db, err = i.getOrCreateTSDB(series.UserId, false)
+			if err != nil {
+				return err
+			}
+			if err := db.acquireAppendLock(); err != nil {
+				return err
+			}
+			app = db.Appender(stream.Context())
+		}
+
+		if err := i.appendHandedOffSeries(app, series); err != nil {
+			return errors.Wrapf(err, "failed to append the series handed off by ingester %s", fromIngesterID)
+		}
+		appSeries++
+		numSeries++
+		i.metrics.handOffReceivedSeries.Inc()
+	}
+
+	if err := commit(); err != nil {
+		return err
+	}
+	if fromIngesterID == "" {
+		return errors.New("the leaving ingester has not been identified")
+	}
+
+	if err := i.mergeTokensOf(stream.Context(), fromIngesterID); err != nil {
+		return errors.Wrapf(err, "failed to claim the tokens of ingester %s", fromIngesterID)
+	}
+
+	level.Info(i.logger).Log("msg", "received the in-memory series handed off by the leaving ingester, and claimed its tokens", "from_ingester", fromIngesterID, "series", numSeries)
+	return stream.SendAndClose(&client.TransferChunksResponse{})
+}
+
+// mergeTokensOf moves the tokens of the leaving ingester to this ingester, keeping the tokens this ingester
+// already owns. The lifecycler's ClaimTokensFor replaces the tokens of the claiming ingester with the tokens of
+// the leaving one, so the tokens of both ingesters are first given to the leaving ingester in the ring, and then
+// claimed through the lifecycler, which keeps them in memory and stores them to the tokens file, if configured.
+func (i *Ingester) mergeTokensOf(ctx context.Context, leavingID string) error {
+	var merged ring.Tokens
+	err := i.lifecycler.KVStore.CAS(ctx, i.lifecycler.RingKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		desc := ring.GetOrCreateRingDesc(in)
+
+		leaving, ok := desc.Ingesters[leavingID]
+		if !ok {
+			return nil, false, fmt.Errorf("ingester %s not found in the ring", leavingID)
+		}
+		if leaving.State != ring.LEAVING {
+			return nil, false, fmt.Errorf("ingester %s is %s instead of LEAVING", leavingID, leaving.State)
+		}
+		own, ok := desc.Ingesters[i.lifecycler.ID]
+		if !ok {
+			return nil, false, fmt.Errorf("ingester %s not found in the ring", i.lifecycler.ID)
+		}
+
+		merged = make(ring.Tokens, 0, len(own.Tokens)+len(leaving.Tokens))
+		merged = append(merged, own.Tokens...)
+		merged = append(merged, leaving.Tokens...)
+		sort.Sort(merged)
+
+		leaving.Tokens = merged
+		desc.Ingesters[leavingID] = leaving
+		return desc, true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// ClaimTokensFor only logs the failures to update the ring, so the ring is checked afterwards.
+	if err := i.lifecycler.ClaimTokensFor(ctx, leavingID); err != nil {
+		return err
+	}
+	desc, err := i.lifecycler.KVStore.Get(ctx, i.lifecycler.RingKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the ring")
+	}
+	if own := ring.GetOrCreateRingDesc(desc).Ingesters[i.lifecycler.ID]; !ring.Tokens(own.Tokens).Equals(merged) {
+		return fmt.Errorf("ingester %s doesn't own the claimed tokens in the ring", i.lifecycler.ID)
+	}
+	return nil
+}
+
+// appendHandedOffSeries appends the samples of the series to the appender. It fails if any sample can't be
+// appended, so that the leaving ingester flushes its series instead of losing them. The samples this ingester
+// already has with the same value aren't errors, because the TSDB accepts the exact duplicates.
+func (i *Ingester) appendHandedOffSeries(app storage.Appender, series *client.TimeSeriesChunk) error {
+	lbls := mimirpb.FromLabelAdaptersToLabelsWithCopy(series.Labels)
+
+	var ref storage.SeriesRef
+	var it chunkenc.Iterator
+	for _, c := range series.Chunks {
+		enc, err := fromClientChunkEncoding(c.Encoding)
+		if err != nil {
+			return err
+		}
+		chk, err := chunkenc.FromData(enc, c.Data)
+		if err != nil {
+			return err
+		}
+
+		it = chk.Iterator(it)
+		for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+			var err error
+			switch vt {
+			case chunkenc.ValFloat:
+				t, v := it.At()
+				ref, err = app.Append(ref, lbls, t, v)
+			case chunkenc.ValHistogram:
+				t, h := it.AtHistogram()
+				ref, err = app.AppendHistogram(ref, lbls, t, h, nil)
+			case chunkenc.ValFloatHistogram:
+				t, fh := it.AtFloatHistogram()
+				ref, err = app.AppendHistogram(ref, lbls, t, nil, fh)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to append a sample of series %s", lbls)
+			}
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fromClientChunkEncoding returns the TSDB encoding of a chunk sent to the clients.
+func fromClientChunkEncoding(encoding int32) (chunkenc.Encoding, error) {
+	switch chunk.Encoding(encoding) {
+	case chunk.PrometheusXorChunk:
+		return chunkenc.EncXOR, nil
+	case chunk.PrometheusHistogramChunk:
+		return chunkenc.EncHistogram, nil
+	case chunk.PrometheusFloatHistogramChunk:
+		return chunkenc.EncFloatHistogram, nil
+	default:
+		return chunkenc.EncNone, fmt.Errorf("unknown chunk encoding: %d", encoding)
+	}
+}
+
+// handOffTarget returns the ingester to which the in-memory series of the leaving ingester are handed off, and
+// which takes over its tokens: the healthy ACTIVE ingester of the same zone owning the fewest tokens, so that
+// successive scale-downs spread the tokens of the leaving ingesters instead of piling them on the same ingester.
+// Ties go to the ingester owning most of the tokens immediately following the tokens of the leaving ingester.
+func handOffTarget(desc *ring.Desc, instanceID string, heartbeatTimeout time.Duration, now time.Time) (ring.InstanceDesc, error) {
+	leaving, ok := desc.Ingesters[instanceID]
+	if !ok {
+		return ring.InstanceDesc{}, fmt.Errorf("ingester %s not found in the ring", instanceID)
+	}
+
+	type instanceToken struct {
+		token      uint32
+		instanceID string
+	}
+	var tokens []instanceToken
+	candidates := map[string]ring.InstanceDesc{}
+	for id, inst := range desc.Ingesters {
+		if inst.Zone != leaving.Zone {
+			continue
+		}
+		if id != instanceID {
+			if !inst.IsHealthy(ring.Write, heartbeatTimeout, now) {
+				continue
+			}
+			candidates[id] = inst
+		}
+		for _, token := range inst.Tokens {
+			tokens = append(tokens, instanceToken{token: token, instanceID: id})
+		}
+	}
+	sort.Slice(tokens, func(a, b int) bool { return tokens[a].token < tokens[b].token })
+
+	successorTokens := map[string]int{}
+	for idx, t := range tokens {
+		if t.instanceID != instanceID {
+			continue
+		}
+		for next := 1; next < len(tokens); next++ {
+			if successor := tokens[(idx+next)%len(tokens)]; successor.instanceID != instanceID {
+				successorTokens[successor.instanceID]++
+				break
+			}
+		}
+	}
+
+	targetID := ""
+	for id, inst := range candidates {
+		if targetID == "" {
+			targetID = id
+			continue
+		}
+		target := candidates[targetID]
+		switch {
+		case len(inst.Tokens) != len(target.Tokens):
+			if len(inst.Tokens) < len(target.Tokens) {
+				targetID = id
+			}
+		case successorTokens[id] != successorTokens[targetID]:
+			if successorTokens[id] > successorTokens[targetID] {
+				targetID = id
+			}
+		case id < targetID:
+			targetID = id
+		}
+	}
+	if targetID == "" {
+		return ring.InstanceDesc{}, errNoHandOffTarget
+	}
+	return desc.Ingesters[targetID], nil
+}
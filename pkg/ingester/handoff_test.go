@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/middleware"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestHandOffTarget(t *testing.T) {
+	now := time.Now()
+	heartbeatTimeout := time.Minute
+
+	instance := func(zone string, state ring.InstanceState, heartbeat time.Time, tokens ...uint32) ring.InstanceDesc {
+		return ring.InstanceDesc{Addr: zone, Zone: zone, State: state, Timestamp: heartbeat.Unix(), Tokens: tokens}
+	}
+
+	tests := map[string]struct {
+		ingesters   map[string]ring.InstanceDesc
+		expectedID  string
+		expectedErr error
+	}{
+		"should pick the ingester owning the fewest tokens": {
+			ingesters: map[string]ring.InstanceDesc{
+				"leaving": instance("a", ring.LEAVING, now, 10, 30, 50),
+				"ing-1":   instance("a", ring.ACTIVE, now, 20, 60),
+				"ing-2":   instance("a", ring.ACTIVE, now, 40),
+			},
+			expectedID: "ing-2",
+		},
+		"should pick the ingester owning most of the tokens following the tokens of the leaving ingester on a tie": {
+			ingesters: map[string]ring.InstanceDesc{
+				"leaving": instance("a", ring.LEAVING, now, 10, 30, 50),
+				"ing-1":   instance("a", ring.ACTIVE, now, 20, 60),
+				"ing-2":   instance("a", ring.ACTIVE, now, 40, 70),
+			},
+			expectedID: "ing-1",
+		},
+		"should not pick the ingester which took over the tokens of a previous leaving ingester": {
+			ingesters: map[string]ring.InstanceDesc{
+				"leaving": instance("a", ring.LEAVING, now, 10, 30),
+				"ing-1":   instance("a", ring.ACTIVE, now, 20, 40, 60, 80),
+				"ing-2":   instance("a", ring.ACTIVE, now, 50, 90),
+			},
+			expectedID: "ing-2",
+		},
+		"should pick the ingester with the smallest ID on a tie": {
+			ingesters: map[string]ring.InstanceDesc{
+				"leaving": instance("a", ring.LEAVING, now, 10, 30),
+				"ing-2":   instance("a", ring.ACTIVE, now, 20),
+				"ing-1":   instance("a", ring.ACTIVE, now, 40),
+			},
+			expectedID: "ing-1",
+		},
+		"should only consider the ingesters of the same zone": {
+			ingesters: map[string]ring.InstanceDesc{
+				"leaving": instance("a", ring.LEAVING, now, 10, 30),
+				"ing-1":   instance("b", ring.ACTIVE, now, 20, 40),
+				"ing-2":   instance("a", ring.ACTIVE, now, 50),
+			},
+			expectedID: "ing-2",
+		},
+		"should skip the unhealthy and not ACTIVE ingesters": {
+			ingesters: map[string]ring.InstanceDesc{
+				"leaving": instance("a", ring.LEAVING, now, 10),
+				"ing-1":   instance("a", ring.ACTIVE, now.Add(-2*heartbeatTimeout), 20),
+				"ing-2":   instance("a", ring.JOINING, now, 30),
+				"ing-3":   instance("a", ring.ACTIVE, now, 40),
+			},
+			expectedID: "ing-3",
+		},
+		"should fail if there's no other healthy ingester in the zone": {
+			ingesters: map[string]ring.InstanceDesc{
+				"leaving": instance("a", ring.LEAVING, now, 10),
+				"ing-1":   instance("a", ring.LEAVING, now, 20),
+				"ing-2":   instance("b", ring.ACTIVE, now, 30),
+			},
+			expectedErr: errNoHandOffTarget,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			target, err := handOffTarget(&ring.Desc{Ingesters: tc.ingesters}, "leaving", heartbeatTimeout, now)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ingesters[tc.expectedID], target)
+		})
+	}
+}
+
+// prepareHandOffIngesters starts two ingesters sharing the same ring, the leaving one handing off its in-memory
+// series to the target one.
+func prepareHandOffIngesters(t *testing.T) (leaving, target *Ingester, leavingReg, targetReg *prometheus.Registry) {
+	leavingCfg := defaultIngesterTestConfig(t)
+	leavingCfg.IngesterRing.InstanceID = "ingester-1"
+	leavingCfg.HandOffEnabled = true
+
+	targetCfg := leavingCfg
+	targetCfg.IngesterRing.InstanceID = "ingester-2"
+	targetCfg.IngesterRing.InstanceAddr = "ingester-2"
+	targetCfg.IngesterRing.TokensFilePath = filepath.Join(t.TempDir(), "tokens")
+
+	targetReg = prometheus.NewPedanticRegistry()
+	target, err := prepareIngesterWithBlocksStorage(t, targetCfg, targetReg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), target))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), target)
+	})
+
+	serv := grpc.NewServer(grpc.StreamInterceptor(middleware.StreamServerUserHeaderInterceptor))
+	t.Cleanup(serv.GracefulStop)
+	client.RegisterIngesterServer(serv, target)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go func() {
+		require.NoError(t, serv.Serve(listener))
+	}()
+
+	leavingCfg.IngesterClientFactory = func(addr string) (client.HealthAndIngesterClient, error) {
+		assert.Equal(t, "ingester-2", strings.Split(addr, ":")[0])
+		return client.MakeIngesterClient(listener.Addr().String(), defaultClientTestConfig())
+	}
+
+	leavingReg = prometheus.NewPedanticRegistry()
+	leaving, err = prepareIngesterWithBlocksStorage(t, leavingCfg, leavingReg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), leaving))
+
+	test.Poll(t, time.Second, 2, func() interface{} {
+		return leaving.lifecycler.HealthyInstancesCount()
+	})
+	return leaving, target, leavingReg, targetReg
+}
+
+// ringTokens returns the tokens of the instance in the ring of the ingester.
+func ringTokens(t *testing.T, i *Ingester, instanceID string) []uint32 {
+	desc, err := i.lifecycler.KVStore.Get(context.Background(), i.lifecycler.RingKey)
+	require.NoError(t, err)
+	return ring.GetOrCreateRingDesc(desc).Ingesters[instanceID].Tokens
+}
+
+func TestIngester_HandOff(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	leaving, target, leavingReg, targetReg := prepareHandOffIngesters(t)
+	leavingTokens := ringTokens(t, target, "ingester-1")
+	require.Len(t, leavingTokens, 1)
+	targetTokens := ringTokens(t, target, "ingester-2")
+	require.Len(t, targetTokens, 1)
+
+	// Push float and histogram series to the leaving ingester.
+	now := time.Now().UnixMilli()
+	for _, metric := range []string{"foo", "bar"} {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, metric), 1, now)
+		_, err := leaving.Push(ctx, req)
+		require.NoError(t, err)
+	}
+	req := writeRequestSingleSeries(labels.FromStrings(labels.MetricName, "histogram"), nil)
+	req.Timeseries[0].Histograms = []mimirpb.Histogram{{
+		Count:          &mimirpb.Histogram_CountInt{CountInt: 5},
+		Sum:            10,
+		ZeroThreshold:  0.001,
+		ZeroCount:      &mimirpb.Histogram_ZeroCountInt{ZeroCountInt: 1},
+		PositiveSpans:  []mimirpb.BucketSpan{{Offset: 0, Length: 2}},
+		PositiveDeltas: []int64{2, 0},
+		Timestamp:      now,
+	}}
+	_, err := leaving.Push(ctx, req)
+	require.NoError(t, err)
+
+	// The target already has one of the samples, which doesn't fail the hand-off.
+	req, _, _, _ = mockWriteRequest(t, labels.FromStrings(labels.MetricName, "foo"), 1, now)
+	_, err = target.Push(ctx, req)
+	require.NoError(t, err)
+
+	// The series are handed off when the ingester shuts down.
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), leaving))
+
+	require.Equal(t, uint64(3), target.getTSDB(userID).Head().NumSeries())
+	res, _, err := runTestQuery(ctx, t, target, labels.MatchRegexp, labels.MetricName, "foo|bar")
+	require.NoError(t, err)
+	assert.Equal(t, model.Matrix{
+		{Metric: model.Metric{model.MetricNameLabel: "bar"}, Values: []model.SamplePair{{Timestamp: model.Time(now), Value: 1}}},
+		{Metric: model.Metric{model.MetricNameLabel: "foo"}, Values: []model.SamplePair{{Timestamp: model.Time(now), Value: 1}}},
+	}, res)
+
+	// The target took over the tokens of the leaving ingester, and kept its own tokens.
+	assert.ElementsMatch(t, append(targetTokens, leavingTokens...), ringTokens(t, target, "ingester-2"))
+	assert.Empty(t, ringTokens(t, target, "ingester-1"))
+
+	// The lifecycler of the target stored the merged tokens to the tokens file.
+	storedTokens, err := ring.LoadTokensFromFile(target.cfg.IngesterRing.TokensFilePath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, append(targetTokens, leavingTokens...), storedTokens)
+
+	require.NoError(t, testutil.GatherAndCompare(leavingReg, strings.NewReader(`
+		# HELP cortex_ingester_hand_off_sent_series_total The total number of in-memory series handed off to another ingester on shutdown.
+		# TYPE cortex_ingester_hand_off_sent_series_total counter
+		cortex_ingester_hand_off_sent_series_total 3
+	`), "cortex_ingester_hand_off_sent_series_total"))
+
+	require.NoError(t, testutil.GatherAndCompare(targetReg, strings.NewReader(`
+		# HELP cortex_ingester_hand_off_received_series_total The total number of in-memory series received from leaving ingesters.
+		# TYPE cortex_ingester_hand_off_received_series_total counter
+		cortex_ingester_hand_off_received_series_total 3
+	`), "cortex_ingester_hand_off_received_series_total"))
+}
+
+func TestIngester_HandOff_ShouldFailIfSamplesCanNotBeAppended(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	leaving, target, _, _ := prepareHandOffIngesters(t)
+	targetTokens := ringTokens(t, target, "ingester-2")
+	defer services.StopAndAwaitTerminated(context.Background(), leaving) //nolint:errcheck
+
+	// The target has another value for the sample of the leaving ingester.
+	now := time.Now().UnixMilli()
+	req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "foo"), 1, now)
+	_, err := leaving.Push(ctx, req)
+	require.NoError(t, err)
+	req, _, _, _ = mockWriteRequest(t, labels.FromStrings(labels.MetricName, "foo"), 2, now)
+	_, err = target.Push(ctx, req)
+	require.NoError(t, err)
+
+	// The hand-off fails, so that the lifecycler flushes the series instead, and the target keeps its tokens only.
+	require.NoError(t, leaving.lifecycler.ChangeState(ctx, ring.LEAVING))
+	require.ErrorContains(t, leaving.TransferOut(context.Background()), storage.ErrDuplicateSampleForTimestamp.Error())
+	assert.Equal(t, targetTokens, ringTokens(t, target, "ingester-2"))
+	assert.NotEmpty(t, ringTokens(t, target, "ingester-1"))
+}
+
+func TestIngester_TransferOut_Disabled(t *testing.T) {
+	i, err := prepareIngesterWithBlocksStorage(t, defaultIngesterTestConfig(t), nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, i.TransferOut(context.Background()), ring.ErrTransferDisabled)
+}
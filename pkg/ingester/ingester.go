@@ -34,6 +34,7 @@ import (
 	"github.com/prometheus/common/model"
 	promcfg "github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
@@ -58,6 +59,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/ephemeral"
 	"github.com/grafana/mimir/pkg/util/globalerror"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	util_math "github.com/grafana/mimir/pkg/util/math"
@@ -98,6 +100,11 @@ const (
 	perUserSeriesLimit   = "per_user_series_limit"
 	perMetricSeriesLimit = "per_metric_series_limit"
 
+	perLabelNameValuesLimit = "per_label_name_values_limit"
+	perSelectorSeriesLimit  = "per_selector_series_limit"
+
+	invalidNativeHistogram = "invalid-native-histogram"
+
 	// Prefix for discard reasons when ingesting ephemeral series.
 	ephemeralDiscardPrefix = "ephemeral-"
 
@@ -115,20 +122,22 @@ const (
 	ephemeralPrometheusMetricsPrefix = "ephemeral_"
 
 	// StorageLabelName is a label name used to select queried storage type.
-	StorageLabelName            = "__mimir_storage__"
-	EphemeralStorageLabelValue  = "ephemeral"
-	PersistentStorageLabelValue = "persistent"
+	StorageLabelName            = ephemeral.StorageLabelName
+	EphemeralStorageLabelValue  = ephemeral.EphemeralStorageLabelValue
+	PersistentStorageLabelValue = ephemeral.PersistentStorageLabelValue
 
 	errInvalidStorageLabelValue = "invalid value of " + StorageLabelName + " label: %s"
 )
 
 var (
-	errInvalidStorageMatcherType    = fmt.Errorf("invalid matcher used together with %s label, only equality check supported", StorageLabelName)
-	errMultipleStorageMatchersFound = fmt.Errorf("multiple matchers for %s label found, only one matcher supported", StorageLabelName)
+	errInvalidStorageMatcherType    = ephemeral.ErrInvalidStorageMatcherType
+	errMultipleStorageMatchersFound = ephemeral.ErrMultipleStorageMatchersFound
 )
 
 var errEphemeralStorageDisabledForUser = errors.New("ephemeral storage is not enabled for user")
 
+var errInvalidSeriesGrowthTrackingWindow = errors.New("the series growth tracking window must be greater than 0")
+
 // BlocksUploader interface is used to have an easy way to mock it in tests.
 type BlocksUploader interface {
 	Sync(ctx context.Context) (uploaded int, err error)
@@ -172,6 +181,18 @@ type Config struct {
 	InstanceLimitsFn func() *InstanceLimits `yaml:"-"`
 
 	IgnoreSeriesLimitForMetricNames string `yaml:"ignore_series_limit_for_metric_names" category:"advanced"`
+
+	PushPressureHeapLimitBytes uint64 `yaml:"push_pressure_heap_limit_bytes" category:"experimental"`
+
+	HandOffEnabled bool          `yaml:"hand_off_enabled" category:"experimental"`
+	HandOffTimeout time.Duration `yaml:"hand_off_timeout" category:"experimental"`
+
+	SeriesGrowthTrackingEnabled    bool          `yaml:"series_growth_tracking_enabled" category:"experimental"`
+	SeriesGrowthTrackingWindow     time.Duration `yaml:"series_growth_tracking_window" category:"experimental"`
+	SeriesGrowthTrackingMaxEntries int           `yaml:"series_growth_tracking_max_entries" category:"experimental"`
+
+	// Injected internally, to connect to the ingester receiving the hand-off.
+	IngesterClientFactory func(addr string) (client.HealthAndIngesterClient, error) `yaml:"-"`
 }
 
 // RegisterFlags adds the flags required to config this to the given FlagSet
@@ -191,6 +212,20 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.DefaultLimits.RegisterFlags(f)
 
 	f.StringVar(&cfg.IgnoreSeriesLimitForMetricNames, "ingester.ignore-series-limit-for-metric-names", "", "Comma-separated list of metric names, for which the -ingester.max-global-series-per-metric limit will be ignored. Does not affect the -ingester.max-global-series-per-user limit.")
+	f.Uint64Var(&cfg.PushPressureHeapLimitBytes, "ingester.push-pressure-heap-limit-bytes", 0, "Heap size in bytes at which the ingester reports the highest push pressure to the distributors. The ingester reports its push pressure in the responses of the push requests, based on its in-flight push requests, ingestion rate and in-memory series compared to its instance limits, and on its heap size compared to this limit. 0 to not take the heap size into account.")
+	f.BoolVar(&cfg.HandOffEnabled, "ingester.hand-off-enabled", false, "Hand off the in-memory series to the successor of the ingester in the ring on shutdown, instead of flushing them to blocks. The successor is the healthy ACTIVE ingester of the same zone which owns most of the tokens following the tokens of this ingester, and it adds the tokens of this ingester to its own tokens once all the series have been transferred. If the hand-off fails, the series are flushed to blocks if flushing on shutdown is enabled.")
+	f.DurationVar(&cfg.HandOffTimeout, "ingester.hand-off-timeout", 10*time.Minute, "Timeout of the hand-off of the in-memory series on shutdown.")
+	f.BoolVar(&cfg.SeriesGrowthTrackingEnabled, "ingester.series-growth-tracking-enabled", false, "Track the number of series created per metric name and per label pair of each tenant, to find the cardinality explosions with the active growth cardinality API.")
+	f.DurationVar(&cfg.SeriesGrowthTrackingWindow, "ingester.series-growth-tracking-window", time.Hour, "Period of time over which the number of created series is tracked.")
+	f.IntVar(&cfg.SeriesGrowthTrackingMaxEntries, "ingester.series-growth-tracking-max-entries", 10000, "Maximum number of metric names, and of label pairs, tracked per tenant within each window. The series of the metric names and label pairs which aren't tracked yet are not counted once this limit is reached.")
+}
+
+// Validate the config.
+func (cfg *Config) Validate() error {
+	if cfg.SeriesGrowthTrackingEnabled && cfg.SeriesGrowthTrackingWindow <= 0 {
+		return errInvalidSeriesGrowthTrackingWindow
+	}
+	return nil
 }
 
 func (cfg *Config) getIgnoreSeriesLimitForMetricNamesMap() map[string]struct{} {
@@ -264,6 +299,9 @@ type Ingester struct {
 	ingestionRate        *util_math.EwmaRate
 	inflightPushRequests atomic.Int64
 
+	// Size of the heap, only sampled if it's used to compute the push pressure.
+	heapBytes atomic.Uint64
+
 	// Anonymous usage statistics tracked by ingester.
 	memorySeriesStats                  *expvar.Int
 	memoryTenantsStats                 *expvar.Int
@@ -501,6 +539,7 @@ func (i *Ingester) updateLoop(ctx context.Context) error {
 			i.purgeUserMetricsMetadata()
 		case <-ingestionRateTicker.C:
 			i.ingestionRate.Tick()
+			i.updateHeapBytes()
 		case <-rateUpdateTicker.C:
 			i.tsdbsMtx.RLock()
 			for _, db := range i.tsdbs {
@@ -562,6 +601,8 @@ func (i *Ingester) updateActiveSeries(now time.Time) {
 					i.metrics.activeSeriesCustomTrackersPerUser.DeleteLabelValues(userID, name)
 				}
 			}
+
+			i.updateAttributedActiveSeries(userDB, now)
 		}
 	}
 }
@@ -645,6 +686,17 @@ func (i *Ingester) applyTSDBSettings() {
 		if err := db.db.ApplyConfig(&cfg); err != nil {
 			level.Error(i.logger).Log("msg", "failed to apply config to TSDB", "user", userID, "err", err)
 		}
+
+		// Recount the series of the per-label limits if the limited label names or selectors changed.
+		labelNames := limitedKeys(i.limits.MaxLabelValuesPerLabelName(userID))
+		selectors := limitedKeys(i.limits.MaxGlobalSeriesPerSelector(userID))
+		if db.seriesInLabels.changed(labelNames, selectors) {
+			if err := db.reloadSeriesInLabels(labelNames, selectors); err != nil {
+				level.Error(i.logger).Log("msg", "failed to count the series of the per-label limits", "user", userID, "err", err)
+			}
+		}
+
+		i.reloadCostAttribution(db)
 	}
 }
 
@@ -655,16 +707,21 @@ type extendedAppender interface {
 }
 
 type pushStats struct {
-	succeededSamplesCount     int
-	failedSamplesCount        int
-	succeededExemplarsCount   int
-	failedExemplarsCount      int
-	sampleOutOfBoundsCount    int
-	sampleOutOfOrderCount     int
-	sampleTooOldCount         int
-	newValueForTimestampCount int
-	perUserSeriesLimitCount   int
-	perMetricSeriesLimitCount int
+	succeededSamplesCount        int
+	failedSamplesCount           int
+	succeededExemplarsCount      int
+	failedExemplarsCount         int
+	sampleOutOfBoundsCount       int
+	sampleOutOfOrderCount        int
+	sampleTooOldCount            int
+	newValueForTimestampCount    int
+	perUserSeriesLimitCount      int
+	perMetricSeriesLimitCount    int
+	perLabelNameValuesLimitCount int
+	perSelectorSeriesLimitCount  int
+	invalidNativeHistogramCount  int
+	// Number of succeeded samples per value of the cost attribution label.
+	attributedSamplesCount map[string]int
 }
 
 // PushWithCleanup is the Push() implementation for blocks storage and takes a WriteRequest and adds it to the TSDB head.
@@ -773,7 +830,7 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, pushReq *push.Request) (
 
 		minAppendTime, minAppendTimeAvailable := db.Head().AppendableMinValidTime()
 
-		err = i.pushSamplesToAppender(userID, req.Timeseries, persistentApp, startAppend, &persistentStats, updateFirstPartial, activeSeries, i.limits.OutOfOrderTimeWindow(userID), minAppendTimeAvailable, minAppendTime, false)
+		err = i.pushSamplesToAppender(userID, req.Timeseries, persistentApp, startAppend, &persistentStats, updateFirstPartial, activeSeries, db.costAttribution.attributionLabel(), i.limits.OutOfOrderTimeWindow(userID), minAppendTimeAvailable, minAppendTime, false)
 		if err != nil {
 			rollback()
 			return nil, err
@@ -806,7 +863,7 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, pushReq *push.Request) (
 
 			minAppendTime, minAppendTimeAvailable := db.getEphemeralStorage().AppendableMinValidTime()
 
-			err = i.pushSamplesToAppender(userID, req.EphemeralTimeseries, ephemeralApp, startAppend, &ephemeralStats, updateFirstPartial, nil, 0, minAppendTimeAvailable, minAppendTime, true)
+			err = i.pushSamplesToAppender(userID, req.EphemeralTimeseries, ephemeralApp, startAppend, &ephemeralStats, updateFirstPartial, nil, "", 0, minAppendTimeAvailable, minAppendTime, true)
 			if err != nil {
 				rollback()
 				return nil, err
@@ -912,6 +969,15 @@ func (i *Ingester) updateMetricsFromPushStats(userID string, group string, stats
 	if stats.perMetricSeriesLimitCount > 0 {
 		discarded.perMetricSeriesLimit.WithLabelValues(userID, group).Add(float64(stats.perMetricSeriesLimitCount))
 	}
+	if stats.perLabelNameValuesLimitCount > 0 {
+		discarded.perLabelNameValuesLimit.WithLabelValues(userID, group).Add(float64(stats.perLabelNameValuesLimitCount))
+	}
+	if stats.perSelectorSeriesLimitCount > 0 {
+		discarded.perSelectorSeriesLimit.WithLabelValues(userID, group).Add(float64(stats.perSelectorSeriesLimitCount))
+	}
+	if stats.invalidNativeHistogramCount > 0 {
+		discarded.invalidNativeHistogram.WithLabelValues(userID, group).Add(float64(stats.invalidNativeHistogramCount))
+	}
 	if stats.succeededSamplesCount > 0 {
 		i.ingestionRate.Add(int64(stats.succeededSamplesCount))
 
@@ -921,12 +987,15 @@ func (i *Ingester) updateMetricsFromPushStats(userID string, group string, stats
 			db.ingestedAPISamples.Add(int64(stats.succeededSamplesCount))
 		}
 	}
+	for value, count := range stats.attributedSamplesCount {
+		i.metrics.attributedSamples.WithLabelValues(userID, db.costAttribution.attribution(value)).Add(float64(count))
+	}
 }
 
 // pushSamplesToAppender appends samples and exemplars to the appender. Most errors are handled via updateFirstPartial function,
 // but in case of unhandled errors, appender is rolled back and such error is returned.
 func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.PreallocTimeseries, app extendedAppender, startAppend time.Time,
-	stats *pushStats, updateFirstPartial func(errFn func() error), activeSeries *activeseries.ActiveSeries,
+	stats *pushStats, updateFirstPartial func(errFn func() error), activeSeries *activeseries.ActiveSeries, costAttributionLabel string,
 	outOfOrderWindow model.Duration, minAppendTimeAvailable bool, minAppendTime int64, ephemeral bool) error {
 	for _, ts := range timeseries {
 		// The labels must be sorted (in our case, it's guaranteed a write request
@@ -937,7 +1006,7 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 		// TODO(jesus.vazquez) If we had too many old samples we might want to
 		// extend the fast path to fail early.
 		if outOfOrderWindow <= 0 && minAppendTimeAvailable &&
-			len(ts.Samples) > 0 && len(ts.Exemplars) == 0 && allOutOfBounds(ts.Samples, minAppendTime) {
+			len(ts.Samples) > 0 && len(ts.Histograms) == 0 && len(ts.Exemplars) == 0 && allOutOfBounds(ts.Samples, minAppendTime) {
 			stats.failedSamplesCount += len(ts.Samples)
 			stats.sampleOutOfBoundsCount += len(ts.Samples)
 
@@ -957,71 +1026,50 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 		// To find out if any sample was added to this series, we keep old value.
 		oldSucceededSamplesCount := stats.succeededSamplesCount
 
-		for _, s := range ts.Samples {
-			var err error
-
-			// If the cached reference exists, we try to use it.
-			if ref != 0 {
-				if _, err = app.Append(ref, copiedLabels, s.TimestampMs, s.Value); err == nil {
-					stats.succeededSamplesCount++
-					continue
-				}
-			} else {
-				// Copy the label set because both TSDB and the active series tracker may retain it.
-				copiedLabels = mimirpb.FromLabelAdaptersToLabelsWithCopy(ts.Labels)
-
-				// Retain the reference in case there are multiple samples for the series.
-				if ref, err = app.Append(0, copiedLabels, s.TimestampMs, s.Value); err == nil {
-					stats.succeededSamplesCount++
-					continue
-				}
-			}
-
-			stats.failedSamplesCount++
-
-			// Check if the error is a soft error we can proceed on. If so, we keep track
-			// of it, so that we can return it back to the distributor, which will return a
-			// 400 error to the client. The client (Prometheus) will not retry on 400, and
-			// we actually ingested all samples which haven't failed.
+		// Check if the error is a soft error we can proceed on. If so, we keep track
+		// of it, so that we can return it back to the distributor, which will return a
+		// 400 error to the client. The client (Prometheus) will not retry on 400, and
+		// we actually ingested all samples which haven't failed.
+		handleAppendError := func(err error, timestampMs int64) bool {
 			//nolint:errorlint // We don't expect the cause error to be wrapped.
 			switch cause := errors.Cause(err); cause {
 			case storage.ErrOutOfBounds:
 				stats.sampleOutOfBoundsCount++
 				updateFirstPartial(func() error {
 					if ephemeral {
-						return newEphemeralIngestErrSampleTimestampTooOld(model.Time(s.TimestampMs), ts.Labels)
+						return newEphemeralIngestErrSampleTimestampTooOld(model.Time(timestampMs), ts.Labels)
 					}
-					return newIngestErrSampleTimestampTooOld(model.Time(s.TimestampMs), ts.Labels)
+					return newIngestErrSampleTimestampTooOld(model.Time(timestampMs), ts.Labels)
 				})
-				continue
+				return true
 
 			case storage.ErrOutOfOrderSample:
 				stats.sampleOutOfOrderCount++
 				updateFirstPartial(func() error {
 					if ephemeral {
-						return newEphemeralIngestErrSampleOutOfOrder(model.Time(s.TimestampMs), ts.Labels)
+						return newEphemeralIngestErrSampleOutOfOrder(model.Time(timestampMs), ts.Labels)
 					}
-					return newIngestErrSampleOutOfOrder(model.Time(s.TimestampMs), ts.Labels)
+					return newIngestErrSampleOutOfOrder(model.Time(timestampMs), ts.Labels)
 				})
-				continue
+				return true
 
 			case storage.ErrTooOldSample:
 				stats.sampleTooOldCount++
 				updateFirstPartial(func() error {
 					// OOO is not enabled for ephemeral storage, so we can't get this error.
-					return newIngestErrSampleTimestampTooOldOOOEnabled(model.Time(s.TimestampMs), ts.Labels, outOfOrderWindow)
+					return newIngestErrSampleTimestampTooOldOOOEnabled(model.Time(timestampMs), ts.Labels, outOfOrderWindow)
 				})
-				continue
+				return true
 
 			case storage.ErrDuplicateSampleForTimestamp:
 				stats.newValueForTimestampCount++
 				updateFirstPartial(func() error {
 					if ephemeral {
-						return newEphemeralIngestErrSampleDuplicateTimestamp(model.Time(s.TimestampMs), ts.Labels)
+						return newEphemeralIngestErrSampleDuplicateTimestamp(model.Time(timestampMs), ts.Labels)
 					}
-					return newIngestErrSampleDuplicateTimestamp(model.Time(s.TimestampMs), ts.Labels)
+					return newIngestErrSampleDuplicateTimestamp(model.Time(timestampMs), ts.Labels)
 				})
-				continue
+				return true
 
 			case errMaxSeriesPerUserLimitExceeded, errMaxEphemeralSeriesPerUserLimitExceeded: // we have special error for this, as we want different help message from FormatError.
 				stats.perUserSeriesLimitCount++
@@ -1031,7 +1079,7 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 					}
 					return makeLimitError(i.limiter.FormatError(userID, cause))
 				})
-				continue
+				return true
 
 			case errMaxSeriesPerMetricLimitExceeded:
 				stats.perMetricSeriesLimitCount++
@@ -1039,6 +1087,98 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 					// Ephemeral storage doesn't have this limit.
 					return makeMetricLimitError(copiedLabels, i.limiter.FormatError(userID, cause))
 				})
+				return true
+
+			case storage.ErrHistogramCountNotBigEnough, storage.ErrHistogramNegativeBucketCount, storage.ErrHistogramSpanNegativeOffset, storage.ErrHistogramSpansBucketsMismatch:
+				stats.invalidNativeHistogramCount++
+				updateFirstPartial(func() error {
+					return newIngestErrNativeHistogramInvalid(model.Time(timestampMs), ts.Labels, err)
+				})
+				return true
+			}
+
+			// The per-label limits errors carry the limited label name or selector.
+			//nolint:errorlint // We don't expect the cause error to be wrapped.
+			switch cause := errors.Cause(err).(type) {
+			case maxLabelValuesPerLabelNameLimitError:
+				stats.perLabelNameValuesLimitCount++
+				updateFirstPartial(func() error {
+					// Ephemeral storage doesn't have this limit.
+					return makeMetricLimitError(copiedLabels, i.limiter.FormatError(userID, cause))
+				})
+				return true
+
+			case maxSeriesPerSelectorLimitError:
+				stats.perSelectorSeriesLimitCount++
+				updateFirstPartial(func() error {
+					// Ephemeral storage doesn't have this limit.
+					return makeMetricLimitError(copiedLabels, i.limiter.FormatError(userID, cause))
+				})
+				return true
+			}
+
+			return false
+		}
+
+		for _, s := range ts.Samples {
+			var err error
+
+			// If the cached reference exists, we try to use it.
+			if ref != 0 {
+				if _, err = app.Append(ref, copiedLabels, s.TimestampMs, s.Value); err == nil {
+					stats.succeededSamplesCount++
+					continue
+				}
+			} else {
+				// Copy the label set because both TSDB and the active series tracker may retain it.
+				copiedLabels = mimirpb.FromLabelAdaptersToLabelsWithCopy(ts.Labels)
+
+				// Retain the reference in case there are multiple samples for the series.
+				if ref, err = app.Append(0, copiedLabels, s.TimestampMs, s.Value); err == nil {
+					stats.succeededSamplesCount++
+					continue
+				}
+			}
+
+			stats.failedSamplesCount++
+			if handleAppendError(err, s.TimestampMs) {
+				continue
+			}
+
+			return wrapWithUser(err, userID)
+		}
+
+		for _, h := range ts.Histograms {
+			var (
+				err error
+				ih  *histogram.Histogram
+				fh  *histogram.FloatHistogram
+			)
+
+			if h.IsFloatHistogram() {
+				fh = mimirpb.FromHistogramProtoToFloatHistogram(h)
+			} else {
+				ih = mimirpb.FromHistogramProtoToHistogram(h)
+			}
+
+			if ref != 0 {
+				if _, err = app.AppendHistogram(ref, copiedLabels, h.Timestamp, ih, fh); err == nil {
+					stats.succeededSamplesCount++
+					continue
+				}
+			} else {
+				// Copy the label set because both TSDB and the active series tracker may retain it.
+				copiedLabels = mimirpb.FromLabelAdaptersToLabelsWithCopy(ts.Labels)
+
+				// Retain the reference in case there are multiple histograms for the series.
+				if ref, err = app.AppendHistogram(0, copiedLabels, h.Timestamp, ih, fh); err == nil {
+					stats.succeededSamplesCount++
+					continue
+				}
+			}
+
+			stats.failedSamplesCount++
+			if handleAppendError(err, h.Timestamp) {
 				continue
 			}
 
@@ -1052,6 +1192,13 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 			})
 		}
 
+		if costAttributionLabel != "" && stats.succeededSamplesCount > oldSucceededSamplesCount {
+			if stats.attributedSamplesCount == nil {
+				stats.attributedSamplesCount = map[string]int{}
+			}
+			stats.attributedSamplesCount[costAttributionValue(ts.Labels, costAttributionLabel)] += stats.succeededSamplesCount - oldSucceededSamplesCount
+		}
+
 		if !ephemeral && len(ts.Exemplars) > 0 && i.limits.MaxGlobalExemplarsPerUser(userID) > 0 {
 			// app.AppendExemplar currently doesn't create the series, it must
 			// already exist.  If it does not then drop.
@@ -1515,13 +1662,21 @@ func (i *Ingester) queryStreamSamples(ctx context.Context, db *userTSDB, from, t
 
 		it = series.Iterator(it)
 		for valType := it.Next(); valType != chunkenc.ValNone; valType = it.Next() {
-			if valType != chunkenc.ValFloat {
+			switch valType {
+			case chunkenc.ValFloat:
+				t, v := it.At()
+				ts.Samples = append(ts.Samples, mimirpb.Sample{Value: v, TimestampMs: t})
+			case chunkenc.ValHistogram:
+				t, h := it.AtHistogram()
+				ts.Histograms = append(ts.Histograms, mimirpb.FromHistogramToHistogramProto(t, h))
+			case chunkenc.ValFloatHistogram:
+				t, fh := it.AtFloatHistogram()
+				ts.Histograms = append(ts.Histograms, mimirpb.FromFloatHistogramToHistogramProto(t, fh))
+			default:
 				return 0, 0, fmt.Errorf("unsupported value type: %v", valType)
 			}
-			t, v := it.At()
-			ts.Samples = append(ts.Samples, mimirpb.Sample{Value: v, TimestampMs: t})
 		}
-		numSamples += len(ts.Samples)
+		numSamples += len(ts.Samples) + len(ts.Histograms)
 		numSeries++
 		tsSize := ts.Size()
 
@@ -1609,17 +1764,9 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 				return 0, 0, errors.Errorf("unfilled chunk returned from TSDB chunk querier")
 			}
 
-			ch := client.Chunk{
-				StartTimestampMs: meta.MinTime,
-				EndTimestampMs:   meta.MaxTime,
-				Data:             meta.Chunk.Bytes(),
-			}
-
-			switch meta.Chunk.Encoding() {
-			case chunkenc.EncXOR:
-				ch.Encoding = int32(chunk.PrometheusXorChunk)
-			default:
-				return 0, 0, errors.Errorf("unknown chunk encoding from TSDB chunk querier: %v", meta.Chunk.Encoding())
+			ch, err := toClientChunk(meta)
+			if err != nil {
+				return 0, 0, err
 			}
 
 			ts.Chunks = append(ts.Chunks, ch)
@@ -1664,6 +1811,27 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 	return numSeries, numSamples, nil
 }
 
+// toClientChunk converts a chunk returned by a TSDB chunk querier to a chunk sent to the clients.
+func toClientChunk(meta chunks.Meta) (client.Chunk, error) {
+	ch := client.Chunk{
+		StartTimestampMs: meta.MinTime,
+		EndTimestampMs:   meta.MaxTime,
+		Data:             meta.Chunk.Bytes(),
+	}
+
+	switch meta.Chunk.Encoding() {
+	case chunkenc.EncXOR:
+		ch.Encoding = int32(chunk.PrometheusXorChunk)
+	case chunkenc.EncHistogram:
+		ch.Encoding = int32(chunk.PrometheusHistogramChunk)
+	case chunkenc.EncFloatHistogram:
+		ch.Encoding = int32(chunk.PrometheusFloatHistogramChunk)
+	default:
+		return client.Chunk{}, errors.Errorf("unknown chunk encoding from TSDB chunk querier: %v", meta.Chunk.Encoding())
+	}
+	return ch, nil
+}
+
 func (i *Ingester) getTSDB(userID string) *userTSDB {
 	i.tsdbsMtx.RLock()
 	defer i.tsdbsMtx.RUnlock()
@@ -1744,6 +1912,8 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 		userID:                       userID,
 		activeSeries:                 activeseries.NewActiveSeries(activeseries.NewMatchers(matchersConfig), i.cfg.ActiveSeriesMetricsIdleTimeout),
 		seriesInMetric:               newMetricCounter(i.limiter, i.cfg.getIgnoreSeriesLimitForMetricNamesMap()),
+		seriesInLabels:               newLabelCounter(i.limiter, limitedKeys(i.limits.MaxLabelValuesPerLabelName(userID)), limitedKeys(i.limits.MaxGlobalSeriesPerSelector(userID))),
+		costAttribution:              newCostAttributionTracker(i.limits.CostAttributionLabel(userID), i.limits.MaxCostAttributionPerUser(userID)),
 		ingestedAPISamples:           util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
 		ingestedRuleSamples:          util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
 		instanceLimitsFn:             i.getInstanceLimits,
@@ -1780,6 +1950,7 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 		HeadPostingsForMatchersCacheTTL:   i.cfg.BlocksStorageConfig.TSDB.HeadPostingsForMatchersCacheTTL,
 		HeadPostingsForMatchersCacheSize:  i.cfg.BlocksStorageConfig.TSDB.HeadPostingsForMatchersCacheSize,
 		HeadPostingsForMatchersCacheForce: i.cfg.BlocksStorageConfig.TSDB.HeadPostingsForMatchersCacheForce,
+		EnableNativeHistograms:            true, // the ingestion of native histograms is controlled by the distributor per tenant
 	}, nil)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to open TSDB: %s", udir)
@@ -1799,6 +1970,10 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 	// We set the limiter here because we don't want to limit
 	// series during WAL replay.
 	userDB.limiter = i.limiter
+	// Likewise, the series replayed from the WAL haven't been created recently.
+	if i.cfg.SeriesGrowthTrackingEnabled {
+		userDB.seriesGrowth = newSeriesGrowthTracker(i.cfg.SeriesGrowthTrackingWindow, i.cfg.SeriesGrowthTrackingMaxEntries, time.Now())
+	}
 
 	if db.Head().NumSeries() > 0 {
 		// If there are series in the head, use max time from head. If this time is too old,
@@ -1827,14 +2002,17 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 
 	i.tsdbMetrics.setRegistryForUser(userID, tsdbPromReg)
 
-	userDB.ephemeralSeriesRetentionPeriod = i.cfg.BlocksStorageConfig.EphemeralTSDB.Retention
+	userDB.ephemeralSeriesRetentionPeriod = func() time.Duration {
+		return i.ephemeralSeriesRetentionPeriod(userID)
+	}
 	userDB.ephemeralFactory = func() (*tsdb.Head, error) {
 		if i.limits.MaxEphemeralSeriesPerUser(userID) <= 0 {
 			return nil, errEphemeralStorageDisabledForUser
 		}
 
+		retention := i.ephemeralSeriesRetentionPeriod(userID)
 		headOptions := &tsdb.HeadOptions{
-			ChunkRange:                     i.cfg.BlocksStorageConfig.EphemeralTSDB.Retention.Milliseconds(),
+			ChunkRange:                     retention.Milliseconds(),
 			ChunkDirRoot:                   filepath.Join(udir, "ephemeral_chunks"),
 			ChunkPool:                      nil,
 			ChunkWriteBufferSize:           i.cfg.BlocksStorageConfig.EphemeralTSDB.HeadChunksWriteBufferSize,
@@ -1853,7 +2031,7 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 		headOptions.MaxExemplars.Store(0)
 		headOptions.OutOfOrderTimeWindow.Store(0)
 		headOptions.OutOfOrderCapMax.Store(int64(tsdb.DefaultOutOfOrderCapMax)) // We need to set this, despite OOO time window being 0.
-		headOptions.EnableNativeHistograms.Store(false)
+		headOptions.EnableNativeHistograms.Store(true)
 
 		h, err := tsdb.NewHead(prometheus.WrapRegistererWithPrefix(ephemeralPrometheusMetricsPrefix, tsdbPromReg), log.With(userLogger, storageKey, "ephemeral"), nil, nil, headOptions, nil)
 		if err != nil {
@@ -1867,7 +2045,7 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 		//
 		// We could have used h.SetMinValidTime() instead, but that only sets minValidTime and not minTime,
 		// and calling h.AppendableMinValidTime() then doesn't return set value. There is no such problem with Truncate.
-		if err := h.Truncate(time.Now().Add(-i.cfg.BlocksStorageConfig.EphemeralTSDB.Retention).UnixMilli()); err != nil {
+		if err := h.Truncate(time.Now().Add(-retention).UnixMilli()); err != nil {
 			return nil, err
 		}
 		return h, err
@@ -1876,6 +2054,15 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 	return userDB, nil
 }
 
+// ephemeralSeriesRetentionPeriod returns the retention of the ephemeral series of the user, which defaults to
+// the retention of the ephemeral storage.
+func (i *Ingester) ephemeralSeriesRetentionPeriod(userID string) time.Duration {
+	if retention := i.limits.EphemeralSeriesRetentionPeriod(userID); retention > 0 {
+		return retention
+	}
+	return i.cfg.BlocksStorageConfig.EphemeralTSDB.Retention
+}
+
 func (i *Ingester) closeAllTSDB() {
 	i.tsdbsMtx.Lock()
 
@@ -1906,6 +2093,7 @@ func (i *Ingester) closeAllTSDB() {
 
 			i.metrics.memUsers.Dec()
 			i.metrics.deletePerUserCustomTrackerMetrics(userID, db.activeSeries.CurrentMatcherNames())
+			i.metrics.deletePerUserCostAttributionMetrics(userID)
 			if ephemeral {
 				i.metrics.memEphemeralUsers.Dec()
 			}
@@ -2304,6 +2492,7 @@ func (i *Ingester) closeAndDeleteUserTSDBIfIdle(userID string) tsdbCloseCheckRes
 	i.deleteUserMetadata(userID)
 	i.metrics.deletePerUserMetrics(userID)
 	i.metrics.deletePerUserCustomTrackerMetrics(userID, userDB.activeSeries.CurrentMatcherNames())
+	i.metrics.deletePerUserCostAttributionMetrics(userID)
 
 	// And delete local data.
 	if err := os.RemoveAll(dir); err != nil {
@@ -2324,11 +2513,6 @@ func (i *Ingester) RemoveGroupMetricsForUser(userID, group string) {
 	i.metrics.deletePerGroupMetricsForUser(userID, group)
 }
 
-// TransferOut implements ring.FlushTransferer.
-func (i *Ingester) TransferOut(_ context.Context) error {
-	return ring.ErrTransferDisabled
-}
-
 // This method will flush all data. It is called as part of Lifecycler's shutdown (if flush on shutdown is configured), or from the flusher.
 //
 // When called as during Lifecycler shutdown, this happens as part of normal Ingester shutdown (see stopping method).
@@ -2460,6 +2644,10 @@ func newEphemeralIngestErrSampleDuplicateTimestamp(timestamp model.Time, labels
 	return newIngestErr(globalerror.EphemeralSampleDuplicateTimestamp, "the sample for ephemeral series has been rejected because another sample with the same timestamp, but a different value, has already been ingested", timestamp, labels)
 }
 
+func newIngestErrNativeHistogramInvalid(timestamp model.Time, labels []mimirpb.LabelAdapter, err error) error {
+	return newIngestErr(globalerror.InvalidNativeHistogram, fmt.Sprintf("the native histogram has been rejected because it's invalid: %s", err.Error()), timestamp, labels)
+}
+
 func newIngestErrExemplarMissingSeries(timestamp model.Time, seriesLabels, exemplarLabels []mimirpb.LabelAdapter) error {
 	return fmt.Errorf("%v. The affected exemplar is %s with timestamp %s for series %s",
 		globalerror.ExemplarSeriesMissing.Message("the exemplar has been rejected because the related series has not been ingested yet"),
@@ -2535,7 +2723,11 @@ func (i *Ingester) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mimirp
 		mimirpb.ReuseSlice(req.Timeseries)
 		mimirpb.ReuseSlice(req.EphemeralTimeseries)
 	})
-	return i.PushWithCleanup(ctx, pushReq)
+	resp, err := i.PushWithCleanup(ctx, pushReq)
+
+	// Report the push pressure to the distributors, it fails if the request doesn't come from the gRPC API.
+	_ = client.SetPushPressure(ctx, i.pushPressure())
+	return resp, err
 }
 
 // pushMetadata returns number of ingested metadata.
@@ -2721,32 +2913,12 @@ func (i *Ingester) UserRegistryHandler(w http.ResponseWriter, r *http.Request) {
 	}).ServeHTTP(w, r)
 }
 
-// findStorageLabelMatcher returns value of storage label matcher and its index, if it exists.
-func findStorageLabelMatcher(matchers []*labels.Matcher) (string, int, error) {
-	resultVal, resultIdx := "", -1
-
-	for idx, matcher := range matchers {
-		if matcher.Name == StorageLabelName {
-			if resultIdx >= 0 {
-				return "", idx, errMultipleStorageMatchersFound
-			}
-			if matcher.Type != labels.MatchEqual {
-				return "", idx, errInvalidStorageMatcherType
-			}
-			resultVal = matcher.Value
-			resultIdx = idx
-		}
-	}
-
-	return resultVal, resultIdx, nil
-}
-
 // This function returns the storage type (PersistentStorageLabelValue or EphemeralStorageLabelValue) from label matchers.
 // If storage label is not found, returns PersistentStorageLabelValue.
 // If storage label matcher is invalid (wrong type or value), returns error.
 // Returned matchers have storage label matcher removed (original slice is reused).
 func removeStorageMatcherAndGetStorageType(matchers []*labels.Matcher) (storageType string, filtered []*labels.Matcher, _ error) {
-	val, idx, err := findStorageLabelMatcher(matchers)
+	val, idx, err := ephemeral.FindStorageLabelMatcher(matchers)
 	if err != nil {
 		return PersistentStorageLabelValue, matchers, err
 	}
@@ -131,6 +131,33 @@ func (i *ActivityTrackerWrapper) LabelValuesCardinality(request *client.LabelVal
 	return i.ing.LabelValuesCardinality(request, server)
 }
 
+func (i *ActivityTrackerWrapper) TransferChunks(server client.Ingester_TransferChunksServer) error {
+	ix := i.tracker.Insert(func() string {
+		return requestActivity(server.Context(), "Ingester/TransferChunks", nil)
+	})
+	defer i.tracker.Delete(ix)
+
+	return i.ing.TransferChunks(server)
+}
+
+func (i *ActivityTrackerWrapper) ActiveGrowth(ctx context.Context, request *client.ActiveGrowthRequest) (*client.ActiveGrowthResponse, error) {
+	ix := i.tracker.Insert(func() string {
+		return requestActivity(ctx, "Ingester/ActiveGrowth", request)
+	})
+	defer i.tracker.Delete(ix)
+
+	return i.ing.ActiveGrowth(ctx, request)
+}
+
+func (i *ActivityTrackerWrapper) ActiveSeries(request *client.ActiveSeriesRequest, server client.Ingester_ActiveSeriesServer) error {
+	ix := i.tracker.Insert(func() string {
+		return requestActivity(server.Context(), "Ingester/ActiveSeries", request)
+	})
+	defer i.tracker.Delete(ix)
+
+	return i.ing.ActiveSeries(request, server)
+}
+
 func (i *ActivityTrackerWrapper) FlushHandler(w http.ResponseWriter, r *http.Request) {
 	ix := i.tracker.Insert(func() string {
 		return requestActivity(r.Context(), "Ingester/FlushHandler", nil)
@@ -2720,6 +2720,90 @@ func TestIngester_QueryStreamManySamplesChunks(t *testing.T) {
 	require.Equal(t, 100000+500000+samplesCount, totalSamples)
 }
 
+func TestIngester_QueryStreamNativeHistograms(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+
+	var streamType QueryStreamType
+	cfg.StreamTypeFn = func() QueryStreamType {
+		return streamType
+	}
+
+	i := requireActiveIngesterWithBlocksStorage(t, cfg, nil)
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	histogram := func(count uint64, timestamp int64) mimirpb.Histogram {
+		return mimirpb.Histogram{
+			Count:          &mimirpb.Histogram_CountInt{CountInt: count},
+			Sum:            10,
+			Schema:         0,
+			ZeroThreshold:  0.001,
+			ZeroCount:      &mimirpb.Histogram_ZeroCountInt{ZeroCountInt: 1},
+			PositiveSpans:  []mimirpb.BucketSpan{{Offset: 0, Length: 2}},
+			PositiveDeltas: []int64{2, 0},
+			Timestamp:      timestamp,
+		}
+	}
+
+	lbls := labels.FromStrings(labels.MetricName, "foo")
+	req := writeRequestSingleSeries(lbls, []mimirpb.Sample{{TimestampMs: 500, Value: 1}})
+	req.Timeseries[0].Histograms = []mimirpb.Histogram{histogram(5, 1000), histogram(5, 2000), histogram(1, 3000)}
+
+	_, err := i.Push(ctx, req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the native histogram has been rejected because it's invalid")
+	assert.Contains(t, err.Error(), string(globalerror.InvalidNativeHistogram))
+
+	queryReq := &client.QueryRequest{
+		StartTimestampMs: math.MinInt64,
+		EndTimestampMs:   math.MaxInt64,
+		Matchers:         []*client.LabelMatcher{{Type: client.EQUAL, Name: model.MetricNameLabel, Value: "foo"}},
+	}
+
+	t.Run("samples", func(t *testing.T) {
+		streamType = QueryStreamSamples
+		stream := &capturingQueryStreamServer{mockQueryStreamServer: mockQueryStreamServer{ctx: ctx}}
+		require.NoError(t, i.QueryStream(queryReq, stream))
+
+		require.Len(t, stream.responses, 1)
+		require.Len(t, stream.responses[0].Timeseries, 1)
+		ts := stream.responses[0].Timeseries[0]
+		assert.Equal(t, []mimirpb.Sample{{TimestampMs: 500, Value: 1}}, ts.Samples)
+		require.Len(t, ts.Histograms, 2)
+		for idx, expected := range []mimirpb.Histogram{histogram(5, 1000), histogram(5, 2000)} {
+			actual := ts.Histograms[idx]
+			actual.ResetHint = expected.ResetHint
+			assert.Equal(t, expected, actual)
+		}
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		streamType = QueryStreamChunks
+		stream := &capturingQueryStreamServer{mockQueryStreamServer: mockQueryStreamServer{ctx: ctx}}
+		require.NoError(t, i.QueryStream(queryReq, stream))
+
+		require.Len(t, stream.responses, 1)
+		require.Len(t, stream.responses[0].Chunkseries, 1)
+
+		var timestamps []int64
+		for _, c := range stream.responses[0].Chunkseries[0].Chunks {
+			ch, err := chunk.NewForEncoding(chunk.Encoding(c.Encoding))
+			require.NoError(t, err)
+			require.NoError(t, ch.UnmarshalFromBuf(c.Data))
+
+			it := chunk.PrometheusChunk(ch).Iterator(nil)
+			for valType := it.Next(); valType != chunkenc.ValNone; valType = it.Next() {
+				if valType == chunkenc.ValHistogram {
+					ts, h := it.AtHistogram()
+					assert.Equal(t, uint64(5), h.Count)
+					timestamps = append(timestamps, ts)
+				}
+			}
+			require.NoError(t, it.Err())
+		}
+		assert.Equal(t, []int64{1000, 2000}, timestamps)
+	})
+}
+
 func writeRequestSingleSeries(lbls labels.Labels, samples []mimirpb.Sample) *mimirpb.WriteRequest {
 	req := &mimirpb.WriteRequest{
 		Source: mimirpb.API,
@@ -2746,6 +2830,17 @@ func (m *mockQueryStreamServer) Context() context.Context {
 	return m.ctx
 }
 
+// capturingQueryStreamServer keeps the responses sent to the stream.
+type capturingQueryStreamServer struct {
+	mockQueryStreamServer
+	responses []*client.QueryStreamResponse
+}
+
+func (m *capturingQueryStreamServer) Send(response *client.QueryStreamResponse) error {
+	m.responses = append(m.responses, response)
+	return nil
+}
+
 func BenchmarkIngester_QueryStream(b *testing.B) {
 	const (
 		numSeries       = 25000 // Number of series to push.
@@ -7209,6 +7304,47 @@ func TestIngesterCompactionTruncatesEphemeralStorage(t *testing.T) {
 	require.Greater(t, newMinT, oldMinT)
 }
 
+func TestIngesterEphemeralStorageRetentionPerTenant(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.BlocksStorageConfig.EphemeralTSDB.Retention = 10 * time.Minute
+	cfg.IngesterRing.ReplicationFactor = 1 // for computing limits.
+
+	limits := defaultLimitsTestConfig()
+	limits.MaxEphemeralSeriesPerUser = 1
+	limits.EphemeralSeriesRetentionPeriod = model.Duration(time.Hour)
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), i)
+	})
+
+	// Wait until it's healthy
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	db, err := i.getOrCreateTSDB(userID, false)
+	require.NoError(t, err)
+
+	now := time.Now()
+	eph, err := db.createEphemeralStorage()
+	require.NoError(t, err)
+
+	// The tenant's retention overrides the retention of the ephemeral storage.
+	minT, ok := eph.AppendableMinValidTime()
+	require.True(t, ok)
+	require.GreaterOrEqual(t, minT, now.Add(-time.Hour).UnixMilli())
+	require.Less(t, minT, now.Add(-time.Hour+time.Minute).UnixMilli())
+
+	require.NoError(t, db.TruncateEphemeral(now.Add(time.Hour)))
+	minT, ok = eph.AppendableMinValidTime()
+	require.True(t, ok)
+	require.Equal(t, now.UnixMilli(), minT)
+}
+
 func TestIngesterQueryingWithStorageLabelErrorHandling(t *testing.T) {
 	cfg := defaultIngesterTestConfig(t)
 
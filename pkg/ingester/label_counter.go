@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/index"
+)
+
+// selectorSeries is the number of in-memory series matching a selector.
+type selectorSeries struct {
+	selector string
+	matchers []*labels.Matcher
+	series   int
+}
+
+// labelCounter counts the in-memory series of a tenant per value of the label names whose number of values is
+// limited, and per selector whose number of series is limited, to enforce these limits when series are created.
+// The counted label names and selectors are the ones of the limits, and are updated with reload() when the
+// limits change.
+type labelCounter struct {
+	limiter *Limiter
+
+	mtx sync.Mutex
+	// Sorted label names and selectors of the limits the counters have been built for.
+	labelNames    []string
+	selectorNames []string
+	// Number of series per label value, keyed by label name.
+	labelValues map[string]map[string]int
+	selectors   []*selectorSeries
+}
+
+// newLabelCounter returns a counter of the given sorted label names and selectors.
+func newLabelCounter(limiter *Limiter, labelNames []string, selectors []string) *labelCounter {
+	c := &labelCounter{limiter: limiter}
+	c.reset(labelNames, selectors)
+	return c
+}
+
+// reset replaces the counted label names and selectors, and resets the counters. The invalid selectors are ignored,
+// because they're rejected by the limits validation.
+func (c *labelCounter) reset(labelNames []string, selectors []string) {
+	c.labelNames, c.selectorNames = labelNames, selectors
+
+	c.labelValues = make(map[string]map[string]int, len(labelNames))
+	for _, name := range labelNames {
+		c.labelValues[name] = map[string]int{}
+	}
+
+	c.selectors = make([]*selectorSeries, 0, len(selectors))
+	for _, selector := range selectors {
+		matchers, err := parser.ParseMetricSelector(selector)
+		if err != nil {
+			continue
+		}
+		c.selectors = append(c.selectors, &selectorSeries{selector: selector, matchers: matchers})
+	}
+}
+
+// changed returns whether the sorted label names and selectors differ from the counted ones.
+func (c *labelCounter) changed(labelNames []string, selectors []string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return !equalStrings(c.labelNames, labelNames) || !equalStrings(c.selectorNames, selectors)
+}
+
+// reload replaces the counted sorted label names and selectors, and counts the series of the index.
+func (c *labelCounter) reload(labelNames []string, selectors []string, idx tsdb.IndexReader) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.reset(labelNames, selectors)
+
+	for name, values := range c.labelValues {
+		indexValues, err := idx.SortedLabelValues(name)
+		if err != nil {
+			return err
+		}
+		for _, value := range indexValues {
+			p, err := idx.Postings(name, value)
+			if err != nil {
+				return err
+			}
+			if values[value], err = countPostings(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, s := range c.selectors {
+		p, err := tsdb.PostingsForMatchers(idx, s.matchers...)
+		if err != nil {
+			return err
+		}
+		if s.series, err = countPostings(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canAddSeries returns an error if the series would exceed the limit of values of one of its label names,
+// or the limit of series of one of the selectors it matches.
+func (c *labelCounter) canAddSeries(userID string, metric labels.Labels) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for name, values := range c.labelValues {
+		value := metric.Get(name)
+		if value == "" {
+			continue
+		}
+		if _, ok := values[value]; ok {
+			continue
+		}
+		if err := c.limiter.AssertMaxLabelValuesPerLabelName(userID, name, len(values)); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range c.selectors {
+		if !matchesAll(s.matchers, metric) {
+			continue
+		}
+		if err := c.limiter.AssertMaxSeriesPerSelector(userID, s.selector, s.series); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *labelCounter) increaseSeries(metric labels.Labels) {
+	c.updateSeries(metric, 1)
+}
+
+func (c *labelCounter) decreaseSeries(metric labels.Labels) {
+	c.updateSeries(metric, -1)
+}
+
+func (c *labelCounter) updateSeries(metric labels.Labels, delta int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for name, values := range c.labelValues {
+		value := metric.Get(name)
+		if value == "" {
+			continue
+		}
+		values[value] += delta
+		if values[value] <= 0 {
+			delete(values, value)
+		}
+	}
+
+	for _, s := range c.selectors {
+		if matchesAll(s.matchers, metric) {
+			s.series += delta
+		}
+	}
+}
+
+func matchesAll(matchers []*labels.Matcher, metric labels.Labels) bool {
+	for _, m := range matchers {
+		if !m.Matches(metric.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func countPostings(p index.Postings) (int, error) {
+	count := 0
+	for p.Next() {
+		count++
+	}
+	return count, errors.Wrap(p.Err(), "failed to count postings")
+}
+
+// limitedKeys returns the sorted keys of the limits which are enabled.
+func limitedKeys(limits map[string]int) []string {
+	keys := make([]string, 0, len(limits))
+	for k, limit := range limits {
+		if limit > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
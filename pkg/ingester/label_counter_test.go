@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestLabelCounter(t *testing.T) {
+	ring := &ringCountMock{}
+	ring.On("HealthyInstancesCount").Return(1)
+	ring.On("ZonesCount").Return(1)
+
+	limits, err := validation.NewOverrides(validation.Limits{
+		MaxLabelValuesPerLabelName: validation.LabelValuesLimits{"pod": 2},
+		MaxGlobalSeriesPerSelector: validation.SeriesSelectorLimits{`{namespace="a"}`: 2},
+	}, nil)
+	require.NoError(t, err)
+
+	c := newLabelCounter(NewLimiter(limits, ring, 1, false), []string{"pod"}, []string{`{namespace="a"}`})
+
+	add := func(lbls labels.Labels) error {
+		if err := c.canAddSeries("test", lbls); err != nil {
+			return err
+		}
+		c.increaseSeries(lbls)
+		return nil
+	}
+
+	// The values of the limited label names are limited.
+	require.NoError(t, add(labels.FromStrings(labels.MetricName, "foo", "pod", "1")))
+	require.NoError(t, add(labels.FromStrings(labels.MetricName, "foo", "pod", "2")))
+	require.NoError(t, add(labels.FromStrings(labels.MetricName, "bar", "pod", "2")))
+	require.Equal(t, maxLabelValuesPerLabelNameLimitError("pod"), add(labels.FromStrings(labels.MetricName, "foo", "pod", "3")))
+
+	// The series without the limited label names aren't limited.
+	require.NoError(t, add(labels.FromStrings(labels.MetricName, "foo")))
+
+	// A value can be added once all the series of a value are deleted.
+	c.decreaseSeries(labels.FromStrings(labels.MetricName, "foo", "pod", "2"))
+	require.Error(t, add(labels.FromStrings(labels.MetricName, "foo", "pod", "3")))
+	c.decreaseSeries(labels.FromStrings(labels.MetricName, "bar", "pod", "2"))
+	require.NoError(t, add(labels.FromStrings(labels.MetricName, "foo", "pod", "3")))
+
+	// The series matching the limited selectors are limited.
+	require.NoError(t, add(labels.FromStrings(labels.MetricName, "foo", "namespace", "a", "instance", "1")))
+	require.NoError(t, add(labels.FromStrings(labels.MetricName, "foo", "namespace", "b", "instance", "2")))
+	require.NoError(t, add(labels.FromStrings(labels.MetricName, "foo", "namespace", "a", "instance", "3")))
+	require.Equal(t, maxSeriesPerSelectorLimitError(`{namespace="a"}`), add(labels.FromStrings(labels.MetricName, "foo", "namespace", "a", "instance", "4")))
+
+	assert.False(t, c.changed([]string{"pod"}, []string{`{namespace="a"}`}))
+	assert.True(t, c.changed(nil, []string{`{namespace="a"}`}))
+	assert.True(t, c.changed([]string{"pod"}, []string{`{namespace="b"}`}))
+}
+
+func TestIngester_Push_LabelLimits(t *testing.T) {
+	tenantLimits := defaultLimitsTestConfig()
+	tenantLimits.MaxLabelValuesPerLabelName = validation.LabelValuesLimits{"request_id": 2}
+	tenantLimits.MaxGlobalSeriesPerSelector = validation.SeriesSelectorLimits{`{namespace="a"}`: 1}
+
+	limitsMock := new(TenantLimitsMock)
+	limitsMock.On("ByUserID", userID).Return(&tenantLimits)
+	overrides, err := validation.NewOverrides(defaultLimitsTestConfig(), limitsMock)
+	require.NoError(t, err)
+
+	cfg := defaultIngesterTestConfig(t)
+	cfg.IngesterRing.ReplicationFactor = 1
+
+	reg := prometheus.NewPedanticRegistry()
+	ing, err := prepareIngesterWithBlockStorageAndOverrides(t, cfg, overrides, "", reg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	test.Poll(t, time.Second, 1, func() interface{} {
+		return ing.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	push := func(lbls labels.Labels) error {
+		_, err := ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{lbls}, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API))
+		return err
+	}
+	requireLimitError := func(err error, lbls labels.Labels, cause error) {
+		httpResp, ok := httpgrpc.HTTPResponseFromError(err)
+		require.True(t, ok, "returned error is not an httpgrpc response")
+		assert.Equal(t, http.StatusBadRequest, int(httpResp.Code))
+		assert.Equal(t, wrapWithUser(makeMetricLimitError(lbls, ing.limiter.FormatError(userID, cause)), userID).Error(), string(httpResp.Body))
+	}
+
+	require.NoError(t, push(labels.FromStrings(labels.MetricName, "requests", "request_id", "1")))
+	require.NoError(t, push(labels.FromStrings(labels.MetricName, "requests", "request_id", "2")))
+	rejected := labels.FromStrings(labels.MetricName, "requests", "request_id", "3")
+	requireLimitError(push(rejected), rejected, maxLabelValuesPerLabelNameLimitError("request_id"))
+
+	require.NoError(t, push(labels.FromStrings(labels.MetricName, "up", "namespace", "a", "pod", "1")))
+	require.NoError(t, push(labels.FromStrings(labels.MetricName, "up", "namespace", "b", "pod", "2")))
+	rejected = labels.FromStrings(labels.MetricName, "up", "namespace", "a", "pod", "3")
+	requireLimitError(push(rejected), rejected, maxSeriesPerSelectorLimitError(`{namespace="a"}`))
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="per_label_name_values_limit",user="1"} 1
+		cortex_discarded_samples_total{group="",reason="per_selector_series_limit",user="1"} 1
+	`), "cortex_discarded_samples_total"))
+
+	// The existing series are counted when the limited label names and selectors change.
+	tenantLimits.MaxLabelValuesPerLabelName = validation.LabelValuesLimits{"pod": 2}
+	tenantLimits.MaxGlobalSeriesPerSelector = validation.SeriesSelectorLimits{`{__name__="requests"}`: 3}
+	ing.applyTSDBSettings()
+
+	rejected = labels.FromStrings(labels.MetricName, "up", "pod", "3")
+	requireLimitError(push(rejected), rejected, maxLabelValuesPerLabelNameLimitError("pod"))
+	require.NoError(t, push(labels.FromStrings(labels.MetricName, "requests", "request_id", "3")))
+	rejected = labels.FromStrings(labels.MetricName, "requests", "request_id", "4")
+	requireLimitError(push(rejected), rejected, maxSeriesPerSelectorLimitError(`{__name__="requests"}`))
+}
@@ -26,6 +26,22 @@ var (
 	errMaxMetadataPerUserLimitExceeded        = errors.New("per-user metric metadata limit exceeded")
 )
 
+// maxLabelValuesPerLabelNameLimitError is returned when a series would add a value to a label name which already
+// has the maximum number of values. It's the label name.
+type maxLabelValuesPerLabelNameLimitError string
+
+func (e maxLabelValuesPerLabelNameLimitError) Error() string {
+	return fmt.Sprintf("per-label-name values limit of label name %s exceeded", string(e))
+}
+
+// maxSeriesPerSelectorLimitError is returned when a series would exceed the maximum number of series matching
+// a selector. It's the selector.
+type maxSeriesPerSelectorLimitError string
+
+func (e maxSeriesPerSelectorLimitError) Error() string {
+	return fmt.Sprintf("per-selector series limit of selector %s exceeded", string(e))
+}
+
 // RingCount is the interface exposed by a ring implementation which allows
 // to count members
 type RingCount interface {
@@ -67,6 +83,26 @@ func (l *Limiter) AssertMaxSeriesPerMetric(userID string, series int) error {
 	return errMaxSeriesPerMetricLimitExceeded
 }
 
+// AssertMaxLabelValuesPerLabelName limit has not been reached compared to the current
+// number of values of the label name in input and returns an error if so.
+func (l *Limiter) AssertMaxLabelValuesPerLabelName(userID, labelName string, values int) error {
+	if actualLimit := l.maxLabelValuesPerLabelName(userID, labelName); values < actualLimit {
+		return nil
+	}
+
+	return maxLabelValuesPerLabelNameLimitError(labelName)
+}
+
+// AssertMaxSeriesPerSelector limit has not been reached compared to the current
+// number of series matching the selector in input and returns an error if so.
+func (l *Limiter) AssertMaxSeriesPerSelector(userID, selector string, series int) error {
+	if actualLimit := l.maxSeriesPerSelector(userID, selector); series < actualLimit {
+		return nil
+	}
+
+	return maxSeriesPerSelectorLimitError(selector)
+}
+
 // AssertMaxMetadataPerMetric limit has not been reached compared to the current
 // number of metadata per metric in input and returns an error if so.
 func (l *Limiter) AssertMaxMetadataPerMetric(userID string, metadata int) error {
@@ -110,6 +146,14 @@ func (l *Limiter) AssertMaxMetricsWithMetadataPerUser(userID string, metrics int
 // FormatError returns the input error enriched with the actual limits for the given user.
 // It acts as pass-through if the input error is unknown.
 func (l *Limiter) FormatError(userID string, err error) error {
+	//nolint:errorlint // We don't expect wrapped errors.
+	switch e := err.(type) {
+	case maxLabelValuesPerLabelNameLimitError:
+		return l.formatMaxLabelValuesPerLabelNameError(userID, string(e))
+	case maxSeriesPerSelectorLimitError:
+		return l.formatMaxSeriesPerSelectorError(userID, string(e))
+	}
+
 	//nolint:errorlint // We don't expect wrapped errors.
 	switch err {
 	case errMaxSeriesPerUserLimitExceeded:
@@ -145,6 +189,24 @@ func (l *Limiter) formatMaxSeriesPerMetricError(userID string) error {
 	))
 }
 
+func (l *Limiter) formatMaxLabelValuesPerLabelNameError(userID, labelName string) error {
+	limit := l.limits.MaxLabelValuesPerLabelName(userID)[labelName]
+
+	return errors.New(globalerror.MaxLabelValuesPerLabelName.MessageWithPerTenantRuntimeConfig(
+		fmt.Sprintf("per-label-name values limit of %d exceeded for label name %s", limit, labelName),
+		"max_label_values_per_label_name",
+	))
+}
+
+func (l *Limiter) formatMaxSeriesPerSelectorError(userID, selector string) error {
+	globalLimit := l.limits.MaxGlobalSeriesPerSelector(userID)[selector]
+
+	return errors.New(globalerror.MaxSeriesPerSelector.MessageWithPerTenantRuntimeConfig(
+		fmt.Sprintf("per-selector series limit of %d exceeded for selector %s", globalLimit, selector),
+		"max_global_series_per_selector",
+	))
+}
+
 func (l *Limiter) formatMaxEphemeralSeriesPerUserError(userID string) error {
 	globalLimit := l.limits.MaxEphemeralSeriesPerUser(userID)
 
@@ -176,6 +238,22 @@ func (l *Limiter) maxSeriesPerMetric(userID string) int {
 	return l.convertGlobalToLocalLimitOrUnlimited(userID, l.limits.MaxGlobalSeriesPerMetric)
 }
 
+func (l *Limiter) maxLabelValuesPerLabelName(userID, labelName string) int {
+	// Unlike the series, the values of a label name aren't spread across the ingesters, because the series
+	// with the same value are sharded to any ingester, so the limit isn't divided by the number of
+	// ingesters. An ingester only reaches it once the tenant has at least that number of values overall.
+	if limit := l.limits.MaxLabelValuesPerLabelName(userID)[labelName]; limit > 0 {
+		return limit
+	}
+	return math.MaxInt32
+}
+
+func (l *Limiter) maxSeriesPerSelector(userID, selector string) int {
+	return l.convertGlobalToLocalLimitOrUnlimited(userID, func(userID string) int {
+		return l.limits.MaxGlobalSeriesPerSelector(userID)[selector]
+	})
+}
+
 func (l *Limiter) maxMetadataPerMetric(userID string) int {
 	return l.convertGlobalToLocalLimitOrUnlimited(userID, l.limits.MaxGlobalMetadataPerMetric)
 }
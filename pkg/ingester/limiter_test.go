@@ -232,6 +232,30 @@ func TestLimiter_AssertMaxSeriesPerMetric(t *testing.T) {
 		})
 	}
 }
+func TestLimiter_AssertMaxLabelValuesPerLabelNameAndSeriesPerSelector(t *testing.T) {
+	ring := &ringCountMock{}
+	ring.On("HealthyInstancesCount").Return(10)
+	ring.On("ZonesCount").Return(1)
+
+	limits, err := validation.NewOverrides(validation.Limits{
+		MaxLabelValuesPerLabelName: validation.LabelValuesLimits{"pod": 1000, "disabled": 0},
+		MaxGlobalSeriesPerSelector: validation.SeriesSelectorLimits{`{namespace="a"}`: 1000},
+	}, nil)
+	require.NoError(t, err)
+
+	limiter := NewLimiter(limits, ring, 3, false)
+
+	// The label values limit isn't divided by the number of ingesters.
+	assert.NoError(t, limiter.AssertMaxLabelValuesPerLabelName("test", "pod", 999))
+	assert.Equal(t, maxLabelValuesPerLabelNameLimitError("pod"), limiter.AssertMaxLabelValuesPerLabelName("test", "pod", 1000))
+	assert.NoError(t, limiter.AssertMaxLabelValuesPerLabelName("test", "disabled", 1000))
+	assert.NoError(t, limiter.AssertMaxLabelValuesPerLabelName("test", "other", 1000))
+
+	assert.NoError(t, limiter.AssertMaxSeriesPerSelector("test", `{namespace="a"}`, 299))
+	assert.Equal(t, maxSeriesPerSelectorLimitError(`{namespace="a"}`), limiter.AssertMaxSeriesPerSelector("test", `{namespace="a"}`, 300))
+	assert.NoError(t, limiter.AssertMaxSeriesPerSelector("test", `{namespace="b"}`, 300))
+}
+
 func TestLimiter_AssertMaxMetadataPerMetric(t *testing.T) {
 	tests := map[string]struct {
 		maxGlobalMetadataPerMetric int
@@ -406,6 +430,8 @@ func TestLimiter_FormatError(t *testing.T) {
 		MaxGlobalSeriesPerMetric:            20,
 		MaxGlobalMetricsWithMetadataPerUser: 10,
 		MaxGlobalMetadataPerMetric:          3,
+		MaxLabelValuesPerLabelName:          validation.LabelValuesLimits{"pod": 50},
+		MaxGlobalSeriesPerSelector:          validation.SeriesSelectorLimits{`{namespace="a"}`: 5},
 	}, nil)
 	require.NoError(t, err)
 
@@ -423,6 +449,12 @@ func TestLimiter_FormatError(t *testing.T) {
 	actual = limiter.FormatError("user-1", errMaxMetadataPerMetricLimitExceeded)
 	assert.ErrorContains(t, actual, "per-metric metadata limit of 3 exceeded")
 
+	actual = limiter.FormatError("user-1", maxLabelValuesPerLabelNameLimitError("pod"))
+	assert.ErrorContains(t, actual, "per-label-name values limit of 50 exceeded for label name pod")
+
+	actual = limiter.FormatError("user-1", maxSeriesPerSelectorLimitError(`{namespace="a"}`))
+	assert.ErrorContains(t, actual, `per-selector series limit of 5 exceeded for selector {namespace="a"}`)
+
 	input := errors.New("unknown error")
 	actual = limiter.FormatError("user-1", input)
 	assert.Equal(t, input, actual)
@@ -45,6 +45,10 @@ type ingesterMetrics struct {
 	activeSeriesPerUser               *prometheus.GaugeVec
 	activeSeriesCustomTrackersPerUser *prometheus.GaugeVec
 
+	// Cost attribution metrics
+	attributedActiveSeries *prometheus.GaugeVec
+	attributedSamples      *prometheus.CounterVec
+
 	// Global limit metrics
 	maxUsersGauge           prometheus.GaugeFunc
 	maxSeriesGauge          prometheus.GaugeFunc
@@ -62,6 +66,10 @@ type ingesterMetrics struct {
 	appenderCommitDuration prometheus.Histogram
 	idleTsdbChecks         *prometheus.CounterVec
 
+	// Hand-off metrics.
+	handOffSentSeries     prometheus.Counter
+	handOffReceivedSeries prometheus.Counter
+
 	discardedPersistent *discardedMetrics
 	discardedEphemeral  *discardedMetrics
 
@@ -288,6 +296,16 @@ func newIngesterMetrics(
 			Help: "Number of currently active series matching a pre-configured label matchers per user.",
 		}, []string{"user", "name"}),
 
+		// Not registered automatically, but only if activeSeriesEnabled is true.
+		attributedActiveSeries: promauto.With(activeSeriesReg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_ingester_attributed_active_series",
+			Help: "Number of currently active series per user and value of the cost attribution label.",
+		}, []string{"user", "attribution"}),
+		attributedSamples: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_attributed_samples_total",
+			Help: "The total number of samples ingested per user and value of the cost attribution label.",
+		}, []string{"user", "attribution"}),
+
 		compactionsTriggered: promauto.With(r).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_ingester_tsdb_compactions_triggered_total",
 			Help: "Total number of triggered compactions.",
@@ -315,6 +333,15 @@ func newIngesterMetrics(
 
 		idleTsdbChecks: idleTsdbChecks,
 
+		handOffSentSeries: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_hand_off_sent_series_total",
+			Help: "The total number of in-memory series handed off to another ingester on shutdown.",
+		}),
+		handOffReceivedSeries: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_hand_off_received_series_total",
+			Help: "The total number of in-memory series received from leaving ingesters.",
+		}),
+
 		discardedPersistent: newDiscardedMetrics(r, ""),
 		discardedEphemeral:  newDiscardedMetrics(r, ephemeralDiscardPrefix),
 
@@ -355,6 +382,17 @@ func (m *ingesterMetrics) deletePerUserCustomTrackerMetrics(userID string, custo
 	}
 }
 
+func (m *ingesterMetrics) deletePerUserCostAttributionMetrics(userID string) {
+	filter := prometheus.Labels{"user": userID}
+	m.attributedActiveSeries.DeletePartialMatch(filter)
+	m.attributedSamples.DeletePartialMatch(filter)
+}
+
+func (m *ingesterMetrics) deletePerAttributionMetrics(userID, attribution string) {
+	m.attributedActiveSeries.DeleteLabelValues(userID, attribution)
+	m.attributedSamples.DeleteLabelValues(userID, attribution)
+}
+
 type discardedMetrics struct {
 	sampleOutOfBounds    *prometheus.CounterVec
 	sampleOutOfOrder     *prometheus.CounterVec
@@ -362,6 +400,11 @@ type discardedMetrics struct {
 	newValueForTimestamp *prometheus.CounterVec
 	perUserSeriesLimit   *prometheus.CounterVec
 	perMetricSeriesLimit *prometheus.CounterVec
+
+	perLabelNameValuesLimit *prometheus.CounterVec
+	perSelectorSeriesLimit  *prometheus.CounterVec
+
+	invalidNativeHistogram *prometheus.CounterVec
 }
 
 func newDiscardedMetrics(r prometheus.Registerer, prefix string) *discardedMetrics {
@@ -372,6 +415,11 @@ func newDiscardedMetrics(r prometheus.Registerer, prefix string) *discardedMetri
 		newValueForTimestamp: validation.DiscardedSamplesCounter(r, prefix+newValueForTimestamp),
 		perUserSeriesLimit:   validation.DiscardedSamplesCounter(r, prefix+perUserSeriesLimit),
 		perMetricSeriesLimit: validation.DiscardedSamplesCounter(r, prefix+perMetricSeriesLimit),
+
+		perLabelNameValuesLimit: validation.DiscardedSamplesCounter(r, prefix+perLabelNameValuesLimit),
+		perSelectorSeriesLimit:  validation.DiscardedSamplesCounter(r, prefix+perSelectorSeriesLimit),
+
+		invalidNativeHistogram: validation.DiscardedSamplesCounter(r, prefix+invalidNativeHistogram),
 	}
 }
 
@@ -382,6 +430,9 @@ func (m *discardedMetrics) DeletePartialMatch(filter prometheus.Labels) {
 	m.newValueForTimestamp.DeletePartialMatch(filter)
 	m.perUserSeriesLimit.DeletePartialMatch(filter)
 	m.perMetricSeriesLimit.DeletePartialMatch(filter)
+	m.perLabelNameValuesLimit.DeletePartialMatch(filter)
+	m.perSelectorSeriesLimit.DeletePartialMatch(filter)
+	m.invalidNativeHistogram.DeletePartialMatch(filter)
 }
 
 func (m *discardedMetrics) DeleteLabelValues(userID string, group string) {
@@ -391,6 +442,9 @@ func (m *discardedMetrics) DeleteLabelValues(userID string, group string) {
 	m.newValueForTimestamp.DeleteLabelValues(userID, group)
 	m.perUserSeriesLimit.DeleteLabelValues(userID, group)
 	m.perMetricSeriesLimit.DeleteLabelValues(userID, group)
+	m.perLabelNameValuesLimit.DeleteLabelValues(userID, group)
+	m.perSelectorSeriesLimit.DeleteLabelValues(userID, group)
+	m.invalidNativeHistogram.DeleteLabelValues(userID, group)
 }
 
 // TSDB metrics collector. Each tenant has its own registry, that TSDB code uses.
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	runtime_metrics "runtime/metrics"
+)
+
+// heapObjectsMetric is the runtime metric of the memory occupied by the live and the unswept objects of the heap.
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// pushPressure returns the load of the ingester, as the highest ratio of the in-flight push requests, the ingestion
+// rate and the in-memory series to their instance limits, and of the heap size to -ingester.push-pressure-heap-limit-bytes.
+// The limits set to 0 are ignored. The pressure is 1 or more when the ingester reaches one of the limits.
+func (i *Ingester) pushPressure() float64 {
+	pressure := 0.0
+	ratio := func(value, limit float64) {
+		if limit > 0 && value/limit > pressure {
+			pressure = value / limit
+		}
+	}
+
+	if il := i.getInstanceLimits(); il != nil {
+		ratio(float64(i.inflightPushRequests.Load()), float64(il.MaxInflightPushRequests))
+		ratio(i.ingestionRate.Rate(), il.MaxIngestionRate)
+		ratio(float64(i.persistentSeriesCount.Load()), float64(il.MaxInMemorySeries))
+	}
+	ratio(float64(i.heapBytes.Load()), float64(i.cfg.PushPressureHeapLimitBytes))
+
+	return pressure
+}
+
+// updateHeapBytes samples the heap size, if it's used to compute the push pressure.
+func (i *Ingester) updateHeapBytes() {
+	if i.cfg.PushPressureHeapLimitBytes == 0 {
+		return
+	}
+
+	sample := []runtime_metrics.Sample{{Name: heapObjectsMetric}}
+	runtime_metrics.Read(sample)
+	if sample[0].Value.Kind() == runtime_metrics.KindUint64 {
+		i.heapBytes.Store(sample[0].Value.Uint64())
+	}
+}
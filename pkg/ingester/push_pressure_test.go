@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngester_pushPressure(t *testing.T) {
+	limits := InstanceLimits{}
+
+	cfg := defaultIngesterTestConfig(t)
+	cfg.InstanceLimitsFn = func() *InstanceLimits { return &limits }
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	test.Poll(t, 100*time.Millisecond, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	// The pressure is 0 without limits.
+	i.inflightPushRequests.Store(10)
+	i.persistentSeriesCount.Store(100)
+	assert.Equal(t, 0.0, i.pushPressure())
+
+	// The pressure is the highest ratio to the limits.
+	limits.MaxInflightPushRequests = 40
+	assert.Equal(t, 0.25, i.pushPressure())
+
+	limits.MaxInMemorySeries = 200
+	assert.Equal(t, 0.5, i.pushPressure())
+
+	// The pressure exceeds 1 when a limit is exceeded.
+	i.cfg.PushPressureHeapLimitBytes = 1
+	i.updateHeapBytes()
+	assert.Greater(t, i.pushPressure(), 1.0)
+}
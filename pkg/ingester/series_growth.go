@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+)
+
+var errSeriesGrowthTrackingDisabled = errors.New("the series growth tracking is disabled in the ingesters")
+
+type labelPair struct {
+	name, value string
+}
+
+// seriesGrowthCounts is the number of series created per metric name and per label pair within a window.
+type seriesGrowthCounts struct {
+	metricNames map[string]uint64
+	labelPairs  map[labelPair]uint64
+}
+
+func newSeriesGrowthCounts() *seriesGrowthCounts {
+	return &seriesGrowthCounts{
+		metricNames: map[string]uint64{},
+		labelPairs:  map[labelPair]uint64{},
+	}
+}
+
+// seriesGrowthTracker tracks the series created by a tenant per metric name and per label pair within a sliding
+// window, to find the cardinality explosions as they happen. The series created within the window are estimated
+// from the counts of the current and of the previous fixed windows, weighting the previous window by the share of
+// it which is still within the sliding window.
+type seriesGrowthTracker struct {
+	window time.Duration
+	// Maximum number of metric names and of label pairs tracked in each fixed window.
+	maxEntries int
+
+	mtx          sync.Mutex
+	currentStart time.Time
+	current      *seriesGrowthCounts
+	previous     *seriesGrowthCounts
+}
+
+func newSeriesGrowthTracker(window time.Duration, maxEntries int, now time.Time) *seriesGrowthTracker {
+	return &seriesGrowthTracker{
+		window:       window,
+		maxEntries:   maxEntries,
+		currentStart: now,
+		current:      newSeriesGrowthCounts(),
+		previous:     newSeriesGrowthCounts(),
+	}
+}
+
+// increaseSeries counts a created series. The metric names and label pairs which aren't tracked yet in the current
+// window are ignored once the maximum number of tracked entries is reached.
+func (t *seriesGrowthTracker) increaseSeries(metric labels.Labels, now time.Time) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.rotate(now)
+
+	metric.Range(func(l labels.Label) {
+		if l.Name == labels.MetricName {
+			if _, ok := t.current.metricNames[l.Value]; ok || len(t.current.metricNames) < t.maxEntries {
+				t.current.metricNames[l.Value]++
+			}
+			return
+		}
+
+		pair := labelPair{name: l.Name, value: l.Value}
+		if _, ok := t.current.labelPairs[pair]; ok || len(t.current.labelPairs) < t.maxEntries {
+			t.current.labelPairs[pair]++
+		}
+	})
+}
+
+// rotate starts a new fixed window when the current one is over. Must be called with the lock held.
+func (t *seriesGrowthTracker) rotate(now time.Time) {
+	elapsed := now.Sub(t.currentStart)
+	if elapsed < t.window {
+		return
+	}
+
+	if elapsed < 2*t.window {
+		t.previous = t.current
+		t.currentStart = t.currentStart.Add(t.window)
+	} else {
+		t.previous = newSeriesGrowthCounts()
+		t.currentStart = now
+	}
+	t.current = newSeriesGrowthCounts()
+}
+
+// top returns the limit metric names and label pairs for which the most series have been created within the
+// sliding window, sorted by decreasing number of created series.
+func (t *seriesGrowthTracker) top(limit int, now time.Time) (metricNames, labelPairs []*client.SeriesGrowth) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.rotate(now)
+
+	previousWeight := 1 - float64(now.Sub(t.currentStart))/float64(t.window)
+	estimate := func(current, previous uint64) uint64 {
+		return current + uint64(math.Round(float64(previous)*previousWeight))
+	}
+
+	for name, count := range t.current.metricNames {
+		metricNames = append(metricNames, &client.SeriesGrowth{LabelName: labels.MetricName, LabelValue: name, CreatedSeries: estimate(count, t.previous.metricNames[name])})
+	}
+	for name, count := range t.previous.metricNames {
+		if _, ok := t.current.metricNames[name]; !ok {
+			metricNames = append(metricNames, &client.SeriesGrowth{LabelName: labels.MetricName, LabelValue: name, CreatedSeries: estimate(0, count)})
+		}
+	}
+
+	for pair, count := range t.current.labelPairs {
+		labelPairs = append(labelPairs, &client.SeriesGrowth{LabelName: pair.name, LabelValue: pair.value, CreatedSeries: estimate(count, t.previous.labelPairs[pair])})
+	}
+	for pair, count := range t.previous.labelPairs {
+		if _, ok := t.current.labelPairs[pair]; !ok {
+			labelPairs = append(labelPairs, &client.SeriesGrowth{LabelName: pair.name, LabelValue: pair.value, CreatedSeries: estimate(0, count)})
+		}
+	}
+
+	return topSeriesGrowth(metricNames, limit), topSeriesGrowth(labelPairs, limit)
+}
+
+// topSeriesGrowth sorts the items by decreasing number of created series, and returns the limit first non-zero ones.
+func topSeriesGrowth(items []*client.SeriesGrowth, limit int) []*client.SeriesGrowth {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CreatedSeries != items[j].CreatedSeries {
+			return items[i].CreatedSeries > items[j].CreatedSeries
+		}
+		if items[i].LabelName != items[j].LabelName {
+			return items[i].LabelName < items[j].LabelName
+		}
+		return items[i].LabelValue < items[j].LabelValue
+	})
+
+	for idx, item := range items {
+		if item.CreatedSeries == 0 {
+			items = items[:idx]
+			break
+		}
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// ActiveGrowth implements client.IngesterServer. It returns the metric names and label pairs for which the most
+// series have been created within the series growth tracking window.
+func (i *Ingester) ActiveGrowth(ctx context.Context, req *client.ActiveGrowthRequest) (*client.ActiveGrowthResponse, error) {
+	if err := i.checkRunning(); err != nil {
+		return nil, err
+	}
+	if !i.cfg.SeriesGrowthTrackingEnabled {
+		return nil, errSeriesGrowthTrackingDisabled
+	}
+
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db := i.getTSDB(userID)
+	if db == nil || db.seriesGrowth == nil {
+		return &client.ActiveGrowthResponse{}, nil
+	}
+
+	metricNames, labelPairs := db.seriesGrowth.top(int(req.GetLimit()), time.Now())
+	return &client.ActiveGrowthResponse{MetricNames: metricNames, LabelPairs: labelPairs}, nil
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestSeriesGrowthTracker(t *testing.T) {
+	now := time.Now()
+	tracker := newSeriesGrowthTracker(time.Hour, 3, now)
+
+	metricName := func(name string, created uint64) *client.SeriesGrowth {
+		return &client.SeriesGrowth{LabelName: labels.MetricName, LabelValue: name, CreatedSeries: created}
+	}
+	labelPair := func(name, value string, created uint64) *client.SeriesGrowth {
+		return &client.SeriesGrowth{LabelName: name, LabelValue: value, CreatedSeries: created}
+	}
+
+	for i := 0; i < 4; i++ {
+		tracker.increaseSeries(labels.FromStrings(labels.MetricName, "foo", "job", "a"), now)
+	}
+	tracker.increaseSeries(labels.FromStrings(labels.MetricName, "bar", "job", "b"), now)
+	// The label pairs which aren't tracked yet are ignored once the limit is reached.
+	tracker.increaseSeries(labels.FromStrings(labels.MetricName, "bar", "job", "c", "pod", "1"), now)
+
+	metricNames, labelPairs := tracker.top(10, now)
+	assert.Equal(t, []*client.SeriesGrowth{metricName("foo", 4), metricName("bar", 2)}, metricNames)
+	assert.Equal(t, []*client.SeriesGrowth{labelPair("job", "a", 4), labelPair("job", "b", 1), labelPair("job", "c", 1)}, labelPairs)
+
+	metricNames, labelPairs = tracker.top(1, now)
+	assert.Equal(t, []*client.SeriesGrowth{metricName("foo", 4)}, metricNames)
+	assert.Equal(t, []*client.SeriesGrowth{labelPair("job", "a", 4)}, labelPairs)
+
+	// The series created in the previous window are weighted by the share of the previous window within the sliding window.
+	now = now.Add(90 * time.Minute)
+	tracker.increaseSeries(labels.FromStrings(labels.MetricName, "baz", "pod", "1"), now)
+
+	metricNames, labelPairs = tracker.top(10, now)
+	assert.Equal(t, []*client.SeriesGrowth{metricName("foo", 2), metricName("bar", 1), metricName("baz", 1)}, metricNames)
+	assert.Equal(t, []*client.SeriesGrowth{labelPair("job", "a", 2), labelPair("job", "b", 1), labelPair("job", "c", 1), labelPair("pod", "1", 1)}, labelPairs)
+
+	// The series created before the sliding window aren't counted anymore.
+	now = now.Add(2 * time.Hour)
+	metricNames, labelPairs = tracker.top(10, now)
+	assert.Empty(t, metricNames)
+	assert.Empty(t, labelPairs)
+}
+
+func TestIngester_ActiveGrowth(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.SeriesGrowthTrackingEnabled = true
+
+	ing, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	// The tenant has no TSDB yet.
+	res, err := ing.ActiveGrowth(ctx, &client.ActiveGrowthRequest{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, &client.ActiveGrowthResponse{}, res)
+
+	for _, lbls := range []labels.Labels{
+		labels.FromStrings(labels.MetricName, "foo", "pod", "1"),
+		labels.FromStrings(labels.MetricName, "foo", "pod", "2"),
+		labels.FromStrings(labels.MetricName, "bar", "pod", "1"),
+	} {
+		_, err := ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{lbls}, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API))
+		require.NoError(t, err)
+	}
+	// The samples of existing series don't create series.
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{labels.FromStrings(labels.MetricName, "bar", "pod", "1")}, []mimirpb.Sample{{TimestampMs: 2, Value: 1}}, nil, nil, mimirpb.API))
+	require.NoError(t, err)
+
+	res, err = ing.ActiveGrowth(ctx, &client.ActiveGrowthRequest{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, &client.ActiveGrowthResponse{
+		MetricNames: []*client.SeriesGrowth{
+			{LabelName: labels.MetricName, LabelValue: "foo", CreatedSeries: 2},
+			{LabelName: labels.MetricName, LabelValue: "bar", CreatedSeries: 1},
+		},
+		LabelPairs: []*client.SeriesGrowth{
+			{LabelName: "pod", LabelValue: "1", CreatedSeries: 2},
+			{LabelName: "pod", LabelValue: "2", CreatedSeries: 1},
+		},
+	}, res)
+}
+
+func TestIngester_ActiveGrowth_Disabled(t *testing.T) {
+	ing, err := prepareIngesterWithBlocksStorage(t, defaultIngesterTestConfig(t), nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	_, err = ing.ActiveGrowth(user.InjectOrgID(context.Background(), userID), &client.ActiveGrowthRequest{Limit: 10})
+	require.ErrorIs(t, err, errSeriesGrowthTrackingDisabled)
+}
@@ -59,11 +59,17 @@ type userTSDB struct {
 	userID         string
 	activeSeries   *activeseries.ActiveSeries
 	seriesInMetric *metricCounter
+	seriesInLabels *labelCounter
 	limiter        *Limiter
+	// Attributes the series to the values of the cost attribution label.
+	costAttribution *costAttributionTracker
+	// Nil if the series growth tracking is disabled.
+	seriesGrowth *seriesGrowthTracker
 
 	// Function that creates ephemeral storage (*tsdb.Head) for the user.
-	ephemeralFactory               func() (*tsdb.Head, error)
-	ephemeralSeriesRetentionPeriod time.Duration
+	ephemeralFactory func() (*tsdb.Head, error)
+	// Function that returns the retention of the ephemeral series of the user.
+	ephemeralSeriesRetentionPeriod func() time.Duration
 
 	ephemeralMtx     sync.RWMutex
 	ephemeralStorage *tsdb.Head
@@ -213,7 +219,7 @@ func (u *userTSDB) Close() error {
 func (u *userTSDB) TruncateEphemeral(now time.Time) error {
 	eph := u.getEphemeralStorage()
 	if eph != nil {
-		return eph.Truncate(now.Add(-u.ephemeralSeriesRetentionPeriod).UnixMilli())
+		return eph.Truncate(now.Add(-u.ephemeralSeriesRetentionPeriod()).UnixMilli())
 	}
 	return nil
 }
@@ -273,6 +279,17 @@ func (u *userTSDB) compactHead(blockDuration int64) error {
 	return u.db.CompactOOOHead()
 }
 
+// reloadSeriesInLabels counts the in-memory series of the given sorted label names and selectors, whose limits changed.
+func (u *userTSDB) reloadSeriesInLabels(labelNames []string, selectors []string) error {
+	idx, err := u.Head().Index()
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	return u.seriesInLabels.reload(labelNames, selectors, idx)
+}
+
 func (u *userTSDB) persistentSeriesCallback() tsdb.SeriesLifecycleCallback {
 	return seriesLifecycleCallback{
 		preCreation:  u.persistentPreCreation,
@@ -308,6 +325,11 @@ func (u *userTSDB) persistentPreCreation(metric labels.Labels) error {
 		return err
 	}
 
+	// Values per label name and series per selector limits.
+	if err := u.seriesInLabels.canAddSeries(u.userID, metric); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -320,6 +342,11 @@ func (u *userTSDB) persistentPostCreation(metric labels.Labels) {
 		return
 	}
 	u.seriesInMetric.increaseSeriesForMetric(metricName)
+	u.seriesInLabels.increaseSeries(metric)
+
+	if u.seriesGrowth != nil {
+		u.seriesGrowth.increaseSeries(metric, time.Now())
+	}
 }
 
 func (u *userTSDB) persistentPostDeletion(metrics ...labels.Labels) {
@@ -332,6 +359,7 @@ func (u *userTSDB) persistentPostDeletion(metrics ...labels.Labels) {
 			continue
 		}
 		u.seriesInMetric.decreaseSeriesForMetric(metricName)
+		u.seriesInLabels.decreaseSeries(metric)
 	}
 }
 
@@ -23,7 +23,6 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
-	"github.com/grafana/dskit/cache"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/services"
@@ -88,7 +87,7 @@ func TestMimir(t *testing.T) {
 				ChunkPoolMinBucketSizeBytes: tsdb.ChunkPoolDefaultMinBucketSize,
 				ChunkPoolMaxBucketSizeBytes: tsdb.ChunkPoolDefaultMaxBucketSize,
 				IndexCache: tsdb.IndexCacheConfig{
-					BackendConfig: cache.BackendConfig{
+					CacheBackendConfig: tsdb.CacheBackendConfig{
 						Backend: tsdb.IndexCacheBackendInMemory,
 					},
 				},
@@ -29,6 +29,7 @@ import (
 	"github.com/prometheus/prometheus/rules"
 	prom_storage "github.com/prometheus/prometheus/storage"
 	prom_remote "github.com/prometheus/prometheus/storage/remote"
+	"github.com/thanos-io/objstore"
 	httpgrpc_server "github.com/weaveworks/common/httpgrpc/server"
 	"github.com/weaveworks/common/server"
 
@@ -42,6 +43,7 @@ import (
 	"github.com/grafana/mimir/pkg/frontend/querymiddleware"
 	"github.com/grafana/mimir/pkg/frontend/transport"
 	"github.com/grafana/mimir/pkg/ingester"
+	"github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/querier"
 	"github.com/grafana/mimir/pkg/querier/engine"
 	"github.com/grafana/mimir/pkg/querier/tenantfederation"
@@ -49,9 +51,11 @@ import (
 	"github.com/grafana/mimir/pkg/ruler"
 	"github.com/grafana/mimir/pkg/scheduler"
 	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/activequeries"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -79,6 +83,7 @@ const (
 	Querier                    string = "querier"
 	Queryable                  string = "queryable"
 	StoreQueryable             string = "store-queryable"
+	TombstonesLoader           string = "tombstones-loader"
 	QueryFrontend              string = "query-frontend"
 	QueryFrontendTripperware   string = "query-frontend-tripperware"
 	RulerStorage               string = "ruler-storage"
@@ -330,6 +335,7 @@ func (t *Mimir) initQueryable() (serv services.Service, err error) {
 
 	// Create a querier queryable and PromQL engine
 	t.QuerierQueryable, t.ExemplarQueryable, t.QuerierEngine = querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.StoreQueryables, querierRegisterer, util_log.Logger, t.ActivityTracker)
+	t.QuerierQueryable = querier.NewTombstonesQueryable(t.QuerierQueryable, t.TombstonesLoader)
 
 	// Use the distributor to return metric metadata by default
 	t.MetadataSupplier = t.Distributor
@@ -421,6 +427,15 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 		t.Overrides,
 	)
 
+	// Report the results cache generation used to filter out the deleted series, so that the query-frontend
+	// doesn't cache the results filtered with outdated series deletion requests.
+	internalQuerierRouter = querier.NewResultsCacheGenHandler(internalQuerierRouter, t.TombstonesLoader)
+
+	// Track the queries executed by the querier, so that they can be listed and canceled.
+	activeQueries := activequeries.NewTracker()
+	internalQuerierRouter = activeQueries.Wrap(internalQuerierRouter)
+	t.API.RegisterActiveQueries("/querier", activeQueries)
+
 	// If the querier is running standalone without the query-frontend or query-scheduler, we must register it's internal
 	// HTTP handler externally and provide the external Mimir Server HTTP handler to the frontend worker
 	// to ensure requests it processes use the default middleware instrumentation.
@@ -460,6 +475,14 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 	return querier_worker.NewQuerierWorker(t.Cfg.Worker, httpgrpc_server.NewServer(internalQuerierRouter), util_log.Logger, t.Registerer)
 }
 
+func (t *Mimir) initTombstonesLoader() (services.Service, error) {
+	t.TombstonesLoader = querier.NewTombstonesLoader(func() (objstore.Bucket, error) {
+		return bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, "querier-tombstones", util_log.Logger, t.Registerer)
+	}, t.Overrides, util_log.Logger)
+
+	return t.TombstonesLoader, nil
+}
+
 func (t *Mimir) initStoreQueryables() (services.Service, error) {
 	var servs []services.Service
 
@@ -498,6 +521,9 @@ func (t *Mimir) initIngesterService() (serv services.Service, err error) {
 	t.Cfg.Ingester.IngesterRing.ListenPort = t.Cfg.Server.GRPCListenPort
 	t.Cfg.Ingester.StreamTypeFn = ingesterChunkStreaming(t.RuntimeConfig)
 	t.Cfg.Ingester.InstanceLimitsFn = ingesterInstanceLimits(t.RuntimeConfig)
+	t.Cfg.Ingester.IngesterClientFactory = func(addr string) (client.HealthAndIngesterClient, error) {
+		return client.MakeIngesterClient(addr, t.Cfg.IngesterClient)
+	}
 	t.tsdbIngesterConfig()
 
 	t.Ingester, err = ingester.New(t.Cfg.Ingester, t.Overrides, t.ActiveGroupsCleanup, t.Registerer, util_log.Logger)
@@ -546,8 +572,29 @@ func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error
 	t.QueryFrontendCodec = querymiddleware.NewPrometheusCodec(t.Registerer)
 	promqlEngineRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "query-frontend"}, t.Registerer)
 
+	middlewareCfg := t.Cfg.Frontend.QueryMiddleware
+	if middlewareCfg.QueryCostBucketIndexEnabled {
+		bucketClient, err := bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, "query-frontend", util_log.Logger, t.Registerer)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create query-frontend bucket client")
+		}
+
+		loader := bucketindex.NewLoader(bucketindex.LoaderConfig{
+			CheckInterval:         time.Minute,
+			UpdateOnStaleInterval: t.Cfg.BlocksStorage.BucketStore.SyncInterval,
+			UpdateOnErrorInterval: t.Cfg.BlocksStorage.BucketStore.BucketIndex.UpdateOnErrorInterval,
+			IdleTimeout:           t.Cfg.BlocksStorage.BucketStore.BucketIndex.IdleTimeout,
+		}, bucketClient, t.Overrides, util_log.Logger, prometheus.WrapRegistererWith(prometheus.Labels{"component": "query-frontend"}, t.Registerer))
+
+		middlewareCfg.BucketIndexLoader = loader
+		serv = loader
+	}
+	if middlewareCfg.CacheResults {
+		middlewareCfg.ResultsCacheGenLoader = t.TombstonesLoader
+	}
+
 	tripperware, err := querymiddleware.NewTripperware(
-		t.Cfg.Frontend.QueryMiddleware,
+		middlewareCfg,
 		util_log.Logger,
 		t.Overrides,
 		t.QueryFrontendCodec,
@@ -560,7 +607,7 @@ func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error
 	}
 
 	t.QueryFrontendTripperware = tripperware
-	return nil, nil
+	return serv, nil
 }
 
 func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
@@ -574,8 +621,10 @@ func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
 	// Wrap roundtripper into Tripperware.
 	roundTripper = t.QueryFrontendTripperware(roundTripper)
 
-	handler := transport.NewHandler(t.Cfg.Frontend.Handler, roundTripper, util_log.Logger, t.Registerer, t.ActivityTracker)
+	activeQueries := activequeries.NewTracker()
+	handler := transport.NewHandler(t.Cfg.Frontend.Handler, roundTripper, util_log.Logger, t.Registerer, t.ActivityTracker, activeQueries)
 	t.API.RegisterQueryFrontendHandler(handler, t.BuildInfoHandler)
+	t.API.RegisterActiveQueries("/query-frontend", activeQueries)
 
 	var frontendSvc services.Service
 	if frontendV1 != nil {
@@ -658,6 +707,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 		rulerRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "ruler"}, t.Registerer)
 
 		queryable, _, eng := querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.StoreQueryables, rulerRegisterer, util_log.Logger, t.ActivityTracker)
+		queryable = querier.NewTombstonesQueryable(queryable, t.TombstonesLoader)
 		queryable = querier.NewErrorTranslateQueryableWithFn(queryable, ruler.WrapQueryableErrors)
 
 		if t.Cfg.Ruler.TenantFederation.Enabled {
@@ -864,6 +914,7 @@ func (t *Mimir) setupModuleManager() error {
 	mm.RegisterModule(Queryable, t.initQueryable, modules.UserInvisibleModule)
 	mm.RegisterModule(Querier, t.initQuerier)
 	mm.RegisterModule(StoreQueryable, t.initStoreQueryables, modules.UserInvisibleModule)
+	mm.RegisterModule(TombstonesLoader, t.initTombstonesLoader, modules.UserInvisibleModule)
 	mm.RegisterModule(QueryFrontendTripperware, t.initQueryFrontendTripperware, modules.UserInvisibleModule)
 	mm.RegisterModule(QueryFrontend, t.initQueryFrontend)
 	mm.RegisterModule(RulerStorage, t.initRulerStorage, modules.UserInvisibleModule)
@@ -894,13 +945,14 @@ func (t *Mimir) setupModuleManager() error {
 		Ingester:                 {IngesterService, API, ActiveGroupsCleanupService},
 		IngesterService:          {Overrides, RuntimeConfig, MemberlistKV},
 		Flusher:                  {Overrides, API},
-		Queryable:                {Overrides, DistributorService, Ring, API, StoreQueryable, MemberlistKV},
+		Queryable:                {Overrides, DistributorService, Ring, API, StoreQueryable, TombstonesLoader, MemberlistKV},
 		Querier:                  {TenantFederation},
 		StoreQueryable:           {Overrides, MemberlistKV},
-		QueryFrontendTripperware: {API, Overrides},
+		TombstonesLoader:         {Overrides},
+		QueryFrontendTripperware: {API, Overrides, TombstonesLoader},
 		QueryFrontend:            {QueryFrontendTripperware, MemberlistKV},
 		QueryScheduler:           {API, Overrides, MemberlistKV},
-		Ruler:                    {DistributorService, StoreQueryable, RulerStorage},
+		Ruler:                    {DistributorService, StoreQueryable, TombstonesLoader, RulerStorage},
 		RulerStorage:             {Overrides},
 		AlertManager:             {API, MemberlistKV, Overrides},
 		Compactor:                {API, MemberlistKV, Overrides},
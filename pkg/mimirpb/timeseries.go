@@ -314,6 +314,7 @@ func ReuseTimeseries(ts *TimeSeries) {
 	}
 	ts.Labels = ts.Labels[:0]
 	ts.Samples = ts.Samples[:0]
+	ts.Histograms = ts.Histograms[:0]
 
 	ClearExemplars(ts)
 	timeSeriesPool.Put(ts)
@@ -64,11 +64,13 @@ func TestTimeseriesFromPool(t *testing.T) {
 		ts := TimeseriesFromPool()
 		ts.Labels = []LabelAdapter{{Name: "foo", Value: "bar"}}
 		ts.Samples = []Sample{{Value: 1, TimestampMs: 2}}
+		ts.Histograms = []Histogram{{Sum: 1, Timestamp: 2}}
 		ReuseTimeseries(ts)
 
 		reused := TimeseriesFromPool()
 		assert.Len(t, reused.Labels, 0)
 		assert.Len(t, reused.Samples, 0)
+		assert.Len(t, reused.Histograms, 0)
 	})
 }
 
@@ -0,0 +1,447 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mimirpb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The remote write 2.0 protocol interns the label names and values, and the metadata strings, of all the
+// series of a request into a single symbols table, and references them by index. It's encoded as:
+//
+//	message Request {
+//	  // The first symbol must be the empty string.
+//	  repeated string symbols = 4;
+//	  repeated TimeSeries timeseries = 5;
+//	}
+//
+//	message TimeSeries {
+//	  // Pairs of references to the label name and value symbols.
+//	  repeated uint32 labels_refs = 1;
+//	  repeated Sample samples = 2;
+//	  repeated Histogram histograms = 3;
+//	  repeated Exemplar exemplars = 4;
+//	  Metadata metadata = 5;
+//	  int64 created_timestamp = 6;
+//	}
+//
+//	message Exemplar {
+//	  repeated uint32 labels_refs = 1;
+//	  double value = 2;
+//	  int64 timestamp = 3;
+//	}
+//
+//	message Metadata {
+//	  MetricType type = 1;
+//	  uint32 help_ref = 3;
+//	  uint32 unit_ref = 4;
+//	}
+//
+// The Sample and Histogram messages are wire compatible with the ones of WriteRequest.
+const (
+	writeV2SymbolsField    = 4
+	writeV2TimeseriesField = 5
+
+	timeSeriesV2LabelsRefsField = 1
+	timeSeriesV2SamplesField    = 2
+	timeSeriesV2HistogramsField = 3
+	timeSeriesV2ExemplarsField  = 4
+	timeSeriesV2MetadataField   = 5
+
+	exemplarV2LabelsRefsField = 1
+	exemplarV2ValueField      = 2
+	exemplarV2TimestampField  = 3
+
+	metadataV2TypeField    = 1
+	metadataV2HelpRefField = 3
+	metadataV2UnitRefField = 4
+)
+
+// PreallocWriteRequestV2 is a PreallocWriteRequest which is unmarshalled from the remote write 2.0 protocol.
+type PreallocWriteRequestV2 struct {
+	*PreallocWriteRequest
+}
+
+// Unmarshal implements proto.Unmarshaler. The label names and values are yoloStrings referencing the symbols
+// in dAtA, so each symbol is shared by all the series referencing it, and dAtA must not be modified until the
+// request is in use.
+func (p PreallocWriteRequestV2) Unmarshal(dAtA []byte) error {
+	// The symbols may follow the series they're referenced by, so they're read first.
+	var symbols []string
+	err := consumeFields(dAtA, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num == writeV2SymbolsField && typ == protowire.BytesType {
+			symbols = append(symbols, yoloString(v))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(symbols) > 0 && symbols[0] != "" {
+		return errors.New("the first symbol must be the empty string")
+	}
+
+	p.Timeseries = PreallocTimeseriesSliceFromPool()
+	metadata := map[string]*MetricMetadata{}
+
+	err = consumeFields(dAtA, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != writeV2TimeseriesField || typ != protowire.BytesType {
+			return nil
+		}
+
+		ts := TimeseriesFromPool()
+		p.Timeseries = append(p.Timeseries, PreallocTimeseries{TimeSeries: ts})
+
+		md, err := unmarshalTimeSeriesV2(ts, v, symbols)
+		if err != nil {
+			return err
+		}
+		if md != nil {
+			// The metadata is per metric family, and it's sent once per family.
+			if _, ok := metadata[md.MetricFamilyName]; !ok {
+				metadata[md.MetricFamilyName] = md
+				p.Metadata = append(p.Metadata, md)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func unmarshalTimeSeriesV2(ts *TimeSeries, dAtA []byte, symbols []string) (*MetricMetadata, error) {
+	var (
+		md     *MetricMetadata
+		mdData []byte
+	)
+
+	err := consumeFields(dAtA, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch num {
+		case timeSeriesV2LabelsRefsField:
+			var err error
+			ts.Labels, err = appendLabelsRefs(ts.Labels, typ, v, symbols)
+			return err
+
+		case timeSeriesV2SamplesField:
+			ts.Samples = append(ts.Samples, Sample{})
+			return ts.Samples[len(ts.Samples)-1].Unmarshal(v)
+
+		case timeSeriesV2HistogramsField:
+			ts.Histograms = append(ts.Histograms, Histogram{})
+			return ts.Histograms[len(ts.Histograms)-1].Unmarshal(v)
+
+		case timeSeriesV2ExemplarsField:
+			ts.Exemplars = append(ts.Exemplars, Exemplar{})
+			return unmarshalExemplarV2(&ts.Exemplars[len(ts.Exemplars)-1], v, symbols)
+
+		case timeSeriesV2MetadataField:
+			// The metadata is decoded once the metric name is known.
+			mdData = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ts.Labels)%2 != 0 {
+		return nil, errors.New("odd number of labels refs")
+	}
+	// The labels refs are decoded in pairs of name and value.
+	labels := ts.Labels[:0]
+	for i := 0; i < len(ts.Labels); i += 2 {
+		labels = append(labels, LabelAdapter{Name: ts.Labels[i].Name, Value: ts.Labels[i+1].Name})
+	}
+	ts.Labels = labels
+
+	if mdData != nil {
+		md, err = unmarshalMetadataV2(mdData, symbols, ts.Labels)
+	}
+	return md, err
+}
+
+func unmarshalExemplarV2(e *Exemplar, dAtA []byte, symbols []string) error {
+	err := consumeFields(dAtA, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch {
+		case num == exemplarV2LabelsRefsField:
+			var err error
+			e.Labels, err = appendLabelsRefs(e.Labels, typ, v, symbols)
+			return err
+
+		case num == exemplarV2ValueField && typ == protowire.Fixed64Type:
+			val, n := protowire.ConsumeFixed64(v)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			e.Value = math.Float64frombits(val)
+
+		case num == exemplarV2TimestampField && typ == protowire.VarintType:
+			val, n := protowire.ConsumeVarint(v)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			e.TimestampMs = int64(val)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(e.Labels)%2 != 0 {
+		return errors.New("odd number of exemplar labels refs")
+	}
+	labels := e.Labels[:0]
+	for i := 0; i < len(e.Labels); i += 2 {
+		labels = append(labels, LabelAdapter{Name: e.Labels[i].Name, Value: e.Labels[i+1].Name})
+	}
+	e.Labels = labels
+	return nil
+}
+
+func unmarshalMetadataV2(dAtA []byte, symbols []string, labels []LabelAdapter) (*MetricMetadata, error) {
+	md := &MetricMetadata{}
+	err := consumeFields(dAtA, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.VarintType {
+			return nil
+		}
+
+		val, n := protowire.ConsumeVarint(v)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		var err error
+		switch num {
+		case metadataV2TypeField:
+			md.Type = MetricMetadata_MetricType(val)
+		case metadataV2HelpRefField:
+			md.Help, err = symbol(symbols, val)
+		case metadataV2UnitRefField:
+			md.Unit, err = symbol(symbols, val)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if md.Type == UNKNOWN && md.Help == "" && md.Unit == "" {
+		return nil, nil
+	}
+
+	for _, l := range labels {
+		if l.Name == model.MetricNameLabel {
+			md.MetricFamilyName = metricFamilyName(l.Value, md.Type)
+			return md, nil
+		}
+	}
+	return nil, nil
+}
+
+// metricFamilyName returns the name of the metric family of the series named name, removing
+// the suffixes of the series of classic histograms and summaries.
+func metricFamilyName(name string, typ MetricMetadata_MetricType) string {
+	var suffixes []string
+	switch typ {
+	case HISTOGRAM, GAUGEHISTOGRAM:
+		suffixes = []string{"_bucket", "_sum", "_count"}
+	case SUMMARY:
+		suffixes = []string{"_sum", "_count"}
+	}
+
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
+
+// appendLabelsRefs appends a label to lbls for each symbol referenced by the packed or unpacked refs in dAtA.
+// Each label only has the name set to the referenced symbol, so the labels must then be paired.
+func appendLabelsRefs(lbls []LabelAdapter, typ protowire.Type, dAtA []byte, symbols []string) ([]LabelAdapter, error) {
+	appendRef := func(b []byte) (int, error) {
+		ref, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		s, err := symbol(symbols, ref)
+		if err != nil {
+			return 0, err
+		}
+		lbls = append(lbls, LabelAdapter{Name: s})
+		return n, nil
+	}
+
+	switch typ {
+	case protowire.VarintType:
+		_, err := appendRef(dAtA)
+		return lbls, err
+
+	case protowire.BytesType:
+		for len(dAtA) > 0 {
+			n, err := appendRef(dAtA)
+			if err != nil {
+				return lbls, err
+			}
+			dAtA = dAtA[n:]
+		}
+		return lbls, nil
+
+	default:
+		return lbls, fmt.Errorf("invalid wire type %d of labels refs", typ)
+	}
+}
+
+func symbol(symbols []string, ref uint64) (string, error) {
+	if ref >= uint64(len(symbols)) {
+		return "", fmt.Errorf("symbol reference %d out of range, the symbols table has %d symbols", ref, len(symbols))
+	}
+	return symbols[ref], nil
+}
+
+// consumeFields calls fn for each field in dAtA, with the value of the field, which for the length-delimited
+// fields is the content without the length prefix.
+func consumeFields(dAtA []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(dAtA) > 0 {
+		num, typ, n := protowire.ConsumeTag(dAtA)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		dAtA = dAtA[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, dAtA)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		v := dAtA[:n]
+		dAtA = dAtA[n:]
+
+		if typ == protowire.BytesType {
+			var m int
+			v, m = protowire.ConsumeBytes(v)
+			if m < 0 {
+				return protowire.ParseError(m)
+			}
+		}
+
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalWriteRequestV2 encodes the request in the remote write 2.0 protocol. The metadata of the request
+// is attached to the series of the metric families it describes.
+func MarshalWriteRequestV2(req *WriteRequest) ([]byte, error) {
+	symbols := map[string]uint64{"": 0}
+	symbolsTable := []string{""}
+	ref := func(s string) uint64 {
+		r, ok := symbols[s]
+		if !ok {
+			r = uint64(len(symbolsTable))
+			symbols[s] = r
+			symbolsTable = append(symbolsTable, s)
+		}
+		return r
+	}
+	appendLabelsRefs := func(b []byte, num protowire.Number, lbls []LabelAdapter) []byte {
+		var refs []byte
+		for _, l := range lbls {
+			refs = protowire.AppendVarint(refs, ref(l.Name))
+			refs = protowire.AppendVarint(refs, ref(l.Value))
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, refs)
+	}
+
+	metadata := make(map[string]*MetricMetadata, len(req.Metadata))
+	for _, md := range req.Metadata {
+		metadata[md.MetricFamilyName] = md
+	}
+
+	var series []byte
+	for _, ts := range req.Timeseries {
+		var b []byte
+		b = appendLabelsRefs(b, timeSeriesV2LabelsRefsField, ts.Labels)
+
+		for _, s := range ts.Samples {
+			data, err := s.Marshal()
+			if err != nil {
+				return nil, err
+			}
+			b = protowire.AppendTag(b, timeSeriesV2SamplesField, protowire.BytesType)
+			b = protowire.AppendBytes(b, data)
+		}
+
+		for _, h := range ts.Histograms {
+			data, err := h.Marshal()
+			if err != nil {
+				return nil, err
+			}
+			b = protowire.AppendTag(b, timeSeriesV2HistogramsField, protowire.BytesType)
+			b = protowire.AppendBytes(b, data)
+		}
+
+		for _, e := range ts.Exemplars {
+			var eb []byte
+			eb = appendLabelsRefs(eb, exemplarV2LabelsRefsField, e.Labels)
+			eb = protowire.AppendTag(eb, exemplarV2ValueField, protowire.Fixed64Type)
+			eb = protowire.AppendFixed64(eb, math.Float64bits(e.Value))
+			eb = protowire.AppendTag(eb, exemplarV2TimestampField, protowire.VarintType)
+			eb = protowire.AppendVarint(eb, uint64(e.TimestampMs))
+
+			b = protowire.AppendTag(b, timeSeriesV2ExemplarsField, protowire.BytesType)
+			b = protowire.AppendBytes(b, eb)
+		}
+
+		if md := seriesMetadata(metadata, ts.Labels); md != nil {
+			var mb []byte
+			mb = protowire.AppendTag(mb, metadataV2TypeField, protowire.VarintType)
+			mb = protowire.AppendVarint(mb, uint64(md.Type))
+			mb = protowire.AppendTag(mb, metadataV2HelpRefField, protowire.VarintType)
+			mb = protowire.AppendVarint(mb, ref(md.Help))
+			mb = protowire.AppendTag(mb, metadataV2UnitRefField, protowire.VarintType)
+			mb = protowire.AppendVarint(mb, ref(md.Unit))
+
+			b = protowire.AppendTag(b, timeSeriesV2MetadataField, protowire.BytesType)
+			b = protowire.AppendBytes(b, mb)
+		}
+
+		series = protowire.AppendTag(series, writeV2TimeseriesField, protowire.BytesType)
+		series = protowire.AppendBytes(series, b)
+	}
+
+	var out []byte
+	for _, s := range symbolsTable {
+		out = protowire.AppendTag(out, writeV2SymbolsField, protowire.BytesType)
+		out = protowire.AppendString(out, s)
+	}
+	return append(out, series...), nil
+}
+
+// seriesMetadata returns the metadata of the metric family of the series with the given labels, if any.
+func seriesMetadata(metadata map[string]*MetricMetadata, lbls []LabelAdapter) *MetricMetadata {
+	for _, l := range lbls {
+		if l.Name != model.MetricNameLabel {
+			continue
+		}
+
+		if md, ok := metadata[l.Value]; ok {
+			return md
+		}
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			family := strings.TrimSuffix(l.Value, suffix)
+			if md, ok := metadata[family]; ok && family != l.Value && metricFamilyName(l.Value, md.Type) == family {
+				return md
+			}
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mimirpb
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestPreallocWriteRequestV2_Unmarshal(t *testing.T) {
+	req := &WriteRequest{
+		Timeseries: []PreallocTimeseries{
+			{TimeSeries: &TimeSeries{
+				Labels:  []LabelAdapter{{Name: "__name__", Value: "http_requests_total"}, {Name: "job", Value: "api"}},
+				Samples: []Sample{{TimestampMs: 1000, Value: 1}, {TimestampMs: 2000, Value: 2}},
+				Exemplars: []Exemplar{
+					{Labels: []LabelAdapter{{Name: "trace_id", Value: "abc"}}, Value: 1, TimestampMs: 1000},
+				},
+			}},
+			{TimeSeries: &TimeSeries{
+				Labels:  []LabelAdapter{{Name: "__name__", Value: "http_request_duration_seconds_bucket"}, {Name: "job", Value: "api"}, {Name: "le", Value: "+Inf"}},
+				Samples: []Sample{{TimestampMs: 1000, Value: 10}},
+			}},
+			{TimeSeries: &TimeSeries{
+				Labels:  []LabelAdapter{{Name: "__name__", Value: "http_request_duration_seconds_count"}, {Name: "job", Value: "api"}},
+				Samples: []Sample{{TimestampMs: 1000, Value: 10}},
+			}},
+			{TimeSeries: &TimeSeries{
+				Labels: []LabelAdapter{{Name: "__name__", Value: "http_request_size_bytes"}, {Name: "job", Value: "api"}},
+				Histograms: []Histogram{{
+					Count:          &Histogram_CountInt{CountInt: 3},
+					Sum:            5,
+					Schema:         1,
+					ZeroThreshold:  0.001,
+					ZeroCount:      &Histogram_ZeroCountInt{ZeroCountInt: 1},
+					PositiveSpans:  []BucketSpan{{Offset: 0, Length: 2}},
+					PositiveDeltas: []int64{1, 0},
+					Timestamp:      1000,
+				}},
+			}},
+		},
+		Metadata: []*MetricMetadata{
+			{Type: COUNTER, MetricFamilyName: "http_requests_total", Help: "Total requests.", Unit: ""},
+			{Type: HISTOGRAM, MetricFamilyName: "http_request_duration_seconds", Help: "Request duration.", Unit: "seconds"},
+		},
+	}
+
+	data, err := MarshalWriteRequestV2(req)
+	require.NoError(t, err)
+
+	// Each label name and value, help and unit is encoded once.
+	symbols := 0
+	require.NoError(t, consumeFields(data, func(num protowire.Number, _ protowire.Type, _ []byte) error {
+		if num == writeV2SymbolsField {
+			symbols++
+		}
+		return nil
+	}))
+	assert.Equal(t, 15, symbols)
+
+	actual := &PreallocWriteRequest{}
+	require.NoError(t, PreallocWriteRequestV2{PreallocWriteRequest: actual}.Unmarshal(data))
+
+	require.Len(t, actual.Timeseries, len(req.Timeseries))
+	for i, ts := range req.Timeseries {
+		assert.Equal(t, ts.Labels, actual.Timeseries[i].Labels)
+		assert.ElementsMatch(t, ts.Samples, actual.Timeseries[i].Samples)
+		assert.ElementsMatch(t, ts.Histograms, actual.Timeseries[i].Histograms)
+		assert.ElementsMatch(t, ts.Exemplars, actual.Timeseries[i].Exemplars)
+	}
+	assert.Equal(t, req.Metadata, actual.Metadata)
+
+	// The labels of different series share the same symbols.
+	assert.Equal(t, stringData(actual.Timeseries[0].Labels[1].Value), stringData(actual.Timeseries[1].Labels[1].Value))
+}
+
+func TestPreallocWriteRequestV2_UnmarshalInvalid(t *testing.T) {
+	appendSymbols := func(b []byte, symbols ...string) []byte {
+		for _, s := range symbols {
+			b = protowire.AppendTag(b, writeV2SymbolsField, protowire.BytesType)
+			b = protowire.AppendString(b, s)
+		}
+		return b
+	}
+	appendSeries := func(b []byte, refs ...uint64) []byte {
+		var packed []byte
+		for _, r := range refs {
+			packed = protowire.AppendVarint(packed, r)
+		}
+		var ts []byte
+		ts = protowire.AppendTag(ts, timeSeriesV2LabelsRefsField, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, packed)
+
+		b = protowire.AppendTag(b, writeV2TimeseriesField, protowire.BytesType)
+		return protowire.AppendBytes(b, ts)
+	}
+
+	for name, tc := range map[string]struct {
+		data        []byte
+		expectedErr string
+	}{
+		"first symbol is not empty": {
+			data:        appendSymbols(nil, "__name__", "up"),
+			expectedErr: "the first symbol must be the empty string",
+		},
+		"symbol reference out of range": {
+			data:        appendSeries(appendSymbols(nil, "", "__name__", "up"), 1, 3),
+			expectedErr: "symbol reference 3 out of range, the symbols table has 3 symbols",
+		},
+		"odd number of labels refs": {
+			data:        appendSeries(appendSymbols(nil, "", "__name__", "up"), 1, 2, 1),
+			expectedErr: "odd number of labels refs",
+		},
+		"truncated request": {
+			data:        appendSymbols(nil, "", "__name__")[:5],
+			expectedErr: "unexpected EOF",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := PreallocWriteRequestV2{PreallocWriteRequest: &PreallocWriteRequest{}}.Unmarshal(tc.data)
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+
+	// The symbols can follow the series.
+	req := &PreallocWriteRequest{}
+	require.NoError(t, PreallocWriteRequestV2{PreallocWriteRequest: req}.Unmarshal(appendSymbols(appendSeries(nil, 1, 2), "", "__name__", "up")))
+	require.Len(t, req.Timeseries, 1)
+	assert.Equal(t, []LabelAdapter{{Name: "__name__", Value: "up"}}, req.Timeseries[0].Labels)
+}
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
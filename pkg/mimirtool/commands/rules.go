@@ -17,15 +17,18 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/alecthomas/kingpin.v2"
 	yamlv3 "gopkg.in/yaml.v3"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/mimirtool/client"
 	"github.com/grafana/mimir/pkg/mimirtool/printer"
 	"github.com/grafana/mimir/pkg/mimirtool/rules"
 	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/grafana/mimir/pkg/util/ephemeral"
 )
 
 const (
@@ -101,6 +104,9 @@ type RuleCommand struct {
 	// List Rules Config
 	Format string
 
+	// List Ephemeral Rules Config
+	EphemeralSeriesMatchers ephemeral.LabelMatchers
+
 	DisableColor bool
 
 	// Diff Rules Config
@@ -153,9 +159,12 @@ func (r *RuleCommand) Register(app *kingpin.Application, envVars EnvVarNames, re
 	deleteNamespaceCmd := rulesCmd.
 		Command("delete-namespace", "Delete a namespace from the ruler.").
 		Action(r.deleteNamespace)
+	listEphemeralCmd := rulesCmd.
+		Command("list-ephemeral", "List the recording rules currently in the Grafana Mimir ruler whose series are marked as ephemeral.").
+		Action(r.listEphemeralRules)
 
 	// Require Mimir cluster address and tenant ID on all these commands
-	for _, c := range []*kingpin.CmdClause{listCmd, printRulesCmd, getRuleGroupCmd, deleteRuleGroupCmd, loadRulesCmd, diffRulesCmd, syncRulesCmd, deleteNamespaceCmd} {
+	for _, c := range []*kingpin.CmdClause{listCmd, printRulesCmd, getRuleGroupCmd, deleteRuleGroupCmd, loadRulesCmd, diffRulesCmd, syncRulesCmd, deleteNamespaceCmd, listEphemeralCmd} {
 		c.Flag("address", "Address of the Grafana Mimir cluster; alternatively, set "+envVars.Address+".").
 			Envar(envVars.Address).
 			Required().
@@ -268,6 +277,10 @@ func (r *RuleCommand) Register(app *kingpin.Application, envVars EnvVarNames, re
 	// Delete Namespace Command
 	deleteNamespaceCmd.Arg("namespace", "Namespace to delete.").Required().StringVar(&r.Namespace)
 
+	// List Ephemeral Command
+	listEphemeralCmd.Flag("ephemeral-series-matchers", "The ephemeral series matchers of the tenant, in the format of the -distributor.ephemeral-series-matchers option. Only the matchers of the rule source are used, and they're matched against the metric name and the labels of the recording rules.").Required().SetValue(&r.EphemeralSeriesMatchers)
+	listEphemeralCmd.Flag("disable-color", "disable colored output").BoolVar(&r.DisableColor)
+
 }
 
 func (r *RuleCommand) setup(_ *kingpin.ParseContext, reg prometheus.Registerer) error {
@@ -817,3 +830,58 @@ func (r *RuleCommand) deleteNamespace(k *kingpin.ParseContext) error {
 	}
 	return nil
 }
+
+func (r *RuleCommand) listEphemeralRules(k *kingpin.ParseContext) error {
+	rules, err := r.cli.ListRules(context.Background(), "")
+	if err != nil {
+		if errors.Is(err, client.ErrResourceNotFound) {
+			log.Infof("no rule groups currently exist for this user")
+			return nil
+		}
+		log.Fatalf("Unable to read rules from Grafana Mimir, %v", err)
+	}
+
+	ephemeralRules := ephemeralRecordingRules(rules, r.EphemeralSeriesMatchers.ForSource(mimirpb.RULE))
+	if len(ephemeralRules) == 0 {
+		log.Infof("no recording rules have their series marked as ephemeral")
+		return nil
+	}
+
+	p := printer.New(r.DisableColor)
+	return p.PrintRuleGroups(ephemeralRules)
+}
+
+// ephemeralRecordingRules returns the rule groups with only the recording rules whose series are marked as ephemeral
+// by the matchers. The other labels of the series depend on the result of the expressions, so the series are matched
+// by their metric name and the labels of the recording rules only.
+func ephemeralRecordingRules(namespaces map[string][]rwrulefmt.RuleGroup, matchers ephemeral.MatcherSetsForSource) map[string][]rwrulefmt.RuleGroup {
+	res := map[string][]rwrulefmt.RuleGroup{}
+	if !matchers.HasMatchers() {
+		return res
+	}
+
+	for ns, groups := range namespaces {
+		for _, group := range groups {
+			var ephemeralRules []rulefmt.RuleNode
+			for _, rule := range group.Rules {
+				if rule.Record.Value == "" {
+					continue
+				}
+
+				lbls := []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: rule.Record.Value}}
+				for name, value := range rule.Labels {
+					lbls = append(lbls, mimirpb.LabelAdapter{Name: name, Value: value})
+				}
+				if matchers.ShouldMarkEphemeral(lbls) {
+					ephemeralRules = append(ephemeralRules, rule)
+				}
+			}
+
+			if len(ephemeralRules) > 0 {
+				group.Rules = ephemeralRules
+				res[ns] = append(res[ns], group)
+			}
+		}
+	}
+	return res
+}
@@ -16,8 +16,10 @@ import (
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/mimirtool/rules"
 	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/grafana/mimir/pkg/util/ephemeral"
 )
 
 func TestRuleCommand_executeChanges(t *testing.T) {
@@ -115,6 +117,67 @@ func TestCheckDuplicates(t *testing.T) {
 	}
 }
 
+func TestEphemeralRecordingRules(t *testing.T) {
+	var matchers ephemeral.LabelMatchers
+	require.NoError(t, matchers.Set(`rule:{__name__=~"tmp:.+"};rule:{team="a",env="dev"};api:{__name__="up"}`))
+
+	groups := map[string][]rwrulefmt.RuleGroup{
+		"namespace-1": {{
+			RuleGroup: rulefmt.RuleGroup{
+				Name: "group-1",
+				Rules: []rulefmt.RuleNode{
+					{Record: yaml.Node{Value: "tmp:requests:rate1m"}, Expr: yaml.Node{Value: "rate(requests[1m])"}},
+					{Record: yaml.Node{Value: "job:requests:rate1m"}, Expr: yaml.Node{Value: "sum by (job) (rate(requests[1m]))"}},
+					{Alert: yaml.Node{Value: "TmpAlert"}, Expr: yaml.Node{Value: "up == 0"}},
+				},
+			},
+		}},
+		"namespace-2": {
+			{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "group-2",
+					Rules: []rulefmt.RuleNode{
+						{Record: yaml.Node{Value: "up"}, Expr: yaml.Node{Value: "up"}},
+						{Record: yaml.Node{Value: "dev:up"}, Expr: yaml.Node{Value: "up"}, Labels: map[string]string{"team": "a", "env": "prod"}},
+					},
+				},
+			},
+			{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "group-3",
+					Rules: []rulefmt.RuleNode{
+						{Record: yaml.Node{Value: "dev:up"}, Expr: yaml.Node{Value: "up"}, Labels: map[string]string{"team": "a", "env": "dev"}},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, map[string][]rwrulefmt.RuleGroup{
+		"namespace-1": {{
+			RuleGroup: rulefmt.RuleGroup{
+				Name: "group-1",
+				Rules: []rulefmt.RuleNode{
+					{Record: yaml.Node{Value: "tmp:requests:rate1m"}, Expr: yaml.Node{Value: "rate(requests[1m])"}},
+				},
+			},
+		}},
+		"namespace-2": {{
+			RuleGroup: rulefmt.RuleGroup{
+				Name: "group-3",
+				Rules: []rulefmt.RuleNode{
+					{Record: yaml.Node{Value: "dev:up"}, Expr: yaml.Node{Value: "up"}, Labels: map[string]string{"team": "a", "env": "dev"}},
+				},
+			},
+		}},
+	}, ephemeralRecordingRules(groups, matchers.ForSource(mimirpb.RULE)))
+
+	// The matchers of the API source don't mark the series of the recording rules as ephemeral.
+	var apiMatchers ephemeral.LabelMatchers
+	require.NoError(t, apiMatchers.Set(`api:{__name__="up"}`))
+	assert.Empty(t, ephemeralRecordingRules(groups, apiMatchers.ForSource(mimirpb.RULE)))
+}
+
 type ruleCommandClientMock struct {
 	mock.Mock
 }
@@ -118,13 +118,21 @@ func (bqs *blockQuerierSeries) Iterator(_ chunkenc.Iterator) chunkenc.Iterator {
 	its := make([]iteratorWithMaxTime, 0, len(bqs.chunks))
 
 	for _, c := range bqs.chunks {
-		if c.Raw.Type != storepb.Chunk_XOR {
-			// TODO enable iterating native histogram chunks
-			continue
+		var enc chunkenc.Encoding
+		switch c.Raw.Type {
+		case storepb.Chunk_XOR:
+			enc = chunkenc.EncXOR
+		case storepb.Chunk_Histogram:
+			enc = chunkenc.EncHistogram
+		case storepb.Chunk_FloatHistogram:
+			enc = chunkenc.EncFloatHistogram
+		default:
+			return series.NewErrIterator(errors.Errorf("unsupported chunk encoding %v (series: %v min time: %d max time: %d)", c.Raw.Type, bqs.Labels(), c.MinTime, c.MaxTime))
 		}
-		ch, err := chunkenc.FromData(chunkenc.EncXOR, c.Raw.Data)
+
+		ch, err := chunkenc.FromData(enc, c.Raw.Data)
 		if err != nil {
-			return series.NewErrIterator(errors.Wrapf(err, "failed to initialize chunk from XOR encoded raw data (series: %v min time: %d max time: %d)", bqs.Labels(), c.MinTime, c.MaxTime))
+			return series.NewErrIterator(errors.Wrapf(err, "failed to initialize chunk from %v encoded raw data (series: %v min time: %d max time: %d)", c.Raw.Type, bqs.Labels(), c.MinTime, c.MaxTime))
 		}
 
 		it := ch.Iterator(nil)
@@ -163,8 +171,8 @@ func (it *blockQuerierSeriesIterator) Seek(t int64) chunkenc.ValueType {
 			// Once we found an iterator which covers a time range that reaches beyond the seeked <t>
 			// we try to seek to and return the result.
 			if typ := it.iterators[it.i].Seek(t); typ != chunkenc.ValNone {
-				// Calling .At() to update it.lastT
-				it.At()
+				// Calling .AtT() to update it.lastT
+				it.AtT()
 				return typ
 			}
 		}
@@ -219,8 +227,8 @@ func (it *blockQuerierSeriesIterator) Next() chunkenc.ValueType {
 	}
 
 	if typ := it.iterators[it.i].Next(); typ != chunkenc.ValNone {
-		// Calling .At() to update it.lastT
-		it.At()
+		// Calling .AtT() to update it.lastT
+		it.AtT()
 		return typ
 	}
 	if it.iterators[it.i].Err() != nil {
@@ -17,6 +17,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -102,6 +103,31 @@ func TestBlockQuerierSeries(t *testing.T) {
 	}
 }
 
+func TestBlockQuerierSeriesWithNativeHistograms(t *testing.T) {
+	histogramChunk := chunkenc.NewHistogramChunk()
+	appender, err := histogramChunk.Appender()
+	require.NoError(t, err)
+	appender.AppendHistogram(3000, tsdb.GenerateTestHistogram(3))
+	appender.AppendHistogram(4000, tsdb.GenerateTestHistogram(4))
+
+	series := newBlockQuerierSeries(labels.FromStrings("foo", "bar"), []storepb.AggrChunk{
+		{MinTime: 1000, MaxTime: 2000, Raw: &storepb.Chunk{Type: storepb.Chunk_XOR, Data: mockTSDBChunkData()}},
+		{MinTime: 3000, MaxTime: 4000, Raw: &storepb.Chunk{Type: storepb.Chunk_Histogram, Data: histogramChunk.Bytes()}},
+	})
+
+	it := series.Iterator(nil)
+	require.Equal(t, chunkenc.ValFloat, it.Next())
+	require.Equal(t, int64(1000), it.AtT())
+	require.Equal(t, chunkenc.ValHistogram, it.Seek(3000))
+	ts, h := it.AtHistogram()
+	require.Equal(t, int64(3000), ts)
+	require.Equal(t, tsdb.GenerateTestHistogram(3).Count, h.Count)
+	require.Equal(t, chunkenc.ValHistogram, it.Next())
+	require.Equal(t, int64(4000), it.AtT())
+	require.Equal(t, chunkenc.ValNone, it.Next())
+	require.NoError(t, it.Err())
+}
+
 func mockTSDBChunkData() []byte {
 	chunk := chunkenc.NewXORChunk()
 	appender, err := chunk.Appender()
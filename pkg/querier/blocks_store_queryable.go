@@ -148,6 +148,7 @@ type BlocksStoreQueryable struct {
 	consistency     *BlocksConsistencyChecker
 	logger          log.Logger
 	queryStoreAfter time.Duration
+	lookbackDelta   time.Duration
 	metrics         *blocksStoreQueryableMetrics
 	limits          BlocksStoreLimits
 
@@ -162,6 +163,7 @@ func NewBlocksStoreQueryable(
 	consistency *BlocksConsistencyChecker,
 	limits BlocksStoreLimits,
 	queryStoreAfter time.Duration,
+	lookbackDelta time.Duration,
 	logger log.Logger,
 	reg prometheus.Registerer,
 ) (*BlocksStoreQueryable, error) {
@@ -175,6 +177,7 @@ func NewBlocksStoreQueryable(
 		finder:             finder,
 		consistency:        consistency,
 		queryStoreAfter:    queryStoreAfter,
+		lookbackDelta:      lookbackDelta,
 		logger:             logger,
 		subservices:        manager,
 		subservicesWatcher: services.NewFailureWatcher(),
@@ -199,7 +202,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 	}
 
 	// Blocks finder doesn't use chunks, but we pass config for consistency.
-	cachingBucket, err := mimir_tsdb.CreateCachingBucket(nil, storageCfg.BucketStore.ChunksCache, storageCfg.BucketStore.MetadataCache, bucketClient, logger, prometheus.WrapRegistererWith(prometheus.Labels{"component": "querier"}, reg))
+	cachingBucket, err := mimir_tsdb.CreateCachingBucket(nil, nil, storageCfg.BucketStore.ChunksCache, storageCfg.BucketStore.MetadataCache, storageCfg.BucketStore.DiskCache, bucketClient, logger, prometheus.WrapRegistererWith(prometheus.Labels{"component": "querier"}, reg))
 	if err != nil {
 		return nil, errors.Wrap(err, "create caching bucket")
 	}
@@ -261,7 +264,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 		reg,
 	)
 
-	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, logger, reg)
+	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, querierCfg.EngineConfig.LookbackDelta, logger, reg)
 }
 
 func (q *BlocksStoreQueryable) starting(ctx context.Context) error {
@@ -312,6 +315,7 @@ func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (s
 		consistency:     q.consistency,
 		logger:          q.logger,
 		queryStoreAfter: q.queryStoreAfter,
+		lookbackDelta:   q.lookbackDelta,
 	}, nil
 }
 
@@ -329,6 +333,10 @@ type blocksStoreQuerier struct {
 	// If set, the querier manipulates the max time to not be greater than
 	// "now - queryStoreAfter" so that most recent blocks are not queried.
 	queryStoreAfter time.Duration
+
+	// The lookback delta of the queries, used to pick the resolution of
+	// the blocks queried for instant vector selectors.
+	lookbackDelta time.Duration
 }
 
 // Select implements storage.Querier interface.
@@ -371,8 +379,7 @@ func (q *blocksStoreQuerier) LabelNames(matchers ...*labels.Matcher) ([]string,
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, queryFunc)
-	if err != nil {
+	if _, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, block.ResolutionRaw, false, queryFunc); err != nil {
 		return nil, nil, err
 	}
 
@@ -412,8 +419,7 @@ func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matche
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, queryFunc)
-	if err != nil {
+	if _, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, block.ResolutionRaw, false, queryFunc); err != nil {
 		return nil, nil, err
 	}
 
@@ -453,10 +459,12 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		return queriedBlocks, nil
 	}
 
-	err = q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, shard, queryFunc)
+	maxResolution, needsRaw := q.maxResolution(sp)
+	warnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, shard, maxResolution, needsRaw, queryFunc)
 	if err != nil {
 		return storage.ErrSeriesSet(err)
 	}
+	resWarnings = append(resWarnings, warnings...)
 
 	if len(resSeriesSets) == 0 {
 		storage.EmptySeriesSet()
@@ -467,8 +475,43 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		resWarnings)
 }
 
-func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64, shard *sharding.ShardSelector,
-	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)) error {
+// errDownsampledBlocksQueried is the warning returned by the queries requiring raw samples whose time range is
+// partially covered by downsampled blocks only.
+var errDownsampledBlocksQueried = errors.New("some of the queried time range is only covered by downsampled blocks: the result of functions depending on all the samples of a range, like rate() or max_over_time(), may be approximated")
+
+// lastSampleRangeFuncs are the functions of range vectors whose result only depends on the last samples of the
+// range, or on their presence, which the downsampled blocks preserve. The result of any other function, like
+// max_over_time() or increase(), depends on all the samples of the range, which the downsampled blocks don't keep.
+var lastSampleRangeFuncs = map[string]struct{}{
+	"last_over_time":    {},
+	"present_over_time": {},
+	"absent_over_time":  {},
+}
+
+// maxResolution returns the maximum resolution of the samples, in milliseconds, which can be queried without
+// affecting the result of the query with the given hints. Downsampled samples can be queried by instant vector
+// selectors, and by range vector selectors of the lastSampleRangeFuncs, as long as at least 5 of them fall within
+// both the query step and the range of the selector, which is the lookback delta for instant vector selectors.
+// The returned bool is true if only raw samples give the exact result of the query: the time ranges not covered by
+// raw blocks are then queried from downsampled blocks, and the query returns errDownsampledBlocksQueried as warning.
+func (q *blocksStoreQuerier) maxResolution(sp *storage.SelectHints) (int64, bool) {
+	window := sp.Range
+	if window > 0 {
+		if _, ok := lastSampleRangeFuncs[sp.Func]; !ok {
+			return block.ResolutionRaw, true
+		}
+	} else {
+		window = q.lookbackDelta.Milliseconds()
+	}
+	if sp.Step > 0 && sp.Step < window {
+		window = sp.Step
+	}
+
+	return window / 5, false
+}
+
+func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64, shard *sharding.ShardSelector, maxResolution int64, needsRaw bool,
+	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)) (storage.Warnings, error) {
 	// If queryStoreAfter is enabled, we do manipulate the query maxt to query samples up until
 	// now - queryStoreAfter, because the most recent time range is covered by ingesters. This
 	// optimization is particularly important for the blocks storage because can be used to skip
@@ -485,20 +528,20 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 		if maxT < minT {
 			q.metrics.storesHit.Observe(0)
 			level.Debug(logger).Log("msg", "empty query time range after max time manipulation")
-			return nil
+			return nil, nil
 		}
 	}
 
 	// Find the list of blocks we need to query given the time range.
 	knownBlocks, knownDeletionMarks, err := q.finder.GetBlocks(ctx, q.userID, minT, maxT)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(knownBlocks) == 0 {
 		q.metrics.storesHit.Observe(0)
 		level.Debug(logger).Log("msg", "no blocks found")
-		return nil
+		return nil, nil
 	}
 
 	q.metrics.blocksFound.Add(float64(len(knownBlocks)))
@@ -514,6 +557,22 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 		knownBlocks = result
 	}
 
+	// Query the downsampled blocks with the lowest resolution allowed by the query, falling back to blocks
+	// with other resolutions for the time ranges not covered by them.
+	knownBlocks = block.SelectBlocksByResolution(knownBlocks, minT, maxT, maxResolution, func(b *bucketindex.Block) (int64, int64, int64) {
+		return b.MinTime, b.MaxTime, b.Resolution
+	})
+
+	// The time ranges only covered by downsampled blocks are queried anyway, because returning no samples at all
+	// for them would be worse than returning approximated results.
+	var warnings storage.Warnings
+	if needsRaw {
+		if downsampled := downsampledBlocks(knownBlocks); len(downsampled) > 0 {
+			level.Debug(logger).Log("msg", "querying downsampled blocks for the time ranges not covered by raw blocks", "blocks", downsampled.String())
+			warnings = append(warnings, errDownsampledBlocksQueried)
+		}
+	}
+
 	q.metrics.blocksQueried.Add(float64(len(knownBlocks)))
 
 	level.Debug(logger).Log("msg", "found blocks to query", "expected", knownBlocks.String())
@@ -539,7 +598,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 				break
 			}
 
-			return err
+			return nil, err
 		}
 		level.Debug(logger).Log("msg", "found store-gateway instances to query", "num instances", len(clients), "attempt", attempt)
 
@@ -547,7 +606,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 		// are only meant to cover missing blocks.
 		queriedBlocks, err := queryFunc(clients, minT, maxT)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		level.Debug(logger).Log("msg", "received series from all store-gateways", "queried blocks", strings.Join(convertULIDsToString(queriedBlocks), " "))
 
@@ -568,7 +627,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 			q.metrics.storesHit.Observe(float64(len(touchedStores)))
 			q.metrics.refetches.Observe(float64(attempt - 1))
 
-			return nil
+			return warnings, nil
 		}
 
 		level.Debug(logger).Log("msg", "consistency check failed", "attempt", attempt, "missing blocks", strings.Join(convertULIDsToString(missingBlocks), " "))
@@ -579,7 +638,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 
 	// We've not been able to query all expected blocks after all retries.
 	level.Warn(util_log.WithContext(ctx, logger)).Log("msg", "failed consistency check", "err", err)
-	return newStoreConsistencyCheckFailedError(remainingBlocks)
+	return nil, newStoreConsistencyCheckFailedError(remainingBlocks)
 }
 
 func newStoreConsistencyCheckFailedError(remainingBlocks []ulid.ULID) error {
@@ -626,6 +685,17 @@ func filterBlocksByShard(blocks bucketindex.Blocks, queryShardIndex, queryShardC
 	return blocks, incompatibleBlocks
 }
 
+// downsampledBlocks returns the blocks in input which have been downsampled.
+func downsampledBlocks(blocks bucketindex.Blocks) bucketindex.Blocks {
+	var downsampled bucketindex.Blocks
+	for _, b := range blocks {
+		if b.Resolution != block.ResolutionRaw {
+			downsampled = append(downsampled, b)
+		}
+	}
+	return downsampled
+}
+
 // canBlockWithCompactorShardIndexContainQueryShard returns false if block with given compactor shard ID can *definitely NOT*
 // contain series for given query shard. Returns true otherwise (we don't know if block *does* contain such series,
 // but we cannot rule it out).
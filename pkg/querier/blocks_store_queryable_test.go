@@ -35,6 +35,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/sharding"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/storegateway/hintspb"
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
@@ -1560,6 +1561,182 @@ func TestBlocksStoreQuerier_SelectSortedShouldHonorQueryStoreAfter(t *testing.T)
 	}
 }
 
+func TestBlocksStoreQuerier_SelectSortedShouldPickBlocksResolution(t *testing.T) {
+	var (
+		raw1 = &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: 10 * time.Hour.Milliseconds()}
+		raw2 = &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: 10 * time.Hour.Milliseconds(), MaxTime: 20 * time.Hour.Milliseconds()}
+		res5 = &bucketindex.Block{ID: ulid.MustNew(3, nil), MinTime: 0, MaxTime: 10 * time.Hour.Milliseconds(), Resolution: block.Resolution5m}
+		res1 = &bucketindex.Block{ID: ulid.MustNew(4, nil), MinTime: 0, MaxTime: 10 * time.Hour.Milliseconds(), Resolution: block.Resolution1h}
+	)
+
+	tests := map[string]struct {
+		step, rng      time.Duration
+		fn             string
+		blocks         bucketindex.Blocks
+		expectedBlocks []ulid.ULID
+	}{
+		"instant vector selector in instant query": {
+			expectedBlocks: []ulid.ULID{raw1.ID, raw2.ID},
+		},
+		"instant vector selector in range query with large step": {
+			step:           time.Hour,
+			expectedBlocks: []ulid.ULID{raw1.ID, raw2.ID},
+		},
+		"range vector selector in range query with small step": {
+			step:           time.Minute,
+			rng:            24 * time.Hour,
+			expectedBlocks: []ulid.ULID{raw1.ID, raw2.ID},
+		},
+		"range vector selector in range query with 5m step": {
+			step:           30 * time.Minute,
+			rng:            time.Hour,
+			fn:             "last_over_time",
+			expectedBlocks: []ulid.ULID{res5.ID, raw2.ID},
+		},
+		"range vector selector in range query with 1h step": {
+			step:           6 * time.Hour,
+			rng:            24 * time.Hour,
+			fn:             "last_over_time",
+			expectedBlocks: []ulid.ULID{res1.ID, raw2.ID},
+		},
+		"range vector selector in instant query": {
+			rng:            24 * time.Hour,
+			fn:             "present_over_time",
+			expectedBlocks: []ulid.ULID{res1.ID, raw2.ID},
+		},
+		"range vector selector of a function depending on all the samples of the range": {
+			step:           6 * time.Hour,
+			rng:            24 * time.Hour,
+			fn:             "max_over_time",
+			expectedBlocks: []ulid.ULID{raw1.ID, raw2.ID},
+		},
+		"range vector selector of a counter function": {
+			rng:            24 * time.Hour,
+			fn:             "increase",
+			expectedBlocks: []ulid.ULID{raw1.ID, raw2.ID},
+		},
+		"range vector selector of a function depending on all the samples of the range, without raw blocks": {
+			step:           6 * time.Hour,
+			rng:            24 * time.Hour,
+			fn:             "sum_over_time",
+			blocks:         bucketindex.Blocks{raw2, res5, res1},
+			expectedBlocks: []ulid.ULID{res5.ID, raw2.ID},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			blocks := testData.blocks
+			if blocks == nil {
+				blocks = bucketindex.Blocks{raw1, raw2, res5, res1}
+			}
+
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", mock.Anything, mock.Anything).Return(blocks, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), error(nil))
+
+			stores := &blocksStoreSetRecorderMock{}
+			q := &blocksStoreQuerier{
+				ctx:           context.Background(),
+				minT:          0,
+				maxT:          20 * time.Hour.Milliseconds(),
+				userID:        "user-1",
+				finder:        finder,
+				stores:        stores,
+				consistency:   NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:        log.NewNopLogger(),
+				metrics:       newBlocksStoreQueryableMetrics(nil),
+				limits:        &blocksStoreLimitsMock{},
+				lookbackDelta: 5 * time.Minute,
+			}
+
+			sp := &storage.SelectHints{
+				Start: 0,
+				End:   20*time.Hour.Milliseconds() - 1,
+				Step:  testData.step.Milliseconds(),
+				Range: testData.rng.Milliseconds(),
+				Func:  testData.fn,
+			}
+
+			set := q.selectSorted(sp)
+			require.Error(t, set.Err())
+			assert.ElementsMatch(t, testData.expectedBlocks, stores.blockIDs)
+		})
+	}
+}
+
+func TestBlocksStoreQuerier_SelectSortedShouldWarnWhenQueryingDownsampledBlocksForRawSamples(t *testing.T) {
+	var (
+		raw = &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: 10 * time.Hour.Milliseconds(), MaxTime: 20 * time.Hour.Milliseconds()}
+		res = &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: 0, MaxTime: 10 * time.Hour.Milliseconds(), Resolution: block.Resolution5m}
+	)
+
+	tests := map[string]struct {
+		fn              string
+		blocks          bucketindex.Blocks
+		expectedWarning bool
+	}{
+		"raw samples required, and time range covered by raw blocks": {
+			fn:     "rate",
+			blocks: bucketindex.Blocks{raw},
+		},
+		"raw samples required, and time range partially covered by downsampled blocks only": {
+			fn:              "rate",
+			blocks:          bucketindex.Blocks{raw, res},
+			expectedWarning: true,
+		},
+		"downsampled samples allowed": {
+			fn:     "last_over_time",
+			blocks: bucketindex.Blocks{raw, res},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", mock.Anything, mock.Anything).Return(testData.blocks, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), error(nil))
+
+			ids := testData.blocks.GetULIDs()
+			stores := &blocksStoreSetMock{mockedResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{mockHintsResponse(ids...)}}: ids,
+				},
+			}}
+
+			q := &blocksStoreQuerier{
+				ctx:           context.Background(),
+				minT:          0,
+				maxT:          20 * time.Hour.Milliseconds(),
+				userID:        "user-1",
+				finder:        finder,
+				stores:        stores,
+				consistency:   NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:        log.NewNopLogger(),
+				metrics:       newBlocksStoreQueryableMetrics(nil),
+				limits:        &blocksStoreLimitsMock{},
+				lookbackDelta: 5 * time.Minute,
+			}
+
+			sp := &storage.SelectHints{
+				Start: 0,
+				End:   20*time.Hour.Milliseconds() - 1,
+				Step:  time.Hour.Milliseconds(),
+				Range: 24 * time.Hour.Milliseconds(),
+				Func:  testData.fn,
+			}
+
+			set := q.selectSorted(sp)
+			require.False(t, set.Next())
+			require.NoError(t, set.Err())
+
+			if testData.expectedWarning {
+				assert.Equal(t, storage.Warnings{errDownsampledBlocksQueried}, set.Warnings())
+			} else {
+				assert.Empty(t, set.Warnings())
+			}
+		})
+	}
+}
+
 func TestBlocksStoreQuerier_MaxLabelsQueryRange(t *testing.T) {
 	const (
 		engineLookbackDelta = 5 * time.Minute
@@ -1732,7 +1909,7 @@ func TestBlocksStoreQuerier_PromQLExecution(t *testing.T) {
 
 			// Instantiate the querier that will be executed to run the query.
 			logger := log.NewNopLogger()
-			queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, logger, nil)
+			queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, 0, logger, nil)
 			require.NoError(t, err)
 			require.NoError(t, services.StartAndAwaitRunning(context.Background(), queryable))
 			defer services.StopAndAwaitTerminated(context.Background(), queryable) // nolint:errcheck
@@ -1884,6 +2061,18 @@ func (m *blocksStoreSetMock) GetClientsFor(_ string, _ []ulid.ULID, _ map[ulid.U
 	return nil, errors.New("unknown data type in the mocked result")
 }
 
+// blocksStoreSetRecorderMock records the blocks to query, and fails the query.
+type blocksStoreSetRecorderMock struct {
+	services.Service
+
+	blockIDs []ulid.ULID
+}
+
+func (m *blocksStoreSetRecorderMock) GetClientsFor(_ string, blockIDs []ulid.ULID, _ map[ulid.ULID][]string) (map[BlocksStoreClient][]ulid.ULID, error) {
+	m.blockIDs = blockIDs
+	return nil, errors.New("no store-gateway available")
+}
+
 type blocksFinderMock struct {
 	services.Service
 	mock.Mock
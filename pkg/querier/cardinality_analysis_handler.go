@@ -87,6 +87,72 @@ func LabelValuesCardinalityHandler(distributor Distributor, limits *validation.O
 	})
 }
 
+// ActiveGrowthCardinalityHandler creates handler for active growth cardinality endpoint.
+func ActiveGrowthCardinalityHandler(d Distributor, limits *validation.Overrides) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		tenantID, err := tenant.TenantID(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !limits.CardinalityAnalysisEnabled(tenantID) {
+			http.Error(w, fmt.Sprintf("cardinality analysis is disabled for the tenant: %v", tenantID), http.StatusBadRequest)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit, err := extractLimit(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response, err := d.ActiveGrowth(ctx, limit)
+		if err != nil {
+			respondFromError(err, w)
+			return
+		}
+		util.WriteJSONResponse(w, toActiveGrowthCardinalityResponse(response))
+	})
+}
+
+// ActiveSeriesCardinalityHandler creates handler for active series cardinality endpoint.
+func ActiveSeriesCardinalityHandler(d Distributor, limits *validation.Overrides) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		tenantID, err := tenant.TenantID(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !limits.CardinalityAnalysisEnabled(tenantID) {
+			http.Error(w, fmt.Sprintf("cardinality analysis is disabled for the tenant: %v", tenantID), http.StatusBadRequest)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		matchers, err := extractSelector(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(matchers) == 0 {
+			http.Error(w, "'selector' param is required", http.StatusBadRequest)
+			return
+		}
+		series, err := d.ActiveSeries(ctx, matchers)
+		if err != nil {
+			respondFromError(err, w)
+			return
+		}
+		util.WriteJSONResponse(w, activeSeriesCardinalityResponse{Data: series})
+	})
+}
+
 func extractLabelNamesRequestParams(r *http.Request) ([]*labels.Matcher, int, error) {
 	err := r.ParseForm()
 	if err != nil {
@@ -307,3 +373,35 @@ type labelValuesCardinalityResponse struct {
 	SeriesCountTotal uint64                  `json:"series_count_total"`
 	Labels           []labelNamesCardinality `json:"labels"`
 }
+
+func toActiveGrowthCardinalityResponse(response *ingester_client.ActiveGrowthResponse) *activeGrowthCardinalityResponse {
+	metricNames := make([]metricNameGrowth, 0, len(response.MetricNames))
+	for _, item := range response.MetricNames {
+		metricNames = append(metricNames, metricNameGrowth{MetricName: item.LabelValue, CreatedSeries: item.CreatedSeries})
+	}
+	labelPairs := make([]labelPairGrowth, 0, len(response.LabelPairs))
+	for _, item := range response.LabelPairs {
+		labelPairs = append(labelPairs, labelPairGrowth{LabelName: item.LabelName, LabelValue: item.LabelValue, CreatedSeries: item.CreatedSeries})
+	}
+	return &activeGrowthCardinalityResponse{MetricNames: metricNames, LabelPairs: labelPairs}
+}
+
+type metricNameGrowth struct {
+	MetricName    string `json:"metric_name"`
+	CreatedSeries uint64 `json:"created_series"`
+}
+
+type labelPairGrowth struct {
+	LabelName     string `json:"label_name"`
+	LabelValue    string `json:"label_value"`
+	CreatedSeries uint64 `json:"created_series"`
+}
+
+type activeGrowthCardinalityResponse struct {
+	MetricNames []metricNameGrowth `json:"metric_names"`
+	LabelPairs  []labelPairGrowth  `json:"label_pairs"`
+}
+
+type activeSeriesCardinalityResponse struct {
+	Data []labels.Labels `json:"data"`
+}
@@ -772,7 +772,175 @@ func TestLabelValuesCardinalityHandler_DistributorError(t *testing.T) {
 	}
 }
 
-// createEnabledHandler creates a cardinalityHandler that can be either a LabelNamesCardinalityHandler or a LabelValuesCardinalityHandler
+func TestActiveGrowthCardinalityHandler(t *testing.T) {
+	distributor := mockDistributorActiveGrowth(5, &client.ActiveGrowthResponse{
+		MetricNames: []*client.SeriesGrowth{
+			{LabelName: labels.MetricName, LabelValue: "foo", CreatedSeries: 20},
+			{LabelName: labels.MetricName, LabelValue: "bar", CreatedSeries: 10},
+		},
+		LabelPairs: []*client.SeriesGrowth{
+			{LabelName: "pod", LabelValue: "a", CreatedSeries: 15},
+		},
+	}, nil)
+	handler := createEnabledHandler(t, ActiveGrowthCardinalityHandler, distributor)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest("/active_growth?limit=5", "team-a"))
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	body := recorder.Result().Body
+	defer func() { _ = body.Close() }()
+
+	bodyContent, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"metric_names": [
+			{"metric_name": "foo", "created_series": 20},
+			{"metric_name": "bar", "created_series": 10}
+		],
+		"label_pairs": [
+			{"label_name": "pod", "label_value": "a", "created_series": 15}
+		]
+	}`, string(bodyContent))
+}
+
+func TestActiveGrowthCardinalityHandler_Errors(t *testing.T) {
+	tests := map[string]struct {
+		request                    *http.Request
+		cardinalityAnalysisEnabled bool
+		distributorError           error
+		expectedStatusCode         int
+		expectedBody               string
+	}{
+		"should return an error if the cardinality analysis feature is disabled": {
+			request:            createRequest("/active_growth", "team-a"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "cardinality analysis is disabled for the tenant: team-a\n",
+		},
+		"should return bad request if the limit param is invalid": {
+			request:                    createRequest("/active_growth?limit=501", "team-a"),
+			cardinalityAnalysisEnabled: true,
+			expectedStatusCode:         http.StatusBadRequest,
+			expectedBody:               "'limit' param cannot be greater than '500'\n",
+		},
+		"should return internal server error if the distributor returns a non httpgrpc error": {
+			request:                    createRequest("/active_growth", "team-a"),
+			cardinalityAnalysisEnabled: true,
+			distributorError:           fmt.Errorf("non httpgrpc error"),
+			expectedStatusCode:         http.StatusInternalServerError,
+			expectedBody:               "non httpgrpc error\n",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			distributor := mockDistributorActiveGrowth(defaultLimit, &client.ActiveGrowthResponse{}, testData.distributorError)
+
+			overrides, err := validation.NewOverrides(validation.Limits{CardinalityAnalysisEnabled: testData.cardinalityAnalysisEnabled}, nil)
+			require.NoError(t, err)
+			handler := ActiveGrowthCardinalityHandler(distributor, overrides)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, testData.request)
+
+			require.Equal(t, testData.expectedStatusCode, recorder.Result().StatusCode)
+
+			body := recorder.Result().Body
+			defer func() { _ = body.Close() }()
+
+			bodyContent, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.Equal(t, testData.expectedBody, string(bodyContent))
+		})
+	}
+}
+
+func TestActiveSeriesCardinalityHandler(t *testing.T) {
+	matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "up")}
+	distributor := mockDistributorActiveSeries(matchers, []labels.Labels{
+		labels.FromStrings(labels.MetricName, "up", "job", "a"),
+		labels.FromStrings(labels.MetricName, "up", "job", "b"),
+	}, nil)
+	handler := createEnabledHandler(t, ActiveSeriesCardinalityHandler, distributor)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest("/active_series?selector=up", "team-a"))
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+	body := recorder.Result().Body
+	defer func() { _ = body.Close() }()
+
+	bodyContent, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"data": [
+			{"__name__": "up", "job": "a"},
+			{"__name__": "up", "job": "b"}
+		]
+	}`, string(bodyContent))
+}
+
+func TestActiveSeriesCardinalityHandler_Errors(t *testing.T) {
+	tests := map[string]struct {
+		request                    *http.Request
+		cardinalityAnalysisEnabled bool
+		distributorError           error
+		expectedStatusCode         int
+		expectedBody               string
+	}{
+		"should return an error if the cardinality analysis feature is disabled": {
+			request:            createRequest("/active_series?selector=up", "team-a"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "cardinality analysis is disabled for the tenant: team-a\n",
+		},
+		"should return bad request if the selector param is missing": {
+			request:                    createRequest("/active_series", "team-a"),
+			cardinalityAnalysisEnabled: true,
+			expectedStatusCode:         http.StatusBadRequest,
+			expectedBody:               "'selector' param is required\n",
+		},
+		"should return bad request if multiple selector params are provided": {
+			request:                    createRequest("/active_series?selector=up&selector=down", "team-a"),
+			cardinalityAnalysisEnabled: true,
+			expectedStatusCode:         http.StatusBadRequest,
+			expectedBody:               "multiple 'selector' params are not allowed\n",
+		},
+		"should return an HTTP response with status code and response body of the httpgrpc error returned by the distributor": {
+			request:                    createRequest("/active_series?selector=up", "team-a"),
+			cardinalityAnalysisEnabled: true,
+			distributorError: httpgrpc.ErrorFromHTTPResponse(&httpgrpc.HTTPResponse{
+				Code: int32(400),
+				Body: []byte("httpgrpc error"),
+			}),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "httpgrpc error",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			distributor := mockDistributorActiveSeries([]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "up")}, []labels.Labels{}, testData.distributorError)
+
+			overrides, err := validation.NewOverrides(validation.Limits{CardinalityAnalysisEnabled: testData.cardinalityAnalysisEnabled}, nil)
+			require.NoError(t, err)
+			handler := ActiveSeriesCardinalityHandler(distributor, overrides)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, testData.request)
+
+			require.Equal(t, testData.expectedStatusCode, recorder.Result().StatusCode)
+
+			body := recorder.Result().Body
+			defer func() { _ = body.Close() }()
+
+			bodyContent, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.Equal(t, testData.expectedBody, string(bodyContent))
+		})
+	}
+}
+
+// createEnabledHandler creates a cardinalityHandler that can be any of the cardinality analysis handlers
 func createEnabledHandler(t *testing.T, cardinalityHandler func(Distributor, *validation.Overrides) http.Handler, distributor *mockDistributor) http.Handler {
 	limits := validation.Limits{CardinalityAnalysisEnabled: true}
 	overrides, err := validation.NewOverrides(limits, nil)
@@ -816,3 +984,15 @@ func mockDistributorLabelValuesCardinality(labelNames []model.LabelName, matcher
 	distributor.On("LabelValuesCardinality", mock.Anything, labelNames, matchers).Return(seriesCount, cardinalityResponse, err)
 	return distributor
 }
+
+func mockDistributorActiveGrowth(limit int, response *client.ActiveGrowthResponse, err error) *mockDistributor {
+	distributor := &mockDistributor{}
+	distributor.On("ActiveGrowth", mock.Anything, limit).Return(response, err)
+	return distributor
+}
+
+func mockDistributorActiveSeries(matchers []*labels.Matcher, series []labels.Labels, err error) *mockDistributor {
+	distributor := &mockDistributor{}
+	distributor.On("ActiveSeries", mock.Anything, matchers).Return(series, err)
+	return distributor
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -337,6 +338,88 @@ func BenchmarkDistributorQueryable_Select(b *testing.B) {
 	}
 }
 
+func TestIngesterStreamingNativeHistograms(t *testing.T) {
+	const (
+		mint = 0
+		maxt = 10000
+	)
+
+	histogramChunk, err := chunk.NewForEncoding(chunk.PrometheusHistogramChunk)
+	require.NoError(t, err)
+	for _, ts := range []int64{1500, 2500} {
+		_, err := histogramChunk.AddHistogram(ts, tsdb.GenerateTestHistogram(int(ts)))
+		require.NoError(t, err)
+	}
+	histogramChunks, err := chunkcompat.ToChunks([]chunk.Chunk{
+		chunk.NewChunk(nil, histogramChunk, 1500, 2500),
+	})
+	require.NoError(t, err)
+
+	samples := []mimirpb.Sample{{Value: 1, TimestampMs: 1000}, {Value: 2, TimestampMs: 2000}}
+
+	d := &mockDistributor{}
+	d.On("QueryStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		&client.QueryStreamResponse{
+			Chunkseries: []client.TimeSeriesChunk{
+				{
+					Labels: []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "one"}},
+					Chunks: append(convertToChunks(t, samples), histogramChunks...),
+				},
+			},
+			Timeseries: []mimirpb.TimeSeries{
+				{
+					Labels:  []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "two"}},
+					Samples: samples,
+					Histograms: []mimirpb.Histogram{
+						mimirpb.FromHistogramToHistogramProto(1500, tsdb.GenerateTestHistogram(1500)),
+						mimirpb.FromHistogramToHistogramProto(2500, tsdb.GenerateTestHistogram(2500)),
+					},
+				},
+			},
+		},
+		nil)
+
+	for name, cfg := range map[string]Config{
+		"merge chunks":    {},
+		"batch iterators": {BatchIterators: true},
+		"iterators":       {Iterators: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), "0")
+			queryable := newDistributorQueryable(d, getChunksIteratorFunction(cfg), 0, log.NewNopLogger())
+			querier, err := queryable.Querier(ctx, mint, maxt)
+			require.NoError(t, err)
+
+			seriesSet := querier.Select(true, &storage.SelectHints{Start: mint, End: maxt}, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".*"))
+			require.NoError(t, seriesSet.Err())
+
+			for _, name := range []string{"one", "two"} {
+				require.True(t, seriesSet.Next())
+				require.Equal(t, labels.FromStrings(labels.MetricName, name), seriesSet.At().Labels())
+
+				it := seriesSet.At().Iterator(nil)
+				require.Equal(t, chunkenc.ValFloat, it.Next())
+				require.Equal(t, int64(1000), it.AtT())
+				require.Equal(t, chunkenc.ValHistogram, it.Next())
+				ts, h := it.AtHistogram()
+				require.Equal(t, int64(1500), ts)
+				require.Equal(t, tsdb.GenerateTestHistogram(1500).Count, h.Count)
+				require.Equal(t, chunkenc.ValFloat, it.Next())
+				require.Equal(t, int64(2000), it.AtT())
+				require.Equal(t, chunkenc.ValHistogram, it.Next())
+				ts, fh := it.AtFloatHistogram()
+				require.Equal(t, int64(2500), ts)
+				require.Equal(t, float64(tsdb.GenerateTestHistogram(2500).Count), fh.Count)
+				require.Equal(t, chunkenc.ValNone, it.Next())
+				require.NoError(t, it.Err())
+			}
+
+			require.False(t, seriesSet.Next())
+			require.NoError(t, seriesSet.Err())
+		})
+	}
+}
+
 func verifySeries(t *testing.T, series storage.Series, l labels.Labels, samples []mimirpb.Sample) {
 	require.Equal(t, l, series.Labels())
 
@@ -411,3 +494,13 @@ func (m *mockDistributor) LabelValuesCardinality(ctx context.Context, labelNames
 	args := m.Called(ctx, labelNames, matchers)
 	return args.Get(0).(uint64), args.Get(1).(*client.LabelValuesCardinalityResponse), args.Error(2)
 }
+
+func (m *mockDistributor) ActiveGrowth(ctx context.Context, limit int) (*client.ActiveGrowthResponse, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).(*client.ActiveGrowthResponse), args.Error(1)
+}
+
+func (m *mockDistributor) ActiveSeries(ctx context.Context, matchers []*labels.Matcher) ([]labels.Labels, error) {
+	args := m.Called(ctx, matchers)
+	return args.Get(0).([]labels.Labels), args.Error(1)
+}
@@ -6,7 +6,10 @@
 package querier
 
 import (
+	"fmt"
+
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 
 	"github.com/grafana/mimir/pkg/storage/chunk"
@@ -28,3 +31,30 @@ func mergeChunks(chunks []chunk.Chunk, from, through model.Time) chunkenc.Iterat
 	merged := util.MergeNSampleSets(samples...)
 	return series.NewConcreteSeriesIterator(series.NewConcreteSeries(nil, merged))
 }
+
+// withNativeHistograms wraps the chunks iterator function, so that the chunks of the series holding native histograms,
+// which the merge iterators don't support, are iterated by chainChunks instead.
+func withNativeHistograms(iteratorFunc chunkIteratorFunc) chunkIteratorFunc {
+	return func(chunks []chunk.Chunk, from, through model.Time) chunkenc.Iterator {
+		for _, c := range chunks {
+			if enc := c.Data.Encoding(); enc == chunk.PrometheusHistogramChunk || enc == chunk.PrometheusFloatHistogramChunk {
+				return chainChunks(chunks)
+			}
+		}
+		return iteratorFunc(chunks, from, through)
+	}
+}
+
+// chainChunks returns an iterator over the samples of all the value types of the chunks. Samples with the same
+// timestamp in overlapping chunks, like the ones received from multiple ingesters, are returned once.
+func chainChunks(chunks []chunk.Chunk) chunkenc.Iterator {
+	its := make([]chunkenc.Iterator, 0, len(chunks))
+	for _, c := range chunks {
+		pc := chunk.PrometheusChunk(c.Data)
+		if pc == nil {
+			return series.NewErrIterator(fmt.Errorf("unsupported chunk encoding %v", c.Data.Encoding()))
+		}
+		its = append(its, pc.Iterator(nil))
+	}
+	return storage.ChainSampleIteratorFromIterators(nil, its)
+}
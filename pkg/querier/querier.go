@@ -10,6 +10,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/weaveworks/common/httpgrpc"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/grafana/dskit/tenant"
@@ -31,6 +33,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/lazyquery"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
+	"github.com/grafana/mimir/pkg/util/ephemeral"
 	"github.com/grafana/mimir/pkg/util/limiter"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -91,11 +94,11 @@ func (cfg *Config) Validate() error {
 
 func getChunksIteratorFunction(cfg Config) chunkIteratorFunc {
 	if cfg.BatchIterators {
-		return batch.NewChunkMergeIterator
+		return withNativeHistograms(batch.NewChunkMergeIterator)
 	} else if cfg.Iterators {
-		return iterators.NewChunkMergeIterator
+		return withNativeHistograms(iterators.NewChunkMergeIterator)
 	}
-	return mergeChunks
+	return withNativeHistograms(mergeChunks)
 }
 
 // New builds a queryable and promql engine.
@@ -189,11 +192,13 @@ func NewQueryable(distributor QueryableWithFilter, stores []QueryableWithFilter,
 			logger:             logger,
 		}
 
+		dqr, err := distributor.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+		q.ephemeralQuerier = ephemeralStorageQuerier{Querier: dqr}
+
 		if distributor.UseQueryable(now, mint, maxt) {
-			dqr, err := distributor.Querier(ctx, mint, maxt)
-			if err != nil {
-				return nil, err
-			}
 			q.queriers = append(q.queriers, dqr)
 		}
 
@@ -218,6 +223,10 @@ func NewQueryable(distributor QueryableWithFilter, stores []QueryableWithFilter,
 type querier struct {
 	queriers []storage.Querier
 
+	// ephemeralQuerier queries the ephemeral storage of the ingesters. It's only used when
+	// the ephemeral storage is selected by the storage label matcher.
+	ephemeralQuerier storage.Querier
+
 	chunkIterFn chunkIteratorFunc
 	ctx         context.Context
 	mint, maxt  int64
@@ -248,6 +257,11 @@ func (q querier) Select(_ bool, sp *storage.SelectHints, matchers ...*labels.Mat
 		return storage.ErrSeriesSet(err)
 	}
 
+	storageType, matchers, err := removeStorageMatcherAndGetStorageType(matchers)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
 	// Validate query time range. Even if the time range has already been validated when we created
 	// the querier, we need to check it again here because the time range specified in hints may be
 	// different.
@@ -275,19 +289,27 @@ func (q querier) Select(_ bool, sp *storage.SelectHints, matchers ...*labels.Mat
 		return storage.ErrSeriesSet(validation.NewMaxQueryLengthError(endTime.Sub(startTime), maxQueryLength))
 	}
 
-	if len(q.queriers) == 1 {
-		return q.queriers[0].Select(true, sp, matchers...)
+	queriers := q.queriers
+	switch storageType {
+	case ephemeral.EphemeralStorageLabelValue:
+		queriers = []storage.Querier{q.ephemeralQuerier}
+	case ephemeral.AllStorageLabelValue:
+		queriers = append(queriers[:len(queriers):len(queriers)], q.ephemeralQuerier)
 	}
 
-	sets := make(chan storage.SeriesSet, len(q.queriers))
-	for _, querier := range q.queriers {
+	if len(queriers) == 1 {
+		return queriers[0].Select(true, sp, matchers...)
+	}
+
+	sets := make(chan storage.SeriesSet, len(queriers))
+	for _, querier := range queriers {
 		go func(querier storage.Querier) {
 			sets <- querier.Select(true, sp, matchers...)
 		}(querier)
 	}
 
 	var result []storage.SeriesSet
-	for range q.queriers {
+	for range queriers {
 		select {
 		case set := <-sets:
 			result = append(result, set)
@@ -430,6 +452,40 @@ func (q querier) mergeSeriesSets(sets []storage.SeriesSet) storage.SeriesSet {
 	return storage.NewMergeSeriesSet(otherSets, storage.ChainedSeriesMerge)
 }
 
+// removeStorageMatcherAndGetStorageType returns the storage type selected by the storage label matcher, and the
+// matchers without it. If the storage label matcher is not found, it returns PersistentStorageLabelValue.
+// The original slice of matchers is not modified.
+func removeStorageMatcherAndGetStorageType(matchers []*labels.Matcher) (string, []*labels.Matcher, error) {
+	val, idx, err := ephemeral.FindStorageLabelMatcher(matchers)
+	if err != nil {
+		return "", nil, httpgrpc.Errorf(http.StatusBadRequest, err.Error())
+	}
+	if idx < 0 {
+		return ephemeral.PersistentStorageLabelValue, matchers, nil
+	}
+
+	switch val {
+	case ephemeral.PersistentStorageLabelValue, ephemeral.EphemeralStorageLabelValue, ephemeral.AllStorageLabelValue:
+	default:
+		return "", nil, httpgrpc.Errorf(http.StatusBadRequest, "invalid value of %s label: %s", ephemeral.StorageLabelName, val)
+	}
+
+	filtered := make([]*labels.Matcher, 0, len(matchers)-1)
+	filtered = append(filtered, matchers[:idx]...)
+	filtered = append(filtered, matchers[idx+1:]...)
+	return val, filtered, nil
+}
+
+// ephemeralStorageQuerier is a storage.Querier which selects the series of the ephemeral storage of the ingesters.
+type ephemeralStorageQuerier struct {
+	storage.Querier
+}
+
+func (q ephemeralStorageQuerier) Select(sortSeries bool, sp *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	matchers = append(matchers[:len(matchers):len(matchers)], labels.MustNewMatcher(labels.MatchEqual, ephemeral.StorageLabelName, ephemeral.EphemeralStorageLabelValue))
+	return q.Querier.Select(sortSeries, sp, matchers...)
+}
+
 type sliceSeriesSet struct {
 	series []storage.Series
 	ix     int
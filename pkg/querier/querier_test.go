@@ -8,6 +8,7 @@ package querier
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 	"testing"
 	"time"
@@ -28,10 +29,13 @@ import (
 	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/user"
 
 	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/ephemeral"
 )
 
 const (
@@ -862,6 +866,14 @@ func (m *errDistributor) LabelValuesCardinality(ctx context.Context, labelNames
 	return 0, nil, errDistributorError
 }
 
+func (m *errDistributor) ActiveGrowth(ctx context.Context, limit int) (*client.ActiveGrowthResponse, error) {
+	return nil, errDistributorError
+}
+
+func (m *errDistributor) ActiveSeries(ctx context.Context, matchers []*labels.Matcher) ([]labels.Labels, error) {
+	return nil, errDistributorError
+}
+
 type emptyDistributor struct{}
 
 func (d *emptyDistributor) LabelNamesAndValues(_ context.Context, _ []*labels.Matcher) (*client.LabelNamesAndValuesResponse, error) {
@@ -900,6 +912,14 @@ func (d *emptyDistributor) LabelValuesCardinality(ctx context.Context, labelName
 	return 0, nil, nil
 }
 
+func (d *emptyDistributor) ActiveGrowth(ctx context.Context, limit int) (*client.ActiveGrowthResponse, error) {
+	return nil, nil
+}
+
+func (d *emptyDistributor) ActiveSeries(ctx context.Context, matchers []*labels.Matcher) ([]labels.Labels, error) {
+	return nil, nil
+}
+
 func TestQuerier_QueryStoreAfterConfig(t *testing.T) {
 	testCases := []struct {
 		name                 string
@@ -996,6 +1016,100 @@ func TestQuerier_QueryStoreAfterConfig(t *testing.T) {
 	}
 }
 
+func TestQuerier_Select_StorageLabelMatcher(t *testing.T) {
+	overrides, err := validation.NewOverrides(defaultLimitsConfig(), nil)
+	require.NoError(t, err)
+
+	ingesters := UseAlwaysQueryable(storage.QueryableFunc(func(context.Context, int64, int64) (storage.Querier, error) {
+		return storageTestQuerier{source: "ingesters"}, nil
+	}))
+	store := UseAlwaysQueryable(storage.QueryableFunc(func(context.Context, int64, int64) (storage.Querier, error) {
+		return storageTestQuerier{source: "store"}, nil
+	}))
+	queryable := NewQueryable(ingesters, []QueryableWithFilter{store}, nil, Config{}, overrides, log.NewNopLogger())
+
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	now := time.Now()
+	q, err := queryable.Querier(ctx, util.TimeToMillis(now.Add(-time.Hour)), util.TimeToMillis(now))
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		matchers       []*labels.Matcher
+		expectedSeries []string
+		expectedErr    string
+	}{
+		"no storage label matcher": {
+			expectedSeries: []string{
+				`{__name__="metric", source="ingesters"}`,
+				`{__name__="metric", source="store"}`,
+			},
+		},
+		"persistent storage": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, ephemeral.StorageLabelName, ephemeral.PersistentStorageLabelValue)},
+			expectedSeries: []string{
+				`{__name__="metric", source="ingesters"}`,
+				`{__name__="metric", source="store"}`,
+			},
+		},
+		"ephemeral storage": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, ephemeral.StorageLabelName, ephemeral.EphemeralStorageLabelValue)},
+			expectedSeries: []string{
+				`{__mimir_storage__="ephemeral", __name__="metric", source="ingesters"}`,
+			},
+		},
+		"all storages": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, ephemeral.StorageLabelName, ephemeral.AllStorageLabelValue)},
+			expectedSeries: []string{
+				`{__mimir_storage__="ephemeral", __name__="metric", source="ingesters"}`,
+				`{__name__="metric", source="ingesters"}`,
+				`{__name__="metric", source="store"}`,
+			},
+		},
+		"invalid storage label value": {
+			matchers:    []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, ephemeral.StorageLabelName, "invalid")},
+			expectedErr: "invalid value of __mimir_storage__ label: invalid",
+		},
+		"invalid storage label matcher type": {
+			matchers:    []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, ephemeral.StorageLabelName, ".+")},
+			expectedErr: ephemeral.ErrInvalidStorageMatcherType.Error(),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			matchers := append([]*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "metric")}, tc.matchers...)
+			set := q.Select(true, nil, matchers...)
+
+			var actual []string
+			for set.Next() {
+				actual = append(actual, set.At().Labels().String())
+			}
+			if tc.expectedErr != "" {
+				resp, ok := httpgrpc.HTTPResponseFromError(set.Err())
+				require.True(t, ok)
+				assert.Equal(t, int32(http.StatusBadRequest), resp.Code)
+				assert.Equal(t, tc.expectedErr, string(resp.Body))
+				return
+			}
+			require.NoError(t, set.Err())
+			assert.Equal(t, tc.expectedSeries, actual)
+		})
+	}
+}
+
+// storageTestQuerier returns a single series, labelled with its source and with the storage type selected by
+// the storage label matcher it receives.
+type storageTestQuerier struct {
+	storage.Querier
+	source string
+}
+
+func (q storageTestQuerier) Select(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	b := labels.NewBuilder(labels.FromStrings(labels.MetricName, "metric", "source", q.source))
+	if storageType, idx, _ := ephemeral.FindStorageLabelMatcher(matchers); idx >= 0 {
+		b.Set(ephemeral.StorageLabelName, storageType)
+	}
+	return series.NewConcreteSeriesSet([]storage.Series{series.NewConcreteSeries(b.Labels(nil), nil)})
+}
+
 func TestUseAlwaysQueryable(t *testing.T) {
 	m := &mockQueryableWithFilter{}
 	qwf := UseAlwaysQueryable(m)
@@ -8,7 +8,6 @@ package querier
 import (
 	"sort"
 
-	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
@@ -54,9 +53,17 @@ type timeseries struct {
 }
 
 // timeSeriesSeriesIterator is a wrapper around a mimirpb.TimeSeries to implement the chunkenc.Iterator.
+// It iterates the float samples and the histograms of the series in timestamp order.
 type timeSeriesSeriesIterator struct {
 	ts *timeseries
-	i  int
+
+	// Index of the next float sample and histogram.
+	nextSample    int
+	nextHistogram int
+
+	// Type and index of the current value.
+	currType chunkenc.ValueType
+	curr     int
 }
 
 type byTimeSeriesLabels []mimirpb.TimeSeries
@@ -77,58 +84,90 @@ func (t *timeseries) Labels() labels.Labels {
 func (t *timeseries) Iterator(_ chunkenc.Iterator) chunkenc.Iterator {
 	return &timeSeriesSeriesIterator{
 		ts: t,
-		i:  -1,
 	}
 }
 
 // Seek implements implements chunkenc.Iterator.
 func (t *timeSeriesSeriesIterator) Seek(s int64) chunkenc.ValueType {
-	offset := 0
-	if t.i > 0 {
-		offset = t.i // only advance via Seek
+	// Only advance via Seek.
+	if t.currType != chunkenc.ValNone && t.AtT() >= s {
+		return t.currType
 	}
 
-	t.i = sort.Search(len(t.ts.series.Samples[offset:]), func(i int) bool {
-		return t.ts.series.Samples[offset+i].TimestampMs >= s
-	}) + offset
+	samples, histograms := t.ts.series.Samples, t.ts.series.Histograms
+	t.nextSample += sort.Search(len(samples[t.nextSample:]), func(i int) bool {
+		return samples[t.nextSample+i].TimestampMs >= s
+	})
+	t.nextHistogram += sort.Search(len(histograms[t.nextHistogram:]), func(i int) bool {
+		return histograms[t.nextHistogram+i].Timestamp >= s
+	})
 
-	if t.i < len(t.ts.series.Samples) {
-		return chunkenc.ValFloat
-	}
-	return chunkenc.ValNone
+	return t.Next()
 }
 
 // At implements the implements chunkenc.Iterator.
 func (t *timeSeriesSeriesIterator) At() (int64, float64) {
-	if t.i < 0 || t.i >= len(t.ts.series.Samples) {
+	if t.currType != chunkenc.ValFloat {
 		return 0, 0
 	}
-	return t.ts.series.Samples[t.i].TimestampMs, t.ts.series.Samples[t.i].Value
+	s := t.ts.series.Samples[t.curr]
+	return s.TimestampMs, s.Value
 }
 
 // AtHistogram implements chunkenc.Iterator.
 func (t *timeSeriesSeriesIterator) AtHistogram() (int64, *histogram.Histogram) {
-	panic(errors.New("timeSeriesSeriesIterator: AtFloatHistogram is not implemented"))
+	if t.currType != chunkenc.ValHistogram {
+		return 0, nil
+	}
+	h := t.ts.series.Histograms[t.curr]
+	return h.Timestamp, mimirpb.FromHistogramProtoToHistogram(h)
 }
 
 // AtFloatHistogram implements chunkenc.Iterator.
 func (t *timeSeriesSeriesIterator) AtFloatHistogram() (int64, *histogram.FloatHistogram) {
-	panic(errors.New("timeSeriesSeriesIterator: AtFloatHistogram is not implemented"))
+	switch t.currType {
+	case chunkenc.ValHistogram:
+		h := t.ts.series.Histograms[t.curr]
+		return h.Timestamp, mimirpb.FromHistogramProtoToHistogram(h).ToFloat()
+	case chunkenc.ValFloatHistogram:
+		h := t.ts.series.Histograms[t.curr]
+		return h.Timestamp, mimirpb.FromHistogramProtoToFloatHistogram(h)
+	default:
+		return 0, nil
+	}
 }
 
 // AtT implements implements chunkenc.Iterator.
 func (t *timeSeriesSeriesIterator) AtT() int64 {
-	ts, _ := t.At()
-	return ts
+	switch t.currType {
+	case chunkenc.ValFloat:
+		return t.ts.series.Samples[t.curr].TimestampMs
+	case chunkenc.ValHistogram, chunkenc.ValFloatHistogram:
+		return t.ts.series.Histograms[t.curr].Timestamp
+	default:
+		return 0
+	}
 }
 
 // Next implements implements chunkenc.Iterator.
 func (t *timeSeriesSeriesIterator) Next() chunkenc.ValueType {
-	t.i++
-	if t.i < len(t.ts.series.Samples) {
-		return chunkenc.ValFloat
+	samples, histograms := t.ts.series.Samples, t.ts.series.Histograms
+
+	switch {
+	case t.nextSample < len(samples) && (t.nextHistogram >= len(histograms) || samples[t.nextSample].TimestampMs <= histograms[t.nextHistogram].Timestamp):
+		t.curr, t.currType = t.nextSample, chunkenc.ValFloat
+		t.nextSample++
+	case t.nextHistogram < len(histograms):
+		t.curr, t.currType = t.nextHistogram, chunkenc.ValHistogram
+		if histograms[t.nextHistogram].IsFloatHistogram() {
+			t.currType = chunkenc.ValFloatHistogram
+		}
+		t.nextHistogram++
+	default:
+		t.currType = chunkenc.ValNone
 	}
-	return chunkenc.ValNone
+
+	return t.currType
 }
 
 // Err implements the implements chunkenc.Iterator.
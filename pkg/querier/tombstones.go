@@ -0,0 +1,373 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/golang/groupcache/singleflight"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tombstones"
+	"github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+const (
+	// tombstonesRefreshInterval is how frequently the series deletion requests of a tenant are reloaded from the bucket.
+	tombstonesRefreshInterval = time.Minute
+
+	// tombstonesIdleTimeout is how long the series deletion requests of a tenant are kept in memory after they
+	// have been requested the last time.
+	tombstonesIdleTimeout = time.Hour
+
+	// readTombstonesTimeout is the maximum allowed time when reading the series deletion requests of a tenant.
+	readTombstonesTimeout = 15 * time.Second
+)
+
+// TombstonesLimits is the interface used to check whether series deletion is enabled for a tenant.
+type TombstonesLimits interface {
+	CompactorSeriesDeletionEnabled(tenantID string) bool
+}
+
+// TombstonesLoader lazy loads the series deletion requests of the tenants from the bucket and, once loaded for
+// the first time, keeps them updated in background. Loaded requests are offloaded once the idle timeout expires.
+type TombstonesLoader struct {
+	services.Service
+
+	limits TombstonesLimits
+	logger log.Logger
+
+	// The bucket client is only created once the series deletion requests of a tenant are loaded, so that
+	// it's not created at all when the series deletion is disabled.
+	newBucket func() (objstore.Bucket, error)
+	bktMtx    sync.Mutex
+	bkt       objstore.Bucket
+
+	// Collapses the concurrent loads of the series deletion requests of the same tenant.
+	loads singleflight.Group
+
+	mtx    sync.RWMutex
+	loaded map[string]*loadedTombstones
+}
+
+type loadedTombstones struct {
+	tombstones      []*mimir_tsdb.Tombstone
+	resultsCacheGen string
+
+	// Unix timestamp (seconds) of when the series deletion requests have been requested the last time.
+	requestedAt atomic.Int64
+}
+
+func NewTombstonesLoader(newBucket func() (objstore.Bucket, error), limits TombstonesLimits, logger log.Logger) *TombstonesLoader {
+	l := &TombstonesLoader{
+		limits:    limits,
+		logger:    logger,
+		newBucket: newBucket,
+		loaded:    map[string]*loadedTombstones{},
+	}
+
+	l.Service = services.NewTimerService(tombstonesRefreshInterval, nil, l.refresh, nil)
+	return l
+}
+
+// Tombstones returns the series deletion requests of the tenant which must be applied at query time:
+// both the pending ones, and the processed ones whose original blocks may still be queried.
+func (l *TombstonesLoader) Tombstones(_ context.Context, userID string) ([]*mimir_tsdb.Tombstone, error) {
+	tombstones, _, err := l.get(userID)
+	return tombstones, err
+}
+
+// ResultsCacheGen returns the results cache generation of the tenants, matching the series deletion requests
+// returned by Tombstones. The generation of a tenant is empty if the series deletion is disabled for the tenant,
+// or if no request has ever been created.
+func (l *TombstonesLoader) ResultsCacheGen(_ context.Context, tenantIDs []string) (string, error) {
+	gens := make([]string, 0, len(tenantIDs))
+	for _, userID := range tenantIDs {
+		_, gen, err := l.get(userID)
+		if err != nil {
+			return "", err
+		}
+		gens = append(gens, gen)
+	}
+
+	// Don't return a non-empty generation if no tenant has one, so that it matches the missing header.
+	gen := strings.Join(gens, ",")
+	if strings.Trim(gen, ",") == "" {
+		return "", nil
+	}
+	return gen, nil
+}
+
+func (l *TombstonesLoader) get(userID string) ([]*mimir_tsdb.Tombstone, string, error) {
+	if !l.limits.CompactorSeriesDeletionEnabled(userID) {
+		return nil, "", nil
+	}
+
+	l.mtx.RLock()
+	entry, ok := l.loaded[userID]
+	if ok {
+		// We don't check if the requests are stale because it's the responsibility of the background job to
+		// keep them updated.
+		entry.requestedAt.Store(time.Now().Unix())
+		tombstones, gen := entry.tombstones, entry.resultsCacheGen
+		l.mtx.RUnlock()
+		return tombstones, gen, nil
+	}
+	l.mtx.RUnlock()
+
+	loaded, err := l.loads.Do(userID, func() (interface{}, error) {
+		tombstones, gen, err := l.load(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &loadedTombstones{tombstones: tombstones, resultsCacheGen: gen}
+		entry.requestedAt.Store(time.Now().Unix())
+
+		l.mtx.Lock()
+		l.loaded[userID] = entry
+		l.mtx.Unlock()
+
+		return entry, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The entry may be updated by the background job in the meanwhile.
+	entry = loaded.(*loadedTombstones)
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return entry.tombstones, entry.resultsCacheGen, nil
+}
+
+// load reads the series deletion requests of the tenant which haven't been cancelled, and the results cache
+// generation, from the bucket.
+func (l *TombstonesLoader) load(userID string) ([]*mimir_tsdb.Tombstone, string, error) {
+	bkt, err := l.bucket()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The load is shared by all the concurrent queries of the tenant, so it doesn't depend on their context.
+	ctx, cancel := context.WithTimeout(context.Background(), readTombstonesTimeout)
+	defer cancel()
+
+	// The generation is read before the requests, and bumped after the requests are written: this guarantees
+	// that the loaded requests are at least as recent as the loaded generation.
+	gen, err := mimir_tsdb.ReadResultsCacheGen(ctx, bkt, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	all, err := mimir_tsdb.ListTombstones(ctx, bkt, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	active := make([]*mimir_tsdb.Tombstone, 0, len(all))
+	for _, t := range all {
+		if t.State != mimir_tsdb.TombstoneCancelled {
+			active = append(active, t)
+		}
+	}
+	return active, gen, nil
+}
+
+func (l *TombstonesLoader) bucket() (objstore.Bucket, error) {
+	l.bktMtx.Lock()
+	defer l.bktMtx.Unlock()
+
+	if l.bkt == nil {
+		bkt, err := l.newBucket()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create tombstones bucket client")
+		}
+		l.bkt = bkt
+	}
+	return l.bkt, nil
+}
+
+// refresh offloads the series deletion requests which haven't been requested since the idle timeout, or whose
+// tenant has the series deletion disabled, and reloads the other ones.
+func (l *TombstonesLoader) refresh(context.Context) error {
+	now := time.Now()
+
+	var toUpdate, toDelete []string
+	l.mtx.RLock()
+	for userID, entry := range l.loaded {
+		if now.Sub(time.Unix(entry.requestedAt.Load(), 0)) >= tombstonesIdleTimeout || !l.limits.CompactorSeriesDeletionEnabled(userID) {
+			toDelete = append(toDelete, userID)
+		} else {
+			toUpdate = append(toUpdate, userID)
+		}
+	}
+	l.mtx.RUnlock()
+
+	l.mtx.Lock()
+	for _, userID := range toDelete {
+		delete(l.loaded, userID)
+	}
+	l.mtx.Unlock()
+
+	for _, userID := range toUpdate {
+		tombstones, gen, err := l.load(userID)
+		if err != nil {
+			// Keep the previous requests, they are reloaded at the next refresh.
+			level.Warn(l.logger).Log("msg", "unable to reload series deletion requests", "user", userID, "err", err)
+			continue
+		}
+
+		l.mtx.Lock()
+		if entry, ok := l.loaded[userID]; ok {
+			entry.tombstones, entry.resultsCacheGen = tombstones, gen
+		}
+		l.mtx.Unlock()
+	}
+
+	// Never return error, otherwise the service terminates.
+	return nil
+}
+
+// NewResultsCacheGenHandler returns a handler setting the results cache generation of the queried tenants in the
+// response headers, so that the query-frontend doesn't cache the results filtered with outdated series deletion
+// requests.
+func NewResultsCacheGenHandler(next http.Handler, loader *TombstonesLoader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantIDs, err := tenant.TenantIDs(r.Context())
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A missing generation doesn't match the one of the query-frontend, if any, so the response is not cached.
+		if gen, err := loader.ResultsCacheGen(r.Context(), tenantIDs); err == nil && gen != "" {
+			w.Header().Set(mimir_tsdb.ResultsCacheGenHeader, gen)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewTombstonesQueryable returns a queryable filtering out the series, and samples, matching the series
+// deletion requests of the tenant. Label names and values queries are not filtered.
+func NewTombstonesQueryable(q storage.Queryable, loader *TombstonesLoader) storage.SampleAndChunkQueryable {
+	return NewSampleAndChunkQueryable(storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		querier, err := q.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			// Queries spanning multiple tenants are not filtered.
+			return querier, nil
+		}
+
+		all, err := loader.Tombstones(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		var overlapping []*mimir_tsdb.Tombstone
+		for _, t := range all {
+			if t.Overlaps(mint, maxt) {
+				overlapping = append(overlapping, t)
+			}
+		}
+		if len(overlapping) == 0 {
+			return querier, nil
+		}
+
+		return &tombstonesQuerier{Querier: querier, tombstones: overlapping, mint: mint, maxt: maxt}, nil
+	}))
+}
+
+type tombstonesQuerier struct {
+	storage.Querier
+
+	tombstones []*mimir_tsdb.Tombstone
+	mint, maxt int64
+}
+
+func (q *tombstonesQuerier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	mint, maxt := q.mint, q.maxt
+	if hints != nil {
+		mint, maxt = hints.Start, hints.End
+	}
+
+	return &tombstonesSeriesSet{
+		SeriesSet:  q.Querier.Select(sortSeries, hints, matchers...),
+		tombstones: q.tombstones,
+		mint:       mint,
+		maxt:       maxt,
+	}
+}
+
+// tombstonesSeriesSet drops the series whose samples in the queried time range have all been deleted,
+// and filters out the deleted samples from the other matching series.
+type tombstonesSeriesSet struct {
+	storage.SeriesSet
+
+	tombstones []*mimir_tsdb.Tombstone
+	mint, maxt int64
+	curr       storage.Series
+}
+
+func (s *tombstonesSeriesSet) Next() bool {
+	for s.SeriesSet.Next() {
+		series := s.SeriesSet.At()
+
+		var intervals tombstones.Intervals
+		for _, t := range s.tombstones {
+			if t.MatchesSeries(series.Labels()) {
+				intervals = intervals.Add(tombstones.Interval{Mint: t.StartTime, Maxt: t.EndTime})
+			}
+		}
+
+		switch {
+		case len(intervals) == 0:
+			s.curr = series
+		case (tombstones.Interval{Mint: s.mint, Maxt: s.maxt}).IsSubrange(intervals):
+			continue
+		default:
+			s.curr = &tombstonesSeries{Series: series, intervals: intervals}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (s *tombstonesSeriesSet) At() storage.Series {
+	return s.curr
+}
+
+type tombstonesSeries struct {
+	storage.Series
+
+	intervals tombstones.Intervals
+}
+
+func (s *tombstonesSeries) Iterator(it chunkenc.Iterator) chunkenc.Iterator {
+	if deleted, ok := it.(*tsdb.DeletedIterator); ok {
+		deleted.Iter = s.Series.Iterator(deleted.Iter)
+		deleted.Intervals = s.intervals
+		return deleted
+	}
+
+	return &tsdb.DeletedIterator{Iter: s.Series.Iterator(nil), Intervals: s.intervals}
+}
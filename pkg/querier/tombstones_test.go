@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/storage/series"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+type tombstonesLimitsMock map[string]bool
+
+func (m tombstonesLimitsMock) CompactorSeriesDeletionEnabled(tenantID string) bool {
+	return m[tenantID]
+}
+
+func TestTombstonesLoader(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	writeTombstone := func(userID, selector string, state mimir_tsdb.TombstoneState) *mimir_tsdb.Tombstone {
+		ts, err := mimir_tsdb.NewTombstone(0, 100, []string{selector}, time.Now())
+		require.NoError(t, err)
+		ts.State = state
+		require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt, userID, nil, ts))
+		return ts
+	}
+
+	pending := writeTombstone("user-1", `{job="pending"}`, mimir_tsdb.TombstonePending)
+	processed := writeTombstone("user-1", `{job="processed"}`, mimir_tsdb.TombstoneProcessed)
+	writeTombstone("user-1", `{job="cancelled"}`, mimir_tsdb.TombstoneCancelled)
+	writeTombstone("user-2", `{job="disabled"}`, mimir_tsdb.TombstonePending)
+
+	bucketCreations := 0
+	loader := NewTombstonesLoader(func() (objstore.Bucket, error) {
+		bucketCreations++
+		return bkt, nil
+	}, tombstonesLimitsMock{"user-1": true}, log.NewNopLogger())
+
+	// Tombstones of tenants with series deletion disabled should not be loaded, nor should the bucket client be created.
+	actual, err := loader.Tombstones(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+	assert.Equal(t, 0, bucketCreations)
+
+	actual, err = loader.Tombstones(ctx, "user-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*mimir_tsdb.Tombstone{pending, processed}, actual)
+	assert.Equal(t, 1, bucketCreations)
+
+	// Tombstones should be cached.
+	added := writeTombstone("user-1", `{job="new"}`, mimir_tsdb.TombstonePending)
+	actual, err = loader.Tombstones(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Len(t, actual, 2)
+
+	// Tombstones should be reloaded in background, along with the results cache generation.
+	gen, err := loader.ResultsCacheGen(ctx, []string{"user-1"})
+	require.NoError(t, err)
+	assert.Empty(t, gen)
+
+	require.NoError(t, mimir_tsdb.BumpResultsCacheGen(ctx, bkt, "user-1", nil, time.Unix(100, 0)))
+	require.NoError(t, loader.refresh(ctx))
+	actual, err = loader.Tombstones(ctx, "user-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*mimir_tsdb.Tombstone{pending, processed, added}, actual)
+	assert.Equal(t, 1, bucketCreations)
+
+	gen, err = loader.ResultsCacheGen(ctx, []string{"user-1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, gen)
+
+	// The generation of the tenants with series deletion disabled should be empty.
+	multiGen, err := loader.ResultsCacheGen(ctx, []string{"user-1", "user-2"})
+	require.NoError(t, err)
+	assert.Equal(t, gen+",", multiGen)
+
+	// Tombstones which haven't been requested since the idle timeout should be offloaded.
+	loader.loaded["user-1"].requestedAt.Store(time.Now().Add(-tombstonesIdleTimeout).Unix())
+	require.NoError(t, loader.refresh(ctx))
+	assert.Empty(t, loader.loaded)
+}
+
+func TestTombstonesLoader_ConcurrentLoads(t *testing.T) {
+	ctx := context.Background()
+	bkt := &countingBucket{Bucket: objstore.NewInMemBucket(), iterStarted: make(chan struct{}), iterRelease: make(chan struct{})}
+
+	ts, err := mimir_tsdb.NewTombstone(0, 100, []string{`{job="deleted"}`}, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt.Bucket, "user-1", nil, ts))
+
+	loader := NewTombstonesLoader(func() (objstore.Bucket, error) {
+		return bkt, nil
+	}, tombstonesLimitsMock{"user-1": true}, log.NewNopLogger())
+
+	// The queries waiting for the tombstones of the same tenant should share a single load.
+	const queries = 10
+	results := make(chan []*mimir_tsdb.Tombstone, queries)
+	for i := 0; i < queries; i++ {
+		go func() {
+			actual, err := loader.Tombstones(ctx, "user-1")
+			assert.NoError(t, err)
+			results <- actual
+		}()
+	}
+
+	<-bkt.iterStarted
+	// Give the other queries the time to wait for the ongoing load.
+	time.Sleep(100 * time.Millisecond)
+	close(bkt.iterRelease)
+
+	for i := 0; i < queries; i++ {
+		assert.Len(t, <-results, 1)
+	}
+	assert.Equal(t, int64(1), bkt.iterCalls.Load())
+}
+
+// countingBucket counts the Iter calls, and blocks the first one until iterRelease is closed.
+type countingBucket struct {
+	objstore.Bucket
+
+	iterCalls   atomic.Int64
+	iterStarted chan struct{}
+	iterRelease chan struct{}
+}
+
+func (b *countingBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	if b.iterCalls.Inc() == 1 {
+		close(b.iterStarted)
+		<-b.iterRelease
+	}
+	return b.Bucket.Iter(ctx, dir, f, options...)
+}
+
+func TestResultsCacheGenHandler(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	require.NoError(t, mimir_tsdb.BumpResultsCacheGen(ctx, bkt, "user-1", nil, time.Unix(100, 0)))
+
+	loader := NewTombstonesLoader(func() (objstore.Bucket, error) {
+		return bkt, nil
+	}, tombstonesLimitsMock{"user-1": true, "user-2": true}, log.NewNopLogger())
+
+	handler := NewResultsCacheGenHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), loader)
+
+	for userID, expected := range map[string]string{
+		"user-1": "100000000000",
+		"user-2": "",
+		"":       "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query_range", nil)
+		if userID != "" {
+			req = req.WithContext(user.InjectOrgID(req.Context(), userID))
+		}
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, expected, resp.Header().Get(mimir_tsdb.ResultsCacheGenHeader), userID)
+	}
+}
+
+func TestTombstonesQueryable(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	bkt := objstore.NewInMemBucket()
+
+	// Delete all samples of job="deleted", and the samples between 20 and 40 of job="partial".
+	for _, req := range []struct {
+		selector   string
+		start, end int64
+	}{
+		{selector: `{job="deleted"}`, start: 0, end: 100},
+		{selector: `{job="partial"}`, start: 20, end: 40},
+	} {
+		ts, err := mimir_tsdb.NewTombstone(req.start, req.end, []string{req.selector}, time.Now())
+		require.NoError(t, err)
+		require.NoError(t, mimir_tsdb.WriteTombstone(ctx, bkt, "user-1", nil, ts))
+	}
+
+	samples := []model.SamplePair{{Timestamp: 10, Value: 1}, {Timestamp: 20, Value: 2}, {Timestamp: 30, Value: 3}, {Timestamp: 40, Value: 4}, {Timestamp: 50, Value: 5}}
+	upstream := NewSampleAndChunkQueryable(storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		return &storage.MockQuerier{SelectMockFunction: func(bool, *storage.SelectHints, ...*labels.Matcher) storage.SeriesSet {
+			return series.NewConcreteSeriesSet([]storage.Series{
+				series.NewConcreteSeries(labels.FromStrings("job", "deleted"), samples),
+				series.NewConcreteSeries(labels.FromStrings("job", "kept"), samples),
+				series.NewConcreteSeries(labels.FromStrings("job", "partial"), samples),
+			})
+		}}, nil
+	}))
+
+	queryable := NewTombstonesQueryable(upstream, NewTombstonesLoader(func() (objstore.Bucket, error) {
+		return bkt, nil
+	}, tombstonesLimitsMock{"user-1": true}, log.NewNopLogger()))
+
+	q, err := queryable.Querier(ctx, 0, 100)
+	require.NoError(t, err)
+
+	actual := map[string][]int64{}
+	set := q.Select(true, &storage.SelectHints{Start: 0, End: 100})
+	for set.Next() {
+		var timestamps []int64
+		it := set.At().Iterator(nil)
+		for it.Next() != chunkenc.ValNone {
+			ts, _ := it.At()
+			timestamps = append(timestamps, ts)
+		}
+		require.NoError(t, it.Err())
+
+		actual[set.At().Labels().Get("job")] = timestamps
+	}
+	require.NoError(t, set.Err())
+
+	assert.Equal(t, map[string][]int64{
+		"kept":    {10, 20, 30, 40, 50},
+		"partial": {10, 50},
+	}, actual)
+
+	// Queries not overlapping the tombstones should not be filtered.
+	q, err = queryable.Querier(ctx, 200, 300)
+	require.NoError(t, err)
+	_, ok := q.(*tombstonesQuerier)
+	assert.False(t, ok)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/weaveworks/common/user"
 	"google.golang.org/grpc"
 
+	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 	"github.com/grafana/mimir/pkg/util/version"
 )
@@ -48,6 +49,9 @@ const (
 	statusError = "error"
 
 	maxRequestRetries = 3
+
+	// queryClass is the class of the queries sent by the ruler, used by the query-scheduler to prioritize them.
+	queryClass = "ruler"
 )
 
 var userAgent = fmt.Sprintf("mimir/%s", version.Version)
@@ -144,6 +148,7 @@ func (q *RemoteQuerier) Read(ctx context.Context, query *prompb.Query) (*prompb.
 			{Key: textproto.CanonicalMIMEHeaderKey("Content-Type"), Values: []string{"application/x-protobuf"}},
 			{Key: textproto.CanonicalMIMEHeaderKey("User-Agent"), Values: []string{userAgent}},
 			{Key: textproto.CanonicalMIMEHeaderKey("X-Prometheus-Remote-Read-Version"), Values: []string{"0.1.0"}},
+			{Key: textproto.CanonicalMIMEHeaderKey(httpgrpcutil.QueryClassHeader), Values: []string{queryClass}},
 		},
 	}
 
@@ -211,6 +216,7 @@ func (q *RemoteQuerier) query(ctx context.Context, query string, ts time.Time, l
 			{Key: textproto.CanonicalMIMEHeaderKey("User-Agent"), Values: []string{userAgent}},
 			{Key: textproto.CanonicalMIMEHeaderKey("Content-Type"), Values: []string{mimeTypeFormPost}},
 			{Key: textproto.CanonicalMIMEHeaderKey("Content-Length"), Values: []string{strconv.Itoa(len(body))}},
+			{Key: textproto.CanonicalMIMEHeaderKey(httpgrpcutil.QueryClassHeader), Values: []string{queryClass}},
 		},
 	}
 
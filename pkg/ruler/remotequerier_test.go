@@ -19,6 +19,8 @@ import (
 	"github.com/weaveworks/common/httpgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+
+	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
 )
 
 type mockHTTPGRPCClient func(ctx context.Context, req *httpgrpc.HTTPRequest, _ ...grpc.CallOption) (*httpgrpc.HTTPResponse, error)
@@ -56,6 +58,7 @@ func TestRemoteQuerier_ReadReq(t *testing.T) {
 	require.Equal(t, http.MethodPost, inReq.Method)
 	require.Equal(t, body, inReq.Body)
 	require.Equal(t, "/prometheus/api/v1/read", inReq.Url)
+	require.Equal(t, "ruler", httpgrpcutil.GetQueryClass(inReq))
 }
 
 func TestRemoteQuerier_ReadReqTimeout(t *testing.T) {
@@ -87,6 +90,7 @@ func TestRemoteQuerier_QueryReq(t *testing.T) {
 	require.Equal(t, http.MethodPost, inReq.Method)
 	require.Equal(t, "query=qs&time="+url.QueryEscape(tm.Format(time.RFC3339Nano)), string(inReq.Body))
 	require.Equal(t, "/prometheus/api/v1/query", inReq.Url)
+	require.Equal(t, "ruler", httpgrpcutil.GetQueryClass(inReq))
 }
 
 func TestRemoteQuerier_QueryReqTimeout(t *testing.T) {
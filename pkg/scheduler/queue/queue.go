@@ -48,6 +48,20 @@ func FirstUser() UserIndex {
 // Request stored into the queue.
 type Request interface{}
 
+// QueryClassesConfig configures how requests of different query classes are dequeued within a tenant.
+type QueryClassesConfig struct {
+	// Query classes from the highest to the lowest priority. Requests of a prioritized class are dequeued
+	// before the requests of the following classes, and of the classes which aren't prioritized.
+	Prioritized []string
+
+	// Query classes whose requests count towards MaxInflightCappedRequestsPerTenant.
+	Capped []string
+
+	// Max number of requests of the capped query classes which can be in-flight for each tenant.
+	// Once reached, the following requests of the capped classes are kept in the queue. 0 to disable.
+	MaxInflightCappedRequestsPerTenant int
+}
+
 // RequestQueue holds incoming requests in per-user queues. It also assigns each user specified number of queriers,
 // and when querier asks for next request to handle (using GetNextRequestForQuerier), it returns requests
 // in a fair fashion.
@@ -65,9 +79,9 @@ type RequestQueue struct {
 	discardedRequests *prometheus.CounterVec // Per user.
 }
 
-func NewRequestQueue(maxOutstandingPerTenant int, forgetDelay time.Duration, queueLength *prometheus.GaugeVec, discardedRequests *prometheus.CounterVec) *RequestQueue {
+func NewRequestQueue(maxOutstandingPerTenant int, forgetDelay time.Duration, classes QueryClassesConfig, queueLength *prometheus.GaugeVec, discardedRequests *prometheus.CounterVec) *RequestQueue {
 	q := &RequestQueue{
-		queues:                  newUserQueues(maxOutstandingPerTenant, forgetDelay),
+		queues:                  newUserQueues(maxOutstandingPerTenant, forgetDelay, classes),
 		connectedQuerierWorkers: atomic.NewInt32(0),
 		queueLength:             queueLength,
 		discardedRequests:       discardedRequests,
@@ -81,10 +95,10 @@ func NewRequestQueue(maxOutstandingPerTenant int, forgetDelay time.Duration, que
 
 // EnqueueRequest puts the request into the queue. MaxQueries is user-specific value that specifies how many queriers can
 // this user use (zero or negative = all queriers). It is passed to each EnqueueRequest, because it can change
-// between calls.
+// between calls. The query class of the request is used to prioritize requests of the same user, and can be empty.
 //
 // If request is successfully enqueued, successFn is called with the lock held, before any querier can receive the request.
-func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers int, successFn func()) error {
+func (q *RequestQueue) EnqueueRequest(userID, queryClass string, req Request, maxQueriers int, successFn func()) error {
 	q.mtx.Lock()
 	defer q.mtx.Unlock()
 
@@ -98,19 +112,18 @@ func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers in
 		return errors.New("no queue found")
 	}
 
-	select {
-	case queue <- req:
-		q.queueLength.WithLabelValues(userID).Inc()
-		q.cond.Broadcast()
-		// Call this function while holding a lock. This guarantees that no querier can fetch the request before function returns.
-		if successFn != nil {
-			successFn()
-		}
-		return nil
-	default:
+	if !q.queues.enqueueRequest(queue, req, queryClass) {
 		q.discardedRequests.WithLabelValues(userID).Inc()
 		return ErrTooManyRequests
 	}
+
+	q.queueLength.WithLabelValues(userID).Inc()
+	q.cond.Broadcast()
+	// Call this function while holding a lock. This guarantees that no querier can fetch the request before function returns.
+	if successFn != nil {
+		successFn()
+	}
+	return nil
 }
 
 // GetNextRequestForQuerier find next user queue and takes the next request off of it. Will block if there are no requests.
@@ -137,35 +150,39 @@ FindQueue:
 		return nil, last, err
 	}
 
-	for {
-		queue, userID, idx := q.queues.getNextQueueForQuerier(last.last, querierID)
-		last.last = idx
-		if queue == nil {
-			break
-		}
-
+	queue, userID, idx := q.queues.getNextQueueForQuerier(last.last, querierID)
+	last.last = idx
+	if queue != nil {
 		// Pick next request from the queue.
-		for {
-			request := <-queue
-			if len(queue) == 0 {
-				q.queues.deleteQueue(userID)
-			}
+		request := q.queues.dequeueRequest(userID, queue)
 
-			q.queueLength.WithLabelValues(userID).Dec()
+		q.queueLength.WithLabelValues(userID).Dec()
 
-			// Tell close() we've processed a request.
-			q.cond.Broadcast()
+		// Tell close() we've processed a request.
+		q.cond.Broadcast()
 
-			return request, last, nil
-		}
+		return request, last, nil
 	}
 
-	// There are no unexpired requests, so we can get back
+	// There are no requests this querier can handle, so we can get back
 	// and wait for more requests.
 	querierWait = true
 	goto FindQueue
 }
 
+// FinishRequest must be called once a request returned by GetNextRequestForQuerier has been handled,
+// with the same user and query class the request has been enqueued with. It's a no-op for the requests
+// of query classes which aren't capped.
+func (q *RequestQueue) FinishRequest(userID, queryClass string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.queues.finishRequest(userID, queryClass) {
+		// Queriers waiting for a request may now be able to dequeue a request of a capped class.
+		q.cond.Broadcast()
+	}
+}
+
 func (q *RequestQueue) forgetDisconnectedQueriers(_ context.Context) error {
 	q.mtx.Lock()
 	defer q.mtx.Unlock()
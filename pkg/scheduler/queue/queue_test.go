@@ -28,7 +28,7 @@ func BenchmarkGetNextRequest(b *testing.B) {
 	queues := make([]*RequestQueue, 0, b.N)
 
 	for n := 0; n < b.N; n++ {
-		queue := NewRequestQueue(maxOutstandingPerTenant, 0,
+		queue := NewRequestQueue(maxOutstandingPerTenant, 0, QueryClassesConfig{},
 			promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
 			promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
 		)
@@ -42,7 +42,7 @@ func BenchmarkGetNextRequest(b *testing.B) {
 			for j := 0; j < numTenants; j++ {
 				userID := strconv.Itoa(j)
 
-				err := queue.EnqueueRequest(userID, "request", 0, nil)
+				err := queue.EnqueueRequest(userID, "", "request", 0, nil)
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -85,7 +85,7 @@ func BenchmarkQueueRequest(b *testing.B) {
 	requests := make([]string, 0, numTenants)
 
 	for n := 0; n < b.N; n++ {
-		q := NewRequestQueue(maxOutstandingPerTenant, 0,
+		q := NewRequestQueue(maxOutstandingPerTenant, 0, QueryClassesConfig{},
 			promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
 			promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}),
 		)
@@ -106,7 +106,7 @@ func BenchmarkQueueRequest(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		for i := 0; i < maxOutstandingPerTenant; i++ {
 			for j := 0; j < numTenants; j++ {
-				err := queues[n].EnqueueRequest(users[j], requests[j], 0, nil)
+				err := queues[n].EnqueueRequest(users[j], "", requests[j], 0, nil)
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -118,7 +118,7 @@ func BenchmarkQueueRequest(b *testing.B) {
 func TestRequestQueue_GetNextRequestForQuerier_ShouldGetRequestAfterReshardingBecauseQuerierHasBeenForgotten(t *testing.T) {
 	const forgetDelay = 3 * time.Second
 
-	queue := NewRequestQueue(1, forgetDelay,
+	queue := NewRequestQueue(1, forgetDelay, QueryClassesConfig{},
 		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
 		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
 
@@ -147,7 +147,7 @@ func TestRequestQueue_GetNextRequestForQuerier_ShouldGetRequestAfterReshardingBe
 
 	// Enqueue a request from an user which would be assigned to querier-1.
 	// NOTE: "user-1" hash falls in the querier-1 shard.
-	require.NoError(t, queue.EnqueueRequest("user-1", "request", 1, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "", "request", 1, nil))
 
 	startTime := time.Now()
 	querier2wg.Wait()
@@ -157,6 +157,72 @@ func TestRequestQueue_GetNextRequestForQuerier_ShouldGetRequestAfterReshardingBe
 	assert.GreaterOrEqual(t, waitTime.Milliseconds(), forgetDelay.Milliseconds())
 }
 
+func TestRequestQueue_GetNextRequestForQuerier_ShouldDequeuePrioritizedQueryClassesFirst(t *testing.T) {
+	queue := NewRequestQueue(10, 0, QueryClassesConfig{Prioritized: []string{"ruler", "dashboard"}},
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+	queue.RegisterQuerierConnection("querier-1")
+
+	for _, r := range []struct{ class, req string }{
+		{class: "", req: "adhoc-1"},
+		{class: "dashboard", req: "dashboard-1"},
+		{class: "long-range", req: "long-range-1"},
+		{class: "ruler", req: "ruler-1"},
+		{class: "", req: "adhoc-2"},
+		{class: "dashboard", req: "dashboard-2"},
+	} {
+		require.NoError(t, queue.EnqueueRequest("user-1", r.class, r.req, 0, nil))
+	}
+
+	// Requests of the classes which aren't prioritized are dequeued in FIFO order.
+	expected := []string{"ruler-1", "dashboard-1", "dashboard-2", "adhoc-1", "long-range-1", "adhoc-2"}
+	last := FirstUser()
+	for _, exp := range expected {
+		req, idx, err := queue.GetNextRequestForQuerier(context.Background(), last, "querier-1")
+		require.NoError(t, err)
+		assert.Equal(t, exp, req)
+		last = idx
+	}
+	assert.Equal(t, 0, queue.queues.len())
+}
+
+func TestRequestQueue_GetNextRequestForQuerier_ShouldCapInflightRequestsOfCappedQueryClasses(t *testing.T) {
+	queue := NewRequestQueue(10, 0, QueryClassesConfig{Capped: []string{"long-range"}, MaxInflightCappedRequestsPerTenant: 1},
+		promauto.With(nil).NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		promauto.With(nil).NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+	queue.RegisterQuerierConnection("querier-1")
+
+	require.NoError(t, queue.EnqueueRequest("user-1", "long-range", "long-range-1", 0, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "long-range", "long-range-2", 0, nil))
+	require.NoError(t, queue.EnqueueRequest("user-1", "", "adhoc-1", 0, nil))
+
+	ctx := context.Background()
+	req, last, err := queue.GetNextRequestForQuerier(ctx, FirstUser(), "querier-1")
+	require.NoError(t, err)
+	assert.Equal(t, "long-range-1", req)
+
+	// The second long-range request is kept in the queue while the first one is in-flight.
+	req, last, err = queue.GetNextRequestForQuerier(ctx, last, "querier-1")
+	require.NoError(t, err)
+	assert.Equal(t, "adhoc-1", req)
+
+	dequeued := make(chan struct{})
+	go func() {
+		defer close(dequeued)
+
+		req, _, err := queue.GetNextRequestForQuerier(ctx, last, "querier-1")
+		require.NoError(t, err)
+		assert.Equal(t, "long-range-2", req)
+	}()
+
+	// Requests of classes which aren't capped don't release the cap.
+	queue.FinishRequest("user-1", "")
+	assertChanNotReceived(t, dequeued, 100*time.Millisecond, "capped request dequeued while the cap is reached")
+
+	queue.FinishRequest("user-1", "long-range")
+	assertChanReceived(t, dequeued, time.Second, "capped request not dequeued after the in-flight request finished")
+}
+
 func TestContextCond(t *testing.T) {
 	t.Run("wait until broadcast", func(t *testing.T) {
 		t.Parallel()
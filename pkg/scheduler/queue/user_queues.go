@@ -32,6 +32,17 @@ type querier struct {
 type queues struct {
 	userQueues map[string]*userQueue
 
+	// Priority of the prioritized query classes: the lower the value, the higher the priority.
+	classPriorities map[string]int
+
+	// Query classes whose in-flight requests are capped, and the max number of in-flight requests
+	// of these classes per user. 0 means no cap.
+	cappedClasses             map[string]struct{}
+	maxInflightCappedRequests int
+
+	// Number of in-flight requests of the capped query classes, per user.
+	inflightCappedRequests map[string]int
+
 	// List of all users with queues, used for iteration when searching for next queue to handle.
 	// Users removed from the middle are replaced with "". To avoid skipping users during iteration, we only shrink
 	// this list when there are ""'s at the end of it.
@@ -51,7 +62,8 @@ type queues struct {
 }
 
 type userQueue struct {
-	ch chan Request
+	// Pending requests, in the order they have been enqueued.
+	requests []queuedRequest
 
 	// If not nil, only these queriers can handle user requests. If nil, all queriers can.
 	// We set this to nil if number of available queriers <= maxQueriers.
@@ -66,15 +78,35 @@ type userQueue struct {
 	index int
 }
 
-func newUserQueues(maxUserQueueSize int, forgetDelay time.Duration) *queues {
-	return &queues{
-		userQueues:       map[string]*userQueue{},
-		users:            nil,
-		maxUserQueueSize: maxUserQueueSize,
-		forgetDelay:      forgetDelay,
-		queriers:         map[string]*querier{},
-		sortedQueriers:   nil,
+type queuedRequest struct {
+	req        Request
+	queryClass string
+}
+
+func newUserQueues(maxUserQueueSize int, forgetDelay time.Duration, classes QueryClassesConfig) *queues {
+	q := &queues{
+		userQueues:                map[string]*userQueue{},
+		classPriorities:           make(map[string]int, len(classes.Prioritized)),
+		cappedClasses:             make(map[string]struct{}, len(classes.Capped)),
+		maxInflightCappedRequests: classes.MaxInflightCappedRequestsPerTenant,
+		inflightCappedRequests:    map[string]int{},
+		users:                     nil,
+		maxUserQueueSize:          maxUserQueueSize,
+		forgetDelay:               forgetDelay,
+		queriers:                  map[string]*querier{},
+		sortedQueriers:            nil,
 	}
+
+	for ix, class := range classes.Prioritized {
+		if _, ok := q.classPriorities[class]; !ok {
+			q.classPriorities[class] = ix
+		}
+	}
+	for _, class := range classes.Capped {
+		q.cappedClasses[class] = struct{}{}
+	}
+
+	return q
 }
 
 func (q *queues) len() int {
@@ -100,7 +132,7 @@ func (q *queues) deleteQueue(userID string) {
 // MaxQueriers is used to compute which queriers should handle requests for this user.
 // If maxQueriers is <= 0, all queriers can handle this user's requests.
 // If maxQueriers has changed since the last call, queriers for this are recomputed.
-func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
+func (q *queues) getOrAddQueue(userID string, maxQueriers int) *userQueue {
 	// Empty user is not allowed, as that would break our users list ("" is used for free spot).
 	if userID == "" {
 		return nil
@@ -114,7 +146,6 @@ func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
 
 	if uq == nil {
 		uq = &userQueue{
-			ch:    make(chan Request, q.maxUserQueueSize),
 			seed:  util.ShuffleShardSeed(userID, ""),
 			index: -1,
 		}
@@ -141,13 +172,97 @@ func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
 		uq.queriers = shuffleQueriersForUser(uq.seed, maxQueriers, q.sortedQueriers, nil)
 	}
 
-	return uq.ch
+	return uq
+}
+
+// enqueueRequest adds the request to the user queue. Returns false if the queue is full.
+func (q *queues) enqueueRequest(uq *userQueue, req Request, queryClass string) bool {
+	if len(uq.requests) >= q.maxUserQueueSize {
+		return false
+	}
+
+	uq.requests = append(uq.requests, queuedRequest{req: req, queryClass: queryClass})
+	return true
+}
+
+// nextRequestIndex returns the index of the next request to dequeue from the user queue, or -1 if all the
+// pending requests belong to capped query classes which have reached the max number of in-flight requests.
+// Requests of prioritized query classes are dequeued first, while requests with the same priority are
+// dequeued in FIFO order.
+func (q *queues) nextRequestIndex(userID string, uq *userQueue) int {
+	capReached := q.maxInflightCappedRequests > 0 && q.inflightCappedRequests[userID] >= q.maxInflightCappedRequests
+
+	next, nextPriority := -1, 0
+	for ix, r := range uq.requests {
+		if capReached && q.isCapped(r.queryClass) {
+			continue
+		}
+
+		priority := q.priority(r.queryClass)
+		if next < 0 || priority < nextPriority {
+			next, nextPriority = ix, priority
+		}
+		if priority == 0 {
+			// Nothing can have a higher priority.
+			break
+		}
+	}
+
+	return next
+}
+
+// dequeueRequest removes the next request from the user queue, and deletes the queue if it's empty.
+// The user queue must have a request to dequeue, according to nextRequestIndex.
+func (q *queues) dequeueRequest(userID string, uq *userQueue) Request {
+	ix := q.nextRequestIndex(userID, uq)
+	r := uq.requests[ix]
+
+	copy(uq.requests[ix:], uq.requests[ix+1:])
+	uq.requests[len(uq.requests)-1] = queuedRequest{}
+	uq.requests = uq.requests[:len(uq.requests)-1]
+	if len(uq.requests) == 0 {
+		q.deleteQueue(userID)
+	}
+
+	if q.isCapped(r.queryClass) {
+		q.inflightCappedRequests[userID]++
+	}
+
+	return r.req
+}
+
+// finishRequest records that a dequeued request has been handled. Returns true if the request
+// belonged to a capped query class.
+func (q *queues) finishRequest(userID, queryClass string) bool {
+	if !q.isCapped(queryClass) {
+		return false
+	}
+
+	if q.inflightCappedRequests[userID] <= 1 {
+		delete(q.inflightCappedRequests, userID)
+	} else {
+		q.inflightCappedRequests[userID]--
+	}
+	return true
+}
+
+func (q *queues) priority(queryClass string) int {
+	if priority, ok := q.classPriorities[queryClass]; ok {
+		return priority
+	}
+	return len(q.classPriorities)
+}
+
+func (q *queues) isCapped(queryClass string) bool {
+	_, ok := q.cappedClasses[queryClass]
+	return ok
 }
 
 // Finds next queue for the querier. To support fair scheduling between users, client is expected
 // to pass last user index returned by this function as argument. Is there was no previous
-// last user index, use -1.
-func (q *queues) getNextQueueForQuerier(lastUserIndex int, querierID string) (chan Request, string, int) {
+// last user index, use -1. Queues whose pending requests all belong to capped query classes which have
+// reached the max number of in-flight requests are skipped.
+func (q *queues) getNextQueueForQuerier(lastUserIndex int, querierID string) (*userQueue, string, int) {
 	uid := lastUserIndex
 
 	// Ensure the querier is not shutting down. If the querier is shutting down, we shouldn't forward
@@ -170,16 +285,21 @@ func (q *queues) getNextQueueForQuerier(lastUserIndex int, querierID string) (ch
 			continue
 		}
 
-		q := q.userQueues[u]
+		uq := q.userQueues[u]
 
-		if q.queriers != nil {
-			if _, ok := q.queriers[querierID]; !ok {
+		if uq.queriers != nil {
+			if _, ok := uq.queriers[querierID]; !ok {
 				// This querier is not handling the user.
 				continue
 			}
 		}
 
-		return q.ch, u, uid
+		if len(uq.requests) > 0 && q.nextRequestIndex(u, uq) < 0 {
+			// All the pending requests of the user belong to capped query classes.
+			continue
+		}
+
+		return uq, u, uid
 	}
 	return nil, "", uid
 }
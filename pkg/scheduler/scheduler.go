@@ -15,6 +15,7 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/grpcclient"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/services"
@@ -39,6 +40,8 @@ import (
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
+var errInvalidMaxInflightCappedRequests = errors.New("the max number of in-flight capped requests per tenant must be greater than or equal to 0")
+
 // Scheduler is responsible for queueing and dispatching queries to Queriers.
 type Scheduler struct {
 	services.Service
@@ -90,23 +93,41 @@ type connectedFrontend struct {
 }
 
 type Config struct {
-	MaxOutstandingPerTenant int                       `yaml:"max_outstanding_requests_per_tenant"`
-	QuerierForgetDelay      time.Duration             `yaml:"querier_forget_delay" category:"experimental"`
-	GRPCClientConfig        grpcclient.Config         `yaml:"grpc_client_config" doc:"description=This configures the gRPC client used to report errors back to the query-frontend."`
-	ServiceDiscovery        schedulerdiscovery.Config `yaml:",inline"`
+	MaxOutstandingPerTenant            int                       `yaml:"max_outstanding_requests_per_tenant"`
+	QuerierForgetDelay                 time.Duration             `yaml:"querier_forget_delay" category:"experimental"`
+	PrioritizedQueryClasses            flagext.StringSliceCSV    `yaml:"prioritized_query_classes" category:"experimental"`
+	CappedQueryClasses                 flagext.StringSliceCSV    `yaml:"capped_query_classes" category:"experimental"`
+	MaxInflightCappedRequestsPerTenant int                       `yaml:"max_inflight_capped_requests_per_tenant" category:"experimental"`
+	GRPCClientConfig                   grpcclient.Config         `yaml:"grpc_client_config" doc:"description=This configures the gRPC client used to report errors back to the query-frontend."`
+	ServiceDiscovery                   schedulerdiscovery.Config `yaml:",inline"`
 }
 
 func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.IntVar(&cfg.MaxOutstandingPerTenant, "query-scheduler.max-outstanding-requests-per-tenant", 100, "Maximum number of outstanding requests per tenant per query-scheduler. In-flight requests above this limit will fail with HTTP response status code 429.")
 	f.DurationVar(&cfg.QuerierForgetDelay, "query-scheduler.querier-forget-delay", 0, "If a querier disconnects without sending notification about graceful shutdown, the query-scheduler will keep the querier in the tenant's shard until the forget delay has passed. This feature is useful to reduce the blast radius when shuffle-sharding is enabled.")
+	f.Var(&cfg.PrioritizedQueryClasses, "query-scheduler.prioritized-query-classes", "Comma-separated list of query classes, from the highest to the lowest priority. Within a tenant, queued requests of a class are dequeued before the requests of the following classes and of the classes which aren't listed. The class of a request is set by the "+httpgrpcutil.QueryClassHeader+" header. If empty, the requests of a tenant are dequeued in FIFO order.")
+	cfg.CappedQueryClasses = []string{"long-range", "expensive"}
+	f.Var(&cfg.CappedQueryClasses, "query-scheduler.capped-query-classes", "Comma-separated list of query classes whose requests count towards -query-scheduler.max-inflight-capped-requests-per-tenant.")
+	f.IntVar(&cfg.MaxInflightCappedRequestsPerTenant, "query-scheduler.max-inflight-capped-requests-per-tenant", 0, "Maximum number of requests of the query classes listed in -query-scheduler.capped-query-classes that queriers can execute concurrently for each tenant. Once reached, the requests of these classes are kept in the queue, while the requests of other classes can still be dequeued. 0 to disable.")
 	cfg.GRPCClientConfig.RegisterFlagsWithPrefix("query-scheduler.grpc-client-config", f)
 	cfg.ServiceDiscovery.RegisterFlags(f, logger)
 }
 
 func (cfg *Config) Validate() error {
+	if cfg.MaxInflightCappedRequestsPerTenant < 0 {
+		return errInvalidMaxInflightCappedRequests
+	}
 	return cfg.ServiceDiscovery.Validate()
 }
 
+func (cfg *Config) queryClassesConfig() queue.QueryClassesConfig {
+	return queue.QueryClassesConfig{
+		Prioritized:                        cfg.PrioritizedQueryClasses,
+		Capped:                             cfg.CappedQueryClasses,
+		MaxInflightCappedRequestsPerTenant: cfg.MaxInflightCappedRequestsPerTenant,
+	}
+}
+
 // NewScheduler creates a new Scheduler.
 func NewScheduler(cfg Config, limits Limits, log log.Logger, registerer prometheus.Registerer) (*Scheduler, error) {
 	var err error
@@ -134,7 +155,7 @@ func NewScheduler(cfg Config, limits Limits, log log.Logger, registerer promethe
 		Name: "cortex_query_scheduler_discarded_requests_total",
 		Help: "Total number of query requests discarded.",
 	}, []string{"user"})
-	s.requestQueue = queue.NewRequestQueue(cfg.MaxOutstandingPerTenant, cfg.QuerierForgetDelay, s.queueLength, s.discardedRequests)
+	s.requestQueue = queue.NewRequestQueue(cfg.MaxOutstandingPerTenant, cfg.QuerierForgetDelay, cfg.queryClassesConfig(), s.queueLength, s.discardedRequests)
 
 	s.queueDuration = promauto.With(registerer).NewHistogram(prometheus.HistogramOpts{
 		Name:    "cortex_query_scheduler_queue_duration_seconds",
@@ -190,6 +211,7 @@ type schedulerRequest struct {
 	frontendAddress string
 	userID          string
 	queryID         uint64
+	queryClass      string
 	request         *httpgrpc.HTTPRequest
 	statsEnabled    bool
 
@@ -329,6 +351,7 @@ func (s *Scheduler) enqueueRequest(frontendContext context.Context, frontendAddr
 		frontendAddress: frontendAddr,
 		userID:          msg.UserID,
 		queryID:         msg.QueryID,
+		queryClass:      httpgrpcutil.GetQueryClass(msg.HttpRequest),
 		request:         msg.HttpRequest,
 		statsEnabled:    msg.StatsEnabled,
 	}
@@ -348,7 +371,7 @@ func (s *Scheduler) enqueueRequest(frontendContext context.Context, frontendAddr
 	maxQueriers := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, s.limits.MaxQueriersPerUser)
 
 	s.activeUsers.UpdateUserTimestamp(userID, now)
-	return s.requestQueue.EnqueueRequest(userID, req, maxQueriers, func() {
+	return s.requestQueue.EnqueueRequest(userID, req.queryClass, req, maxQueriers, func() {
 		shouldCancel = false
 
 		s.pendingRequestsMu.Lock()
@@ -418,12 +441,15 @@ func (s *Scheduler) QuerierLoop(querier schedulerpb.SchedulerForQuerier_QuerierL
 		if r.ctx.Err() != nil {
 			// Remove from pending requests.
 			s.cancelRequestAndRemoveFromPending(r.frontendAddress, r.queryID)
+			s.requestQueue.FinishRequest(r.userID, r.queryClass)
 
 			lastUserIndex = lastUserIndex.ReuseLastUser()
 			continue
 		}
 
-		if err := s.forwardRequestToQuerier(querier, r); err != nil {
+		err = s.forwardRequestToQuerier(querier, r)
+		s.requestQueue.FinishRequest(r.userID, r.queryClass)
+		if err != nil {
 			return err
 		}
 	}
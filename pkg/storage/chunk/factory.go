@@ -27,6 +27,9 @@ const (
 	// PrometheusHistogramChunk is a wrapper around Prometheus histogram-encoded chunk.
 	// IMPORTANT: for backward compatibility reasons we need to keep the value hardcoded.
 	PrometheusHistogramChunk Encoding = 5
+	// PrometheusFloatHistogramChunk is a wrapper around Prometheus float histogram-encoded chunk.
+	// IMPORTANT: for backward compatibility reasons we need to keep the value hardcoded.
+	PrometheusFloatHistogramChunk Encoding = 6
 )
 
 type encoding struct {
@@ -47,6 +50,12 @@ var encodings = map[Encoding]encoding{
 			return newPrometheusHistogramChunk()
 		},
 	},
+	PrometheusFloatHistogramChunk: {
+		Name: "PrometheusFloatHistogramChunk",
+		New: func() EncodedChunk {
+			return newPrometheusFloatHistogramChunk()
+		},
+	},
 }
 
 // NewForEncoding allows configuring what chunk type you want
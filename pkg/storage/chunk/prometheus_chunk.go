@@ -49,6 +49,19 @@ func (p *prometheusChunk) Len() int {
 	return p.chunk.NumSamples()
 }
 
+func (p *prometheusChunk) wrappedChunk() chunkenc.Chunk {
+	return p.chunk
+}
+
+// PrometheusChunk returns the Prometheus chunk wrapped by the encoded chunk, or nil if it doesn't wrap any.
+// Unlike the chunk Iterator, the iterator of the Prometheus chunk supports native histograms.
+func PrometheusChunk(c EncodedChunk) chunkenc.Chunk {
+	if pc, ok := c.(interface{ wrappedChunk() chunkenc.Chunk }); ok {
+		return pc.wrappedChunk()
+	}
+	return nil
+}
+
 // Wrapper around a Prometheus XOR chunk.
 type prometheusXorChunk struct {
 	prometheusChunk
@@ -138,6 +151,37 @@ func (p *prometheusHistogramChunk) Encoding() Encoding {
 	return PrometheusHistogramChunk
 }
 
+// Wrapper around a Prometheus float histogram chunk.
+type prometheusFloatHistogramChunk struct {
+	prometheusChunk
+}
+
+func newPrometheusFloatHistogramChunk() *prometheusFloatHistogramChunk {
+	return &prometheusFloatHistogramChunk{}
+}
+
+func (p *prometheusFloatHistogramChunk) Add(sample model.SamplePair) (EncodedChunk, error) {
+	return nil, fmt.Errorf("cannot add float sample to float histogram chunk")
+}
+
+func (p *prometheusFloatHistogramChunk) AddHistogram(timestamp int64, h *histogram.Histogram) (EncodedChunk, error) {
+	return nil, fmt.Errorf("cannot add integer histogram to float histogram chunk")
+}
+
+func (p *prometheusFloatHistogramChunk) UnmarshalFromBuf(bytes []byte) error {
+	c, err := chunkenc.FromData(chunkenc.EncFloatHistogram, bytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to create Prometheus chunk from bytes")
+	}
+
+	p.chunk = c
+	return nil
+}
+
+func (p *prometheusFloatHistogramChunk) Encoding() Encoding {
+	return PrometheusFloatHistogramChunk
+}
+
 type prometheusChunkIterator struct {
 	c  chunkenc.Chunk // we need chunk, because FindAtOrAfter needs to start with fresh iterator.
 	it chunkenc.Iterator
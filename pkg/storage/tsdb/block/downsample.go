@@ -0,0 +1,367 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+// Resolutions of the blocks, in milliseconds.
+const (
+	ResolutionRaw int64 = 0
+	Resolution5m  int64 = 5 * 60 * 1000
+	Resolution1h  int64 = 60 * 60 * 1000
+)
+
+// resolutions are all the supported resolutions, from the highest to the lowest.
+var resolutions = []int64{ResolutionRaw, Resolution5m, Resolution1h}
+
+// Downsample opens the block with given id in dir and creates a new one with the given resolution, in milliseconds.
+//
+// For each series, the downsampled block keeps the last sample of every resolution window. If the value of the
+// series decreased within the window, the sample preceding the last decrease is kept too, so that the increase
+// of counters is preserved across resets. The downsampled block contains regular TSDB chunks, so it can be
+// queried and compacted like any other block.
+func Downsample(logger log.Logger, dir string, id ulid.ULID, resolution int64) (resid ulid.ULID, err error) {
+	bdir := filepath.Join(dir, id.String())
+
+	meta, err := metadata.ReadFromDir(bdir)
+	if err != nil {
+		return resid, errors.Wrap(err, "read meta file")
+	}
+	if resolution <= meta.Thanos.Downsample.Resolution {
+		return resid, fmt.Errorf("cannot downsample block with resolution %d to resolution %d", meta.Thanos.Downsample.Resolution, resolution)
+	}
+
+	b, err := tsdb.OpenBlock(logger, bdir, nil)
+	if err != nil {
+		return resid, errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "downsample block reader")
+
+	indexr, err := b.Index()
+	if err != nil {
+		return resid, errors.Wrap(err, "open index")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexr, "downsample index reader")
+
+	chunkr, err := b.Chunks()
+	if err != nil {
+		return resid, errors.Wrap(err, "open chunks")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkr, "downsample chunk reader")
+
+	entropy := rand.New(rand.NewSource(time.Now().UnixNano()))
+	resid = ulid.MustNew(ulid.Now(), entropy)
+	resdir := filepath.Join(dir, resid.String())
+
+	chunkw, err := chunks.NewWriter(filepath.Join(resdir, ChunksDirname))
+	if err != nil {
+		return resid, errors.Wrap(err, "open chunk writer")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkw, "downsample chunk writer")
+
+	indexw, err := index.NewWriter(context.TODO(), filepath.Join(resdir, IndexFilename))
+	if err != nil {
+		return resid, errors.Wrap(err, "open index writer")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexw, "downsample index writer")
+
+	resmeta := *meta
+	resmeta.ULID = resid
+	resmeta.Stats = tsdb.BlockStats{} // Reset stats.
+	resmeta.Thanos.Source = metadata.CompactorSource
+	resmeta.Thanos.Downsample.Resolution = resolution
+
+	if err := writeDownsampledSeries(indexr, chunkr, indexw, chunkw, resolution, &resmeta); err != nil {
+		return resid, errors.Wrap(err, "write downsampled series")
+	}
+
+	resmeta.Thanos.SegmentFiles = GetSegmentFiles(resdir)
+	if err := resmeta.WriteToDir(logger, resdir); err != nil {
+		return resid, err
+	}
+
+	return resid, nil
+}
+
+func writeDownsampledSeries(indexr tsdb.IndexReader, chunkr tsdb.ChunkReader, indexw tsdb.IndexWriter, chunkw tsdb.ChunkWriter, resolution int64, meta *metadata.Meta) error {
+	// Series labels don't change, so the symbols of the original block can be reused.
+	symbols := indexr.Symbols()
+	for symbols.Next() {
+		if err := indexw.AddSymbol(symbols.At()); err != nil {
+			return errors.Wrap(err, "add symbol")
+		}
+	}
+	if symbols.Err() != nil {
+		return errors.Wrap(symbols.Err(), "iterate symbols")
+	}
+
+	all, err := indexr.Postings(index.AllPostingsKey())
+	if err != nil {
+		return errors.Wrap(err, "postings")
+	}
+	all = indexr.SortedPostings(all)
+
+	var (
+		builder labels.ScratchBuilder
+		chks    []chunks.Meta
+		ref     = storage.SeriesRef(0)
+	)
+	for all.Next() {
+		if err := indexr.Series(all.At(), &builder, &chks); err != nil {
+			return errors.Wrap(err, "series")
+		}
+
+		samples, err := downsampleSeriesChunks(chunkr, chks, resolution)
+		if err != nil {
+			return err
+		}
+		if len(samples) == 0 {
+			continue
+		}
+
+		lset := builder.Labels()
+		resChks, err := encodeSamples(lset, samples)
+		if err != nil {
+			return errors.Wrap(err, "encode downsampled chunks")
+		}
+
+		if err := chunkw.WriteChunks(resChks...); err != nil {
+			return errors.Wrap(err, "write chunks")
+		}
+		if err := indexw.AddSeries(ref, lset, resChks...); err != nil {
+			return errors.Wrap(err, "add series")
+		}
+
+		meta.Stats.NumChunks += uint64(len(resChks))
+		meta.Stats.NumSeries++
+		meta.Stats.NumSamples += uint64(len(samples))
+
+		ref++
+	}
+
+	return errors.Wrap(all.Err(), "iterate series")
+}
+
+// downsampleSeriesChunks returns the samples of the series chunks to keep in the downsampled block.
+func downsampleSeriesChunks(chunkr tsdb.ChunkReader, chks []chunks.Meta, resolution int64) ([]tsdbutil.Sample, error) {
+	d := newSeriesDownsampler(resolution)
+
+	var it chunkenc.Iterator
+	for _, c := range chks {
+		chk, err := chunkr.Chunk(c)
+		if err != nil {
+			return nil, errors.Wrap(err, "chunk read")
+		}
+
+		it = chk.Iterator(it)
+		for typ := it.Next(); typ != chunkenc.ValNone; typ = it.Next() {
+			switch typ {
+			case chunkenc.ValFloat:
+				t, v := it.At()
+				d.add(downsampledSample{t: t, v: v})
+			case chunkenc.ValHistogram:
+				t, h := it.AtHistogram()
+				d.add(downsampledSample{t: t, h: h.Copy()})
+			case chunkenc.ValFloatHistogram:
+				t, fh := it.AtFloatHistogram()
+				d.add(downsampledSample{t: t, fh: fh.Copy()})
+			}
+		}
+		if it.Err() != nil {
+			return nil, errors.Wrap(it.Err(), "iterate chunk")
+		}
+	}
+
+	return d.result(), nil
+}
+
+func encodeSamples(lset labels.Labels, samples []tsdbutil.Sample) ([]chunks.Meta, error) {
+	var chks []chunks.Meta
+
+	it := storage.NewSeriesToChunkEncoder(storage.NewListSeries(lset, samples)).Iterator(nil)
+	for it.Next() {
+		chks = append(chks, it.At())
+	}
+
+	return chks, it.Err()
+}
+
+// seriesDownsampler keeps the last sample of each resolution window, and the sample preceding the last
+// decrease of the float value within the window.
+type seriesDownsampler struct {
+	resolution int64
+	out        []tsdbutil.Sample
+	lastT      int64
+
+	// State of the current window.
+	window         int64
+	last           *downsampledSample
+	beforeDecrease *downsampledSample
+}
+
+func newSeriesDownsampler(resolution int64) *seriesDownsampler {
+	return &seriesDownsampler{resolution: resolution, lastT: math.MinInt64}
+}
+
+func (d *seriesDownsampler) add(s downsampledSample) {
+	// Skip out of order and duplicated samples, which may only be found in overlapping chunks.
+	if s.t <= d.lastT {
+		return
+	}
+	d.lastT = s.t
+
+	window := s.t - ((s.t%d.resolution)+d.resolution)%d.resolution
+	if d.last != nil && window != d.window {
+		d.flush()
+	}
+
+	if d.last != nil && s.isFloat() && d.last.isFloat() && s.v < d.last.v {
+		d.beforeDecrease = d.last
+	}
+
+	d.window = window
+	d.last = &s
+}
+
+func (d *seriesDownsampler) flush() {
+	if d.last == nil {
+		return
+	}
+
+	if d.beforeDecrease != nil {
+		d.out = append(d.out, *d.beforeDecrease)
+	}
+	d.out = append(d.out, *d.last)
+
+	d.last = nil
+	d.beforeDecrease = nil
+}
+
+func (d *seriesDownsampler) result() []tsdbutil.Sample {
+	d.flush()
+	return d.out
+}
+
+type downsampledSample struct {
+	t  int64
+	v  float64
+	h  *histogram.Histogram
+	fh *histogram.FloatHistogram
+}
+
+func (s downsampledSample) T() int64                      { return s.t }
+func (s downsampledSample) V() float64                    { return s.v }
+func (s downsampledSample) H() *histogram.Histogram       { return s.h }
+func (s downsampledSample) FH() *histogram.FloatHistogram { return s.fh }
+
+func (s downsampledSample) Type() chunkenc.ValueType {
+	switch {
+	case s.h != nil:
+		return chunkenc.ValHistogram
+	case s.fh != nil:
+		return chunkenc.ValFloatHistogram
+	default:
+		return chunkenc.ValFloat
+	}
+}
+
+func (s downsampledSample) isFloat() bool {
+	return s.h == nil && s.fh == nil
+}
+
+// SelectBlocksByResolution returns the blocks to query in order to cover the time range between mint and maxt,
+// both inclusive, preferring the lowest resolution not greater than maxResolution. Time ranges not covered by
+// blocks with the preferred resolution are filled with blocks of higher resolutions first, and then of lower
+// resolutions. The info function returns the time range and resolution of a block.
+//
+// The input blocks are returned as is if they all have the same resolution.
+func SelectBlocksByResolution[B any](blocks []B, mint, maxt, maxResolution int64, info func(B) (minTime, maxTime, resolution int64)) []B {
+	sameResolution := true
+	for i := 1; i < len(blocks) && sameResolution; i++ {
+		_, _, prev := info(blocks[i-1])
+		_, _, curr := info(blocks[i])
+		sameResolution = prev == curr
+	}
+	if sameResolution {
+		return blocks
+	}
+
+	// Resolutions sorted by preference.
+	var preferred []int64
+	for i := len(resolutions) - 1; i >= 0; i-- {
+		if resolutions[i] <= maxResolution {
+			preferred = append(preferred, resolutions[i])
+		}
+	}
+	for _, res := range resolutions {
+		if res > maxResolution {
+			preferred = append(preferred, res)
+		}
+	}
+
+	byResolution := map[int64][]B{}
+	for _, b := range blocks {
+		_, _, res := info(b)
+		byResolution[res] = append(byResolution[res], b)
+	}
+	for _, bs := range byResolution {
+		sort.SliceStable(bs, func(i, j int) bool {
+			mi, _, _ := info(bs[i])
+			mj, _, _ := info(bs[j])
+			return mi < mj
+		})
+	}
+
+	var fill func(mint, maxt int64, level int) []B
+	fill = func(mint, maxt int64, level int) (res []B) {
+		if mint > maxt || level >= len(preferred) {
+			return nil
+		}
+
+		start := mint
+		for _, b := range byResolution[preferred[level]] {
+			bmin, bmax, _ := info(b)
+
+			// NOTE: Block intervals are half-open: [MinTime, MaxTime).
+			if bmax <= mint {
+				continue
+			}
+			if bmin > maxt {
+				break
+			}
+
+			res = append(res, fill(start, bmin-1, level+1)...)
+			res = append(res, b)
+			if bmax > start {
+				start = bmax
+			}
+		}
+
+		return append(res, fill(start, maxt, level+1)...)
+	}
+
+	return fill(mint, maxt, 0)
+}
@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	e2eutil "github.com/grafana/mimir/pkg/storegateway/testhelper"
+)
+
+func TestDownsample(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	// 720 samples over 2h, one every 10s.
+	id, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{
+		labels.FromStrings("__name__", "float", "instance", "1"),
+		labels.FromStrings("__name__", "histogram", "instance", "1"),
+		labels.FromStrings("__name__", "float_histogram", "instance", "1"),
+	}, 720, 0, 2*time.Hour.Milliseconds(), labels.FromStrings("ext", "1"))
+	require.NoError(t, err)
+
+	_, err = Downsample(log.NewNopLogger(), tmpDir, id, ResolutionRaw)
+	require.Error(t, err)
+
+	id5m, err := Downsample(log.NewNopLogger(), tmpDir, id, Resolution5m)
+	require.NoError(t, err)
+
+	meta5m := readAndVerifyDownsampledBlock(t, tmpDir, id5m)
+	assert.Equal(t, Resolution5m, meta5m.Thanos.Downsample.Resolution)
+	assert.Equal(t, metadata.CompactorSource, meta5m.Thanos.Source)
+	assert.Equal(t, map[string]string{"ext": "1"}, meta5m.Thanos.Labels)
+	assert.Equal(t, uint64(3), meta5m.Stats.NumSeries)
+
+	// Blocks can't be downsampled to their own resolution.
+	_, err = Downsample(log.NewNopLogger(), tmpDir, id5m, Resolution5m)
+	require.Error(t, err)
+
+	id1h, err := Downsample(log.NewNopLogger(), tmpDir, id5m, Resolution1h)
+	require.NoError(t, err)
+
+	meta1h := readAndVerifyDownsampledBlock(t, tmpDir, id1h)
+	assert.Equal(t, Resolution1h, meta1h.Thanos.Downsample.Resolution)
+	assert.Equal(t, uint64(3), meta1h.Stats.NumSeries)
+	assert.Less(t, meta1h.Stats.NumSamples, meta5m.Stats.NumSamples)
+
+	// Each series should have at most 2 samples per window, with the last sample of the window always kept.
+	for name, res := range map[string]struct {
+		dir        string
+		resolution int64
+	}{
+		"5m": {dir: filepath.Join(tmpDir, id5m.String()), resolution: Resolution5m},
+		"1h": {dir: filepath.Join(tmpDir, id1h.String()), resolution: Resolution1h},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, err := tsdb.OpenBlock(log.NewNopLogger(), res.dir, nil)
+			require.NoError(t, err)
+			t.Cleanup(func() { require.NoError(t, b.Close()) })
+
+			q, err := tsdb.NewBlockQuerier(b, b.MinTime(), b.MaxTime())
+			require.NoError(t, err)
+			t.Cleanup(func() { require.NoError(t, q.Close()) })
+
+			set := q.Select(false, nil, labels.MustNewMatcher(labels.MatchRegexp, "__name__", ".+"))
+			numSeries := 0
+			for set.Next() {
+				numSeries++
+
+				perWindow := map[int64]int{}
+				it := set.At().Iterator(nil)
+				for typ := it.Next(); typ != chunkenc.ValNone; typ = it.Next() {
+					perWindow[it.AtT()/res.resolution]++
+				}
+				require.NoError(t, it.Err())
+
+				assert.Len(t, perWindow, int(2*time.Hour.Milliseconds()/res.resolution))
+				for _, count := range perWindow {
+					assert.LessOrEqual(t, count, 2)
+				}
+			}
+			require.NoError(t, set.Err())
+			assert.Equal(t, 3, numSeries)
+		})
+	}
+}
+
+func readAndVerifyDownsampledBlock(t *testing.T, dir string, id ulid.ULID) *metadata.Meta {
+	meta, err := metadata.ReadFromDir(filepath.Join(dir, id.String()))
+	require.NoError(t, err)
+	require.NoError(t, VerifyIndex(log.NewNopLogger(), filepath.Join(dir, id.String(), IndexFilename), meta.MinTime, meta.MaxTime))
+	return meta
+}
+
+func TestSeriesDownsampler(t *testing.T) {
+	d := newSeriesDownsampler(10)
+	for _, s := range []downsampledSample{
+		{t: 1, v: 1}, {t: 5, v: 2}, {t: 9, v: 3},
+		// Counter reset within the window.
+		{t: 10, v: 4}, {t: 12, v: 5}, {t: 14, v: 1}, {t: 18, v: 2},
+		// Duplicated and out of order samples are skipped.
+		{t: 18, v: 100}, {t: 15, v: 100},
+		{t: 25, v: 3},
+		// Empty windows are skipped.
+		{t: 51, v: 4},
+	} {
+		d.add(s)
+	}
+
+	var actual [][2]float64
+	for _, s := range d.result() {
+		actual = append(actual, [2]float64{float64(s.T()), s.V()})
+	}
+
+	assert.Equal(t, [][2]float64{{9, 3}, {12, 5}, {18, 2}, {25, 3}, {51, 4}}, actual)
+}
+
+func TestSelectBlocksByResolution(t *testing.T) {
+	type testBlock struct {
+		name            string
+		minT, maxT, res int64
+	}
+
+	info := func(b testBlock) (int64, int64, int64) { return b.minT, b.maxT, b.res }
+
+	blocks := []testBlock{
+		{name: "raw-1", minT: 0, maxT: 100, res: ResolutionRaw},
+		{name: "raw-2", minT: 100, maxT: 200, res: ResolutionRaw},
+		{name: "raw-3", minT: 200, maxT: 300, res: ResolutionRaw},
+		{name: "5m-1", minT: 0, maxT: 200, res: Resolution5m},
+		{name: "1h-1", minT: 0, maxT: 100, res: Resolution1h},
+	}
+
+	tests := map[string]struct {
+		mint, maxt    int64
+		maxResolution int64
+		expected      []string
+	}{
+		"raw resolution": {
+			mint: 0, maxt: 299, maxResolution: ResolutionRaw,
+			expected: []string{"raw-1", "raw-2", "raw-3"},
+		},
+		"5m resolution should be filled with raw blocks": {
+			mint: 0, maxt: 299, maxResolution: Resolution5m,
+			expected: []string{"5m-1", "raw-3"},
+		},
+		"1h resolution should be filled with 5m and raw blocks": {
+			mint: 0, maxt: 299, maxResolution: Resolution1h,
+			expected: []string{"1h-1", "5m-1", "raw-3"},
+		},
+		"resolution between 5m and 1h": {
+			mint: 0, maxt: 299, maxResolution: Resolution5m + 1,
+			expected: []string{"5m-1", "raw-3"},
+		},
+		"partial time range": {
+			mint: 150, maxt: 250, maxResolution: Resolution1h,
+			expected: []string{"5m-1", "raw-3"},
+		},
+		"time range without blocks": {
+			mint: 300, maxt: 400, maxResolution: Resolution1h,
+			expected: nil,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var actual []string
+			for _, b := range SelectBlocksByResolution(blocks, tc.mint, tc.maxt, tc.maxResolution, info) {
+				actual = append(actual, b.name)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("missing higher resolution blocks should be filled with lower resolution blocks", func(t *testing.T) {
+		blocks := []testBlock{
+			{name: "raw-1", minT: 0, maxT: 100, res: ResolutionRaw},
+			{name: "1h-1", minT: 0, maxT: 300, res: Resolution1h},
+		}
+
+		var actual []string
+		for _, b := range SelectBlocksByResolution(blocks, 0, 299, ResolutionRaw, info) {
+			actual = append(actual, b.name)
+		}
+		assert.Equal(t, []string{"raw-1", "1h-1"}, actual)
+	})
+}
@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"golang.org/x/exp/slices"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+// Relabel opens the block with given id in dir and creates a new one, applying the relabel configs to the
+// labels of each series. Series dropped by the relabel configs are removed from the new block, while series
+// whose labels become identical after relabelling are merged together. Downsampled blocks keep their resolution.
+// If the relabel configs don't change any series, no block is created and false is returned.
+func Relabel(logger log.Logger, dir string, id ulid.ULID, source metadata.SourceType, relabelConfigs []*relabel.Config) (resid ulid.ULID, changed bool, err error) {
+	bdir := filepath.Join(dir, id.String())
+
+	meta, err := metadata.ReadFromDir(bdir)
+	if err != nil {
+		return resid, false, errors.Wrap(err, "read meta file")
+	}
+
+	b, err := tsdb.OpenBlock(logger, bdir, nil)
+	if err != nil {
+		return resid, false, errors.Wrap(err, "open block")
+	}
+	defer runutil.CloseWithErrCapture(&err, b, "relabel block reader")
+
+	indexr, err := b.Index()
+	if err != nil {
+		return resid, false, errors.Wrap(err, "open index")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexr, "relabel index reader")
+
+	chunkr, err := b.Chunks()
+	if err != nil {
+		return resid, false, errors.Wrap(err, "open chunks")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkr, "relabel chunk reader")
+
+	series, changed, err := relabelSeries(indexr, relabelConfigs)
+	if err != nil {
+		return resid, false, err
+	}
+	if !changed {
+		return resid, false, nil
+	}
+
+	entropy := rand.New(rand.NewSource(time.Now().UnixNano()))
+	resid = ulid.MustNew(ulid.Now(), entropy)
+	resdir := filepath.Join(dir, resid.String())
+
+	chunkw, err := chunks.NewWriter(filepath.Join(resdir, ChunksDirname))
+	if err != nil {
+		return resid, false, errors.Wrap(err, "open chunk writer")
+	}
+	defer runutil.CloseWithErrCapture(&err, chunkw, "relabel chunk writer")
+
+	indexw, err := index.NewWriter(context.TODO(), filepath.Join(resdir, IndexFilename))
+	if err != nil {
+		return resid, false, errors.Wrap(err, "open index writer")
+	}
+	defer runutil.CloseWithErrCapture(&err, indexw, "relabel index writer")
+
+	resmeta := *meta
+	resmeta.ULID = resid
+	resmeta.Stats = tsdb.BlockStats{} // Reset stats.
+	resmeta.Thanos.Source = source    // Update source.
+
+	if err := writeRelabelledSeries(chunkr, indexw, chunkw, series, &resmeta); err != nil {
+		return resid, false, errors.Wrap(err, "write relabelled series")
+	}
+
+	resmeta.Thanos.SegmentFiles = GetSegmentFiles(resdir)
+	if err := resmeta.WriteToDir(logger, resdir); err != nil {
+		return resid, false, err
+	}
+
+	return resid, true, nil
+}
+
+// relabelSeries returns the series of the block with the relabel configs applied, sorted by labels.
+// Series dropped by the relabel configs are not returned.
+func relabelSeries(indexr tsdb.IndexReader, relabelConfigs []*relabel.Config) (series []seriesRepair, changed bool, err error) {
+	all, err := indexr.Postings(index.AllPostingsKey())
+	if err != nil {
+		return nil, false, errors.Wrap(err, "postings")
+	}
+	all = indexr.SortedPostings(all)
+
+	var builder labels.ScratchBuilder
+	for all.Next() {
+		var chks []chunks.Meta
+		if err := indexr.Series(all.At(), &builder, &chks); err != nil {
+			return nil, false, errors.Wrap(err, "series")
+		}
+
+		lset := builder.Labels()
+		relabelled, keep := relabel.Process(lset, relabelConfigs...)
+		if !keep || relabelled.IsEmpty() {
+			changed = true
+			continue
+		}
+		if !labels.Equal(lset, relabelled) {
+			changed = true
+		}
+
+		series = append(series, seriesRepair{lset: relabelled, chks: chks})
+	}
+	if all.Err() != nil {
+		return nil, false, errors.Wrap(all.Err(), "iterate series")
+	}
+
+	// Relabelling may change the ordering of the series. The sort is stable, so that
+	// series with identical labels are merged in the original order.
+	sort.SliceStable(series, func(i, j int) bool {
+		return labels.Compare(series[i].lset, series[j].lset) < 0
+	})
+
+	return series, changed, nil
+}
+
+func writeRelabelledSeries(chunkr tsdb.ChunkReader, indexw tsdb.IndexWriter, chunkw tsdb.ChunkWriter, series []seriesRepair, meta *metadata.Meta) error {
+	// Relabelling may introduce new symbols, so we can't reuse the ones of the original block.
+	symbols := map[string]struct{}{}
+	for _, s := range series {
+		s.lset.Range(func(l labels.Label) {
+			symbols[l.Name] = struct{}{}
+			symbols[l.Value] = struct{}{}
+		})
+	}
+
+	sortedSymbols := make([]string, 0, len(symbols))
+	for s := range symbols {
+		sortedSymbols = append(sortedSymbols, s)
+	}
+	slices.Sort(sortedSymbols)
+
+	for _, s := range sortedSymbols {
+		if err := indexw.AddSymbol(s); err != nil {
+			return errors.Wrap(err, "add symbol")
+		}
+	}
+
+	ref := storage.SeriesRef(0)
+	for start := 0; start < len(series); {
+		// Find all the series with the same labels.
+		end := start + 1
+		for end < len(series) && labels.Equal(series[start].lset, series[end].lset) {
+			end++
+		}
+
+		chks, err := mergeRelabelledSeriesChunks(chunkr, series[start:end])
+		if err != nil {
+			return err
+		}
+
+		if err := chunkw.WriteChunks(chks...); err != nil {
+			return errors.Wrap(err, "write chunks")
+		}
+		if err := indexw.AddSeries(ref, series[start].lset, chks...); err != nil {
+			return errors.Wrap(err, "add series")
+		}
+
+		meta.Stats.NumChunks += uint64(len(chks))
+		meta.Stats.NumSeries++
+		for _, chk := range chks {
+			meta.Stats.NumSamples += uint64(chk.Chunk.NumSamples())
+		}
+
+		ref++
+		start = end
+	}
+
+	return nil
+}
+
+// mergeRelabelledSeriesChunks returns the chunks of the input series, which are expected to have the same labels.
+// Overlapping chunks of different series are merged.
+func mergeRelabelledSeriesChunks(chunkr tsdb.ChunkReader, series []seriesRepair) ([]chunks.Meta, error) {
+	toMerge := make([]storage.ChunkSeries, 0, len(series))
+	for _, s := range series {
+		chks := make([]chunks.Meta, 0, len(s.chks))
+		for _, c := range s.chks {
+			chk, err := chunkr.Chunk(c)
+			if err != nil {
+				return nil, errors.Wrap(err, "chunk read")
+			}
+			c.Chunk = chk
+			chks = append(chks, c)
+		}
+
+		if len(series) == 1 {
+			return chks, nil
+		}
+
+		toMerge = append(toMerge, &storage.ChunkSeriesEntry{
+			Lset: s.lset,
+			ChunkIteratorFn: func(chunks.Iterator) chunks.Iterator {
+				return storage.NewListChunkSeriesIterator(chks...)
+			},
+		})
+	}
+
+	merged := storage.NewCompactingChunkSeriesMerger(storage.ChainedSeriesMerge)(toMerge...)
+
+	var chks []chunks.Meta
+	it := merged.Iterator(nil)
+	for it.Next() {
+		chks = append(chks, it.At())
+	}
+	if it.Err() != nil {
+		return nil, errors.Wrap(it.Err(), "merge chunks")
+	}
+
+	return chks, nil
+}
@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	e2eutil "github.com/grafana/mimir/pkg/storegateway/testhelper"
+)
+
+func TestRelabel(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	id, err := e2eutil.CreateBlock(ctx, tmpDir, []labels.Labels{
+		labels.FromStrings("__name__", "old", "instance", "1"),
+		labels.FromStrings("__name__", "old", "instance", "2"),
+		labels.FromStrings("__name__", "kept", "instance", "1"),
+		labels.FromStrings("__name__", "dropped", "instance", "1"),
+	}, 100, 0, 1000, labels.FromStrings("ext", "1"))
+	require.NoError(t, err)
+
+	parseRelabelConfigs := func(cfg string) []*relabel.Config {
+		var configs []*relabel.Config
+		require.NoError(t, yaml.Unmarshal([]byte(cfg), &configs))
+		return configs
+	}
+
+	t.Run("should not create a new block if no series changed", func(t *testing.T) {
+		_, changed, err := Relabel(log.NewNopLogger(), tmpDir, id, metadata.CompactorRewriteSource, parseRelabelConfigs(`
+- source_labels: [__name__]
+  regex: missing
+  action: drop
+`))
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("should drop series, drop labels and rename metrics", func(t *testing.T) {
+		resid, changed, err := Relabel(log.NewNopLogger(), tmpDir, id, metadata.CompactorRewriteSource, parseRelabelConfigs(`
+- source_labels: [__name__]
+  regex: dropped
+  action: drop
+- source_labels: [__name__]
+  regex: old
+  target_label: __name__
+  replacement: new
+- source_labels: [__name__, instance]
+  regex: new;.*
+  target_label: instance
+  replacement: ""
+`))
+		require.NoError(t, err)
+		require.True(t, changed)
+
+		meta, err := metadata.ReadFromDir(filepath.Join(tmpDir, resid.String()))
+		require.NoError(t, err)
+		assert.Equal(t, metadata.CompactorRewriteSource, meta.Thanos.Source)
+		assert.Equal(t, map[string]string{"ext": "1"}, meta.Thanos.Labels)
+		assert.Equal(t, uint64(2), meta.Stats.NumSeries)
+		assert.Equal(t, uint64(200), meta.Stats.NumSamples)
+		require.NoError(t, VerifyIndex(log.NewNopLogger(), filepath.Join(tmpDir, resid.String(), IndexFilename), meta.MinTime, meta.MaxTime))
+
+		b, err := tsdb.OpenBlock(log.NewNopLogger(), filepath.Join(tmpDir, resid.String()), nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, b.Close()) })
+
+		q, err := tsdb.NewBlockQuerier(b, meta.MinTime, meta.MaxTime)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, q.Close()) })
+
+		actual := map[string]int{}
+		set := q.Select(true, nil, labels.MustNewMatcher(labels.MatchRegexp, "__name__", ".+"))
+		for set.Next() {
+			samples := 0
+			it := set.At().Iterator(nil)
+			for it.Next() != chunkenc.ValNone {
+				samples++
+			}
+			require.NoError(t, it.Err())
+
+			actual[set.At().Labels().String()] = samples
+		}
+		require.NoError(t, set.Err())
+
+		// The two "old" series have been merged, and their samples with the same timestamp deduplicated.
+		assert.Equal(t, map[string]int{
+			`{__name__="kept", instance="1"}`: 100,
+			`{__name__="new"}`:                100,
+		}, actual)
+	})
+}
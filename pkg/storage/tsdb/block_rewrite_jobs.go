@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/thanos-io/objstore"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+// BlockRewriteJobsPath is the location of the block rewrite jobs, relative to user-specific prefix.
+const BlockRewriteJobsPath = "block-rewrite-jobs"
+
+// BlockRewriteJobState is the state of a block rewrite job.
+type BlockRewriteJobState string
+
+const (
+	// BlockRewriteJobPending is the state of a job which hasn't been applied to all blocks yet.
+	BlockRewriteJobPending BlockRewriteJobState = "pending"
+
+	// BlockRewriteJobCompleted is the state of a job which has been applied to all blocks.
+	BlockRewriteJobCompleted BlockRewriteJobState = "completed"
+)
+
+var ErrBlockRewriteJobNoRelabelConfigs = errors.New("at least one relabel config is required")
+
+// BlockRewriteJob is a request to rewrite the blocks of a tenant, applying the relabel configs to the stored series.
+// The job applies to the blocks with samples older than the job creation time.
+type BlockRewriteJob struct {
+	ID             string               `yaml:"id"`
+	RelabelConfigs []*relabel.Config    `yaml:"relabel_configs"`
+	State          BlockRewriteJobState `yaml:"state"`
+
+	// Unix timestamp when the job was created.
+	CreationTime int64 `yaml:"creation_time"`
+
+	// Unix timestamp of the last state change.
+	StateChangeTime int64 `yaml:"state_change_time,omitempty"`
+
+	// Number of blocks which have been rewritten by the job.
+	RewrittenBlocks int `yaml:"rewritten_blocks"`
+
+	// Blocks which have been rewritten by the job, or don't contain any series changed by the job.
+	ProcessedBlocks []ulid.ULID `yaml:"processed_blocks,omitempty"`
+}
+
+// NewBlockRewriteJob makes a new pending BlockRewriteJob.
+func NewBlockRewriteJob(relabelConfigs []*relabel.Config, creationTime time.Time) (*BlockRewriteJob, error) {
+	if len(relabelConfigs) == 0 {
+		return nil, ErrBlockRewriteJobNoRelabelConfigs
+	}
+
+	return &BlockRewriteJob{
+		ID:             ulid.MustNew(ulid.Timestamp(creationTime), rand.Reader).String(),
+		RelabelConfigs: relabelConfigs,
+		State:          BlockRewriteJobPending,
+		CreationTime:   creationTime.Unix(),
+	}, nil
+}
+
+// AppliesToBlock returns whether the job should be applied to the block with the given min time, in milliseconds.
+func (j *BlockRewriteJob) AppliesToBlock(id ulid.ULID, minTime int64) bool {
+	if minTime > j.CreationTime*1000 {
+		return false
+	}
+
+	return !j.IsBlockProcessed(id)
+}
+
+// IsBlockProcessed returns whether the block has already been processed by the job.
+func (j *BlockRewriteJob) IsBlockProcessed(id ulid.ULID) bool {
+	for _, processed := range j.ProcessedBlocks {
+		if processed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteBlockRewriteJob uploads the job to the tenant location in the bucket, replacing the existing one, if any.
+func WriteBlockRewriteJob(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, j *BlockRewriteJob) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	data, err := yaml.Marshal(j)
+	if err != nil {
+		return errors.Wrap(err, "serialize block rewrite job")
+	}
+
+	return errors.Wrap(bkt.Upload(ctx, blockRewriteJobFilename(j.ID), bytes.NewReader(data)), "upload block rewrite job")
+}
+
+// ListBlockRewriteJobs returns all the block rewrite jobs of the tenant, regardless of their state.
+func ListBlockRewriteJobs(ctx context.Context, bkt objstore.BucketReader, userID string) ([]*BlockRewriteJob, error) {
+	var names []string
+	err := bkt.Iter(ctx, path.Join(userID, BlockRewriteJobsPath)+"/", func(name string) error {
+		if strings.HasSuffix(name, ".yaml") {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list block rewrite jobs")
+	}
+
+	jobs := make([]*BlockRewriteJob, 0, len(names))
+	for _, name := range names {
+		r, err := bkt.Get(ctx, name)
+		if bkt.IsObjNotFoundErr(err) {
+			// The job may have been deleted in the meanwhile.
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read block rewrite job object: %s", name)
+		}
+
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read block rewrite job object: %s", name)
+		}
+
+		j := &BlockRewriteJob{}
+		if err := yaml.Unmarshal(data, j); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode block rewrite job object: %s", name)
+		}
+		jobs = append(jobs, j)
+	}
+
+	return jobs, nil
+}
+
+func blockRewriteJobFilename(id string) string {
+	return path.Join(BlockRewriteJobsPath, id+".yaml")
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNewBlockRewriteJob(t *testing.T) {
+	_, err := NewBlockRewriteJob(nil, time.Now())
+	assert.Equal(t, ErrBlockRewriteJobNoRelabelConfigs, err)
+
+	job, err := NewBlockRewriteJob([]*relabel.Config{{Action: relabel.LabelDrop, Regex: relabel.MustNewRegexp("pod")}}, time.Unix(100, 0))
+	require.NoError(t, err)
+	assert.Equal(t, BlockRewriteJobPending, job.State)
+	assert.Equal(t, int64(100), job.CreationTime)
+
+	processed := ulid.MustNew(1, nil)
+	job.ProcessedBlocks = []ulid.ULID{processed}
+
+	assert.True(t, job.AppliesToBlock(ulid.MustNew(2, nil), 100*1000))
+	assert.False(t, job.AppliesToBlock(ulid.MustNew(2, nil), 100*1000+1))
+	assert.False(t, job.AppliesToBlock(processed, 0))
+}
+
+func TestWriteAndListBlockRewriteJobs(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	var relabelConfigs []*relabel.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- source_labels: [__name__]
+  regex: old
+  target_label: __name__
+  replacement: new
+- regex: pod
+  action: labeldrop
+`), &relabelConfigs))
+
+	job, err := NewBlockRewriteJob(relabelConfigs, time.Now())
+	require.NoError(t, err)
+	job.ProcessedBlocks = []ulid.ULID{ulid.MustNew(1, nil)}
+
+	other, err := NewBlockRewriteJob(relabelConfigs, time.Now())
+	require.NoError(t, err)
+
+	require.NoError(t, WriteBlockRewriteJob(ctx, bkt, "user-1", nil, job))
+	require.NoError(t, WriteBlockRewriteJob(ctx, bkt, "user-2", nil, other))
+
+	jobs, err := ListBlockRewriteJobs(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, job.ID, jobs[0].ID)
+	assert.Equal(t, job.State, jobs[0].State)
+	assert.Equal(t, job.CreationTime, jobs[0].CreationTime)
+	assert.Equal(t, job.ProcessedBlocks, jobs[0].ProcessedBlocks)
+
+	// Relabel configs should be decoded with their defaults applied.
+	require.Len(t, jobs[0].RelabelConfigs, 2)
+	assert.Equal(t, relabel.Replace, jobs[0].RelabelConfigs[0].Action)
+	assert.Equal(t, "new", jobs[0].RelabelConfigs[0].Replacement)
+	assert.Equal(t, relabel.LabelDrop, jobs[0].RelabelConfigs[1].Action)
+
+	jobs, err = ListBlockRewriteJobs(ctx, bkt, "user-3")
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
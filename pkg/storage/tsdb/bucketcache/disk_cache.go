@@ -0,0 +1,535 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucketcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cache"
+	lru "github.com/hashicorp/golang-lru/simplelru"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// DiskCacheBackend is the value of the "backend" label of the disk cache metrics.
+	DiskCacheBackend = "disk"
+
+	diskCacheOpSet      = "set"
+	diskCacheOpGetMulti = "getmulti"
+	diskCacheOpDelete   = "delete"
+
+	diskCacheReasonMaxItemSize = "max-item-size"
+
+	diskCacheTmpSuffix = ".tmp"
+
+	// diskCacheHeaderSize is the size of the fixed part of each cache file header:
+	// magic (4 bytes), CRC32 (4 bytes), expiration time (8 bytes) and key length (4 bytes).
+	diskCacheHeaderSize = 4 + 4 + 8 + 4
+
+	maxInt = int(^uint(0) >> 1)
+)
+
+var (
+	diskCacheMagic    = []byte{'M', 'D', 'C', '1'}
+	diskCacheCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errDiskCacheCorrupted   = errors.New("corrupted cache file")
+	errDiskCacheExpired     = errors.New("cache item expired")
+	errDiskCacheKeyMismatch = errors.New("cache item key mismatch")
+
+	_ cache.Cache = (*DiskCache)(nil)
+)
+
+// DiskCache is a cache.Cache storing each item in a file on the local disk. The cache is bounded by
+// the total size of the files it holds and the least recently used items are evicted first. Each file
+// is checksummed, so corrupted items are detected and removed on read. Items stored in the directory
+// are loaded back when the cache is created, so the cache content survives process restarts.
+type DiskCache struct {
+	name         string
+	dir          string
+	logger       log.Logger
+	maxSizeBytes uint64
+
+	// The LRU is keyed by the file name, and the value is the size of the file.
+	mtx     sync.Mutex
+	lru     *lru.LRU
+	curSize uint64
+
+	// Metrics.
+	requests   prometheus.Counter
+	hits       prometheus.Counter
+	operations *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+	skipped    *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	evicted    prometheus.Counter
+	expired    prometheus.Counter
+	corrupted  prometheus.Counter
+}
+
+// NewDiskCache makes a new DiskCache storing items in dir, and loads the items already stored in it.
+func NewDiskCache(name, dir string, maxSizeBytes uint64, logger log.Logger, reg prometheus.Registerer) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "create disk cache directory")
+	}
+
+	c := &DiskCache{
+		name:         name,
+		dir:          dir,
+		logger:       log.With(logger, "name", name),
+		maxSizeBytes: maxSizeBytes,
+	}
+
+	// Initialize the LRU cache with a high size limit since we manage evictions ourselves
+	// based on the stored size using the RemoveOldest() method.
+	l, err := lru.NewLRU(maxInt, c.onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = l
+
+	reg = prometheus.WrapRegistererWith(
+		prometheus.Labels{"name": name, "backend": DiskCacheBackend},
+		prometheus.WrapRegistererWithPrefix("cache_", reg))
+
+	c.requests = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "requests_total",
+		Help: "Total number of items requests to cache.",
+	})
+	c.hits = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "hits_total",
+		Help: "Total number of items requests to the cache that were a hit.",
+	})
+	c.operations = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "operations_total",
+		Help: "Total number of operations against cache.",
+	}, []string{"operation"})
+	c.failures = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "operation_failures_total",
+		Help: "Total number of operations against cache that failed.",
+	}, []string{"operation"})
+	c.skipped = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "operation_skipped_total",
+		Help: "Total number of operations against cache that have been skipped.",
+	}, []string{"operation", "reason"})
+	c.duration = promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "operation_duration_seconds",
+		Help:    "Duration of operations against cache.",
+		Buckets: []float64{0.00001, 0.0001, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.2, 0.5, 1},
+	}, []string{"operation"})
+	for _, op := range []string{diskCacheOpSet, diskCacheOpGetMulti, diskCacheOpDelete} {
+		c.operations.WithLabelValues(op)
+		c.failures.WithLabelValues(op)
+		c.duration.WithLabelValues(op)
+	}
+	c.skipped.WithLabelValues(diskCacheOpSet, diskCacheReasonMaxItemSize)
+
+	c.evicted = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "items_evicted_total",
+		Help: "Total number of items evicted from the disk cache to make room for new items.",
+	})
+	c.expired = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "items_expired_total",
+		Help: "Total number of items removed from the disk cache because their TTL expired.",
+	})
+	c.corrupted = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "items_corrupted_total",
+		Help: "Total number of items removed from the disk cache because they failed the checksum validation.",
+	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "items",
+		Help: "Current number of items in the disk cache.",
+	}, func() float64 {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		return float64(c.lru.Len())
+	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "items_size_bytes",
+		Help: "Current size in bytes of the items in the disk cache.",
+	}, func() float64 {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		return float64(c.curSize)
+	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "max_size_bytes",
+		Help: "Maximum size in bytes of the items held in the disk cache.",
+	}, func() float64 {
+		return float64(c.maxSizeBytes)
+	})
+
+	if err := c.load(); err != nil {
+		return nil, errors.Wrap(err, "load disk cache")
+	}
+
+	level.Info(c.logger).Log("msg", "created disk cache", "dir", dir, "maxSizeBytes", maxSizeBytes, "items", c.lru.Len(), "sizeBytes", c.curSize)
+
+	return c, nil
+}
+
+// load scans the cache directory and adds the files found to the LRU, from the least
+// to the most recently used one (according to their modification time). Leftover temporary
+// files are removed. The content of each file is only validated once it's read.
+func (c *DiskCache) load() error {
+	type file struct {
+		name    string
+		size    uint64
+		modTime time.Time
+	}
+
+	var files []file
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		if strings.HasSuffix(path, diskCacheTmpSuffix) {
+			if err := os.Remove(path); err != nil {
+				level.Warn(c.logger).Log("msg", "failed to remove temporary disk cache file", "path", path, "err", err)
+			}
+			return nil
+		}
+
+		// Ignore any file which hasn't been created by the cache.
+		if len(d.Name()) != 2*sha256.Size || filepath.Base(filepath.Dir(path)) != d.Name()[:2] {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, file{name: d.Name(), size: uint64(info.Size()), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, f := range files {
+		c.lru.Add(f.name, f.size)
+		c.curSize += f.size
+	}
+
+	for c.curSize > c.maxSizeBytes {
+		if _, _, ok := c.lru.RemoveOldest(); !ok {
+			break
+		}
+		c.evicted.Inc()
+	}
+
+	return nil
+}
+
+// Store implements cache.Cache.
+func (c *DiskCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	start := time.Now()
+	expiresAt := start.Add(ttl)
+
+	for key, value := range data {
+		c.operations.WithLabelValues(diskCacheOpSet).Inc()
+
+		encoded := encodeDiskCacheItem(key, value, expiresAt)
+		size := uint64(len(encoded))
+		if size > c.maxSizeBytes {
+			c.skipped.WithLabelValues(diskCacheOpSet, diskCacheReasonMaxItemSize).Inc()
+			continue
+		}
+
+		name := diskCacheFileName(key)
+		if err := c.writeFile(name, encoded); err != nil {
+			c.failures.WithLabelValues(diskCacheOpSet).Inc()
+			level.Warn(c.logger).Log("msg", "failed to store item to disk cache", "key", key, "err", err)
+			continue
+		}
+
+		c.mtx.Lock()
+		// The file has been replaced, so we release the size of the previous one (if any)
+		// without going through the eviction callback, which would remove the new file.
+		if prev, ok := c.lru.Peek(name); ok {
+			c.curSize -= prev.(uint64)
+		}
+		c.lru.Add(name, size)
+		c.curSize += size
+
+		for c.curSize > c.maxSizeBytes {
+			if _, _, ok := c.lru.RemoveOldest(); !ok {
+				break
+			}
+			c.evicted.Inc()
+		}
+		c.mtx.Unlock()
+	}
+
+	c.duration.WithLabelValues(diskCacheOpSet).Observe(time.Since(start).Seconds())
+}
+
+// Fetch implements cache.Cache.
+func (c *DiskCache) Fetch(_ context.Context, keys []string, _ ...cache.Option) map[string][]byte {
+	start := time.Now()
+	c.operations.WithLabelValues(diskCacheOpGetMulti).Inc()
+	c.requests.Add(float64(len(keys)))
+
+	found := make(map[string][]byte, len(keys))
+
+	for _, key := range keys {
+		name := diskCacheFileName(key)
+
+		c.mtx.Lock()
+		_, ok := c.lru.Get(name)
+		c.mtx.Unlock()
+		if !ok {
+			continue
+		}
+
+		value, err := c.readFile(name, key, start)
+		switch {
+		case err == nil:
+			found[key] = value
+		case errors.Is(err, errDiskCacheCorrupted):
+			c.corrupted.Inc()
+			level.Warn(c.logger).Log("msg", "removing corrupted item from disk cache", "key", key, "err", err)
+			c.remove(name)
+		case errors.Is(err, errDiskCacheExpired):
+			c.expired.Inc()
+			c.remove(name)
+		case errors.Is(err, errDiskCacheKeyMismatch):
+			// Another key is stored in the same file (hash collision), so it's just a miss.
+		case errors.Is(err, os.ErrNotExist):
+			c.remove(name)
+		default:
+			c.failures.WithLabelValues(diskCacheOpGetMulti).Inc()
+			level.Warn(c.logger).Log("msg", "failed to read item from disk cache", "key", key, "err", err)
+		}
+	}
+
+	c.hits.Add(float64(len(found)))
+	c.duration.WithLabelValues(diskCacheOpGetMulti).Observe(time.Since(start).Seconds())
+
+	return found
+}
+
+// Name implements cache.Cache.
+func (c *DiskCache) Name() string {
+	return c.name
+}
+
+// Delete implements cache.Cache.
+func (c *DiskCache) Delete(_ context.Context, key string) error {
+	start := time.Now()
+	c.operations.WithLabelValues(diskCacheOpDelete).Inc()
+
+	c.remove(diskCacheFileName(key))
+
+	c.duration.WithLabelValues(diskCacheOpDelete).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// remove removes the file from the LRU, which in turn deletes it from disk.
+func (c *DiskCache) remove(name string) {
+	c.mtx.Lock()
+	c.lru.Remove(name)
+	c.mtx.Unlock()
+}
+
+// onEvict is called by the LRU each time an entry is removed, either explicitly or because it's the oldest one.
+// It's always called while holding the lock.
+func (c *DiskCache) onEvict(key, value interface{}) {
+	c.curSize -= value.(uint64)
+
+	if err := os.Remove(c.filePath(key.(string))); err != nil && !os.IsNotExist(err) {
+		level.Warn(c.logger).Log("msg", "failed to remove disk cache file", "file", key, "err", err)
+	}
+}
+
+// writeFile atomically writes the file, so that readers never see a partially written item.
+func (c *DiskCache) writeFile(name string, data []byte) error {
+	path := c.filePath(name)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), name+".*"+diskCacheTmpSuffix)
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// readFile reads and validates the file. On success, the file modification time is updated,
+// so that the LRU order is preserved when the cache is loaded again after a restart.
+func (c *DiskCache) readFile(name, key string, now time.Time) ([]byte, error) {
+	path := c.filePath(name)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := decodeDiskCacheItem(data, key, now)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = os.Chtimes(path, now, now)
+	return value, nil
+}
+
+// filePath returns the path of the file. Files are spread across sub-directories,
+// to avoid storing a huge number of files in a single directory.
+func (c *DiskCache) filePath(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+func diskCacheFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// encodeDiskCacheItem encodes an item in the format stored on disk:
+//
+//	| magic (4 bytes) | CRC32 (4 bytes) | expires at, in unix milliseconds (8 bytes) | key length (4 bytes) | key | value |
+//
+// The CRC32 covers all the bytes following it.
+func encodeDiskCacheItem(key string, value []byte, expiresAt time.Time) []byte {
+	buf := make([]byte, diskCacheHeaderSize+len(key)+len(value))
+	copy(buf[0:4], diskCacheMagic)
+	binary.BigEndian.PutUint64(buf[8:16], uint64(expiresAt.UnixMilli()))
+	binary.BigEndian.PutUint32(buf[16:20], uint32(len(key)))
+	copy(buf[diskCacheHeaderSize:], key)
+	copy(buf[diskCacheHeaderSize+len(key):], value)
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(buf[8:], diskCacheCRCTable))
+	return buf
+}
+
+// decodeDiskCacheItem validates the encoded item and returns its value.
+func decodeDiskCacheItem(buf []byte, key string, now time.Time) ([]byte, error) {
+	if len(buf) < diskCacheHeaderSize {
+		return nil, errors.Wrap(errDiskCacheCorrupted, "header too short")
+	}
+	if string(buf[0:4]) != string(diskCacheMagic) {
+		return nil, errors.Wrap(errDiskCacheCorrupted, "invalid magic number")
+	}
+	if crc32.Checksum(buf[8:], diskCacheCRCTable) != binary.BigEndian.Uint32(buf[4:8]) {
+		return nil, errors.Wrap(errDiskCacheCorrupted, "checksum mismatch")
+	}
+
+	keyLen := int(binary.BigEndian.Uint32(buf[16:20]))
+	if diskCacheHeaderSize+keyLen > len(buf) {
+		return nil, errors.Wrap(errDiskCacheCorrupted, "invalid key length")
+	}
+	if string(buf[diskCacheHeaderSize:diskCacheHeaderSize+keyLen]) != key {
+		return nil, errDiskCacheKeyMismatch
+	}
+	if now.UnixMilli() > int64(binary.BigEndian.Uint64(buf[8:16])) {
+		return nil, errDiskCacheExpired
+	}
+
+	return buf[diskCacheHeaderSize+keyLen:], nil
+}
+
+// TieredCache is a two-tier cache.Cache. Lookups are first served by the first tier, which is expected to be
+// the cheaper one to read from (e.g. the local disk in front of a remote cache), and only the keys missing from
+// it are looked up in the second tier. Items are stored in both tiers, and items found in the second tier are
+// stored back in the first one, so that the next lookups don't reach the second tier.
+type TieredCache struct {
+	first        cache.Cache
+	second       cache.Cache
+	writeBackTTL time.Duration
+}
+
+// NewTieredCache makes a new TieredCache. Items found in the second tier are stored back in the first one
+// for writeBackTTL, because the remaining TTL of the items isn't known.
+func NewTieredCache(first, second cache.Cache, writeBackTTL time.Duration) *TieredCache {
+	return &TieredCache{first: first, second: second, writeBackTTL: writeBackTTL}
+}
+
+// Store implements cache.Cache.
+func (t *TieredCache) Store(ctx context.Context, data map[string][]byte, ttl time.Duration) {
+	t.first.Store(ctx, data, ttl)
+	t.second.Store(ctx, data, ttl)
+}
+
+// Fetch implements cache.Cache.
+func (t *TieredCache) Fetch(ctx context.Context, keys []string, opts ...cache.Option) map[string][]byte {
+	found := t.first.Fetch(ctx, keys, opts...)
+	if len(found) == len(keys) {
+		return found
+	}
+
+	missing := make([]string, 0, len(keys)-len(found))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	fromSecond := t.second.Fetch(ctx, missing, opts...)
+	if len(fromSecond) == 0 {
+		return found
+	}
+
+	t.first.Store(ctx, fromSecond, t.writeBackTTL)
+
+	if found == nil {
+		found = make(map[string][]byte, len(fromSecond))
+	}
+	for key, value := range fromSecond {
+		found[key] = value
+	}
+
+	return found
+}
+
+// Name implements cache.Cache. It returns the name of the second tier, which is the shared one.
+func (t *TieredCache) Name() string {
+	return t.second.Name()
+}
+
+// Delete implements cache.Cache.
+func (t *TieredCache) Delete(ctx context.Context, key string) error {
+	if err := t.first.Delete(ctx, key); err != nil {
+		return err
+	}
+	return t.second.Delete(ctx, key)
+}
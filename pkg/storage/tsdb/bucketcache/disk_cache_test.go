@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucketcache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskCache_StoreAndFetch(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewPedanticRegistry()
+
+	c, err := NewDiskCache("test", t.TempDir(), 1024*1024, log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	c.Store(ctx, map[string][]byte{"key-1": []byte("value-1"), "key-2": []byte("value-2")}, time.Minute)
+	c.Store(ctx, map[string][]byte{"expired": []byte("value")}, -time.Minute)
+
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+	}, c.Fetch(ctx, []string{"key-1", "key-2", "expired", "missing"}))
+
+	require.NoError(t, c.Delete(ctx, "key-1"))
+	assert.Equal(t, map[string][]byte{"key-2": []byte("value-2")}, c.Fetch(ctx, []string{"key-1", "key-2"}))
+	assert.NoFileExists(t, c.filePath(diskCacheFileName("key-1")))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cache_requests_total Total number of items requests to cache.
+		# TYPE cache_requests_total counter
+		cache_requests_total{backend="disk",name="test"} 6
+
+		# HELP cache_hits_total Total number of items requests to the cache that were a hit.
+		# TYPE cache_hits_total counter
+		cache_hits_total{backend="disk",name="test"} 3
+
+		# HELP cache_items Current number of items in the disk cache.
+		# TYPE cache_items gauge
+		cache_items{backend="disk",name="test"} 1
+
+		# HELP cache_items_expired_total Total number of items removed from the disk cache because their TTL expired.
+		# TYPE cache_items_expired_total counter
+		cache_items_expired_total{backend="disk",name="test"} 1
+	`), "cache_requests_total", "cache_hits_total", "cache_items", "cache_items_expired_total"))
+}
+
+func TestDiskCache_ShouldEvictLeastRecentlyUsedItemsWhenFull(t *testing.T) {
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("x"), 100)
+	itemSize := uint64(len(encodeDiskCacheItem("key-0", value, time.Now())))
+
+	c, err := NewDiskCache("test", t.TempDir(), 3*itemSize, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	c.Store(ctx, map[string][]byte{"key-0": value}, time.Minute)
+	c.Store(ctx, map[string][]byte{"key-1": value}, time.Minute)
+	c.Store(ctx, map[string][]byte{"key-2": value}, time.Minute)
+
+	// Access the oldest item, so that it's not the least recently used anymore.
+	require.Len(t, c.Fetch(ctx, []string{"key-0"}), 1)
+
+	c.Store(ctx, map[string][]byte{"key-3": value}, time.Minute)
+
+	found := c.Fetch(ctx, []string{"key-0", "key-1", "key-2", "key-3"})
+	assert.Contains(t, found, "key-0")
+	assert.NotContains(t, found, "key-1")
+	assert.Contains(t, found, "key-2")
+	assert.Contains(t, found, "key-3")
+	assert.NoFileExists(t, c.filePath(diskCacheFileName("key-1")))
+
+	// Replacing an item should not change the cache size.
+	c.Store(ctx, map[string][]byte{"key-3": value}, time.Minute)
+	assert.Equal(t, 3*itemSize, c.curSize)
+
+	// An item bigger than the whole cache should be skipped.
+	c.Store(ctx, map[string][]byte{"huge": bytes.Repeat([]byte("x"), int(4*itemSize))}, time.Minute)
+	assert.Empty(t, c.Fetch(ctx, []string{"huge"}))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.evicted))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.skipped.WithLabelValues(diskCacheOpSet, diskCacheReasonMaxItemSize)))
+	assert.Equal(t, 3*itemSize, c.curSize)
+}
+
+func TestDiskCache_ShouldRemoveCorruptedItems(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := NewDiskCache("test", t.TempDir(), 1024*1024, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	c.Store(ctx, map[string][]byte{"key-1": []byte("value-1"), "key-2": []byte("value-2")}, time.Minute)
+
+	// Flip a byte of the value.
+	path := c.filePath(diskCacheFileName("key-1"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, os.ModePerm))
+
+	assert.Equal(t, map[string][]byte{"key-2": []byte("value-2")}, c.Fetch(ctx, []string{"key-1", "key-2"}))
+	assert.NoFileExists(t, path)
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.corrupted))
+	assert.Equal(t, 1, c.lru.Len())
+}
+
+func TestDiskCache_ShouldLoadItemsOnStartup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	value := bytes.Repeat([]byte("x"), 100)
+	itemSize := uint64(len(encodeDiskCacheItem("key-0", value, time.Now())))
+
+	c, err := NewDiskCache("test", dir, 3*itemSize, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i, key := range []string{"key-0", "key-1", "key-2"} {
+		c.Store(ctx, map[string][]byte{key: value}, time.Minute)
+
+		// Make sure the files have a distinct modification time.
+		mtime := now.Add(time.Duration(i) * time.Second)
+		require.NoError(t, os.Chtimes(c.filePath(diskCacheFileName(key)), mtime, mtime))
+	}
+
+	// Leftover temporary files and unknown files should be ignored.
+	tmpFile := filepath.Join(dir, "leftover"+diskCacheTmpSuffix)
+	require.NoError(t, os.WriteFile(tmpFile, value, os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unknown"), value, os.ModePerm))
+
+	// Restart the cache with a smaller size, so that the least recently used item gets evicted.
+	reg := prometheus.NewPedanticRegistry()
+	c, err = NewDiskCache("test", dir, 2*itemSize, log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]byte{
+		"key-1": value,
+		"key-2": value,
+	}, c.Fetch(ctx, []string{"key-0", "key-1", "key-2"}))
+	assert.NoFileExists(t, tmpFile)
+	assert.FileExists(t, filepath.Join(dir, "unknown"))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cache_items Current number of items in the disk cache.
+		# TYPE cache_items gauge
+		cache_items{backend="disk",name="test"} 2
+
+		# HELP cache_items_size_bytes Current size in bytes of the items in the disk cache.
+		# TYPE cache_items_size_bytes gauge
+		cache_items_size_bytes{backend="disk",name="test"} 250
+	`), "cache_items", "cache_items_size_bytes"))
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+
+	first, err := NewDiskCache("test", t.TempDir(), 1024*1024, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	second := cache.NewInstrumentedMockCache()
+
+	c := NewTieredCache(first, second, time.Hour)
+
+	// Items stored through the tiered cache should be stored in both tiers.
+	c.Store(ctx, map[string][]byte{"key-1": []byte("value-1")}, time.Hour)
+	assert.Equal(t, map[string][]byte{"key-1": []byte("value-1")}, first.Fetch(ctx, []string{"key-1"}))
+	assert.Equal(t, map[string][]byte{"key-1": []byte("value-1")}, second.Fetch(ctx, []string{"key-1"}))
+
+	// Items only found in the second tier should be returned, and stored back in the first one.
+	second.Store(ctx, map[string][]byte{"key-2": []byte("value-2")}, time.Hour)
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+	}, c.Fetch(ctx, []string{"key-1", "key-2", "missing"}))
+	assert.Equal(t, map[string][]byte{"key-2": []byte("value-2")}, first.Fetch(ctx, []string{"key-2"}))
+
+	// Deletions should be propagated to both tiers.
+	require.NoError(t, c.Delete(ctx, "key-1"))
+	assert.Empty(t, first.Fetch(ctx, []string{"key-1"}))
+	assert.Empty(t, second.Fetch(ctx, []string{"key-1"}))
+}
@@ -91,6 +91,13 @@ type Block struct {
 
 	// Block's compactor shard ID, copied from tsdb.CompactorShardIDExternalLabel label.
 	CompactorShardID string `json:"compactor_shard_id,omitempty"`
+
+	// Resolution of the block samples in milliseconds, 0 for blocks which haven't been downsampled.
+	Resolution int64 `json:"resolution,omitempty"`
+
+	// SeriesCount is the number of series in the block, copied from the block meta.json stats.
+	// It's 0 if the stats are unknown.
+	SeriesCount uint64 `json:"series_count,omitempty"`
 }
 
 // Within returns whether the block contains samples within the provided range.
@@ -118,6 +125,7 @@ func (m *Block) ThanosMeta() *metadata.Meta {
 		Thanos: metadata.Thanos{
 			Version:      metadata.ThanosVersion1,
 			SegmentFiles: m.thanosMetaSegmentFiles(),
+			Downsample:   metadata.ThanosDownsample{Resolution: m.Resolution},
 		},
 	}
 }
@@ -154,6 +162,8 @@ func BlockFromThanosMeta(meta metadata.Meta) *Block {
 		SegmentsFormat:   segmentsFormat,
 		SegmentsNum:      segmentsNum,
 		CompactorShardID: meta.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel],
+		Resolution:       meta.Thanos.Downsample.Resolution,
+		SeriesCount:      meta.Stats.NumSeries,
 	}
 }
 
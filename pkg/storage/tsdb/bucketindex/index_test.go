@@ -208,6 +208,40 @@ func TestBlockFromThanosMeta(t *testing.T) {
 				SegmentsNum:    3,
 			},
 		},
+		"meta.json of downsampled block": {
+			meta: metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+				},
+				Thanos: metadata.Thanos{
+					Downsample: metadata.ThanosDownsample{Resolution: 300000},
+				},
+			},
+			expected: Block{
+				ID:         blockID,
+				MinTime:    10,
+				MaxTime:    20,
+				Resolution: 300000,
+			},
+		},
+		"meta.json with stats": {
+			meta: metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+					Stats:   tsdb.BlockStats{NumSeries: 100, NumSamples: 1000},
+				},
+			},
+			expected: Block{
+				ID:          blockID,
+				MinTime:     10,
+				MaxTime:     20,
+				SeriesCount: 100,
+			},
+		},
 		"meta.json with external labels, no compactor shard ID": {
 			meta: metadata.Meta{
 				BlockMeta: tsdb.BlockMeta{
@@ -358,6 +392,26 @@ func TestBlock_ThanosMeta(t *testing.T) {
 				},
 			},
 		},
+		"downsampled block": {
+			block: Block{
+				ID:         blockID,
+				MinTime:    10,
+				MaxTime:    20,
+				Resolution: 300000,
+			},
+			expected: &metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+					Version: metadata.TSDBVersion1,
+				},
+				Thanos: metadata.Thanos{
+					Version:    metadata.ThanosVersion1,
+					Downsample: metadata.ThanosDownsample{Resolution: 300000},
+				},
+			},
+		},
 		"block with unknown segment files format": {
 			block: Block{
 				ID:             blockID,
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheBackendConfig is the config of a remote cache backend. It's a superset of the dskit cache.BackendConfig,
+// which only supports the Memcached backend, adding the Redis backend.
+type CacheBackendConfig struct {
+	Backend   string                  `yaml:"backend"`
+	Memcached cache.MemcachedConfig   `yaml:"memcached"`
+	Redis     cache.RedisClientConfig `yaml:"redis"`
+}
+
+// Validate the config.
+func (cfg *CacheBackendConfig) Validate() error {
+	switch cfg.Backend {
+	case "":
+		return nil
+	case cache.BackendMemcached:
+		return cfg.Memcached.Validate()
+	case cache.BackendRedis:
+		return cfg.Redis.Validate()
+	default:
+		return fmt.Errorf("unsupported cache backend: %s", cfg.Backend)
+	}
+}
+
+// CreateCacheClient creates the client of the configured cache backend, or returns nil if no backend is configured.
+func CreateCacheClient(cacheName string, cfg CacheBackendConfig, logger log.Logger, reg prometheus.Registerer) (cache.Cache, error) {
+	switch cfg.Backend {
+	case cache.BackendRedis:
+		client, err := cache.NewRedisClient(logger, cacheName, cfg.Redis, reg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create redis client")
+		}
+		return cache.NewRedisCache(cacheName, logger, client, reg), nil
+
+	default:
+		return cache.CreateClient(cacheName, cache.BackendConfig{Backend: cfg.Backend, Memcached: cfg.Memcached}, logger, reg)
+	}
+}
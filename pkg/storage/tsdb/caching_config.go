@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/go-kit/log"
 	"github.com/golang/snappy"
 	"github.com/grafana/dskit/cache"
@@ -31,8 +32,16 @@ import (
 // subrangeSize is the size of each subrange that bucket objects are split into for better caching
 const subrangeSize int64 = 16000
 
+var supportedCacheBackends = []string{cache.BackendMemcached, cache.BackendRedis}
+
+var (
+	errDiskCacheDirRequired        = errors.New("the disk cache directory is required")
+	errDiskCacheMaxSizeNotPositive = errors.New("the disk cache max size must be greater than 0")
+	errDiskCacheDirInsideSyncDir   = errors.New("the disk cache directory must not be located inside the bucket store sync directory")
+)
+
 type ChunksCacheConfig struct {
-	cache.BackendConfig `yaml:",inline"`
+	CacheBackendConfig `yaml:",inline"`
 
 	MaxGetRangeRequests        int           `yaml:"max_get_range_requests" category:"advanced"`
 	AttributesTTL              time.Duration `yaml:"attributes_ttl" category:"advanced"`
@@ -41,9 +50,10 @@ type ChunksCacheConfig struct {
 }
 
 func (cfg *ChunksCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string, logger log.Logger) {
-	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend for chunks cache, if not empty. Supported values: %s.", cache.BackendMemcached))
+	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend for chunks cache, if not empty. Supported values: %s.", strings.Join(supportedCacheBackends, ", ")))
 
 	cfg.Memcached.RegisterFlagsWithPrefix(f, prefix+"memcached.")
+	cfg.Redis.RegisterFlagsWithPrefix(prefix+"redis", f)
 
 	// TODO: Deprecated in Mimir 2.7, remove in Mimir 2.9
 	flagext.DeprecatedFlag(f, prefix+"subrange-size", fmt.Sprintf("Deprecated, %d bytes is now always used. Size of each subrange that bucket object is split into for better caching.", subrangeSize), logger)
@@ -54,11 +64,46 @@ func (cfg *ChunksCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix st
 }
 
 func (cfg *ChunksCacheConfig) Validate() error {
-	return cfg.BackendConfig.Validate()
+	return cfg.CacheBackendConfig.Validate()
+}
+
+// DiskCacheConfig configures the local disk cache tier for object store ranges.
+type DiskCacheConfig struct {
+	Enabled          bool          `yaml:"enabled" category:"experimental"`
+	Dir              string        `yaml:"dir" category:"experimental"`
+	MaxSizeBytes     uint64        `yaml:"max_size_bytes" category:"experimental"`
+	IndexSubrangeTTL time.Duration `yaml:"index_subrange_ttl" category:"experimental"`
+}
+
+func (cfg *DiskCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.BoolVar(&cfg.Enabled, prefix+"enabled", false, "If enabled, chunks and index ranges fetched from the object storage are cached on the local disk. When the chunks cache is configured, the disk cache is used as a first tier in front of it, and the items found in the chunks cache are stored on disk.")
+	f.StringVar(&cfg.Dir, prefix+"dir", "./tsdb-disk-cache/", "Directory to store the disk cache. The directory content is preserved across restarts. We recommend that you place it on the same volume as the sync directory, but it must not be located inside the sync directory.")
+	f.Uint64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", uint64(10*units.Gibibyte), "Maximum size in bytes of the items stored in the disk cache. When the limit is reached, the least recently used items are evicted.")
+	f.DurationVar(&cfg.IndexSubrangeTTL, prefix+"index-subrange-ttl", 24*time.Hour, "TTL for caching individual index subranges on disk. Chunks subranges are cached for the chunks cache subrange TTL.")
+}
+
+// Validate the config. The sync directory is required because the store-gateway removes any
+// unknown directory inside it, so the disk cache can't be located there.
+func (cfg *DiskCacheConfig) Validate(syncDir string) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Dir == "" {
+		return errDiskCacheDirRequired
+	}
+	if cfg.MaxSizeBytes == 0 {
+		return errDiskCacheMaxSizeNotPositive
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(syncDir), filepath.Clean(cfg.Dir))
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errDiskCacheDirInsideSyncDir
+	}
+	return nil
 }
 
 type MetadataCacheConfig struct {
-	cache.BackendConfig `yaml:",inline"`
+	CacheBackendConfig `yaml:",inline"`
 
 	TenantsListTTL          time.Duration `yaml:"tenants_list_ttl" category:"advanced"`
 	TenantBlocksListTTL     time.Duration `yaml:"tenant_blocks_list_ttl" category:"advanced"`
@@ -74,9 +119,10 @@ type MetadataCacheConfig struct {
 }
 
 func (cfg *MetadataCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
-	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend for metadata cache, if not empty. Supported values: %s.", cache.BackendMemcached))
+	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend for metadata cache, if not empty. Supported values: %s.", strings.Join(supportedCacheBackends, ", ")))
 
 	cfg.Memcached.RegisterFlagsWithPrefix(f, prefix+"memcached.")
+	cfg.Redis.RegisterFlagsWithPrefix(prefix+"redis", f)
 
 	f.DurationVar(&cfg.TenantsListTTL, prefix+"tenants-list-ttl", 15*time.Minute, "How long to cache list of tenants in the bucket.")
 	f.DurationVar(&cfg.TenantBlocksListTTL, prefix+"tenant-blocks-list-ttl", 5*time.Minute, "How long to cache list of blocks for each tenant.")
@@ -92,14 +138,14 @@ func (cfg *MetadataCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix
 }
 
 func (cfg *MetadataCacheConfig) Validate() error {
-	return cfg.BackendConfig.Validate()
+	return cfg.CacheBackendConfig.Validate()
 }
 
-func CreateCachingBucket(chunksCache cache.Cache, chunksConfig ChunksCacheConfig, metadataConfig MetadataCacheConfig, bkt objstore.Bucket, logger log.Logger, reg prometheus.Registerer) (objstore.Bucket, error) {
+func CreateCachingBucket(chunksCache, diskCache cache.Cache, chunksConfig ChunksCacheConfig, metadataConfig MetadataCacheConfig, diskConfig DiskCacheConfig, bkt objstore.Bucket, logger log.Logger, reg prometheus.Registerer) (objstore.Bucket, error) {
 	cfg := bucketcache.NewCachingBucketConfig()
 	cachingConfigured := false
 
-	metadataCache, err := cache.CreateClient("metadata-cache", metadataConfig.BackendConfig, logger, prometheus.WrapRegistererWithPrefix("thanos_", reg))
+	metadataCache, err := CreateCacheClient("metadata-cache", metadataConfig.CacheBackendConfig, logger, prometheus.WrapRegistererWithPrefix("thanos_", reg))
 	if err != nil {
 		return nil, errors.Wrapf(err, "metadata-cache")
 	}
@@ -120,12 +166,26 @@ func CreateCachingBucket(chunksCache cache.Cache, chunksConfig ChunksCacheConfig
 	}
 
 	if chunksCache != nil {
-		cachingConfigured = true
 		chunksCache = cache.NewSpanlessTracingCache(chunksCache, logger, tenant.NewMultiResolver())
+	}
 
-		// Use the metadata cache for attributes if configured, otherwise fallback to chunks cache.
+	// The disk cache is used as a first tier in front of the chunks cache, if configured, because reading
+	// from the local disk is cheaper than a round trip to the chunks cache. The ranges found in the chunks
+	// cache are stored back on disk for the chunks subrange TTL, which they are stored with.
+	rangesCache := chunksCache
+	switch {
+	case chunksCache != nil && diskCache != nil:
+		rangesCache = bucketcache.NewTieredCache(diskCache, chunksCache, chunksConfig.SubrangeTTL)
+	case diskCache != nil:
+		rangesCache = diskCache
+	}
+
+	if rangesCache != nil {
+		cachingConfigured = true
+
+		// Use the metadata cache for attributes if configured, otherwise fallback to chunks cache (or disk cache).
 		// If in-memory cache is enabled, wrap the attributes cache with the in-memory LRU cache.
-		attributesCache := chunksCache
+		attributesCache := rangesCache
 		if metadataCache != nil {
 			attributesCache = metadataCache
 		}
@@ -137,7 +197,13 @@ func CreateCachingBucket(chunksCache cache.Cache, chunksConfig ChunksCacheConfig
 			}
 		}
 
-		cfg.CacheGetRange("chunks", chunksCache, isTSDBChunkFile, subrangeSize, attributesCache, chunksConfig.AttributesTTL, chunksConfig.SubrangeTTL, chunksConfig.MaxGetRangeRequests)
+		cfg.CacheGetRange("chunks", rangesCache, isTSDBChunkFile, subrangeSize, attributesCache, chunksConfig.AttributesTTL, chunksConfig.SubrangeTTL, chunksConfig.MaxGetRangeRequests)
+
+		// Index ranges (postings and series) are only cached on disk, because the index cache
+		// already caches them in the remote cache after they've been decoded.
+		if diskCache != nil {
+			cfg.CacheGetRange("index", diskCache, isBlockIndexFile, subrangeSize, attributesCache, chunksConfig.AttributesTTL, diskConfig.IndexSubrangeTTL, chunksConfig.MaxGetRangeRequests)
+		}
 	}
 
 	if !cachingConfigured {
@@ -6,11 +6,22 @@
 package tsdb
 
 import (
+	"bytes"
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"testing"
 
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
 	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketcache"
 )
 
 func TestIsTenantDir(t *testing.T) {
@@ -39,3 +50,113 @@ func TestIsBlockIndexFile(t *testing.T) {
 	assert.True(t, isBlockIndexFile(fmt.Sprintf("%s/index", blockID.String())))
 	assert.True(t, isBlockIndexFile(fmt.Sprintf("/%s/index", blockID.String())))
 }
+
+func TestDiskCacheConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		setup       func(cfg *DiskCacheConfig)
+		syncDir     string
+		expectedErr error
+	}{
+		"should pass with default config": {
+			setup:   func(cfg *DiskCacheConfig) {},
+			syncDir: "./tsdb-sync/",
+		},
+		"should pass when enabled with default config": {
+			setup: func(cfg *DiskCacheConfig) {
+				cfg.Enabled = true
+			},
+			syncDir: "./tsdb-sync/",
+		},
+		"should pass when the directory is a sibling of the sync directory": {
+			setup: func(cfg *DiskCacheConfig) {
+				cfg.Enabled = true
+				cfg.Dir = "/data/tsdb-sync-cache"
+			},
+			syncDir: "/data/tsdb-sync",
+		},
+		"should fail when the directory is empty": {
+			setup: func(cfg *DiskCacheConfig) {
+				cfg.Enabled = true
+				cfg.Dir = ""
+			},
+			syncDir:     "./tsdb-sync/",
+			expectedErr: errDiskCacheDirRequired,
+		},
+		"should fail when the max size is 0": {
+			setup: func(cfg *DiskCacheConfig) {
+				cfg.Enabled = true
+				cfg.MaxSizeBytes = 0
+			},
+			syncDir:     "./tsdb-sync/",
+			expectedErr: errDiskCacheMaxSizeNotPositive,
+		},
+		"should fail when the directory is inside the sync directory": {
+			setup: func(cfg *DiskCacheConfig) {
+				cfg.Enabled = true
+				cfg.Dir = "/data/tsdb-sync/disk-cache"
+			},
+			syncDir:     "/data/tsdb-sync/",
+			expectedErr: errDiskCacheDirInsideSyncDir,
+		},
+		"should fail when the directory is the sync directory": {
+			setup: func(cfg *DiskCacheConfig) {
+				cfg.Enabled = true
+				cfg.Dir = "./tsdb-sync"
+			},
+			syncDir:     "./tsdb-sync/",
+			expectedErr: errDiskCacheDirInsideSyncDir,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg := DiskCacheConfig{}
+			cfg.RegisterFlagsWithPrefix(flag.NewFlagSet("", flag.PanicOnError), "")
+			testData.setup(&cfg)
+
+			assert.Equal(t, testData.expectedErr, cfg.Validate(testData.syncDir))
+		})
+	}
+}
+
+func TestCreateCachingBucket_DiskCache(t *testing.T) {
+	ctx := context.Background()
+	blockID := ulid.MustNew(1, nil)
+	chunksFile := fmt.Sprintf("user/%s/chunks/000001", blockID.String())
+	indexFile := fmt.Sprintf("user/%s/index", blockID.String())
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+
+	bkt := objstore.NewInMemBucket()
+	require.NoError(t, bkt.Upload(ctx, chunksFile, bytes.NewReader(content)))
+	require.NoError(t, bkt.Upload(ctx, indexFile, bytes.NewReader(content)))
+
+	cfg := BlocksStorageConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.BucketStore.DiskCache.Enabled = true
+
+	diskCache, err := bucketcache.NewDiskCache("disk-cache", t.TempDir(), cfg.BucketStore.DiskCache.MaxSizeBytes, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	cachingBkt, err := CreateCachingBucket(nil, diskCache, cfg.BucketStore.ChunksCache, cfg.BucketStore.MetadataCache, cfg.BucketStore.DiskCache, bkt, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	require.IsType(t, &bucketcache.CachingBucket{}, cachingBkt)
+
+	readRange := func(name string, off, length int64) []byte {
+		r, err := cachingBkt.GetRange(ctx, name, off, length)
+		require.NoError(t, err)
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return data
+	}
+
+	for _, name := range []string{chunksFile, indexFile} {
+		// The first read populates the disk cache.
+		assert.Equal(t, content[20000:50000], readRange(name, 20000, 30000))
+
+		// Once the object has been deleted, the same range can only be served by the disk cache.
+		require.NoError(t, bkt.Delete(ctx, name))
+		assert.Equal(t, content[20000:50000], readRange(name, 20000, 30000))
+	}
+}
@@ -28,6 +28,9 @@ const (
 	// IndexCacheBackendMemcached is the value for the memcached index cache backend.
 	IndexCacheBackendMemcached = cache.BackendMemcached
 
+	// IndexCacheBackendRedis is the value for the redis index cache backend.
+	IndexCacheBackendRedis = cache.BackendRedis
+
 	// IndexCacheBackendDefault is the value for the default index cache backend.
 	IndexCacheBackendDefault = IndexCacheBackendInMemory
 
@@ -35,14 +38,14 @@ const (
 )
 
 var (
-	supportedIndexCacheBackends = []string{IndexCacheBackendInMemory, IndexCacheBackendMemcached}
+	supportedIndexCacheBackends = []string{IndexCacheBackendInMemory, IndexCacheBackendMemcached, IndexCacheBackendRedis}
 
 	errUnsupportedIndexCacheBackend = errors.New("unsupported index cache backend")
 )
 
 type IndexCacheConfig struct {
-	cache.BackendConfig `yaml:",inline"`
-	InMemory            InMemoryIndexCacheConfig `yaml:"inmemory"`
+	CacheBackendConfig `yaml:",inline"`
+	InMemory           InMemoryIndexCacheConfig `yaml:"inmemory"`
 }
 
 func (cfg *IndexCacheConfig) RegisterFlags(f *flag.FlagSet) {
@@ -54,6 +57,7 @@ func (cfg *IndexCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix str
 
 	cfg.InMemory.RegisterFlagsWithPrefix(f, prefix+"inmemory.")
 	cfg.Memcached.RegisterFlagsWithPrefix(f, prefix+"memcached.")
+	cfg.Redis.RegisterFlagsWithPrefix(prefix+"redis", f)
 }
 
 // Validate the config.
@@ -62,10 +66,11 @@ func (cfg *IndexCacheConfig) Validate() error {
 		return errUnsupportedIndexCacheBackend
 	}
 
-	if cfg.Backend == IndexCacheBackendMemcached {
-		if err := cfg.Memcached.Validate(); err != nil {
-			return err
-		}
+	switch cfg.Backend {
+	case IndexCacheBackendMemcached:
+		return cfg.Memcached.Validate()
+	case IndexCacheBackendRedis:
+		return cfg.Redis.Validate()
 	}
 
 	return nil
@@ -86,6 +91,8 @@ func NewIndexCache(cfg IndexCacheConfig, logger log.Logger, registerer prometheu
 		return newInMemoryIndexCache(cfg.InMemory, logger, registerer)
 	case IndexCacheBackendMemcached:
 		return newMemcachedIndexCache(cfg.Memcached, logger, registerer)
+	case IndexCacheBackendRedis:
+		return newRedisIndexCache(cfg.Redis, logger, registerer)
 	default:
 		return nil, errUnsupportedIndexCacheBackend
 	}
@@ -112,9 +119,22 @@ func newMemcachedIndexCache(cfg cache.MemcachedConfig, logger log.Logger, regist
 		return nil, errors.Wrap(err, "create index cache memcached client")
 	}
 
-	cache, err := indexcache.NewMemcachedIndexCache(logger, client, registerer)
+	return newRemoteIndexCache(client, logger, registerer)
+}
+
+func newRedisIndexCache(cfg cache.RedisClientConfig, logger log.Logger, registerer prometheus.Registerer) (indexcache.IndexCache, error) {
+	client, err := cache.NewRedisClient(logger, "index-cache", cfg, prometheus.WrapRegistererWithPrefix("thanos_", registerer))
+	if err != nil {
+		return nil, errors.Wrap(err, "create index cache redis client")
+	}
+
+	return newRemoteIndexCache(client, logger, registerer)
+}
+
+func newRemoteIndexCache(client cache.RemoteCacheClient, logger log.Logger, registerer prometheus.Registerer) (indexcache.IndexCache, error) {
+	cache, err := indexcache.NewRemoteIndexCache(logger, client, registerer)
 	if err != nil {
-		return nil, errors.Wrap(err, "create memcached-based index cache")
+		return nil, errors.Wrap(err, "create remote index cache")
 	}
 
 	return indexcache.NewTracingIndexCache(cache, logger), nil
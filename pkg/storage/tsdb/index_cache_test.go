@@ -6,11 +6,21 @@
 package tsdb
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/go-kit/log"
 	"github.com/grafana/dskit/cache"
 	"github.com/grafana/dskit/flagext"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util/test"
 )
 
 func TestIndexCacheConfig_Validate(t *testing.T) {
@@ -27,7 +37,7 @@ func TestIndexCacheConfig_Validate(t *testing.T) {
 		},
 		"unsupported backend should fail": {
 			cfg: IndexCacheConfig{
-				BackendConfig: cache.BackendConfig{
+				CacheBackendConfig: CacheBackendConfig{
 					Backend: "xxx",
 				},
 			},
@@ -35,7 +45,7 @@ func TestIndexCacheConfig_Validate(t *testing.T) {
 		},
 		"no memcached addresses should fail": {
 			cfg: IndexCacheConfig{
-				BackendConfig: cache.BackendConfig{
+				CacheBackendConfig: CacheBackendConfig{
 					Backend: IndexCacheBackendMemcached,
 				},
 			},
@@ -43,18 +53,66 @@ func TestIndexCacheConfig_Validate(t *testing.T) {
 		},
 		"one memcached address should pass": {
 			cfg: IndexCacheConfig{
-				BackendConfig: cache.BackendConfig{Backend: IndexCacheBackendMemcached,
+				CacheBackendConfig: CacheBackendConfig{Backend: IndexCacheBackendMemcached,
 					Memcached: cache.MemcachedConfig{
 						Addresses: "dns+localhost:11211",
 					},
 				},
 			},
 		},
+		"no redis endpoint should fail": {
+			cfg: IndexCacheConfig{
+				CacheBackendConfig: CacheBackendConfig{
+					Backend: IndexCacheBackendRedis,
+				},
+			},
+			expected: errors.New("no redis endpoint provided"),
+		},
+		"one redis endpoint should pass": {
+			cfg: IndexCacheConfig{
+				CacheBackendConfig: CacheBackendConfig{Backend: IndexCacheBackendRedis,
+					Redis: cache.RedisClientConfig{
+						Endpoint:            []string{"localhost:6379"},
+						MaxAsyncConcurrency: 1,
+					},
+				},
+			},
+		},
 	}
 
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
-			assert.Equal(t, testData.expected, testData.cfg.Validate())
+			if testData.expected != nil {
+				assert.EqualError(t, testData.cfg.Validate(), testData.expected.Error())
+			} else {
+				assert.NoError(t, testData.cfg.Validate())
+			}
 		})
 	}
 }
+
+func TestNewIndexCache_Redis(t *testing.T) {
+	server := test.NewRedisServer(t, "")
+
+	cfg := IndexCacheConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.Backend = IndexCacheBackendRedis
+	cfg.Redis.Endpoint = []string{server.Addr()}
+	require.NoError(t, cfg.Validate())
+
+	c, err := NewIndexCache(cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	blockID := ulid.MustNew(1, nil)
+	lbl := labels.Label{Name: "foo", Value: "bar"}
+	c.StorePostings(ctx, "user-1", blockID, lbl, []byte("postings"))
+
+	require.Eventually(t, func() bool {
+		return server.Keys(0) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	hits, misses := c.FetchMultiPostings(ctx, "user-1", blockID, []labels.Label{lbl, {Name: "foo", Value: "baz"}})
+	assert.Equal(t, map[labels.Label][]byte{lbl: []byte("postings")}, hits)
+	assert.Equal(t, []labels.Label{{Name: "foo", Value: "baz"}}, misses)
+}
@@ -33,11 +33,12 @@ import (
 type SourceType string
 
 const (
-	ReceiveSource         SourceType = "receive"
-	CompactorSource       SourceType = "compactor"
-	CompactorRepairSource SourceType = "compactor.repair"
-	BucketRepairSource    SourceType = "bucket.repair"
-	TestSource            SourceType = "test"
+	ReceiveSource          SourceType = "receive"
+	CompactorSource        SourceType = "compactor"
+	CompactorRepairSource  SourceType = "compactor.repair"
+	CompactorRewriteSource SourceType = "compactor.rewrite"
+	BucketRepairSource     SourceType = "bucket.repair"
+	TestSource             SourceType = "test"
 )
 
 const (
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// RetentionRulesStatusFilename is the location of the status of the retention rules, relative to user-specific prefix.
+const RetentionRulesStatusFilename = "retention-rules-status.json"
+
+// RetentionRulesStatus tracks the blocks which have been checked by the compactor while applying the retention rules.
+type RetentionRulesStatus struct {
+	// Blocks which have been checked by the compactor, and don't contain series matching the selector of a
+	// retention rule, keyed by selector.
+	CleanBlocks map[string][]ulid.ULID `json:"clean_blocks"`
+}
+
+// IsBlockClean returns whether the block has been already checked and doesn't contain series matching the selector.
+func (s *RetentionRulesStatus) IsBlockClean(selector string, id ulid.ULID) bool {
+	for _, clean := range s.CleanBlocks[selector] {
+		if clean == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCleanBlock records that the block doesn't contain series matching the selector.
+func (s *RetentionRulesStatus) AddCleanBlock(selector string, id ulid.ULID) {
+	if s.IsBlockClean(selector, id) {
+		return
+	}
+	if s.CleanBlocks == nil {
+		s.CleanBlocks = map[string][]ulid.ULID{}
+	}
+	s.CleanBlocks[selector] = append(s.CleanBlocks[selector], id)
+}
+
+// Retain removes the selectors and blocks for which the input functions return false, so that the status
+// doesn't grow indefinitely. Returns whether the status has been changed.
+func (s *RetentionRulesStatus) Retain(keepSelector func(string) bool, keepBlock func(ulid.ULID) bool) bool {
+	changed := false
+
+	for selector, blocks := range s.CleanBlocks {
+		if !keepSelector(selector) {
+			delete(s.CleanBlocks, selector)
+			changed = true
+			continue
+		}
+
+		kept := blocks[:0]
+		for _, id := range blocks {
+			if keepBlock(id) {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) != len(blocks) {
+			s.CleanBlocks[selector] = kept
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// WriteRetentionRulesStatus uploads the status of the retention rules to the tenant location in the bucket,
+// replacing the existing one, if any.
+func WriteRetentionRulesStatus(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, s *RetentionRulesStatus) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "serialize retention rules status")
+	}
+
+	return errors.Wrap(bkt.Upload(ctx, RetentionRulesStatusFilename, bytes.NewReader(data)), "upload retention rules status")
+}
+
+// ReadRetentionRulesStatus returns the status of the retention rules of the tenant. If it doesn't exist,
+// returns an empty status, and no error.
+func ReadRetentionRulesStatus(ctx context.Context, bkt objstore.BucketReader, userID string) (*RetentionRulesStatus, error) {
+	name := path.Join(userID, RetentionRulesStatusFilename)
+
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		if bkt.IsObjNotFoundErr(err) {
+			return &RetentionRulesStatus{}, nil
+		}
+
+		return nil, errors.Wrapf(err, "failed to read retention rules status object: %s", name)
+	}
+
+	s := &RetentionRulesStatus{}
+	err = json.NewDecoder(r).Decode(s)
+
+	// Close reader before dealing with decode error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode retention rules status object: %s", name)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestRetentionRulesStatus(t *testing.T) {
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+
+	s := &RetentionRulesStatus{}
+	assert.False(t, s.IsBlockClean(`{env="dev"}`, block1))
+
+	s.AddCleanBlock(`{env="dev"}`, block1)
+	s.AddCleanBlock(`{env="dev"}`, block1)
+	s.AddCleanBlock(`{env="dev"}`, block2)
+	s.AddCleanBlock(`{env="test"}`, block2)
+	assert.True(t, s.IsBlockClean(`{env="dev"}`, block1))
+	assert.False(t, s.IsBlockClean(`{env="test"}`, block1))
+	assert.Equal(t, []ulid.ULID{block1, block2}, s.CleanBlocks[`{env="dev"}`])
+
+	// Nothing should change if all the selectors and blocks are kept.
+	assert.False(t, s.Retain(func(string) bool { return true }, func(ulid.ULID) bool { return true }))
+
+	changed := s.Retain(func(selector string) bool {
+		return selector == `{env="dev"}`
+	}, func(id ulid.ULID) bool {
+		return id == block2
+	})
+	assert.True(t, changed)
+	assert.Equal(t, map[string][]ulid.ULID{`{env="dev"}`: {block2}}, s.CleanBlocks)
+}
+
+func TestWriteAndReadRetentionRulesStatus(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	s, err := ReadRetentionRulesStatus(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, s.CleanBlocks)
+
+	s.AddCleanBlock(`{env="dev"}`, ulid.MustNew(1, nil))
+	require.NoError(t, WriteRetentionRulesStatus(ctx, bkt, "user-1", nil, s))
+
+	read, err := ReadRetentionRulesStatus(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, s, read)
+
+	read, err = ReadRetentionRulesStatus(ctx, bkt, "user-2")
+	require.NoError(t, err)
+	assert.Empty(t, read.CleanBlocks)
+}
@@ -0,0 +1,291 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// TombstonesPath is the location of the series deletion requests, relative to user-specific prefix.
+const TombstonesPath = "tombstones"
+
+// TombstoneState is the state of a series deletion request.
+type TombstoneState string
+
+const (
+	// TombstonePending is the state of a deletion request that hasn't been applied to the blocks yet.
+	// The series matching a pending deletion request are filtered out at query time.
+	TombstonePending TombstoneState = "pending"
+
+	// TombstoneProcessed is the state of a deletion request whose series have been removed from all blocks.
+	TombstoneProcessed TombstoneState = "processed"
+
+	// TombstoneCancelled is the state of a deletion request cancelled before being processed.
+	TombstoneCancelled TombstoneState = "cancelled"
+)
+
+var (
+	ErrTombstoneNoSelectors      = errors.New("at least one series selector is required")
+	ErrTombstoneInvalidTimeRange = errors.New("the end time must be after the start time")
+)
+
+// Tombstone is a request to delete the series matching any of the selectors, in the given time range.
+type Tombstone struct {
+	RequestID string `json:"request_id"`
+
+	// Time range of the samples to delete, in milliseconds since epoch (both inclusive).
+	StartTime int64 `json:"start_time"`
+	EndTime   int64 `json:"end_time"`
+
+	Selectors []string       `json:"selectors"`
+	State     TombstoneState `json:"state"`
+
+	// Unix timestamp when the request was created.
+	CreationTime int64 `json:"creation_time"`
+
+	// Unix timestamp of the last state change.
+	StateChangeTime int64 `json:"state_change_time,omitempty"`
+
+	// Blocks which have been checked by the compactor, and don't contain series matching the request.
+	CleanBlocks []ulid.ULID `json:"clean_blocks,omitempty"`
+
+	matchers [][]*labels.Matcher
+}
+
+// NewTombstone makes a new pending Tombstone. The request ID only depends on the time range and selectors,
+// so that submitting the same request twice doesn't create two different requests.
+func NewTombstone(startTime, endTime int64, selectors []string, creationTime time.Time) (*Tombstone, error) {
+	if len(selectors) == 0 {
+		return nil, ErrTombstoneNoSelectors
+	}
+	if endTime < startTime {
+		return nil, ErrTombstoneInvalidTimeRange
+	}
+
+	t := &Tombstone{
+		StartTime:    startTime,
+		EndTime:      endTime,
+		Selectors:    selectors,
+		State:        TombstonePending,
+		CreationTime: creationTime.Unix(),
+	}
+	if err := t.parseSelectors(); err != nil {
+		return nil, err
+	}
+
+	t.RequestID = tombstoneRequestID(startTime, endTime, selectors)
+	return t, nil
+}
+
+func tombstoneRequestID(startTime, endTime int64, selectors []string) string {
+	sorted := append([]string(nil), selectors...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	_ = binary.Write(h, binary.BigEndian, startTime)
+	_ = binary.Write(h, binary.BigEndian, endTime)
+	_, _ = h.Write([]byte(strings.Join(sorted, "\xff")))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func (t *Tombstone) parseSelectors() error {
+	t.matchers = make([][]*labels.Matcher, 0, len(t.Selectors))
+	for _, selector := range t.Selectors {
+		ms, err := parser.ParseMetricSelector(selector)
+		if err != nil {
+			return errors.Wrapf(err, "invalid series selector %q", selector)
+		}
+		t.matchers = append(t.matchers, ms)
+	}
+	return nil
+}
+
+// Matchers returns the parsed selectors of the request.
+func (t *Tombstone) Matchers() [][]*labels.Matcher {
+	return t.matchers
+}
+
+// MatchesSeries returns whether the series labels match any of the request selectors.
+func (t *Tombstone) MatchesSeries(lbls labels.Labels) bool {
+	for _, ms := range t.matchers {
+		if matchesAll(ms, lbls) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlaps returns whether the request time range overlaps the input one (both inclusive).
+func (t *Tombstone) Overlaps(mint, maxt int64) bool {
+	return t.StartTime <= maxt && mint <= t.EndTime
+}
+
+// IsBlockClean returns whether the block has been already checked and doesn't contain series matching the request.
+func (t *Tombstone) IsBlockClean(id ulid.ULID) bool {
+	for _, clean := range t.CleanBlocks {
+		if clean == id {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAll(ms []*labels.Matcher, lbls labels.Labels) bool {
+	for _, m := range ms {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTombstone uploads the tombstone to the tenant location in the bucket, replacing the existing one, if any.
+func WriteTombstone(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, t *Tombstone) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return errors.Wrap(err, "serialize tombstone")
+	}
+
+	return errors.Wrap(bkt.Upload(ctx, tombstoneFilename(t.RequestID), bytes.NewReader(data)), "upload tombstone")
+}
+
+// ReadTombstone returns the tombstone with the given request ID. If it doesn't exist, returns nil tombstone, and no error.
+func ReadTombstone(ctx context.Context, bkt objstore.BucketReader, userID, requestID string) (*Tombstone, error) {
+	return readTombstone(ctx, bkt, path.Join(userID, tombstoneFilename(requestID)))
+}
+
+// ListTombstones returns all the tombstones of the tenant, regardless of their state.
+func ListTombstones(ctx context.Context, bkt objstore.BucketReader, userID string) ([]*Tombstone, error) {
+	var names []string
+	err := bkt.Iter(ctx, path.Join(userID, TombstonesPath)+"/", func(name string) error {
+		if strings.HasSuffix(name, ".json") {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list tombstones")
+	}
+
+	tombstones := make([]*Tombstone, 0, len(names))
+	for _, name := range names {
+		t, err := readTombstone(ctx, bkt, name)
+		if err != nil {
+			return nil, err
+		}
+
+		// The tombstone may have been deleted in the meanwhile.
+		if t != nil {
+			tombstones = append(tombstones, t)
+		}
+	}
+
+	return tombstones, nil
+}
+
+func readTombstone(ctx context.Context, bkt objstore.BucketReader, name string) (*Tombstone, error) {
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		if bkt.IsObjNotFoundErr(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "failed to read tombstone object: %s", name)
+	}
+
+	t := &Tombstone{}
+	err = json.NewDecoder(r).Decode(t)
+
+	// Close reader before dealing with decode error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode tombstone object: %s", name)
+	}
+	if err := t.parseSelectors(); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode tombstone object: %s", name)
+	}
+
+	return t, nil
+}
+
+func tombstoneFilename(requestID string) string {
+	return path.Join(TombstonesPath, requestID+".json")
+}
+
+// DeleteTombstone removes the tombstone with the given request ID from the bucket.
+func DeleteTombstone(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, requestID string) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+	return errors.Wrap(bkt.Delete(ctx, tombstoneFilename(requestID)), "delete tombstone")
+}
+
+// ResultsCacheGenHeader is the HTTP header carrying the results cache generation of the tenant which the querier
+// used to filter out the deleted series. The query-frontend doesn't cache the responses computed with a different
+// generation than the one of the cache key.
+const ResultsCacheGenHeader = "X-Mimir-Results-Cache-Gen"
+
+// resultsCacheGenFilename is the name of the object storing the results cache generation of the tenant. It doesn't
+// have the .json extension, so that it's not listed as a tombstone.
+const resultsCacheGenFilename = "results-cache-gen.txt"
+
+// BumpResultsCacheGen updates the results cache generation of the tenant to the input time. The query-frontend includes
+// the generation in the results cache keys, so that the results cached before the series deletion requests of the
+// tenant changed are not used anymore.
+func BumpResultsCacheGen(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, now time.Time) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	gen := strconv.FormatInt(now.UnixNano(), 10)
+	return errors.Wrap(bkt.Upload(ctx, path.Join(TombstonesPath, resultsCacheGenFilename), strings.NewReader(gen)), "upload results cache generation")
+}
+
+// ReadResultsCacheGen returns the results cache generation of the tenant. If it has never been bumped, returns an empty
+// generation, and no error.
+func ReadResultsCacheGen(ctx context.Context, bkt objstore.BucketReader, userID string) (string, error) {
+	name := path.Join(userID, TombstonesPath, resultsCacheGenFilename)
+
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		if bkt.IsObjNotFoundErr(err) {
+			return "", nil
+		}
+
+		return "", errors.Wrapf(err, "failed to read results cache generation object: %s", name)
+	}
+
+	gen, err := io.ReadAll(r)
+
+	// Close reader before dealing with read error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read results cache generation object: %s", name)
+	}
+	return string(gen), nil
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestNewTombstone(t *testing.T) {
+	now := time.Now()
+
+	t.Run("should fail without selectors", func(t *testing.T) {
+		_, err := NewTombstone(0, 10, nil, now)
+		assert.Equal(t, ErrTombstoneNoSelectors, err)
+	})
+
+	t.Run("should fail on invalid time range", func(t *testing.T) {
+		_, err := NewTombstone(10, 0, []string{`{job="test"}`}, now)
+		assert.Equal(t, ErrTombstoneInvalidTimeRange, err)
+	})
+
+	t.Run("should fail on invalid selector", func(t *testing.T) {
+		_, err := NewTombstone(0, 10, []string{`{job=}`}, now)
+		assert.ErrorContains(t, err, "invalid series selector")
+	})
+
+	t.Run("should generate the same request ID for the same request", func(t *testing.T) {
+		first, err := NewTombstone(0, 10, []string{`{job="a"}`, `{job="b"}`}, now)
+		require.NoError(t, err)
+		second, err := NewTombstone(0, 10, []string{`{job="b"}`, `{job="a"}`}, now.Add(time.Hour))
+		require.NoError(t, err)
+		third, err := NewTombstone(0, 11, []string{`{job="a"}`, `{job="b"}`}, now)
+		require.NoError(t, err)
+
+		assert.Equal(t, first.RequestID, second.RequestID)
+		assert.NotEqual(t, first.RequestID, third.RequestID)
+		assert.Equal(t, TombstonePending, first.State)
+	})
+}
+
+func TestTombstone_MatchesSeries(t *testing.T) {
+	ts, err := NewTombstone(0, 10, []string{`{__name__="up", job="a"}`, `{pii=~".+"}`}, time.Now())
+	require.NoError(t, err)
+
+	assert.True(t, ts.MatchesSeries(labels.FromStrings("__name__", "up", "job", "a", "instance", "1")))
+	assert.True(t, ts.MatchesSeries(labels.FromStrings("__name__", "other", "pii", "secret")))
+	assert.False(t, ts.MatchesSeries(labels.FromStrings("__name__", "up", "job", "b")))
+	assert.False(t, ts.MatchesSeries(labels.FromStrings("__name__", "other", "job", "a")))
+
+	assert.True(t, ts.Overlaps(10, 20))
+	assert.True(t, ts.Overlaps(-5, 0))
+	assert.False(t, ts.Overlaps(11, 20))
+}
+
+func TestWriteReadListTombstones(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	// Unrelated objects should be ignored.
+	require.NoError(t, bkt.Upload(ctx, "user-1/01EQK4QKFHVSZYVJ908Y7HH9E0/meta.json", bytes.NewReader([]byte("data"))))
+
+	list, err := ListTombstones(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	first, err := NewTombstone(0, 10, []string{`{job="a"}`}, time.Unix(100, 0))
+	require.NoError(t, err)
+	second, err := NewTombstone(20, 30, []string{`{job="b"}`}, time.Unix(200, 0))
+	require.NoError(t, err)
+
+	require.NoError(t, WriteTombstone(ctx, bkt, "user-1", nil, first))
+	require.NoError(t, WriteTombstone(ctx, bkt, "user-1", nil, second))
+	require.NoError(t, WriteTombstone(ctx, bkt, "user-2", nil, second))
+
+	// Update the state of a tombstone.
+	second.State = TombstoneProcessed
+	second.CleanBlocks = []ulid.ULID{ulid.MustNew(1, nil)}
+	require.NoError(t, WriteTombstone(ctx, bkt, "user-1", nil, second))
+
+	read, err := ReadTombstone(ctx, bkt, "user-1", second.RequestID)
+	require.NoError(t, err)
+	assert.Equal(t, second, read)
+	assert.True(t, read.IsBlockClean(ulid.MustNew(1, nil)))
+	assert.False(t, read.IsBlockClean(ulid.MustNew(2, nil)))
+
+	read, err = ReadTombstone(ctx, bkt, "user-1", "missing")
+	require.NoError(t, err)
+	assert.Nil(t, read)
+
+	list, err = ListTombstones(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*Tombstone{first, second}, list)
+
+	require.NoError(t, DeleteTombstone(ctx, bkt, "user-1", nil, first.RequestID))
+
+	list, err = ListTombstones(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*Tombstone{second}, list)
+}
+
+func TestBumpReadResultsCacheGen(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	gen, err := ReadResultsCacheGen(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, gen)
+
+	require.NoError(t, BumpResultsCacheGen(ctx, bkt, "user-1", nil, time.Unix(100, 0)))
+
+	first, err := ReadResultsCacheGen(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, first)
+
+	require.NoError(t, BumpResultsCacheGen(ctx, bkt, "user-1", nil, time.Unix(200, 0)))
+
+	second, err := ReadResultsCacheGen(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	// The generation should not be listed as a tombstone, nor affect the other tenants.
+	list, err := ListTombstones(ctx, bkt, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	gen, err = ReadResultsCacheGen(ctx, bkt, "user-2")
+	require.NoError(t, err)
+	assert.Empty(t, gen)
+}
@@ -1593,8 +1593,7 @@ type bucketBlockSet struct {
 	blocks []*bucketBlock // Blocks sorted by mint, then maxt.
 }
 
-// newBucketBlockSet initializes a new set with the known downsampling windows hard-configured.
-// (Mimir only supports no-downsampling)
+// newBucketBlockSet initializes a new set of blocks.
 // The set currently does not support arbitrary ranges.
 func newBucketBlockSet() *bucketBlockSet {
 	return &bucketBlockSet{}
@@ -1630,7 +1629,9 @@ func (s *bucketBlockSet) remove(id ulid.ULID) {
 }
 
 // getFor returns a time-ordered list of blocks that cover date between mint and maxt.
-// It supports overlapping blocks.
+// It supports overlapping blocks. If no block-level matchers are given, downsampled blocks
+// are only returned for the time ranges not covered by raw blocks: the querier selects the
+// blocks with the resolution to query by their ID.
 //
 // NOTE: s.blocks are expected to be sorted in minTime order.
 func (s *bucketBlockSet) getFor(mint, maxt int64, blockMatchers []*labels.Matcher) (bs []*bucketBlock) {
@@ -1658,6 +1659,12 @@ func (s *bucketBlockSet) getFor(mint, maxt int64, blockMatchers []*labels.Matche
 		}
 	}
 
+	if len(blockMatchers) == 0 {
+		bs = block.SelectBlocksByResolution(bs, mint, maxt, block.ResolutionRaw, func(b *bucketBlock) (int64, int64, int64) {
+			return b.meta.MinTime, b.meta.MaxTime, b.meta.Thanos.Downsample.Resolution
+		})
+	}
+
 	return bs
 }
 
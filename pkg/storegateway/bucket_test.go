@@ -188,6 +188,41 @@ func TestBucketBlockSet_remove(t *testing.T) {
 	assert.Equal(t, input[2].id, res[1].meta.ULID)
 }
 
+func TestBucketBlockSet_getForWithDownsampledBlocks(t *testing.T) {
+	set := newBucketBlockSet()
+
+	type resBlock struct {
+		id              ulid.ULID
+		mint, maxt, res int64
+	}
+	input := []resBlock{
+		{id: ulid.MustNew(1, nil), mint: 0, maxt: 100, res: block.Resolution5m},
+		{id: ulid.MustNew(2, nil), mint: 100, maxt: 200, res: block.Resolution5m},
+		{id: ulid.MustNew(3, nil), mint: 100, maxt: 200, res: block.ResolutionRaw},
+		{id: ulid.MustNew(4, nil), mint: 200, maxt: 300, res: block.ResolutionRaw},
+	}
+
+	for _, in := range input {
+		var m metadata.Meta
+		m.ULID = in.id
+		m.MinTime = in.mint
+		m.MaxTime = in.maxt
+		m.Thanos.Downsample.Resolution = in.res
+		assert.NoError(t, set.add(&bucketBlock{meta: &m, blockLabels: labels.FromStrings(block.BlockIDLabel, in.id.String())}))
+	}
+
+	// Without block matchers, raw blocks should be preferred, and downsampled ones used to fill the gaps.
+	res := set.getFor(0, 300, nil)
+	require.Len(t, res, 3)
+	assert.Equal(t, input[0].id, res[0].meta.ULID)
+	assert.Equal(t, input[2].id, res[1].meta.ULID)
+	assert.Equal(t, input[3].id, res[2].meta.ULID)
+
+	// With block matchers, all the matching blocks should be returned.
+	res = set.getFor(0, 300, []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, block.BlockIDLabel, ".+")})
+	assert.Len(t, res, 4)
+}
+
 // Regression tests against: https://github.com/thanos-io/thanos/issues/1983.
 func TestReadIndexCache_LoadSeries(t *testing.T) {
 	bkt := objstore.NewInMemBucket()
@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package activequeries
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/tenant"
+
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// Query describes an in-flight query.
+type Query struct {
+	ID             string    `json:"id"`
+	Tenant         string    `json:"tenant"`
+	Path           string    `json:"path"`
+	Query          string    `json:"query,omitempty"`
+	Start          string    `json:"start,omitempty"`
+	End            string    `json:"end,omitempty"`
+	Step           string    `json:"step,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+
+	// Statistics, available only if query statistics are enabled.
+	ShardedQueries     uint32 `json:"sharded_queries"`
+	SplitQueries       uint32 `json:"split_queries"`
+	FetchedSeriesCount uint64 `json:"fetched_series_count"`
+	FetchedChunksCount uint64 `json:"fetched_chunks_count"`
+	FetchedChunkBytes  uint64 `json:"fetched_chunk_bytes"`
+}
+
+type activeQuery struct {
+	query  Query
+	stats  *querier_stats.Stats
+	cancel context.CancelFunc
+}
+
+// Tracker keeps track of the in-flight queries, and allows to cancel them.
+// A nil Tracker is valid, and doesn't track any query.
+type Tracker struct {
+	mtx     sync.Mutex
+	nextID  uint64
+	queries map[string]*activeQuery
+}
+
+// NewTracker makes a new Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		queries: map[string]*activeQuery{},
+	}
+}
+
+// Track registers the query of the request r as in-flight, until the returned function is called. The returned
+// context is canceled when the query is canceled via Cancel. The query parameters are read from params, while stats
+// are used to report the query progress and may be nil.
+func (t *Tracker) Track(ctx context.Context, r *http.Request, params url.Values, stats *querier_stats.Stats) (context.Context, func()) {
+	if t == nil {
+		return ctx, func() {}
+	}
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	t.mtx.Lock()
+	t.nextID++
+	id := strconv.FormatUint(t.nextID, 10)
+	t.queries[id] = &activeQuery{
+		query: Query{
+			ID:        id,
+			Tenant:    tenant.JoinTenantIDs(tenantIDs),
+			Path:      r.URL.Path,
+			Query:     params.Get("query"),
+			Start:     params.Get("start"),
+			End:       params.Get("end"),
+			Step:      params.Get("step"),
+			StartedAt: time.Now(),
+		},
+		stats:  stats,
+		cancel: cancel,
+	}
+	t.mtx.Unlock()
+
+	return ctx, func() {
+		t.mtx.Lock()
+		delete(t.queries, id)
+		t.mtx.Unlock()
+
+		cancel()
+	}
+}
+
+// Wrap returns an http.Handler tracking the requests it serves as in-flight queries.
+func (t *Tracker) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Parsing the form doesn't prevent the wrapped handler from reading it again. Requests
+		// with an invalid form are not tracked, and the wrapped handler reports the error.
+		if err := r.ParseForm(); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, done := t.Track(r.Context(), r, r.Form, querier_stats.FromContext(r.Context()))
+		defer done()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// List returns the in-flight queries of the tenant, sorted by start time.
+func (t *Tracker) List(tenantID string) []Query {
+	if t == nil {
+		return nil
+	}
+
+	now := time.Now()
+	queries := []Query{}
+
+	t.mtx.Lock()
+	for _, q := range t.queries {
+		if q.query.Tenant != tenantID {
+			continue
+		}
+
+		query := q.query
+		query.ElapsedSeconds = now.Sub(query.StartedAt).Seconds()
+		query.ShardedQueries = q.stats.LoadShardedQueries()
+		query.SplitQueries = q.stats.LoadSplitQueries()
+		query.FetchedSeriesCount = q.stats.LoadFetchedSeries()
+		query.FetchedChunksCount = q.stats.LoadFetchedChunks()
+		query.FetchedChunkBytes = q.stats.LoadFetchedChunkBytes()
+		queries = append(queries, query)
+	}
+	t.mtx.Unlock()
+
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].StartedAt.Equal(queries[j].StartedAt) {
+			return queries[i].ID < queries[j].ID
+		}
+		return queries[i].StartedAt.Before(queries[j].StartedAt)
+	})
+	return queries
+}
+
+// Cancel cancels the in-flight query with the given ID, if it belongs to the tenant. Returns false if the query
+// hasn't been found.
+func (t *Tracker) Cancel(tenantID, id string) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mtx.Lock()
+	q, ok := t.queries[id]
+	t.mtx.Unlock()
+
+	if !ok || q.query.Tenant != tenantID {
+		return false
+	}
+
+	q.cancel()
+	return true
+}
+
+type listResponse struct {
+	Queries []Query `json:"queries"`
+}
+
+// ListHandler is the HTTP handler listing the in-flight queries of the tenant.
+func (t *Tracker) ListHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := requestTenant(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	util.WriteJSONResponse(w, listResponse{Queries: t.List(tenantID)})
+}
+
+// CancelHandler is the HTTP handler canceling an in-flight query of the tenant.
+func (t *Tracker) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := requestTenant(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		http.Error(w, "missing query ID", http.StatusBadRequest)
+		return
+	}
+
+	if !t.Cancel(tenantID, id) {
+		http.Error(w, "query not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// requestTenant returns the tenant of the request. Cross-tenant queries are identified by the joined tenant IDs.
+func requestTenant(r *http.Request) (string, error) {
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return "", err
+	}
+	return tenant.JoinTenantIDs(tenantIDs), nil
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package activequeries
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+
+	stats := &querier_stats.Stats{}
+	stats.AddShardedQueries(16)
+	stats.AddFetchedSeries(100)
+	stats.AddFetchedChunks(200)
+
+	r := httptest.NewRequest(http.MethodGet, "/prometheus/api/v1/query_range?query=up&start=0&end=3600&step=60", nil)
+	require.NoError(t, r.ParseForm())
+
+	ctx, done := tracker.Track(user.InjectOrgID(context.Background(), "user-1"), r, r.Form, stats)
+	_, doneOther := tracker.Track(user.InjectOrgID(context.Background(), "user-2"), r, r.Form, nil)
+	defer doneOther()
+
+	queries := tracker.List("user-1")
+	require.Len(t, queries, 1)
+	q := queries[0]
+	assert.Equal(t, "user-1", q.Tenant)
+	assert.Equal(t, "/prometheus/api/v1/query_range", q.Path)
+	assert.Equal(t, "up", q.Query)
+	assert.Equal(t, "60", q.Step)
+	assert.Equal(t, uint32(16), q.ShardedQueries)
+	assert.Equal(t, uint64(100), q.FetchedSeriesCount)
+	assert.Equal(t, uint64(200), q.FetchedChunksCount)
+
+	// A tenant can't cancel queries of other tenants.
+	assert.False(t, tracker.Cancel("user-2", q.ID))
+	assert.NoError(t, ctx.Err())
+
+	assert.True(t, tracker.Cancel("user-1", q.ID))
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	done()
+	assert.Empty(t, tracker.List("user-1"))
+	assert.False(t, tracker.Cancel("user-1", q.ID))
+	assert.Len(t, tracker.List("user-2"), 1)
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+
+	ctx := user.InjectOrgID(context.Background(), "user-1")
+	trackedCtx, done := tracker.Track(ctx, httptest.NewRequest(http.MethodGet, "/", nil), nil, nil)
+	defer done()
+
+	assert.Equal(t, ctx, trackedCtx)
+	assert.Empty(t, tracker.List("user-1"))
+	assert.False(t, tracker.Cancel("user-1", "1"))
+}
+
+func TestTracker_Handlers(t *testing.T) {
+	tracker := NewTracker()
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	handler := tracker.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+
+	go func() {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(user.InjectOrgID(r.Context(), "user-1")))
+	}()
+	<-started
+
+	router := mux.NewRouter()
+	router.Path("/active_queries").Methods(http.MethodGet).HandlerFunc(tracker.ListHandler)
+	router.Path("/active_queries/{id}").Methods(http.MethodDelete).HandlerFunc(tracker.CancelHandler)
+
+	do := func(method, path, tenantID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r = r.WithContext(user.InjectOrgID(r.Context(), tenantID))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodGet, "/active_queries", "user-1")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp listResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Queries, 1)
+	assert.Equal(t, "up", resp.Queries[0].Query)
+
+	w = do(http.MethodGet, "/active_queries", "user-2")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"queries":[]}`, w.Body.String())
+
+	w = do(http.MethodDelete, "/active_queries/"+resp.Queries[0].ID, "user-2")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(http.MethodDelete, "/active_queries/"+resp.Queries[0].ID, "user-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	<-canceled
+}
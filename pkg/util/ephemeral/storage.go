@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ephemeral
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+const (
+	// StorageLabelName is a label name used to select queried storage type.
+	StorageLabelName            = "__mimir_storage__"
+	EphemeralStorageLabelValue  = "ephemeral"
+	PersistentStorageLabelValue = "persistent"
+
+	// AllStorageLabelValue selects both the persistent and the ephemeral storage. It's only supported by the
+	// queriers, which query each storage separately and merge the results.
+	AllStorageLabelValue = "all"
+)
+
+var (
+	ErrInvalidStorageMatcherType    = fmt.Errorf("invalid matcher used together with %s label, only equality check supported", StorageLabelName)
+	ErrMultipleStorageMatchersFound = fmt.Errorf("multiple matchers for %s label found, only one matcher supported", StorageLabelName)
+)
+
+// FindStorageLabelMatcher returns value of storage label matcher and its index, if it exists.
+// If it doesn't exist, the returned index is -1.
+func FindStorageLabelMatcher(matchers []*labels.Matcher) (string, int, error) {
+	resultVal, resultIdx := "", -1
+
+	for idx, matcher := range matchers {
+		if matcher.Name == StorageLabelName {
+			if resultIdx >= 0 {
+				return "", idx, ErrMultipleStorageMatchersFound
+			}
+			if matcher.Type != labels.MatchEqual {
+				return "", idx, ErrInvalidStorageMatcherType
+			}
+			resultVal = matcher.Value
+			resultIdx = idx
+		}
+	}
+
+	return resultVal, resultIdx, nil
+}
@@ -23,7 +23,11 @@ const (
 	SeriesWithDuplicateLabelNames ID = "duplicate-label-names"
 	SeriesLabelsNotSorted         ID = "labels-not-sorted"
 	SampleTooFarInFuture          ID = "too-far-in-future"
+	MaxNativeHistogramBuckets     ID = "max-native-histogram-buckets"
+	InvalidNativeHistogram        ID = "invalid-native-histogram"
 	MaxSeriesPerMetric            ID = "max-series-per-metric"
+	MaxLabelValuesPerLabelName    ID = "max-label-values-per-label-name"
+	MaxSeriesPerSelector          ID = "max-series-per-selector"
 	MaxMetadataPerMetric          ID = "max-metadata-per-metric"
 	MaxSeriesPerUser              ID = "max-series-per-user"
 	MaxEphemeralSeriesPerUser     ID = "max-ephemeral-series-per-user"
@@ -51,11 +55,14 @@ const (
 	MetricMetadataHelpTooLong       ID = "help-too-long" // unused, left here to prevent reuse for different purpose
 	MetricMetadataUnitTooLong       ID = "unit-too-long"
 
-	MaxQueryLength       ID = "max-query-length"
-	MaxTotalQueryLength  ID = "max-total-query-length"
-	RequestRateLimited   ID = "tenant-max-request-rate"
-	IngestionRateLimited ID = "tenant-max-ingestion-rate"
-	TooManyHAClusters    ID = "tenant-too-many-ha-clusters"
+	MaxQueryLength          ID = "max-query-length"
+	MaxTotalQueryLength     ID = "max-total-query-length"
+	QueryCostBudgetExceeded ID = "query-cost-budget-exceeded"
+	RequestRateLimited      ID = "tenant-max-request-rate"
+	IngestionRateLimited    ID = "tenant-max-ingestion-rate"
+	TooManyHAClusters       ID = "tenant-too-many-ha-clusters"
+
+	AdaptiveIngestionRateLimited ID = "tenant-adaptive-ingestion-rate"
 
 	SampleTimestampTooOld    ID = "sample-timestamp-too-old"
 	SampleOutOfOrder         ID = "sample-out-of-order"
@@ -93,6 +100,28 @@ func (id ID) MessageWithPerTenantLimitConfig(msg, flag string, addFlags ...strin
 	return fmt.Sprintf("%s (%s%s). To adjust the related per-tenant limit%s, configure %s, or contact your service administrator.", msg, errPrefix, id, plural, flagsList)
 }
 
+// MessageWithPerTenantRuntimeConfig returns the provided msg, appending the error id and a suggestion on
+// which runtime configuration option to use to change the per-tenant limit, for the limits without a flag.
+func (id ID) MessageWithPerTenantRuntimeConfig(msg, option string) string {
+	return fmt.Sprintf("%s (%s%s). To adjust the related per-tenant limit, configure %s in the runtime configuration, or contact your service administrator.", msg, errPrefix, id, option)
+}
+
+// IDFromMessage returns the ID of the error with the given message, if the message has been built with
+// one of the ID's message functions.
+func IDFromMessage(msg string) (ID, bool) {
+	start := strings.Index(msg, "("+errPrefix)
+	if start < 0 {
+		return "", false
+	}
+	msg = msg[start+len(errPrefix)+1:]
+
+	end := strings.IndexByte(msg, ')')
+	if end <= 0 {
+		return "", false
+	}
+	return ID(msg[:end]), true
+}
+
 func buildFlagsList(flag string, addFlags ...string) (string, string) {
 	var sb strings.Builder
 	sb.WriteString("-")
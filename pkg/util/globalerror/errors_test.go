@@ -58,3 +58,27 @@ func TestID_MessageWithPerTenantLimitConfig(t *testing.T) {
 		assert.Equal(t, tc.expected, tc.actual)
 	}
 }
+
+func TestID_MessageWithPerTenantRuntimeConfig(t *testing.T) {
+	assert.Equal(
+		t,
+		"an error (err-mimir-missing-metric-name). To adjust the related per-tenant limit, configure my_option in the runtime configuration, or contact your service administrator.",
+		MissingMetricName.MessageWithPerTenantRuntimeConfig("an error", "my_option"))
+}
+
+func TestIDFromMessage(t *testing.T) {
+	for _, tc := range []struct {
+		msg      string
+		expected ID
+	}{
+		{msg: MissingMetricName.Message("an error"), expected: MissingMetricName},
+		{msg: MaxSeriesPerUser.MessageWithPerTenantLimitConfig("an error", "my-flag1"), expected: MaxSeriesPerUser},
+		{msg: "failed pushing to ingester: " + SampleOutOfOrder.Message("an error"), expected: SampleOutOfOrder},
+		{msg: "an error"},
+		{msg: "an error (err-mimir-)"},
+	} {
+		id, ok := IDFromMessage(tc.msg)
+		assert.Equal(t, tc.expected, id, tc.msg)
+		assert.Equal(t, tc.expected != "", ok, tc.msg)
+	}
+}
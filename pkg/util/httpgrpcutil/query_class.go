@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package httpgrpcutil
+
+import (
+	"strings"
+
+	"github.com/weaveworks/common/httpgrpc"
+)
+
+// QueryClassHeader is the HTTP header carrying the class of a query. The query-scheduler uses the class
+// to prioritize the queries of the same tenant.
+const QueryClassHeader = "X-Mimir-Query-Class"
+
+// GetQueryClass returns the query class set in the request headers, or an empty string if it's not set.
+func GetQueryClass(req *httpgrpc.HTTPRequest) string {
+	for _, h := range req.GetHeaders() {
+		if strings.EqualFold(h.Key, QueryClassHeader) && len(h.Values) > 0 {
+			return h.Values[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package httpgrpcutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks/common/httpgrpc"
+)
+
+func TestGetQueryClass(t *testing.T) {
+	assert.Equal(t, "", GetQueryClass(&httpgrpc.HTTPRequest{}))
+
+	assert.Equal(t, "ruler", GetQueryClass(&httpgrpc.HTTPRequest{
+		Headers: []*httpgrpc.Header{
+			{Key: "Content-Type", Values: []string{"application/x-www-form-urlencoded"}},
+			{Key: "X-Mimir-Query-Class", Values: []string{"ruler"}},
+		},
+	}))
+
+	// Header keys are case-insensitive.
+	assert.Equal(t, "dashboard", GetQueryClass(&httpgrpc.HTTPRequest{
+		Headers: []*httpgrpc.Header{{Key: "x-mimir-query-class", Values: []string{"dashboard"}}},
+	}))
+}
@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package push
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/middleware"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	graphiteParseError = "graphite_parse_error"
+
+	graphiteTemplateName       = "name"
+	graphiteTemplateGreedyName = "name*"
+)
+
+// GraphiteConfig configures how the Graphite plaintext protocol is translated into series.
+type GraphiteConfig struct {
+	Templates flagext.StringSliceCSV `yaml:"templates" category:"experimental"`
+}
+
+// RegisterFlags registers the flags of the Graphite plaintext protocol ingestion.
+func (cfg *GraphiteConfig) RegisterFlags(f *flag.FlagSet) {
+	f.Var(&cfg.Templates, "distributor.graphite.templates", "Comma-separated list of templates used to translate the Graphite metric paths into a metric name and labels. "+
+		"Each template is in the format \"[<filter> ]<template>\", where the optional filter is a dot-separated pattern of the paths the template applies to, and the template is a dot-separated list of nodes: "+
+		"\"name\" appends the node to the metric name, \"name*\" appends the node and all the following ones to the metric name, an empty node is ignored, and any other node is a label name whose value is the node. "+
+		"The first template whose filter matches the path is used. The paths not matching any template are translated into a metric name replacing the dots with underscores.")
+}
+
+// Validate validates the config.
+func (cfg *GraphiteConfig) Validate() error {
+	_, err := parseGraphiteTemplates(cfg.Templates)
+	return err
+}
+
+// GraphiteHandler is a http.Handler which accepts the Graphite plaintext protocol, and translates it into WriteRequests.
+func GraphiteHandler(
+	maxRecvMsgSize int,
+	sourceIPs *middleware.SourceIPExtractor,
+	cfg GraphiteConfig,
+	reg prometheus.Registerer,
+	push Func,
+) http.Handler {
+	discardedDueToParseError := validation.DiscardedSamplesCounter(reg, graphiteParseError)
+
+	// The templates have been validated at startup.
+	templates, templatesErr := parseGraphiteTemplates(cfg.Templates)
+
+	return handler(maxRecvMsgSize, sourceIPs, false, push, func(ctx context.Context, r *http.Request, maxRecvMsgSize int, dst []byte, req *mimirpb.PreallocWriteRequest) ([]byte, error) {
+		if templatesErr != nil {
+			return nil, httpgrpc.Errorf(http.StatusInternalServerError, templatesErr.Error())
+		}
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := readRequestBody(r, maxRecvMsgSize)
+		if err != nil {
+			return body, err
+		}
+
+		p := graphiteParser{templates: templates, now: time.Now()}
+		p.parse(body)
+
+		if len(p.errs) > 0 {
+			discardedDueToParseError.WithLabelValues(userID, "").Add(float64(len(p.errs)))
+
+			parseErrs := formatParseErrors(p.errs)
+			if len(p.series) == 0 {
+				return body, errors.New(parseErrs)
+			}
+
+			level.Warn(log.WithContext(ctx, log.Logger)).Log("msg", "Graphite plaintext protocol parse error", "err", parseErrs)
+		}
+
+		req.Timeseries = p.series
+		return body, nil
+	})
+}
+
+type graphiteTemplate struct {
+	filter []string
+	nodes  []string
+}
+
+// parseGraphiteTemplates parses templates in the format "[<filter> ]<template>".
+func parseGraphiteTemplates(templates []string) ([]graphiteTemplate, error) {
+	parsed := make([]graphiteTemplate, 0, len(templates))
+	for _, t := range templates {
+		var tmpl graphiteTemplate
+
+		parts := strings.Fields(t)
+		switch len(parts) {
+		case 1:
+			tmpl.nodes = strings.Split(parts[0], ".")
+		case 2:
+			tmpl.filter = strings.Split(parts[0], ".")
+			tmpl.nodes = strings.Split(parts[1], ".")
+		default:
+			return nil, fmt.Errorf("invalid Graphite template %q: expected an optional filter and a template separated by a space", t)
+		}
+
+		for _, node := range tmpl.filter {
+			if _, err := path.Match(node, ""); err != nil {
+				return nil, fmt.Errorf("invalid filter of Graphite template %q: %w", t, err)
+			}
+		}
+
+		hasName := false
+		for i, node := range tmpl.nodes {
+			switch {
+			case node == graphiteTemplateName:
+				hasName = true
+			case node == graphiteTemplateGreedyName:
+				if i != len(tmpl.nodes)-1 {
+					return nil, fmt.Errorf("invalid Graphite template %q: %q must be the last node", t, graphiteTemplateGreedyName)
+				}
+				hasName = true
+			case strings.Contains(node, "*"):
+				return nil, fmt.Errorf("invalid Graphite template %q: only %q can contain a wildcard", t, graphiteTemplateGreedyName)
+			}
+		}
+		if !hasName {
+			return nil, fmt.Errorf("invalid Graphite template %q: at least one node must be %q or %q", t, graphiteTemplateName, graphiteTemplateGreedyName)
+		}
+
+		parsed = append(parsed, tmpl)
+	}
+	return parsed, nil
+}
+
+// matches returns whether the filter of the template matches the path nodes. A template without filter matches any path.
+func (t graphiteTemplate) matches(nodes []string) bool {
+	if len(t.filter) > len(nodes) {
+		return false
+	}
+	for i, pattern := range t.filter {
+		if ok, _ := path.Match(pattern, nodes[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// apply translates the path nodes into a metric name and labels.
+func (t graphiteTemplate) apply(nodes []string) (string, []mimirpb.LabelAdapter) {
+	var (
+		name []string
+		lbls []mimirpb.LabelAdapter
+	)
+
+	for i, node := range t.nodes {
+		if i >= len(nodes) {
+			break
+		}
+
+		switch node {
+		case "":
+		case graphiteTemplateName:
+			name = append(name, nodes[i])
+		case graphiteTemplateGreedyName:
+			name = append(name, nodes[i:]...)
+		default:
+			lbls = append(lbls, mimirpb.LabelAdapter{Name: node, Value: nodes[i]})
+		}
+	}
+
+	return strings.Join(name, "_"), lbls
+}
+
+type graphiteParser struct {
+	textSeriesBuilder
+
+	templates []graphiteTemplate
+	now       time.Time
+
+	errs []error
+}
+
+func (p *graphiteParser) parse(body []byte) {
+	for i, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := p.parseLine(string(line)); err != nil {
+			p.errs = append(p.errs, fmt.Errorf("line %d: %w", i+1, err))
+		}
+	}
+}
+
+// parseLine parses a line in the format:
+// <path>[;<tag>=<value>...] <value> [<timestamp>]
+func (p *graphiteParser) parseLine(line string) error {
+	parts := strings.Fields(line)
+	if len(parts) < 2 || len(parts) > 3 {
+		return errors.New("expected path, value and optional timestamp separated by spaces")
+	}
+
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return fmt.Errorf("invalid value %q", parts[1])
+	}
+
+	timestampMs := p.now.UnixMilli()
+	if len(parts) == 3 {
+		ts, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", parts[2])
+		}
+		// A negative timestamp means the time the sample has been received.
+		if ts >= 0 {
+			timestampMs = int64(ts * 1000)
+		}
+	}
+
+	tags := strings.Split(parts[0], ";")
+	metricPath := tags[0]
+	if metricPath == "" {
+		return errors.New("missing path")
+	}
+
+	nodes := strings.Split(metricPath, ".")
+	name := strings.Join(nodes, "_")
+	var lbls []mimirpb.LabelAdapter
+	for _, t := range p.templates {
+		if t.matches(nodes) {
+			name, lbls = t.apply(nodes)
+			break
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("the template matching the path %q produces an empty metric name", metricPath)
+	}
+
+	for _, tag := range tags[1:] {
+		tagName, tagValue, ok := strings.Cut(tag, "=")
+		if !ok || tagName == "" {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+		lbls = append(lbls, mimirpb.LabelAdapter{Name: tagName, Value: tagValue})
+	}
+
+	p.add(name, lbls, timestampMs, value)
+	return nil
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package push
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestGraphiteParser(t *testing.T) {
+	now := time.UnixMilli(1000)
+
+	templates, err := parseGraphiteTemplates([]string{
+		"servers.*.cpu .host.name*",
+		"servers.* .host.name.name.region",
+		"stats.*.counts name.name..name",
+	})
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		body         string
+		expected     []mimirpb.PreallocTimeseries
+		expectedErrs []string
+	}{
+		"path without matching template": {
+			body: "app.requests.count 12 1600000000",
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("app_requests_count", 1600000000000, 12),
+			},
+		},
+		"first matching template is used": {
+			body: "servers.host-1.cpu.user.percent 42.5 1600000000\nservers.host-1.disk.used.eu 10 1600000000",
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("cpu_user_percent", 1600000000000, 42.5, "host", "host-1"),
+				textSeries("disk_used", 1600000000000, 10, "host", "host-1", "region", "eu"),
+			},
+		},
+		"skipped nodes and extra nodes": {
+			body: "stats.prod.counts.requests.ignored 1 1600000000",
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("stats_prod_requests", 1600000000000, 1),
+			},
+		},
+		"tags": {
+			body: "app.requests;env=prod;data-center=eu 1 1600000000.5",
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("app_requests", 1600000000500, 1, "env", "prod", "data_center", "eu"),
+			},
+		},
+		"missing or negative timestamp": {
+			body: "app.requests 1\napp.requests 2 -1",
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("app_requests", 1000, 1),
+				textSeries("app_requests", 1000, 2),
+			},
+		},
+		"invalid lines": {
+			body: "app.requests\napp.requests foo 1600000000\napp.requests;env 1 1600000000\napp.requests 1 foo\napp.requests 1 1600000000",
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("app_requests", 1600000000000, 1),
+			},
+			expectedErrs: []string{
+				"line 1: expected path, value and optional timestamp separated by spaces",
+				`line 2: invalid value "foo"`,
+				`line 3: invalid tag "env"`,
+				`line 4: invalid timestamp "foo"`,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := graphiteParser{templates: templates, now: now}
+			p.parse([]byte(tc.body))
+
+			errs := make([]string, 0, len(p.errs))
+			for _, err := range p.errs {
+				errs = append(errs, err.Error())
+			}
+			assert.Equal(t, tc.expectedErrs, nilIfEmpty(errs))
+			assert.Equal(t, tc.expected, clearExemplars(p.series))
+		})
+	}
+}
+
+func TestParseGraphiteTemplates(t *testing.T) {
+	for template, expectedErr := range map[string]string{
+		"host.name*":              "",
+		"servers.* host.name":     "",
+		"host.region":             `invalid Graphite template "host.region": at least one node must be "name" or "name*"`,
+		"name*.host":              `invalid Graphite template "name*.host": "name*" must be the last node`,
+		"host*.name":              `invalid Graphite template "host*.name": only "name*" can contain a wildcard`,
+		"servers.[ host.name":     `invalid filter of Graphite template "servers.[ host.name": syntax error in pattern`,
+		"servers.* host.name foo": `invalid Graphite template "servers.* host.name foo": expected an optional filter and a template separated by a space`,
+	} {
+		t.Run(template, func(t *testing.T) {
+			_, err := parseGraphiteTemplates([]string{template})
+			if expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, expectedErr)
+			}
+		})
+	}
+}
+
+func TestGraphiteHandler(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+
+	var received []string
+	handler := GraphiteHandler(100000, nil, GraphiteConfig{Templates: []string{"servers.* .host.name*"}}, reg, receivedSeriesPushFunc(&received))
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/push/graphite", bytes.NewReader([]byte(body)))
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do("servers.host-1.load 1 1600000000\nservers.host-1.load foo 1600000000")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{`{__name__="load", host="host-1"}`}, received)
+
+	resp = do("servers.host-1.load")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "line 1: expected path, value and optional timestamp")
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="graphite_parse_error",user="user-1"} 2
+	`), "cortex_discarded_samples_total"))
+}
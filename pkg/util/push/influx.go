@@ -0,0 +1,292 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package push
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/middleware"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	influxParseError           = "influx_parse_error"
+	influxUnsupportedFieldType = "influx_unsupported_field_type"
+)
+
+// InfluxConfig configures how the Influx line protocol is translated into series.
+type InfluxConfig struct {
+	FieldSeparator string `yaml:"field_separator" category:"experimental"`
+	ValueFieldName string `yaml:"value_field_name" category:"experimental"`
+}
+
+// RegisterFlags registers the flags of the Influx line protocol ingestion.
+func (cfg *InfluxConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.FieldSeparator, "distributor.influx.field-separator", "_", "Separator between the measurement name and the field name used to build the metric name of the series ingested through the Influx line protocol endpoint.")
+	f.StringVar(&cfg.ValueFieldName, "distributor.influx.value-field-name", "value", "Name of the Influx field whose series are named after the measurement only, without the field name. Empty to always append the field name.")
+}
+
+// InfluxHandler is a http.Handler which accepts the Influx line protocol, and translates it into WriteRequests.
+// Each field of a line is translated into a series named <measurement><separator><field>, labeled with the tags of the line.
+func InfluxHandler(
+	maxRecvMsgSize int,
+	sourceIPs *middleware.SourceIPExtractor,
+	cfg InfluxConfig,
+	reg prometheus.Registerer,
+	push Func,
+) http.Handler {
+	discardedDueToParseError := validation.DiscardedSamplesCounter(reg, influxParseError)
+	discardedDueToUnsupportedFieldType := validation.DiscardedSamplesCounter(reg, influxUnsupportedFieldType)
+
+	return handler(maxRecvMsgSize, sourceIPs, false, push, func(ctx context.Context, r *http.Request, maxRecvMsgSize int, dst []byte, req *mimirpb.PreallocWriteRequest) ([]byte, error) {
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		precision, err := influxPrecision(r.URL.Query().Get("precision"))
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := readRequestBody(r, maxRecvMsgSize)
+		if err != nil {
+			return body, err
+		}
+
+		p := influxParser{cfg: cfg, precision: precision, now: time.Now()}
+		p.parse(body)
+
+		discardedDueToUnsupportedFieldType.WithLabelValues(userID, "").Add(float64(p.unsupportedFields))
+		if len(p.errs) > 0 {
+			discardedDueToParseError.WithLabelValues(userID, "").Add(float64(len(p.errs)))
+
+			parseErrs := formatParseErrors(p.errs)
+			if len(p.series) == 0 {
+				return body, errors.New(parseErrs)
+			}
+
+			level.Warn(log.WithContext(ctx, log.Logger)).Log("msg", "Influx line protocol parse error", "err", parseErrs)
+		}
+
+		req.Timeseries = p.series
+		return body, nil
+	})
+}
+
+// influxPrecision returns the duration of a timestamp unit for the given precision request param.
+func influxPrecision(precision string) (time.Duration, error) {
+	switch precision {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us", "µ", "µs":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	default:
+		return 0, httpgrpc.Errorf(http.StatusBadRequest, "invalid precision %q, supported: [ns, us, ms, s]", precision)
+	}
+}
+
+type influxParser struct {
+	textSeriesBuilder
+
+	cfg       InfluxConfig
+	precision time.Duration
+	now       time.Time
+
+	errs              []error
+	unsupportedFields int
+}
+
+func (p *influxParser) parse(body []byte) {
+	for i, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		if err := p.parseLine(string(line)); err != nil {
+			p.errs = append(p.errs, fmt.Errorf("line %d: %w", i+1, err))
+		}
+	}
+}
+
+// parseLine parses a line in the format:
+// <measurement>[,<tag>=<value>...] <field>=<value>[,<field>=<value>...] [<timestamp>]
+func (p *influxParser) parseLine(line string) error {
+	sections := splitUnescaped(line, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return errors.New("expected measurement, fields and optional timestamp separated by spaces")
+	}
+
+	timestampMs := p.now.UnixMilli()
+	if len(sections) == 3 {
+		ts, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", sections[2])
+		}
+		timestampMs = time.Unix(0, ts*int64(p.precision)).UnixMilli()
+	}
+
+	series := splitUnescaped(sections[0], ',', false)
+	measurement := unescapeInflux(series[0])
+	if measurement == "" {
+		return errors.New("missing measurement")
+	}
+
+	lbls := make([]mimirpb.LabelAdapter, 0, len(series)-1)
+	for _, tag := range series[1:] {
+		name, value, ok := splitKeyValue(tag)
+		if !ok {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+		lbls = append(lbls, mimirpb.LabelAdapter{Name: name, Value: value})
+	}
+
+	fields := splitUnescaped(sections[1], ',', true)
+	values := make([]float64, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	unsupported := 0
+	for _, field := range fields {
+		name, rawValue, ok := splitKeyValue(field)
+		if !ok {
+			return fmt.Errorf("invalid field %q", field)
+		}
+
+		value, supported, err := parseInfluxFieldValue(rawValue)
+		if err != nil {
+			return fmt.Errorf("invalid value of field %q: %w", name, err)
+		}
+		if !supported {
+			unsupported++
+			continue
+		}
+
+		names = append(names, name)
+		values = append(values, value)
+	}
+
+	// Add the series only once the whole line has been successfully parsed.
+	p.unsupportedFields += unsupported
+	for i, field := range names {
+		name := measurement
+		if p.cfg.ValueFieldName == "" || field != p.cfg.ValueFieldName {
+			name = measurement + p.cfg.FieldSeparator + field
+		}
+		p.add(name, lbls, timestampMs, values[i])
+	}
+	return nil
+}
+
+// parseInfluxFieldValue parses the value of a field. Returns false if the value is a string, which
+// is not supported.
+func parseInfluxFieldValue(value string) (float64, bool, error) {
+	if value == "" {
+		return 0, false, errors.New("empty value")
+	}
+
+	if value[0] == '"' {
+		if len(value) < 2 || value[len(value)-1] != '"' {
+			return 0, false, errors.New("unterminated string")
+		}
+		return 0, false, nil
+	}
+
+	switch value {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return 0, true, nil
+	}
+
+	switch value[len(value)-1] {
+	case 'i':
+		v, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+		return float64(v), true, err
+	case 'u':
+		v, err := strconv.ParseUint(value[:len(value)-1], 10, 64)
+		return float64(v), true, err
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	return v, true, err
+}
+
+// splitUnescaped splits s around the occurrences of sep which are not escaped with a backslash
+// and, if quotes is true, are not within double quotes. Escape sequences are preserved.
+func splitUnescaped(s string, sep byte, quotes bool) []string {
+	var (
+		parts   []string
+		start   int
+		inQuote bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quotes && s[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && s[i] == sep:
+			// Consecutive spaces are a single separator.
+			if sep == ' ' && i == start {
+				start = i + 1
+				continue
+			}
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// splitKeyValue splits an escaped <key>=<value> pair, and unescapes the key and, unless it's a string, the value.
+func splitKeyValue(s string) (string, string, bool) {
+	parts := splitUnescaped(s, '=', true)
+	if len(parts) < 2 || parts[0] == "" {
+		return "", "", false
+	}
+
+	key := unescapeInflux(parts[0])
+	value := strings.Join(parts[1:], "=")
+	if !strings.HasPrefix(value, `"`) {
+		value = unescapeInflux(value)
+	}
+	return key, value, true
+}
+
+// unescapeInflux removes the backslashes escaping commas, equal signs and spaces.
+func unescapeInflux(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == ',' || s[i+1] == '=' || s[i+1] == ' ') {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package push
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestInfluxParser(t *testing.T) {
+	now := time.UnixMilli(1000)
+	cfg := InfluxConfig{FieldSeparator: "_", ValueFieldName: "value"}
+
+	for name, tc := range map[string]struct {
+		body              string
+		precision         time.Duration
+		expected          []mimirpb.PreallocTimeseries
+		expectedErrs      []string
+		expectedUnsupport int
+	}{
+		"single field with tags and timestamp": {
+			body:      "cpu,host=a,region=eu-west usage_idle=12.5 1600000000000000000",
+			precision: time.Nanosecond,
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("cpu_usage_idle", 1600000000000, 12.5, "host", "a", "region", "eu-west"),
+			},
+		},
+		"multiple fields and types": {
+			body:      "disk,path=/ used=10i,free=20u,ok=true,label=\"some string\" 1600000000",
+			precision: time.Second,
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("disk_used", 1600000000000, 10, "path", "/"),
+				textSeries("disk_free", 1600000000000, 20, "path", "/"),
+				textSeries("disk_ok", 1600000000000, 1, "path", "/"),
+			},
+			expectedUnsupport: 1,
+		},
+		"value field and missing timestamp": {
+			body:      "temperature value=21.5",
+			precision: time.Nanosecond,
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("temperature", 1000, 21.5),
+			},
+		},
+		"escaped characters and sanitized names": {
+			body:      `my\ measurement,tag\,key=tag\ value,1tag=x field-name=1 1600000000000`,
+			precision: time.Millisecond,
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("my_measurement_field_name", 1600000000000, 1, "tag_key", "tag value", "_1tag", "x"),
+			},
+		},
+		"comments and empty lines": {
+			body:      "# comment\n\ncpu value=1 1600000000000\n",
+			precision: time.Millisecond,
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("cpu", 1600000000000, 1),
+			},
+		},
+		"invalid lines": {
+			body:      "cpu\ncpu value=foo\ncpu,host value=1\ncpu value=1 1600000000000\ncpu value=1 foo",
+			precision: time.Millisecond,
+			expected: []mimirpb.PreallocTimeseries{
+				textSeries("cpu", 1600000000000, 1),
+			},
+			expectedErrs: []string{
+				"line 1: expected measurement, fields and optional timestamp separated by spaces",
+				`line 2: invalid value of field "value": strconv.ParseFloat: parsing "foo": invalid syntax`,
+				`line 3: invalid tag "host"`,
+				`line 5: invalid timestamp "foo"`,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := influxParser{cfg: cfg, precision: tc.precision, now: now}
+			p.parse([]byte(tc.body))
+
+			errs := make([]string, 0, len(p.errs))
+			for _, err := range p.errs {
+				errs = append(errs, err.Error())
+			}
+			assert.Equal(t, tc.expectedErrs, nilIfEmpty(errs))
+			assert.Equal(t, tc.expected, clearExemplars(p.series))
+			assert.Equal(t, tc.expectedUnsupport, p.unsupportedFields)
+		})
+	}
+}
+
+func TestInfluxHandler(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+
+	var received []string
+	handler := InfluxHandler(100000, nil, InfluxConfig{FieldSeparator: "_", ValueFieldName: "value"}, reg, receivedSeriesPushFunc(&received))
+
+	do := func(url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(body)))
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do("/api/v1/push/influx/write?precision=s", "cpu,host=a value=1 1600000000\ncpu value=foo\nmem free=\"a lot\" 1600000000")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{`{__name__="cpu", host="a"}`}, received)
+
+	resp = do("/api/v1/push/influx/write", "cpu value=foo")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "line 1: invalid value")
+
+	resp = do("/api/v1/push/influx/write?precision=h", "cpu value=1")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="influx_parse_error",user="user-1"} 2
+		cortex_discarded_samples_total{group="",reason="influx_unsupported_field_type",user="user-1"} 1
+	`), "cortex_discarded_samples_total"))
+}
+
+// receivedSeriesPushFunc returns a push function appending the labels of the received series to received.
+func receivedSeriesPushFunc(received *[]string) Func {
+	return func(ctx context.Context, pushReq *Request) (*mimirpb.WriteResponse, error) {
+		req, err := pushReq.WriteRequest()
+		if err != nil {
+			return nil, err
+		}
+		defer pushReq.CleanUp()
+
+		for _, ts := range req.Timeseries {
+			*received = append(*received, mimirpb.FromLabelAdaptersToLabels(ts.Labels).String())
+		}
+		return &mimirpb.WriteResponse{}, nil
+	}
+}
+
+func textSeries(name string, timestampMs int64, value float64, lbls ...string) mimirpb.PreallocTimeseries {
+	ts := &mimirpb.TimeSeries{
+		Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: name}},
+		Samples: []mimirpb.Sample{{TimestampMs: timestampMs, Value: value}},
+	}
+	for i := 0; i < len(lbls); i += 2 {
+		ts.Labels = append(ts.Labels, mimirpb.LabelAdapter{Name: lbls[i], Value: lbls[i+1]})
+	}
+	return mimirpb.PreallocTimeseries{TimeSeries: ts}
+}
+
+// clearExemplars clears the empty exemplars of the series retrieved from the pool, to compare them with the expected ones.
+func clearExemplars(series []mimirpb.PreallocTimeseries) []mimirpb.PreallocTimeseries {
+	for _, s := range series {
+		s.Exemplars = nil
+	}
+	return series
+}
+
+func nilIfEmpty(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
@@ -3,29 +3,32 @@
 package push
 
 import (
-	"compress/gzip"
 	"context"
 	"errors"
-	"io"
+	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"time"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/tenant"
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/middleware"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	conventions "go.opentelemetry.io/collector/semconv/v1.6.1"
 	"go.uber.org/multierr"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
-	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
@@ -36,6 +39,15 @@ const (
 
 	otelParseError = "otlp_parse_error"
 	maxErrMsgLen   = 1024
+
+	// The native histograms support the schemas from -4 to 8, while the OTLP exponential histograms
+	// support any scale, so the histograms with a higher scale are downscaled.
+	minNativeHistogramSchema = -4
+	maxNativeHistogramSchema = 8
+
+	// otelZeroThreshold is the zero threshold of the native histograms translated from the OTLP exponential
+	// histograms, whose zero bucket only counts the observations equal to zero.
+	otelZeroThreshold = 1e-128
 )
 
 func OTLPHandler(
@@ -70,42 +82,8 @@ func OTLPHandler(
 			return nil, httpgrpc.Errorf(http.StatusUnsupportedMediaType, "unsupported content type: %s, supported: [%s, %s]", contentType, jsonContentType, pbContentType)
 		}
 
-		if r.ContentLength > int64(maxRecvMsgSize) {
-			return nil, httpgrpc.Errorf(http.StatusRequestEntityTooLarge, distributorMaxWriteMessageSizeErr{actual: int(r.ContentLength), limit: maxRecvMsgSize}.Error())
-		}
-
-		reader := r.Body
-		// Handle compression.
-		switch r.Header.Get("Content-Encoding") {
-		case "gzip":
-			gr, err := gzip.NewReader(reader)
-			if err != nil {
-				return nil, err
-			}
-			reader = gr
-
-		case "":
-			// No compression.
-
-		default:
-			return nil, httpgrpc.Errorf(http.StatusUnsupportedMediaType, "unsupported compression: %s. Only \"gzip\" or no compression supported", r.Header.Get("Content-Encoding"))
-		}
-
-		// Protect against a large input.
-		reader = http.MaxBytesReader(nil, reader, int64(maxRecvMsgSize))
-
-		body, err := io.ReadAll(reader)
+		body, err := readRequestBody(r, maxRecvMsgSize)
 		if err != nil {
-			r.Body.Close()
-
-			if util.IsRequestBodyTooLarge(err) {
-				return body, httpgrpc.Errorf(http.StatusRequestEntityTooLarge, distributorMaxWriteMessageSizeErr{actual: -1, limit: maxRecvMsgSize}.Error())
-			}
-
-			return body, err
-		}
-
-		if err = r.Body.Close(); err != nil {
 			return body, err
 		}
 
@@ -125,7 +103,11 @@ func OTLPHandler(
 }
 
 func otelMetricsToTimeseries(ctx context.Context, discardedDueToOtelParseError *prometheus.CounterVec, logger kitlog.Logger, md pmetric.Metrics) ([]mimirpb.PreallocTimeseries, error) {
+	// The exponential histograms aren't supported by the translator, so they're translated separately.
+	histogramsTs, histogramsErrs := otelExponentialHistogramsToTimeseries(md)
+
 	tsMap, errs := prometheusremotewrite.FromMetrics(md, prometheusremotewrite.Settings{})
+	errs = multierr.Append(errs, histogramsErrs)
 
 	if errs != nil {
 		userID, err := tenant.TenantID(ctx)
@@ -141,7 +123,7 @@ func otelMetricsToTimeseries(ctx context.Context, discardedDueToOtelParseError *
 			parseErrs = parseErrs[:maxErrMsgLen]
 		}
 
-		if len(tsMap) == 0 {
+		if len(tsMap) == 0 && len(histogramsTs) == 0 {
 			return nil, errors.New(parseErrs)
 		}
 
@@ -152,10 +134,175 @@ func otelMetricsToTimeseries(ctx context.Context, discardedDueToOtelParseError *
 	for _, promTs := range tsMap {
 		mimirTs = append(mimirTs, promToMimirTimeseries(promTs))
 	}
+	mimirTs = append(mimirTs, histogramsTs...)
 
 	return mimirTs, nil
 }
 
+// otelExponentialHistogramsToTimeseries removes the exponential histograms from the metrics,
+// and translates them into series of native histograms.
+func otelExponentialHistogramsToTimeseries(md pmetric.Metrics) ([]mimirpb.PreallocTimeseries, error) {
+	var (
+		errs   error
+		series []mimirpb.PreallocTimeseries
+		byKey  = map[string]*mimirpb.TimeSeries{}
+	)
+
+	resourceMetrics := md.ResourceMetrics()
+	for i := 0; i < resourceMetrics.Len(); i++ {
+		resource := resourceMetrics.At(i).Resource()
+		scopeMetrics := resourceMetrics.At(i).ScopeMetrics()
+
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			scopeMetrics.At(j).Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				if metric.Type() != pmetric.MetricTypeExponentialHistogram {
+					return false
+				}
+
+				if metric.ExponentialHistogram().AggregationTemporality() != pmetric.AggregationTemporalityCumulative {
+					errs = multierr.Append(errs, fmt.Errorf("invalid temporality and type combination for metric %q", metric.Name()))
+					return true
+				}
+
+				name := prometheustranslator.BuildPromCompliantName(metric, "")
+				points := metric.ExponentialHistogram().DataPoints()
+				for k := 0; k < points.Len(); k++ {
+					h, err := otelExponentialHistogramToHistogram(points.At(k))
+					if err != nil {
+						errs = multierr.Append(errs, fmt.Errorf("metric %q: %w", metric.Name(), err))
+						continue
+					}
+
+					lbls := otelLabels(resource, points.At(k).Attributes(), name)
+					key := mimirpb.FromLabelAdaptersToLabels(lbls).String()
+					ts, ok := byKey[key]
+					if !ok {
+						ts = mimirpb.TimeseriesFromPool()
+						ts.Labels = lbls
+						byKey[key] = ts
+						series = append(series, mimirpb.PreallocTimeseries{TimeSeries: ts})
+					}
+					ts.Histograms = append(ts.Histograms, h)
+				}
+				return true
+			})
+		}
+	}
+
+	for _, ts := range series {
+		sort.Slice(ts.Histograms, func(i, j int) bool {
+			return ts.Histograms[i].Timestamp < ts.Histograms[j].Timestamp
+		})
+	}
+
+	return series, errs
+}
+
+// otelExponentialHistogramToHistogram translates an OTLP exponential histogram data point into a native histogram.
+func otelExponentialHistogramToHistogram(p pmetric.ExponentialHistogramDataPoint) (mimirpb.Histogram, error) {
+	scale := p.Scale()
+	if scale < minNativeHistogramSchema {
+		return mimirpb.Histogram{}, fmt.Errorf("the exponential histogram scale %d is lower than the minimum supported %d", scale, minNativeHistogramSchema)
+	}
+
+	var scaleDown int32
+	if scale > maxNativeHistogramSchema {
+		scaleDown = scale - maxNativeHistogramSchema
+		scale = maxNativeHistogramSchema
+	}
+
+	positiveSpans, positiveDeltas := otelBucketsToSpans(p.Positive(), scaleDown)
+	negativeSpans, negativeDeltas := otelBucketsToSpans(p.Negative(), scaleDown)
+
+	h := mimirpb.Histogram{
+		Count:          &mimirpb.Histogram_CountInt{CountInt: p.Count()},
+		Schema:         scale,
+		ZeroThreshold:  otelZeroThreshold,
+		ZeroCount:      &mimirpb.Histogram_ZeroCountInt{ZeroCountInt: p.ZeroCount()},
+		PositiveSpans:  positiveSpans,
+		PositiveDeltas: positiveDeltas,
+		NegativeSpans:  negativeSpans,
+		NegativeDeltas: negativeDeltas,
+		Timestamp:      p.Timestamp().AsTime().UnixMilli(),
+	}
+	if p.HasSum() {
+		h.Sum = p.Sum()
+	}
+	if p.Flags().NoRecordedValue() {
+		h.Sum = math.Float64frombits(value.StaleNaN)
+	}
+
+	return h, nil
+}
+
+// otelBucketsToSpans translates the OTLP exponential histogram buckets into a single span of delta-encoded
+// bucket counts, merging 2^scaleDown adjacent buckets into one.
+func otelBucketsToSpans(buckets pmetric.ExponentialHistogramDataPointBuckets, scaleDown int32) ([]mimirpb.BucketSpan, []int64) {
+	counts := buckets.BucketCounts()
+	if counts.Len() == 0 {
+		return nil, nil
+	}
+
+	// The OTLP bucket with index i covers the range (base^i, base^(i+1)], while the native histogram
+	// bucket with the same range has index i+1.
+	bucketIndex := func(i int) int32 {
+		return (buckets.Offset()+int32(i))>>scaleDown + 1
+	}
+
+	first := bucketIndex(0)
+	merged := make([]int64, bucketIndex(counts.Len()-1)-first+1)
+	for i := 0; i < counts.Len(); i++ {
+		merged[bucketIndex(i)-first] += int64(counts.At(i))
+	}
+
+	deltas := make([]int64, len(merged))
+	prev := int64(0)
+	for i, c := range merged {
+		deltas[i] = c - prev
+		prev = c
+	}
+
+	return []mimirpb.BucketSpan{{Offset: first, Length: uint32(len(merged))}}, deltas
+}
+
+// otelLabels returns the sorted labels of a data point, built the same way the prometheusremotewrite translator does.
+func otelLabels(resource pcommon.Resource, attributes pcommon.Map, name string) []mimirpb.LabelAdapter {
+	byName := map[string]string{}
+
+	// The attributes are sorted to consistently merge the ones colliding once normalized.
+	sorted := pcommon.NewMap()
+	attributes.CopyTo(sorted)
+	sorted.Sort()
+	sorted.Range(func(key string, v pcommon.Value) bool {
+		normalized := prometheustranslator.NormalizeLabel(key)
+		if existing, ok := byName[normalized]; ok {
+			byName[normalized] = existing + ";" + v.AsString()
+		} else {
+			byName[normalized] = v.AsString()
+		}
+		return true
+	})
+
+	if serviceName, ok := resource.Attributes().Get(conventions.AttributeServiceName); ok {
+		job := serviceName.AsString()
+		if serviceNamespace, ok := resource.Attributes().Get(conventions.AttributeServiceNamespace); ok {
+			job = serviceNamespace.AsString() + "/" + job
+		}
+		byName[model.JobLabel] = job
+	}
+	if instance, ok := resource.Attributes().Get(conventions.AttributeServiceInstanceID); ok {
+		byName[model.InstanceLabel] = instance.AsString()
+	}
+	byName[model.MetricNameLabel] = name
+
+	lbls := make([]mimirpb.LabelAdapter, 0, len(byName))
+	for n, v := range byName {
+		lbls = append(lbls, mimirpb.LabelAdapter{Name: n, Value: v})
+	}
+	sort.Slice(lbls, func(i, j int) bool { return lbls[i].Name < lbls[j].Name })
+	return lbls
+}
+
 func promToMimirTimeseries(promTs *prompb.TimeSeries) mimirpb.PreallocTimeseries {
 	labels := make([]mimirpb.LabelAdapter, 0, len(promTs.Labels))
 	for _, label := range promTs.Labels {
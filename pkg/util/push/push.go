@@ -6,13 +6,17 @@
 package push
 
 import (
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"sync"
 
 	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/middleware"
 
@@ -40,7 +44,13 @@ var bufferPool = sync.Pool{
 const SkipLabelNameValidationHeader = "X-Mimir-SkipLabelNameValidation"
 const statusClientClosedRequest = 499
 
-// Handler is a http.Handler which accepts WriteRequests.
+const (
+	remoteWriteV1Proto = "prometheus.WriteRequest"
+	remoteWriteV2Proto = "io.prometheus.write.v2.Request"
+)
+
+// Handler is a http.Handler which accepts WriteRequests. Requests in the remote write 2.0 protocol, whose label
+// names and values are interned into a symbols table, are accepted too, negotiated by the content type.
 func Handler(
 	maxRecvMsgSize int,
 	sourceIPs *middleware.SourceIPExtractor,
@@ -48,7 +58,17 @@ func Handler(
 	push Func,
 ) http.Handler {
 	return handler(maxRecvMsgSize, sourceIPs, allowSkipLabelNameValidation, push, func(ctx context.Context, r *http.Request, maxRecvMsgSize int, dst []byte, req *mimirpb.PreallocWriteRequest) ([]byte, error) {
-		res, err := util.ParseProtoReader(ctx, r.Body, int(r.ContentLength), maxRecvMsgSize, dst, req, util.RawSnappy)
+		v2, err := isRemoteWriteV2(r.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+
+		var msg proto.Message = req
+		if v2 {
+			msg = mimirpb.PreallocWriteRequestV2{PreallocWriteRequest: req}
+		}
+
+		res, err := util.ParseProtoReader(ctx, r.Body, int(r.ContentLength), maxRecvMsgSize, dst, msg, util.RawSnappy)
 		if errors.Is(err, util.MsgSizeTooLargeErr{}) {
 			err = distributorMaxWriteMessageSizeErr{actual: int(r.ContentLength), limit: maxRecvMsgSize}
 		}
@@ -56,6 +76,29 @@ func Handler(
 	})
 }
 
+// isRemoteWriteV2 returns whether the content type of a push request is the one of the remote write 2.0 protocol,
+// which is negotiated with the "proto" parameter of the protobuf content type.
+func isRemoteWriteV2(contentType string) (bool, error) {
+	if contentType == "" {
+		return false, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != pbContentType {
+		// Keep accepting the requests with a missing or invalid content type, as remote write 1.0 requests.
+		return false, nil
+	}
+
+	switch params["proto"] {
+	case "", remoteWriteV1Proto:
+		return false, nil
+	case remoteWriteV2Proto:
+		return true, nil
+	default:
+		return false, httpgrpc.Errorf(http.StatusUnsupportedMediaType, "unsupported proto %q in content type, supported: [%s, %s]", params["proto"], remoteWriteV1Proto, remoteWriteV2Proto)
+	}
+}
+
 type distributorMaxWriteMessageSizeErr struct {
 	actual, limit int
 }
@@ -68,6 +111,50 @@ func (e distributorMaxWriteMessageSizeErr) Error() string {
 	return globalerror.DistributorMaxWriteMessageSize.MessageWithPerInstanceLimitConfig(fmt.Sprintf("the incoming push request has been rejected because its message size%s is larger than the allowed limit of %d bytes", msgSizeDesc, e.limit), "distributor.max-recv-msg-size")
 }
 
+// readRequestBody reads the gzip-compressed or uncompressed body of the request r, up to maxRecvMsgSize bytes.
+func readRequestBody(r *http.Request, maxRecvMsgSize int) ([]byte, error) {
+	if r.ContentLength > int64(maxRecvMsgSize) {
+		return nil, httpgrpc.Errorf(http.StatusRequestEntityTooLarge, distributorMaxWriteMessageSizeErr{actual: int(r.ContentLength), limit: maxRecvMsgSize}.Error())
+	}
+
+	reader := r.Body
+	// Handle compression.
+	switch r.Header.Get("Content-Encoding") {
+	case "gzip":
+		gr, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		reader = gr
+
+	case "":
+		// No compression.
+
+	default:
+		return nil, httpgrpc.Errorf(http.StatusUnsupportedMediaType, "unsupported compression: %s. Only \"gzip\" or no compression supported", r.Header.Get("Content-Encoding"))
+	}
+
+	// Protect against a large input.
+	reader = http.MaxBytesReader(nil, reader, int64(maxRecvMsgSize))
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		r.Body.Close()
+
+		if util.IsRequestBodyTooLarge(err) {
+			return body, httpgrpc.Errorf(http.StatusRequestEntityTooLarge, distributorMaxWriteMessageSizeErr{actual: -1, limit: maxRecvMsgSize}.Error())
+		}
+
+		return body, err
+	}
+
+	if err = r.Body.Close(); err != nil {
+		return body, err
+	}
+
+	return body, nil
+}
+
 func handler(maxRecvMsgSize int,
 	sourceIPs *middleware.SourceIPExtractor,
 	allowSkipLabelNameValidation bool,
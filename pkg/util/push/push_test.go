@@ -20,6 +20,7 @@ import (
 	"github.com/golang/snappy"
 	"github.com/grafana/dskit/tenant"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -40,6 +41,47 @@ func TestHandler_remoteWrite(t *testing.T) {
 	assert.Equal(t, 200, resp.Code)
 }
 
+func TestHandler_remoteWriteV2(t *testing.T) {
+	data, err := mimirpb.MarshalWriteRequestV2(&mimirpb.WriteRequest{
+		Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: "foo"}},
+			Samples: []mimirpb.Sample{{Value: 1, TimestampMs: 2}},
+		}}},
+		Metadata: []*mimirpb.MetricMetadata{{Type: mimirpb.COUNTER, MetricFamilyName: "foo", Help: "Foo."}},
+	})
+	require.NoError(t, err)
+
+	for contentType, expectedCode := range map[string]int{
+		"application/x-protobuf;proto=io.prometheus.write.v2.Request": http.StatusOK,
+		"application/x-protobuf;proto=unknown":                        http.StatusUnsupportedMediaType,
+	} {
+		t.Run(contentType, func(t *testing.T) {
+			req := createRequest(t, data)
+			req.Header.Set("Content-Type", contentType)
+
+			var received *mimirpb.WriteRequest
+			resp := httptest.NewRecorder()
+			handler := Handler(100000, nil, false, func(ctx context.Context, pushReq *Request) (*mimirpb.WriteResponse, error) {
+				request, err := pushReq.WriteRequest()
+				if err != nil {
+					return nil, err
+				}
+				received = request
+				return &mimirpb.WriteResponse{}, nil
+			})
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, expectedCode, resp.Code)
+
+			if expectedCode == http.StatusOK {
+				require.Len(t, received.Timeseries, 1)
+				assert.Equal(t, []mimirpb.LabelAdapter{{Name: "__name__", Value: "foo"}}, received.Timeseries[0].Labels)
+				assert.Equal(t, []mimirpb.Sample{{Value: 1, TimestampMs: 2}}, received.Timeseries[0].Samples)
+				assert.Equal(t, []*mimirpb.MetricMetadata{{Type: mimirpb.COUNTER, MetricFamilyName: "foo", Help: "Foo."}}, received.Metadata)
+			}
+		})
+	}
+}
+
 func TestHandler_otlpWriteNoCompression(t *testing.T) {
 	req := createOTLPRequest(t, createOTLPMetricRequest(t), false)
 	resp := httptest.NewRecorder()
@@ -166,6 +208,145 @@ func TestHandler_otlpDroppedMetricsPanic2(t *testing.T) {
 	assert.Equal(t, 200, resp.Code)
 }
 
+func TestHandler_otlpExponentialHistograms(t *testing.T) {
+	md := pmetric.NewMetrics()
+	resource := md.ResourceMetrics().AppendEmpty()
+	resource.Resource().Attributes().PutStr("service.name", "api")
+	resource.Resource().Attributes().PutStr("service.instance.id", "instance-1")
+
+	metrics := resource.ScopeMetrics().AppendEmpty().Metrics()
+
+	metric1 := metrics.AppendEmpty()
+	metric1.SetName("request_duration")
+	metric1.SetEmptyExponentialHistogram()
+	metric1.ExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	for _, ts := range []int64{2000, 1000} {
+		datapoint := metric1.ExponentialHistogram().DataPoints().AppendEmpty()
+		datapoint.SetTimestamp(pcommon.NewTimestampFromTime(time.UnixMilli(ts)))
+		datapoint.SetCount(16)
+		datapoint.SetSum(30)
+		datapoint.SetScale(10)
+		datapoint.SetZeroCount(1)
+		datapoint.Positive().SetOffset(-1)
+		datapoint.Positive().BucketCounts().FromRaw([]uint64{1, 2, 3, 4, 5})
+		datapoint.Attributes().PutStr("http.method", "GET")
+	}
+
+	metric2 := metrics.AppendEmpty()
+	metric2.SetName("delta_duration")
+	metric2.SetEmptyExponentialHistogram()
+	metric2.ExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	metric2.ExponentialHistogram().DataPoints().AppendEmpty()
+
+	metric3 := metrics.AppendEmpty()
+	metric3.SetName("up")
+	metric3.SetEmptyGauge()
+	datapoint := metric3.Gauge().DataPoints().AppendEmpty()
+	datapoint.SetTimestamp(pcommon.NewTimestampFromTime(time.UnixMilli(1000)))
+	datapoint.SetDoubleValue(1)
+
+	expectedHistogram := func(ts int64) mimirpb.Histogram {
+		return mimirpb.Histogram{
+			Count:          &mimirpb.Histogram_CountInt{CountInt: 16},
+			Sum:            30,
+			Schema:         8,
+			ZeroThreshold:  otelZeroThreshold,
+			ZeroCount:      &mimirpb.Histogram_ZeroCountInt{ZeroCountInt: 1},
+			PositiveSpans:  []mimirpb.BucketSpan{{Offset: 0, Length: 2}},
+			PositiveDeltas: []int64{1, 13},
+			Timestamp:      ts,
+		}
+	}
+
+	req := createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
+	resp := httptest.NewRecorder()
+	handler := OTLPHandler(100000, nil, false, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+		request, err := pushReq.WriteRequest()
+		require.NoError(t, err)
+		require.Len(t, request.Timeseries, 2)
+
+		ts := request.Timeseries[1]
+		assert.Equal(t, []mimirpb.LabelAdapter{
+			{Name: "__name__", Value: "request_duration"},
+			{Name: "http_method", Value: "GET"},
+			{Name: "instance", Value: "instance-1"},
+			{Name: "job", Value: "api"},
+		}, ts.Labels)
+		assert.Empty(t, ts.Samples)
+		assert.Equal(t, []mimirpb.Histogram{expectedHistogram(1000), expectedHistogram(2000)}, ts.Histograms)
+
+		pushReq.CleanUp()
+		return &mimirpb.WriteResponse{}, nil
+	})
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, 200, resp.Code)
+}
+
+func TestOTLPExponentialHistogramToHistogram(t *testing.T) {
+	for name, tc := range map[string]struct {
+		scale          int32
+		offset         int32
+		counts         []uint64
+		expectedSchema int32
+		expectedSpans  []mimirpb.BucketSpan
+		expectedDeltas []int64
+		expectedErr    string
+	}{
+		"supported scale": {
+			scale:          3,
+			offset:         2,
+			counts:         []uint64{4, 2, 0, 1},
+			expectedSchema: 3,
+			expectedSpans:  []mimirpb.BucketSpan{{Offset: 3, Length: 4}},
+			expectedDeltas: []int64{4, -2, -2, 1},
+		},
+		"scale higher than the maximum schema": {
+			scale:          9,
+			offset:         -3,
+			counts:         []uint64{1, 2, 3, 4},
+			expectedSchema: 8,
+			expectedSpans:  []mimirpb.BucketSpan{{Offset: -1, Length: 3}},
+			expectedDeltas: []int64{1, 4, -1},
+		},
+		"no buckets": {
+			scale:          0,
+			expectedSchema: 0,
+		},
+		"scale lower than the minimum schema": {
+			scale:       -5,
+			expectedErr: "the exponential histogram scale -5 is lower than the minimum supported -4",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := pmetric.NewExponentialHistogramDataPoint()
+			p.SetScale(tc.scale)
+			p.Positive().SetOffset(tc.offset)
+			p.Positive().BucketCounts().FromRaw(tc.counts)
+			p.Negative().SetOffset(tc.offset)
+			p.Negative().BucketCounts().FromRaw(tc.counts)
+
+			h, err := otelExponentialHistogramToHistogram(p)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSchema, h.Schema)
+			assert.Equal(t, tc.expectedSpans, h.PositiveSpans)
+			assert.Equal(t, tc.expectedDeltas, h.PositiveDeltas)
+			assert.Equal(t, tc.expectedSpans, h.NegativeSpans)
+			assert.Equal(t, tc.expectedDeltas, h.NegativeDeltas)
+		})
+	}
+
+	p := pmetric.NewExponentialHistogramDataPoint()
+	p.SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(true))
+	h, err := otelExponentialHistogramToHistogram(p)
+	require.NoError(t, err)
+	assert.True(t, value.IsStaleNaN(h.Sum))
+}
+
 func TestHandler_otlpWriteWithCompression(t *testing.T) {
 	req := createOTLPRequest(t, createOTLPMetricRequest(t), true)
 	resp := httptest.NewRecorder()
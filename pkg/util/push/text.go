@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package push
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// maxTextParseErrors is the max number of parse errors reported back to the client when
+// parsing a text-based ingestion protocol.
+const maxTextParseErrors = 10
+
+// sanitizeMetricName replaces the characters which are not allowed in a metric name with underscores.
+func sanitizeMetricName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// sanitizeLabelName replaces the characters which are not allowed in a label name with underscores.
+func sanitizeLabelName(name string) string {
+	return sanitizeName(name, false)
+}
+
+func sanitizeName(name string, allowColons bool) string {
+	if name == "" {
+		return name
+	}
+
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	if name[0] >= '0' && name[0] <= '9' {
+		b.WriteByte('_')
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || (allowColons && c == ':') {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// textSeriesBuilder builds the series of a push request parsed from a text-based ingestion protocol.
+type textSeriesBuilder struct {
+	series []mimirpb.PreallocTimeseries
+}
+
+// add adds a series with the given name, labels and sample. The labels are sanitized, and the
+// labels whose sanitized name is the metric name label are dropped.
+func (b *textSeriesBuilder) add(name string, lbls []mimirpb.LabelAdapter, timestampMs int64, value float64) {
+	if b.series == nil {
+		b.series = mimirpb.PreallocTimeseriesSliceFromPool()
+	}
+
+	ts := mimirpb.TimeseriesFromPool()
+	ts.Labels = append(ts.Labels, mimirpb.LabelAdapter{Name: model.MetricNameLabel, Value: sanitizeMetricName(name)})
+	for _, l := range lbls {
+		labelName := sanitizeLabelName(l.Name)
+		if labelName == model.MetricNameLabel {
+			continue
+		}
+		ts.Labels = append(ts.Labels, mimirpb.LabelAdapter{Name: labelName, Value: l.Value})
+	}
+	ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: timestampMs, Value: value})
+
+	b.series = append(b.series, mimirpb.PreallocTimeseries{TimeSeries: ts})
+}
+
+// formatParseErrors formats the first parse errors into a single message.
+func formatParseErrors(errs []error) string {
+	msgs := make([]string, 0, maxTextParseErrors)
+	for i, err := range errs {
+		if i == maxTextParseErrors {
+			msgs = append(msgs, fmt.Sprintf("and %d more errors", len(errs)-maxTextParseErrors))
+			break
+		}
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,312 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// RedisServer is a minimal in-process Redis stand-in speaking the RESP protocol. It only
+// supports the subset of commands used by the Redis cache client (GET, SET, MGET, DEL,
+// AUTH, SELECT and PING) and it's meant to be used in tests only.
+type RedisServer struct {
+	listener net.Listener
+	password string
+
+	mtx   sync.Mutex
+	dbs   map[int]map[string]redisItem
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+type redisItem struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewRedisServer starts a new RedisServer listening on a random local port. If password is
+// not empty, clients are required to authenticate before issuing any other command. The
+// server is automatically stopped at the end of the test.
+func NewRedisServer(t testing.TB, password string) *RedisServer {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &RedisServer{
+		listener: listener,
+		password: password,
+		dbs:      map[int]map[string]redisItem{},
+		conns:    map[net.Conn]struct{}{},
+	}
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Addr returns the address the server is listening on.
+func (s *RedisServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Keys returns the number of keys currently stored in the given database.
+func (s *RedisServer) Keys(db int) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return len(s.dbs[db])
+}
+
+// Close stops the server and closes all client connections.
+func (s *RedisServer) Close() {
+	_ = s.listener.Close()
+
+	s.mtx.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mtx.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *RedisServer) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mtx.Lock()
+		s.conns[conn] = struct{}{}
+		s.mtx.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *RedisServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mtx.Lock()
+		delete(s.conns, conn)
+		s.mtx.Unlock()
+		_ = conn.Close()
+	}()
+
+	var (
+		r             = bufio.NewReader(conn)
+		w             = bufio.NewWriter(conn)
+		db            = 0
+		authenticated = s.password == ""
+	)
+
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			// AUTH [username] password
+			if args[len(args)-1] != s.password {
+				writeRESPError(w, "WRONGPASS invalid username-password pair")
+				break
+			}
+			authenticated = true
+			writeRESPSimpleString(w, "OK")
+		case !authenticated:
+			writeRESPError(w, "NOAUTH Authentication required.")
+		case cmd == "PING":
+			writeRESPSimpleString(w, "PONG")
+		case cmd == "SELECT" && len(args) == 2:
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				writeRESPError(w, "ERR invalid DB index")
+				break
+			}
+			db = n
+			writeRESPSimpleString(w, "OK")
+		case cmd == "GET" && len(args) == 2:
+			if v, ok := s.get(db, args[1]); ok {
+				writeRESPBulkString(w, v)
+			} else {
+				writeRESPNull(w)
+			}
+		case cmd == "MGET" && len(args) >= 2:
+			fmt.Fprintf(w, "*%d\r\n", len(args)-1)
+			for _, key := range args[1:] {
+				if v, ok := s.get(db, key); ok {
+					writeRESPBulkString(w, v)
+				} else {
+					writeRESPNull(w)
+				}
+			}
+		case cmd == "SET" && len(args) >= 3:
+			ttl, err := parseRESPSetTTL(args[3:])
+			if err != nil {
+				writeRESPError(w, "ERR "+err.Error())
+				break
+			}
+			s.set(db, args[1], args[2], ttl)
+			writeRESPSimpleString(w, "OK")
+		case cmd == "DEL" && len(args) >= 2:
+			fmt.Fprintf(w, ":%d\r\n", s.del(db, args[1:]))
+		default:
+			writeRESPError(w, fmt.Sprintf("ERR unknown command or wrong number of arguments for '%s'", args[0]))
+		}
+
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *RedisServer) get(db int, key string) (string, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	item, ok := s.dbs[db][key]
+	if !ok {
+		return "", false
+	}
+	if !item.expiresAt.IsZero() && time.Now().After(item.expiresAt) {
+		delete(s.dbs[db], key)
+		return "", false
+	}
+	return item.value, true
+}
+
+func (s *RedisServer) set(db int, key, value string, ttl time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.dbs[db] == nil {
+		s.dbs[db] = map[string]redisItem{}
+	}
+
+	item := redisItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+	s.dbs[db][key] = item
+}
+
+func (s *RedisServer) del(db int, keys []string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	deleted := 0
+	for _, key := range keys {
+		if _, ok := s.dbs[db][key]; ok {
+			delete(s.dbs[db], key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// parseRESPSetTTL parses the optional EX and PX arguments of the SET command.
+func parseRESPSetTTL(args []string) (time.Duration, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	if len(args) != 2 {
+		return 0, errors.New("syntax error")
+	}
+
+	n, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return 0, errors.New("value is not an integer or out of range")
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "EX":
+		return time.Duration(n) * time.Second, nil
+	case "PX":
+		return time.Duration(n) * time.Millisecond, nil
+	default:
+		return 0, errors.New("syntax error")
+	}
+}
+
+// readRESPCommand reads a command sent by a client, encoded as a RESP array of bulk strings.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[0] != '*' {
+		return nil, fmt.Errorf("unexpected command header %q", line)
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid number of arguments %q", line)
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readRESPLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) < 2 || line[0] != '$' {
+			return nil, fmt.Errorf("unexpected argument header %q", line)
+		}
+
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid argument size %q", line)
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+
+	return args, nil
+}
+
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func writeRESPSimpleString(w io.Writer, s string) {
+	fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func writeRESPError(w io.Writer, msg string) {
+	fmt.Fprintf(w, "-%s\r\n", msg)
+}
+
+func writeRESPBulkString(w io.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeRESPNull(w io.Writer) {
+	fmt.Fprint(w, "$-1\r\n")
+}
@@ -152,6 +152,31 @@ func newSampleTimestampTooNewError(metricName string, timestamp int64) Validatio
 	}
 }
 
+// maxNativeHistogramBucketsError is a ValidationError implementation for native histogram samples with too many buckets.
+type maxNativeHistogramBucketsError struct {
+	metricName string
+	timestamp  int64
+	buckets    int
+	limit      int
+}
+
+var maxNativeHistogramBucketsMsgFormat = globalerror.MaxNativeHistogramBuckets.MessageWithPerTenantLimitConfig(
+	"received a native histogram sample with too many buckets, timestamp: %d series: '%.200s' buckets: %d limit: %d",
+	maxNativeHistogramBucketsFlag)
+
+func newMaxNativeHistogramBucketsError(metricName string, timestamp int64, buckets, limit int) ValidationError {
+	return maxNativeHistogramBucketsError{
+		metricName: metricName,
+		timestamp:  timestamp,
+		buckets:    buckets,
+		limit:      limit,
+	}
+}
+
+func (e maxNativeHistogramBucketsError) Error() string {
+	return fmt.Sprintf(maxNativeHistogramBucketsMsgFormat, e.timestamp, e.metricName, e.buckets, e.limit)
+}
+
 // exemplarValidationError is a ValidationError implementation suitable for exemplar validation errors.
 type exemplarValidationError struct {
 	message        string
@@ -258,6 +283,12 @@ func NewMaxTotalQueryLengthError(actualQueryLen, maxTotalQueryLength time.Durati
 		maxTotalQueryLengthFlag))
 }
 
+func NewQueryCostBudgetExceededError(estimatedCost uint64, budget int, selector string, selectorCost uint64) LimitError {
+	return LimitError(globalerror.QueryCostBudgetExceeded.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the estimated cost of the query exceeds the limit (estimated fetched series: %d, limit: %d); the most expensive selector is %s, which is estimated to fetch %d series, consider adding more selective matchers to it", estimatedCost, budget, selector, selectorCost),
+		queryCostBudgetFlag))
+}
+
 func NewRequestRateLimitedError(limit float64, burst int) LimitError {
 	return LimitError(globalerror.RequestRateLimited.MessageWithPerTenantLimitConfig(
 		fmt.Sprintf("the request has been rejected because the tenant exceeded the request rate limit, set to %v requests/s across all distributors with a maximum allowed burst of %d", limit, burst),
@@ -270,6 +301,11 @@ func NewIngestionRateLimitedError(limit float64, burst int) LimitError {
 		ingestionRateFlag, ingestionBurstSizeFlag))
 }
 
+func NewAdaptiveIngestionRateLimitedError(limit float64) LimitError {
+	return LimitError(globalerror.AdaptiveIngestionRateLimited.Message(
+		fmt.Sprintf("the request has been rejected because the ingesters are overloaded and the tenant is one of the tenants with the highest ingestion rate, which is temporarily limited to %.0f items/s on this distributor", limit)))
+}
+
 // formatLabelSet formats label adapters as a metric name with labels, while preserving
 // label order, and keeping duplicates. If there are multiple "__name__" labels, only
 // first one is used as metric name, other ones will be included as regular labels.
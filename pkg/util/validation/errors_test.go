@@ -45,3 +45,8 @@ func TestNewIngestionRateLimitedError(t *testing.T) {
 	err := NewIngestionRateLimitedError(10, 5)
 	assert.Equal(t, "the request has been rejected because the tenant exceeded the ingestion rate limit, set to 10 items/s with a maximum allowed burst of 5. This limit is applied on the total number of samples, exemplars and metadata received across all distributors (err-mimir-tenant-max-ingestion-rate). To adjust the related per-tenant limits, configure -distributor.ingestion-rate-limit and -distributor.ingestion-burst-size, or contact your service administrator.", err.Error())
 }
+
+func TestNewAdaptiveIngestionRateLimitedError(t *testing.T) {
+	err := NewAdaptiveIngestionRateLimitedError(1234.4)
+	assert.Equal(t, "the request has been rejected because the ingesters are overloaded and the tenant is one of the tenants with the highest ingestion rate, which is temporarily limited to 1234 items/s on this distributor (err-mimir-tenant-adaptive-ingestion-rate)", err.Error())
+}
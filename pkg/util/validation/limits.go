@@ -15,14 +15,17 @@ import (
 	"time"
 
 	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/tenant"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/promql/parser"
 	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/ephemeral"
 )
 
@@ -40,19 +43,30 @@ const (
 	maxLabelValueLengthFlag       = "validation.max-length-label-value"
 	maxMetadataLengthFlag         = "validation.max-metadata-length"
 	creationGracePeriodFlag       = "validation.create-grace-period"
+	maxNativeHistogramBucketsFlag = "validation.max-native-histogram-buckets"
 	maxQueryLengthFlag            = "store.max-query-length"
 	maxPartialQueryLengthFlag     = "querier.max-partial-query-length"
 	maxTotalQueryLengthFlag       = "query-frontend.max-total-query-length"
+	queryCostBudgetFlag           = "query-frontend.query-cost-budget"
 	requestRateFlag               = "distributor.request-rate-limit"
 	requestBurstSizeFlag          = "distributor.request-burst-size"
 	ingestionRateFlag             = "distributor.ingestion-rate-limit"
 	ingestionBurstSizeFlag        = "distributor.ingestion-burst-size"
 	HATrackerMaxClustersFlag      = "distributor.ha-tracker.max-clusters"
 
+	// QueryCostBudgetActionReject rejects the queries exceeding the query cost budget.
+	QueryCostBudgetActionReject = "reject"
+
+	// QueryCostBudgetActionDeprioritize admits the queries exceeding the query cost budget, but assigns them
+	// the expensive query class, so that the query-scheduler can deprioritize them.
+	QueryCostBudgetActionDeprioritize = "deprioritize"
+
 	// MinCompactorPartialBlockDeletionDelay is the minimum partial blocks deletion delay that can be configured in Mimir.
 	MinCompactorPartialBlockDeletionDelay = 4 * time.Hour
 )
 
+var queryCostBudgetActions = []string{QueryCostBudgetActionReject, QueryCostBudgetActionDeprioritize}
+
 // LimitError are errors that do not comply with the limits specified.
 type LimitError string
 
@@ -63,39 +77,77 @@ func (e LimitError) Error() string {
 type ForwardingRule struct {
 	// Ingest defines whether a metric should still be pushed to the Ingesters despite it being forwarded.
 	Ingest bool `yaml:"ingest" json:"ingest"`
+	// Tenant is the tenant of the same cluster into which the series of the metric get copied, instead of
+	// being forwarded to the forwarding endpoint.
+	Tenant string `yaml:"tenant,omitempty" json:"tenant,omitempty"`
+	// Labels are added to the series copied into the tenant, replacing the labels with the same names.
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
 }
 
 // ForwardingRules are keyed by metric names, excluding labels.
 type ForwardingRules map[string]ForwardingRule
 
+// AggregationRule defines how the distributor aggregates the series of a metric at ingestion.
+type AggregationRule struct {
+	// Without lists the labels removed from the series, the series with the same remaining labels get summed.
+	Without []string `yaml:"without" json:"without"`
+	// Interval is the interval at which the aggregated series get a sample.
+	Interval model.Duration `yaml:"interval" json:"interval"`
+	// Counter defines whether the metric is a counter. The aggregated series of a counter is a counter too,
+	// summing the increases of the series instead of their values.
+	Counter bool `yaml:"counter" json:"counter"`
+	// DropInput defines whether the aggregated series are dropped instead of being ingested.
+	DropInput bool `yaml:"drop_input" json:"drop_input"`
+}
+
+// AggregationRules are keyed by metric names, excluding labels.
+type AggregationRules map[string]AggregationRule
+
+// CompactorRetentionRules are retention periods keyed by series selector.
+type CompactorRetentionRules map[string]model.Duration
+
+// LabelValuesLimits are the maximum numbers of values keyed by label name.
+type LabelValuesLimits map[string]int
+
+// SeriesSelectorLimits are the maximum numbers of series keyed by series selector.
+type SeriesSelectorLimits map[string]int
+
 // Limits describe all the limits for users; can be used to describe global default
 // limits via flags, or per-user limits via yaml config.
 type Limits struct {
 	// Distributor enforced limits.
-	RequestRate               float64             `yaml:"request_rate" json:"request_rate" category:"experimental"`
-	RequestBurstSize          int                 `yaml:"request_burst_size" json:"request_burst_size" category:"experimental"`
-	IngestionRate             float64             `yaml:"ingestion_rate" json:"ingestion_rate"`
-	IngestionBurstSize        int                 `yaml:"ingestion_burst_size" json:"ingestion_burst_size"`
-	AcceptHASamples           bool                `yaml:"accept_ha_samples" json:"accept_ha_samples"`
-	HAClusterLabel            string              `yaml:"ha_cluster_label" json:"ha_cluster_label"`
-	HAReplicaLabel            string              `yaml:"ha_replica_label" json:"ha_replica_label"`
-	HAMaxClusters             int                 `yaml:"ha_max_clusters" json:"ha_max_clusters"`
-	DropLabels                flagext.StringSlice `yaml:"drop_labels" json:"drop_labels" category:"advanced"`
-	MaxLabelNameLength        int                 `yaml:"max_label_name_length" json:"max_label_name_length"`
-	MaxLabelValueLength       int                 `yaml:"max_label_value_length" json:"max_label_value_length"`
-	MaxLabelNamesPerSeries    int                 `yaml:"max_label_names_per_series" json:"max_label_names_per_series"`
-	MaxMetadataLength         int                 `yaml:"max_metadata_length" json:"max_metadata_length"`
-	CreationGracePeriod       model.Duration      `yaml:"creation_grace_period" json:"creation_grace_period" category:"advanced"`
-	EnforceMetadataMetricName bool                `yaml:"enforce_metadata_metric_name" json:"enforce_metadata_metric_name" category:"advanced"`
-	IngestionTenantShardSize  int                 `yaml:"ingestion_tenant_shard_size" json:"ingestion_tenant_shard_size"`
-	MetricRelabelConfigs      []*relabel.Config   `yaml:"metric_relabel_configs,omitempty" json:"metric_relabel_configs,omitempty" doc:"nocli|description=List of metric relabel configurations. Note that in most situations, it is more effective to use metrics relabeling directly in the Prometheus server, e.g. remote_write.write_relabel_configs." category:"experimental"`
+	RequestRate                      float64             `yaml:"request_rate" json:"request_rate" category:"experimental"`
+	RequestBurstSize                 int                 `yaml:"request_burst_size" json:"request_burst_size" category:"experimental"`
+	IngestionRate                    float64             `yaml:"ingestion_rate" json:"ingestion_rate"`
+	IngestionBurstSize               int                 `yaml:"ingestion_burst_size" json:"ingestion_burst_size"`
+	AcceptHASamples                  bool                `yaml:"accept_ha_samples" json:"accept_ha_samples"`
+	HAClusterLabel                   string              `yaml:"ha_cluster_label" json:"ha_cluster_label"`
+	HAReplicaLabel                   string              `yaml:"ha_replica_label" json:"ha_replica_label"`
+	HAMaxClusters                    int                 `yaml:"ha_max_clusters" json:"ha_max_clusters"`
+	HAPerSeriesDedupWindow           model.Duration      `yaml:"ha_per_series_dedup_window" json:"ha_per_series_dedup_window" category:"experimental"`
+	DropLabels                       flagext.StringSlice `yaml:"drop_labels" json:"drop_labels" category:"advanced"`
+	MaxLabelNameLength               int                 `yaml:"max_label_name_length" json:"max_label_name_length"`
+	MaxLabelValueLength              int                 `yaml:"max_label_value_length" json:"max_label_value_length"`
+	MaxLabelNamesPerSeries           int                 `yaml:"max_label_names_per_series" json:"max_label_names_per_series"`
+	MaxMetadataLength                int                 `yaml:"max_metadata_length" json:"max_metadata_length"`
+	CreationGracePeriod              model.Duration      `yaml:"creation_grace_period" json:"creation_grace_period" category:"advanced"`
+	EnforceMetadataMetricName        bool                `yaml:"enforce_metadata_metric_name" json:"enforce_metadata_metric_name" category:"advanced"`
+	IngestionTenantShardSize         int                 `yaml:"ingestion_tenant_shard_size" json:"ingestion_tenant_shard_size"`
+	NativeHistogramsIngestionEnabled bool                `yaml:"native_histograms_ingestion_enabled" json:"native_histograms_ingestion_enabled" category:"experimental"`
+	MaxNativeHistogramBuckets        int                 `yaml:"max_native_histogram_buckets" json:"max_native_histogram_buckets" category:"experimental"`
+	DeadLetterMaxEntries             int                 `yaml:"dead_letter_max_entries" json:"dead_letter_max_entries" category:"experimental"`
+	MetricRelabelConfigs             []*relabel.Config   `yaml:"metric_relabel_configs,omitempty" json:"metric_relabel_configs,omitempty" doc:"nocli|description=List of metric relabel configurations. Note that in most situations, it is more effective to use metrics relabeling directly in the Prometheus server, e.g. remote_write.write_relabel_configs." category:"experimental"`
 
 	// Ingester enforced limits.
 	// Series
 	MaxGlobalSeriesPerUser   int `yaml:"max_global_series_per_user" json:"max_global_series_per_user"`
 	MaxGlobalSeriesPerMetric int `yaml:"max_global_series_per_metric" json:"max_global_series_per_metric"`
+	// Series per label
+	MaxLabelValuesPerLabelName LabelValuesLimits    `yaml:"max_label_values_per_label_name" json:"max_label_values_per_label_name" category:"experimental" doc:"nocli|description=The maximum number of distinct values of the given label names in the in-memory series, keyed by label name. Each ingester rejects the series which would add a new value to a label name which already has this number of values in its in-memory series. The limit isn't divided by the number of ingesters, because the series with the same value can be on any ingester, so the tenant can have more values across the cluster."`
+	MaxGlobalSeriesPerSelector SeriesSelectorLimits `yaml:"max_global_series_per_selector" json:"max_global_series_per_selector" category:"experimental" doc:"nocli|description=The maximum number of in-memory series matching the given selectors, across the cluster before replication, keyed by series selector. The series which would exceed the limit of one of the selectors they match are rejected."`
 	// Ephemeral series
-	MaxEphemeralSeriesPerUser int `yaml:"max_ephemeral_series_per_user" json:"max_ephemeral_series_per_user" category:"experimental"`
+	MaxEphemeralSeriesPerUser      int            `yaml:"max_ephemeral_series_per_user" json:"max_ephemeral_series_per_user" category:"experimental"`
+	EphemeralSeriesRetentionPeriod model.Duration `yaml:"ephemeral_series_retention_period" json:"ephemeral_series_retention_period" category:"experimental"`
 	// Metadata
 	MaxGlobalMetricsWithMetadataPerUser int `yaml:"max_global_metadata_per_user" json:"max_global_metadata_per_user"`
 	MaxGlobalMetadataPerMetric          int `yaml:"max_global_metadata_per_metric" json:"max_global_metadata_per_metric"`
@@ -109,6 +161,10 @@ type Limits struct {
 	// User defined label to give the option of subdividing specific metrics by another label
 	SeparateMetricsGroupLabel string `yaml:"separate_metrics_group_label" json:"separate_metrics_group_label" category:"experimental"`
 
+	// Cost attribution
+	CostAttributionLabel      string `yaml:"cost_attribution_label" json:"cost_attribution_label" category:"experimental"`
+	MaxCostAttributionPerUser int    `yaml:"max_cost_attribution_per_user" json:"max_cost_attribution_per_user" category:"experimental"`
+
 	// Querier enforced limits.
 	MaxChunksPerQuery              int            `yaml:"max_fetched_chunks_per_query" json:"max_fetched_chunks_per_query"`
 	MaxFetchedSeriesPerQuery       int            `yaml:"max_fetched_series_per_query" json:"max_fetched_series_per_query"`
@@ -125,7 +181,9 @@ type Limits struct {
 	SplitInstantQueriesByInterval  model.Duration `yaml:"split_instant_queries_by_interval" json:"split_instant_queries_by_interval" category:"experimental"`
 
 	// Query-frontend limits.
-	MaxTotalQueryLength model.Duration `yaml:"max_total_query_length" json:"max_total_query_length"`
+	MaxTotalQueryLength   model.Duration `yaml:"max_total_query_length" json:"max_total_query_length"`
+	QueryCostBudget       int            `yaml:"query_cost_budget" json:"query_cost_budget" category:"experimental"`
+	QueryCostBudgetAction string         `yaml:"query_cost_budget_action" json:"query_cost_budget_action" category:"experimental"`
 
 	// Cardinality
 	CardinalityAnalysisEnabled                    bool `yaml:"cardinality_analysis_enabled" json:"cardinality_analysis_enabled"`
@@ -144,12 +202,19 @@ type Limits struct {
 	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
 
 	// Compactor.
-	CompactorBlocksRetentionPeriod     model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
-	CompactorSplitAndMergeShards       int            `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
-	CompactorSplitGroups               int            `yaml:"compactor_split_groups" json:"compactor_split_groups"`
-	CompactorTenantShardSize           int            `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
-	CompactorPartialBlockDeletionDelay model.Duration `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
-	CompactorBlockUploadEnabled        bool           `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
+	CompactorBlocksRetentionPeriod     model.Duration          `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
+	CompactorSplitAndMergeShards       int                     `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
+	CompactorSplitGroups               int                     `yaml:"compactor_split_groups" json:"compactor_split_groups"`
+	CompactorTenantShardSize           int                     `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
+	CompactorPartialBlockDeletionDelay model.Duration          `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
+	CompactorBlockUploadEnabled        bool                    `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
+	CompactorSeriesDeletionEnabled     bool                    `yaml:"compactor_series_deletion_enabled" json:"compactor_series_deletion_enabled" category:"experimental"`
+	CompactorBlockRewriteEnabled       bool                    `yaml:"compactor_block_rewrite_enabled" json:"compactor_block_rewrite_enabled" category:"experimental"`
+	CompactorDownsampling5mAfter       model.Duration          `yaml:"compactor_downsampling_5m_after" json:"compactor_downsampling_5m_after" category:"experimental"`
+	CompactorDownsampling1hAfter       model.Duration          `yaml:"compactor_downsampling_1h_after" json:"compactor_downsampling_1h_after" category:"experimental"`
+	CompactorBlocksRetentionPeriod5m   model.Duration          `yaml:"compactor_blocks_retention_period_5m" json:"compactor_blocks_retention_period_5m" category:"experimental"`
+	CompactorBlocksRetentionPeriod1h   model.Duration          `yaml:"compactor_blocks_retention_period_1h" json:"compactor_blocks_retention_period_1h" category:"experimental"`
+	CompactorRetentionRules            CompactorRetentionRules `yaml:"compactor_retention_rules" json:"compactor_retention_rules" category:"experimental" doc:"nocli|description=Retention periods of the series matching the given selectors, keyed by series selector. Once all the samples of a block are older than the retention period of a rule, the compactor rewrites the block without the series matching the rule's selector. If a series matches multiple rules, the shortest retention period applies. Retention periods longer than -compactor.blocks-retention-period have no effect."`
 
 	// This config doesn't have a CLI flag registered here because they're registered in
 	// their own original config struct.
@@ -173,7 +238,10 @@ type Limits struct {
 
 	ForwardingEndpoint      string          `yaml:"forwarding_endpoint" json:"forwarding_endpoint" doc:"nocli|description=Remote-write endpoint where metrics specified in forwarding_rules are forwarded to. If set, takes precedence over endpoints specified in forwarding rules."`
 	ForwardingDropOlderThan model.Duration  `yaml:"forwarding_drop_older_than" json:"forwarding_drop_older_than" doc:"nocli|description=If set, forwarding drops samples that are older than this duration. If unset or 0, no samples get dropped."`
-	ForwardingRules         ForwardingRules `yaml:"forwarding_rules" json:"forwarding_rules" doc:"nocli|description=Rules based on which the Distributor decides whether a metric should be forwarded to an alternative remote_write API endpoint."`
+	ForwardingRules         ForwardingRules `yaml:"forwarding_rules" json:"forwarding_rules" doc:"nocli|description=Rules based on which the Distributor decides whether a metric should be forwarded to an alternative remote_write API endpoint, or copied into another tenant of the cluster."`
+
+	AggregationRules           AggregationRules `yaml:"aggregation_rules" json:"aggregation_rules" category:"experimental" doc:"nocli|description=Rules based on which the distributor aggregates the series of a metric, keyed by metric name. Each rule sums the series of the metric without the labels listed in 'without', and emits the sum every 'interval' as the metric '<metric>:sum_without_<labels>', with the 'aggregator' label set to the distributor instance ID. If 'counter' is true, the sum is a counter of the increases of the series. If 'drop_input' is true, the aggregated series are not ingested. The series of each metric are routed to, and aggregated by, a single distributor, chosen among the healthy distributors of the ring."`
+	MaxAggregatedSeriesPerUser int              `yaml:"max_aggregated_series_per_user" json:"max_aggregated_series_per_user" category:"experimental"`
 
 	EphemeralSeriesMatchers ephemeral.LabelMatchers `yaml:"ephemeral_series_matchers" json:"ephemeral_series_matchers" category:"experimental"`
 }
@@ -189,6 +257,8 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&l.HAClusterLabel, "distributor.ha-tracker.cluster", "cluster", "Prometheus label to look for in samples to identify a Prometheus HA cluster.")
 	f.StringVar(&l.HAReplicaLabel, "distributor.ha-tracker.replica", "__replica__", "Prometheus label to look for in samples to identify a Prometheus HA replica.")
 	f.IntVar(&l.HAMaxClusters, HATrackerMaxClustersFlag, 100, "Maximum number of clusters that HA tracker will keep track of for a single tenant. 0 to disable the limit.")
+	f.Var(&l.HAPerSeriesDedupWindow, "distributor.ha-tracker.per-series-dedup-window", "If greater than 0, the HA tracker deduplicates the samples per series instead of per cluster: the samples received from a non-elected replica are accepted for the series for which the elected replica has not sent any sample within this window, filling the gaps of the elected replica. The samples received from the elected replica are tracked in the memory of the distributor which deduplicates them, so all the push requests of the tenant are routed to, and handled by, a single distributor chosen among the healthy distributors of the ring, which enforces the whole ingestion rate limit of the tenant. 0 to disable.")
+	f.IntVar(&l.MaxAggregatedSeriesPerUser, "distributor.max-aggregated-series-per-user", 10000, "Maximum number of aggregated series created by the aggregation rules of a tenant. Once reached, the series which would create a new aggregated series are not aggregated, and they are ingested even if their aggregation rule drops the input series. 0 to disable the limit.")
 	f.Var(&l.DropLabels, "distributor.drop-label", "This flag can be used to specify label names that to drop during sample ingestion within the distributor and can be repeated in order to drop multiple labels.")
 	f.IntVar(&l.MaxLabelNameLength, maxLabelNameLengthFlag, 1024, "Maximum length accepted for label names")
 	f.IntVar(&l.MaxLabelValueLength, maxLabelValueLengthFlag, 2048, "Maximum length accepted for label value. This setting also applies to the metric name")
@@ -197,10 +267,14 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	_ = l.CreationGracePeriod.Set("10m")
 	f.Var(&l.CreationGracePeriod, creationGracePeriodFlag, "Controls how far into the future incoming samples are accepted compared to the wall clock. Any sample with timestamp `t` will be rejected if `t > (now + validation.create-grace-period)`. Also used by query-frontend to avoid querying too far into the future. 0 to disable.")
 	f.BoolVar(&l.EnforceMetadataMetricName, "validation.enforce-metadata-metric-name", true, "Enforce every metadata has a metric name.")
+	f.BoolVar(&l.NativeHistogramsIngestionEnabled, "distributor.native-histograms-ingestion-enabled", false, "Enable the ingestion of native histograms. If disabled, the native histograms of the received series are discarded.")
+	f.IntVar(&l.MaxNativeHistogramBuckets, maxNativeHistogramBucketsFlag, 0, "Maximum number of buckets of a native histogram sample. Series with native histogram samples exceeding the limit are rejected. 0 to disable.")
+	f.IntVar(&l.DeadLetterMaxEntries, "distributor.dead-letter-max-entries", 0, "Maximum number of series rejected at ingestion that each distributor keeps in memory for the tenant, to be listed by the dead-letter API. The oldest ones are replaced by the newest ones. 0 to disable.")
 
 	f.IntVar(&l.MaxGlobalSeriesPerUser, MaxSeriesPerUserFlag, 150000, "The maximum number of in-memory series per tenant, across the cluster before replication. 0 to disable.")
 	f.IntVar(&l.MaxGlobalSeriesPerMetric, MaxSeriesPerMetricFlag, 0, "The maximum number of in-memory series per metric name, across the cluster before replication. 0 to disable.")
 	f.IntVar(&l.MaxEphemeralSeriesPerUser, MaxEphemeralSeriesPerUserFlag, 0, "The maximum number of in-memory ephemeral series per tenant, across the cluster before replication. 0 to disable ephemeral storage.")
+	f.Var(&l.EphemeralSeriesRetentionPeriod, "ingester.ephemeral-series-retention-period", "Retention of the ephemeral series of the tenant. 0 to use the retention configured by -blocks-storage.ephemeral-tsdb.retention-period.")
 
 	f.IntVar(&l.MaxGlobalMetricsWithMetadataPerUser, MaxMetadataPerUserFlag, 0, "The maximum number of in-memory metrics with metadata per tenant, across the cluster. 0 to disable.")
 	f.IntVar(&l.MaxGlobalMetadataPerMetric, MaxMetadataPerMetricFlag, 0, "The maximum number of metadata per metric, across the cluster. 0 to disable.")
@@ -209,6 +283,8 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.OutOfOrderTimeWindow, "ingester.out-of-order-time-window", "Non-zero value enables out-of-order support for most recent samples that are within the time window in relation to the TSDB's maximum time, i.e., within [db.maxTime-timeWindow, db.maxTime]). The ingester will need more memory as a factor of rate of out-of-order samples being ingested and the number of series that are getting out-of-order samples. A lower TTL of 10 minutes will be set for the query cache entries that overlap with this window.")
 
 	f.StringVar(&l.SeparateMetricsGroupLabel, "validation.separate-metrics-group-label", "", "Label used to define the group label for metrics separation. For each write request, the group is obtained from the first non-empty group label from the first timeseries in the incoming list of timeseries. Specific distributor and ingester metrics will be further separated adding a 'group' label with group label's value. Currently applies to the following metrics: cortex_discarded_samples_total")
+	f.StringVar(&l.CostAttributionLabel, "validation.cost-attribution-label", "", "Label used to attribute the active series and the ingested samples of the tenant, for example to the teams sharing it. The ingesters expose the counts per value of the label in the cortex_ingester_attributed_active_series and cortex_ingester_attributed_samples_total metrics. The series without the label are attributed to the __missing__ value. Empty to disable.")
+	f.IntVar(&l.MaxCostAttributionPerUser, "validation.max-cost-attribution-per-user", 100, "The maximum number of values of the cost attribution label tracked per tenant by each ingester. The series with other values are attributed to the __overflow__ value. The __missing__ value of the series without the label isn't counted.")
 
 	f.IntVar(&l.MaxChunksPerQuery, MaxChunksPerQueryFlag, 2e6, "Maximum number of chunks that can be fetched in a single query from ingesters and long-term storage. This limit is enforced in the querier, ruler and store-gateway. 0 to disable.")
 	f.IntVar(&l.MaxFetchedSeriesPerQuery, MaxSeriesPerQueryFlag, 0, "The maximum number of unique series for which a query can fetch samples from each ingesters and storage. This limit is enforced in the querier, ruler and store-gateway. 0 to disable")
@@ -216,6 +292,8 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	// TODO: Deprecated in Mimir 2.6, remove in Mimir 2.8
 	f.Var(&l.MaxQueryLength, maxQueryLengthFlag, fmt.Sprintf("Deprecated: Limit the query time range (end - start time). This limit is enforced in the querier (on the query possibly split by the query-frontend) and ruler. 0 to disable. This option is deprecated, use -%s or -%s instead.", maxPartialQueryLengthFlag, maxTotalQueryLengthFlag))
 	f.Var(&l.MaxPartialQueryLength, maxPartialQueryLengthFlag, fmt.Sprintf("Limit the time range for partial queries at the querier level. Defaults to the value of -%s if set to 0.", maxQueryLengthFlag))
+	f.IntVar(&l.QueryCostBudget, queryCostBudgetFlag, 0, "Maximum estimated cost of a query, expressed as the number of series the query is estimated to fetch from ingesters and store-gateways. The estimate is based on the cardinality of the query selectors in the ingesters and on the blocks stats in the bucket index. Requires -querier.cardinality-analysis-enabled. 0 to disable.")
+	f.StringVar(&l.QueryCostBudgetAction, "query-frontend.query-cost-budget-action", QueryCostBudgetActionReject, fmt.Sprintf("Action taken on the queries whose estimated cost exceeds -%s. Supported values: %s. The %s action assigns the expensive query class to the queries, which the query-scheduler only limits if the class is listed in -query-scheduler.capped-query-classes and -query-scheduler.max-inflight-capped-requests-per-tenant is set.", queryCostBudgetFlag, strings.Join(queryCostBudgetActions, ", "), QueryCostBudgetActionDeprioritize))
 	f.Var(&l.MaxQueryLookback, "querier.max-query-lookback", "Limit how long back data (series and metadata) can be queried, up until <lookback> duration ago. This limit is enforced in the query-frontend, querier and ruler. If the requested time range is outside the allowed range, the request will not fail but will be manipulated to only query data within the allowed time range. 0 to disable.")
 	f.IntVar(&l.MaxQueryParallelism, "querier.max-query-parallelism", 14, "Maximum number of split (by time) or partial (by shard) queries that will be scheduled in parallel by the query-frontend for a single input query. This limit is introduced to have a fairer query scheduling and avoid a single query over a large time range saturating all available queriers.")
 	f.Var(&l.MaxLabelsQueryLength, "store.max-labels-query-length", "Limit the time range (end - start time) of series, label names and values queries. This limit is enforced in the querier. If the requested time range is outside the allowed range, the request will not fail but will be manipulated to only query data within the allowed time range. 0 to disable.")
@@ -242,6 +320,12 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.CompactorTenantShardSize, "compactor.compactor-tenant-shard-size", 0, "Max number of compactors that can compact blocks for single tenant. 0 to disable the limit and use all compactors.")
 	f.Var(&l.CompactorPartialBlockDeletionDelay, "compactor.partial-block-deletion-delay", fmt.Sprintf("If a partial block (unfinished block without %s file) hasn't been modified for this time, it will be marked for deletion. The minimum accepted value is %s: a lower value will be ignored and the feature disabled. 0 to disable.", block.MetaFilename, MinCompactorPartialBlockDeletionDelay.String()))
 	f.BoolVar(&l.CompactorBlockUploadEnabled, "compactor.block-upload-enabled", false, "Enable block upload API for the tenant.")
+	f.BoolVar(&l.CompactorSeriesDeletionEnabled, "compactor.series-deletion-enabled", false, "Enable the series deletion API for the tenant. Series matching a pending deletion request are filtered out at query time, and removed from the blocks by the compactor once the request can't be cancelled anymore.")
+	f.BoolVar(&l.CompactorBlockRewriteEnabled, "compactor.block-rewrite-enabled", false, "Enable the block rewrite API for the tenant. Block rewrite jobs apply relabel configs to the series of the existing blocks.")
+	f.Var(&l.CompactorDownsampling5mAfter, "compactor.downsampling-5m-after", "Downsample raw blocks to 5m resolution once all their samples are older than this period. 0 to disable downsampling.")
+	f.Var(&l.CompactorDownsampling1hAfter, "compactor.downsampling-1h-after", "Downsample 5m resolution blocks to 1h resolution once all their samples are older than this period. Requires -compactor.downsampling-5m-after to be enabled. 0 to disable.")
+	f.Var(&l.CompactorBlocksRetentionPeriod5m, "compactor.blocks-retention-period-5m", "Delete 5m resolution blocks containing samples older than the specified retention period. 0 to use -compactor.blocks-retention-period.")
+	f.Var(&l.CompactorBlocksRetentionPeriod1h, "compactor.blocks-retention-period-1h", "Delete 1h resolution blocks containing samples older than the specified retention period. 0 to use -compactor.blocks-retention-period.")
 
 	// Query-frontend.
 	f.Var(&l.MaxTotalQueryLength, maxTotalQueryLengthFlag, fmt.Sprintf("Limit the total query time range (end - start time). This limit is enforced in the query-frontend on the received query. Defaults to the value of -%s if set to 0.", maxQueryLengthFlag))
@@ -321,6 +405,71 @@ func (l *Limits) validate() error {
 		}
 	}
 
+	if l.QueryCostBudgetAction != "" && !util.StringsContain(queryCostBudgetActions, l.QueryCostBudgetAction) {
+		return fmt.Errorf("unsupported query_cost_budget_action %q, supported values: %s", l.QueryCostBudgetAction, strings.Join(queryCostBudgetActions, ", "))
+	}
+
+	// The query cost is estimated from the cardinality API, so without it the budget would never be enforced.
+	if l.QueryCostBudget > 0 && !l.CardinalityAnalysisEnabled {
+		return fmt.Errorf("query_cost_budget requires cardinality_analysis_enabled")
+	}
+
+	for metric, rule := range l.ForwardingRules {
+		if rule.Tenant != "" {
+			if err := tenant.ValidTenantID(rule.Tenant); err != nil {
+				return fmt.Errorf("invalid tenant %q for metric %q in forwarding_rules: %w", rule.Tenant, metric, err)
+			}
+		} else if len(rule.Labels) > 0 {
+			return fmt.Errorf("invalid labels for metric %q in forwarding_rules: labels can only be added to the series copied into a tenant", metric)
+		}
+		for name := range rule.Labels {
+			if name == model.MetricNameLabel || !model.LabelName(name).IsValid() {
+				return fmt.Errorf("invalid label %q in the labels of metric %q in forwarding_rules", name, metric)
+			}
+		}
+	}
+
+	for metric, rule := range l.AggregationRules {
+		if !model.IsValidMetricName(model.LabelValue(metric)) {
+			return fmt.Errorf("invalid metric name %q in aggregation_rules", metric)
+		}
+		if rule.Interval <= 0 {
+			return fmt.Errorf("invalid interval for metric %q in aggregation_rules: must be greater than 0", metric)
+		}
+		for _, name := range rule.Without {
+			if name == model.MetricNameLabel || !model.LabelName(name).IsValid() {
+				return fmt.Errorf("invalid label %q in the without labels of metric %q in aggregation_rules", name, metric)
+			}
+		}
+	}
+
+	for name, limit := range l.MaxLabelValuesPerLabelName {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid label name %q in max_label_values_per_label_name", name)
+		}
+		if limit < 0 {
+			return fmt.Errorf("invalid limit for label name %q in max_label_values_per_label_name: must not be negative", name)
+		}
+	}
+
+	for selector, limit := range l.MaxGlobalSeriesPerSelector {
+		if _, err := parser.ParseMetricSelector(selector); err != nil {
+			return fmt.Errorf("invalid selector %q in max_global_series_per_selector: %w", selector, err)
+		}
+		if limit < 0 {
+			return fmt.Errorf("invalid limit for selector %q in max_global_series_per_selector: must not be negative", selector)
+		}
+	}
+
+	for selector, period := range l.CompactorRetentionRules {
+		if _, err := parser.ParseMetricSelector(selector); err != nil {
+			return fmt.Errorf("invalid selector %q in compactor_retention_rules: %w", selector, err)
+		}
+		if period <= 0 {
+			return fmt.Errorf("invalid retention period for selector %q in compactor_retention_rules: must be greater than 0", selector)
+		}
+	}
+
 	return nil
 }
 
@@ -450,6 +599,21 @@ func (o *Overrides) CreationGracePeriod(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).CreationGracePeriod)
 }
 
+// NativeHistogramsIngestionEnabled returns whether the ingestion of native histograms is enabled for the user.
+func (o *Overrides) NativeHistogramsIngestionEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).NativeHistogramsIngestionEnabled
+}
+
+// MaxNativeHistogramBuckets returns the maximum number of buckets of a native histogram sample.
+func (o *Overrides) MaxNativeHistogramBuckets(userID string) int {
+	return o.getOverridesForUser(userID).MaxNativeHistogramBuckets
+}
+
+// DeadLetterMaxEntries returns the maximum number of series rejected at ingestion the distributor keeps for the user.
+func (o *Overrides) DeadLetterMaxEntries(userID string) int {
+	return o.getOverridesForUser(userID).DeadLetterMaxEntries
+}
+
 // MaxGlobalSeriesPerUser returns the maximum number of series a user is allowed to store across the cluster.
 func (o *Overrides) MaxGlobalSeriesPerUser(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalSeriesPerUser
@@ -460,11 +624,29 @@ func (o *Overrides) MaxGlobalSeriesPerMetric(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalSeriesPerMetric
 }
 
+// MaxLabelValuesPerLabelName returns the maximum number of distinct values of the label names in the
+// in-memory series of a user in each ingester, keyed by label name.
+func (o *Overrides) MaxLabelValuesPerLabelName(userID string) map[string]int {
+	return o.getOverridesForUser(userID).MaxLabelValuesPerLabelName
+}
+
+// MaxGlobalSeriesPerSelector returns the maximum number of in-memory series matching the selectors a user
+// is allowed to store across the cluster, keyed by series selector.
+func (o *Overrides) MaxGlobalSeriesPerSelector(userID string) map[string]int {
+	return o.getOverridesForUser(userID).MaxGlobalSeriesPerSelector
+}
+
 // MaxEphemeralSeriesPerUser returns the maximum number of ephemeral series a user is allowed to store across the cluster.
 func (o *Overrides) MaxEphemeralSeriesPerUser(userID string) int {
 	return o.getOverridesForUser(userID).MaxEphemeralSeriesPerUser
 }
 
+// EphemeralSeriesRetentionPeriod returns the retention of the ephemeral series of the user.
+// 0 means the retention of the ephemeral storage configured for the ingesters.
+func (o *Overrides) EphemeralSeriesRetentionPeriod(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).EphemeralSeriesRetentionPeriod)
+}
+
 func (o *Overrides) MaxChunksPerQuery(userID string) int {
 	return o.getOverridesForUser(userID).MaxChunksPerQuery
 }
@@ -509,6 +691,16 @@ func (o *Overrides) MaxTotalQueryLength(userID string) time.Duration {
 	return t
 }
 
+// QueryCostBudget returns the maximum estimated cost of a query, expressed as number of fetched series.
+func (o *Overrides) QueryCostBudget(userID string) int {
+	return o.getOverridesForUser(userID).QueryCostBudget
+}
+
+// QueryCostBudgetAction returns the action taken on the queries exceeding the query cost budget.
+func (o *Overrides) QueryCostBudgetAction(userID string) string {
+	return o.getOverridesForUser(userID).QueryCostBudgetAction
+}
+
 // MaxLabelsQueryLength returns the limit of the length (in time) of a label names or values request.
 func (o *Overrides) MaxLabelsQueryLength(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxLabelsQueryLength)
@@ -583,6 +775,16 @@ func (o *Overrides) SeparateMetricsGroupLabel(userID string) string {
 	return o.getOverridesForUser(userID).SeparateMetricsGroupLabel
 }
 
+// CostAttributionLabel returns the label used to attribute the active series and ingested samples of a user.
+func (o *Overrides) CostAttributionLabel(userID string) string {
+	return o.getOverridesForUser(userID).CostAttributionLabel
+}
+
+// MaxCostAttributionPerUser returns the maximum number of values of the cost attribution label tracked for a user.
+func (o *Overrides) MaxCostAttributionPerUser(userID string) int {
+	return o.getOverridesForUser(userID).MaxCostAttributionPerUser
+}
+
 // IngestionTenantShardSize returns the ingesters shard size for a given user.
 func (o *Overrides) IngestionTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).IngestionTenantShardSize
@@ -632,6 +834,95 @@ func (o *Overrides) CompactorBlockUploadEnabled(tenantID string) bool {
 	return o.getOverridesForUser(tenantID).CompactorBlockUploadEnabled
 }
 
+// CompactorSeriesDeletionEnabled returns whether series deletion is enabled for a certain tenant.
+func (o *Overrides) CompactorSeriesDeletionEnabled(tenantID string) bool {
+	return o.getOverridesForUser(tenantID).CompactorSeriesDeletionEnabled
+}
+
+// CompactorBlockRewriteEnabled returns whether block rewrite is enabled for a certain tenant.
+func (o *Overrides) CompactorBlockRewriteEnabled(tenantID string) bool {
+	return o.getOverridesForUser(tenantID).CompactorBlockRewriteEnabled
+}
+
+// CompactorDownsampling5mAfter returns the age after which raw blocks are downsampled to 5m resolution
+// for a given user. 0 means downsampling is disabled.
+func (o *Overrides) CompactorDownsampling5mAfter(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).CompactorDownsampling5mAfter)
+}
+
+// CompactorDownsampling1hAfter returns the age after which 5m resolution blocks are downsampled to 1h resolution
+// for a given user. 0 means downsampling to 1h resolution is disabled.
+func (o *Overrides) CompactorDownsampling1hAfter(userID string) time.Duration {
+	if o.CompactorDownsampling5mAfter(userID) <= 0 {
+		return 0
+	}
+	return time.Duration(o.getOverridesForUser(userID).CompactorDownsampling1hAfter)
+}
+
+// CompactorBlocksRetentionPeriodForResolution returns the retention period of the blocks with the given
+// resolution for a given user.
+func (o *Overrides) CompactorBlocksRetentionPeriodForResolution(userID string, resolution int64) time.Duration {
+	var retention model.Duration
+	switch resolution {
+	case block.Resolution5m:
+		retention = o.getOverridesForUser(userID).CompactorBlocksRetentionPeriod5m
+	case block.Resolution1h:
+		retention = o.getOverridesForUser(userID).CompactorBlocksRetentionPeriod1h
+	}
+
+	if retention == 0 {
+		return o.CompactorBlocksRetentionPeriod(userID)
+	}
+	return time.Duration(retention)
+}
+
+// CompactorRetentionRules returns the retention periods of the series matching the given selectors,
+// keyed by series selector.
+func (o *Overrides) CompactorRetentionRules(userID string) map[string]time.Duration {
+	rules := o.getOverridesForUser(userID).CompactorRetentionRules
+	if len(rules) == 0 {
+		return nil
+	}
+
+	periods := make(map[string]time.Duration, len(rules))
+	for selector, period := range rules {
+		periods[selector] = time.Duration(period)
+	}
+	return periods
+}
+
+// CompactorBlocksMaxRetentionPeriod returns the longest retention period of the blocks of any resolution
+// for a given user. 0 means that blocks of at least one resolution are never deleted.
+func (o *Overrides) CompactorBlocksMaxRetentionPeriod(userID string) time.Duration {
+	maxRetention := o.CompactorBlocksRetentionPeriod(userID)
+	if maxRetention <= 0 {
+		return 0
+	}
+
+	resolutions := []struct {
+		resolution int64
+		enabled    bool
+	}{
+		{resolution: block.Resolution5m, enabled: o.CompactorDownsampling5mAfter(userID) > 0},
+		{resolution: block.Resolution1h, enabled: o.CompactorDownsampling1hAfter(userID) > 0},
+	}
+	for _, r := range resolutions {
+		if !r.enabled {
+			continue
+		}
+
+		retention := o.CompactorBlocksRetentionPeriodForResolution(userID, r.resolution)
+		if retention <= 0 {
+			return 0
+		}
+		if retention > maxRetention {
+			maxRetention = retention
+		}
+	}
+
+	return maxRetention
+}
+
 // MetricRelabelConfigs returns the metric relabel configs for a given user.
 func (o *Overrides) MetricRelabelConfigs(userID string) []*relabel.Config {
 	return o.getOverridesForUser(userID).MetricRelabelConfigs
@@ -672,6 +963,12 @@ func (o *Overrides) MaxHAClusters(user string) int {
 	return o.getOverridesForUser(user).HAMaxClusters
 }
 
+// HAPerSeriesDedupWindow returns the window within which the samples of the elected replica of a HA cluster
+// take precedence over the samples of the non-elected replicas, per series. 0 if the per-series deduplication is disabled.
+func (o *Overrides) HAPerSeriesDedupWindow(user string) time.Duration {
+	return time.Duration(o.getOverridesForUser(user).HAPerSeriesDedupWindow)
+}
+
 // S3SSEType returns the per-tenant S3 SSE type.
 func (o *Overrides) S3SSEType(user string) string {
 	return o.getOverridesForUser(user).S3SSEType
@@ -792,6 +1089,16 @@ func (o *Overrides) ForwardingDropOlderThan(user string) time.Duration {
 	return time.Duration(o.getOverridesForUser(user).ForwardingDropOlderThan)
 }
 
+// AggregationRules returns the rules based on which the distributor aggregates the series of the user.
+func (o *Overrides) AggregationRules(user string) AggregationRules {
+	return o.getOverridesForUser(user).AggregationRules
+}
+
+// MaxAggregatedSeriesPerUser returns the maximum number of aggregated series created by the aggregation rules of the user.
+func (o *Overrides) MaxAggregatedSeriesPerUser(user string) int {
+	return o.getOverridesForUser(user).MaxAggregatedSeriesPerUser
+}
+
 func (o *Overrides) getOverridesForUser(userID string) *Limits {
 	if o.tenantLimits != nil {
 		l := o.tenantLimits.ByUserID(userID)
@@ -21,6 +21,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 )
 
 func TestOverridesManager_GetOverrides(t *testing.T) {
@@ -406,6 +407,238 @@ func TestMaxPartialQueryLengthWithoutDefault(t *testing.T) {
 	assert.Equal(t, 2*time.Hour, ov.MaxPartialQueryLength("tenant-c"))
 }
 
+func TestCompactorBlocksRetentionPeriodWithDownsampling(t *testing.T) {
+	tenantLimits := map[string]*Limits{
+		"no-downsampling": {
+			CompactorBlocksRetentionPeriod:   model.Duration(24 * time.Hour),
+			CompactorBlocksRetentionPeriod1h: model.Duration(72 * time.Hour),
+		},
+		"5m-only": {
+			CompactorBlocksRetentionPeriod:   model.Duration(24 * time.Hour),
+			CompactorDownsampling5mAfter:     model.Duration(10 * time.Hour),
+			CompactorBlocksRetentionPeriod5m: model.Duration(48 * time.Hour),
+			CompactorBlocksRetentionPeriod1h: model.Duration(72 * time.Hour),
+		},
+		"5m-and-1h": {
+			CompactorBlocksRetentionPeriod:   model.Duration(24 * time.Hour),
+			CompactorDownsampling5mAfter:     model.Duration(10 * time.Hour),
+			CompactorDownsampling1hAfter:     model.Duration(20 * time.Hour),
+			CompactorBlocksRetentionPeriod5m: model.Duration(48 * time.Hour),
+			CompactorBlocksRetentionPeriod1h: model.Duration(72 * time.Hour),
+		},
+		"default-retention": {
+			CompactorBlocksRetentionPeriod: model.Duration(24 * time.Hour),
+			CompactorDownsampling5mAfter:   model.Duration(10 * time.Hour),
+			CompactorDownsampling1hAfter:   model.Duration(20 * time.Hour),
+		},
+	}
+	ov, err := NewOverrides(Limits{}, NewMockTenantLimits(tenantLimits))
+	require.NoError(t, err)
+
+	assert.Equal(t, 72*time.Hour, ov.CompactorBlocksRetentionPeriodForResolution("no-downsampling", block.Resolution1h))
+	assert.Equal(t, 24*time.Hour, ov.CompactorBlocksRetentionPeriodForResolution("no-downsampling", block.Resolution5m))
+	assert.Equal(t, 24*time.Hour, ov.CompactorBlocksRetentionPeriodForResolution("no-downsampling", block.ResolutionRaw))
+	assert.Equal(t, 24*time.Hour, ov.CompactorBlocksMaxRetentionPeriod("no-downsampling"))
+
+	assert.Equal(t, 48*time.Hour, ov.CompactorBlocksMaxRetentionPeriod("5m-only"))
+	assert.Equal(t, 72*time.Hour, ov.CompactorBlocksMaxRetentionPeriod("5m-and-1h"))
+	assert.Equal(t, time.Duration(0), ov.CompactorBlocksMaxRetentionPeriod("unknown"))
+
+	// Blocks without a specific retention period fall back to the default one.
+	assert.Equal(t, 24*time.Hour, ov.CompactorBlocksRetentionPeriodForResolution("default-retention", block.Resolution1h))
+	assert.Equal(t, 24*time.Hour, ov.CompactorBlocksMaxRetentionPeriod("default-retention"))
+}
+
+func TestCompactorRetentionRules(t *testing.T) {
+	t.Run("valid rules", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `
+compactor_retention_rules:
+  '{env="dev"}': 7d
+  '{__name__=~"debug_.+"}': 1d
+`
+		require.NoError(t, yaml.Unmarshal([]byte(cfg), &limits))
+
+		ov, err := NewOverrides(limits, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]time.Duration{
+			`{env="dev"}`:            7 * 24 * time.Hour,
+			`{__name__=~"debug_.+"}`: 24 * time.Hour,
+		}, ov.CompactorRetentionRules("user"))
+	})
+
+	t.Run("no rules", func(t *testing.T) {
+		ov, err := NewOverrides(Limits{}, nil)
+		require.NoError(t, err)
+		assert.Nil(t, ov.CompactorRetentionRules("user"))
+	})
+
+	t.Run("invalid selector", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `{"compactor_retention_rules": {"{env=": "7d"}}`
+		require.ErrorContains(t, json.Unmarshal([]byte(cfg), &limits), "invalid selector")
+	})
+
+	t.Run("invalid period", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `
+compactor_retention_rules:
+  '{env="dev"}': 0s
+`
+		require.ErrorContains(t, yaml.Unmarshal([]byte(cfg), &limits), "invalid retention period")
+	})
+}
+
+func TestLabelLimits(t *testing.T) {
+	t.Run("valid limits", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `
+max_label_values_per_label_name:
+  request_id: 1000
+max_global_series_per_selector:
+  '{namespace="a"}': 100000
+`
+		require.NoError(t, yaml.Unmarshal([]byte(cfg), &limits))
+
+		ov, err := NewOverrides(limits, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"request_id": 1000}, ov.MaxLabelValuesPerLabelName("user"))
+		assert.Equal(t, map[string]int{`{namespace="a"}`: 100000}, ov.MaxGlobalSeriesPerSelector("user"))
+	})
+
+	t.Run("invalid label name", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `{"max_label_values_per_label_name": {"request-id": 1000}}`
+		require.ErrorContains(t, json.Unmarshal([]byte(cfg), &limits), "invalid label name")
+	})
+
+	t.Run("invalid selector", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `{"max_global_series_per_selector": {"{namespace=": 1000}}`
+		require.ErrorContains(t, json.Unmarshal([]byte(cfg), &limits), "invalid selector")
+	})
+
+	t.Run("negative limit", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `
+max_global_series_per_selector:
+  '{namespace="a"}': -1
+`
+		require.ErrorContains(t, yaml.Unmarshal([]byte(cfg), &limits), "must not be negative")
+	})
+}
+
+func TestForwardingRules_Tenant(t *testing.T) {
+	t.Run("valid rules", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `
+forwarding_rules:
+  slo_errors_total:
+    ingest: true
+    tenant: shared
+    labels:
+      team: payments
+`
+		require.NoError(t, yaml.Unmarshal([]byte(cfg), &limits))
+
+		ov, err := NewOverrides(limits, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ForwardingRules{
+			"slo_errors_total": {Ingest: true, Tenant: "shared", Labels: map[string]string{"team": "payments"}},
+		}, ov.ForwardingRules("user"))
+	})
+
+	for name, tc := range map[string]struct {
+		cfg         string
+		expectedErr string
+	}{
+		"invalid tenant": {
+			cfg:         `{"forwarding_rules": {"metric": {"tenant": "a/b"}}}`,
+			expectedErr: "invalid tenant",
+		},
+		"labels without tenant": {
+			cfg:         `{"forwarding_rules": {"metric": {"labels": {"team": "payments"}}}}`,
+			expectedErr: "invalid labels",
+		},
+		"metric name label": {
+			cfg:         `{"forwarding_rules": {"metric": {"tenant": "shared", "labels": {"__name__": "other"}}}}`,
+			expectedErr: "invalid label",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			limits := Limits{}
+			require.ErrorContains(t, json.Unmarshal([]byte(tc.cfg), &limits), tc.expectedErr)
+		})
+	}
+}
+
+func TestAggregationRules(t *testing.T) {
+	t.Run("valid rules", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `
+aggregation_rules:
+  http_requests_total:
+    without: [pod, instance]
+    interval: 1m
+    counter: true
+    drop_input: true
+`
+		require.NoError(t, yaml.Unmarshal([]byte(cfg), &limits))
+
+		ov, err := NewOverrides(limits, nil)
+		require.NoError(t, err)
+		assert.Equal(t, AggregationRules{
+			"http_requests_total": {Without: []string{"pod", "instance"}, Interval: model.Duration(time.Minute), Counter: true, DropInput: true},
+		}, ov.AggregationRules("user"))
+	})
+
+	for name, tc := range map[string]struct {
+		cfg         string
+		expectedErr string
+	}{
+		"invalid metric name": {
+			cfg:         `{"aggregation_rules": {"1metric": {"interval": "1m"}}}`,
+			expectedErr: "invalid metric name",
+		},
+		"missing interval": {
+			cfg:         `{"aggregation_rules": {"metric": {"without": ["pod"]}}}`,
+			expectedErr: "invalid interval",
+		},
+		"metric name label": {
+			cfg:         `{"aggregation_rules": {"metric": {"without": ["__name__"], "interval": "1m"}}}`,
+			expectedErr: "invalid label",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			limits := Limits{}
+			require.ErrorContains(t, json.Unmarshal([]byte(tc.cfg), &limits), tc.expectedErr)
+		})
+	}
+}
+
+func TestQueryCostBudgetAction(t *testing.T) {
+	for _, action := range []string{QueryCostBudgetActionReject, QueryCostBudgetActionDeprioritize} {
+		limits := Limits{}
+		require.NoError(t, yaml.Unmarshal([]byte("query_cost_budget_action: "+action), &limits))
+
+		ov, err := NewOverrides(limits, nil)
+		require.NoError(t, err)
+		assert.Equal(t, action, ov.QueryCostBudgetAction("user"))
+	}
+
+	limits := Limits{}
+	require.ErrorContains(t, yaml.Unmarshal([]byte("query_cost_budget_action: drop"), &limits), "unsupported query_cost_budget_action")
+}
+
+func TestQueryCostBudgetRequiresCardinalityAnalysis(t *testing.T) {
+	limits := Limits{}
+	require.ErrorContains(t, yaml.Unmarshal([]byte("query_cost_budget: 1000"), &limits), "query_cost_budget requires cardinality_analysis_enabled")
+
+	limits = Limits{}
+	require.NoError(t, yaml.Unmarshal([]byte("query_cost_budget: 1000\ncardinality_analysis_enabled: true"), &limits))
+	assert.Equal(t, 1000, limits.QueryCostBudget)
+}
+
 func TestAlertmanagerNotificationLimits(t *testing.T) {
 	for name, tc := range map[string]struct {
 		inputYAML         string
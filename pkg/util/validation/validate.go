@@ -29,14 +29,15 @@ const (
 
 var (
 	// Discarded series / samples reasons.
-	reasonMissingMetricName      = metricReasonFromErrorID(globalerror.MissingMetricName)
-	reasonInvalidMetricName      = metricReasonFromErrorID(globalerror.InvalidMetricName)
-	reasonMaxLabelNamesPerSeries = metricReasonFromErrorID(globalerror.MaxLabelNamesPerSeries)
-	reasonInvalidLabel           = metricReasonFromErrorID(globalerror.SeriesInvalidLabel)
-	reasonLabelNameTooLong       = metricReasonFromErrorID(globalerror.SeriesLabelNameTooLong)
-	reasonLabelValueTooLong      = metricReasonFromErrorID(globalerror.SeriesLabelValueTooLong)
-	reasonDuplicateLabelNames    = metricReasonFromErrorID(globalerror.SeriesWithDuplicateLabelNames)
-	reasonTooFarInFuture         = metricReasonFromErrorID(globalerror.SampleTooFarInFuture)
+	reasonMissingMetricName         = metricReasonFromErrorID(globalerror.MissingMetricName)
+	reasonInvalidMetricName         = metricReasonFromErrorID(globalerror.InvalidMetricName)
+	reasonMaxLabelNamesPerSeries    = metricReasonFromErrorID(globalerror.MaxLabelNamesPerSeries)
+	reasonInvalidLabel              = metricReasonFromErrorID(globalerror.SeriesInvalidLabel)
+	reasonLabelNameTooLong          = metricReasonFromErrorID(globalerror.SeriesLabelNameTooLong)
+	reasonLabelValueTooLong         = metricReasonFromErrorID(globalerror.SeriesLabelValueTooLong)
+	reasonDuplicateLabelNames       = metricReasonFromErrorID(globalerror.SeriesWithDuplicateLabelNames)
+	reasonTooFarInFuture            = metricReasonFromErrorID(globalerror.SampleTooFarInFuture)
+	reasonMaxNativeHistogramBuckets = metricReasonFromErrorID(globalerror.MaxNativeHistogramBuckets)
 
 	// Discarded exemplars reasons.
 	reasonExemplarLabelsMissing    = metricReasonFromErrorID(globalerror.ExemplarLabelsMissing)
@@ -53,14 +54,31 @@ var (
 	// Declared here to avoid duplication in ingester and distributor.
 	ReasonRateLimited = "rate_limited" // same for request and ingestion which are separate errors, so not using metricReasonFromErrorID with global error
 
+	// ReasonAdaptiveRateLimited is the reason for discarding the data of the tenants limited by the distributors
+	// when the ingesters are overloaded.
+	ReasonAdaptiveRateLimited = "adaptive_rate_limited"
+
 	// ReasonTooManyHAClusters is one of the reasons for discarding samples.
 	ReasonTooManyHAClusters = "too_many_ha_clusters"
+
+	// ReasonNativeHistogramsDisabled is the reason for discarding the native histograms of the tenants
+	// whose native histograms ingestion is disabled.
+	ReasonNativeHistogramsDisabled = "native_histograms_disabled"
 )
 
 func metricReasonFromErrorID(id globalerror.ID) string {
 	return strings.ReplaceAll(string(id), "-", "_")
 }
 
+// DiscardReasonFromError returns the reason for discarding the data rejected with the given error,
+// based on the error ID of its message, or "unknown" if the message has no error ID.
+func DiscardReasonFromError(err error) string {
+	if id, ok := globalerror.IDFromMessage(err.Error()); ok {
+		return metricReasonFromErrorID(id)
+	}
+	return "unknown"
+}
+
 // DiscardedRequestsCounter creates per-user counter vector for requests discarded for a given reason.
 func DiscardedRequestsCounter(reg prometheus.Registerer, reason string) *prometheus.CounterVec {
 	return promauto.With(reg).NewCounterVec(
@@ -109,18 +127,20 @@ func DiscardedMetadataCounter(reg prometheus.Registerer, reason string) *prometh
 // SampleValidationConfig helps with getting required config to validate sample.
 type SampleValidationConfig interface {
 	CreationGracePeriod(userID string) time.Duration
+	MaxNativeHistogramBuckets(userID string) int
 }
 
 // SampleValidationMetrics is a collection of metrics used during sample validation.
 type SampleValidationMetrics struct {
-	missingMetricName      *prometheus.CounterVec
-	invalidMetricName      *prometheus.CounterVec
-	maxLabelNamesPerSeries *prometheus.CounterVec
-	invalidLabel           *prometheus.CounterVec
-	labelNameTooLong       *prometheus.CounterVec
-	labelValueTooLong      *prometheus.CounterVec
-	duplicateLabelNames    *prometheus.CounterVec
-	tooFarInFuture         *prometheus.CounterVec
+	missingMetricName         *prometheus.CounterVec
+	invalidMetricName         *prometheus.CounterVec
+	maxLabelNamesPerSeries    *prometheus.CounterVec
+	invalidLabel              *prometheus.CounterVec
+	labelNameTooLong          *prometheus.CounterVec
+	labelValueTooLong         *prometheus.CounterVec
+	duplicateLabelNames       *prometheus.CounterVec
+	tooFarInFuture            *prometheus.CounterVec
+	maxNativeHistogramBuckets *prometheus.CounterVec
 }
 
 func (m *SampleValidationMetrics) DeleteUserMetrics(userID string) {
@@ -134,6 +154,7 @@ func (m *SampleValidationMetrics) DeleteUserMetrics(userID string) {
 	m.labelValueTooLong.DeletePartialMatch(filter)
 	m.duplicateLabelNames.DeletePartialMatch(filter)
 	m.tooFarInFuture.DeletePartialMatch(filter)
+	m.maxNativeHistogramBuckets.DeletePartialMatch(filter)
 }
 
 func (m *SampleValidationMetrics) DeleteUserMetricsForGroup(userID, group string) {
@@ -145,18 +166,20 @@ func (m *SampleValidationMetrics) DeleteUserMetricsForGroup(userID, group string
 	m.labelValueTooLong.DeleteLabelValues(userID, group)
 	m.duplicateLabelNames.DeleteLabelValues(userID, group)
 	m.tooFarInFuture.DeleteLabelValues(userID, group)
+	m.maxNativeHistogramBuckets.DeleteLabelValues(userID, group)
 }
 
 func NewSampleValidationMetrics(r prometheus.Registerer) *SampleValidationMetrics {
 	return &SampleValidationMetrics{
-		missingMetricName:      DiscardedSamplesCounter(r, reasonMissingMetricName),
-		invalidMetricName:      DiscardedSamplesCounter(r, reasonInvalidMetricName),
-		maxLabelNamesPerSeries: DiscardedSamplesCounter(r, reasonMaxLabelNamesPerSeries),
-		invalidLabel:           DiscardedSamplesCounter(r, reasonInvalidLabel),
-		labelNameTooLong:       DiscardedSamplesCounter(r, reasonLabelNameTooLong),
-		labelValueTooLong:      DiscardedSamplesCounter(r, reasonLabelValueTooLong),
-		duplicateLabelNames:    DiscardedSamplesCounter(r, reasonDuplicateLabelNames),
-		tooFarInFuture:         DiscardedSamplesCounter(r, reasonTooFarInFuture),
+		missingMetricName:         DiscardedSamplesCounter(r, reasonMissingMetricName),
+		invalidMetricName:         DiscardedSamplesCounter(r, reasonInvalidMetricName),
+		maxLabelNamesPerSeries:    DiscardedSamplesCounter(r, reasonMaxLabelNamesPerSeries),
+		invalidLabel:              DiscardedSamplesCounter(r, reasonInvalidLabel),
+		labelNameTooLong:          DiscardedSamplesCounter(r, reasonLabelNameTooLong),
+		labelValueTooLong:         DiscardedSamplesCounter(r, reasonLabelValueTooLong),
+		duplicateLabelNames:       DiscardedSamplesCounter(r, reasonDuplicateLabelNames),
+		tooFarInFuture:            DiscardedSamplesCounter(r, reasonTooFarInFuture),
+		maxNativeHistogramBuckets: DiscardedSamplesCounter(r, reasonMaxNativeHistogramBuckets),
 	}
 }
 
@@ -201,6 +224,28 @@ func ValidateSample(m *SampleValidationMetrics, now model.Time, cfg SampleValida
 	return nil
 }
 
+// ValidateSampleHistogram returns an err if the native histogram sample is invalid.
+// The returned error may retain the provided series labels.
+// It uses the passed 'now' time to measure the relative time of the sample.
+func ValidateSampleHistogram(m *SampleValidationMetrics, now model.Time, cfg SampleValidationConfig, userID, group string, ls []mimirpb.LabelAdapter, s mimirpb.Histogram) ValidationError {
+	unsafeMetricName, _ := extract.UnsafeMetricNameFromLabelAdapters(ls)
+
+	if model.Time(s.Timestamp) > now.Add(cfg.CreationGracePeriod(userID)) {
+		m.tooFarInFuture.WithLabelValues(userID, group).Inc()
+		return newSampleTimestampTooNewError(unsafeMetricName, s.Timestamp)
+	}
+
+	if limit := cfg.MaxNativeHistogramBuckets(userID); limit > 0 {
+		buckets := len(s.PositiveDeltas) + len(s.PositiveCounts) + len(s.NegativeDeltas) + len(s.NegativeCounts)
+		if buckets > limit {
+			m.maxNativeHistogramBuckets.WithLabelValues(userID, group).Inc()
+			return newMaxNativeHistogramBucketsError(unsafeMetricName, s.Timestamp, buckets, limit)
+		}
+	}
+
+	return nil
+}
+
 // ValidateExemplar returns an error if the exemplar is invalid.
 // The returned error may retain the provided series labels.
 func ValidateExemplar(m *ExemplarValidationMetrics, userID string, ls []mimirpb.LabelAdapter, e mimirpb.Exemplar) ValidationError {
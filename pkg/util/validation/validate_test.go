@@ -6,6 +6,7 @@
 package validation
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -341,3 +342,14 @@ func TestValidateLabelDuplication(t *testing.T) {
 	}, "a")
 	assert.Equal(t, expected, actual)
 }
+
+func TestDiscardReasonFromError(t *testing.T) {
+	err := ValidateLabels(NewSampleValidationMetrics(nil), validateLabelsCfg{maxLabelNamesPerSeries: 10, maxLabelNameLength: 10, maxLabelValueLength: 10}, "testUser", "", []mimirpb.LabelAdapter{
+		{Name: model.MetricNameLabel, Value: "a"},
+		{Name: "a", Value: "a"},
+		{Name: "a", Value: "a"},
+	}, false)
+	assert.Equal(t, "duplicate_label_names", DiscardReasonFromError(err))
+
+	assert.Equal(t, "unknown", DiscardReasonFromError(errors.New("failed to push")))
+}
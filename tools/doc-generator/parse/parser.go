@@ -351,6 +351,12 @@ func getFieldCustomType(t reflect.Type) (string, bool) {
 		return "map of tracker name (string) to matcher (string)", true
 	case reflect.TypeOf(ephemeral.LabelMatchers{}).String():
 		return "map of source name (string) to series matchers ([]string)", true
+	case reflect.TypeOf(validation.CompactorRetentionRules{}).String():
+		return "map of series selector (string) to retention period (duration)", true
+	case reflect.TypeOf(validation.LabelValuesLimits{}).String():
+		return "map of label name (string) to max values (int)", true
+	case reflect.TypeOf(validation.SeriesSelectorLimits{}).String():
+		return "map of series selector (string) to max series (int)", true
 	default:
 		return "", false
 	}
@@ -433,6 +439,12 @@ func getCustomFieldType(t reflect.Type) (string, bool) {
 		return "map of tracker name (string) to matcher (string)", true
 	case reflect.TypeOf(ephemeral.LabelMatchers{}).String():
 		return "map of source name (string) to series matchers ([]string)", true
+	case reflect.TypeOf(validation.CompactorRetentionRules{}).String():
+		return "map of series selector (string) to retention period (duration)", true
+	case reflect.TypeOf(validation.LabelValuesLimits{}).String():
+		return "map of label name (string) to max values (int)", true
+	case reflect.TypeOf(validation.SeriesSelectorLimits{}).String():
+		return "map of series selector (string) to max series (int)", true
 	default:
 		return "", false
 	}
@@ -468,8 +480,16 @@ func ReflectType(typ string) reflect.Type {
 		return reflect.TypeOf(tsdb.DurationList{})
 	case "map of string to validation.ForwardingRule":
 		return reflect.TypeOf(map[string]validation.ForwardingRule{})
+	case "map of string to validation.AggregationRule":
+		return reflect.TypeOf(map[string]validation.AggregationRule{})
 	case "map of source name (string) to series matchers ([]string)":
 		return reflect.TypeOf(ephemeral.LabelMatchers{})
+	case "map of series selector (string) to retention period (duration)":
+		return reflect.TypeOf(validation.CompactorRetentionRules{})
+	case "map of label name (string) to max values (int)":
+		return reflect.TypeOf(validation.LabelValuesLimits{})
+	case "map of series selector (string) to max series (int)":
+		return reflect.TypeOf(validation.SeriesSelectorLimits{})
 	default:
 		panic("unknown field type " + typ)
 	}